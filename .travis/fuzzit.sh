@@ -29,8 +29,13 @@ cd ../frames
 go-fuzz-build -libfuzzer -o fuzz-frames.a .
 clang -fsanitize=fuzzer fuzz-frames.a -o fuzz-frames
 
+cd ../transportparameters
+go-fuzz-build -libfuzzer -o fuzz-transportparameters.a .
+clang -fsanitize=fuzzer fuzz-transportparameters.a -o fuzz-transportparameters
+
 cd ../..
 
 # Create the jobs
 ./fuzzit create job --type ${FUZZING_TYPE} --branch ${BRANCH} --revision=${TRAVIS_COMMIT} quic-go/fuzz-header fuzzing/header/fuzz-header
 ./fuzzit create job --type ${FUZZING_TYPE} --branch ${BRANCH} --revision=${TRAVIS_COMMIT} quic-go/fuzz-frames fuzzing/frames/fuzz-frames
+./fuzzit create job --type ${FUZZING_TYPE} --branch ${BRANCH} --revision=${TRAVIS_COMMIT} quic-go/fuzz-transportparameters fuzzing/transportparameters/fuzz-transportparameters
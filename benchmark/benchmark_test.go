@@ -8,6 +8,7 @@ import (
 	"io"
 	"math/rand"
 	"net"
+	"runtime"
 
 	quic "github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -76,13 +77,19 @@ var _ = Describe("Benchmarks", func() {
 				buf := &bytes.Buffer{}
 				// measure the time it takes to download the dataLen bytes
 				// note we're measuring the time for the transfer, i.e. excluding the handshake
-				runtime := b.Time("transfer time", func() {
+				buf.Grow(dataLen)
+				var memStatsBefore, memStatsAfter runtime.MemStats
+				runtime.ReadMemStats(&memStatsBefore)
+				transferTime := b.Time("transfer time", func() {
 					_, err := io.Copy(buf, str)
 					Expect(err).NotTo(HaveOccurred())
 				})
+				runtime.ReadMemStats(&memStatsAfter)
 				Expect(buf.Bytes()).To(Equal(data))
 
-				b.RecordValue("transfer rate [MB/s]", float64(dataLen)/1e6/runtime.Seconds())
+				b.RecordValue("transfer rate [MB/s]", float64(dataLen)/1e6/transferTime.Seconds())
+				// This includes the allocations made by the server, which runs in the same process.
+				b.RecordValue("allocations during transfer [MB]", float64(memStatsAfter.TotalAlloc-memStatsBefore.TotalAlloc)/1e6)
 
 				ln.Close()
 				sess.CloseWithError(0, "")
@@ -2,10 +2,16 @@ package quic
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
+// releasedBufferPoison is written to every byte of a packet buffer when it is released,
+// if the buffer pool is disabled using the quic_nobufferpool build tag.
+const releasedBufferPoison = 0xde
+
 type packetBuffer struct {
 	Slice []byte
 
@@ -13,6 +19,11 @@ type packetBuffer struct {
 	// It doesn't support concurrent use.
 	// It is > 1 when used for coalesced packet.
 	refCount int
+	// large buffers are used for packets larger than MaxReceivePacketSize.
+	// They are not put back into the pool.
+	large bool
+	// counted is set if the buffer was counted in the buffer pool stats when it was checked out.
+	counted bool
 }
 
 // Split increases the refCount.
@@ -51,23 +62,108 @@ func (b *packetBuffer) Release() {
 }
 
 func (b *packetBuffer) putBack() {
-	if cap(b.Slice) != int(protocol.MaxReceivePacketSize) {
+	if b.counted {
+		atomic.AddInt64(&bufferPoolStats.InUse, -1)
+		b.counted = false
+	}
+	if !b.large && cap(b.Slice) != int(protocol.MaxReceivePacketSize) {
 		panic("putPacketBuffer called with packet of wrong size!")
 	}
+	if !bufferPoolEnabled {
+		// Overwrite the contents, so that data used after the buffer was released is easy to spot,
+		// and remove the slice, so that accessing it via the buffer panics.
+		b.Slice = b.Slice[:cap(b.Slice)]
+		for i := range b.Slice {
+			b.Slice[i] = releasedBufferPoison
+		}
+		b.Slice = nil
+		return
+	}
+	if b.large {
+		return
+	}
 	bufferPool.Put(b)
 }
 
 var bufferPool sync.Pool
 
+var (
+	bufferPoolStatsEnabled utils.AtomicBool
+	// bufferPoolStats is only updated if bufferPoolStatsEnabled is set.
+	// All fields are accessed atomically.
+	// Hits is not updated directly, it is derived from bufferPoolGets.
+	bufferPoolStats BufferPoolStats
+	bufferPoolGets  uint64
+)
+
+// EnableBufferPoolStats starts collecting statistics about the pool of packet buffers.
+// Collecting statistics adds a small overhead to every packet sent and received.
+// Buffers that were checked out before calling EnableBufferPoolStats are not counted.
+// Warning: This API should not be considered stable and might change soon.
+func EnableBufferPoolStats() {
+	bufferPoolStatsEnabled.Set(true)
+}
+
+// ReadBufferPoolStats returns the statistics about the pool of packet buffers.
+// Statistics are only collected after calling EnableBufferPoolStats.
+// Warning: This API should not be considered stable and might change soon.
+func ReadBufferPoolStats() BufferPoolStats {
+	gets := atomic.LoadUint64(&bufferPoolGets)
+	misses := atomic.LoadUint64(&bufferPoolStats.Misses)
+	var hits uint64
+	if gets > misses {
+		hits = gets - misses
+	}
+	return BufferPoolStats{
+		InUse:       atomic.LoadInt64(&bufferPoolStats.InUse),
+		Allocations: atomic.LoadUint64(&bufferPoolStats.Allocations),
+		Hits:        hits,
+		Misses:      misses,
+	}
+}
+
 func getPacketBuffer() *packetBuffer {
-	buf := bufferPool.Get().(*packetBuffer)
+	var buf *packetBuffer
+	if bufferPoolEnabled {
+		buf = bufferPool.Get().(*packetBuffer)
+		buf.Slice = buf.Slice[:protocol.MaxReceivePacketSize]
+	} else {
+		buf = &packetBuffer{Slice: make([]byte, protocol.MaxReceivePacketSize)}
+	}
 	buf.refCount = 1
-	buf.Slice = buf.Slice[:protocol.MaxReceivePacketSize]
+	if bufferPoolStatsEnabled.Get() {
+		buf.counted = true
+		atomic.AddInt64(&bufferPoolStats.InUse, 1)
+		if bufferPoolEnabled {
+			atomic.AddUint64(&bufferPoolGets, 1)
+		} else {
+			atomic.AddUint64(&bufferPoolStats.Allocations, 1)
+		}
+	}
+	return buf
+}
+
+// getLargePacketBuffer returns a packet buffer that can hold packets of the given size.
+func getLargePacketBuffer(size protocol.ByteCount) *packetBuffer {
+	buf := &packetBuffer{
+		Slice:    make([]byte, size),
+		refCount: 1,
+		large:    true,
+	}
+	if bufferPoolStatsEnabled.Get() {
+		buf.counted = true
+		atomic.AddInt64(&bufferPoolStats.InUse, 1)
+		atomic.AddUint64(&bufferPoolStats.Allocations, 1)
+	}
 	return buf
 }
 
 func init() {
 	bufferPool.New = func() interface{} {
+		if bufferPoolStatsEnabled.Get() {
+			atomic.AddUint64(&bufferPoolStats.Misses, 1)
+			atomic.AddUint64(&bufferPoolStats.Allocations, 1)
+		}
 		return &packetBuffer{
 			Slice: make([]byte, 0, protocol.MaxReceivePacketSize),
 		}
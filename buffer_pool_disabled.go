@@ -0,0 +1,11 @@
+// +build quic_nobufferpool
+
+package quic
+
+// bufferPoolEnabled says if packet buffers are reused.
+// With the quic_nobufferpool build tag, every packet uses a freshly allocated buffer,
+// and releasing a buffer overwrites its contents with releasedBufferPoison.
+// This makes it easier to detect a buffer being used after it was released
+// (e.g. using the race detector), at the cost of a lot of allocations.
+// It should only be used for debugging.
+const bufferPoolEnabled = false
@@ -0,0 +1,41 @@
+// +build quic_nobufferpool
+
+package quic
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Buffer Pool, disabled", func() {
+	It("allocates a new buffer every time", func() {
+		buf := getPacketBuffer()
+		Expect(buf.Slice).To(HaveLen(int(protocol.MaxReceivePacketSize)))
+		first := &buf.Slice[0]
+		buf.Release()
+		buf2 := getPacketBuffer()
+		Expect(buf2).ToNot(BeIdenticalTo(buf))
+		Expect(&buf2.Slice[0]).ToNot(BeIdenticalTo(first))
+	})
+
+	It("poisons released buffers", func() {
+		buf := getPacketBuffer()
+		data := buf.Slice[:10]
+		copy(data, "foobar")
+		buf.Release()
+		Expect(buf.Slice).To(BeNil())
+		Expect(data).To(Equal(bytes.Repeat([]byte{releasedBufferPoison}, 10)))
+	})
+
+	It("poisons released large buffers", func() {
+		buf := getLargePacketBuffer(9000)
+		data := buf.Slice
+		buf.Release()
+		Expect(buf.Slice).To(BeNil())
+		Expect(data).To(Equal(bytes.Repeat([]byte{releasedBufferPoison}, 9000)))
+	})
+})
@@ -0,0 +1,7 @@
+// +build !quic_nobufferpool
+
+package quic
+
+// bufferPoolEnabled says if packet buffers are reused.
+// Use the quic_nobufferpool build tag to disable the pool.
+const bufferPoolEnabled = true
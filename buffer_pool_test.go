@@ -24,6 +24,13 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(func() { buf.Release() }).To(Panic())
 	})
 
+	It("returns large buffers", func() {
+		buf := getLargePacketBuffer(9000)
+		Expect(buf.Slice).To(HaveLen(9000))
+		buf.Release()
+		Expect(getPacketBuffer().Slice).To(HaveCap(int(protocol.MaxReceivePacketSize)))
+	})
+
 	It("panics if it is released twice", func() {
 		buf := getPacketBuffer()
 		buf.Release()
@@ -46,4 +53,40 @@ var _ = Describe("Buffer Pool", func() {
 		buf.Decrement()
 		Expect(func() { buf.Decrement() }).To(Panic())
 	})
+
+	It("collects statistics", func() {
+		EnableBufferPoolStats()
+		before := ReadBufferPoolStats()
+		buf := getPacketBuffer()
+		large := getLargePacketBuffer(9000)
+		stats := ReadBufferPoolStats()
+		Expect(stats.InUse).To(Equal(before.InUse + 2))
+		Expect(stats.Allocations).To(BeNumerically(">=", before.Allocations+1))
+		if bufferPoolEnabled {
+			Expect(stats.Hits + stats.Misses).To(Equal(before.Hits + before.Misses + 1))
+		}
+		buf.Release()
+		Expect(ReadBufferPoolStats().InUse).To(Equal(before.InUse + 1))
+		large.Release()
+		Expect(ReadBufferPoolStats().InUse).To(Equal(before.InUse))
+	})
+
+	It("counts pool misses as allocations", func() {
+		if !bufferPoolEnabled {
+			Skip("The buffer pool is disabled.")
+		}
+		EnableBufferPoolStats()
+		before := ReadBufferPoolStats()
+		var bufs []*packetBuffer
+		// The pool can't hold that many buffers, at least one of them will be allocated.
+		for i := 0; i < 1000; i++ {
+			bufs = append(bufs, getPacketBuffer())
+		}
+		stats := ReadBufferPoolStats()
+		Expect(stats.Misses).To(BeNumerically(">", before.Misses))
+		Expect(stats.Allocations - before.Allocations).To(Equal(stats.Misses - before.Misses))
+		for _, b := range bufs {
+			b.Release()
+		}
+	})
 })
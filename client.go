@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -50,6 +51,29 @@ type client struct {
 
 var _ packetHandler = &client{}
 
+// A VersionNegotiationError occurs when the client and the server can't agree on a QUIC version.
+// It contains the versions offered by the server in its Version Negotiation packet.
+type VersionNegotiationError struct {
+	Ours   []VersionNumber
+	Theirs []VersionNumber
+}
+
+func (e *VersionNegotiationError) Error() string {
+	return fmt.Sprintf("No compatible QUIC version found. We support %s, server offered %s", e.Ours, e.Theirs)
+}
+
+// VerifyRetryIntegrityTag checks the integrity tag of a Retry packet.
+// The packet must include the integrity tag, and origDestConnID is the destination connection ID
+// of the Initial packet that the client sent.
+// It returns false if the version is not supported.
+// This is useful for interop testing; the client performs this check on every Retry it receives.
+func VerifyRetryIntegrityTag(packet []byte, origDestConnID []byte, version VersionNumber) bool {
+	if !protocol.IsValidVersion(version) {
+		return false
+	}
+	return handshake.VerifyRetryIntegrityTag(packet, protocol.ConnectionID(origDestConnID), version)
+}
+
 var (
 	// make it possible to mock connection ID generation in the tests
 	generateConnectionID           = protocol.GenerateConnectionID
@@ -72,6 +96,7 @@ func DialAddr(
 // It uses a new UDP connection and closes this connection when the QUIC session is closed.
 // The hostname for SNI is taken from the given address.
 // The tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites.
+// 0-RTT is used if the tls.Config.ClientSessionCache contains a session ticket for the server.
 func DialAddrEarly(
 	addr string,
 	tlsConf *tls.Config,
@@ -99,15 +124,52 @@ func dialAddrContext(
 	config *Config,
 	use0RTT bool,
 ) (quicSession, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	udpAddr, err := resolveUDPAddr(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	udpConn, err := listenUDP(ctx, &net.UDPAddr{IP: net.IPv4zero, Port: 0}, config)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := dialContext(ctx, udpConn, udpAddr, addr, tlsConf, config, use0RTT, true)
+	if err != nil {
+		// If the session was started, the packet conn was already closed when it was shut down.
+		udpConn.Close()
+		return nil, err
+	}
+	return sess, nil
+}
+
+// resolveUDPAddr works like net.ResolveUDPAddr, but aborts the DNS lookup when the context is canceled.
+// Like net.ResolveUDPAddr, it prefers IPv4 addresses.
+func resolveUDPAddr(ctx context.Context, addr string) (*net.UDPAddr, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := net.DefaultResolver.LookupPort(ctx, "udp", portStr)
 	if err != nil {
 		return nil, err
 	}
-	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if host == "" {
+		return &net.UDPAddr{Port: port}, nil
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
 	if err != nil {
 		return nil, err
 	}
-	return dialContext(ctx, udpConn, udpAddr, addr, tlsConf, config, use0RTT, true)
+	ip := ips[0]
+	for _, a := range ips {
+		if a.IP.To4() != nil {
+			ip = a
+			break
+		}
+	}
+	return &net.UDPAddr{IP: ip.IP, Port: port, Zone: ip.Zone}, nil
 }
 
 // Dial establishes a new QUIC connection to a server using a net.PacketConn.
@@ -130,13 +192,14 @@ func Dial(
 // QUIC connection IDs are used for demultiplexing the different connections.
 // The host parameter is used for SNI.
 // The tls.Config must define an application protocol (using NextProtos).
+// 0-RTT is used if the tls.Config.ClientSessionCache contains a session ticket for the server.
 func DialEarly(
 	pconn net.PacketConn,
 	remoteAddr net.Addr,
 	host string,
 	tlsConf *tls.Config,
 	config *Config,
-) (Session, error) {
+) (EarlySession, error) {
 	return dialContext(context.Background(), pconn, remoteAddr, host, tlsConf, config, true, false)
 }
 
@@ -153,6 +216,39 @@ func DialContext(
 	return dialContext(ctx, pconn, remoteAddr, host, tlsConf, config, false, false)
 }
 
+// DialSessionSocket establishes a new QUIC connection to a server,
+// using the same net.PacketConn as an existing session.
+// Packets belonging to the two sessions are demultiplexed using their connection IDs,
+// so the existing session must not use zero-length connection IDs (which is the default for DialAddr).
+// If not set in the config, the connection ID length of the existing session is used.
+// The new session is independent from the existing session, but if the existing session
+// created the packet conn (using DialAddr), the packet conn is closed when it is closed.
+// Warning: This API should not be considered stable and might change soon.
+func DialSessionSocket(
+	ctx context.Context,
+	sess Session,
+	remoteAddr net.Addr,
+	host string,
+	tlsConf *tls.Config,
+	config *Config,
+) (Session, error) {
+	s, ok := sess.(*session)
+	if !ok {
+		return nil, errors.New("quic: session not created by quic-go")
+	}
+	if s.srcConnIDLen == 0 {
+		return nil, errors.New("quic: session uses zero-length connection IDs, can't share its packet conn")
+	}
+	conf := &Config{}
+	if config != nil {
+		*conf = *config
+	}
+	if conf.ConnectionIDLength == 0 {
+		conf.ConnectionIDLength = s.srcConnIDLen
+	}
+	return dialContext(ctx, s.conn.PacketConn(), remoteAddr, host, tlsConf, conf, false, false)
+}
+
 func dialContext(
 	ctx context.Context,
 	pconn net.PacketConn,
@@ -166,6 +262,9 @@ func dialContext(
 	if tlsConf == nil {
 		return nil, errors.New("quic: tls.Config not set")
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	config = populateClientConfig(config, createdPacketConn)
 	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.StatelessResetKey)
 	if err != nil {
@@ -194,7 +293,7 @@ func newClient(
 	if tlsConf == nil {
 		tlsConf = &tls.Config{}
 	}
-	if tlsConf.ServerName == "" {
+	if tlsConf.ServerName == "" && (config == nil || !config.DisableSNI) {
 		sni := host
 		if strings.IndexByte(sni, ':') != -1 {
 			var err error
@@ -207,11 +306,13 @@ func newClient(
 		tlsConf.ServerName = sni
 	}
 
-	// check that all versions are actually supported
 	if config != nil {
-		for _, v := range config.Versions {
-			if !protocol.IsValidVersion(v) {
-				return nil, fmt.Errorf("%s is not a valid QUIC version", v)
+		if err := validateConfig(config); err != nil {
+			return nil, err
+		}
+		if config.DSCP != 0 {
+			if err := setDSCP(pconn, config.DSCP); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -236,6 +337,11 @@ func newClient(
 		handshakeChan:     make(chan struct{}),
 		logger:            utils.DefaultLogger.WithPrefix("client"),
 	}
+	// Don't override a receive buffer size set by the application,
+	// neither on a net.PacketConn passed to Dial, nor using the SocketControl.
+	if err := setReceiveBuffer(pconn, createdPacketConn && config.SocketControl == nil); err != nil && !config.DisableReceiveBufferWarning {
+		c.logger.Errorf("%s. Packets might be dropped under load.", err)
+	}
 	return c, nil
 }
 
@@ -243,7 +349,7 @@ func newClient(
 // it may be called with nil
 func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 	config = populateConfig(config)
-	if config.ConnectionIDLength == 0 && !createdPacketConn {
+	if config.ConnectionIDLength == 0 && !createdPacketConn && !config.ZeroLengthConnectionIDs {
 		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
 	}
 	return config
@@ -304,6 +410,9 @@ func (c *client) establishSecureConnection(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		c.session.shutdown()
+		// Wait for the run loop to return, so that no go routines are leaked.
+		// If we created the packet conn, it is closed when this returns.
+		<-errorChan
 		return ctx.Err()
 	case err := <-errorChan:
 		return err
@@ -356,12 +465,31 @@ func (c *client) handleVersionNegotiationPacket(p *receivedPacket) {
 	}
 
 	c.logger.Infof("Received a Version Negotiation packet. Supported Versions: %s", hdr.SupportedVersions)
-	newVersion, ok := protocol.ChooseSupportedVersion(c.config.Versions, hdr.SupportedVersions)
-	if !ok {
-		//nolint:stylecheck
-		c.session.destroy(fmt.Errorf("No compatible QUIC version found. We support %s, server offered %s", c.config.Versions, hdr.SupportedVersions))
-		c.logger.Debugf("No compatible QUIC version found.")
-		return
+	var newVersion protocol.VersionNumber
+	if c.config.VersionNegotiation != nil {
+		v, err := c.config.VersionNegotiation(c.config.Versions, hdr.SupportedVersions)
+		if err != nil {
+			c.session.destroy(err)
+			c.logger.Debugf("Version negotiation callback returned an error: %s", err)
+			return
+		}
+		if !protocol.IsSupportedVersion(c.config.Versions, v) {
+			c.session.destroy(fmt.Errorf("version negotiation callback chose version %s, which is not in Config.Versions", v))
+			c.logger.Debugf("Version negotiation callback chose an unsupported version: %s", v)
+			return
+		}
+		newVersion = v
+	} else {
+		v, ok := protocol.ChooseSupportedVersion(c.config.Versions, hdr.SupportedVersions)
+		if !ok {
+			c.session.destroy(&VersionNegotiationError{
+				Ours:   c.config.Versions,
+				Theirs: hdr.SupportedVersions,
+			})
+			c.logger.Debugf("No compatible QUIC version found.")
+			return
+		}
+		newVersion = v
 	}
 	c.receivedVersionNegotiationPacket = true
 	c.negotiatedVersions = hdr.SupportedVersions
@@ -7,9 +7,11 @@ import (
 	"errors"
 	"net"
 	"os"
+	"syscall"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -188,6 +190,52 @@ var _ = Describe("Client", func() {
 			Eventually(hostnameChan).Should(Receive(Equal("foobar")))
 		})
 
+		It("applies the socket control function to the socket created by DialAddr", func() {
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Add(gomock.Any(), gomock.Any())
+			manager.EXPECT().Destroy()
+			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
+
+			newClientSession = func(
+				_ connection,
+				_ sessionRunner,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ *Config,
+				_ *tls.Config,
+				_ protocol.PacketNumber,
+				_ protocol.VersionNumber,
+				_ bool,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) quicSession {
+				sess := NewMockQuicSession(mockCtrl)
+				sess.EXPECT().run()
+				sess.EXPECT().HandshakeComplete().Return(context.Background())
+				return sess
+			}
+			networkChan := make(chan string, 1)
+			config := &Config{
+				SocketControl: func(network, _ string, c syscall.RawConn) error {
+					networkChan <- network
+					return c.Control(func(uintptr) {})
+				},
+			}
+			_, err := DialAddr("localhost:17890", tlsConf, config)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(networkChan).Should(Receive(HavePrefix("udp")))
+		})
+
+		It("returns the error of the socket control function", func() {
+			testErr := errors.New("socket control error")
+			config := &Config{
+				SocketControl: func(string, string, syscall.RawConn) error { return testErr },
+			}
+			_, err := DialAddr("localhost:17890", tlsConf, config)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, testErr)).To(BeTrue())
+		})
+
 		It("allows passing host without port as server name", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
@@ -224,6 +272,42 @@ var _ = Describe("Client", func() {
 			Eventually(hostnameChan).Should(Receive(Equal("test.com")))
 		})
 
+		It("doesn't use the host as the server name, if SNI is disabled", func() {
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Add(gomock.Any(), gomock.Any())
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+			hostnameChan := make(chan string, 1)
+			newClientSession = func(
+				_ connection,
+				_ sessionRunner,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ *Config,
+				tlsConf *tls.Config,
+				_ protocol.PacketNumber,
+				_ protocol.VersionNumber,
+				_ bool,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) quicSession {
+				hostnameChan <- tlsConf.ServerName
+				sess := NewMockQuicSession(mockCtrl)
+				sess.EXPECT().HandshakeComplete().Return(context.Background())
+				sess.EXPECT().run()
+				return sess
+			}
+			_, err := Dial(
+				packetConn,
+				addr,
+				"test.com",
+				tlsConf,
+				&Config{DisableSNI: true},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(hostnameChan).Should(Receive(BeEmpty()))
+		})
+
 		It("returns after the handshake is complete", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
@@ -350,7 +434,6 @@ var _ = Describe("Client", func() {
 			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
 
 			sessionRunning := make(chan struct{})
-			defer close(sessionRunning)
 			sess := NewMockQuicSession(mockCtrl)
 			sess.EXPECT().run().Do(func() {
 				<-sessionRunning
@@ -387,11 +470,40 @@ var _ = Describe("Client", func() {
 				close(dialed)
 			}()
 			Consistently(dialed).ShouldNot(BeClosed())
-			sess.EXPECT().shutdown()
+			shutdownCalled := make(chan struct{})
+			sess.EXPECT().shutdown().Do(func() { close(shutdownCalled) })
 			cancel()
+			Eventually(shutdownCalled).Should(BeClosed())
+			// Dial only returns after the run loop returned
+			Consistently(dialed, 50*time.Millisecond).ShouldNot(BeClosed())
+			close(sessionRunning)
 			Eventually(dialed).Should(BeClosed())
 		})
 
+		It("returns the context error without dialing when the context is already canceled", func() {
+			// don't EXPECT any calls to AddConn
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := DialAddrContext(ctx, "localhost:1337", tlsConf, nil)
+			Expect(err).To(MatchError(context.Canceled))
+			_, err = DialContext(ctx, packetConn, addr, "localhost:1337", tlsConf, nil)
+			Expect(err).To(MatchError(context.Canceled))
+		})
+
+		It("closes the packet conn it created when dialing fails", func() {
+			testErr := errors.New("test error")
+			var conn net.PacketConn
+			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(c net.PacketConn, _ int, _ []byte) (packetHandlerManager, error) {
+				conn = c
+				return nil, testErr
+			})
+			_, err := DialAddrContext(context.Background(), "localhost:1337", tlsConf, nil)
+			Expect(err).To(MatchError(testErr))
+			Expect(conn).ToNot(BeNil())
+			_, err = conn.WriteTo([]byte("foobar"), addr)
+			Expect(err).To(HaveOccurred())
+		})
+
 		It("closes the connection when it was created by DialAddr", func() {
 			if os.Getenv("APPVEYOR") == "True" {
 				Skip("This test is flaky on AppVeyor.")
@@ -447,29 +559,94 @@ var _ = Describe("Client", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		Context("sharing the packet conn of an existing session", func() {
+			It("dials using the packet conn of the existing session", func() {
+				existing := &session{
+					srcConnIDLen: 6,
+					conn:         &conn{pconn: packetConn, currentAddr: addr},
+				}
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				manager.EXPECT().Add(gomock.Any(), gomock.Any())
+				mockMultiplexer.EXPECT().AddConn(packetConn, 6, gomock.Any()).Return(manager, nil)
+
+				remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 4242}
+				newClientSession = func(
+					conn connection,
+					_ sessionRunner,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					conf *Config,
+					_ *tls.Config,
+					_ protocol.PacketNumber,
+					_ protocol.VersionNumber,
+					_ bool,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					Expect(conn.PacketConn()).To(Equal(packetConn))
+					Expect(conn.RemoteAddr()).To(Equal(remoteAddr))
+					Expect(conf.ConnectionIDLength).To(Equal(6))
+					sess := NewMockQuicSession(mockCtrl)
+					sess.EXPECT().run()
+					sess.EXPECT().HandshakeComplete().Return(context.Background())
+					return sess
+				}
+				_, err := DialSessionSocket(context.Background(), existing, remoteAddr, "localhost:4242", tlsConf, nil)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("refuses to share the packet conn of a session that uses zero-length connection IDs", func() {
+				existing := &session{conn: &conn{pconn: packetConn, currentAddr: addr}}
+				_, err := DialSessionSocket(context.Background(), existing, addr, "localhost:1337", tlsConf, nil)
+				Expect(err).To(MatchError("quic: session uses zero-length connection IDs, can't share its packet conn"))
+			})
+		})
+
 		Context("quic.Config", func() {
 			It("setups with the right values", func() {
 				tracer := quictrace.NewTracer()
 				tokenStore := NewLRUTokenStore(10, 4)
 				config := &Config{
-					HandshakeTimeout:      1337 * time.Minute,
-					MaxIdleTimeout:        42 * time.Hour,
-					MaxIncomingStreams:    1234,
-					MaxIncomingUniStreams: 4321,
-					ConnectionIDLength:    13,
-					StatelessResetKey:     []byte("foobar"),
-					QuicTracer:            tracer,
-					TokenStore:            tokenStore,
+					HandshakeTimeout:          1337 * time.Minute,
+					MaxIdleTimeout:            42 * time.Hour,
+					MaxIncomingStreams:        1234,
+					MaxIncomingUniStreams:     4321,
+					MaxIncomingStreamsLimit:   10000,
+					ConnectionIDLength:        13,
+					ActiveConnectionIDLimit:   7,
+					StatelessResetKey:         []byte("foobar"),
+					QuicTracer:                tracer,
+					TokenStore:                tokenStore,
+					DisableSNI:                true,
+					MaxAckDelay:               42 * time.Millisecond,
+					AckDelayExponent:          5,
+					CongestionControl:         CongestionControlCubic,
+					InitialMaxPacketSize:      8900,
+					InitialRTT:                42 * time.Millisecond,
+					DisableWriteRetries:       true,
+					DSCP:                      46,
+					MaxStreamReassemblyBuffer: 1 << 16,
 				}
 				c := populateClientConfig(config, false)
 				Expect(c.HandshakeTimeout).To(Equal(1337 * time.Minute))
 				Expect(c.MaxIdleTimeout).To(Equal(42 * time.Hour))
 				Expect(c.MaxIncomingStreams).To(Equal(1234))
 				Expect(c.MaxIncomingUniStreams).To(Equal(4321))
+				Expect(c.MaxIncomingStreamsLimit).To(BeEquivalentTo(10000))
 				Expect(c.ConnectionIDLength).To(Equal(13))
+				Expect(c.ActiveConnectionIDLimit).To(BeEquivalentTo(7))
 				Expect(c.StatelessResetKey).To(Equal([]byte("foobar")))
 				Expect(c.QuicTracer).To(Equal(tracer))
 				Expect(c.TokenStore).To(Equal(tokenStore))
+				Expect(c.DisableSNI).To(BeTrue())
+				Expect(c.MaxAckDelay).To(Equal(42 * time.Millisecond))
+				Expect(c.InitialRTT).To(Equal(42 * time.Millisecond))
+				Expect(c.AckDelayExponent).To(Equal(5))
+				Expect(c.CongestionControl).To(Equal(CongestionControlCubic))
+				Expect(c.InitialMaxPacketSize).To(BeEquivalentTo(8900))
+				Expect(c.DisableWriteRetries).To(BeTrue())
+				Expect(c.DSCP).To(Equal(46))
+				Expect(c.MaxStreamReassemblyBuffer).To(BeEquivalentTo(1 << 16))
 			})
 
 			It("errors when the Config contains an invalid version", func() {
@@ -481,6 +658,64 @@ var _ = Describe("Client", func() {
 				Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
 			})
 
+			It("errors when the Config contains a too small MaxUDPPayloadSize", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", tlsConf, &Config{MaxUDPPayloadSize: 1199})
+				Expect(err).To(MatchError("invalid MaxUDPPayloadSize: 1199 (minimum 1200)"))
+			})
+
+			It("errors when the Config contains an invalid InitialMaxPacketSize", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", tlsConf, &Config{InitialMaxPacketSize: 1000})
+				Expect(err).To(MatchError("invalid InitialMaxPacketSize: 1000 (must be between 1200 and 65527)"))
+			})
+
+			It("errors when the Config contains an invalid DSCP", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", tlsConf, &Config{DSCP: 64})
+				Expect(err).To(MatchError("invalid DSCP: 64 (must be between 0 and 63)"))
+			})
+
+			It("errors when the Config contains a too large MaxAckDelay", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", tlsConf, &Config{MaxAckDelay: 20 * time.Second})
+				Expect(err).To(MatchError("invalid MaxAckDelay: 20s (must be smaller than 16.383s)"))
+			})
+
+			It("errors when the Config contains a too large AckDelayExponent", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", tlsConf, &Config{AckDelayExponent: 21})
+				Expect(err).To(MatchError("invalid AckDelayExponent: 21 (maximum 20)"))
+			})
+
+			It("errors when the Config contains an invalid CongestionControl", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", tlsConf, &Config{CongestionControl: 42})
+				Expect(err).To(MatchError("invalid CongestionControl: 42"))
+			})
+
+			It("uses an ack_delay_exponent of 0", func() {
+				c := populateClientConfig(&Config{AckDelayExponent: -1}, false)
+				Expect(c.AckDelayExponent).To(BeZero())
+			})
+
+			It("limits the MaxUDPPayloadSize to the maximum receive packet size", func() {
+				c := populateClientConfig(&Config{MaxUDPPayloadSize: 9000}, false)
+				Expect(c.MaxUDPPayloadSize).To(BeEquivalentTo(protocol.MaxReceivePacketSize))
+			})
+
 			It("disables bidirectional streams", func() {
 				config := &Config{
 					MaxIncomingStreams:    -1,
@@ -507,11 +742,57 @@ var _ = Describe("Client", func() {
 				Expect(c.ConnectionIDLength).To(BeZero())
 			})
 
+			It("uses 0-byte connection IDs when configured to", func() {
+				c := populateClientConfig(&Config{ZeroLengthConnectionIDs: true}, false)
+				Expect(c.ConnectionIDLength).To(BeZero())
+				Expect(c.ZeroLengthConnectionIDs).To(BeTrue())
+			})
+
 			It("fills in default values if options are not set in the Config", func() {
 				c := populateClientConfig(&Config{}, false)
 				Expect(c.Versions).To(Equal(protocol.SupportedVersions))
 				Expect(c.HandshakeTimeout).To(Equal(protocol.DefaultHandshakeTimeout))
 				Expect(c.MaxIdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
+				Expect(c.ActiveConnectionIDLimit).To(BeEquivalentTo(protocol.MaxActiveConnectionIDs))
+				Expect(c.MaxRetiredConnectionIDs).To(BeEquivalentTo(protocol.MaxRetiredConnectionIDsPerActiveConnectionID * protocol.MaxActiveConnectionIDs))
+				Expect(c.MaxAckDelay).To(Equal(protocol.MaxAckDelay))
+				Expect(c.AckDelayExponent).To(Equal(protocol.AckDelayExponent))
+				Expect(c.MaxIncomingStreamsLimit).To(BeEquivalentTo(protocol.MaxStreamCount))
+			})
+
+			It("caps the connection-level flow control window at the MaxConnectionReceiveBuffer", func() {
+				c := populateClientConfig(&Config{MaxConnectionReceiveBuffer: 1 << 20}, false)
+				Expect(c.MaxReceiveConnectionFlowControlWindow).To(BeEquivalentTo(1 << 20))
+				Expect(c.MaxConnectionReceiveBuffer).To(BeEquivalentTo(1 << 20))
+				c = populateClientConfig(&Config{
+					MaxConnectionReceiveBuffer:            1 << 20,
+					MaxReceiveConnectionFlowControlWindow: 1 << 10,
+				}, false)
+				Expect(c.MaxReceiveConnectionFlowControlWindow).To(BeEquivalentTo(1 << 10))
+			})
+
+			It("caps the stream-level flow control window at the MaxStreamReassemblyBuffer", func() {
+				c := populateClientConfig(&Config{MaxStreamReassemblyBuffer: 1 << 16}, false)
+				Expect(c.MaxReceiveStreamFlowControlWindow).To(BeEquivalentTo(1 << 16))
+				c = populateClientConfig(&Config{
+					MaxStreamReassemblyBuffer:         1 << 16,
+					MaxReceiveStreamFlowControlWindow: 1 << 10,
+				}, false)
+				Expect(c.MaxReceiveStreamFlowControlWindow).To(BeEquivalentTo(1 << 10))
+			})
+
+			It("enforces the minimum active_connection_id_limit", func() {
+				c := populateClientConfig(&Config{ActiveConnectionIDLimit: 1}, false)
+				Expect(c.ActiveConnectionIDLimit).To(BeEquivalentTo(2))
+			})
+
+			It("allows at least twice as many retired connection IDs as active connection IDs", func() {
+				c := populateClientConfig(&Config{ActiveConnectionIDLimit: 10, MaxRetiredConnectionIDs: 5}, false)
+				Expect(c.MaxRetiredConnectionIDs).To(BeEquivalentTo(20))
+				c = populateClientConfig(&Config{ActiveConnectionIDLimit: 2, MaxRetiredConnectionIDs: 2}, false)
+				Expect(c.MaxRetiredConnectionIDs).To(BeEquivalentTo(4))
+				c = populateClientConfig(&Config{ActiveConnectionIDLimit: 10, MaxRetiredConnectionIDs: 50}, false)
+				Expect(c.MaxRetiredConnectionIDs).To(BeEquivalentTo(50))
 			})
 		})
 
@@ -556,6 +837,28 @@ var _ = Describe("Client", func() {
 			Expect(conf.Versions).To(Equal(config.Versions))
 		})
 
+		It("verifies Retry integrity tags", func() {
+			origDestConnID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeRetry,
+					SrcConnectionID:  protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
+					DestConnectionID: protocol.ConnectionID{8, 7, 6, 5, 4, 3, 2, 1},
+					Token:            []byte("foobar"),
+					Version:          protocol.VersionTLS,
+				},
+			}
+			buf := &bytes.Buffer{}
+			Expect(hdr.Write(buf, protocol.VersionTLS)).To(Succeed())
+			packet := append(buf.Bytes(), handshake.GetRetryIntegrityTag(buf.Bytes(), origDestConnID, protocol.VersionTLS)[:]...)
+			Expect(VerifyRetryIntegrityTag(packet, origDestConnID, protocol.VersionTLS)).To(BeTrue())
+			Expect(VerifyRetryIntegrityTag(packet, []byte{1, 2, 3, 4}, protocol.VersionTLS)).To(BeFalse())
+			Expect(VerifyRetryIntegrityTag(packet, origDestConnID, 0x1234)).To(BeFalse())
+			packet[len(packet)-1]++
+			Expect(VerifyRetryIntegrityTag(packet, origDestConnID, protocol.VersionTLS)).To(BeFalse())
+		})
+
 		Context("version negotiation", func() {
 			var origSupportedVersions []protocol.VersionNumber
 
@@ -630,6 +933,10 @@ var _ = Describe("Client", func() {
 					defer GinkgoRecover()
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("No compatible QUIC version found."))
+					Expect(err).To(BeAssignableToTypeOf(&VersionNegotiationError{}))
+					vnErr := err.(*VersionNegotiationError)
+					Expect(vnErr.Ours).To(Equal(protocol.SupportedVersions))
+					Expect(vnErr.Theirs).To(ContainElement(protocol.VersionNumber(1337)))
 					close(done)
 				})
 				cl.session = sess
@@ -672,6 +979,65 @@ var _ = Describe("Client", func() {
 				Expect(cl.version).To(Equal(protocol.VersionNumber(1234)))
 			})
 
+			It("changes to the version chosen by the version negotiation callback", func() {
+				phm := NewMockPacketHandlerManager(mockCtrl)
+				cl.packetHandlers = phm
+
+				sess := NewMockQuicSession(mockCtrl)
+				destroyed := make(chan struct{})
+				sess.EXPECT().closeForRecreating().Do(func() {
+					close(destroyed)
+				})
+				cl.session = sess
+				versions := []protocol.VersionNumber{1234, 4321}
+				cl.config = &Config{
+					Versions: versions,
+					VersionNegotiation: func(ours, theirs []VersionNumber) (VersionNumber, error) {
+						Expect(ours).To(Equal(versions))
+						Expect(theirs).To(ContainElement(protocol.VersionNumber(4321)))
+						return 4321, nil
+					},
+				}
+				cl.handlePacket(composeVersionNegotiationPacket(connID, versions))
+				Eventually(destroyed).Should(BeClosed())
+				Expect(cl.version).To(Equal(protocol.VersionNumber(4321)))
+			})
+
+			It("errors if the version negotiation callback returns an error", func() {
+				sess := NewMockQuicSession(mockCtrl)
+				done := make(chan struct{})
+				testErr := errors.New("no version for you")
+				sess.EXPECT().destroy(testErr).Do(func(error) { close(done) })
+				cl.session = sess
+				cl.config = &Config{
+					Versions: []protocol.VersionNumber{1234},
+					VersionNegotiation: func(_, _ []VersionNumber) (VersionNumber, error) {
+						return 0, testErr
+					},
+				}
+				cl.handlePacket(composeVersionNegotiationPacket(connID, []protocol.VersionNumber{1234}))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("errors if the version negotiation callback chooses a version that is not configured", func() {
+				sess := NewMockQuicSession(mockCtrl)
+				done := make(chan struct{})
+				sess.EXPECT().destroy(gomock.Any()).Do(func(err error) {
+					defer GinkgoRecover()
+					Expect(err).To(MatchError("version negotiation callback chose version 0x10e1, which is not in Config.Versions"))
+					close(done)
+				})
+				cl.session = sess
+				cl.config = &Config{
+					Versions: []protocol.VersionNumber{1234},
+					VersionNegotiation: func(_, _ []VersionNumber) (VersionNumber, error) {
+						return 4321, nil
+					},
+				}
+				cl.handlePacket(composeVersionNegotiationPacket(connID, []protocol.VersionNumber{4321}))
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("drops version negotiation packets that contain the offered version", func() {
 				cl.config = &Config{}
 				ver := cl.version
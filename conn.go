@@ -7,11 +7,15 @@ import (
 
 type connection interface {
 	Write([]byte) error
+	// WriteTo writes a packet to an address other than the current remote address.
+	WriteTo([]byte, net.Addr) error
 	Read([]byte) (int, net.Addr, error)
 	Close() error
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
 	SetCurrentRemoteAddr(net.Addr)
+	// PacketConn returns the underlying packet conn.
+	PacketConn() net.PacketConn
 }
 
 type conn struct {
@@ -19,12 +23,34 @@ type conn struct {
 
 	pconn       net.PacketConn
 	currentAddr net.Addr
+
+	// If the packet conn supports it, packets are sent from the local address
+	// that the peer sent its first packet to.
+	oob  *oobConn
+	info *packetInfo
 }
 
 var _ connection = &conn{}
 
 func (c *conn) Write(p []byte) error {
-	_, err := c.pconn.WriteTo(p, c.currentAddr)
+	c.mutex.RLock()
+	addr, info := c.currentAddr, c.info
+	c.mutex.RUnlock()
+	return c.writeTo(p, addr, info)
+}
+
+// WriteTo doesn't use the packet info of the current path.
+// A packet sent to a new path leaves from the local address chosen by the kernel.
+func (c *conn) WriteTo(p []byte, addr net.Addr) error {
+	return c.writeTo(p, addr, nil)
+}
+
+func (c *conn) writeTo(p []byte, addr net.Addr, info *packetInfo) error {
+	if c.oob != nil {
+		_, err := c.oob.WritePacket(p, addr, info)
+		return err
+	}
+	_, err := c.pconn.WriteTo(p, addr)
 	return err
 }
 
@@ -32,9 +58,12 @@ func (c *conn) Read(p []byte) (int, net.Addr, error) {
 	return c.pconn.ReadFrom(p)
 }
 
+// SetCurrentRemoteAddr migrates to a new path.
+// The packet info of the old path is discarded.
 func (c *conn) SetCurrentRemoteAddr(addr net.Addr) {
 	c.mutex.Lock()
 	c.currentAddr = addr
+	c.info = nil
 	c.mutex.Unlock()
 }
 
@@ -49,6 +78,10 @@ func (c *conn) RemoteAddr() net.Addr {
 	return addr
 }
 
+func (c *conn) PacketConn() net.PacketConn {
+	return c.pconn
+}
+
 func (c *conn) Close() error {
 	return c.pconn.Close()
 }
@@ -86,7 +86,7 @@ func (m *connIDGenerator) Retire(seq uint64) error {
 }
 
 func (m *connIDGenerator) issueNewConnID() error {
-	connID, err := protocol.GenerateConnectionID(m.connIDLen)
+	connID, err := generateConnectionID(m.connIDLen)
 	if err != nil {
 		return err
 	}
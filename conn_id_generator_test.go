@@ -1,7 +1,14 @@
 package quic
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -136,3 +143,157 @@ var _ = Describe("Connection ID Generator", func() {
 		}
 	})
 })
+
+// useFixedConnectionIDs replaces the random connection ID generation with a fixed set of connection IDs.
+// Connection IDs are handed out in order, separately for every connection ID length,
+// such that client and server don't interfere with each other if they use different lengths.
+// The Initial destination connection ID of the client is taken from the queue for initialConnIDLen.
+// It returns a function that restores random connection ID generation.
+func useFixedConnectionIDs(initialConnIDLen int, connIDs ...protocol.ConnectionID) (restore func()) {
+	var mutex sync.Mutex
+	queues := make(map[int][]protocol.ConnectionID)
+	for _, c := range connIDs {
+		queues[c.Len()] = append(queues[c.Len()], c)
+	}
+	pop := func(l int) (protocol.ConnectionID, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if l == 0 {
+			return protocol.ConnectionID{}, nil
+		}
+		if len(queues[l]) == 0 {
+			return nil, fmt.Errorf("no fixed connection ID of length %d left", l)
+		}
+		c := queues[l][0]
+		queues[l] = queues[l][1:]
+		return c, nil
+	}
+
+	origGenerateConnectionID := generateConnectionID
+	origGenerateConnectionIDForInitial := generateConnectionIDForInitial
+	generateConnectionID = pop
+	generateConnectionIDForInitial = func() (protocol.ConnectionID, error) { return pop(initialConnIDLen) }
+	return func() {
+		generateConnectionID = origGenerateConnectionID
+		generateConnectionIDForInitial = origGenerateConnectionIDForInitial
+	}
+}
+
+// A headerRecordingConn records the connection ID part of the header of every packet sent.
+type headerRecordingConn struct {
+	net.PacketConn
+
+	mutex   sync.Mutex
+	headers [][]byte
+}
+
+func (c *headerRecordingConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	hdr, _, _, err := wire.ParsePacket(b, 0)
+	if err == nil && hdr.IsLongHeader {
+		// first byte, version, both connection IDs and their lengths
+		hdrLen := 1 + 4 + 1 + hdr.DestConnectionID.Len() + 1 + hdr.SrcConnectionID.Len()
+		c.mutex.Lock()
+		c.headers = append(c.headers, append([]byte{}, b[1:hdrLen]...))
+		c.mutex.Unlock()
+	}
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func (c *headerRecordingConn) Headers() [][]byte {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.headers
+}
+
+var _ = Describe("Fixed connection IDs", func() {
+	var serverConnIDs, clientConnIDs []protocol.ConnectionID
+	initialConnID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe}
+
+	BeforeEach(func() {
+		serverConnIDs = nil
+		clientConnIDs = nil
+		for i := byte(0); i < 10; i++ {
+			serverConnIDs = append(serverConnIDs, protocol.ConnectionID{0x5e, 0x5e, 0x5e, 0x5e, 0x5e, 0x5e, 0x5e, i})
+			clientConnIDs = append(clientConnIDs, protocol.ConnectionID{0xc1, 0xc1, 0xc1, 0xc1, 0xc1, i})
+		}
+	})
+
+	// handshake runs a handshake using the fixed connection IDs,
+	// and returns the headers of the long header packets sent by client and server.
+	handshake := func() (clientHeaders, serverHeaders [][]byte) {
+		connIDs := append([]protocol.ConnectionID{initialConnID}, serverConnIDs...)
+		restore := useFixedConnectionIDs(initialConnID.Len(), append(connIDs, clientConnIDs...)...)
+		defer restore()
+
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		serverConn := &headerRecordingConn{PacketConn: udpConn}
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"fixed-conn-ids"}
+		ln, err := Listen(serverConn, tlsConf, &Config{
+			ConnectionIDLength: serverConnIDs[0].Len(),
+			AcceptToken:        func(net.Addr, *Token) bool { return true },
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		udpConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		clientConn := &headerRecordingConn{PacketConn: udpConn}
+		sess, err := Dial(
+			clientConn,
+			ln.Addr(),
+			"localhost",
+			&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"fixed-conn-ids"}},
+			&Config{ConnectionIDLength: clientConnIDs[0].Len()},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		serverSess, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+		Eventually(serverSess.Context().Done()).Should(BeClosed())
+		Expect(ln.Close()).To(Succeed())
+		Expect(clientConn.Close()).To(Succeed())
+		Expect(serverConn.Close()).To(Succeed())
+		// wait until the multiplexer has stopped listening on both connections
+		Eventually(func() int {
+			m := getMultiplexer().(*connMultiplexer)
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			return len(m.conns)
+		}).Should(BeZero())
+		return clientConn.Headers(), serverConn.Headers()
+	}
+
+	It("uses the fixed connection IDs during the handshake", func() {
+		clientHeaders, serverHeaders := handshake()
+		Expect(clientHeaders).ToNot(BeEmpty())
+		Expect(serverHeaders).ToNot(BeEmpty())
+		// The client's first Initial is sent to the Initial destination connection ID.
+		Expect(clientHeaders[0][5 : 5+initialConnID.Len()]).To(Equal([]byte(initialConnID)))
+		Expect(clientHeaders[0][6+initialConnID.Len():]).To(Equal([]byte(clientConnIDs[0])))
+		// The server chooses the first connection ID from the queue.
+		Expect(serverHeaders[0][5 : 5+clientConnIDs[0].Len()]).To(Equal([]byte(clientConnIDs[0])))
+		Expect(serverHeaders[0][6+clientConnIDs[0].Len():]).To(Equal([]byte(serverConnIDs[0])))
+	})
+
+	It("produces reproducible headers", func() {
+		clientHeaders1, serverHeaders1 := handshake()
+		clientHeaders2, serverHeaders2 := handshake()
+		Expect(clientHeaders1[0]).To(Equal(clientHeaders2[0]))
+		Expect(serverHeaders1[0]).To(Equal(serverHeaders2[0]))
+		// Retransmissions might change the number of packets sent, but not the headers used.
+		unique := func(headers [][]byte) []string {
+			var u []string
+			seen := make(map[string]bool)
+			for _, h := range headers {
+				if !seen[string(h)] {
+					seen[string(h)] = true
+					u = append(u, string(h))
+				}
+			}
+			return u
+		}
+		Expect(unique(clientHeaders1)).To(Equal(unique(clientHeaders2)))
+		Expect(unique(serverHeaders1)).To(Equal(unique(serverHeaders2)))
+	})
+})
@@ -3,15 +3,19 @@ package quic
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	mrand "math/rand"
 
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
+var errNoConnectionIDAvailable = errors.New("no unused connection ID available")
+
 type connIDManager struct {
 	queue utils.NewConnectionIDList
 
@@ -19,6 +23,11 @@ type connIDManager struct {
 	highestRetired            uint64
 	activeConnectionID        protocol.ConnectionID
 	activeStatelessResetToken *[16]byte
+	// the value we sent in the active_connection_id_limit transport parameter
+	activeConnectionIDLimit uint64
+	// RETIRE_CONNECTION_ID frames requested by the peer that haven't been acknowledged yet
+	unackedRetirements    uint64
+	maxUnackedRetirements uint64
 
 	// We change the connection ID after sending on average
 	// protocol.PacketsPerConnectionID packets. The actual value is randomized
@@ -30,21 +39,25 @@ type connIDManager struct {
 	addStatelessResetToken    func([16]byte)
 	removeStatelessResetToken func([16]byte)
 	retireStatelessResetToken func([16]byte)
-	queueControlFrame         func(wire.Frame)
+	queueControlFrame         func(ackhandler.Frame)
 }
 
 func newConnIDManager(
 	initialDestConnID protocol.ConnectionID,
+	activeConnectionIDLimit uint64,
+	maxUnackedRetirements uint64,
 	addStatelessResetToken func([16]byte),
 	removeStatelessResetToken func([16]byte),
 	retireStatelessResetToken func([16]byte),
-	queueControlFrame func(wire.Frame),
+	queueControlFrame func(ackhandler.Frame),
 ) *connIDManager {
 	b := make([]byte, 8)
 	_, _ = rand.Read(b) // ignore the error here. Nothing bad will happen if the seed is not perfectly random.
 	seed := int64(binary.BigEndian.Uint64(b))
 	return &connIDManager{
 		activeConnectionID:        initialDestConnID,
+		activeConnectionIDLimit:   activeConnectionIDLimit,
+		maxUnackedRetirements:     maxUnackedRetirements,
 		addStatelessResetToken:    addStatelessResetToken,
 		removeStatelessResetToken: removeStatelessResetToken,
 		retireStatelessResetToken: retireStatelessResetToken,
@@ -57,9 +70,15 @@ func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
 	if err := h.add(f); err != nil {
 		return err
 	}
-	if h.queue.Len() >= protocol.MaxActiveConnectionIDs {
+	// The active connection ID is not stored in the queue.
+	if uint64(h.queue.Len()) >= h.activeConnectionIDLimit {
 		return qerr.ConnectionIDLimitError
 	}
+	// Every connection ID the peer makes us retire costs a RETIRE_CONNECTION_ID frame.
+	// Don't allow the peer to make us queue an unbounded number of them.
+	if h.unackedRetirements > h.maxUnackedRetirements {
+		return qerr.Error(qerr.ConnectionIDLimitError, "too many unacknowledged RETIRE_CONNECTION_ID frames")
+	}
 	return nil
 }
 
@@ -67,9 +86,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 	// If the NEW_CONNECTION_ID frame is reordered, such that its sequenece number
 	// was already retired, send the RETIRE_CONNECTION_ID frame immediately.
 	if f.SequenceNumber < h.highestRetired {
-		h.queueControlFrame(&wire.RetireConnectionIDFrame{
-			SequenceNumber: f.SequenceNumber,
-		})
+		h.retire(f.SequenceNumber, true)
 		return nil
 	}
 
@@ -82,9 +99,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 				break
 			}
 			next = el.Next()
-			h.queueControlFrame(&wire.RetireConnectionIDFrame{
-				SequenceNumber: el.Value.SequenceNumber,
-			})
+			h.retire(el.Value.SequenceNumber, true)
 			h.queue.Remove(el)
 		}
 		h.highestRetired = f.RetirePriorTo
@@ -127,15 +142,13 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 	// Retire the active connection ID, if necessary.
 	if h.activeSequenceNumber < f.RetirePriorTo {
 		// The queue is guaranteed to have at least one element at this point.
-		h.updateConnectionID()
+		h.updateConnectionID(true)
 	}
 	return nil
 }
 
-func (h *connIDManager) updateConnectionID() {
-	h.queueControlFrame(&wire.RetireConnectionIDFrame{
-		SequenceNumber: h.activeSequenceNumber,
-	})
+func (h *connIDManager) updateConnectionID(requestedByPeer bool) {
+	h.retire(h.activeSequenceNumber, requestedByPeer)
 	h.highestRetired = utils.MaxUint64(h.highestRetired, h.activeSequenceNumber)
 	if h.activeStatelessResetToken != nil {
 		h.retireStatelessResetToken(*h.activeStatelessResetToken)
@@ -150,6 +163,38 @@ func (h *connIDManager) updateConnectionID() {
 	h.addStatelessResetToken(*h.activeStatelessResetToken)
 }
 
+// retire queues a RETIRE_CONNECTION_ID frame.
+// Only retirements requested by the peer count towards the limit of unacknowledged retirements,
+// since we control how often we retire connection IDs ourselves.
+func (h *connIDManager) retire(seq uint64, requestedByPeer bool) {
+	if requestedByPeer {
+		h.unackedRetirements++
+	}
+	h.queueRetireConnectionIDFrame(&wire.RetireConnectionIDFrame{SequenceNumber: seq}, requestedByPeer)
+}
+
+func (h *connIDManager) queueRetireConnectionIDFrame(f *wire.RetireConnectionIDFrame, requestedByPeer bool) {
+	h.queueControlFrame(ackhandler.Frame{
+		Frame: f,
+		OnAcked: func(wire.Frame) {
+			if requestedByPeer {
+				h.unackedRetirements--
+			}
+		},
+		OnLost: func(wire.Frame) { h.queueRetireConnectionIDFrame(f, requestedByPeer) },
+	})
+}
+
+// Rotate switches to the next connection ID provided by the peer, and retires the active connection ID.
+// It errors if the peer didn't provide any unused connection IDs.
+func (h *connIDManager) Rotate() error {
+	if h.queue.Len() == 0 {
+		return errNoConnectionIDAvailable
+	}
+	h.updateConnectionID(false)
+	return nil
+}
+
 func (h *connIDManager) Close() {
 	if h.activeStatelessResetToken != nil {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
@@ -186,13 +231,13 @@ func (h *connIDManager) shouldUpdateConnID() bool {
 	// For later changes, only change if
 	// 1. The queue of connection IDs is filled more than 50%.
 	// 2. We sent at least PacketsPerConnectionID packets
-	return 2*h.queue.Len() >= protocol.MaxActiveConnectionIDs &&
+	return 2*uint64(h.queue.Len()) >= h.activeConnectionIDLimit &&
 		h.packetsSinceLastChange >= h.packetsPerConnectionID
 }
 
 func (h *connIDManager) Get() protocol.ConnectionID {
 	if h.shouldUpdateConnID() {
-		h.updateConnectionID()
+		h.updateConnectionID(false)
 	}
 	return h.activeConnectionID
 }
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	. "github.com/onsi/ginkgo"
@@ -11,6 +12,7 @@ var _ = Describe("Connection ID Manager", func() {
 	var (
 		m             *connIDManager
 		frameQueue    []wire.Frame
+		queuedFrames  []ackhandler.Frame
 		tokenAdded    *[16]byte
 		retiredTokens [][16]byte
 		removedTokens [][16]byte
@@ -19,17 +21,20 @@ var _ = Describe("Connection ID Manager", func() {
 
 	BeforeEach(func() {
 		frameQueue = nil
+		queuedFrames = nil
 		tokenAdded = nil
 		retiredTokens = nil
 		removedTokens = nil
 		m = newConnIDManager(
 			initialConnID,
+			protocol.MaxActiveConnectionIDs,
+			protocol.MaxRetiredConnectionIDsPerActiveConnectionID*protocol.MaxActiveConnectionIDs,
 			func(token [16]byte) { tokenAdded = &token },
 			func(token [16]byte) { removedTokens = append(removedTokens, token) },
 			func(token [16]byte) { retiredTokens = append(retiredTokens, token) },
-			func(f wire.Frame,
-			) {
-				frameQueue = append(frameQueue, f)
+			func(f ackhandler.Frame) {
+				frameQueue = append(frameQueue, f.Frame)
+				queuedFrames = append(queuedFrames, f)
 			})
 	})
 
@@ -191,6 +196,89 @@ var _ = Describe("Connection ID Manager", func() {
 		})).To(MatchError("CONNECTION_ID_LIMIT_ERROR"))
 	})
 
+	It("uses the configured active_connection_id_limit", func() {
+		m = newConnIDManager(
+			initialConnID,
+			2,
+			8,
+			func([16]byte) {},
+			func([16]byte) {},
+			func([16]byte) {},
+			func(ackhandler.Frame) {},
+		)
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ConnectionID{1, 1, 1, 1},
+		})).To(Succeed())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 2,
+			ConnectionID:   protocol.ConnectionID{2, 2, 2, 2},
+		})).To(MatchError("CONNECTION_ID_LIMIT_ERROR"))
+	})
+
+	Context("limiting retirements", func() {
+		const maxUnackedRetirements = protocol.MaxRetiredConnectionIDsPerActiveConnectionID * protocol.MaxActiveConnectionIDs
+
+		// retireNext adds a NEW_CONNECTION_ID frame that retires the currently active connection ID
+		retireNext := func(seq uint8) error {
+			return m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(seq),
+				RetirePriorTo:       uint64(seq),
+				ConnectionID:        protocol.ConnectionID{seq, seq, seq, seq},
+				StatelessResetToken: [16]byte{seq},
+			})
+		}
+
+		It("errors when the peer makes us retire too many connection IDs without acknowledging them", func() {
+			for i := uint8(1); i <= maxUnackedRetirements; i++ {
+				Expect(retireNext(i)).To(Succeed())
+			}
+			Expect(frameQueue).To(HaveLen(maxUnackedRetirements))
+			Expect(m.unackedRetirements).To(BeEquivalentTo(maxUnackedRetirements))
+			err := retireNext(maxUnackedRetirements + 1)
+			Expect(err).To(MatchError("CONNECTION_ID_LIMIT_ERROR: too many unacknowledged RETIRE_CONNECTION_ID frames"))
+		})
+
+		It("doesn't count retirements that we initiated ourselves", func() {
+			for i := uint8(1); i <= maxUnackedRetirements+1; i++ {
+				Expect(m.Add(&wire.NewConnectionIDFrame{
+					SequenceNumber:      uint64(i),
+					ConnectionID:        protocol.ConnectionID{i, i, i, i},
+					StatelessResetToken: [16]byte{i},
+				})).To(Succeed())
+				Expect(m.Rotate()).To(Succeed())
+			}
+			Expect(frameQueue).To(HaveLen(maxUnackedRetirements + 1))
+			Expect(m.unackedRetirements).To(BeZero())
+			Expect(retireNext(maxUnackedRetirements + 2)).To(Succeed())
+			Expect(m.unackedRetirements).To(BeEquivalentTo(1))
+		})
+
+		It("allows more retirements when the RETIRE_CONNECTION_ID frames are acknowledged", func() {
+			for i := uint8(1); i <= 3*maxUnackedRetirements; i++ {
+				Expect(retireNext(i)).To(Succeed())
+				Expect(queuedFrames).To(HaveLen(1))
+				queuedFrames[0].OnAcked(queuedFrames[0].Frame)
+				queuedFrames = nil
+			}
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{3 * maxUnackedRetirements, 3 * maxUnackedRetirements, 3 * maxUnackedRetirements, 3 * maxUnackedRetirements}))
+		})
+
+		It("retransmits lost RETIRE_CONNECTION_ID frames", func() {
+			Expect(retireNext(1)).To(Succeed())
+			Expect(queuedFrames).To(HaveLen(1))
+			f := queuedFrames[0]
+			queuedFrames = nil
+			f.OnLost(f.Frame)
+			Expect(queuedFrames).To(HaveLen(1))
+			Expect(queuedFrames[0].Frame).To(Equal(&wire.RetireConnectionIDFrame{SequenceNumber: 0}))
+			// a retransmission doesn't count as a new retirement
+			Expect(m.unackedRetirements).To(BeEquivalentTo(1))
+			queuedFrames[0].OnAcked(queuedFrames[0].Frame)
+			Expect(m.unackedRetirements).To(BeZero())
+		})
+	})
+
 	It("initiates the first connection ID update as soon as possible", func() {
 		Expect(m.Get()).To(Equal(initialConnID))
 		Expect(m.Add(&wire.NewConnectionIDFrame{
@@ -225,6 +313,10 @@ var _ = Describe("Connection ID Manager", func() {
 				lastConnID = connID
 				Expect(retiredTokens).To(HaveLen(1))
 				retiredTokens = nil
+				for _, f := range queuedFrames {
+					f.OnAcked(f.Frame)
+				}
+				queuedFrames = nil
 				Expect(m.Add(&wire.NewConnectionIDFrame{
 					SequenceNumber:      uint64(s),
 					ConnectionID:        protocol.ConnectionID{s, s, s, s},
@@ -287,6 +379,36 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(retiredTokens[0]).To(Equal([16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
 	})
 
+	It("rotates the connection ID", func() {
+		for i := uint8(1); i <= 2; i++ {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(i),
+				ConnectionID:        protocol.ConnectionID{i, i, i, i},
+				StatelessResetToken: [16]byte{i, i, i, i, i, i, i, i, i, i, i, i, i, i, i, i},
+			})).To(Succeed())
+		}
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+		frameQueue = nil
+		Expect(m.Rotate()).To(Succeed())
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+		Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		Expect(retiredTokens).To(HaveLen(1))
+		Expect(retiredTokens[0]).To(Equal([16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
+		Expect(*tokenAdded).To(Equal([16]byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}))
+	})
+
+	It("errors when rotating the connection ID without unused connection IDs", func() {
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
+		})).To(Succeed())
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		frameQueue = nil
+		Expect(m.Rotate()).To(MatchError(errNoConnectionIDAvailable))
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(frameQueue).To(BeEmpty())
+	})
+
 	It("removes the currently active stateless reset token when it is closed", func() {
 		m.Close()
 		Expect(retiredTokens).To(BeEmpty())
@@ -0,0 +1,92 @@
+package quic
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// packetInfo contains the local address and the interface a packet was received on.
+type packetInfo struct {
+	addr    net.IP
+	ifIndex int
+}
+
+// An oobConn is a UDP conn that reads the destination address of received packets
+// from the IP_PKTINFO / IPV6_PKTINFO control message.
+// When sending a packet, this address can be used as the source address.
+// This is necessary for servers listening on a wildcard address on multi-homed hosts:
+// Replies need to be sent from the address that the client sent its packets to.
+type oobConn struct {
+	*net.UDPConn
+
+	v4 *ipv4.PacketConn // only set for IPv4 sockets
+	v6 *ipv6.PacketConn // only set for IPv6 and dual-stack sockets
+}
+
+// newOOBConn returns nil if conn is not a *net.UDPConn,
+// or if reading the destination address is not supported on this platform.
+func newOOBConn(conn net.PacketConn) *oobConn {
+	c, ok := conn.(*net.UDPConn)
+	if !ok {
+		return nil
+	}
+	addr, ok := c.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	if addr.IP.To4() != nil {
+		p := ipv4.NewPacketConn(c)
+		if err := p.SetControlMessage(ipv4.FlagDst|ipv4.FlagInterface, true); err != nil {
+			return nil
+		}
+		return &oobConn{UDPConn: c, v4: p}
+	}
+	p := ipv6.NewPacketConn(c)
+	if err := p.SetControlMessage(ipv6.FlagDst|ipv6.FlagInterface, true); err != nil {
+		return nil
+	}
+	return &oobConn{UDPConn: c, v6: p}
+}
+
+// ReadPacket reads a packet.
+// The packetInfo is nil if the control message didn't contain the destination address.
+func (c *oobConn) ReadPacket(b []byte) (int, net.Addr, *packetInfo, error) {
+	if c.v4 != nil {
+		n, cm, addr, err := c.v4.ReadFrom(b)
+		if err != nil || cm == nil || cm.Dst == nil {
+			return n, addr, nil, err
+		}
+		return n, addr, &packetInfo{addr: cm.Dst, ifIndex: cm.IfIndex}, nil
+	}
+	n, cm, addr, err := c.v6.ReadFrom(b)
+	if err != nil || cm == nil || cm.Dst == nil {
+		return n, addr, nil, err
+	}
+	return n, addr, &packetInfo{addr: cm.Dst, ifIndex: cm.IfIndex}, nil
+}
+
+// WritePacket writes a packet.
+// If info is set, the packet is sent from the address (and on the interface) contained in info.
+func (c *oobConn) WritePacket(b []byte, addr net.Addr, info *packetInfo) (int, error) {
+	if info == nil {
+		return c.WriteTo(b, addr)
+	}
+	if c.v4 != nil {
+		return c.v4.WriteTo(b, &ipv4.ControlMessage{Src: info.addr, IfIndex: info.ifIndex}, addr)
+	}
+	// On a dual-stack socket, IPv4 packets are reported with an IPv4-mapped IPv6 address.
+	// The kernel doesn't accept those in the IPV6_PKTINFO control message,
+	// so we need to use the IP_PKTINFO control message instead.
+	if ip4 := info.addr.To4(); ip4 != nil {
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			return c.WriteTo(b, addr)
+		}
+		oob := (&ipv4.ControlMessage{Src: ip4, IfIndex: info.ifIndex}).Marshal()
+		n, _, err := c.WriteMsgUDP(b, oob, udpAddr)
+		return n, err
+	}
+	return c.v6.WriteTo(b, &ipv6.ControlMessage{Src: info.addr, IfIndex: info.ifIndex}, addr)
+}
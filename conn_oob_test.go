@@ -0,0 +1,60 @@
+package quic
+
+import (
+	"net"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OOB Conn", func() {
+	It("doesn't use OOB data for packet conns that are not UDP conns", func() {
+		Expect(newOOBConn(newMockPacketConn())).To(BeNil())
+	})
+
+	for _, n := range []string{"udp4", "udp"} {
+		network := n
+
+		Context(network, func() {
+			BeforeEach(func() {
+				if runtime.GOOS != "linux" {
+					Skip("only Linux routes the whole 127.0.0.0/8 to the loopback interface")
+				}
+			})
+
+			It("replies from the local address that the packet was sent to", func() {
+				var laddr *net.UDPAddr
+				if network == "udp4" {
+					laddr = &net.UDPAddr{IP: net.IPv4zero}
+				}
+				udpConn, err := net.ListenUDP(network, laddr)
+				Expect(err).ToNot(HaveOccurred())
+				defer udpConn.Close()
+				c := newOOBConn(udpConn)
+				Expect(c).ToNot(BeNil())
+
+				sender, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+				Expect(err).ToNot(HaveOccurred())
+				defer sender.Close()
+				port := udpConn.LocalAddr().(*net.UDPAddr).Port
+				_, err = sender.WriteTo([]byte("foobar"), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: port})
+				Expect(err).ToNot(HaveOccurred())
+
+				b := make([]byte, 100)
+				n, addr, info, err := c.ReadPacket(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foobar")))
+				Expect(info).ToNot(BeNil())
+				Expect(info.addr.Equal(net.IPv4(127, 0, 0, 2))).To(BeTrue())
+
+				_, err = c.WritePacket([]byte("raboof"), addr, info)
+				Expect(err).ToNot(HaveOccurred())
+				n, from, err := sender.ReadFrom(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("raboof")))
+				Expect(from.(*net.UDPAddr).IP.Equal(net.IPv4(127, 0, 0, 2))).To(BeTrue())
+			})
+		})
+	}
+})
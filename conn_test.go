@@ -44,8 +44,11 @@ func (c *mockPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
 }
 
 func (c *mockPacketConn) WriteTo(b []byte, addr net.Addr) (n int, err error) {
+	// Like a real conn, don't hold on to the buffer, since it's released after it was written.
+	data := make([]byte, len(b))
+	copy(data, b)
 	select {
-	case c.dataWritten <- mockPacketConnWrite{to: addr, data: b}:
+	case c.dataWritten <- mockPacketConnWrite{to: addr, data: data}:
 		return len(b), nil
 	default:
 		panic("channel full")
@@ -90,6 +93,16 @@ var _ = Describe("Connection", func() {
 		Expect(write.data).To(Equal([]byte("foobar")))
 	})
 
+	It("writes to a different address", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7331}
+		Expect(c.WriteTo([]byte("foobar"), addr)).To(Succeed())
+		var write mockPacketConnWrite
+		Expect(packetConn.dataWritten).To(Receive(&write))
+		Expect(write.to).To(Equal(addr))
+		Expect(write.data).To(Equal([]byte("foobar")))
+		Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
+	})
+
 	It("reads", func() {
 		packetConn.dataToRead <- []byte("foo")
 		packetConn.dataReadFrom = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1336}
@@ -123,6 +136,12 @@ var _ = Describe("Connection", func() {
 		Expect(c.RemoteAddr().String()).To(Equal(addr.String()))
 	})
 
+	It("discards the packet info when changing the remote address", func() {
+		c.info = &packetInfo{addr: net.IPv4(192, 168, 0, 1)}
+		c.SetCurrentRemoteAddr(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7331})
+		Expect(c.info).To(BeNil())
+	})
+
 	It("closes", func() {
 		err := c.Close()
 		Expect(err).ToNot(HaveOccurred())
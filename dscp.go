@@ -0,0 +1,34 @@
+package quic
+
+import (
+	"errors"
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// setDSCP sets the DSCP value on a UDP socket.
+// It is written to the upper 6 bits of the IPv4 TOS field, or the IPv6 Traffic Class field.
+// On a dual-stack socket, both fields are set.
+func setDSCP(conn net.PacketConn, dscp int) error {
+	c, ok := conn.(*net.UDPConn)
+	if !ok {
+		return errors.New("quic: the DSCP can only be set on a *net.UDPConn")
+	}
+	addr, ok := c.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return errors.New("quic: the DSCP can only be set on a UDP socket")
+	}
+	tos := dscp << 2
+	if addr.IP.To4() != nil {
+		return ipv4.NewConn(c).SetTOS(tos)
+	}
+	if err := ipv6.NewConn(c).SetTrafficClass(tos); err != nil {
+		return err
+	}
+	if isDualStack(c) {
+		return ipv4.NewConn(c).SetTOS(tos)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// +build linux
+
+package quic
+
+import (
+	"context"
+	"net"
+	"syscall"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DSCP", func() {
+	// listenRecvTOS creates a UDP socket that reports the TOS field of received packets.
+	listenRecvTOS := func() *net.UDPConn {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		rawConn, err := conn.SyscallConn()
+		Expect(err).ToNot(HaveOccurred())
+		var serr error
+		Expect(rawConn.Control(func(fd uintptr) {
+			serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_RECVTOS, 1)
+		})).To(Succeed())
+		Expect(serr).ToNot(HaveOccurred())
+		return conn
+	}
+
+	readTOS := func(conn *net.UDPConn) byte {
+		b := make([]byte, 1500)
+		oob := make([]byte, 128)
+		_, oobn, _, _, err := conn.ReadMsgUDP(b, oob)
+		Expect(err).ToNot(HaveOccurred())
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		Expect(err).ToNot(HaveOccurred())
+		for _, msg := range msgs {
+			if msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == syscall.IP_TOS {
+				return msg.Data[0]
+			}
+		}
+		Fail("no TOS control message received")
+		return 0
+	}
+
+	It("sets the DSCP on the socket", func() {
+		receiver := listenRecvTOS()
+		defer receiver.Close()
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		Expect(setDSCP(conn, 46)).To(Succeed())
+		_, err = conn.WriteTo([]byte("foobar"), receiver.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readTOS(receiver) >> 2).To(BeEquivalentTo(46))
+	})
+
+	It("marks packets sent by the client", func() {
+		receiver := listenRecvTOS()
+		defer receiver.Close()
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := DialContext(ctx, conn, receiver.LocalAddr(), "localhost", testdata.GetTLSConfig(), &Config{DSCP: 10})
+			Expect(err).To(MatchError(context.Canceled))
+		}()
+		Expect(readTOS(receiver) >> 2).To(BeEquivalentTo(10))
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("errors when setting the DSCP on a conn that is not a *net.UDPConn", func() {
+		Expect(setDSCP(newMockPacketConn(), 46)).To(MatchError("quic: the DSCP can only be set on a *net.UDPConn"))
+	})
+})
@@ -0,0 +1,31 @@
+package quic
+
+import (
+	"net"
+	"syscall"
+)
+
+// isDualStack says if conn is an IPv6 socket that also accepts IPv4 packets.
+// It returns false if this can't be determined.
+func isDualStack(conn net.PacketConn) bool {
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok || addr.IP.To4() != nil {
+		return false
+	}
+	c, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var v6only bool
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		v6only, serr = isV6Only(fd)
+	}); err != nil || serr != nil {
+		return false
+	}
+	return !v6only
+}
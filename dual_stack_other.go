@@ -0,0 +1,9 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package quic
+
+import "errors"
+
+func isV6Only(uintptr) (bool, error) {
+	return false, errors.New("reading IPV6_V6ONLY not supported on this platform")
+}
@@ -0,0 +1,10 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package quic
+
+import "syscall"
+
+func isV6Only(fd uintptr) (bool, error) {
+	v, err := syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY)
+	return v != 0, err
+}
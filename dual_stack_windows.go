@@ -0,0 +1,13 @@
+package quic
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func isV6Only(fd uintptr) (bool, error) {
+	var v int32
+	l := int32(unsafe.Sizeof(v))
+	err := syscall.Getsockopt(syscall.Handle(fd), syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY, (*byte)(unsafe.Pointer(&v)), &l)
+	return v != 0, err
+}
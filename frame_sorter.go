@@ -12,6 +12,9 @@ type frameSorterEntry struct {
 	DoneCb func()
 }
 
+// The frameSorter buffers data received out of order.
+// The amount of buffered data is bounded by the flow control window, since the receiveStream
+// rejects frames exceeding it before they are pushed to the frameSorter.
 type frameSorter struct {
 	queue   map[protocol.ByteCount]frameSorterEntry
 	readPos protocol.ByteCount
@@ -174,6 +177,11 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 	return offset, entry.Data, entry.DoneCb
 }
 
+// ContiguousEnd returns the offset up to which all data was received.
+func (s *frameSorter) ContiguousEnd() protocol.ByteCount {
+	return s.gaps.Front().Value.Start
+}
+
 // HasMoreData says if there is any more data queued at *any* offset.
 func (s *frameSorter) HasMoreData() bool {
 	return len(s.queue) > 0
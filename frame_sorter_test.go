@@ -93,6 +93,16 @@ var _ = Describe("frame sorter", func() {
 			Expect(s.HasMoreData()).To(BeFalse())
 		})
 
+		It("says up to which offset all data was received", func() {
+			Expect(s.ContiguousEnd()).To(BeZero())
+			Expect(s.Push([]byte("bar"), 3, nil)).To(Succeed())
+			Expect(s.ContiguousEnd()).To(BeZero())
+			Expect(s.Push([]byte("foo"), 0, nil)).To(Succeed())
+			Expect(s.ContiguousEnd()).To(BeEquivalentTo(6))
+			s.Pop()
+			Expect(s.ContiguousEnd()).To(BeEquivalentTo(6))
+		})
+
 		Context("Gap handling", func() {
 			It("finds the first gap", func() {
 				Expect(s.Push([]byte("foobar"), 10, nil)).To(Succeed())
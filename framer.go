@@ -11,6 +11,9 @@ import (
 
 type framer interface {
 	QueueControlFrame(wire.Frame)
+	// QueueControlFrameWithCallbacks queues a control frame.
+	// The callbacks are called when the frame is acknowledged or lost.
+	QueueControlFrameWithCallbacks(ackhandler.Frame)
 	AppendControlFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
 
 	AddActiveStream(protocol.StreamID)
@@ -27,7 +30,7 @@ type framerI struct {
 	streamQueue   []protocol.StreamID
 
 	controlFrameMutex sync.Mutex
-	controlFrames     []wire.Frame
+	controlFrames     []ackhandler.Frame
 }
 
 var _ framer = &framerI{}
@@ -44,6 +47,10 @@ func newFramer(
 }
 
 func (f *framerI) QueueControlFrame(frame wire.Frame) {
+	f.QueueControlFrameWithCallbacks(ackhandler.Frame{Frame: frame})
+}
+
+func (f *framerI) QueueControlFrameWithCallbacks(frame ackhandler.Frame) {
 	f.controlFrameMutex.Lock()
 	f.controlFrames = append(f.controlFrames, frame)
 	f.controlFrameMutex.Unlock()
@@ -58,7 +65,7 @@ func (f *framerI) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol
 		if length+frameLen > maxLen {
 			break
 		}
-		frames = append(frames, ackhandler.Frame{Frame: frame})
+		frames = append(frames, frame)
 		length += frameLen
 		f.controlFrames = f.controlFrames[:len(f.controlFrames)-1]
 	}
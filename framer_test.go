@@ -50,6 +50,22 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(Equal(mdf.Length(version) + msf.Length(version)))
 		})
 
+		It("adds control frames with callbacks", func() {
+			var acked bool
+			ping := &wire.PingFrame{}
+			framer.QueueControlFrameWithCallbacks(ackhandler.Frame{
+				Frame:   ping,
+				OnAcked: func(wire.Frame) { acked = true },
+			})
+			frames, length := framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(ping))
+			Expect(frames[0].OnLost).To(BeNil())
+			Expect(length).To(Equal(ping.Length(version)))
+			frames[0].OnAcked(ping)
+			Expect(acked).To(BeTrue())
+		})
+
 		It("appends to the slice given", func() {
 			ping := &wire.PingFrame{}
 			mdf := &wire.MaxDataFrame{ByteOffset: 0x42}
@@ -36,7 +36,7 @@ func Fuzz(data []byte) int {
 	var frames []wire.Frame
 
 	for r.Len() > 0 {
-		f, err := parser.ParseNext(r, encLevel)
+		_, f, err := parser.ParseNext(r, encLevel)
 		if err != nil {
 			break
 		}
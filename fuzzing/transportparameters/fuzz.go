@@ -0,0 +1,37 @@
+// +build gofuzz
+
+package transportparameters
+
+import (
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// versions are the versions that use different transport parameter encodings
+var versions = []protocol.VersionNumber{protocol.VersionTLS, protocol.Version2}
+
+func Fuzz(data []byte) int {
+	if len(data) < 1 {
+		return 0
+	}
+	version := versions[int(data[0])%len(versions)]
+	sentBy := protocol.PerspectiveClient
+	if data[0]&0x80 > 0 {
+		sentBy = protocol.PerspectiveServer
+	}
+	data = data[1:]
+
+	tp := &handshake.TransportParameters{}
+	if err := tp.Unmarshal(data, sentBy, version); err != nil {
+		return 0
+	}
+	_ = tp.String()
+
+	tp2 := &handshake.TransportParameters{}
+	if err := tp2.Unmarshal(tp.Marshal(version), sentBy, version); err != nil {
+		panic(fmt.Sprintf("failed to unmarshal marshaled transport parameters (%s): %s", version, err))
+	}
+	return 1
+}
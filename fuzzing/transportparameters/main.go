@@ -0,0 +1,95 @@
+// +build !gofuzz
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// versions are the versions that use different transport parameter encodings
+var versions = []protocol.VersionNumber{protocol.VersionTLS, protocol.Version2}
+
+func getRandomData(l int) []byte {
+	b := make([]byte, l)
+	rand.Read(b)
+	return b
+}
+
+func getRandomValue() uint64 {
+	maxVals := []int64{1 << 6, 1 << 14, 1 << 30, 1 << 62}
+	return uint64(rand.Int63n(maxVals[rand.Intn(4)]))
+}
+
+func main() {
+	rand.Seed(1337)
+
+	for i := 0; i < 30; i++ {
+		versionIndex := rand.Intn(len(versions))
+		version := versions[versionIndex]
+		tp := &handshake.TransportParameters{
+			InitialMaxStreamDataBidiLocal:  protocol.ByteCount(getRandomValue()),
+			InitialMaxStreamDataBidiRemote: protocol.ByteCount(getRandomValue()),
+			InitialMaxStreamDataUni:        protocol.ByteCount(getRandomValue()),
+			InitialMaxData:                 protocol.ByteCount(getRandomValue()),
+			MaxAckDelay:                    time.Duration(getRandomValue()) % (16 * time.Second),
+			AckDelayExponent:               uint8(getRandomValue() % 21),
+			DisableActiveMigration:         getRandomValue()%2 == 0,
+			MaxPacketSize:                  protocol.ByteCount(1200 + getRandomValue()%(1<<14)),
+			MaxUniStreamNum:                protocol.StreamNum(getRandomValue()),
+			MaxBidiStreamNum:               protocol.StreamNum(getRandomValue()),
+			MaxIdleTimeout:                 time.Duration(getRandomValue()) % time.Hour,
+			ActiveConnectionIDLimit:        getRandomValue(),
+			InitialSourceConnectionID:      getRandomData(rand.Intn(21)),
+		}
+		sentBy := protocol.PerspectiveClient
+		if rand.Intn(2) == 0 {
+			sentBy = protocol.PerspectiveServer
+			var token [16]byte
+			copy(token[:], getRandomData(16))
+			tp.StatelessResetToken = &token
+			tp.OriginalConnectionID = getRandomData(8 + rand.Intn(13))
+			if rand.Intn(2) == 0 {
+				connID := protocol.ConnectionID(getRandomData(rand.Intn(21)))
+				tp.RetrySourceConnectionID = &connID
+			}
+			if rand.Intn(2) == 0 {
+				var resetToken [16]byte
+				copy(resetToken[:], getRandomData(16))
+				tp.PreferredAddress = &handshake.PreferredAddress{
+					IPv4:                net.IP(getRandomData(4)),
+					IPv4Port:            uint16(rand.Int()),
+					IPv6:                net.IP(getRandomData(16)),
+					IPv6Port:            uint16(rand.Int()),
+					ConnectionID:        getRandomData(rand.Intn(21)),
+					StatelessResetToken: resetToken,
+				}
+			}
+		}
+		prefix := byte(versionIndex)
+		if sentBy == protocol.PerspectiveServer {
+			prefix |= 0x80
+		}
+		data := append([]byte{prefix}, tp.Marshal(version)...)
+		if err := writeCorpusFile(fmt.Sprintf("tp%d", i), data); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func writeCorpusFile(name string, data []byte) error {
+	file, err := os.Create("corpus/" + name)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Close()
+}
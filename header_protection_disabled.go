@@ -0,0 +1,10 @@
+// +build quic_noheaderprotection
+
+package quic
+
+// headerProtectionEnabled says if header protection is applied to sent packets and removed from received packets.
+// With the quic_noheaderprotection build tag, packets are sent and received without header protection,
+// so that packet numbers can be read on the wire.
+// Both endpoints need to be built with this tag to be able to communicate.
+// It should only be used for debugging, and must never be used in production.
+const headerProtectionEnabled = false
@@ -0,0 +1,25 @@
+// +build quic_noheaderprotection
+
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/mocks"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Header Protection, disabled", func() {
+	It("disables header protection in the packer", func() {
+		packer := newPacketPacker(nil, nil, nil, nil, nil, nil, &net.UDPAddr{}, 0, nil, nil, nil, protocol.PerspectiveClient, protocol.VersionTLS)
+		Expect(packer.disableHeaderProtection).To(BeTrue())
+	})
+
+	It("disables header protection in the unpacker", func() {
+		unpacker := newPacketUnpacker(mocks.NewMockCryptoSetup(mockCtrl), protocol.VersionTLS).(*packetUnpacker)
+		Expect(unpacker.disableHeaderProtection).To(BeTrue())
+	})
+})
@@ -0,0 +1,7 @@
+// +build !quic_noheaderprotection
+
+package quic
+
+// headerProtectionEnabled says if header protection is applied to sent packets and removed from received packets.
+// Use the quic_noheaderprotection build tag to disable it.
+const headerProtectionEnabled = true
@@ -2,6 +2,7 @@ package self_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -420,6 +421,51 @@ var _ = Describe("Stream Cancelations", func() {
 		})
 	})
 
+	Context("rejecting streams", func() {
+		It("sends the error code to the peer in both directions", func() {
+			server, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				sess, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := sess.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str.RejectStream(1337)
+			}()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				nil,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			// the RESET_STREAM frame cancels the read side
+			_, err = str.Read([]byte{0})
+			Expect(err).To(HaveOccurred())
+			var streamErr quic.StreamError
+			Expect(errors.As(err, &streamErr)).To(BeTrue())
+			Expect(streamErr.Canceled()).To(BeTrue())
+			Expect(streamErr.ErrorCode()).To(BeEquivalentTo(1337))
+			// the STOP_SENDING frame cancels the write side
+			Eventually(func() error {
+				_, err := str.Write([]byte("foobar"))
+				return err
+			}).Should(HaveOccurred())
+			_, err = str.Write([]byte("foobar"))
+			Expect(errors.As(err, &streamErr)).To(BeTrue())
+			Expect(streamErr.Canceled()).To(BeTrue())
+			Expect(streamErr.ErrorCode()).To(BeEquivalentTo(1337))
+		})
+	})
+
 	Context("canceling the context", func() {
 		It("downloads data when the receiving peer cancels the context for accepting streams", func() {
 			server, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
@@ -9,6 +9,7 @@ import (
 
 	quic "github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -52,6 +53,8 @@ var _ = Describe("Connection ID lengths tests", func() {
 		)
 		Expect(err).ToNot(HaveOccurred())
 		defer cl.CloseWithError(0, "")
+		Expect(cl.LocalConnectionID().Len()).To(Equal(conf.ConnectionIDLength))
+		Expect(cl.RemoteConnectionID().Len()).ToNot(BeZero())
 		str, err := cl.AcceptStream(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		data, err := ioutil.ReadAll(str)
@@ -59,6 +62,43 @@ var _ = Describe("Connection ID lengths tests", func() {
 		Expect(data).To(Equal(PRData))
 	}
 
+	It("rotates the connection ID", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		serverSessChan := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverSessChan <- sess
+		}()
+
+		cl, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer cl.CloseWithError(0, "")
+		var serverSess quic.Session
+		Eventually(serverSessChan).Should(Receive(&serverSess))
+
+		// wait until the server provided connection IDs
+		Eventually(cl.RotateConnectionID).Should(Succeed())
+		_, err = cl.SendPing(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		oldConnID := cl.RemoteConnectionID()
+		Eventually(serverSess.LocalConnectionID).Should(Equal(oldConnID))
+
+		Expect(cl.RotateConnectionID()).To(Succeed())
+		_, err = cl.SendPing(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		newConnID := cl.RemoteConnectionID()
+		Expect(newConnID).ToNot(Equal(oldConnID))
+		Eventually(serverSess.LocalConnectionID).Should(Equal(newConnID))
+	})
+
 	It("downloads a file using a 0-byte connection ID for the client", func() {
 		serverConf := &quic.Config{
 			ConnectionIDLength: randomConnIDLen(),
@@ -87,4 +127,52 @@ var _ = Describe("Connection ID lengths tests", func() {
 		defer ln.Close()
 		runClient(ln.Addr(), clientConf)
 	})
+
+	It("downloads a file when both client and server use zero-length connection IDs", func() {
+		conf := &quic.Config{
+			ZeroLengthConnectionIDs: true,
+			Versions:                []protocol.VersionNumber{protocol.VersionTLS},
+		}
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		serverSessChan := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			serverSessChan <- sess
+		}()
+
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		cl, err := quic.Dial(udpConn, ln.Addr(), "localhost", getTLSClientConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer cl.CloseWithError(0, "")
+		Expect(cl.LocalConnectionID().Len()).To(BeZero())
+		Expect(cl.RemoteConnectionID().Len()).To(BeZero())
+		str, err := cl.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		var serverSess quic.Session
+		Eventually(serverSessChan).Should(Receive(&serverSess))
+		Expect(serverSess.LocalConnectionID().Len()).To(BeZero())
+
+		// The server can't route packets for a second connection.
+		_, err = quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			&quic.Config{Versions: []protocol.VersionNumber{protocol.VersionTLS}},
+		)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ConnectionRefused))
+	})
 })
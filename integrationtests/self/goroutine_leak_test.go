@@ -0,0 +1,122 @@
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"runtime/pprof"
+	"strings"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// quicGoroutines returns the stack traces of all goroutines that are currently running quic-go code,
+// indexed by the goroutine header (e.g. "goroutine 42 [select]:").
+func quicGoroutines() map[string]string {
+	var b bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&b, 2)
+	goroutines := make(map[string]string)
+	for _, g := range strings.Split(b.String(), "\n\n") {
+		if !strings.Contains(g, "github.com/lucas-clemente/quic-go.") &&
+			!strings.Contains(g, "github.com/lucas-clemente/quic-go/internal/") {
+			continue
+		}
+		header := strings.SplitN(g, "\n", 2)[0]
+		// strip the state, it might change while the goroutine is running
+		if i := strings.Index(header, " ["); i >= 0 {
+			header = header[:i]
+		}
+		goroutines[header] = g
+	}
+	return goroutines
+}
+
+// leakedGoroutines returns the stack traces of all goroutines running quic-go code that were not running before.
+func leakedGoroutines(before map[string]string) []string {
+	var leaked []string
+	for header, stack := range quicGoroutines() {
+		if _, ok := before[header]; !ok {
+			leaked = append(leaked, stack)
+		}
+	}
+	return leaked
+}
+
+var _ = Describe("Goroutine leaks", func() {
+	for _, v := range protocol.SupportedVersions {
+		version := v
+
+		Context(fmt.Sprintf("with QUIC version %s", version), func() {
+			var before map[string]string
+
+			BeforeEach(func() {
+				before = quicGoroutines()
+			})
+
+			runServer := func() (quic.Listener, <-chan quic.Session) {
+				ln, err := quic.ListenAddr(
+					"localhost:0",
+					getTLSConfig(),
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				sessChan := make(chan quic.Session, 1)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					if err != nil {
+						return
+					}
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					_, err = ioutil.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					sessChan <- sess
+				}()
+				return ln, sessChan
+			}
+
+			dial := func(ln quic.Listener) quic.Session {
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				str, err := sess.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				return sess
+			}
+
+			It("doesn't leak goroutines after the session is closed", func() {
+				ln, sessChan := runServer()
+				defer ln.Close()
+				sess := dial(ln)
+				var serverSess quic.Session
+				Eventually(sessChan).Should(Receive(&serverSess))
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+				Expect(serverSess.CloseWithError(0, "")).To(Succeed())
+				Expect(ln.Close()).To(Succeed())
+				Eventually(func() []string { return leakedGoroutines(before) }).Should(BeEmpty())
+			})
+
+			It("doesn't leak goroutines after the listener is closed", func() {
+				ln, sessChan := runServer()
+				sess := dial(ln)
+				Eventually(sessChan).Should(Receive())
+				Expect(ln.Close()).To(Succeed())
+				Eventually(sess.Context().Done()).Should(BeClosed())
+				Eventually(func() []string { return leakedGoroutines(before) }).Should(BeEmpty())
+			})
+		})
+	}
+})
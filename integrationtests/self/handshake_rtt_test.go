@@ -114,6 +114,28 @@ var _ = Describe("Handshake RTT tests", func() {
 		expectDurationInRTTs(2)
 	})
 
+	It("records the handshake timing", func() {
+		runServerAndProxy()
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalAddr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			clientConfig,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		timing := sess.HandshakeTiming()
+		Expect(timing.StartTime).To(BeTemporally(">=", testStartedAt))
+		Expect(timing.FirstInitialSentTime).To(BeTemporally(">=", timing.StartTime))
+		// the server sends a Retry in response to the first Initial
+		Expect(timing.RetryReceivedTime.Sub(timing.FirstInitialSentTime)).To(BeNumerically(">=", rtt))
+		Expect(timing.CompleteTime).To(BeTemporally(">", timing.RetryReceivedTime))
+		rtts := float32(timing.HandshakeDuration()) / float32(rtt)
+		Expect(rtts).To(SatisfyAll(
+			BeNumerically(">=", 2),
+			BeNumerically("<", 3),
+		))
+	})
+
 	It("establishes a connection in 1 RTT when the server doesn't require a token", func() {
 		serverConfig.AcceptToken = func(_ net.Addr, _ *quic.Token) bool {
 			return true
@@ -1,8 +1,11 @@
 package self_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -10,8 +13,12 @@ import (
 
 	quic "github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/integrationtests/tools/israce"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/wire"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -133,9 +140,131 @@ var _ = Describe("Handshake tests", func() {
 				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.SupportedVersions[0]))
 				Expect(sess.CloseWithError(0, "")).To(Succeed())
 			})
+
+			It("returns a VersionNegotiationError when the server doesn't support any of the client's versions", func() {
+				serverConfig.Versions = []protocol.VersionNumber{7, 8}
+				server := runServer()
+				defer server.Close()
+				conf := &quic.Config{Versions: []protocol.VersionNumber{supportedVersions[0]}}
+				_, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					conf,
+				)
+				Expect(err).To(HaveOccurred())
+				var vnErr *quic.VersionNegotiationError
+				Expect(errors.As(err, &vnErr)).To(BeTrue())
+				Expect(vnErr.Ours).To(Equal([]protocol.VersionNumber{supportedVersions[0]}))
+				Expect(vnErr.Theirs).To(ContainElement(protocol.VersionNumber(7)))
+				Expect(vnErr.Theirs).To(ContainElement(protocol.VersionNumber(8)))
+				Expect(vnErr.Theirs).ToNot(ContainElement(supportedVersions[0]))
+			})
 		})
 	}
 
+	Context("using QUIC v2", func() {
+		// openInitial removes header protection from an Initial packet sent by the client, and decrypts it,
+		// using the Initial keys of the given QUIC version.
+		openInitial := func(data []byte, v protocol.VersionNumber) error {
+			data = append([]byte{}, data...) // header protection is removed in place
+			hdr, packetData, _, err := wire.ParsePacket(data, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.Type).To(Equal(protocol.PacketTypeInitial))
+			_, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, v)
+			hdrLen := hdr.ParsedLen()
+			opener.DecryptHeader(packetData[hdrLen+4:hdrLen+4+16], &packetData[0], packetData[hdrLen:hdrLen+4])
+			extHdr, err := hdr.ParseExtended(bytes.NewReader(packetData), v)
+			if err != nil {
+				return err
+			}
+			extHdrLen := extHdr.ParsedLen()
+			_, err = opener.Open(nil, packetData[extHdrLen:], extHdr.PacketNumber, packetData[:extHdrLen])
+			return err
+		}
+
+		It("performs a handshake", func() {
+			serverConfig.Versions = []protocol.VersionNumber{protocol.Version2}
+			server := runServer()
+			defer server.Close()
+
+			firstPacket := make(chan []byte, 1)
+			proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+				RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				DropPacket: func(dir quicproxy.Direction, packet []byte) bool {
+					if dir == quicproxy.DirectionIncoming {
+						select {
+						case firstPacket <- append([]byte{}, packet...):
+						default:
+						}
+					}
+					return false
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer proxy.Close()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+				getTLSClientConfig(),
+				&quic.Config{Versions: []protocol.VersionNumber{protocol.Version2}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.(versioner).GetVersion()).To(Equal(protocol.Version2))
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+
+			var data []byte
+			Eventually(firstPacket).Should(Receive(&data))
+			// check the version field and the v2 packet type of the Initial
+			Expect(data[0] & 0x30 >> 4).To(BeEquivalentTo(0x1))
+			Expect(protocol.VersionNumber(binary.BigEndian.Uint32(data[1:5]))).To(Equal(protocol.Version2))
+			// check that the v2 salt and labels were used
+			Expect(openInitial(data, protocol.Version2)).To(Succeed())
+			Expect(openInitial(data, protocol.VersionTLS)).ToNot(Succeed())
+			Expect(sess.CloseWithError(0, "")).To(Succeed())
+		})
+
+		It("performs a handshake with a Retry", func() {
+			serverConfig.Versions = []protocol.VersionNumber{protocol.Version2}
+			usedRetryToken := make(chan struct{})
+			serverConfig.AcceptToken = func(_ net.Addr, token *quic.Token) bool {
+				if token == nil || !token.IsRetryToken {
+					return false
+				}
+				close(usedRetryToken)
+				return true
+			}
+			server := runServer()
+			defer server.Close()
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				&quic.Config{Versions: []protocol.VersionNumber{protocol.Version2}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(usedRetryToken).To(BeClosed())
+			Expect(sess.(versioner).GetVersion()).To(Equal(protocol.Version2))
+			Expect(sess.CloseWithError(0, "")).To(Succeed())
+		})
+
+		It("negotiates QUIC v2 using Version Negotiation", func() {
+			serverConfig.Versions = []protocol.VersionNumber{protocol.Version2}
+			server := runServer()
+			defer server.Close()
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				&quic.Config{Versions: []protocol.VersionNumber{protocol.VersionTLS, protocol.Version2}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.(versioner).GetVersion()).To(Equal(protocol.Version2))
+			Expect(sess.CloseWithError(0, "")).To(Succeed())
+		})
+	})
+
 	Context("using different cipher suites", func() {
 		for n, id := range map[string]uint16{
 			"TLS_AES_128_GCM_SHA256":       tls.TLS_AES_128_GCM_SHA256,
@@ -236,6 +365,41 @@ var _ = Describe("Handshake tests", func() {
 					Expect(err).To(MatchError("CRYPTO_ERROR: tls: bad certificate"))
 				})
 
+				It("makes the client certificate available to the server", func() {
+					tlsConf := getTLSConfig()
+					tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+					tlsConf.ClientCAs = testdata.GetRootCA()
+					ln, err := quic.ListenAddr("localhost:0", tlsConf, serverConfig)
+					Expect(err).ToNot(HaveOccurred())
+					defer ln.Close()
+
+					serverSessChan := make(chan quic.Session, 1)
+					go func() {
+						defer GinkgoRecover()
+						sess, err := ln.Accept(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						serverSessChan <- sess
+					}()
+
+					clientTLSConf := getTLSClientConfig()
+					clientTLSConf.Certificates = getTLSConfig().Certificates
+					sess, err := quic.DialAddr(
+						fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+						clientTLSConf,
+						clientConfig,
+					)
+					Expect(err).ToNot(HaveOccurred())
+					defer sess.CloseWithError(0, "")
+					Expect(sess.ConnectionState().PeerCertificates).ToNot(BeEmpty())
+
+					var serverSess quic.Session
+					Eventually(serverSessChan).Should(Receive(&serverSess))
+					state := serverSess.ConnectionState()
+					Expect(state.PeerCertificates).To(HaveLen(1))
+					Expect(state.PeerCertificates[0].Raw).To(Equal(clientTLSConf.Certificates[0].Certificate[0]))
+					Expect(state.VerifiedChains).ToNot(BeEmpty())
+				})
+
 				It("uses the ServerName in the tls.Config", func() {
 					tlsConf := getTLSClientConfig()
 					tlsConf.ServerName = "localhost"
@@ -246,6 +410,25 @@ var _ = Describe("Handshake tests", func() {
 					)
 					Expect(err).ToNot(HaveOccurred())
 				})
+
+				It("doesn't send SNI, if disabled", func() {
+					serverNames := make(chan string, 1)
+					tlsServerConf.GetConfigForClient = func(ch *tls.ClientHelloInfo) (*tls.Config, error) {
+						serverNames <- ch.ServerName
+						return nil, nil
+					}
+					tlsConf := getTLSClientConfig()
+					tlsConf.InsecureSkipVerify = true
+					clientConfig.DisableSNI = true
+					_, err := quic.DialAddr(
+						fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+						tlsConf,
+						clientConfig,
+					)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(tlsConf.ServerName).To(BeEmpty())
+					Eventually(serverNames).Should(Receive(BeEmpty()))
+				})
 			})
 		}
 	})
@@ -303,7 +486,7 @@ var _ = Describe("Handshake tests", func() {
 
 			_, err := dial()
 			Expect(err).To(HaveOccurred())
-			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ServerBusy))
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ConnectionRefused))
 
 			// now accept one session, freeing one spot in the queue
 			_, err = server.Accept(context.Background())
@@ -316,7 +499,7 @@ var _ = Describe("Handshake tests", func() {
 
 			_, err = dial()
 			Expect(err).To(HaveOccurred())
-			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ServerBusy))
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ConnectionRefused))
 		})
 
 		It("removes closed connections from the accept queue", func() {
@@ -332,7 +515,7 @@ var _ = Describe("Handshake tests", func() {
 
 			_, err = dial()
 			Expect(err).To(HaveOccurred())
-			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ServerBusy))
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ConnectionRefused))
 
 			// Now close the one of the session that are waiting to be accepted.
 			// This should free one spot in the queue.
@@ -346,11 +529,56 @@ var _ = Describe("Handshake tests", func() {
 
 			_, err = dial()
 			Expect(err).To(HaveOccurred())
-			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ServerBusy))
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ConnectionRefused))
 		})
 
 	})
 
+	Context("limiting the number of active sessions", func() {
+		It("rejects new connection attempts once the maximum number of sessions is reached", func() {
+			serverConfig.MaxActiveSessions = 2
+			server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+			go func() {
+				defer GinkgoRecover()
+				for {
+					if _, err := server.Accept(context.Background()); err != nil {
+						return
+					}
+				}
+			}()
+
+			dial := func() (quic.Session, error) {
+				return quic.DialAddr(
+					fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					nil,
+				)
+			}
+
+			firstSess, err := dial()
+			Expect(err).ToNot(HaveOccurred())
+			sess, err := dial()
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+			Eventually(func() int { return server.Stats().ActiveSessions }).Should(Equal(2))
+
+			_, err = dial()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ConnectionRefused))
+			Expect(server.Stats().SessionsRefused).To(BeEquivalentTo(1))
+
+			// closing a session frees a spot
+			Expect(firstSess.CloseWithError(0, "")).To(Succeed())
+			Eventually(func() int { return server.Stats().ActiveSessions }).Should(Equal(1))
+			sess, err = dial()
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+			Eventually(func() int { return server.Stats().ActiveSessions }).Should(Equal(2))
+		})
+	})
+
 	Context("ALPN", func() {
 		It("negotiates an application protocol", func() {
 			ln, err := quic.ListenAddr("localhost:0", tlsServerConf, serverConfig)
@@ -381,6 +609,31 @@ var _ = Describe("Handshake tests", func() {
 			Expect(ln.Close()).To(Succeed())
 		})
 
+		It("doesn't accept a session if application protocol negotiation fails", func() {
+			tlsServerConf.NextProtos = []string{"foo", "bar"}
+			ln, err := quic.ListenAddr("localhost:0", tlsServerConf, serverConfig)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			accepted := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				if _, err := ln.Accept(context.Background()); err == nil {
+					close(accepted)
+				}
+			}()
+
+			tlsConf := getTLSClientConfig()
+			tlsConf.NextProtos = []string{"baz"}
+			_, err = quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				tlsConf,
+				nil,
+			)
+			Expect(errors.Is(err, quic.NoApplicationProtocol)).To(BeTrue())
+			Consistently(accepted).ShouldNot(BeClosed())
+		})
+
 		It("errors if application protocol negotiation fails", func() {
 			server := runServer()
 
@@ -394,10 +647,81 @@ var _ = Describe("Handshake tests", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("CRYPTO_ERROR"))
 			Expect(err.Error()).To(ContainSubstring("no application protocol"))
+			var qErr *quic.Error
+			Expect(errors.As(err, &qErr)).To(BeTrue())
+			Expect(qErr.IsCryptoError()).To(BeTrue())
+			Expect(errors.Is(err, quic.NoApplicationProtocol)).To(BeTrue())
 			Expect(server.Close()).To(Succeed())
 		})
 	})
 
+	Context("disabling active migration", func() {
+		for _, e := range []bool{true, false} {
+			enable := e
+
+			It(fmt.Sprintf("sends the disable_active_migration transport parameter, unless active migration is enabled: %t", enable), func() {
+				serverConfig.EnableActiveMigration = enable
+				ln, err := quic.ListenAddr("localhost:0", tlsServerConf, serverConfig)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					params, err := sess.RemoteTransportParameters()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(params.DisableActiveMigration).To(Equal(!enable))
+					close(done)
+				}()
+
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					&quic.Config{EnableActiveMigration: enable},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				params, err := sess.RemoteTransportParameters()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(params.DisableActiveMigration).To(Equal(!enable))
+				Eventually(done).Should(BeClosed())
+			})
+		}
+	})
+
+	It("sends transport parameters modified by the TransportParametersHook", func() {
+		ln, err := quic.ListenAddr("localhost:0", tlsServerConf, serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			params, err := sess.RemoteTransportParameters()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(params.MaxIdleTimeout).To(Equal(1337 * time.Second))
+			close(done)
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			&quic.Config{
+				TransportParametersHook: func(p *quic.TransportParameters) {
+					Expect(p.MaxIdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
+					p.MaxIdleTimeout = 1337 * time.Second
+				},
+			},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		Eventually(done).Should(BeClosed())
+	})
+
 	Context("using tokens", func() {
 		It("uses tokens provided in NEW_TOKEN frames", func() {
 			tokenChan := make(chan *quic.Token, 100)
@@ -0,0 +1,125 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/integrationtests/tools/interceptor"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Interceptor Tests", func() {
+	It("downloads a file when packets are dropped, duplicated and reordered", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		var counter, numDropped int32
+		conn := interceptor.NewPacketConn(udpConn, &interceptor.Opts{
+			// Drop every 10th packet, starting after the handshake.
+			DropPacket: func(interceptor.Direction, []byte) bool {
+				if c := atomic.AddInt32(&counter, 1); c > 10 && c%10 == 0 {
+					atomic.AddInt32(&numDropped, 1)
+					return true
+				}
+				return false
+			},
+			DelayPacket: func(interceptor.Direction, []byte) time.Duration {
+				return time.Duration(rand.Int63n(int64(5 * time.Millisecond)))
+			},
+			DuplicatePacket: func(interceptor.Direction, []byte) int {
+				if rand.Intn(20) == 0 {
+					return 1
+				}
+				return 0
+			},
+		})
+		defer conn.Close()
+
+		sess, err := quic.Dial(conn, ln.Addr(), "localhost", getTLSClientConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		Expect(atomic.LoadInt32(&numDropped)).ToNot(BeZero())
+	})
+
+	It("counts retransmitted bytes when packets are dropped", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		var counter, numDropped int32
+		conn := interceptor.NewPacketConn(udpConn, &interceptor.Opts{
+			// Drop every 10th packet sent by the server, starting after the handshake.
+			DropPacket: func(dir interceptor.Direction, _ []byte) bool {
+				if dir != interceptor.DirectionSend {
+					return false
+				}
+				if c := atomic.AddInt32(&counter, 1); c > 10 && c%10 == 0 {
+					atomic.AddInt32(&numDropped, 1)
+					return true
+				}
+				return false
+			},
+		})
+		defer conn.Close()
+		ln, err := quic.Listen(conn, getTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		strChan := make(chan quic.SendStream, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			strChan <- str
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		Expect(atomic.LoadInt32(&numDropped)).ToNot(BeZero())
+
+		var serverStr quic.SendStream
+		Eventually(strChan).Should(Receive(&serverStr))
+		stats := serverStr.SendStats()
+		Expect(stats.BytesSent).To(BeEquivalentTo(len(PRData)))
+		Expect(stats.BytesRetransmitted).ToNot(BeZero())
+	})
+})
@@ -3,11 +3,14 @@ package self_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
 
 	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quictrace"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -54,3 +57,86 @@ var _ = Describe("Key Update tests", func() {
 		Expect(data).To(Equal(PRDataLong))
 	})
 })
+
+type keyUpdate struct {
+	phase  protocol.KeyPhase
+	remote bool
+}
+
+type keyUpdateTracer struct {
+	quictrace.Tracer
+	updates chan keyUpdate
+}
+
+var _ quictrace.KeyUpdateTracer = &keyUpdateTracer{}
+
+func newKeyUpdateTracer() *keyUpdateTracer {
+	return &keyUpdateTracer{
+		Tracer:  quictrace.NewTracer(),
+		updates: make(chan keyUpdate, 10),
+	}
+}
+
+func (t *keyUpdateTracer) UpdatedKey(_ protocol.ConnectionID, phase protocol.KeyPhase, remote bool) {
+	t.updates <- keyUpdate{phase: phase, remote: remote}
+}
+
+var _ = Describe("Initiating Key Updates", func() {
+	It("updates the keys on request, and continues transferring data", func() {
+		serverTracer := newKeyUpdateTracer()
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), &quic.Config{QuicTracer: serverTracer})
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			for {
+				str, err := sess.AcceptStream(context.Background())
+				if err != nil {
+					return
+				}
+				go func() {
+					defer GinkgoRecover()
+					_, err := io.Copy(str, str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+			}
+		}()
+
+		clientTracer := newKeyUpdateTracer()
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			&quic.Config{QuicTracer: clientTracer},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+
+		echo := func() {
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			go func() {
+				defer GinkgoRecover()
+				_, err := str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			}()
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+		}
+
+		echo()
+		Expect(clientTracer.updates).ToNot(Receive())
+		// The client confirms the handshake when it receives the HANDSHAKE_DONE frame.
+		Eventually(sess.InitiateKeyUpdate).Should(Succeed())
+		echo()
+		Eventually(clientTracer.updates).Should(Receive(Equal(keyUpdate{phase: 1, remote: false})))
+		Eventually(serverTracer.updates).Should(Receive(Equal(keyUpdate{phase: 1, remote: true})))
+		echo()
+		Consistently(clientTracer.updates).ShouldNot(Receive())
+	})
+})
@@ -99,6 +99,52 @@ var _ = Describe("Multiplexing", func() {
 					Eventually(done2, timeout).Should(BeClosed())
 				})
 
+				It("dials a second session using the packet conn of an existing session", func() {
+					server := getListener()
+					runServer(server)
+					defer server.Close()
+
+					addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+					Expect(err).ToNot(HaveOccurred())
+					conn, err := net.ListenUDP("udp", addr)
+					Expect(err).ToNot(HaveOccurred())
+					defer conn.Close()
+
+					readData := func(sess quic.Session) {
+						str, err := sess.AcceptStream(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						data, err := ioutil.ReadAll(str)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(data).To(Equal(PRData))
+					}
+
+					host := fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port)
+					sess1, err := quic.Dial(
+						conn,
+						server.Addr(),
+						host,
+						getTLSClientConfig(),
+						&quic.Config{Versions: []protocol.VersionNumber{version}},
+					)
+					Expect(err).ToNot(HaveOccurred())
+					sess2, err := quic.DialSessionSocket(
+						context.Background(),
+						sess1,
+						server.Addr(),
+						host,
+						getTLSClientConfig(),
+						&quic.Config{Versions: []protocol.VersionNumber{version}},
+					)
+					Expect(err).ToNot(HaveOccurred())
+					defer sess2.CloseWithError(0, "")
+					Expect(sess2.LocalAddr()).To(Equal(sess1.LocalAddr()))
+
+					readData(sess1)
+					// closing the first session doesn't affect the second one
+					Expect(sess1.CloseWithError(0, "")).To(Succeed())
+					readData(sess2)
+				})
+
 				It("multiplexes connections to different servers", func() {
 					server1 := getListener()
 					runServer(server1)
@@ -0,0 +1,67 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pacing", func() {
+	for _, v := range []protocol.VersionNumber{protocol.VersionTLS} {
+		version := v
+
+		Context(fmt.Sprintf("with QUIC %s", version), func() {
+			It("doesn't send faster than the maximum send rate", func() {
+				const maxSendRate = 1 << 20     // 1 MB/s
+				data := GeneratePRData(3 << 19) // 1.5 MB
+
+				server, err := quic.ListenAddr(
+					"localhost:0",
+					getTLSConfig(),
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer server.Close()
+
+				go func() {
+					defer GinkgoRecover()
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					sess.SetMaxSendRate(maxSendRate)
+					str, err := sess.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write(data)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				str, err := sess.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				start := time.Now()
+				received, err := ioutil.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(received).To(Equal(data))
+				rate := float64(len(data)) / time.Since(start).Seconds()
+				fmt.Fprintf(GinkgoWriter, "Transferred data at %.0f bytes per second.\n", rate)
+				// allow for some tolerance, since the first packets might be sent before the stream is accepted
+				Expect(rate).To(BeNumerically("<", 1.1*maxSendRate))
+				Expect(rate).To(BeNumerically(">", 0.5*maxSendRate))
+			})
+		})
+	}
+})
@@ -0,0 +1,99 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream reassembly", func() {
+	var (
+		proxy *quicproxy.QuicProxy
+		ln    quic.Listener
+	)
+
+	startListenerAndProxy := func(conf *quic.Config) {
+		var err error
+		ln, err = quic.ListenAddr("localhost:0", getTLSConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		var dropped int32
+		proxy, err = quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration {
+				return 5 * time.Millisecond
+			},
+			// drop the first large 1-RTT packet sent by the client,
+			// so that the stream data sent after it arrives out of order
+			DropPacket: func(dir quicproxy.Direction, data []byte) bool {
+				if dir != quicproxy.DirectionIncoming || data[0]&0x80 > 0 || len(data) < 1000 {
+					return false
+				}
+				return atomic.CompareAndSwapInt32(&dropped, 0, 1)
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	AfterEach(func() {
+		Expect(proxy.Close()).To(Succeed())
+		Expect(ln.Close()).To(Succeed())
+	})
+
+	sendData := func(data []byte) quic.Session {
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			if _, err := str.Write(data); err != nil {
+				return
+			}
+			str.Close()
+		}()
+		return sess
+	}
+
+	It("reassembles data that arrives out of order", func() {
+		startListenerAndProxy(nil)
+		data := GeneratePRData(100 * 1024)
+		sess := sendData(data)
+		defer sess.CloseWithError(0, "")
+
+		serverSess, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		str, err := serverSess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		received, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received).To(Equal(data))
+	})
+
+	It("transfers the data when the reassembly buffer is smaller than the default flow control window", func() {
+		startListenerAndProxy(&quic.Config{MaxStreamReassemblyBuffer: 5000})
+		data := GeneratePRData(100 * 1024)
+		sess := sendData(data)
+		defer sess.CloseWithError(0, "")
+
+		serverSess, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		str, err := serverSess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		received, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received).To(Equal(data))
+	})
+})
@@ -0,0 +1,88 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"syscall"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SO_REUSEPORT", func() {
+	listenReusePort := func(addr string) net.PacketConn {
+		lc := net.ListenConfig{
+			Control: func(_, _ string, c syscall.RawConn) error {
+				var opErr error
+				if err := c.Control(func(fd uintptr) {
+					opErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+				}); err != nil {
+					return err
+				}
+				return opErr
+			},
+		}
+		conn, err := lc.ListenPacket(context.Background(), "udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		return conn
+	}
+
+	It("runs multiple servers on the same port", func() {
+		conn1 := listenReusePort("127.0.0.1:0")
+		defer conn1.Close()
+		conn2 := listenReusePort(conn1.LocalAddr().String())
+		defer conn2.Close()
+
+		var numAccepted [2]int32 // to be used as atomics
+		for i, conn := range []net.PacketConn{conn1, conn2} {
+			ln, err := quic.Listen(conn, getTLSConfig(), &quic.Config{Versions: []protocol.VersionNumber{protocol.VersionTLS}})
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			counter := &numAccepted[i]
+			go func() {
+				defer GinkgoRecover()
+				for {
+					sess, err := ln.Accept(context.Background())
+					if err != nil {
+						return
+					}
+					atomic.AddInt32(counter, 1)
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}
+			}()
+		}
+
+		// The kernel picks the socket based on the client's address.
+		// With enough connections, both servers will handle some of them.
+		const numConns = 20
+		for i := 0; i < numConns; i++ {
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", conn1.LocalAddr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				&quic.Config{Versions: []protocol.VersionNumber{protocol.VersionTLS}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(sess.CloseWithError(0, "")).To(Succeed())
+		}
+		Eventually(func() int32 { return atomic.LoadInt32(&numAccepted[0]) + atomic.LoadInt32(&numAccepted[1]) }).Should(BeEquivalentTo(numConns))
+		Expect(atomic.LoadInt32(&numAccepted[0])).ToNot(BeZero())
+		Expect(atomic.LoadInt32(&numAccepted[1])).ToNot(BeZero())
+	})
+})
@@ -98,7 +98,10 @@ var _ = Describe("Stateless Resets", func() {
 			if serr == nil {
 				_, serr = str.Read([]byte{0})
 			}
-			Expect(serr).To(MatchError("INTERNAL_ERROR: received a stateless reset"))
+			Expect(serr).To(BeAssignableToTypeOf(&quic.StatelessResetError{}))
+			Eventually(sess.Context().Done()).Should(BeClosed())
+			_, err = sess.AcceptStream(context.Background())
+			Expect(err).To(BeAssignableToTypeOf(&quic.StatelessResetError{}))
 
 			Expect(ln2.Close()).To(Succeed())
 			Eventually(acceptStopped).Should(BeClosed())
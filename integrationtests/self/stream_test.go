@@ -2,12 +2,17 @@ package self_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 
 	. "github.com/onsi/ginkgo"
@@ -158,6 +163,386 @@ var _ = Describe("Bidirectional streams", func() {
 				<-done1
 				<-done2
 			})
+
+			It(fmt.Sprintf("client opening %d streams to a server that uses IncomingStreams", numStreams), func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					var numAccepted int
+					for str := range sess.IncomingStreams() {
+						numAccepted++
+						go func(str quic.Stream) {
+							defer GinkgoRecover()
+							data, err := ioutil.ReadAll(str)
+							Expect(err).ToNot(HaveOccurred())
+							_, err = str.Write(data)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(str.Close()).To(Succeed())
+						}(str)
+					}
+					// the channel is closed when the client closes the session
+					Expect(numAccepted).To(Equal(numStreams))
+					Expect(sess.CloseCause()).To(MatchError(ContainSubstring("Application error 0x1")))
+				}()
+
+				client, err := quic.DialAddr(
+					serverAddr,
+					getTLSClientConfig(),
+					qconf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				runSendingPeer(client)
+				Consistently(done).ShouldNot(BeClosed())
+				Expect(client.CloseWithError(1, "")).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("allows the peer to open more streams", func() {
+				const maxStreams = 10
+				ln, err := quic.ListenAddr(
+					"localhost:0",
+					getTLSConfig(),
+					&quic.Config{
+						Versions:           []protocol.VersionNumber{version},
+						MaxIncomingStreams: maxStreams,
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				serverSess := make(chan quic.Session, 1)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					serverSess <- sess
+				}()
+
+				client, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					qconf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer client.CloseWithError(0, "")
+				for i := 0; i < maxStreams; i++ {
+					_, err := client.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+				}
+				_, err = client.OpenStream()
+				Expect(err).To(HaveOccurred())
+				Expect(err.(net.Error).Temporary()).To(BeTrue())
+
+				var sess quic.Session
+				Eventually(serverSess).Should(Receive(&sess))
+				sess.AllowMoreIncomingStreams(5, 0)
+				for i := 0; i < 5; i++ {
+					ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+					_, err := client.OpenStreamSync(ctx)
+					cancel()
+					Expect(err).ToNot(HaveOccurred())
+				}
+				_, err = client.OpenStream()
+				Expect(err).To(HaveOccurred())
+				Expect(err.(net.Error).Temporary()).To(BeTrue())
+			})
+
+			It("reads data that arrived before the stream was accepted", func() {
+				data := GeneratePRData(10 << 10) // 10 KB, less than the initial flow control window
+				serverSess := make(chan quic.Session, 1)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					serverSess <- sess
+				}()
+
+				client, err := quic.DialAddr(serverAddr, getTLSClientConfig(), qconf)
+				Expect(err).ToNot(HaveOccurred())
+				defer client.CloseWithError(0, "")
+				str, err := client.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				// wait until the server received all the data, before accepting the stream
+				Eventually(str.AckedOffset).Should(BeEquivalentTo(len(data)))
+
+				var sess quic.Session
+				Eventually(serverSess).Should(Receive(&sess))
+				serverStr, err := sess.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				dataRead, err := ioutil.ReadAll(serverStr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dataRead).To(Equal(data))
+			})
+
+			It("limits the amount of data buffered for the connection", func() {
+				const maxBuffer = 100 << 10 // 100 KB
+				const numSlowStreams = 10
+				const dataLen = 200 << 10 // 200 KB per stream
+				const chunkSize = 1 << 10
+
+				var bytesWritten, bytesRead int64 // to be used as atomics
+				ln, err := quic.ListenAddr(
+					"localhost:0",
+					getTLSConfig(),
+					&quic.Config{
+						Versions:                   []protocol.VersionNumber{version},
+						MaxConnectionReceiveBuffer: maxBuffer,
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				var wg sync.WaitGroup
+				wg.Add(numSlowStreams)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					for i := 0; i < numSlowStreams; i++ {
+						str, err := sess.AcceptUniStream(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						go func() {
+							defer GinkgoRecover()
+							defer wg.Done()
+							b := make([]byte, chunkSize)
+							for {
+								// Load the number of bytes written first.
+								// Data is only counted as written after it was sent, so this can only overestimate the buffered data.
+								written := atomic.LoadInt64(&bytesWritten)
+								Expect(written - atomic.LoadInt64(&bytesRead)).To(BeNumerically("<=", maxBuffer))
+								n, err := str.Read(b)
+								atomic.AddInt64(&bytesRead, int64(n))
+								if err != nil {
+									Expect(err).To(Equal(io.EOF))
+									return
+								}
+								time.Sleep(time.Millisecond) // drain slowly
+							}
+						}()
+					}
+				}()
+
+				client, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					qconf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer client.CloseWithError(0, "")
+				data := GeneratePRData(dataLen)
+				for i := 0; i < numSlowStreams; i++ {
+					str, err := client.OpenUniStreamSync(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					go func() {
+						defer GinkgoRecover()
+						for j := 0; j < dataLen; j += chunkSize {
+							_, err := str.Write(data[j : j+chunkSize])
+							Expect(err).ToNot(HaveOccurred())
+							atomic.AddInt64(&bytesWritten, chunkSize)
+						}
+						Expect(str.Close()).To(Succeed())
+					}()
+				}
+				wg.Wait()
+				Expect(atomic.LoadInt64(&bytesRead)).To(BeEquivalentTo(numSlowStreams * dataLen))
+			})
+
+			It("reports when sending is blocked by connection-level flow control", func() {
+				const maxBuffer = 10 << 10 // 10 KB
+				ln, err := quic.ListenAddr(
+					"localhost:0",
+					getTLSConfig(),
+					&quic.Config{
+						Versions:                   []protocol.VersionNumber{version},
+						MaxConnectionReceiveBuffer: maxBuffer,
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				go func() {
+					defer GinkgoRecover()
+					// accept the session, but never read any data
+					_, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+				}()
+
+				type blockedEvent struct {
+					connectionLevel bool
+					limit           uint64
+				}
+				blocked := make(chan blockedEvent, 100)
+				conf := &quic.Config{
+					Versions: []protocol.VersionNumber{version},
+					OnFlowControlBlocked: func(connectionLevel bool, _ quic.StreamID, limit uint64) {
+						select {
+						case blocked <- blockedEvent{connectionLevel: connectionLevel, limit: limit}:
+						default:
+						}
+					},
+				}
+				client, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					conf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer client.CloseWithError(0, "")
+				str, err := client.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				go func() {
+					defer GinkgoRecover()
+					// this Write blocks, since the server never grants more flow control credit
+					str.Write(GeneratePRData(2 * maxBuffer))
+				}()
+				var ev blockedEvent
+				Eventually(blocked).Should(Receive(&ev))
+				Expect(ev.connectionLevel).To(BeTrue())
+				Expect(ev.limit).To(BeEquivalentTo(maxBuffer))
+			})
+
+			It("drains the session", func() {
+				ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), qconf)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					sess.Drain(500 * time.Millisecond)
+					data, err := ioutil.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal([]byte("request")))
+					_, err = str.Write([]byte("response"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+					// streams opened after draining are never accepted
+					_, err = sess.AcceptStream(context.Background())
+					Expect(err).To(HaveOccurred())
+				}()
+
+				client, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					qconf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				str, err := client.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write([]byte("request"))
+				Expect(err).ToNot(HaveOccurred())
+				// give the server some time to accept the stream and start draining
+				time.Sleep(100 * time.Millisecond)
+				// the server refuses new streams
+				newStr, err := client.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newStr.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = ioutil.ReadAll(newStr)
+				Expect(err).To(HaveOccurred())
+				// the existing stream completes
+				Expect(str.Close()).To(Succeed())
+				data, err := ioutil.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("response")))
+				// the server closes the session after the drain timeout
+				Eventually(client.Context().Done(), time.Second).Should(BeClosed())
+				Expect(client.CloseCause()).To(MatchError(ContainSubstring("draining")))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("closes the session gracefully, after the peer received all data", func() {
+				var numPackets int32
+				proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+					RemoteAddr:  serverAddr,
+					DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 10 * time.Millisecond },
+					// drop every 10th large packet sent by the client, so that stream data needs to be retransmitted
+					DropPacket: func(dir quicproxy.Direction, data []byte) bool {
+						if dir != quicproxy.DirectionIncoming || len(data) < 1000 {
+							return false
+						}
+						return atomic.AddInt32(&numPackets, 1)%10 == 0
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				defer proxy.Close()
+
+				data := GeneratePRData(200 * 1024)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					received, err := ioutil.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(received).To(Equal(data))
+					Eventually(sess.Context().Done()).Should(BeClosed())
+					var qErr *quic.Error
+					Expect(errors.As(sess.CloseCause(), &qErr)).To(BeTrue())
+					Expect(qErr.IsApplicationError()).To(BeTrue())
+					Expect(qErr.ErrorCode).To(BeEquivalentTo(0x42))
+				}()
+
+				client, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+					getTLSClientConfig(),
+					qconf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				str, err := client.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				Expect(client.CloseGracefully(ctx, 0x42, "bye")).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("reads all data received before the peer closed the session", func() {
+				data := GeneratePRData(1000) // small enough to be sent in a single packet, together with the FIN
+				accepted := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write(data)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+					Eventually(accepted).Should(BeClosed())
+					Expect(sess.CloseWithError(0, "")).To(Succeed())
+				}()
+
+				client, err := quic.DialAddr(
+					serverAddr,
+					getTLSClientConfig(),
+					qconf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				str, err := client.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				close(accepted)
+				Eventually(client.Context().Done()).Should(BeClosed())
+				received, err := ioutil.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(received).To(Equal(data))
+			})
 		})
 	}
 })
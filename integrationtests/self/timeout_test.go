@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"runtime"
 	"strconv"
 	"time"
 
 	quic "github.com/lucas-clemente/quic-go"
 	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -57,6 +59,36 @@ var _ = Describe("Timeout tests", func() {
 		Expect(err).To(MatchError(context.DeadlineExceeded))
 	})
 
+	It("aborts the handshake promptly when the context is canceled", func() {
+		// a server that never responds
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		numGoroutines := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error)
+		go func() {
+			_, err := quic.DialAddrContext(
+				ctx,
+				fmt.Sprintf("localhost:%d", conn.LocalAddr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				nil,
+			)
+			errChan <- err
+		}()
+		// wait until the client sent the first Initial
+		Expect(conn.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+		_, _, err = conn.ReadFrom(make([]byte, 2000))
+		Expect(err).ToNot(HaveOccurred())
+		cancel()
+		var dialErr error
+		Eventually(errChan, 100*time.Millisecond).Should(Receive(&dialErr))
+		Expect(dialErr).To(MatchError(context.Canceled))
+		// all go routines started for the session have returned
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+	})
+
 	It("returns net.Error timeout errors when an idle timeout occurs", func() {
 		const idleTimeout = 100 * time.Millisecond
 
@@ -230,6 +262,41 @@ var _ = Describe("Timeout tests", func() {
 		})
 	})
 
+	It("closes the connection after the maximum connection duration", func() {
+		const maxDuration = 300 * time.Millisecond
+
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			&quic.Config{MaxConnectionDuration: maxDuration},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			sess.AcceptStream(context.Background()) // blocks until the session is closed
+		}()
+
+		startTime := time.Now()
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			&quic.Config{KeepAlive: true},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(sess.Context().Done(), maxDuration/2).ShouldNot(BeClosed())
+		Eventually(sess.Context().Done(), maxDuration).Should(BeClosed())
+		Expect(time.Since(startTime)).To(BeNumerically("~", maxDuration, maxDuration/4))
+		Expect(sess.CloseCause()).To(BeAssignableToTypeOf(&qerr.QuicError{}))
+		qErr := sess.CloseCause().(*qerr.QuicError)
+		Expect(qErr.ErrorCode).To(Equal(qerr.NoError))
+		Expect(qErr.IsApplicationError()).To(BeFalse())
+		Expect(qErr.ErrorMessage).To(Equal("maximum connection duration reached"))
+	})
+
 	It("does not time out if keepalive is set", func() {
 		const idleTimeout = 100 * time.Millisecond
 
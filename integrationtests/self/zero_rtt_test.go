@@ -81,6 +81,7 @@ var _ = Describe("0-RTT", func() {
 					defer GinkgoRecover()
 					sess, err := ln.Accept(context.Background())
 					Expect(err).ToNot(HaveOccurred())
+					Expect(sess.Used0RTT()).To(Equal(expect0RTT))
 					str, err := sess.AcceptUniStream(context.Background())
 					Expect(err).ToNot(HaveOccurred())
 					data, err := ioutil.ReadAll(str)
@@ -347,6 +348,68 @@ var _ = Describe("0-RTT", func() {
 				Expect(num0RTT).ToNot(BeZero())
 			})
 
+			It("exposes the remembered transport parameters until the handshake completes", func() {
+				const maxStreams = 42
+				tlsConf := getTLSConfig()
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					&quic.Config{
+						Versions:           []protocol.VersionNumber{version},
+						AcceptToken:        func(_ net.Addr, _ *quic.Token) bool { return true },
+						MaxIncomingStreams: maxStreams,
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+
+				clientConf := dialAndReceiveSessionTicket(ln, ln.Addr().(*net.UDPAddr).Port)
+
+				// now close the listener and restart it with a different config
+				Expect(ln.Close()).To(Succeed())
+				ln, err = quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					&quic.Config{
+						Versions:           []protocol.VersionNumber{version},
+						AcceptToken:        func(_ net.Addr, _ *quic.Token) bool { return true },
+						MaxIncomingStreams: maxStreams + 1,
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				proxy, _ := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sess.Used0RTT()).To(BeFalse())
+				}()
+
+				sess, err := quic.DialAddrEarly(
+					fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+					clientConf,
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				// The server's response takes at least one RTT.
+				Expect(sess.Used0RTT()).To(BeTrue())
+				params := sess.EarlyTransportParameters()
+				Expect(params).ToNot(BeNil())
+				Expect(params.MaxBidiStreams).To(BeEquivalentTo(maxStreams))
+				Expect(params.MaxIdleTimeout).To(BeZero()) // not remembered
+
+				Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+				Expect(sess.ZeroRTTRejected()).To(BeTrue())
+				Expect(sess.Used0RTT()).To(BeFalse())
+				params = sess.EarlyTransportParameters()
+				Expect(params.MaxBidiStreams).To(BeEquivalentTo(maxStreams + 1))
+				Expect(params.MaxIdleTimeout).ToNot(BeZero())
+				Expect(sess.RemoteTransportParameters()).To(Equal(params))
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+			})
+
 			It("rejects 0-RTT when the ALPN changed", func() {
 				const maxStreams = 42
 				tlsConf := getTLSConfig()
@@ -384,6 +447,79 @@ var _ = Describe("0-RTT", func() {
 				fmt.Fprintf(GinkgoWriter, "Sent %d 0-RTT packets.", num0RTT)
 				Expect(num0RTT).ToNot(BeZero())
 			})
+
+			It("resets non-idempotent streams when 0-RTT is rejected", func() {
+				const maxStreams = 42
+				tlsConf := getTLSConfig()
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					&quic.Config{
+						Versions:           []protocol.VersionNumber{version},
+						AcceptToken:        func(_ net.Addr, _ *quic.Token) bool { return true },
+						MaxIncomingStreams: maxStreams,
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+
+				clientConf := dialAndReceiveSessionTicket(ln, ln.Addr().(*net.UDPAddr).Port)
+
+				// now close the listener and restart it with a different config
+				Expect(ln.Close()).To(Succeed())
+				ln, err = quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					&quic.Config{
+						Versions:           []protocol.VersionNumber{version},
+						AcceptToken:        func(_ net.Addr, _ *quic.Token) bool { return true },
+						MaxIncomingStreams: maxStreams + 1,
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sess.Used0RTT()).To(BeFalse())
+					str, err := sess.AcceptUniStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					data, err := ioutil.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal([]byte("foobar")))
+					close(done)
+				}()
+
+				sess, err := quic.DialAddrEarly(
+					fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+					clientConf,
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				// data written on this stream is retransmitted in 1-RTT packets
+				str, err := sess.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				// data written on this stream must not be replayed
+				nonIdempotentStr, err := sess.OpenStreamWithOptions(quic.StreamOptions{NonIdempotent: true})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = nonIdempotentStr.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+				Expect(sess.ZeroRTTRejected()).To(BeTrue())
+				_, err = nonIdempotentStr.Write([]byte("foobar"))
+				Expect(err).To(MatchError(quic.Err0RTTRejected))
+				Eventually(done).Should(BeClosed())
+				Expect(atomic.LoadUint32(num0RTTPackets)).ToNot(BeZero())
+			})
 		})
 	}
 })
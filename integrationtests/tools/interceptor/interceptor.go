@@ -0,0 +1,209 @@
+// Package interceptor provides a net.PacketConn that allows tests to drop, delay, duplicate and modify packets.
+// It wraps the net.PacketConn passed to quic.Listen or quic.Dial, and therefore sees the actual packets on the wire.
+package interceptor
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// Direction is the direction a packet is sent.
+type Direction int
+
+const (
+	// DirectionSend is the direction of packets written to the PacketConn.
+	DirectionSend Direction = iota
+	// DirectionReceive is the direction of packets read from the PacketConn.
+	DirectionReceive
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionSend:
+		return "send"
+	case DirectionReceive:
+		return "receive"
+	default:
+		panic("unknown direction")
+	}
+}
+
+// Opts are the options for the PacketConn.
+// All callbacks are optional.
+type Opts struct {
+	// DropPacket determines whether a packet gets dropped.
+	DropPacket func(dir Direction, packet []byte) bool
+	// DelayPacket determines how long a packet gets delayed.
+	// Delaying packets by different amounts of time allows reordering them.
+	DelayPacket func(dir Direction, packet []byte) time.Duration
+	// DuplicatePacket determines how many additional copies of a packet are sent or received.
+	DuplicatePacket func(dir Direction, packet []byte) int
+	// ModifyPacket can be used to modify (e.g. corrupt) a packet.
+	// It is called after the decision to drop, delay and duplicate the packet was made.
+	ModifyPacket func(dir Direction, packet []byte) []byte
+}
+
+type receivedPacket struct {
+	data []byte
+	addr net.Addr
+}
+
+// PacketConn is a net.PacketConn that applies the Opts to every packet sent and received.
+type PacketConn struct {
+	net.PacketConn
+
+	opts Opts
+
+	mutex    sync.Mutex
+	closed   bool
+	timerID  uint64
+	timers   map[uint64]*time.Timer
+	received chan receivedPacket
+	readErr  error
+	readDone chan struct{}
+
+	logger utils.Logger
+}
+
+var _ net.PacketConn = &PacketConn{}
+
+// NewPacketConn wraps a net.PacketConn.
+func NewPacketConn(conn net.PacketConn, opts *Opts) *PacketConn {
+	if opts == nil {
+		opts = &Opts{}
+	}
+	c := &PacketConn{
+		PacketConn: conn,
+		opts:       *opts,
+		timers:     make(map[uint64]*time.Timer),
+		received:   make(chan receivedPacket, 1000),
+		readDone:   make(chan struct{}),
+		logger:     utils.DefaultLogger.WithPrefix("interceptor"),
+	}
+	go c.runReadLoop()
+	return c
+}
+
+func (c *PacketConn) runReadLoop() {
+	defer close(c.readDone)
+	for {
+		buffer := make([]byte, protocol.MaxReceivePacketSize)
+		n, addr, err := c.PacketConn.ReadFrom(buffer)
+		if err != nil {
+			c.readErr = err
+			return
+		}
+		_ = c.handle(DirectionReceive, buffer[:n], func(data []byte) error {
+			select {
+			case c.received <- receivedPacket{data: data, addr: addr}:
+			default:
+				c.logger.Debugf("dropping received packet (%d bytes). Queue full.", len(data))
+			}
+			return nil
+		})
+	}
+}
+
+// handle applies the Opts to a packet, and calls deliver for every copy of the packet that is not dropped.
+// It returns the first error returned by deliver, unless the packet was delayed.
+func (c *PacketConn) handle(dir Direction, packet []byte, deliver func([]byte) error) error {
+	if c.opts.DropPacket != nil && c.opts.DropPacket(dir, packet) {
+		if c.logger.Debug() {
+			c.logger.Debugf("dropping %s packet (%d bytes)", dir, len(packet))
+		}
+		return nil
+	}
+	copies := 1
+	if c.opts.DuplicatePacket != nil {
+		copies += c.opts.DuplicatePacket(dir, packet)
+	}
+	var delay time.Duration
+	if c.opts.DelayPacket != nil {
+		delay = c.opts.DelayPacket(dir, packet)
+	}
+	if c.opts.ModifyPacket != nil {
+		packet = c.opts.ModifyPacket(dir, packet)
+	}
+	if delay == 0 {
+		for i := 0; i < copies; i++ {
+			if err := deliver(packet); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if c.logger.Debug() {
+		c.logger.Debugf("delaying %s packet (%d bytes) by %s", dir, len(packet), delay)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.timerID++
+	id := c.timerID
+	c.timers[id] = time.AfterFunc(delay, func() {
+		c.mutex.Lock()
+		delete(c.timers, id)
+		closed := c.closed
+		c.mutex.Unlock()
+		if closed {
+			return
+		}
+		for i := 0; i < copies; i++ {
+			if err := deliver(packet); err != nil {
+				c.logger.Debugf("error delivering delayed %s packet: %s", dir, err)
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// ReadFrom reads a packet from the connection.
+func (c *PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.received:
+		return copy(b, p.data), p.addr, nil
+	case <-c.readDone:
+		// deliver packets that were received before the read loop stopped
+		select {
+		case p := <-c.received:
+			return copy(b, p.data), p.addr, nil
+		default:
+			return 0, nil, c.readErr
+		}
+	}
+}
+
+// WriteTo writes a packet to the connection.
+// Errors that occur when writing a delayed packet are not reported.
+func (c *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	// The caller is allowed to reuse the buffer as soon as WriteTo returns.
+	packet := make([]byte, len(b))
+	copy(packet, b)
+	err := c.handle(DirectionSend, packet, func(data []byte) error {
+		_, err := c.PacketConn.WriteTo(data, addr)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the connection.
+// Packets that are currently delayed are dropped.
+func (c *PacketConn) Close() error {
+	c.mutex.Lock()
+	c.closed = true
+	for _, t := range c.timers {
+		t.Stop()
+	}
+	c.mutex.Unlock()
+	return c.PacketConn.Close()
+}
@@ -0,0 +1,13 @@
+package interceptor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestInterceptor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Interceptor Suite")
+}
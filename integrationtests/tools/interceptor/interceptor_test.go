@@ -0,0 +1,173 @@
+package interceptor
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Interceptor", func() {
+	var (
+		peer *net.UDPConn
+		conn *net.UDPConn
+	)
+
+	BeforeEach(func() {
+		var err error
+		peer, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		conn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(peer.Close()).To(Succeed())
+	})
+
+	// receive reads packets from a connection, and sends them on the returned channel
+	receive := func(c net.PacketConn) <-chan []byte {
+		ch := make(chan []byte, 100)
+		go func() {
+			defer GinkgoRecover()
+			for {
+				b := make([]byte, 1500)
+				n, _, err := c.ReadFrom(b)
+				if err != nil {
+					return
+				}
+				ch <- b[:n]
+			}
+		}()
+		return ch
+	}
+
+	Context("sending", func() {
+		It("sends packets", func() {
+			c := NewPacketConn(conn, nil)
+			defer c.Close()
+			received := receive(peer)
+			n, err := c.WriteTo([]byte("foobar"), peer.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+			Eventually(received).Should(Receive(Equal([]byte("foobar"))))
+		})
+
+		It("drops packets", func() {
+			c := NewPacketConn(conn, &Opts{
+				DropPacket: func(dir Direction, p []byte) bool {
+					Expect(dir).To(Equal(DirectionSend))
+					return string(p) == "drop"
+				},
+			})
+			defer c.Close()
+			received := receive(peer)
+			_, err := c.WriteTo([]byte("drop"), peer.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = c.WriteTo([]byte("foobar"), peer.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(received).Should(Receive(Equal([]byte("foobar"))))
+			Consistently(received).ShouldNot(Receive())
+		})
+
+		It("reorders packets", func() {
+			c := NewPacketConn(conn, &Opts{
+				DelayPacket: func(_ Direction, p []byte) time.Duration {
+					if string(p) == "first" {
+						return 50 * time.Millisecond
+					}
+					return 0
+				},
+			})
+			defer c.Close()
+			received := receive(peer)
+			_, err := c.WriteTo([]byte("first"), peer.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = c.WriteTo([]byte("second"), peer.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(received).Should(Receive(Equal([]byte("second"))))
+			Eventually(received).Should(Receive(Equal([]byte("first"))))
+		})
+
+		It("duplicates and modifies packets", func() {
+			c := NewPacketConn(conn, &Opts{
+				DuplicatePacket: func(Direction, []byte) int { return 2 },
+				ModifyPacket: func(_ Direction, p []byte) []byte {
+					p[0] = 'F'
+					return p
+				},
+			})
+			defer c.Close()
+			received := receive(peer)
+			b := []byte("foobar")
+			_, err := c.WriteTo(b, peer.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("foobar"))) // the caller's buffer is not modified
+			for i := 0; i < 3; i++ {
+				Eventually(received).Should(Receive(Equal([]byte("Foobar"))))
+			}
+			Consistently(received).ShouldNot(Receive())
+		})
+
+		It("doesn't send delayed packets after the connection was closed", func() {
+			c := NewPacketConn(conn, &Opts{
+				DelayPacket: func(Direction, []byte) time.Duration { return 50 * time.Millisecond },
+			})
+			received := receive(peer)
+			_, err := c.WriteTo([]byte("foobar"), peer.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Close()).To(Succeed())
+			Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+
+	Context("receiving", func() {
+		It("receives packets", func() {
+			c := NewPacketConn(conn, nil)
+			defer c.Close()
+			received := receive(c)
+			_, err := peer.WriteTo([]byte("foobar"), conn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(received).Should(Receive(Equal([]byte("foobar"))))
+		})
+
+		It("drops and duplicates packets", func() {
+			c := NewPacketConn(conn, &Opts{
+				DropPacket: func(dir Direction, p []byte) bool {
+					Expect(dir).To(Equal(DirectionReceive))
+					return string(p) == "drop"
+				},
+				DuplicatePacket: func(Direction, []byte) int { return 1 },
+			})
+			defer c.Close()
+			received := receive(c)
+			_, err := peer.WriteTo([]byte("drop"), conn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = peer.WriteTo([]byte("foobar"), conn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(received).Should(Receive(Equal([]byte("foobar"))))
+			Eventually(received).Should(Receive(Equal([]byte("foobar"))))
+			Consistently(received).ShouldNot(Receive())
+		})
+
+		It("delays packets", func() {
+			c := NewPacketConn(conn, &Opts{
+				DelayPacket: func(Direction, []byte) time.Duration { return 100 * time.Millisecond },
+			})
+			defer c.Close()
+			received := receive(c)
+			_, err := peer.WriteTo([]byte("foobar"), conn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(received, 50*time.Millisecond).ShouldNot(Receive())
+			Eventually(received).Should(Receive(Equal([]byte("foobar"))))
+		})
+
+		It("returns an error when the connection is closed", func() {
+			c := NewPacketConn(conn, nil)
+			Expect(c.Close()).To(Succeed())
+			_, _, err := c.ReadFrom(make([]byte, 100))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
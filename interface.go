@@ -2,12 +2,15 @@ package quic
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net"
+	"syscall"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
@@ -17,6 +20,9 @@ type StreamID = protocol.StreamID
 // A VersionNumber is a QUIC version number.
 type VersionNumber = protocol.VersionNumber
 
+// A ConnectionID is a QUIC connection ID.
+type ConnectionID = protocol.ConnectionID
+
 // A Token can be used to verify the ownership of the client address.
 type Token struct {
 	// IsRetryToken encodes how the client received the token. There are two ways:
@@ -25,8 +31,61 @@ type Token struct {
 	IsRetryToken bool
 	RemoteAddr   string
 	SentTime     time.Time
+	// OriginalDestConnectionID is the destination connection ID of the client's first Initial packet.
+	// It is only set for Retry tokens.
+	OriginalDestConnectionID ConnectionID
+}
+
+// An InitialHeader contains the header fields of the Initial packet that a client uses to establish a new connection.
+// Warning: This API should not be considered stable and might change soon.
+type InitialHeader struct {
+	Version VersionNumber
+	// DestConnectionID is the connection ID chosen by the client.
+	DestConnectionID ConnectionID
+	// SrcConnectionID is the connection ID that the client uses for itself.
+	SrcConnectionID ConnectionID
+	// Token is the token sent by the client, if any.
+	Token []byte
+}
+
+// A TokenGenerator generates the tokens that the server uses to validate client addresses.
+// It can be used to take full control over the token format and the keys used to protect tokens.
+// Warning: This API should not be considered stable and might change soon.
+type TokenGenerator interface {
+	// NewRetryToken generates a token that is sent in a Retry packet.
+	NewRetryToken(remoteAddr net.Addr, origDestConnID ConnectionID) ([]byte, error)
+	// NewToken generates a token that is sent in a NEW_TOKEN frame.
+	NewToken(remoteAddr net.Addr) ([]byte, error)
+	// DecodeToken decodes a token received in an Initial packet.
+	// If the token is invalid, it must return an error.
+	DecodeToken(token []byte) (*Token, error)
+}
+
+// StreamOptions are options for opening a stream.
+type StreamOptions struct {
+	// InitialReceiveWindow is a hint for the size of the stream's receive window.
+	// Streams that are expected to receive a lot of data should use a large window,
+	// so that the peer doesn't have to wait for window updates.
+	// The value is limited by Config.MaxReceiveStreamFlowControlWindow.
+	// If the value is smaller than the initial window announced in the transport parameters,
+	// the window won't shrink, but window updates will use the smaller window size.
+	// As for all streams, auto-tuning still increases the window size (up to Config.MaxReceiveStreamFlowControlWindow),
+	// if the application reads the data faster than it arrives.
+	// If not set, the stream uses the default receive window.
+	InitialReceiveWindow uint64
+	// NonIdempotent marks the data sent on this stream as unsafe to replay.
+	// By default, when the server rejects 0-RTT, data that was sent in 0-RTT packets
+	// is transparently retransmitted in 1-RTT packets.
+	// For non-idempotent streams, the stream is reset instead,
+	// and Write returns Err0RTTRejected.
+	NonIdempotent bool
 }
 
+// Err0RTTRejected is returned by Write on a stream opened with StreamOptions.NonIdempotent,
+// if data was sent on this stream in 0-RTT packets and the server rejected 0-RTT.
+// Warning: This API should not be considered stable and might change soon.
+var Err0RTTRejected = errors.New("0-RTT rejected")
+
 // A ClientToken is a token received by the client.
 // It can be used to skip address validation on future connection attempts.
 type ClientToken struct {
@@ -48,6 +107,58 @@ type TokenStore interface {
 // Valid values range between 0 and MAX_UINT62.
 type ErrorCode = protocol.ApplicationErrorCode
 
+// A TransportErrorCode is a QUIC transport error code.
+// Warning: This API should not be considered stable and might change soon.
+type TransportErrorCode = qerr.ErrorCode
+
+// The transport error codes defined by QUIC.
+// ConnectionRefused is used when the server refuses a connection.
+const (
+	NoError                 TransportErrorCode = qerr.NoError
+	InternalError           TransportErrorCode = qerr.InternalError
+	ConnectionRefused       TransportErrorCode = qerr.ConnectionRefused
+	FlowControlError        TransportErrorCode = qerr.FlowControlError
+	StreamLimitError        TransportErrorCode = qerr.StreamLimitError
+	StreamStateError        TransportErrorCode = qerr.StreamStateError
+	FinalSizeError          TransportErrorCode = qerr.FinalSizeError
+	FrameEncodingError      TransportErrorCode = qerr.FrameEncodingError
+	TransportParameterError TransportErrorCode = qerr.TransportParameterError
+	ConnectionIDLimitError  TransportErrorCode = qerr.ConnectionIDLimitError
+	ProtocolViolation       TransportErrorCode = qerr.ProtocolViolation
+	CryptoBufferExceeded    TransportErrorCode = qerr.CryptoBufferExceeded
+)
+
+// NoApplicationProtocol is the error code (a CRYPTO_ERROR carrying a TLS no_application_protocol alert)
+// that the server closes the connection with if none of the application protocols offered by the client
+// (tls.Config.NextProtos) are supported by the server.
+// On the client side, this can be checked using errors.Is(err, quic.NoApplicationProtocol).
+// Warning: This API should not be considered stable and might change soon.
+const NoApplicationProtocol TransportErrorCode = qerr.NoApplicationProtocol
+
+// An Error is the error that a session was closed with.
+// It is returned by the methods of the session and its streams (e.g. Read, Write, AcceptStream) after the session was closed,
+// and can be obtained using errors.As.
+// If IsApplicationError returns true, the ErrorCode is the application error code passed to CloseWithError.
+// Otherwise, it is a transport error code, and errors.Is can be used to check for a specific code,
+// e.g. errors.Is(err, quic.FlowControlError).
+// Sessions closed by a stateless reset return a StatelessResetError instead.
+// Warning: This API should not be considered stable and might change soon.
+type Error = qerr.QuicError
+
+// CongestionControl is the congestion control algorithm.
+// Warning: This API should not be considered stable and might change soon.
+type CongestionControl = protocol.CongestionControl
+
+const (
+	// CongestionControlHybrid uses NewReno, and exits slow start when an increase of the RTT is detected (using HyStart++, see Config.DisableHyStart).
+	// This is the default.
+	CongestionControlHybrid = protocol.CongestionControlHybrid
+	// CongestionControlReno uses NewReno, and only exits slow start when a packet is lost.
+	CongestionControlReno = protocol.CongestionControlReno
+	// CongestionControlCubic uses CUBIC, and exits slow start when an increase of the RTT is detected (using HyStart++, see Config.DisableHyStart).
+	CongestionControlCubic = protocol.CongestionControlCubic
+)
+
 // Stream is the interface implemented by QUIC streams
 type Stream interface {
 	// StreamID returns the stream ID.
@@ -61,6 +172,9 @@ type Stream interface {
 	// the net.Error interface, and Timeout() will be true.
 	io.Reader
 	// Write writes data to the stream.
+	// Small writes are not delayed in order to coalesce them with subsequent writes:
+	// Write only returns once all data has been packed into STREAM frames,
+	// which happens as soon as flow control, congestion control and pacing allow.
 	// Write can be made to time out and return a net.Error with Timeout() == true
 	// after a fixed time limit; see SetDeadline and SetWriteDeadline.
 	// If the stream was canceled by the peer, the error implements the StreamError
@@ -68,11 +182,50 @@ type Stream interface {
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	io.Writer
+	// WriteTo writes the data received on the stream to w,
+	// until the end of the stream is reached or an error occurs.
+	// It writes directly from the buffers that the data was received in,
+	// without copying the data into an intermediate buffer.
+	// It returns a nil error when the end of the stream is reached.
+	// If the stream was canceled by the peer, the error implements the StreamError
+	// interface, and Canceled() == true.
+	// The number of bytes written to w is returned, even if an error occurs.
+	// It implements io.WriterTo, such that io.Copy(dst, stream) uses it automatically.
+	io.WriterTo
+	// ReadFrom reads data from r into a pooled buffer and writes it to the stream,
+	// until r returns io.EOF or an error occurs.
+	// It returns a nil error when r returns io.EOF. The stream is not closed.
+	// Writing blocks when the stream is flow control blocked, and
+	// is subject to the write deadline; see SetWriteDeadline.
+	// If the stream was canceled by the peer, the error implements the StreamError
+	// interface, and Canceled() == true.
+	// The number of bytes written to the stream is returned, even if an error occurs.
+	// It implements io.ReaderFrom, such that io.Copy(stream, src) uses it automatically.
+	io.ReaderFrom
 	// Close closes the write-direction of the stream.
 	// Future calls to Write are not permitted after calling Close.
 	// It must not be called concurrently with Write.
 	// It must not be called after calling CancelWrite.
 	io.Closer
+	// BufferedAmount returns the number of bytes that were written to the stream, but not sent yet.
+	// This includes data that is still being passed to a blocked Write call (e.g. when the stream is flow control blocked),
+	// and data that was lost and is waiting to be retransmitted.
+	// It can be used to implement backpressure when the network is slow.
+	// Warning: This API should not be considered stable and might change soon.
+	BufferedAmount() uint64
+	// AckedOffset returns the offset up to which all data written to the stream was acknowledged by the peer.
+	// After a connection is lost, an application can resume sending from this offset on a new connection.
+	// Warning: This API should not be considered stable and might change soon.
+	AckedOffset() uint64
+	// SendStats returns statistics about the data sent on the stream.
+	// Warning: This API should not be considered stable and might change soon.
+	SendStats() SendStreamStats
+	// CloseWithContext closes the write-direction of the stream, like Close.
+	// It then blocks until the peer acknowledged all data written to the stream, including the FIN,
+	// or until the context is done, in which case it returns the context's error.
+	// If the stream is canceled or the session is closed before that, it returns the respective error.
+	// Warning: This API should not be considered stable and might change soon.
+	CloseWithContext(context.Context) error
 	// CancelWrite aborts sending on this stream.
 	// Data already written, but not yet delivered to the peer is not guaranteed to be delivered reliably.
 	// Write will unblock immediately, and future calls to Write will fail.
@@ -83,6 +236,11 @@ type Stream interface {
 	// Read will unblock immediately, and future Read calls will fail.
 	// When called multiple times or after reading the io.EOF it is a no-op.
 	CancelRead(ErrorCode)
+	// RejectStream rejects a stream opened by the peer, by canceling both directions with the same error code.
+	// The peer receives a STOP_SENDING and a RESET_STREAM frame, both carrying the error code.
+	// It is equivalent to calling CancelRead and CancelWrite with the same error code.
+	// Warning: This API should not be considered stable and might change soon.
+	RejectStream(ErrorCode)
 	// The context is canceled as soon as the write-side of the stream is closed.
 	// This happens when Close() or CancelWrite() is called, or when the peer
 	// cancels the read-side of their stream.
@@ -110,6 +268,8 @@ type ReceiveStream interface {
 	StreamID() StreamID
 	// see Stream.Read
 	io.Reader
+	// see Stream.WriteTo
+	io.WriterTo
 	// see Stream.CancelRead
 	CancelRead(ErrorCode)
 	// see Stream.SetReadDealine
@@ -122,8 +282,18 @@ type SendStream interface {
 	StreamID() StreamID
 	// see Stream.Write
 	io.Writer
+	// see Stream.ReadFrom
+	io.ReaderFrom
+	// see Stream.BufferedAmount
+	BufferedAmount() uint64
+	// see Stream.AckedOffset
+	AckedOffset() uint64
+	// see Stream.SendStats
+	SendStats() SendStreamStats
 	// see Stream.Close
 	io.Closer
+	// see Stream.CloseWithContext
+	CloseWithContext(context.Context) error
 	// see Stream.CancelWrite
 	CancelWrite(ErrorCode)
 	// see Stream.Context
@@ -132,6 +302,17 @@ type SendStream interface {
 	SetWriteDeadline(t time.Time) error
 }
 
+// SendStreamStats are statistics about the data sent on a stream.
+// Warning: This API should not be considered stable and might change soon.
+type SendStreamStats struct {
+	// BytesSent is the number of bytes of stream data that were sent.
+	// Every byte written by the application is only counted once, retransmissions are not included.
+	BytesSent uint64
+	// BytesRetransmitted is the number of bytes of stream data that were retransmitted,
+	// because the packet they were sent in was declared lost.
+	BytesRetransmitted uint64
+}
+
 // StreamError is returned by Read and Write when the peer cancels the stream.
 type StreamError interface {
 	error
@@ -139,18 +320,79 @@ type StreamError interface {
 	ErrorCode() ErrorCode
 }
 
+// ConnectionState records basic TLS details about the QUIC connection.
+// Used0RTT is true if 0-RTT was both offered by the client and accepted by the server.
+// For the client, it reflects the server's decision, for the server, whether it accepted the client's 0-RTT data.
+// PeerCertificates contains the certificate chain presented by the peer, and VerifiedChains the chains built from it.
+// On the server, these are only set if a client certificate was requested (see tls.Config.ClientAuth).
 type ConnectionState = handshake.ConnectionState
 
+// TransportParameters are the transport parameters sent during the handshake.
+type TransportParameters struct {
+	MaxIdleTimeout time.Duration
+	MaxPacketSize  uint64
+
+	InitialMaxData                 uint64
+	InitialMaxStreamDataBidiLocal  uint64
+	InitialMaxStreamDataBidiRemote uint64
+	InitialMaxStreamDataUni        uint64
+
+	MaxBidiStreams int64
+	MaxUniStreams  int64
+
+	AckDelayExponent uint8
+	MaxAckDelay      time.Duration
+
+	DisableActiveMigration  bool
+	ActiveConnectionIDLimit uint64
+}
+
+// HandshakeTiming records when the phases of the handshake happened.
+// Times are zero if the respective event didn't happen (yet).
+type HandshakeTiming struct {
+	// StartTime is the time the session was started.
+	StartTime time.Time
+	// FirstInitialSentTime is the time the first Initial packet was sent.
+	FirstInitialSentTime time.Time
+	// RetryReceivedTime is the time a Retry packet was received.
+	// This is only set for clients.
+	RetryReceivedTime time.Time
+	// CompleteTime is the time the handshake completed.
+	CompleteTime time.Time
+}
+
+// HandshakeDuration returns how long the handshake took.
+// It returns 0 if the handshake didn't complete (yet).
+func (t HandshakeTiming) HandshakeDuration() time.Duration {
+	if t.CompleteTime.IsZero() {
+		return 0
+	}
+	return t.CompleteTime.Sub(t.StartTime)
+}
+
 // A Session is a QUIC connection between two peers.
 type Session interface {
 	// AcceptStream returns the next stream opened by the peer, blocking until one is available.
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
+	// It can be called concurrently. Streams are returned to the callers in the order the calls were made.
+	// If the context is canceled, the call returns the context's error, and no stream is consumed.
 	AcceptStream(context.Context) (Stream, error)
 	// AcceptUniStream returns the next unidirectional stream opened by the peer, blocking until one is available.
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
+	// It can be called concurrently. Streams are returned to the callers in the order the calls were made.
+	// If the context is canceled, the call returns the context's error, and no stream is consumed.
 	AcceptUniStream(context.Context) (ReceiveStream, error)
+	// IncomingStreams returns a channel that delivers the streams opened by the peer, as accepted by AcceptStream.
+	// The channel is closed when the session is closed. CloseCause then returns the error that caused the session to close.
+	// Streams are accepted from the first call on, so it shouldn't be used together with AcceptStream.
+	// All calls return the same channel.
+	// Warning: This API should not be considered stable and might change soon.
+	IncomingStreams() <-chan Stream
+	// IncomingUniStreams works like IncomingStreams, but for unidirectional streams.
+	// Warning: This API should not be considered stable and might change soon.
+	IncomingUniStreams() <-chan ReceiveStream
 	// OpenStream opens a new bidirectional QUIC stream.
 	// There is no signaling to the peer about new streams:
 	// The peer can only accept the stream after data has been sent on the stream.
@@ -163,6 +405,9 @@ type Session interface {
 	// If the error is non-nil, it satisfies the net.Error interface.
 	// If the session was closed due to a timeout, Timeout() will be true.
 	OpenStreamSync(context.Context) (Stream, error)
+	// OpenStreamWithOptions works like OpenStream, but applies the StreamOptions to the new stream.
+	// Warning: This API should not be considered stable and might change soon.
+	OpenStreamWithOptions(StreamOptions) (Stream, error)
 	// OpenUniStream opens a new outgoing unidirectional QUIC stream.
 	// If the error is non-nil, it satisfies the net.Error interface.
 	// When reaching the peer's stream limit, Temporary() will be true.
@@ -173,20 +418,120 @@ type Session interface {
 	// If the error is non-nil, it satisfies the net.Error interface.
 	// If the session was closed due to a timeout, Timeout() will be true.
 	OpenUniStreamSync(context.Context) (SendStream, error)
+	// AllowMoreIncomingStreams allows the peer to open more streams concurrently,
+	// in addition to the limits configured by Config.MaxIncomingStreams and Config.MaxIncomingUniStreams.
+	// The increase is additive: calling it multiple times increases the limits multiple times.
+	// The new limits are sent to the peer immediately, using MAX_STREAMS frames.
+	// Negative values are ignored. The limits can't be increased beyond Config.MaxIncomingStreamsLimit.
+	// It is safe to call it concurrently.
+	// Warning: This API should not be considered stable and might change soon.
+	AllowMoreIncomingStreams(bidi, uni int)
+	// Drain gracefully shuts down the session.
+	// The peer isn't allowed to open any new streams: streams it opens after this call are reset
+	// with error code 0, in both directions, and are never returned by AcceptStream and AcceptUniStream.
+	// Existing streams continue to work, so that requests that are in flight can complete.
+	// The session is closed with error code 0 after the timeout.
+	// It doesn't block. Subsequent calls are ignored.
+	// Warning: This API should not be considered stable and might change soon.
+	Drain(timeout time.Duration)
 	// LocalAddr returns the local address.
 	LocalAddr() net.Addr
 	// RemoteAddr returns the address of the peer.
 	RemoteAddr() net.Addr
+	// LocalConnectionID returns the connection ID that the peer currently uses to send packets to us,
+	// i.e. the destination connection ID of the last packet we received.
+	// Connection IDs change over the lifetime of a connection, so this is only the current value.
+	// Warning: This API should not be considered stable and might change soon.
+	LocalConnectionID() ConnectionID
+	// RemoteConnectionID returns the connection ID that we currently use to send packets to the peer.
+	// Connection IDs change over the lifetime of a connection, so this is only the current value.
+	// Warning: This API should not be considered stable and might change soon.
+	RemoteConnectionID() ConnectionID
+	// RotateConnectionID switches to a new connection ID for sending packets to the peer,
+	// and retires the connection ID currently used by sending a RETIRE_CONNECTION_ID frame.
+	// This can be used to prevent linking packets sent before and after the change, e.g. when changing networks.
+	// The new connection ID is one of the connection IDs the peer provided in NEW_CONNECTION_ID frames.
+	// We never store more connection IDs than allowed by Config.ActiveConnectionIDLimit,
+	// and the peer is expected to provide a new connection ID when one is retired.
+	// It returns an error if the peer didn't provide any unused connection IDs.
+	// Warning: This API should not be considered stable and might change soon.
+	RotateConnectionID() error
 	// Close the connection with an error.
 	// The error string will be sent to the peer.
 	CloseWithError(ErrorCode, string) error
+	// CloseGracefully closes the connection with an error, like CloseWithError.
+	// Before sending the CONNECTION_CLOSE, it waits until the peer acknowledged all data written to streams,
+	// including the FINs of streams that were closed, such that no data is lost.
+	// If the context is done before that, the connection is closed anyway, and the context's error is returned.
+	// If the connection is closed for a different reason while waiting (e.g. by the peer), the close error is returned.
+	// Data written to streams after calling CloseGracefully is not waited for.
+	// Warning: This API should not be considered stable and might change soon.
+	CloseGracefully(context.Context, ErrorCode, string) error
 	// The context is cancelled when the session is closed.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
+	// CloseCause returns the error that caused the session to close.
+	// This is a net.Error with Timeout() == true for idle and handshake timeouts,
+	// the error received in the peer's CONNECTION_CLOSE frame, or the error that the session was closed with locally.
+	// It returns nil as long as the context returned by Context() is not canceled.
+	// Warning: This API should not be considered stable and might change soon.
+	CloseCause() error
+	// SetUserData stores an arbitrary value on the session.
+	// The value is released when the session is closed, such that it can be garbage collected.
+	// Values set after the session was closed are ignored.
+	// Warning: This API should not be considered stable and might change soon.
+	SetUserData(interface{})
+	// UserData returns the value stored by SetUserData.
+	// It returns nil if no value was set, or if the session was already closed.
+	// Warning: This API should not be considered stable and might change soon.
+	UserData() interface{}
 	// ConnectionState returns basic details about the QUIC connection.
 	// It blocks until the handshake completes.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// SendPing sends a PING frame and blocks until the peer acknowledges it.
+	// It returns the RTT sample taken from that acknowledgement.
+	// If the session is closed before the PING is acknowledged, the close error is returned.
+	// Warning: This API should not be considered stable and might change soon.
+	SendPing(context.Context) (time.Duration, error)
+	// RemoteTransportParameters returns the transport parameters sent by the peer.
+	// It returns an error if the handshake hasn't completed yet.
+	// Warning: This API should not be considered stable and might change soon.
+	RemoteTransportParameters() (*TransportParameters, error)
+	// UsedRetry says if the client's address was validated using a Retry packet.
+	// For servers, this is the case if the client presented a token that was sent in a Retry packet,
+	// as opposed to a token from a previous connection (or no token at all).
+	// For clients, this is the case if the server sent a Retry packet.
+	// Warning: This API should not be considered stable and might change soon.
+	UsedRetry() bool
+	// HandshakeTiming returns when the phases of the handshake happened.
+	// It can be called at any time, events that didn't happen yet are reported as zero times.
+	// Warning: This API should not be considered stable and might change soon.
+	HandshakeTiming() HandshakeTiming
+	// SetMaxSendRate limits the rate at which this session sends data, in bytes per second.
+	// Packets are paced such that they are sent no faster than the smaller of this rate
+	// and the rate allowed by the congestion controller.
+	// A value of 0 removes the limit.
+	// Warning: This API should not be considered stable and might change soon.
+	SetMaxSendRate(bytesPerSecond uint64)
+	// PacingInterval returns the time between two packets that the pacer currently uses.
+	// It is calculated from the congestion window and the smoothed RTT when a packet is sent,
+	// and takes the limit set by SetMaxSendRate into account.
+	// It is 0 if packets can be sent without delay, e.g. before an RTT sample was taken.
+	// Warning: This API should not be considered stable and might change soon.
+	PacingInterval() time.Duration
+	// InitialRTT returns the RTT estimate that was used before the first RTT sample was taken.
+	// This is the RTT restored from the session ticket, if any, Config.InitialRTT otherwise,
+	// or 100ms if neither is available.
+	// Warning: This API should not be considered stable and might change soon.
+	InitialRTT() time.Duration
+	// InitiateKeyUpdate updates the 1-RTT keys.
+	// The new keys are used for the next packet sent, as soon as the peer acknowledged
+	// a packet sent with the current keys (this is required by the QUIC specification).
+	// Key updates, both initiated by us and by the peer, are reported to a quictrace.KeyUpdateTracer.
+	// It returns an error if the handshake is not confirmed yet.
+	// Warning: This API should not be considered stable and might change soon.
+	InitiateKeyUpdate() error
 }
 
 // An EarlySession is a session that is handshaking.
@@ -200,14 +545,62 @@ type EarlySession interface {
 	// Data sent before completion of the handshake is encrypted with 1-RTT keys.
 	// Note that the client's identity hasn't been verified yet.
 	HandshakeComplete() context.Context
+	// HandshakeConfirmed returns a channel that is closed when the handshake is confirmed.
+	// This happens after completion of the handshake: a server confirms the handshake when it completes,
+	// a client when it receives the server's HANDSHAKE_DONE frame.
+	// If the session is closed before, the channel is never closed.
+	// Warning: This API should not be considered stable and might change soon.
+	HandshakeConfirmed() <-chan struct{}
+	// ZeroRTTRejected says if the server rejected 0-RTT.
+	// This is only meaningful for clients, after the handshake completed.
+	// Data that was sent in 0-RTT packets is retransmitted using 1-RTT keys.
+	// Applications that need to replay requests at 1-RTT (e.g. because they are not idempotent)
+	// should check this after HandshakeComplete() is done.
+	// Warning: This API should not be considered stable and might change soon.
+	ZeroRTTRejected() bool
+	// Used0RTT says if 0-RTT is used on this session.
+	// For clients, this is the case if the transport parameters were restored from a session ticket
+	// and 0-RTT packets were sent, as long as the server didn't reject 0-RTT.
+	// For servers, this is the case if 0-RTT was accepted.
+	// Warning: This API should not be considered stable and might change soon.
+	Used0RTT() bool
+	// EarlyTransportParameters returns the peer's transport parameters that apply to data sent before the handshake completes.
+	// When using 0-RTT, a client uses the parameters remembered from the previous connection
+	// until it receives the server's transport parameters.
+	// Only the flow control and stream limits are remembered, all other values are zero.
+	// Once the handshake completes, it returns the parameters negotiated on this connection,
+	// which might differ from the remembered values, if 0-RTT was rejected.
+	// It returns nil if no transport parameters are available yet.
+	// Warning: This API should not be considered stable and might change soon.
+	EarlyTransportParameters() *TransportParameters
+	// OpenStreamWithData opens a new bidirectional stream, queues data for sending on it,
+	// and closes the send direction of the stream.
+	// Unlike Write, it doesn't block until the data was sent.
+	// When called from the Config.OnEarlySession callback, the data is sent in the client's first flight,
+	// in 0-RTT packets directly following the Initial packet carrying the ClientHello.
+	// Warning: This API should not be considered stable and might change soon.
+	OpenStreamWithData(data []byte) (Stream, error)
 }
 
 // Config contains all configuration data needed for a QUIC server or client.
 type Config struct {
 	// The QUIC versions that can be negotiated.
 	// If not set, it uses all versions available.
+	// QUIC version 2 (RFC 9369, 0x6b3343cf) is only used if it is listed here.
+	// For this version, the transport parameters are sent in the TLS extension of RFC 9001 (0x39),
+	// and the connection IDs used during the handshake are authenticated as described in RFC 9000.
 	// Warning: This API should not be considered stable and will change soon.
 	Versions []VersionNumber
+	// VersionNegotiation is called by the client when it receives a Version Negotiation packet.
+	// It is passed the versions configured in Versions, and the versions offered by the server,
+	// and returns the version that the client uses for the new connection attempt.
+	// The version must be one of the configured Versions.
+	// If it returns an error, the connection attempt fails with this error.
+	// If not set, the first of the configured Versions that is offered by the server is used.
+	// If there's no such version, dialing fails with a VersionNegotiationError.
+	// This option is only valid for the client.
+	// Warning: This API should not be considered stable and might change soon.
+	VersionNegotiation func(ours, theirs []VersionNumber) (VersionNumber, error)
 	// The length of the connection ID in bytes.
 	// It can be 0, or any value between 4 and 18.
 	// If not set, the interpretation depends on where the Config is used:
@@ -215,6 +608,14 @@ type Config struct {
 	// If used for a server, or dialing on a packet conn, a 4 byte connection ID will be used.
 	// When dialing on a packet conn, the ConnectionIDLength value must be the same for every Dial call.
 	ConnectionIDLength int
+	// ZeroLengthConnectionIDs makes us use zero-length connection IDs, also when the Config is used for a server,
+	// or for dialing on a packet conn. ConnectionIDLength must not be set then.
+	// Packets are then routed to connections by the packet conn they are received on,
+	// so the packet conn can only be used for a single connection at a time:
+	// a server refuses new connections while a connection is active.
+	// This can be useful for dedicated sockets, e.g. for peer-to-peer connections.
+	// Warning: This API should not be considered stable and might change soon.
+	ZeroLengthConnectionIDs bool
 	// HandshakeTimeout is the maximum duration that the cryptographic handshake may take.
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 10 seconds.
@@ -225,6 +626,11 @@ type Config struct {
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 30 seconds.
 	MaxIdleTimeout time.Duration
+	// MaxConnectionDuration is the maximum lifetime of a connection, independent of the activity on it.
+	// When it is reached, the connection is closed with a CONNECTION_CLOSE frame with a NO_ERROR error code.
+	// If this value is zero, the lifetime of connections is not limited.
+	// Warning: This API should not be considered stable and might change soon.
+	MaxConnectionDuration time.Duration
 	// AcceptToken determines if a Token is accepted.
 	// It is called with token = nil if the client didn't send a token.
 	// If not set, a default verification function is used:
@@ -238,12 +644,40 @@ type Config struct {
 	// The key used to store tokens is the ServerName from the tls.Config, if set
 	// otherwise the token is associated with the server's IP address.
 	TokenStore TokenStore
+	// TokenGenerator generates the tokens sent in Retry packets and NEW_TOKEN frames,
+	// and decodes the tokens received in Initial packets.
+	// If not set, a built-in token format is used.
+	// This option is only valid for the server.
+	TokenGenerator TokenGenerator
+	// DisableSNI prevents the client from using the host (or the address) it dials as the server name.
+	// If the tls.Config.ServerName is empty, the ClientHello then doesn't contain a server_name extension.
+	// Note that the server's certificate can only be verified against a server name,
+	// so either tls.Config.InsecureSkipVerify needs to be set, or the verification
+	// has to be performed in tls.Config.VerifyPeerCertificate.
+	// This option is only valid for the client.
+	DisableSNI bool
 	// MaxReceiveStreamFlowControlWindow is the maximum stream-level flow control window for receiving data.
 	// If this value is zero, it will default to 1 MB for the server and 6 MB for the client.
 	MaxReceiveStreamFlowControlWindow uint64
 	// MaxReceiveConnectionFlowControlWindow is the connection-level flow control window for receiving data.
 	// If this value is zero, it will default to 1.5 MB for the server and 15 MB for the client.
 	MaxReceiveConnectionFlowControlWindow uint64
+	// MaxConnectionReceiveBuffer limits the amount of data that was received on all streams of a connection,
+	// but not read by the application yet.
+	// It is enforced by the connection-level flow control window: both the initial window and the window auto-tuning are capped,
+	// so the peer is only granted more credit (using MAX_DATA frames) as the application reads data.
+	// This is stricter than MaxReceiveConnectionFlowControlWindow, which doesn't apply to the initial window.
+	// If not set, the amount of buffered data is only limited by the connection-level flow control window.
+	// Warning: This API should not be considered stable and might change soon.
+	MaxConnectionReceiveBuffer uint64
+	// MaxStreamReassemblyBuffer limits how much data received out of order is buffered on a single stream.
+	// Out-of-order data is held until the gaps before it are filled, which is bounded by the stream-level flow control window.
+	// It is enforced by capping both the initial stream-level flow control window and MaxReceiveStreamFlowControlWindow,
+	// so a peer that stays within the advertised window can never exceed it.
+	// Setting this value too low limits the throughput of a single stream.
+	// If not set, the amount of out-of-order data is only limited by the flow control window.
+	// Warning: This API should not be considered stable and might change soon.
+	MaxStreamReassemblyBuffer uint64
 	// MaxIncomingStreams is the maximum number of concurrent bidirectional streams that a peer is allowed to open.
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any bidirectional streams.
@@ -252,9 +686,223 @@ type Config struct {
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any unidirectional streams.
 	MaxIncomingUniStreams int
+	// MaxIncomingStreamsLimit is the maximum number of concurrent streams (for each stream type)
+	// that Session.AllowMoreIncomingStreams can raise the stream limits to.
+	// If not set, the limits can be raised up to 2^60 streams, the maximum allowed by the protocol.
+	// Warning: This API should not be considered stable and might change soon.
+	MaxIncomingStreamsLimit uint64
+	// ActiveConnectionIDLimit is the number of connection IDs issued by the peer that we're willing to store.
+	// It is sent to the peer in the active_connection_id_limit transport parameter.
+	// If not set, it will default to 4.
+	// Values smaller than 2 are increased to 2, the minimum value allowed by the QUIC specification.
+	ActiveConnectionIDLimit uint64
+	// MaxRetiredConnectionIDs is the maximum number of retired connection IDs
+	// for which the RETIRE_CONNECTION_ID frame hasn't been acknowledged yet.
+	// This prevents a peer from flooding us with NEW_CONNECTION_ID frames that retire previously issued connection IDs.
+	// If a NEW_CONNECTION_ID frame exceeds this limit, the connection is closed with a CONNECTION_ID_LIMIT_ERROR.
+	// If not set, it will default to 4 times the ActiveConnectionIDLimit.
+	// Values smaller than twice the ActiveConnectionIDLimit are increased to twice the ActiveConnectionIDLimit.
+	MaxRetiredConnectionIDs uint64
+	// MaxUDPPayloadSize is the maximum size of UDP payloads that we're willing to receive.
+	// It is sent to the peer in the max_packet_size transport parameter,
+	// and packets exceeding this size are dropped.
+	// It must be at least 1200 bytes. Values larger than 1452 bytes are reduced to 1452 bytes.
+	// If not set, it will default to 1452 bytes.
+	MaxUDPPayloadSize uint64
+	// InitialMaxPacketSize is the maximum size of the packets that we send.
+	// By default, the maximum packet size is chosen conservatively, such that packets are not fragmented on common paths.
+	// It can be set to send larger packets right from the start of the connection, e.g. on networks using jumbo frames.
+	// This doesn't perform any MTU discovery: the path must be able to transmit packets of this size.
+	// After the handshake, the packet size is limited to the peer's max_packet_size transport parameter.
+	// Note that quic-go itself only receives packets up to 1452 bytes.
+	// It must be at least 1200 bytes, and at most 65527 bytes.
+	InitialMaxPacketSize uint64
+	// MaxAckDelay is the maximum time by which we delay sending ACKs.
+	// It is sent to the peer in the max_ack_delay transport parameter (increased by the timer granularity).
+	// It must be smaller than 16.383 seconds.
+	// If not set, it will default to 25 ms.
+	MaxAckDelay time.Duration
+	// AckDelayExponent is the exponent used to encode the ACK delay in the ACK frames we send.
+	// It is sent to the peer in the ack_delay_exponent transport parameter.
+	// It must not be larger than 20.
+	// If not set, it will default to 3.
+	// If set to a negative value, an exponent of 0 is used.
+	AckDelayExponent int
+	// DisableDelayedAcks makes us acknowledge every ack-eliciting packet immediately,
+	// instead of coalescing acknowledgements for multiple packets and delaying them by up to MaxAckDelay.
+	// This keeps the peer's RTT estimate and loss detection as fresh as possible,
+	// at the cost of sending more ACK frames.
+	// Warning: This API should not be considered stable and might change soon.
+	DisableDelayedAcks bool
+	// EnableACKFrequency enables the ACK frequency extension (draft-iyengar-quic-delayed-ack).
+	// We then advertise the min_ack_delay transport parameter, and honor the ACK frequency requested by the peer.
+	// If the peer supports the extension as well, we ask it to only acknowledge every 10th ack-eliciting packet
+	// once the handshake completes, reducing the number of ACKs it sends.
+	// Warning: This API should not be considered stable and might change soon.
+	EnableACKFrequency bool
+	// CongestionControl is the congestion control algorithm used to send packets.
+	// If not set, it will default to NewReno with HyStart++.
+	CongestionControl CongestionControl
+	// DisableHyStart disables HyStart++.
+	// By default, the congestion controller uses HyStart++ to exit slow start when the RTT increases:
+	// it then grows the congestion window more slowly for a few round trips, and exits slow start
+	// if the RTT increase persists. This reduces the number of packets lost due to overshooting in slow start.
+	// If set, hybrid slow start is used instead.
+	// It has no effect when using CongestionControlReno, which only exits slow start on packet loss.
+	// Warning: This API should not be considered stable and might change soon.
+	DisableHyStart bool
+	// MinCongestionWindow is the minimum congestion window, in bytes.
+	// The congestion window is never reduced below this value, neither on packet loss nor on a retransmission timeout.
+	// This can improve throughput on paths that are known to be lossy (e.g. cellular links),
+	// at the cost of being less fair to other flows.
+	// It must not be larger than the maximum congestion window (10000 packets).
+	// If not set, it will default to 2 packets.
+	// Warning: This API should not be considered stable and might change soon.
+	MinCongestionWindow uint64
+	// MinRTT and MaxRTT bound the RTT used to calculate the probe timeout (PTO).
+	// This limits the effect of stale RTT estimates, e.g. after the path changed.
+	// If not set, the RTT isn't bounded.
+	// If both are set, MaxRTT must not be smaller than MinRTT.
+	// Warning: This API should not be considered stable and might change soon.
+	MinRTT time.Duration
+	MaxRTT time.Duration
+	// InitialRTT is the RTT estimate used before the first RTT sample is taken.
+	// Setting it on paths with a known RTT avoids a slow first probe timeout (PTO).
+	// When resuming a session, the RTT restored from the session ticket takes precedence.
+	// If set, it must be between 1ms and 10s. If not set, it will default to 100ms.
+	// Warning: This API should not be considered stable and might change soon.
+	InitialRTT time.Duration
+	// DisableVersionNegotiationPackets disables the sending of Version Negotiation packets.
+	// Packets with an unsupported version are then dropped silently.
+	// This can be useful if all clients are known to support one of the configured versions.
+	// This option is only valid for the server.
+	DisableVersionNegotiationPackets bool
+	// RefuseConnection is called when the server refuses a new connection because the accept queue is full,
+	// or because MaxActiveSessions was reached.
+	// It returns the error code and the reason phrase that are sent to the client in the CONNECTION_CLOSE frame.
+	// If not set, connections are refused with a CONNECTION_REFUSED error and an empty reason phrase.
+	// This option is only valid for the server.
+	RefuseConnection func(clientAddr net.Addr) (TransportErrorCode, string)
+	// OnNewConnection is called when the server receives an Initial packet for a new connection,
+	// before any state is allocated for the connection (and before AcceptToken is called).
+	// If it returns an error, the connection attempt is refused.
+	// If the error is an Error with a transport error code, the error code and error message are sent to the client
+	// in the CONNECTION_CLOSE frame. Otherwise, a CONNECTION_REFUSED error is sent, using the error string as the reason phrase.
+	// This option is only valid for the server.
+	// Warning: This API should not be considered stable and might change soon.
+	OnNewConnection func(clientAddr net.Addr, hdr *InitialHeader) error
+	// ChooseConnectionID is called when the server creates a new session.
+	// It is passed the header of the client's Initial packet, and returns the connection ID that the server uses for this session,
+	// i.e. the source connection ID of the first packet the server sends.
+	// The connection ID must be ConnectionIDLength bytes long.
+	// If it returns an error, or a connection ID of the wrong length, the Initial packet is dropped.
+	// Connection IDs issued later in NEW_CONNECTION_ID frames are still chosen randomly.
+	// If not set, a random connection ID is used.
+	// This option is only valid for the server.
+	// Warning: This API should not be considered stable and might change soon.
+	ChooseConnectionID func(clientAddr net.Addr, hdr *InitialHeader) (ConnectionID, error)
+	// ChooseRetryConnectionID is called when the server sends a Retry packet.
+	// It is passed the destination connection ID of the client's Initial packet, and returns the source connection ID of the Retry,
+	// which the client then uses as the destination connection ID of its next Initial packet.
+	// This allows deriving the connection ID, such that the follow-up Initial can be routed without sharing state.
+	// The connection ID must be ConnectionIDLength bytes long, and it must differ from origDestConnID.
+	// If it returns an error, or an invalid connection ID, no Retry is sent.
+	// If not set, a random connection ID is used.
+	// This option is only valid for the server.
+	// Warning: This API should not be considered stable and might change soon.
+	ChooseRetryConnectionID func(clientAddr net.Addr, origDestConnID ConnectionID) (ConnectionID, error)
+	// NumSessionTickets is the number of session tickets the server sends after the handshake completes.
+	// A client can use a different ticket for every connection it resumes.
+	// To disable session tickets, set tls.Config.SessionTicketsDisabled.
+	// If not set, a single session ticket is sent.
+	// This option is only valid for the server.
+	// Warning: This API should not be considered stable and might change soon.
+	NumSessionTickets int
+	// MaxIncomingPacketQueue is the number of packets that the server queues before processing them.
+	// This only applies to packets that don't belong to an existing session (e.g. Initial packets).
+	// Packets received while the queue is full are dropped, so that reading from the socket never blocks.
+	// If not set, it will default to 1000.
+	// This option is only valid for the server.
+	MaxIncomingPacketQueue int
+	// MaxActiveSessions is the maximum number of sessions that the server handles at the same time.
+	// This includes sessions that are still handshaking, and sessions that weren't accepted yet.
+	// When it is reached, new connection attempts are refused, as if the accept queue was full (see RefuseConnection).
+	// If not set, the number of sessions is not limited.
+	// This option is only valid for the server.
+	// Warning: This API should not be considered stable and might change soon.
+	MaxActiveSessions int
 	// The StatelessResetKey is used to generate stateless reset tokens.
 	// If no key is configured, sending of stateless resets is disabled.
 	StatelessResetKey []byte
+	// EnableActiveMigration allows the peer to migrate the connection to a new address.
+	// If not set, the disable_active_migration transport parameter is sent,
+	// telling the peer that it must not migrate the connection.
+	// A server then drops packets arriving from a new client address.
+	// If set, a server validates a new client address before migrating the connection to it.
+	// quic-go never initiates a migration itself, so it always respects the peer's disable_active_migration parameter.
+	EnableActiveMigration bool
+	// SocketControl is called on the UDP socket created by DialAddr and ListenAddr (and their variants),
+	// after the socket was created, but before it is bound.
+	// This allows setting socket options, e.g. SO_MARK, SO_BINDTODEVICE, SO_RCVBUF or SO_SNDBUF on Linux.
+	// See net.ListenConfig.Control for details.
+	// It is not used for the net.PacketConn passed to Dial and Listen (and their variants).
+	// Warning: This API should not be considered stable and might change soon.
+	SocketControl func(network, address string, c syscall.RawConn) error
+	// DisableWriteRetries disables retrying to send a packet when writing to the socket fails with a transient error
+	// (ENOBUFS or EAGAIN, e.g. when the socket's send buffer is full).
+	// By default, the write is retried a few times with an exponential backoff,
+	// and the connection is only closed if it still fails.
+	// Any other write error closes the connection immediately.
+	// Warning: This API should not be considered stable and might change soon.
+	DisableWriteRetries bool
+	// DSCP is the Differentiated Services Code Point that outgoing packets are marked with (e.g. 46 for Expedited Forwarding).
+	// It is set on the socket (using the IPv4 TOS and the IPv6 Traffic Class field), so it applies to all connections using that socket.
+	// Valid values are 0 to 63. If not set, the socket is not modified.
+	// It is an error to set it if the net.PacketConn is not a *net.UDPConn.
+	// Warning: This API should not be considered stable and might change soon.
+	DSCP int
+	// When setting up a socket created by DialAddr or ListenAddr, quic-go tries to increase its receive buffer,
+	// since a small receive buffer leads to packet loss under load.
+	// If the receive buffer can't be increased sufficiently, an error is logged.
+	// The receive buffer of a net.PacketConn passed to Dial or Listen, and of a socket configured using SocketControl,
+	// is not modified, but an error is logged if it is too small.
+	// DisableReceiveBufferWarning disables logging this error.
+	// Listener.ReceiveBufferWarning can be used to check the receive buffer programmatically.
+	// On Linux, the maximum receive buffer size can be increased using the net.core.rmem_max sysctl.
+	// Warning: This API should not be considered stable and might change soon.
+	DisableReceiveBufferWarning bool
+	// OnPathChange is called when the client's address changes.
+	// When a packet is received from a new address, the server validates the new path by sending a PATH_CHALLENGE.
+	// If the client responds, the connection is migrated to the new address, and OnPathChange is called with validated set to true.
+	// If the path validation fails, the connection continues to use the old address, and OnPathChange is called with validated set to false.
+	// It is only called if EnableActiveMigration is set.
+	// OnPathChange is called from the session's run loop, and must not block.
+	// This option is only valid for the server.
+	OnPathChange func(oldAddr, newAddr net.Addr, validated bool)
+	// OnEarlySession is called when the client uses 0-RTT, after the session was resumed,
+	// but before the first packet is sent.
+	// This allows sending a request in the first flight, using EarlySession.OpenStreamWithData.
+	// Data written after DialEarly returned might be sent in a later flight.
+	// OnEarlySession is called from the session's run loop, and must not block.
+	// This option is only valid for the client.
+	// Warning: This API should not be considered stable and might change soon.
+	OnEarlySession func(EarlySession)
+	// OnFlowControlBlocked is called every time a DATA_BLOCKED or STREAM_DATA_BLOCKED frame is sent,
+	// i.e. when there's data to send, but the peer's flow control window is exhausted.
+	// For connection-level flow control, connectionLevel is true, and streamID is 0.
+	// Otherwise, streamID is the stream that is blocked.
+	// limit is the flow control limit that was hit.
+	// OnFlowControlBlocked is called from the session's run loop, and must not block.
+	// Warning: This API should not be considered stable and might change soon.
+	OnFlowControlBlocked func(connectionLevel bool, streamID StreamID, limit uint64)
+	// TransportParametersHook is called with the transport parameters we're about to send to the peer,
+	// after they were populated from this Config, and right before they are marshaled into the handshake.
+	// Fields modified by the hook are sent on the wire as is, without any validation.
+	// The modified values are only advertised to the peer: this endpoint keeps behaving according to the Config.
+	// This allows sending values that violate the protocol, or that this endpoint doesn't enforce.
+	// It is intended for testing and research, and should not be used in production.
+	// Warning: This API should not be considered stable and might change soon.
+	TransportParametersHook func(*TransportParameters)
 	// KeepAlive defines whether this peer will periodically send a packet to keep the connection alive.
 	KeepAlive bool
 	// QUIC Event Tracer.
@@ -262,14 +910,67 @@ type Config struct {
 	QuicTracer quictrace.Tracer
 }
 
+// BufferPoolStats are statistics about the pool of buffers used for sending and receiving packets.
+// They are only collected after calling EnableBufferPoolStats.
+// Warning: This API should not be considered stable and might change soon.
+type BufferPoolStats struct {
+	// InUse is the number of buffers that are currently in use, i.e. that were not released yet.
+	// A number that keeps growing indicates a buffer leak.
+	InUse int64
+	// Allocations is the number of buffers that were allocated.
+	// This includes buffers for packets that are too large for the pool, which are never reused.
+	Allocations uint64
+	// Hits is the number of times a buffer was reused from the pool.
+	Hits uint64
+	// Misses is the number of times a buffer had to be allocated, because the pool was empty.
+	Misses uint64
+}
+
+// ServerStats are statistics about the connection attempts a server handled.
+type ServerStats struct {
+	// SessionsAccepted is the number of connection attempts that a session was created for.
+	SessionsAccepted uint64
+	// SessionsRefused is the number of connection attempts that were refused, because the server was busy.
+	SessionsRefused uint64
+	// ActiveSessions is the number of sessions that were created and haven't been closed yet.
+	ActiveSessions int
+	// RetriesSent is the number of Retry packets sent.
+	RetriesSent uint64
+	// VersionNegotiationPacketsSent is the number of Version Negotiation packets sent.
+	VersionNegotiationPacketsSent uint64
+	// MalformedPacketsDropped is the number of packets that were dropped because they couldn't be parsed.
+	MalformedPacketsDropped uint64
+	// DroppedPackets is the number of packets that were dropped because the packet queue was full.
+	DroppedPackets uint64
+}
+
 // A Listener for incoming QUIC connections
 type Listener interface {
 	// Close the server. All active sessions will be closed.
 	Close() error
 	// Addr returns the local network addr that the server is listening on.
+	// This is the address the socket is bound to.
+	// A socket bound to an IPv4 address only receives IPv4 packets.
+	// A socket bound to an IPv6 address may also receive IPv4 packets, see DualStack.
 	Addr() net.Addr
+	// DualStack says if the server's socket is an IPv6 socket that also accepts IPv4 packets,
+	// as is the case when listening on an unspecified address (like ":443") on most platforms.
+	// It returns false if the socket's address family can't be determined.
+	// Warning: This API should not be considered stable and might change soon.
+	DualStack() bool
 	// Accept returns new sessions. It should be called in a loop.
 	Accept(context.Context) (Session, error)
+	// DroppedPackets returns the number of packets that were dropped because the packet queue was full.
+	// Warning: This API should not be considered stable and might change soon.
+	DroppedPackets() uint64
+	// Stats returns statistics about the connection attempts the server handled.
+	// Warning: This API should not be considered stable and might change soon.
+	Stats() ServerStats
+	// ReceiveBufferWarning returns an error if the receive buffer of the socket couldn't be
+	// increased to a size that is sufficient for high throughput.
+	// It returns nil if the buffer is large enough, or if the net.PacketConn doesn't expose the socket.
+	// Warning: This API should not be considered stable and might change soon.
+	ReceiveBufferWarning() error
 }
 
 // An EarlyListener listens for incoming QUIC connections,
@@ -278,7 +979,24 @@ type EarlyListener interface {
 	// Close the server. All active sessions will be closed.
 	Close() error
 	// Addr returns the local network addr that the server is listening on.
+	// This is the address the socket is bound to.
+	// A socket bound to an IPv4 address only receives IPv4 packets.
+	// A socket bound to an IPv6 address may also receive IPv4 packets, see DualStack.
 	Addr() net.Addr
+	// DualStack says if the server's socket is an IPv6 socket that also accepts IPv4 packets,
+	// as is the case when listening on an unspecified address (like ":443") on most platforms.
+	// It returns false if the socket's address family can't be determined.
+	// Warning: This API should not be considered stable and might change soon.
+	DualStack() bool
 	// Accept returns new early sessions. It should be called in a loop.
 	Accept(context.Context) (EarlySession, error)
+	// DroppedPackets returns the number of packets that were dropped because the packet queue was full.
+	// Warning: This API should not be considered stable and might change soon.
+	DroppedPackets() uint64
+	// Stats returns statistics about the connection attempts the server handled.
+	// Warning: This API should not be considered stable and might change soon.
+	Stats() ServerStats
+	// ReceiveBufferWarning works like Listener.ReceiveBufferWarning.
+	// Warning: This API should not be considered stable and might change soon.
+	ReceiveBufferWarning() error
 }
@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	"github.com/lucas-clemente/quic-go/quictrace"
@@ -18,6 +19,7 @@ type Packet struct {
 	SendTime        time.Time
 
 	includedInBytesInFlight bool
+	deliveryState           congestion.DeliveryState
 }
 
 // SentPacketHandler handles ACKs received for outgoing packets
@@ -34,6 +36,13 @@ type SentPacketHandler interface {
 	// TimeUntilSend is the time when the next packet should be sent.
 	// It is used for pacing packets.
 	TimeUntilSend() time.Time
+	// PacingInterval is the time between two packets, as determined by the pacer when the last packet was sent.
+	// It may be called concurrently with all other methods.
+	PacingInterval() time.Duration
+	// SetMaxSendRate limits the rate at which packets are sent, in bytes per second.
+	// 0 means that the rate is only limited by the congestion controller.
+	// It may be called concurrently with all other methods.
+	SetMaxSendRate(bytesPerSecond uint64)
 	// ShouldSendNumPackets returns the number of packets that should be sent immediately.
 	// It always returns a number greater or equal than 1.
 	// A number greater than 1 is returned when the pacing delay is smaller than the minimum pacing delay.
@@ -57,9 +66,16 @@ type SentPacketHandler interface {
 
 // ReceivedPacketHandler handles ACKs needed to send for incoming packets
 type ReceivedPacketHandler interface {
+	// IsPotentiallyDuplicate says if a packet might have been received before.
+	// It must be called before ReceivedPacket is called for that packet.
+	IsPotentiallyDuplicate(protocol.PacketNumber, protocol.EncryptionLevel) bool
 	ReceivedPacket(pn protocol.PacketNumber, encLevel protocol.EncryptionLevel, rcvTime time.Time, shouldInstigateAck bool) error
 	IgnoreBelow(protocol.PacketNumber)
 	DropPackets(protocol.EncryptionLevel)
+	// ReceivedAckFrequency applies the parameters requested by the peer in an ACK_FREQUENCY frame.
+	ReceivedAckFrequency(*wire.AckFrequencyFrame)
+	// ReceivedImmediateAck makes sure that an ACK is sent right away.
+	ReceivedImmediateAck()
 
 	GetAlarmTimeout() time.Time
 	GetAckFrame(protocol.EncryptionLevel) *wire.AckFrame
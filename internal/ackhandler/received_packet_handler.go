@@ -44,13 +44,17 @@ var _ ReceivedPacketHandler = &receivedPacketHandler{}
 // NewReceivedPacketHandler creates a new receivedPacketHandler
 func NewReceivedPacketHandler(
 	rttStats *congestion.RTTStats,
+	maxAckDelay time.Duration,
+	ackDelayExponent uint8,
+	disableDelayedAcks bool,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) ReceivedPacketHandler {
 	return &receivedPacketHandler{
-		initialPackets:   newReceivedPacketTracker(rttStats, logger, version),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger, version),
-		appDataPackets:   newReceivedPacketTracker(rttStats, logger, version),
+		// The ACK delay is not used for Initial and Handshake packets.
+		initialPackets:   newReceivedPacketTracker(rttStats, protocol.MaxAckDelay, protocol.AckDelayExponent, disableDelayedAcks, logger, version),
+		handshakePackets: newReceivedPacketTracker(rttStats, protocol.MaxAckDelay, protocol.AckDelayExponent, disableDelayedAcks, logger, version),
+		appDataPackets:   newReceivedPacketTracker(rttStats, maxAckDelay, ackDelayExponent, disableDelayedAcks, logger, version),
 		lowest1RTTPacket: protocol.InvalidPacketNumber,
 	}
 }
@@ -82,11 +86,37 @@ func (h *receivedPacketHandler) ReceivedPacket(
 	return nil
 }
 
+func (h *receivedPacketHandler) IsPotentiallyDuplicate(pn protocol.PacketNumber, encLevel protocol.EncryptionLevel) bool {
+	switch encLevel {
+	case protocol.EncryptionInitial:
+		if h.initialPackets != nil {
+			return h.initialPackets.IsPotentiallyDuplicate(pn)
+		}
+	case protocol.EncryptionHandshake:
+		if h.handshakePackets != nil {
+			return h.handshakePackets.IsPotentiallyDuplicate(pn)
+		}
+	case protocol.Encryption0RTT, protocol.Encryption1RTT:
+		return h.appDataPackets.IsPotentiallyDuplicate(pn)
+	}
+	panic("unexpected encryption level")
+}
+
 // only to be used with 1-RTT packets
 func (h *receivedPacketHandler) IgnoreBelow(pn protocol.PacketNumber) {
 	h.appDataPackets.IgnoreBelow(pn)
 }
 
+// only to be used with 0-RTT and 1-RTT packets
+func (h *receivedPacketHandler) ReceivedAckFrequency(f *wire.AckFrequencyFrame) {
+	h.appDataPackets.SetAckFrequency(f)
+}
+
+// only to be used with 0-RTT and 1-RTT packets
+func (h *receivedPacketHandler) ReceivedImmediateAck() {
+	h.appDataPackets.QueueAck()
+}
+
 func (h *receivedPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 	switch encLevel {
 	case protocol.EncryptionInitial:
@@ -18,6 +18,9 @@ var _ = Describe("Received Packet Handler", func() {
 	BeforeEach(func() {
 		handler = NewReceivedPacketHandler(
 			&congestion.RTTStats{},
+			protocol.MaxAckDelay,
+			protocol.AckDelayExponent,
+			false,
 			utils.DefaultLogger,
 			protocol.VersionWhatever,
 		)
@@ -92,6 +95,20 @@ var _ = Describe("Received Packet Handler", func() {
 		Expect(handler.GetAckFrame(protocol.Encryption1RTT)).ToNot(BeNil())
 	})
 
+	It("detects duplicates for the packet number space of the encryption level", func() {
+		sendTime := time.Now()
+		Expect(handler.ReceivedPacket(2, protocol.EncryptionInitial, sendTime, true)).To(Succeed())
+		Expect(handler.ReceivedPacket(3, protocol.EncryptionHandshake, sendTime, true)).To(Succeed())
+		Expect(handler.ReceivedPacket(4, protocol.Encryption0RTT, sendTime, true)).To(Succeed())
+		Expect(handler.IsPotentiallyDuplicate(2, protocol.EncryptionInitial)).To(BeTrue())
+		Expect(handler.IsPotentiallyDuplicate(2, protocol.EncryptionHandshake)).To(BeFalse())
+		Expect(handler.IsPotentiallyDuplicate(3, protocol.EncryptionHandshake)).To(BeTrue())
+		Expect(handler.IsPotentiallyDuplicate(3, protocol.Encryption1RTT)).To(BeFalse())
+		Expect(handler.IsPotentiallyDuplicate(4, protocol.Encryption1RTT)).To(BeTrue())
+		Expect(handler.IsPotentiallyDuplicate(4, protocol.Encryption0RTT)).To(BeTrue())
+		Expect(handler.IsPotentiallyDuplicate(4, protocol.EncryptionInitial)).To(BeFalse())
+	})
+
 	It("does nothing when droping 0-RTT packets", func() {
 		handler.DropPackets(protocol.Encryption0RTT)
 	})
@@ -246,4 +246,51 @@ var _ = Describe("receivedPacketHistory", func() {
 			Expect(hist.GetHighestAckRange()).To(Equal(wire.AckRange{Smallest: 6, Largest: 7}))
 		})
 	})
+
+	Context("duplicate detection", func() {
+		It("doesn't declare the first packet a duplicate", func() {
+			Expect(hist.IsPotentiallyDuplicate(5)).To(BeFalse())
+		})
+
+		It("detects a duplicate in a range", func() {
+			hist.ReceivedPacket(4)
+			hist.ReceivedPacket(5)
+			hist.ReceivedPacket(6)
+			Expect(hist.IsPotentiallyDuplicate(3)).To(BeFalse())
+			Expect(hist.IsPotentiallyDuplicate(4)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(5)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(6)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(7)).To(BeFalse())
+		})
+
+		It("detects a duplicate in multiple ranges", func() {
+			hist.ReceivedPacket(4)
+			hist.ReceivedPacket(5)
+			hist.ReceivedPacket(8)
+			hist.ReceivedPacket(9)
+			Expect(hist.IsPotentiallyDuplicate(3)).To(BeFalse())
+			Expect(hist.IsPotentiallyDuplicate(4)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(5)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(6)).To(BeFalse())
+			Expect(hist.IsPotentiallyDuplicate(7)).To(BeFalse())
+			Expect(hist.IsPotentiallyDuplicate(8)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(9)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(10)).To(BeFalse())
+		})
+
+		It("says a packet is a potentially duplicate if the ranges were already deleted", func() {
+			hist.ReceivedPacket(4)
+			hist.ReceivedPacket(5)
+			hist.ReceivedPacket(8)
+			hist.ReceivedPacket(9)
+			hist.ReceivedPacket(11)
+			hist.DeleteBelow(8)
+			Expect(hist.IsPotentiallyDuplicate(3)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(7)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(8)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(10)).To(BeFalse())
+			Expect(hist.IsPotentiallyDuplicate(11)).To(BeTrue())
+			Expect(hist.IsPotentiallyDuplicate(12)).To(BeFalse())
+		})
+	})
 })
@@ -16,8 +16,14 @@ type receivedPacketTracker struct {
 
 	packetHistory *receivedPacketHistory
 
-	maxAckDelay time.Duration
-	rttStats    *congestion.RTTStats
+	maxAckDelay      time.Duration
+	ackDelayExponent uint8
+	rttStats         *congestion.RTTStats
+	// if set, every ack-eliciting packet is acknowledged immediately
+	disableDelayedAcks bool
+
+	// the last ACK_FREQUENCY frame applied, if any
+	ackFrequency *wire.AckFrequencyFrame
 
 	packetsReceivedSinceLastAck             int
 	ackElicitingPacketsReceivedSinceLastAck int
@@ -32,15 +38,20 @@ type receivedPacketTracker struct {
 
 func newReceivedPacketTracker(
 	rttStats *congestion.RTTStats,
+	maxAckDelay time.Duration,
+	ackDelayExponent uint8,
+	disableDelayedAcks bool,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
-		maxAckDelay:   protocol.MaxAckDelay,
-		rttStats:      rttStats,
-		logger:        logger,
-		version:       version,
+		packetHistory:      newReceivedPacketHistory(),
+		maxAckDelay:        maxAckDelay,
+		ackDelayExponent:   ackDelayExponent,
+		disableDelayedAcks: disableDelayedAcks,
+		rttStats:           rttStats,
+		logger:             logger,
+		version:            version,
 	}
 }
 
@@ -72,6 +83,34 @@ func (h *receivedPacketTracker) IgnoreBelow(p protocol.PacketNumber) {
 	}
 }
 
+// IsPotentiallyDuplicate says if a packet with packet number p might have been received before.
+func (h *receivedPacketTracker) IsPotentiallyDuplicate(p protocol.PacketNumber) bool {
+	return h.packetHistory.IsPotentiallyDuplicate(p)
+}
+
+// SetAckFrequency applies the parameters of an ACK_FREQUENCY frame.
+// Frames that are not newer than the last frame applied are ignored.
+func (h *receivedPacketTracker) SetAckFrequency(f *wire.AckFrequencyFrame) {
+	if h.ackFrequency != nil && f.SequenceNumber <= h.ackFrequency.SequenceNumber {
+		return
+	}
+	h.ackFrequency = f
+	h.maxAckDelay = f.UpdateMaxAckDelay
+	if h.logger.Debug() {
+		h.logger.Debugf("\tUsing ACK frequency requested by the peer: packet tolerance %d, max ack delay %s, ignore order: %t", f.PacketTolerance, f.UpdateMaxAckDelay, f.IgnoreOrder)
+	}
+}
+
+// QueueAck makes sure that an ACK is sent with the next packet.
+func (h *receivedPacketTracker) QueueAck() {
+	h.ackQueued = true
+	h.ackAlarm = time.Time{}
+}
+
+func (h *receivedPacketTracker) ignoreOrder() bool {
+	return h.ackFrequency != nil && h.ackFrequency.IgnoreOrder
+}
+
 // isMissing says if a packet was reported missing in the last ACK.
 func (h *receivedPacketTracker) isMissing(p protocol.PacketNumber) bool {
 	if h.lastAck == nil || p < h.ignoreBelow {
@@ -104,7 +143,7 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 	// Send an ACK if this packet was reported missing in an ACK sent before.
 	// Ack decimation with reordering relies on the timer to send an ACK, but if
 	// missing packets we reported in the previous ack, send an ACK immediately.
-	if wasMissing {
+	if wasMissing && !h.ignoreOrder() {
 		if h.logger.Debug() {
 			h.logger.Debugf("\tQueueing ACK because packet %#x was missing before.", packetNumber)
 		}
@@ -114,7 +153,23 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 	if !h.ackQueued && shouldInstigateAck {
 		h.ackElicitingPacketsReceivedSinceLastAck++
 
-		if packetNumber > minReceivedBeforeAckDecimation {
+		if h.disableDelayedAcks {
+			h.logger.Debugf("\tQueueing ACK because delayed ACKs are disabled.")
+			h.ackQueued = true
+		} else if h.ackFrequency != nil {
+			// use the packet tolerance and max ack delay requested by the peer
+			if h.ackElicitingPacketsReceivedSinceLastAck >= int(h.ackFrequency.PacketTolerance) {
+				h.ackQueued = true
+				if h.logger.Debug() {
+					h.logger.Debugf("\tQueueing ACK because %d packets were received after the last ACK (using requested packet tolerance: %d).", h.ackElicitingPacketsReceivedSinceLastAck, h.ackFrequency.PacketTolerance)
+				}
+			} else if h.ackAlarm.IsZero() {
+				if h.logger.Debug() {
+					h.logger.Debugf("\tSetting ACK timer to requested max ack delay: %s", h.maxAckDelay)
+				}
+				h.ackAlarm = rcvTime.Add(h.maxAckDelay)
+			}
+		} else if packetNumber > minReceivedBeforeAckDecimation {
 			// ack up to 10 packets at once
 			if h.ackElicitingPacketsReceivedSinceLastAck >= ackElicitingPacketsBeforeAck {
 				h.ackQueued = true
@@ -144,7 +199,7 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 			}
 		}
 		// If there are new missing packets to report, set a short timer to send an ACK.
-		if h.hasNewMissingPackets() {
+		if !h.ignoreOrder() && h.hasNewMissingPackets() {
 			// wait the minimum of 1/8 min RTT and the existing ack time
 			ackDelay := time.Duration(float64(h.rttStats.MinRTT()) * float64(shortAckDecimationDelay))
 			ackTime := rcvTime.Add(ackDelay)
@@ -176,7 +231,8 @@ func (h *receivedPacketTracker) GetAckFrame() *wire.AckFrame {
 		AckRanges: h.packetHistory.GetAckRanges(),
 		// Make sure that the DelayTime is always positive.
 		// This is not guaranteed on systems that don't have a monotonic clock.
-		DelayTime: utils.MaxDuration(0, now.Sub(h.largestObservedReceivedTime)),
+		DelayTime:     utils.MaxDuration(0, now.Sub(h.largestObservedReceivedTime)),
+		DelayExponent: h.ackDelayExponent,
 	}
 
 	h.lastAck = ack
@@ -20,7 +20,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &congestion.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger, protocol.VersionWhatever)
+		tracker = newReceivedPacketTracker(rttStats, protocol.MaxAckDelay, protocol.AckDelayExponent, false, utils.DefaultLogger, protocol.VersionWhatever)
 	})
 
 	Context("accepting packets", func() {
@@ -121,6 +121,31 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(protocol.MaxAckDelay)))
 			})
 
+			It("delays the ACK up to the configured max_ack_delay", func() {
+				tracker = newReceivedPacketTracker(rttStats, 100*time.Millisecond, protocol.AckDelayExponent, false, utils.DefaultLogger, protocol.VersionWhatever)
+				receiveAndAck10Packets()
+				rcvTime := time.Now()
+				tracker.ReceivedPacket(11, rcvTime, true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(100 * time.Millisecond)))
+				Expect(tracker.GetAckFrame()).To(BeNil())
+			})
+
+			It("acknowledges every ack-eliciting packet immediately when delayed ACKs are disabled", func() {
+				tracker = newReceivedPacketTracker(rttStats, protocol.MaxAckDelay, protocol.AckDelayExponent, true, utils.DefaultLogger, protocol.VersionWhatever)
+				receiveAndAck10Packets()
+				for p := protocol.PacketNumber(11); p <= minReceivedBeforeAckDecimation+20; p++ {
+					tracker.ReceivedPacket(p, time.Now(), true)
+					Expect(tracker.GetAlarmTimeout()).To(BeZero())
+					ack := tracker.GetAckFrame()
+					Expect(ack).ToNot(BeNil())
+					Expect(ack.LargestAcked()).To(Equal(p))
+				}
+				// packets that are not ack-eliciting are still not acknowledged on their own
+				tracker.ReceivedPacket(minReceivedBeforeAckDecimation+21, time.Now(), false)
+				Expect(tracker.GetAckFrame()).To(BeNil())
+			})
+
 			It("queues an ACK if it was reported missing before", func() {
 				receiveAndAck10Packets()
 				tracker.ReceivedPacket(11, time.Time{}, true)
@@ -173,6 +198,76 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(ack.HasMissingRanges()).To(BeTrue())
 				Expect(ack).ToNot(BeNil())
 			})
+
+			Context("ACK frequency", func() {
+				// receives packets in order, and counts how many ACKs are generated
+				countAcks := func(first, last protocol.PacketNumber) int {
+					var numAcks int
+					for p := first; p <= last; p++ {
+						tracker.ReceivedPacket(p, time.Now(), true)
+						if tracker.GetAckFrame() != nil {
+							numAcks++
+						}
+					}
+					return numAcks
+				}
+
+				It("reduces the number of ACKs sent when honoring an ACK_FREQUENCY request", func() {
+					receiveAndAck10Packets()
+					numAcksDefault := countAcks(11, 1010)
+
+					tracker = newReceivedPacketTracker(rttStats, protocol.MaxAckDelay, protocol.AckDelayExponent, false, utils.DefaultLogger, protocol.VersionWhatever)
+					receiveAndAck10Packets()
+					tracker.SetAckFrequency(&wire.AckFrequencyFrame{PacketTolerance: 50, UpdateMaxAckDelay: protocol.MaxAckDelay})
+					numAcks := countAcks(11, 1010)
+					Expect(numAcks).To(Equal(20))
+					Expect(numAcks).To(BeNumerically("<", numAcksDefault))
+				})
+
+				It("uses the requested max ack delay", func() {
+					receiveAndAck10Packets()
+					tracker.SetAckFrequency(&wire.AckFrequencyFrame{PacketTolerance: 5, UpdateMaxAckDelay: 100 * time.Millisecond})
+					rcvTime := time.Now()
+					tracker.ReceivedPacket(11, rcvTime, true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(100 * time.Millisecond)))
+				})
+
+				It("ignores ACK_FREQUENCY frames that are not newer than the last one", func() {
+					receiveAndAck10Packets()
+					tracker.SetAckFrequency(&wire.AckFrequencyFrame{SequenceNumber: 2, PacketTolerance: 5, UpdateMaxAckDelay: protocol.MaxAckDelay})
+					tracker.SetAckFrequency(&wire.AckFrequencyFrame{SequenceNumber: 1, PacketTolerance: 2, UpdateMaxAckDelay: protocol.MaxAckDelay})
+					for p := protocol.PacketNumber(11); p < 15; p++ {
+						tracker.ReceivedPacket(p, time.Now(), true)
+						Expect(tracker.ackQueued).To(BeFalse())
+					}
+					tracker.ReceivedPacket(15, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeTrue())
+				})
+
+				It("doesn't queue an ACK for reordered packets, if requested to ignore the order", func() {
+					receiveAndAck10Packets()
+					tracker.SetAckFrequency(&wire.AckFrequencyFrame{PacketTolerance: 10, UpdateMaxAckDelay: protocol.MaxAckDelay, IgnoreOrder: true})
+					tracker.ReceivedPacket(11, time.Now(), true)
+					tracker.ReceivedPacket(13, time.Now(), true)
+					tracker.QueueAck()
+					ack := tracker.GetAckFrame() // ACK: 1-11 and 13, missing: 12
+					Expect(ack).ToNot(BeNil())
+					Expect(ack.HasMissingRanges()).To(BeTrue())
+					tracker.ReceivedPacket(12, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+				})
+
+				It("queues an ACK when receiving an IMMEDIATE_ACK", func() {
+					receiveAndAck10Packets()
+					tracker.ReceivedPacket(11, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
+					tracker.QueueAck()
+					Expect(tracker.GetAlarmTimeout()).To(BeZero())
+					Expect(tracker.GetAckFrame()).ToNot(BeNil())
+				})
+			})
 		})
 
 		Context("ACK generation", func() {
@@ -207,6 +302,14 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(ack.DelayTime).To(BeNumerically("~", 1337*time.Millisecond, 50*time.Millisecond))
 			})
 
+			It("uses the configured ack_delay_exponent", func() {
+				tracker = newReceivedPacketTracker(rttStats, protocol.MaxAckDelay, 10, false, utils.DefaultLogger, protocol.VersionWhatever)
+				tracker.ReceivedPacket(1, time.Now(), true)
+				ack := tracker.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.DelayExponent).To(BeEquivalentTo(10))
+			})
+
 			It("uses a 0 delay time if the delay would be negative", func() {
 				tracker.ReceivedPacket(0, time.Now().Add(time.Hour), true)
 				ack := tracker.GetAckFrame()
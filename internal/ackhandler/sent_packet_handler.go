@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"fmt"
 	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -19,6 +20,8 @@ const (
 	timeThreshold = 9.0 / 8
 	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
 	packetThreshold = 3
+	// When a maximum send rate is set, the pacer may fall behind by this duration before the time is lost.
+	maxSendRateTimerSlack = 2 * time.Millisecond
 )
 
 type packetNumberSpace struct {
@@ -42,6 +45,16 @@ func newPacketNumberSpace(initialPN protocol.PacketNumber) *packetNumberSpace {
 }
 
 type sentPacketHandler struct {
+	// The 64 bit fields accessed atomically must be at the beginning of the struct,
+	// to guarantee 64 bit alignment on 32 bit platforms (see https://golang.org/pkg/sync/atomic/#pkg-note-BUG).
+
+	// maxSendRate is the maximum send rate in bytes per second. 0 means unlimited.
+	// It is accessed atomically, since it can be set by the application at any time.
+	maxSendRate uint64
+	// pacingInterval is the pacing delay applied after the last packet sent.
+	// It is accessed atomically, since it can be read by the application at any time.
+	pacingInterval int64
+
 	nextSendTime time.Time
 
 	initialPackets   *packetNumberSpace
@@ -56,10 +69,12 @@ type sentPacketHandler struct {
 	// Only applies to the application-data packet number space.
 	lowestNotConfirmedAcked protocol.PacketNumber
 
-	bytesInFlight protocol.ByteCount
+	bytesInFlight   protocol.ByteCount
+	packetsInFlight int
 
 	congestion congestion.SendAlgorithmWithDebugInfos
 	rttStats   *congestion.RTTStats
+	bandwidth  *congestion.BandwidthSampler
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
@@ -72,6 +87,11 @@ type sentPacketHandler struct {
 	alarm time.Time
 
 	traceCallback func(quictrace.Event)
+	// the congestion state reported in the last CongestionStateUpdated event
+	tracedCongestionState *quictrace.CongestionState
+	// metrics is reused for the MetricsUpdated and CongestionStateUpdated events,
+	// so that tracing the metrics doesn't allocate on every ACK.
+	metrics quictrace.TransportState
 
 	logger utils.Logger
 }
@@ -80,13 +100,19 @@ type sentPacketHandler struct {
 func NewSentPacketHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *congestion.RTTStats,
+	congestionControl protocol.CongestionControl,
+	hyStart bool,
+	minCongestionWindow protocol.ByteCount,
 	traceCallback func(quictrace.Event),
 	logger utils.Logger,
 ) SentPacketHandler {
+	bandwidth := congestion.NewBandwidthSampler(rttStats)
 	congestion := congestion.NewCubicSender(
 		congestion.DefaultClock{},
 		rttStats,
-		true, // use Reno
+		congestionControl,
+		hyStart,
+		minCongestionWindow,
 	)
 
 	return &sentPacketHandler{
@@ -95,6 +121,7 @@ func NewSentPacketHandler(
 		appDataPackets:   newPacketNumberSpace(0),
 		rttStats:         rttStats,
 		congestion:       congestion,
+		bandwidth:        bandwidth,
 		traceCallback:    traceCallback,
 		logger:           logger,
 	}
@@ -107,6 +134,7 @@ func (h *sentPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 		pnSpace.history.Iterate(func(p *Packet) (bool, error) {
 			if p.includedInBytesInFlight {
 				h.bytesInFlight -= p.Length
+				h.packetsInFlight--
 			}
 			return true, nil
 		})
@@ -126,6 +154,7 @@ func (h *sentPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 			h.queueFramesForRetransmission(p)
 			if p.includedInBytesInFlight {
 				h.bytesInFlight -= p.Length
+				h.packetsInFlight--
 			}
 			h.appDataPackets.history.Remove(p.PacketNumber)
 			return true, nil
@@ -172,14 +201,24 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* is ack-elicit
 	if isAckEliciting {
 		pnSpace.lastSentAckElicitingPacketTime = packet.SendTime
 		packet.includedInBytesInFlight = true
+		packet.deliveryState = h.bandwidth.OnPacketSent(packet.SendTime, h.bytesInFlight)
 		h.bytesInFlight += packet.Length
+		h.packetsInFlight++
 		if h.numProbesToSend > 0 {
 			h.numProbesToSend--
 		}
 	}
 	h.congestion.OnPacketSent(packet.SendTime, h.bytesInFlight, packet.PacketNumber, packet.Length, isAckEliciting)
 
-	h.nextSendTime = utils.MaxTime(h.nextSendTime, packet.SendTime).Add(h.congestion.TimeUntilSend(h.bytesInFlight))
+	lastSendTime := packet.SendTime
+	if atomic.LoadUint64(&h.maxSendRate) > 0 {
+		// Timers fire late, so packets are usually sent a bit after the pacing deadline.
+		// Allow the pacer to make up for that, otherwise the maximum send rate can't be reached.
+		lastSendTime = lastSendTime.Add(-maxSendRateTimerSlack)
+	}
+	pacingDelay := h.pacingDelay(packet.Length)
+	atomic.StoreInt64(&h.pacingInterval, int64(pacingDelay))
+	h.nextSendTime = utils.MaxTime(h.nextSendTime, lastSendTime).Add(pacingDelay)
 	return isAckEliciting
 }
 
@@ -229,12 +268,14 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 		}
 		if p.includedInBytesInFlight {
 			h.congestion.OnPacketAcked(p.PacketNumber, p.Length, priorInFlight, rcvTime)
+			h.bandwidth.OnPacketAcked(rcvTime, p.Length, p.deliveryState)
 		}
 	}
 
 	if err := h.detectLostPackets(rcvTime, encLevel, priorInFlight); err != nil {
 		return err
 	}
+	h.traceMetrics(rcvTime, encLevel)
 
 	h.ptoCount = 0
 	h.numProbesToSend = 0
@@ -421,6 +462,7 @@ func (h *sentPacketHandler) detectLostPackets(
 		// the bytes in flight need to be reduced no matter if this packet will be retransmitted
 		if p.includedInBytesInFlight {
 			h.bytesInFlight -= p.Length
+			h.packetsInFlight--
 			h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
 		}
 		pnSpace.history.Remove(p.PacketNumber)
@@ -464,7 +506,12 @@ func (h *sentPacketHandler) onVerifiedLossDetectionTimeout() error {
 			h.logger.Debugf("Loss detection alarm fired in loss timer mode. Loss time: %s", earliestLossTime)
 		}
 		// Early retransmit or time loss detection
-		return h.detectLostPackets(time.Now(), encLevel, h.bytesInFlight)
+		now := time.Now()
+		if err := h.detectLostPackets(now, encLevel, h.bytesInFlight); err != nil {
+			return err
+		}
+		h.traceMetrics(now, encLevel)
+		return nil
 	}
 
 	// PTO
@@ -504,6 +551,7 @@ func (h *sentPacketHandler) onPacketAcked(p *Packet) error {
 	}
 	if p.includedInBytesInFlight {
 		h.bytesInFlight -= p.Length
+		h.packetsInFlight--
 	}
 	return pnSpace.history.Remove(p.PacketNumber)
 }
@@ -561,6 +609,14 @@ func (h *sentPacketHandler) SendMode() SendMode {
 		}
 		return SendAck
 	}
+	// When a maximum send rate is set, pacing is enforced strictly:
+	// Only send ACKs until it's time to send the next packet.
+	if atomic.LoadUint64(&h.maxSendRate) > 0 && time.Now().Add(protocol.MinPacingDelay).Before(h.nextSendTime) {
+		if h.logger.Debug() {
+			h.logger.Debugf("Send rate limited: next packet can be sent in %s", time.Until(h.nextSendTime))
+		}
+		return SendAck
+	}
 	return SendAny
 }
 
@@ -568,12 +624,30 @@ func (h *sentPacketHandler) TimeUntilSend() time.Time {
 	return h.nextSendTime
 }
 
+func (h *sentPacketHandler) PacingInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&h.pacingInterval))
+}
+
+func (h *sentPacketHandler) SetMaxSendRate(bytesPerSecond uint64) {
+	atomic.StoreUint64(&h.maxSendRate, bytesPerSecond)
+}
+
+// pacingDelay is the time that has to pass after sending a packet of the given size.
+// It is determined by the congestion controller, and limited by the maximum send rate.
+func (h *sentPacketHandler) pacingDelay(size protocol.ByteCount) time.Duration {
+	delay := h.congestion.TimeUntilSend(h.bytesInFlight)
+	if rate := atomic.LoadUint64(&h.maxSendRate); rate > 0 {
+		delay = utils.MaxDuration(delay, time.Duration(float64(size)/float64(rate)*float64(time.Second)))
+	}
+	return delay
+}
+
 func (h *sentPacketHandler) ShouldSendNumPackets() int {
 	if h.numProbesToSend > 0 {
 		// RTO probes should not be paced, but must be sent immediately.
 		return h.numProbesToSend
 	}
-	delay := h.congestion.TimeUntilSend(h.bytesInFlight)
+	delay := h.pacingDelay(protocol.MaxPacketSizeIPv4)
 	if delay == 0 || delay > protocol.MinPacingDelay {
 		return 1
 	}
@@ -591,6 +665,7 @@ func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel)
 	// Keep track of acknowledged frames instead.
 	if p.includedInBytesInFlight {
 		h.bytesInFlight -= p.Length
+		h.packetsInFlight--
 	}
 	if err := pnSpace.history.Remove(p.PacketNumber); err != nil {
 		// should never happen. We just got this packet from the history.
@@ -607,6 +682,7 @@ func (h *sentPacketHandler) queueFramesForRetransmission(p *Packet) {
 
 func (h *sentPacketHandler) ResetForRetry() error {
 	h.bytesInFlight = 0
+	h.packetsInFlight = 0
 	h.initialPackets.history.Iterate(func(p *Packet) (bool, error) {
 		h.queueFramesForRetransmission(p)
 		return true, nil
@@ -631,14 +707,51 @@ func (h *sentPacketHandler) SetHandshakeComplete() {
 	h.setLossDetectionTimer()
 }
 
+// traceMetrics is called after processing an ACK frame, and after packets were declared lost on a timeout.
+func (h *sentPacketHandler) traceMetrics(now time.Time, encLevel protocol.EncryptionLevel) {
+	if h.traceCallback == nil {
+		return
+	}
+	stats := &h.metrics
+	h.fillStats(stats)
+	largestAcked := h.getPacketNumberSpace(encLevel).largestAcked
+	h.traceCallback(quictrace.Event{
+		Time:            now,
+		EventType:       quictrace.MetricsUpdated,
+		EncryptionLevel: encLevel,
+		PacketNumber:    largestAcked,
+		TransportState:  stats,
+	})
+	state := stats.CongestionState()
+	if h.tracedCongestionState != nil && *h.tracedCongestionState == state {
+		return
+	}
+	h.tracedCongestionState = &state
+	h.traceCallback(quictrace.Event{
+		Time:            now,
+		EventType:       quictrace.CongestionStateUpdated,
+		EncryptionLevel: encLevel,
+		PacketNumber:    largestAcked,
+		TransportState:  stats,
+	})
+}
+
 func (h *sentPacketHandler) GetStats() *quictrace.TransportState {
-	return &quictrace.TransportState{
-		MinRTT:           h.rttStats.MinRTT(),
-		SmoothedRTT:      h.rttStats.SmoothedRTT(),
-		LatestRTT:        h.rttStats.LatestRTT(),
-		BytesInFlight:    h.bytesInFlight,
-		CongestionWindow: h.congestion.GetCongestionWindow(),
-		InSlowStart:      h.congestion.InSlowStart(),
-		InRecovery:       h.congestion.InRecovery(),
+	stats := &quictrace.TransportState{}
+	h.fillStats(stats)
+	return stats
+}
+
+func (h *sentPacketHandler) fillStats(stats *quictrace.TransportState) {
+	*stats = quictrace.TransportState{
+		MinRTT:            h.rttStats.MinRTT(),
+		SmoothedRTT:       h.rttStats.SmoothedRTT(),
+		LatestRTT:         h.rttStats.LatestRTT(),
+		BytesInFlight:     h.bytesInFlight,
+		PacketsInFlight:   h.packetsInFlight,
+		CongestionWindow:  h.congestion.GetCongestionWindow(),
+		InSlowStart:       h.congestion.InSlowStart(),
+		InRecovery:        h.congestion.InRecovery(),
+		BandwidthEstimate: uint64(h.bandwidth.BandwidthEstimate() / congestion.BitsPerSecond),
 	}
 }
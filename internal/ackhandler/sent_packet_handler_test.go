@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"bytes"
 	"fmt"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -24,7 +26,7 @@ var _ = Describe("SentPacketHandler", func() {
 	BeforeEach(func() {
 		lostPackets = nil
 		rttStats := &congestion.RTTStats{}
-		handler = NewSentPacketHandler(42, rttStats, nil, utils.DefaultLogger).(*sentPacketHandler)
+		handler = NewSentPacketHandler(42, rttStats, protocol.CongestionControlHybrid, false, 0, nil, utils.DefaultLogger).(*sentPacketHandler)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -126,6 +128,21 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.appDataPackets.lastSentAckElicitingPacketTime).To(BeZero())
 			Expect(handler.bytesInFlight).To(BeZero())
 		})
+
+		It("estimates the bandwidth", func() {
+			Expect(handler.GetStats().BandwidthEstimate).To(BeZero())
+			start := time.Now().Add(-time.Second)
+			for i := 1; i <= 10; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{
+					PacketNumber: protocol.PacketNumber(i),
+					Length:       1000,
+					SendTime:     start.Add(time.Duration(i) * 10 * time.Millisecond),
+				}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, start.Add(200*time.Millisecond))).To(Succeed())
+			Expect(handler.GetStats().BandwidthEstimate).To(BeEquivalentTo(congestion.BandwidthFromDelta(10000, 190*time.Millisecond)))
+		})
 	})
 
 	Context("ACK processing", func() {
@@ -332,6 +349,27 @@ var _ = Describe("SentPacketHandler", func() {
 				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 5*time.Minute, 1*time.Second))
 			})
 
+			It("subtracts the DelayTime decoded using the peer's ack_delay_exponent", func() {
+				handler.rttStats.SetMaxAckDelay(time.Hour)
+				// make sure the rttStats have a min RTT, so that the delay is used
+				handler.rttStats.UpdateRTT(5*time.Minute, 0, time.Now())
+				getPacket(1, protocol.Encryption1RTT).SendTime = time.Now().Add(-10 * time.Minute)
+				buf := &bytes.Buffer{}
+				Expect((&wire.AckFrame{
+					AckRanges:     []wire.AckRange{{Smallest: 1, Largest: 1}},
+					DelayTime:     5 * time.Minute,
+					DelayExponent: 15,
+				}).Write(buf, protocol.VersionWhatever)).To(Succeed())
+				parser := wire.NewFrameParser(protocol.VersionWhatever)
+				parser.SetAckDelayExponent(15)
+				_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+				Expect(err).ToNot(HaveOccurred())
+				ack := frame.(*wire.AckFrame)
+				Expect(ack.DelayTime).To(BeNumerically("~", 5*time.Minute, time.Second))
+				Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 5*time.Minute, 1*time.Second))
+			})
+
 			It("limits the DelayTime in the ACK frame to max_ack_delay", func() {
 				handler.rttStats.SetMaxAckDelay(time.Minute)
 				// make sure the rttStats have a min RTT, so that the delay is used
@@ -553,6 +591,72 @@ var _ = Describe("SentPacketHandler", func() {
 			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(pacingDelay)
 			Expect(handler.ShouldSendNumPackets()).To(Equal(3))
 		})
+
+		It("limits the pacing delay by the maximum send rate", func() {
+			sendTime := time.Now().Add(-time.Minute)
+			handler.SetMaxSendRate(1000) // 1000 bytes per second
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(time.Millisecond)
+			handler.SentPacket(&Packet{PacketNumber: 1, Length: 500, SendTime: sendTime, EncryptionLevel: protocol.Encryption1RTT})
+			Expect(handler.TimeUntilSend()).To(Equal(sendTime.Add(500*time.Millisecond - maxSendRateTimerSlack)))
+			// the next packet is paced relative to the previous pacing deadline
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(time.Millisecond)
+			handler.SentPacket(&Packet{PacketNumber: 2, Length: 1000, SendTime: sendTime.Add(500 * time.Millisecond), EncryptionLevel: protocol.Encryption1RTT})
+			Expect(handler.TimeUntilSend()).To(Equal(sendTime.Add(1500*time.Millisecond - maxSendRateTimerSlack)))
+			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(time.Duration(0))
+			Expect(handler.ShouldSendNumPackets()).To(Equal(1))
+		})
+
+		It("uses the congestion controller's pacing delay, if it is larger than the one derived from the maximum send rate", func() {
+			sendTime := time.Now().Add(-time.Minute)
+			handler.SetMaxSendRate(1000)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(time.Hour)
+			handler.SentPacket(&Packet{PacketNumber: 1, Length: 500, SendTime: sendTime, EncryptionLevel: protocol.Encryption1RTT})
+			Expect(handler.TimeUntilSend()).To(Equal(sendTime.Add(time.Hour - maxSendRateTimerSlack)))
+		})
+
+		It("only allows sending of ACKs before the next packet can be sent, if a maximum send rate is set", func() {
+			cong.EXPECT().CanSend(gomock.Any()).Return(true).AnyTimes()
+			handler.nextSendTime = time.Now().Add(time.Hour)
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			handler.SetMaxSendRate(1000)
+			Expect(handler.SendMode()).To(Equal(SendAck))
+			handler.nextSendTime = time.Now()
+			Expect(handler.SendMode()).To(Equal(SendAny))
+		})
+
+		It("removes the send rate limit", func() {
+			handler.SetMaxSendRate(1000)
+			handler.SetMaxSendRate(0)
+			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(protocol.MinPacingDelay / 10)
+			Expect(handler.ShouldSendNumPackets()).To(Equal(10))
+		})
+	})
+
+	It("reports a larger pacing interval for a small congestion window and a large RTT", func() {
+		getPacingInterval := func(rtt time.Duration, reduceCongestionWindow bool) time.Duration {
+			rttStats := &congestion.RTTStats{}
+			rttStats.UpdateRTT(rtt, 0, time.Now())
+			h := NewSentPacketHandler(0, rttStats, protocol.CongestionControlHybrid, false, 0, nil, utils.DefaultLogger).(*sentPacketHandler)
+			if reduceCongestionWindow {
+				h.congestion.OnRetransmissionTimeout(true)
+			}
+			Expect(h.PacingInterval()).To(BeZero())
+			h.SentPacket(&Packet{
+				PacketNumber:    0,
+				Length:          1000,
+				Frames:          []Frame{{Frame: &wire.PingFrame{}}},
+				EncryptionLevel: protocol.Encryption1RTT,
+				SendTime:        time.Now(),
+			})
+			return h.PacingInterval()
+		}
+		slow := getPacingInterval(500*time.Millisecond, true)
+		fast := getPacingInterval(10*time.Millisecond, false)
+		Expect(fast).ToNot(BeZero())
+		Expect(slow).To(BeNumerically(">", fast))
 	})
 
 	It("doesn't set an alarm if there are no outstanding packets", func() {
@@ -578,6 +682,13 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{10}))
 		})
 
+		It("uses the default RTT for the first PTO", func() {
+			handler.rttStats.SetDefaultRTT(30 * time.Millisecond)
+			sendTime := time.Now().Add(-time.Hour)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: sendTime, EncryptionLevel: protocol.EncryptionInitial}))
+			Expect(handler.GetLossDetectionTimeout()).To(Equal(sendTime.Add(60 * time.Millisecond)))
+		})
+
 		It("says when it can't queue a probe packet", func() {
 			queued := handler.QueueProbePacket(protocol.Encryption1RTT)
 			Expect(queued).To(BeFalse())
@@ -718,6 +829,26 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).To(Equal(SendAny))
 		})
 
+		It("doesn't declare packets lost after the RTT estimate was reset on a path change", func() {
+			handler.SetHandshakeComplete()
+			// the RTT of the old path
+			updateRTT(10 * time.Millisecond)
+			handler.rttStats.OnConnectionMigration()
+			// the RTT of the new path is 100ms
+			sendTime := time.Now().Add(-100 * time.Millisecond)
+			for pn := protocol.PacketNumber(1); pn <= 10; pn++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: pn, SendTime: sendTime}))
+			}
+			// When using the RTT of the old path, the PTO would already have expired.
+			Expect(handler.GetLossDetectionTimeout()).To(BeTemporally(">", time.Now()))
+			// leave packet 9 unacknowledged
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 10, Largest: 10}, {Smallest: 1, Largest: 8}}}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(lostPackets).To(BeEmpty())
+			expectInPacketHistory([]protocol.PacketNumber{9}, protocol.Encryption1RTT)
+			Expect(handler.rttStats.SmoothedRTT()).To(BeNumerically(">=", 100*time.Millisecond))
+		})
+
 		It("handles ACKs for the original packet", func() {
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 5, SendTime: time.Now().Add(-time.Hour)}))
 			handler.rttStats.UpdateRTT(time.Second, 0, time.Now())
@@ -728,6 +859,92 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("tracing metrics", func() {
+		var events []quictrace.Event
+
+		BeforeEach(func() {
+			events = nil
+			handler.traceCallback = func(ev quictrace.Event) {
+				// the transport state of metrics events is reused
+				state := *ev.TransportState
+				ev.TransportState = &state
+				events = append(events, ev)
+			}
+		})
+
+		eventsOfType := func(t quictrace.EventType) []quictrace.Event {
+			var evs []quictrace.Event
+			for _, ev := range events {
+				if ev.EventType == t {
+					evs = append(evs, ev)
+				}
+			}
+			return evs
+		}
+
+		It("traces the metrics after every ACK", func() {
+			for i := protocol.PacketNumber(1); i <= 10; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, Length: 10}))
+			}
+			for i := protocol.PacketNumber(1); i <= 10; i += 2 {
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: i}}}
+				Expect(handler.ReceivedAck(ack, i, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			}
+			metrics := eventsOfType(quictrace.MetricsUpdated)
+			Expect(metrics).To(HaveLen(5))
+			for i, ev := range metrics {
+				Expect(ev.EncryptionLevel).To(Equal(protocol.Encryption1RTT))
+				Expect(ev.PacketNumber).To(Equal(protocol.PacketNumber(2*i + 1)))
+				Expect(ev.TransportState.PacketsInFlight).To(Equal(10 - (2*i + 1)))
+				Expect(ev.TransportState.BytesInFlight).To(Equal(protocol.ByteCount(10 * (10 - (2*i + 1)))))
+				Expect(ev.TransportState.CongestionWindow).ToNot(BeZero())
+			}
+			// the congestion state is only traced when it changes
+			states := eventsOfType(quictrace.CongestionStateUpdated)
+			Expect(states).To(HaveLen(1))
+			Expect(states[0].TransportState.CongestionState()).To(Equal(quictrace.CongestionStateSlowStart))
+		})
+
+		It("traces the metrics with monotonic packet numbers when packets are lost", func() {
+			for i := protocol.PacketNumber(1); i <= 20; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			// packets 1, 2 and 3 are lost
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 6}}}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 20}}}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, time.Now())).To(Succeed())
+
+			metrics := eventsOfType(quictrace.MetricsUpdated)
+			Expect(metrics).To(HaveLen(2))
+			Expect(metrics[0].PacketNumber).To(Equal(protocol.PacketNumber(6)))
+			Expect(metrics[0].TransportState.PacketsInFlight).To(Equal(14))
+			Expect(metrics[1].PacketNumber).To(Equal(protocol.PacketNumber(20)))
+			Expect(metrics[1].TransportState.PacketsInFlight).To(BeZero())
+			states := eventsOfType(quictrace.CongestionStateUpdated)
+			Expect(states).To(HaveLen(1))
+			Expect(states[0].TransportState.CongestionState()).To(Equal(quictrace.CongestionStateRecovery))
+		})
+
+		It("reuses the transport state for every ACK", func() {
+			var states []*quictrace.TransportState
+			handler.traceCallback = func(ev quictrace.Event) {
+				if ev.EventType == quictrace.MetricsUpdated {
+					states = append(states, ev.TransportState)
+				}
+			}
+			for i := protocol.PacketNumber(1); i <= 3; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: i}}}
+				Expect(handler.ReceivedAck(ack, i, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			}
+			Expect(states).To(HaveLen(3))
+			Expect(states[1]).To(BeIdenticalTo(states[0]))
+			Expect(states[2]).To(BeIdenticalTo(states[0]))
+		})
+	})
+
 	Context("Packet-based loss detection", func() {
 		It("declares packet below the packet loss threshold as lost", func() {
 			for i := protocol.PacketNumber(1); i <= 6; i++ {
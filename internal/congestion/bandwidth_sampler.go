@@ -0,0 +1,150 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The maximum bandwidth sample is remembered for this many round trips.
+const bandwidthWindowRTTs = 10
+
+// A DeliveryState is the state of the BandwidthSampler at the time a packet was sent.
+// It is saved with the packet, and passed back to the sampler when the packet is acknowledged.
+type DeliveryState struct {
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+	firstSentTime time.Time
+	sentTime      time.Time
+}
+
+// A BandwidthSampler estimates the delivery rate of a connection.
+// For every acknowledged packet, it takes a bandwidth sample by dividing the number of bytes
+// delivered while the packet was in flight by the time that elapsed.
+// The estimate is the maximum sample taken over the last few round trips.
+// See https://tools.ietf.org/html/draft-cheng-iccrg-delivery-rate-estimation-00.
+type BandwidthSampler struct {
+	rttStats *RTTStats
+
+	// the total number of bytes acknowledged
+	delivered protocol.ByteCount
+	// the time when delivered was last updated
+	deliveredTime time.Time
+	// the send time of the packet that was most recently acknowledged
+	firstSentTime time.Time
+
+	maxBandwidth windowedMaxFilter
+}
+
+// NewBandwidthSampler creates a new BandwidthSampler.
+func NewBandwidthSampler(rttStats *RTTStats) *BandwidthSampler {
+	return &BandwidthSampler{rttStats: rttStats}
+}
+
+// OnPacketSent is called for every packet that counts towards bytes in flight.
+// priorInFlight is the number of bytes in flight before the packet was sent.
+func (s *BandwidthSampler) OnPacketSent(sentTime time.Time, priorInFlight protocol.ByteCount) DeliveryState {
+	// When starting a new flight, don't count the time that the connection was idle.
+	if priorInFlight == 0 {
+		s.firstSentTime = sentTime
+		s.deliveredTime = sentTime
+	}
+	return DeliveryState{
+		delivered:     s.delivered,
+		deliveredTime: s.deliveredTime,
+		firstSentTime: s.firstSentTime,
+		sentTime:      sentTime,
+	}
+}
+
+// OnPacketAcked is called when a packet is acknowledged.
+// Packets must be passed in the order they were sent.
+func (s *BandwidthSampler) OnPacketAcked(ackTime time.Time, bytes protocol.ByteCount, state DeliveryState) {
+	s.delivered += bytes
+	s.deliveredTime = ackTime
+	s.firstSentTime = state.sentTime
+
+	if state.sentTime.IsZero() {
+		return
+	}
+	// The interval is the longer of the send and the ack interval.
+	// This prevents ACK compression from inflating the sample.
+	sendElapsed := state.sentTime.Sub(state.firstSentTime)
+	ackElapsed := ackTime.Sub(state.deliveredTime)
+	interval := utils.MaxDuration(sendElapsed, ackElapsed)
+	if interval <= 0 {
+		return
+	}
+	sample := BandwidthFromDelta(s.delivered-state.delivered, interval)
+	s.maxBandwidth.Update(sample, ackTime, s.window())
+}
+
+// BandwidthEstimate returns the current estimate of the delivery rate.
+// It returns 0 if no sample has been taken yet.
+func (s *BandwidthSampler) BandwidthEstimate() Bandwidth {
+	return s.maxBandwidth.Get()
+}
+
+func (s *BandwidthSampler) window() time.Duration {
+	rtt := s.rttStats.SmoothedRTT()
+	if rtt == 0 {
+		rtt = s.rttStats.InitialRTT()
+	}
+	return bandwidthWindowRTTs * rtt
+}
+
+type bandwidthSample struct {
+	bandwidth Bandwidth
+	time      time.Time
+}
+
+// windowedMaxFilter keeps track of the maximum bandwidth sample within a time window.
+// It stores the best, second best and third best sample, as described by Kathleen Nichols,
+// such that it doesn't need to keep every sample.
+type windowedMaxFilter struct {
+	estimates [3]bandwidthSample
+}
+
+func (f *windowedMaxFilter) Get() Bandwidth {
+	return f.estimates[0].bandwidth
+}
+
+func (f *windowedMaxFilter) Update(bw Bandwidth, now time.Time, window time.Duration) {
+	sample := bandwidthSample{bandwidth: bw, time: now}
+	// Reset all estimates if this is the first sample, if it's a new maximum,
+	// or if nothing was sampled during the whole window.
+	if f.estimates[0].time.IsZero() || bw >= f.estimates[0].bandwidth || now.Sub(f.estimates[2].time) > window {
+		f.estimates = [3]bandwidthSample{sample, sample, sample}
+		return
+	}
+
+	if bw >= f.estimates[1].bandwidth {
+		f.estimates[1] = sample
+		f.estimates[2] = sample
+	} else if bw >= f.estimates[2].bandwidth {
+		f.estimates[2] = sample
+	}
+
+	// Expire the best estimate, and promote the second and third best.
+	if now.Sub(f.estimates[0].time) > window {
+		f.estimates[0] = f.estimates[1]
+		f.estimates[1] = f.estimates[2]
+		f.estimates[2] = sample
+		if now.Sub(f.estimates[0].time) > window {
+			f.estimates[0] = f.estimates[1]
+			f.estimates[1] = f.estimates[2]
+		}
+		return
+	}
+	// The second best estimate is still the same as the best one, and a quarter of the window has passed.
+	// Use the current sample for the second and third best, so that we have a fallback when the best one expires.
+	if f.estimates[1].time.Equal(f.estimates[0].time) && now.Sub(f.estimates[1].time) > window/4 {
+		f.estimates[1] = sample
+		f.estimates[2] = sample
+		return
+	}
+	if f.estimates[2].time.Equal(f.estimates[1].time) && now.Sub(f.estimates[2].time) > window/2 {
+		f.estimates[2] = sample
+	}
+}
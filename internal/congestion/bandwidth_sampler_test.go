@@ -0,0 +1,137 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bandwidth Sampler", func() {
+	const packetSize protocol.ByteCount = 1000
+
+	var (
+		sampler  *BandwidthSampler
+		rttStats *RTTStats
+		start    time.Time
+	)
+
+	BeforeEach(func() {
+		rttStats = NewRTTStats()
+		rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+		sampler = NewBandwidthSampler(rttStats)
+		start = time.Now()
+	})
+
+	It("returns 0 before the first packet was acknowledged", func() {
+		sampler.OnPacketSent(start, 0)
+		Expect(sampler.BandwidthEstimate()).To(BeZero())
+	})
+
+	It("estimates the bandwidth of a single packet", func() {
+		state := sampler.OnPacketSent(start, 0)
+		sampler.OnPacketAcked(start.Add(100*time.Millisecond), packetSize, state)
+		Expect(sampler.BandwidthEstimate()).To(Equal(10000 * BytesPerSecond))
+	})
+
+	// sendAndAck sends one packet every interval, and every packet is acknowledged one RTT after it was sent.
+	sendAndAck := func(t time.Time, numPackets int, interval, rtt time.Duration) time.Time {
+		var bytesInFlight protocol.ByteCount
+		states := make([]DeliveryState, numPackets)
+		sendTimes := make([]time.Time, numPackets)
+		var acked int
+		for i := 0; i < numPackets; i++ {
+			sendTime := t.Add(time.Duration(i) * interval)
+			for acked < i && !sendTimes[acked].Add(rtt).After(sendTime) {
+				sampler.OnPacketAcked(sendTimes[acked].Add(rtt), packetSize, states[acked])
+				bytesInFlight -= packetSize
+				acked++
+			}
+			sendTimes[i] = sendTime
+			states[i] = sampler.OnPacketSent(sendTime, bytesInFlight)
+			bytesInFlight += packetSize
+		}
+		for ; acked < numPackets; acked++ {
+			sampler.OnPacketAcked(sendTimes[acked].Add(rtt), packetSize, states[acked])
+		}
+		return sendTimes[numPackets-1].Add(rtt)
+	}
+
+	It("estimates the bandwidth when sending at a constant rate", func() {
+		sendAndAck(start, 100, 10*time.Millisecond, 100*time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(100000 * BytesPerSecond))
+	})
+
+	It("doesn't count the time the connection was idle", func() {
+		state := sampler.OnPacketSent(start, 0)
+		sampler.OnPacketAcked(start.Add(200*time.Millisecond), packetSize, state)
+		Expect(sampler.BandwidthEstimate()).To(Equal(5000 * BytesPerSecond))
+		// start a new flight after 1 second
+		now := start.Add(1200 * time.Millisecond)
+		state = sampler.OnPacketSent(now, 0)
+		sampler.OnPacketAcked(now.Add(100*time.Millisecond), packetSize, state)
+		Expect(sampler.BandwidthEstimate()).To(Equal(10000 * BytesPerSecond))
+	})
+
+	It("doesn't let ACK compression inflate the estimate", func() {
+		var states []DeliveryState
+		for i := 0; i < 10; i++ {
+			states = append(states, sampler.OnPacketSent(start.Add(time.Duration(i)*10*time.Millisecond), protocol.ByteCount(i)*packetSize))
+		}
+		sampler.OnPacketAcked(start.Add(100*time.Millisecond), packetSize, states[0])
+		for i := 10; i < 20; i++ {
+			states = append(states, sampler.OnPacketSent(start.Add(time.Duration(i)*10*time.Millisecond), 9*packetSize))
+		}
+		// the ACKs for all other packets arrive at the same time
+		for _, state := range states[1:] {
+			sampler.OnPacketAcked(start.Add(200*time.Millisecond), packetSize, state)
+		}
+		// Looking at the ACKs only, 19 packets were delivered in 100ms.
+		// However, they were sent at a rate of 1 packet every 10ms.
+		Expect(sampler.BandwidthEstimate()).To(Equal(100000 * BytesPerSecond))
+	})
+
+	It("keeps the maximum sample for 10 RTTs", func() {
+		now := sendAndAck(start, 50, 10*time.Millisecond, 100*time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(100000 * BytesPerSecond))
+		// the send rate drops by half
+		now = sendAndAck(now, 25, 20*time.Millisecond, 100*time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(100000 * BytesPerSecond))
+		// after 10 RTTs, the old maximum expires
+		sendAndAck(now, 50, 20*time.Millisecond, 100*time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(50000 * BytesPerSecond))
+	})
+
+	Context("windowed max filter", func() {
+		var filter windowedMaxFilter
+
+		BeforeEach(func() {
+			filter = windowedMaxFilter{}
+		})
+
+		It("returns the maximum within the window", func() {
+			filter.Update(10, start, time.Second)
+			filter.Update(30, start.Add(100*time.Millisecond), time.Second)
+			filter.Update(20, start.Add(200*time.Millisecond), time.Second)
+			Expect(filter.Get()).To(Equal(Bandwidth(30)))
+		})
+
+		It("expires old samples", func() {
+			filter.Update(30, start, time.Second)
+			filter.Update(20, start.Add(300*time.Millisecond), time.Second)
+			filter.Update(10, start.Add(900*time.Millisecond), time.Second)
+			Expect(filter.Get()).To(Equal(Bandwidth(30)))
+			filter.Update(5, start.Add(1100*time.Millisecond), time.Second)
+			Expect(filter.Get()).To(Equal(Bandwidth(20)))
+			filter.Update(5, start.Add(1400*time.Millisecond), time.Second)
+			Expect(filter.Get()).To(Equal(Bandwidth(10)))
+		})
+
+		It("resets when no sample was taken during the whole window", func() {
+			filter.Update(30, start, time.Second)
+			filter.Update(5, start.Add(3*time.Second), time.Second)
+			Expect(filter.Get()).To(Equal(Bandwidth(5)))
+		})
+	})
+})
@@ -20,6 +20,7 @@ const (
 
 type cubicSender struct {
 	hybridSlowStart HybridSlowStart
+	hyStart         HyStart
 	prr             PrrSender
 	rttStats        *RTTStats
 	stats           connectionStats
@@ -27,6 +28,10 @@ type cubicSender struct {
 
 	noPRR bool
 	reno  bool
+	// When true, slow start is only exited on packet loss.
+	noHybridSlowStart bool
+	// When true, HyStart++ is used instead of hybrid slow start.
+	useHyStart bool
 
 	// Track the largest packet that has been sent.
 	largestSentPacketNumber protocol.PacketNumber
@@ -72,11 +77,21 @@ var _ SendAlgorithm = &cubicSender{}
 var _ SendAlgorithmWithDebugInfos = &cubicSender{}
 
 // NewCubicSender makes a new cubic sender
-func NewCubicSender(clock Clock, rttStats *RTTStats, reno bool) *cubicSender {
-	return newCubicSender(clock, rttStats, reno, initialCongestionWindow, maxCongestionWindow)
+// If hyStart is set, HyStart++ is used instead of hybrid slow start.
+// It has no effect when using Reno, which only exits slow start on packet loss.
+// The congestion window is never reduced below minWindow.
+// If minWindow is 0, a minimum congestion window of 2 packets is used.
+func NewCubicSender(clock Clock, rttStats *RTTStats, algorithm protocol.CongestionControl, hyStart bool, minWindow protocol.ByteCount) *cubicSender {
+	return newCubicSender(clock, rttStats, algorithm, hyStart, initialCongestionWindow, maxCongestionWindow, minWindow)
 }
 
-func newCubicSender(clock Clock, rttStats *RTTStats, reno bool, initialCongestionWindow, initialMaxCongestionWindow protocol.ByteCount) *cubicSender {
+func newCubicSender(clock Clock, rttStats *RTTStats, algorithm protocol.CongestionControl, hyStart bool, initialCongestionWindow, initialMaxCongestionWindow, minWindow protocol.ByteCount) *cubicSender {
+	if minWindow == 0 {
+		minWindow = minCongestionWindow
+	}
+	if initialCongestionWindow < minWindow {
+		initialCongestionWindow = minWindow
+	}
 	return &cubicSender{
 		rttStats:                   rttStats,
 		largestSentPacketNumber:    protocol.InvalidPacketNumber,
@@ -85,12 +100,14 @@ func newCubicSender(clock Clock, rttStats *RTTStats, reno bool, initialCongestio
 		initialCongestionWindow:    initialCongestionWindow,
 		initialMaxCongestionWindow: initialMaxCongestionWindow,
 		congestionWindow:           initialCongestionWindow,
-		minCongestionWindow:        minCongestionWindow,
+		minCongestionWindow:        minWindow,
 		slowstartThreshold:         initialMaxCongestionWindow,
 		maxCongestionWindow:        initialMaxCongestionWindow,
 		numConnections:             defaultNumConnections,
 		cubic:                      NewCubic(clock),
-		reno:                       reno,
+		reno:                       algorithm != protocol.CongestionControlCubic,
+		noHybridSlowStart:          algorithm == protocol.CongestionControlReno,
+		useHyStart:                 hyStart,
 	}
 }
 
@@ -121,6 +138,7 @@ func (c *cubicSender) OnPacketSent(
 	}
 	c.largestSentPacketNumber = packetNumber
 	c.hybridSlowStart.OnPacketSent(packetNumber)
+	c.hyStart.OnPacketSent(packetNumber)
 }
 
 func (c *cubicSender) CanSend(bytesInFlight protocol.ByteCount) bool {
@@ -155,7 +173,14 @@ func (c *cubicSender) SlowstartThreshold() protocol.ByteCount {
 }
 
 func (c *cubicSender) MaybeExitSlowStart() {
-	if c.InSlowStart() && c.hybridSlowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/maxDatagramSize) {
+	if c.noHybridSlowStart || !c.InSlowStart() {
+		return
+	}
+	if c.useHyStart {
+		c.hyStart.OnRTTSample(c.rttStats.LatestRTT())
+		return
+	}
+	if c.hybridSlowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/maxDatagramSize) {
 		c.ExitSlowstart()
 	}
 }
@@ -177,6 +202,9 @@ func (c *cubicSender) OnPacketAcked(
 	c.maybeIncreaseCwnd(ackedPacketNumber, ackedBytes, priorInFlight, eventTime)
 	if c.InSlowStart() {
 		c.hybridSlowStart.OnPacketAcked(ackedPacketNumber)
+		if c.useHyStart && !c.noHybridSlowStart && c.hyStart.OnPacketAcked(ackedPacketNumber) {
+			c.ExitSlowstart()
+		}
 	}
 }
 
@@ -256,6 +284,10 @@ func (c *cubicSender) maybeIncreaseCwnd(
 	}
 	if c.InSlowStart() {
 		// TCP slow start, exponential growth, increase by one for each ACK.
+		if c.useHyStart && c.hyStart.InConservativeSlowStart() {
+			c.congestionWindow += maxDatagramSize / hyStartCSSGrowthDivisor
+			return
+		}
 		c.congestionWindow += maxDatagramSize
 		return
 	}
@@ -312,6 +344,7 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 		return
 	}
 	c.hybridSlowStart.Restart()
+	c.hyStart.Restart()
 	c.cubic.Reset()
 	c.slowstartThreshold = c.congestionWindow / 2
 	c.congestionWindow = c.minCongestionWindow
@@ -320,6 +353,7 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 // OnConnectionMigration is called when the connection is migrated (?)
 func (c *cubicSender) OnConnectionMigration() {
 	c.hybridSlowStart.Restart()
+	c.hyStart.Restart()
 	c.prr = PrrSender{}
 	c.largestSentPacketNumber = protocol.InvalidPacketNumber
 	c.largestAckedPacketNumber = protocol.InvalidPacketNumber
@@ -40,7 +40,7 @@ var _ = Describe("Cubic Sender", func() {
 		ackedPacketNumber = 0
 		clock = mockClock{}
 		rttStats = NewRTTStats()
-		sender = newCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow)
+		sender = newCubicSender(&clock, rttStats, protocol.CongestionControlHybrid, false, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, 0)
 	})
 
 	SendAvailableSendWindowLen := func(packetLength protocol.ByteCount) int {
@@ -397,10 +397,30 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
 	})
 
+	It("doesn't reduce the window below the configured minimum", func() {
+		minWindow := 10 * maxDatagramSize
+		sender = newCubicSender(&clock, rttStats, protocol.CongestionControlReno, false, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, minWindow)
+		for i := 0; i < 20; i++ {
+			sender.OnPacketSent(clock.Now(), 0, packetNumber, maxDatagramSize, true)
+			sender.OnPacketLost(packetNumber, maxDatagramSize, maxDatagramSize)
+			packetNumber++
+			Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", minWindow))
+		}
+		Expect(sender.GetCongestionWindow()).To(Equal(minWindow))
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(minWindow))
+	})
+
+	It("starts with the minimum window if it is larger than the initial window", func() {
+		minWindow := 2 * initialCongestionWindowPackets * maxDatagramSize
+		sender = newCubicSender(&clock, rttStats, protocol.CongestionControlReno, false, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, minWindow)
+		Expect(sender.GetCongestionWindow()).To(Equal(minWindow))
+	})
+
 	It("tcp cubic reset epoch on quiescence", func() {
 		const maxCongestionWindow = 50
 		const maxCongestionWindowBytes = maxCongestionWindow * maxDatagramSize
-		sender = newCubicSender(&clock, rttStats, false, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes)
+		sender = newCubicSender(&clock, rttStats, protocol.CongestionControlCubic, false, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, 0)
 
 		numSent := SendAvailableSendWindow()
 
@@ -600,7 +620,7 @@ var _ = Describe("Cubic Sender", func() {
 	})
 
 	It("default max cwnd", func() {
-		sender = newCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindow)
+		sender = newCubicSender(&clock, rttStats, protocol.CongestionControlHybrid, false, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindow, 0)
 
 		defaultMaxCongestionWindowPackets := maxCongestionWindow / maxDatagramSize
 		for i := 1; i < int(defaultMaxCongestionWindowPackets); i++ {
@@ -612,7 +632,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("limit cwnd increase in congestion avoidance", func() {
 		// Enable Cubic.
-		sender = newCubicSender(&clock, rttStats, false, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow)
+		sender = newCubicSender(&clock, rttStats, protocol.CongestionControlCubic, false, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, 0)
 		numSent := SendAvailableSendWindow()
 
 		// Make sure we fall out of slow start.
@@ -646,4 +666,105 @@ var _ = Describe("Cubic Sender", func() {
 		AckNPackets(2)
 		Expect(sender.GetCongestionWindow()).To(Equal(savedCwnd + maxDatagramSize))
 	})
+	Context("congestion control algorithms", func() {
+		// windowGrowth runs the same ACK pattern for every algorithm,
+		// and returns the congestion window after every round trip.
+		// The RTT increases by 5ms every round trip, and a packet is lost in the 6th round trip.
+		windowGrowth := func(algorithm protocol.CongestionControl) []protocol.ByteCount {
+			rttStats = NewRTTStats()
+			sender = newCubicSender(&clock, rttStats, algorithm, false, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, 0)
+			var cwnds []protocol.ByteCount
+			for round := 0; round < 20; round++ {
+				numSent := SendAvailableSendWindow()
+				if round == 5 {
+					LoseNPackets(1)
+					numSent--
+				}
+				for i := 0; i < numSent; i++ {
+					rttStats.UpdateRTT(time.Duration(60+5*round)*time.Millisecond, 0, clock.Now())
+					sender.MaybeExitSlowStart()
+					ackedPacketNumber++
+					sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+					bytesInFlight -= maxDatagramSize
+				}
+				clock.Advance(time.Duration(60+5*round) * time.Millisecond)
+				cwnds = append(cwnds, sender.GetCongestionWindow())
+			}
+			return cwnds
+		}
+
+		It("only exits slow start on packet loss when using Reno", func() {
+			hybrid := windowGrowth(protocol.CongestionControlHybrid)
+			reno := windowGrowth(protocol.CongestionControlReno)
+			Expect(reno).ToNot(Equal(hybrid))
+			Expect(reno[:3]).To(Equal(hybrid[:3]))
+			// hybrid slow start detects the RTT increase in the 3rd round trip
+			Expect(hybrid[3]).To(Equal(hybrid[2]))
+			Expect(reno[3]).To(BeNumerically(">", reno[2]))
+			Expect(reno[4]).To(BeNumerically(">", reno[3]))
+			// the packet loss in the 6th round trip ends slow start
+			Expect(reno[5]).To(BeNumerically("<", reno[4]))
+			Expect(reno[5]).To(BeNumerically(">", hybrid[5]))
+		})
+
+		It("exits slow start before a packet is lost when using HyStart++", func() {
+			rttStats = NewRTTStats()
+			sender = newCubicSender(&clock, rttStats, protocol.CongestionControlHybrid, true, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, 0)
+			var cwnds []protocol.ByteCount
+			for round := 0; round < 20 && sender.InSlowStart(); round++ {
+				rtt := 60 * time.Millisecond
+				if round >= 3 { // the queue at the bottleneck starts filling up
+					rtt = 100 * time.Millisecond
+				}
+				numSent := SendAvailableSendWindow()
+				for i := 0; i < numSent; i++ {
+					rttStats.UpdateRTT(rtt, 0, clock.Now())
+					sender.MaybeExitSlowStart()
+					ackedPacketNumber++
+					sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+					bytesInFlight -= maxDatagramSize
+				}
+				clock.Advance(rtt)
+				cwnds = append(cwnds, sender.GetCongestionWindow())
+			}
+			// No packet was lost, but the RTT increase ended slow start.
+			Expect(sender.InSlowStart()).To(BeFalse())
+			// The RTT increase is detected in the 4th round trip, and slow start is exited after the CSS rounds.
+			Expect(cwnds).To(HaveLen(3 + 1 + hyStartCSSRounds))
+			// In Conservative Slow Start, the window grows a lot slower.
+			for i := 4; i < 3+hyStartCSSRounds; i++ {
+				Expect(cwnds[i] - cwnds[i-1]).To(BeNumerically("<", cwnds[3]-cwnds[2]))
+			}
+		})
+
+		It("doesn't use HyStart++ when using Reno", func() {
+			rttStats = NewRTTStats()
+			sender = newCubicSender(&clock, rttStats, protocol.CongestionControlReno, true, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, 0)
+			for round := 0; round < 4; round++ {
+				rtt := time.Duration(60+20*round) * time.Millisecond
+				numSent := SendAvailableSendWindow()
+				for i := 0; i < numSent; i++ {
+					rttStats.UpdateRTT(rtt, 0, clock.Now())
+					sender.MaybeExitSlowStart()
+					ackedPacketNumber++
+					sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+					bytesInFlight -= maxDatagramSize
+				}
+				clock.Advance(rtt)
+			}
+			Expect(sender.InSlowStart()).To(BeTrue())
+			Expect(sender.hyStart.InConservativeSlowStart()).To(BeFalse())
+		})
+
+		It("grows the window faster in congestion avoidance when using CUBIC", func() {
+			hybrid := windowGrowth(protocol.CongestionControlHybrid)
+			cubic := windowGrowth(protocol.CongestionControlCubic)
+			Expect(cubic).ToNot(Equal(hybrid))
+			Expect(cubic[:3]).To(Equal(hybrid[:3]))
+			for i := 6; i < len(cubic); i++ {
+				Expect(cubic[i]).To(BeNumerically(">", cubic[i-1]))
+				Expect(cubic[i]).To(BeNumerically(">", hybrid[i]))
+			}
+		})
+	})
 })
@@ -0,0 +1,121 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The constants are the values recommended by the HyStart++ draft.
+const (
+	// Number of RTT samples taken in every round, before an RTT increase is detected.
+	hyStartMinRTTSamples = uint32(8)
+	// The RTT threshold is 1/8th of the minimum RTT of the last round,
+	// clamped between hyStartMinRTTThreshold and hyStartMaxRTTThreshold.
+	hyStartRTTThresholdFactorExp = 3 // 2^3 = 8
+	hyStartMinRTTThreshold       = 4 * time.Millisecond
+	hyStartMaxRTTThreshold       = 16 * time.Millisecond
+	// During Conservative Slow Start, the congestion window grows 4 times slower than in slow start.
+	hyStartCSSGrowthDivisor = 4
+	// Number of rounds spent in Conservative Slow Start, before slow start is exited.
+	hyStartCSSRounds = 5
+)
+
+// HyStart implements the HyStart++ slow start algorithm.
+// When the RTT increases during slow start, it enters Conservative Slow Start (CSS),
+// and exits slow start after a few rounds in CSS, if the RTT increase persists.
+// This avoids overshooting the bottleneck bandwidth, which would cause a large number of packets to be lost.
+type HyStart struct {
+	endPacketNumber      protocol.PacketNumber
+	lastSentPacketNumber protocol.PacketNumber
+	started              bool
+
+	lastRoundMinRTT    time.Duration
+	currentRoundMinRTT time.Duration
+	rttSampleCount     uint32
+
+	// cssBaselineMinRTT is the minimum RTT of the round in which CSS was entered.
+	// It is 0 when not in CSS.
+	cssBaselineMinRTT time.Duration
+	cssRounds         int
+}
+
+// StartReceiveRound is called for the start of each receive round (burst) in the slow start phase.
+func (s *HyStart) StartReceiveRound(lastSent protocol.PacketNumber) {
+	s.endPacketNumber = lastSent
+	s.lastRoundMinRTT = s.currentRoundMinRTT
+	s.currentRoundMinRTT = 0
+	s.rttSampleCount = 0
+	s.started = true
+}
+
+// IsEndOfRound returns true if this ack is the last packet number of our current slow start round.
+// It always returns false if no round was started.
+func (s *HyStart) IsEndOfRound(ack protocol.PacketNumber) bool {
+	return s.started && s.endPacketNumber < ack
+}
+
+// InConservativeSlowStart says if the RTT increased, and the congestion window should grow more slowly.
+func (s *HyStart) InConservativeSlowStart() bool {
+	return s.cssBaselineMinRTT != 0
+}
+
+// OnRTTSample should be called on every new ack frame, since a new
+// RTT measurement can be made then.
+func (s *HyStart) OnRTTSample(latestRTT time.Duration) {
+	if !s.started {
+		s.StartReceiveRound(s.lastSentPacketNumber)
+	}
+	s.rttSampleCount++
+	if s.currentRoundMinRTT == 0 || s.currentRoundMinRTT > latestRTT {
+		s.currentRoundMinRTT = latestRTT
+	}
+	if s.rttSampleCount < hyStartMinRTTSamples || s.lastRoundMinRTT == 0 {
+		return
+	}
+	if s.InConservativeSlowStart() {
+		// The RTT increase was spurious. Resume slow start.
+		if s.currentRoundMinRTT < s.cssBaselineMinRTT {
+			s.cssBaselineMinRTT = 0
+			s.cssRounds = 0
+		}
+		return
+	}
+	threshold := utils.MinDuration(
+		utils.MaxDuration(s.lastRoundMinRTT>>hyStartRTTThresholdFactorExp, hyStartMinRTTThreshold),
+		hyStartMaxRTTThreshold,
+	)
+	if s.currentRoundMinRTT >= s.lastRoundMinRTT+threshold {
+		s.cssBaselineMinRTT = s.currentRoundMinRTT
+	}
+}
+
+// OnPacketSent is called when a packet was sent
+func (s *HyStart) OnPacketSent(packetNumber protocol.PacketNumber) {
+	s.lastSentPacketNumber = packetNumber
+}
+
+// OnPacketAcked gets invoked after OnRTTSample.
+// It ends the round when the final packet of the burst is received,
+// and returns true if slow start should be exited.
+func (s *HyStart) OnPacketAcked(ackedPacketNumber protocol.PacketNumber) bool {
+	if !s.IsEndOfRound(ackedPacketNumber) {
+		return false
+	}
+	s.started = false
+	if !s.InConservativeSlowStart() {
+		return false
+	}
+	s.cssRounds++
+	return s.cssRounds >= hyStartCSSRounds
+}
+
+// Restart the slow start phase
+func (s *HyStart) Restart() {
+	s.started = false
+	s.lastRoundMinRTT = 0
+	s.currentRoundMinRTT = 0
+	s.cssBaselineMinRTT = 0
+	s.cssRounds = 0
+}
@@ -0,0 +1,129 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HyStart++", func() {
+	var (
+		hyStart      HyStart
+		packetNumber protocol.PacketNumber
+	)
+
+	BeforeEach(func() {
+		hyStart = HyStart{}
+		packetNumber = 0
+	})
+
+	// runRound sends 10 packets, and receives an ACK for every packet.
+	// It returns true if HyStart++ decided to exit slow start.
+	runRound := func(rtt time.Duration) bool {
+		firstPacketNumber := packetNumber + 1
+		for i := 0; i < 10; i++ {
+			packetNumber++
+			hyStart.OnPacketSent(packetNumber)
+		}
+		var exit bool
+		for pn := firstPacketNumber; pn <= packetNumber; pn++ {
+			hyStart.OnRTTSample(rtt)
+			if hyStart.OnPacketAcked(pn) {
+				exit = true
+			}
+		}
+		return exit
+	}
+
+	// runRoundWithSingleAck sends 10 packets, and receives a single ACK acknowledging all of them.
+	runRoundWithSingleAck := func(rtt time.Duration) bool {
+		firstPacketNumber := packetNumber + 1
+		for i := 0; i < 10; i++ {
+			packetNumber++
+			hyStart.OnPacketSent(packetNumber)
+		}
+		hyStart.OnRTTSample(rtt)
+		var exit bool
+		for pn := firstPacketNumber; pn <= packetNumber; pn++ {
+			if hyStart.OnPacketAcked(pn) {
+				exit = true
+			}
+		}
+		return exit
+	}
+
+	It("doesn't end a round before it started", func() {
+		Expect(hyStart.IsEndOfRound(1)).To(BeFalse())
+		hyStart.OnPacketSent(1)
+		Expect(hyStart.OnPacketAcked(1)).To(BeFalse())
+		Expect(hyStart.IsEndOfRound(2)).To(BeFalse())
+	})
+
+	It("ends at most one round when a single ACK acknowledges a whole burst", func() {
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(80 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.InConservativeSlowStart()).To(BeTrue())
+		Expect(runRoundWithSingleAck(80 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.cssRounds).To(Equal(1))
+	})
+
+	It("stays in slow start when the RTT doesn't increase", func() {
+		for i := 0; i < 10; i++ {
+			Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+			Expect(hyStart.InConservativeSlowStart()).To(BeFalse())
+		}
+	})
+
+	It("ignores an RTT increase smaller than the threshold", func() {
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		// The threshold is 60ms / 8 = 7.5ms.
+		Expect(runRound(67 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.InConservativeSlowStart()).To(BeFalse())
+	})
+
+	It("enters Conservative Slow Start when the RTT increases, and exits slow start after a few rounds", func() {
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(80 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.InConservativeSlowStart()).To(BeTrue())
+		var rounds int
+		for !runRound(80 * time.Millisecond) {
+			rounds++
+			Expect(rounds).To(BeNumerically("<", hyStartCSSRounds))
+		}
+	})
+
+	It("resumes slow start if the RTT increase was spurious", func() {
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(80 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.InConservativeSlowStart()).To(BeTrue())
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.InConservativeSlowStart()).To(BeFalse())
+	})
+
+	It("clamps the RTT threshold", func() {
+		// For a minimum RTT of 10ms, the threshold is 4ms (and not 10ms / 8 = 1.25ms).
+		Expect(runRound(10 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(10 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(13 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.InConservativeSlowStart()).To(BeFalse())
+		Expect(runRound(17 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.InConservativeSlowStart()).To(BeTrue())
+	})
+
+	It("restarts", func() {
+		Expect(runRound(60 * time.Millisecond)).To(BeFalse())
+		Expect(runRound(80 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.InConservativeSlowStart()).To(BeTrue())
+		hyStart.Restart()
+		Expect(hyStart.InConservativeSlowStart()).To(BeFalse())
+		// the first round after the restart doesn't have a minimum RTT to compare to
+		Expect(runRound(100 * time.Millisecond)).To(BeFalse())
+		Expect(hyStart.InConservativeSlowStart()).To(BeFalse())
+	})
+})
@@ -1,6 +1,7 @@
 package congestion
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -18,12 +19,23 @@ const (
 
 // RTTStats provides round-trip statistics
 type RTTStats struct {
+	// the RTT used before the first RTT sample is taken, 0 if not set.
+	// It is accessed atomically, since it is restored from the session state by the handshake.
+	initialRTT int64
+
+	// the RTT used if no initial RTT was set, 0 if not set (defaultInitialRTT is used then)
+	defaultRTT time.Duration
+
 	minRTT        time.Duration
 	latestRTT     time.Duration
 	smoothedRTT   time.Duration
 	meanDeviation time.Duration
 
 	maxAckDelay time.Duration
+
+	// lower and upper bound for the RTT used to calculate the PTO, 0 if not set
+	minRTTBound time.Duration
+	maxRTTBound time.Duration
 }
 
 // NewRTTStats makes a properly initialized RTTStats object
@@ -50,11 +62,12 @@ func (r *RTTStats) MeanDeviation() time.Duration { return r.meanDeviation }
 func (r *RTTStats) MaxAckDelay() time.Duration { return r.maxAckDelay }
 
 // PTO gets the probe timeout duration.
+// The RTT used to calculate it is bounded by the values set with SetRTTBounds.
 func (r *RTTStats) PTO(includeMaxAckDelay bool) time.Duration {
 	if r.SmoothedRTT() == 0 {
-		return 2 * defaultInitialRTT
+		return 2 * r.boundRTT(r.InitialRTT())
 	}
-	pto := r.SmoothedRTT() + utils.MaxDuration(4*r.MeanDeviation(), protocol.TimerGranularity)
+	pto := r.boundRTT(r.SmoothedRTT()) + utils.MaxDuration(4*r.MeanDeviation(), protocol.TimerGranularity)
 	if includeMaxAckDelay {
 		pto += r.MaxAckDelay()
 	}
@@ -93,10 +106,59 @@ func (r *RTTStats) UpdateRTT(sendDelta, ackDelay time.Duration, now time.Time) {
 	}
 }
 
+// SetInitialRTT sets the initial RTT.
+// It is used during the 0-RTT handshake when restoring the RTT stats from the session state.
+// It has no effect once an RTT sample was taken.
+func (r *RTTStats) SetInitialRTT(t time.Duration) {
+	if r.smoothedRTT != 0 {
+		return
+	}
+	r.smoothedRTT = t
+	r.latestRTT = t
+	atomic.StoreInt64(&r.initialRTT, int64(t))
+}
+
+// SetDefaultRTT sets the RTT that is used before the first RTT sample is taken,
+// if no initial RTT is restored using SetInitialRTT.
+// A value of 0 means that the default value of 100ms is used.
+func (r *RTTStats) SetDefaultRTT(t time.Duration) {
+	r.defaultRTT = t
+}
+
+// InitialRTT returns the RTT that is used before the first RTT sample is taken:
+// the value set by SetInitialRTT, or the value set by SetDefaultRTT, or 100ms.
+// It is safe to call it concurrently.
+func (r *RTTStats) InitialRTT() time.Duration {
+	if rtt := time.Duration(atomic.LoadInt64(&r.initialRTT)); rtt != 0 {
+		return rtt
+	}
+	if r.defaultRTT != 0 {
+		return r.defaultRTT
+	}
+	return defaultInitialRTT
+}
+
 func (r *RTTStats) SetMaxAckDelay(mad time.Duration) {
 	r.maxAckDelay = mad
 }
 
+// SetRTTBounds sets a lower and an upper bound for the RTT used to calculate the PTO.
+// A value of 0 means that there's no bound.
+func (r *RTTStats) SetRTTBounds(min, max time.Duration) {
+	r.minRTTBound = min
+	r.maxRTTBound = max
+}
+
+func (r *RTTStats) boundRTT(rtt time.Duration) time.Duration {
+	if r.minRTTBound != 0 && rtt < r.minRTTBound {
+		return r.minRTTBound
+	}
+	if r.maxRTTBound != 0 && rtt > r.maxRTTBound {
+		return r.maxRTTBound
+	}
+	return rtt
+}
+
 // OnConnectionMigration is called when connection migrates and rtt measurement needs to be reset.
 func (r *RTTStats) OnConnectionMigration() {
 	r.latestRTT = 0
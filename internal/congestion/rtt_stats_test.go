@@ -76,6 +76,24 @@ var _ = Describe("RTT stats", func() {
 		Expect(rttStats.PTO(true)).To(Equal(rtt + protocol.TimerGranularity))
 	})
 
+	It("bounds the RTT used for computing the PTO", func() {
+		rttStats.SetRTTBounds(20*time.Millisecond, 200*time.Millisecond)
+		Expect(rttStats.PTO(false)).To(Equal(2 * defaultInitialRTT))
+		rttStats.UpdateRTT(time.Millisecond, 0, time.Time{})
+		Expect(rttStats.SmoothedRTT()).To(Equal(time.Millisecond))
+		Expect(rttStats.PTO(false)).To(Equal(20*time.Millisecond + 4*(time.Millisecond/2)))
+		rttStats = NewRTTStats()
+		rttStats.SetRTTBounds(20*time.Millisecond, 200*time.Millisecond)
+		rttStats.UpdateRTT(time.Second, 0, time.Time{})
+		Expect(rttStats.SmoothedRTT()).To(Equal(time.Second))
+		Expect(rttStats.PTO(false)).To(Equal(200*time.Millisecond + 4*(time.Second/2)))
+	})
+
+	It("bounds the default RTT used for computing the PTO", func() {
+		rttStats.SetRTTBounds(0, 10*time.Millisecond)
+		Expect(rttStats.PTO(false)).To(Equal(20 * time.Millisecond))
+	})
+
 	It("ExpireSmoothedMetrics", func() {
 		initialRtt := (10 * time.Millisecond)
 		rttStats.UpdateRTT(initialRtt, 0, time.Time{})
@@ -102,6 +120,35 @@ var _ = Describe("RTT stats", func() {
 		Expect(initialRtt).To(BeNumerically("<", rttStats.MeanDeviation()))
 	})
 
+	It("sets the initial RTT", func() {
+		rttStats.SetInitialRTT(42 * time.Millisecond)
+		Expect(rttStats.SmoothedRTT()).To(Equal(42 * time.Millisecond))
+		Expect(rttStats.LatestRTT()).To(Equal(42 * time.Millisecond))
+		Expect(rttStats.MinRTT()).To(BeZero())
+	})
+
+	It("doesn't set the initial RTT after an RTT sample was taken", func() {
+		rttStats.UpdateRTT(10*time.Millisecond, 0, time.Time{})
+		rttStats.SetInitialRTT(42 * time.Millisecond)
+		Expect(rttStats.SmoothedRTT()).To(Equal(10 * time.Millisecond))
+		Expect(rttStats.LatestRTT()).To(Equal(10 * time.Millisecond))
+	})
+
+	It("uses the default RTT for computing the PTO", func() {
+		Expect(rttStats.InitialRTT()).To(Equal(defaultInitialRTT))
+		rttStats.SetDefaultRTT(30 * time.Millisecond)
+		Expect(rttStats.InitialRTT()).To(Equal(30 * time.Millisecond))
+		Expect(rttStats.SmoothedRTT()).To(BeZero())
+		Expect(rttStats.PTO(false)).To(Equal(60 * time.Millisecond))
+	})
+
+	It("prefers the initial RTT over the default RTT", func() {
+		rttStats.SetDefaultRTT(30 * time.Millisecond)
+		rttStats.SetInitialRTT(42 * time.Millisecond)
+		Expect(rttStats.InitialRTT()).To(Equal(42 * time.Millisecond))
+		Expect(rttStats.SmoothedRTT()).To(Equal(42 * time.Millisecond))
+	})
+
 	It("UpdateRTTWithBadSendDeltas", func() {
 		// Make sure we ignore bad RTTs.
 		// base::test::MockLog log;
@@ -21,6 +21,9 @@ type StreamFlowController interface {
 	// final has to be to true if this is the final offset of the stream,
 	// as contained in a STREAM frame with FIN bit, and the RESET_STREAM frame
 	UpdateHighestReceived(offset protocol.ByteCount, final bool) error
+	// SetReceiveWindowSize sets the size of the receive window.
+	// It returns the new offset if the window was increased, and 0 otherwise.
+	SetReceiveWindowSize(protocol.ByteCount) protocol.ByteCount
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
@@ -119,6 +119,22 @@ func (c *streamFlowController) maybeQueueWindowUpdate() {
 	}
 }
 
+// SetReceiveWindowSize sets the size of the receive window.
+// The size is limited by the maximum receive window size.
+// It returns the new offset if the receive window was increased, and 0 otherwise.
+func (c *streamFlowController) SetReceiveWindowSize(size protocol.ByteCount) protocol.ByteCount {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.receiveWindowSize = utils.MinByteCount(size, c.maxReceiveWindowSize)
+	c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(c.receiveWindowSize) * protocol.ConnectionFlowControlMultiplier))
+	if c.receivedFinalOffset || c.bytesRead+c.receiveWindowSize <= c.receiveWindow {
+		return 0
+	}
+	c.receiveWindow = c.bytesRead + c.receiveWindowSize
+	return c.receiveWindow
+}
+
 func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
 	// don't use defer for unlocking the mutex here, GetWindowUpdate() is called frequently and defer shows up in the profiler
 	c.mutex.Lock()
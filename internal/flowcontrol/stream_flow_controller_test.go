@@ -195,6 +195,49 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(controller.connection.GetWindowUpdate()).ToNot(BeZero())
 			})
 
+			It("increases the receive window when setting a larger window size", func() {
+				Expect(controller.SetReceiveWindowSize(200)).To(Equal(protocol.ByteCount(40 + 200)))
+				Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(200)))
+				Expect(controller.receiveWindow).To(Equal(protocol.ByteCount(40 + 200)))
+				Expect(controller.connection.(*connectionFlowController).receiveWindowSize).To(Equal(protocol.ByteCount(float64(200) * protocol.ConnectionFlowControlMultiplier)))
+			})
+
+			It("limits the window size to the maximum window size", func() {
+				Expect(controller.SetReceiveWindowSize(1e6)).To(Equal(40 + controller.maxReceiveWindowSize))
+				Expect(controller.receiveWindowSize).To(Equal(controller.maxReceiveWindowSize))
+			})
+
+			It("doesn't shrink the receive window when setting a smaller window size", func() {
+				Expect(controller.SetReceiveWindowSize(30)).To(BeZero())
+				Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(30)))
+				Expect(controller.receiveWindow).To(Equal(protocol.ByteCount(100)))
+			})
+
+			It("sends fewer window updates when the window size is set to a large value", func() {
+				countWindowUpdates := func(fc *streamFlowController) int {
+					var count int
+					for fc.bytesRead < 5000 {
+						Expect(fc.UpdateHighestReceived(fc.bytesRead+10, false)).To(Succeed())
+						fc.AddBytesRead(10)
+						if fc.GetWindowUpdate() > 0 {
+							count++
+						}
+					}
+					return count
+				}
+				newController := func() *streamFlowController {
+					rttStats := &congestion.RTTStats{}
+					cfc := NewConnectionFlowController(1e6, 1e6, func() {}, rttStats, utils.DefaultLogger)
+					return NewStreamFlowController(10, cfc, 100, 10000, 0, func(protocol.StreamID) {}, rttStats, utils.DefaultLogger).(*streamFlowController)
+				}
+				withoutHint := countWindowUpdates(newController())
+				fc := newController()
+				fc.SetReceiveWindowSize(5000)
+				withHint := countWindowUpdates(fc)
+				Expect(withHint).ToNot(BeZero())
+				Expect(withHint).To(BeNumerically("<", withoutHint/10))
+			})
+
 			It("doesn't increase the window after a final offset was already received", func() {
 				Expect(controller.UpdateHighestReceived(90, true)).To(Succeed())
 				controller.AddBytesRead(30)
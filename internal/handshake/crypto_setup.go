@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -23,6 +24,13 @@ const (
 	alertInternalError uint8 = 80
 )
 
+// clientSessionStateRevision is the revision of the data the client saves in the session ticket.
+// It must be incremented every time the format changes.
+// Session tickets with a different revision are ignored.
+// Revision 1 is not used: the format used before the revision was introduced
+// started with the transportParameterMarshalingVersion, which is 1.
+const clientSessionStateRevision = 2
+
 type messageType uint8
 
 // TLS handshake message types.
@@ -64,6 +72,9 @@ type cryptoSetup struct {
 	tlsConf *qtls.Config
 	conn    *qtls.Conn
 
+	// only set for the server
+	numSessionTickets int
+
 	messageChan chan []byte
 
 	ourParams  *TransportParameters
@@ -78,6 +89,8 @@ type cryptoSetup struct {
 	// is closed when Close() is called
 	closeChan chan struct{}
 
+	rttStats *congestion.RTTStats
+
 	zeroRTTParameters      *TransportParameters
 	clientHelloWritten     bool
 	clientHelloWrittenChan chan *TransportParameters
@@ -94,6 +107,7 @@ type cryptoSetup struct {
 	logger utils.Logger
 
 	perspective protocol.Perspective
+	version     protocol.VersionNumber
 
 	mutex sync.Mutex // protects all members below
 
@@ -133,6 +147,7 @@ func NewCryptoSetupClient(
 	enable0RTT bool,
 	rttStats *congestion.RTTStats,
 	logger utils.Logger,
+	version protocol.VersionNumber,
 ) (CryptoSetup, <-chan *TransportParameters /* ClientHello written. Receive nil for non-0-RTT */) {
 	cs, clientHelloWritten := newCryptoSetup(
 		initialStream,
@@ -145,6 +160,7 @@ func NewCryptoSetupClient(
 		enable0RTT,
 		rttStats,
 		logger,
+		version,
 		protocol.PerspectiveClient,
 	)
 	cs.conn = qtls.Client(newConn(remoteAddr), cs.tlsConf)
@@ -162,8 +178,10 @@ func NewCryptoSetupServer(
 	runner handshakeRunner,
 	tlsConf *tls.Config,
 	enable0RTT bool,
+	numSessionTickets int,
 	rttStats *congestion.RTTStats,
 	logger utils.Logger,
+	version protocol.VersionNumber,
 ) CryptoSetup {
 	cs, _ := newCryptoSetup(
 		initialStream,
@@ -176,8 +194,10 @@ func NewCryptoSetupServer(
 		enable0RTT,
 		rttStats,
 		logger,
+		version,
 		protocol.PerspectiveServer,
 	)
+	cs.numSessionTickets = numSessionTickets
 	cs.conn = qtls.Server(newConn(remoteAddr), cs.tlsConf)
 	return cs
 }
@@ -193,17 +213,19 @@ func newCryptoSetup(
 	enable0RTT bool,
 	rttStats *congestion.RTTStats,
 	logger utils.Logger,
+	version protocol.VersionNumber,
 	perspective protocol.Perspective,
 ) (*cryptoSetup, <-chan *TransportParameters /* ClientHello written. Receive nil for non-0-RTT */) {
-	initialSealer, initialOpener := NewInitialAEAD(connID, perspective)
-	extHandler := newExtensionHandler(tp.Marshal(), perspective)
+	initialSealer, initialOpener := NewInitialAEAD(connID, perspective, version)
+	extHandler := newExtensionHandler(tp.Marshal(version), perspective, version)
 	cs := &cryptoSetup{
 		initialStream:          initialStream,
 		initialSealer:          initialSealer,
 		initialOpener:          initialOpener,
 		handshakeStream:        handshakeStream,
 		oneRTTStream:           oneRTTStream,
-		aead:                   newUpdatableAEAD(rttStats, logger),
+		aead:                   newUpdatableAEAD(rttStats, runner.OnKeyUpdate, logger, version),
+		rttStats:               rttStats,
 		readEncLevel:           protocol.EncryptionInitial,
 		writeEncLevel:          protocol.EncryptionInitial,
 		runner:                 runner,
@@ -211,6 +233,7 @@ func newCryptoSetup(
 		paramsChan:             extHandler.TransportParameters(),
 		logger:                 logger,
 		perspective:            perspective,
+		version:                version,
 		handshakeDone:          make(chan struct{}),
 		alertChan:              make(chan uint8),
 		clientHelloWrittenChan: make(chan *TransportParameters, 1),
@@ -220,13 +243,13 @@ func newCryptoSetup(
 		writeRecord:            make(chan struct{}, 1),
 		closeChan:              make(chan struct{}),
 	}
-	qtlsConf := tlsConfigToQtlsConfig(tlsConf, cs, extHandler, cs.marshalPeerParamsForSessionState, cs.handlePeerParamsFromSessionState, cs.accept0RTT, cs.rejected0RTT, enable0RTT)
+	qtlsConf := tlsConfigToQtlsConfig(tlsConf, cs, extHandler, cs.marshalDataForSessionState, cs.handleDataFromSessionState, cs.accept0RTT, cs.rejected0RTT, enable0RTT)
 	cs.tlsConf = qtlsConf
 	return cs, cs.clientHelloWrittenChan
 }
 
 func (h *cryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
-	initialSealer, initialOpener := NewInitialAEAD(id, h.perspective)
+	initialSealer, initialOpener := NewInitialAEAD(id, h.perspective, h.version)
 	h.initialSealer = initialSealer
 	h.initialOpener = initialOpener
 }
@@ -235,6 +258,10 @@ func (h *cryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) {
 	h.aead.SetLargestAcked(pn)
 }
 
+func (h *cryptoSetup) InitiateKeyUpdate() {
+	h.aead.InitiateKeyUpdate()
+}
+
 func (h *cryptoSetup) RunHandshake() {
 	// Handle errors that might occur when HandleData() is called.
 	handshakeComplete := make(chan struct{})
@@ -334,14 +361,14 @@ func (h *cryptoSetup) checkEncryptionLevel(msgType messageType, encLevel protoco
 func (h *cryptoSetup) handleMessageForServer(msgType messageType) bool {
 	switch msgType {
 	case typeClientHello:
+		var params []byte
 		select {
 		case <-h.writeRecord:
 			// If qtls sends a HelloRetryRequest, it will only write the record.
 			// If it accepts the ClientHello, it will first read the transport parameters.
 			h.logger.Debugf("Sending HelloRetryRequest")
 			return false
-		case data := <-h.paramsChan:
-			h.handleTransportParameters(data)
+		case params = <-h.paramsChan:
 		case <-h.handshakeDone:
 			return false
 		}
@@ -351,6 +378,10 @@ func (h *cryptoSetup) handleMessageForServer(msgType messageType) bool {
 		case <-h.handshakeDone:
 			return false
 		}
+		// Only handle the transport parameters now.
+		// qtls has decided whether to accept 0-RTT by the time it installs the handshake keys,
+		// and the session relies on that decision when the client's transport parameters are processed.
+		h.handleTransportParameters(params)
 		// get the handshake write key
 		select {
 		case <-h.receivedWriteKey:
@@ -435,7 +466,7 @@ func (h *cryptoSetup) handleMessageForClient(msgType messageType) bool {
 
 func (h *cryptoSetup) handleTransportParameters(data []byte) {
 	var tp TransportParameters
-	if err := tp.Unmarshal(data, h.perspective.Opposite()); err != nil {
+	if err := tp.Unmarshal(data, h.perspective.Opposite(), h.version); err != nil {
 		h.runner.OnError(qerr.Error(qerr.TransportParameterError, err.Error()))
 	}
 	h.peerParams = &tp
@@ -443,12 +474,16 @@ func (h *cryptoSetup) handleTransportParameters(data []byte) {
 }
 
 // must be called after receiving the transport parameters
-func (h *cryptoSetup) marshalPeerParamsForSessionState() []byte {
-	return h.peerParams.MarshalForSessionTicket()
+func (h *cryptoSetup) marshalDataForSessionState() []byte {
+	b := &bytes.Buffer{}
+	utils.WriteVarInt(b, clientSessionStateRevision)
+	utils.WriteVarInt(b, uint64(h.rttStats.SmoothedRTT().Microseconds()))
+	b.Write(h.peerParams.MarshalForSessionTicket())
+	return b.Bytes()
 }
 
-func (h *cryptoSetup) handlePeerParamsFromSessionState(data []byte) {
-	tp, err := h.handlePeerParamsFromSessionStateImpl(data)
+func (h *cryptoSetup) handleDataFromSessionState(data []byte) {
+	tp, err := h.handleDataFromSessionStateImpl(data)
 	if err != nil {
 		h.logger.Debugf("Restoring of transport parameters from session ticket failed: %s", err.Error())
 		return
@@ -456,19 +491,24 @@ func (h *cryptoSetup) handlePeerParamsFromSessionState(data []byte) {
 	h.zeroRTTParameters = tp
 }
 
-func (h *cryptoSetup) handlePeerParamsFromSessionStateImpl(data []byte) (*TransportParameters, error) {
+func (h *cryptoSetup) handleDataFromSessionStateImpl(data []byte) (*TransportParameters, error) {
 	r := bytes.NewReader(data)
-	version, err := utils.ReadVarInt(r)
+	ver, err := utils.ReadVarInt(r)
 	if err != nil {
 		return nil, err
 	}
-	if version != transportParameterMarshalingVersion {
-		return nil, fmt.Errorf("unknown transport parameter marshaling version: %d", version)
+	if ver != clientSessionStateRevision {
+		return nil, fmt.Errorf("mismatching version. Got %d, expected %d", ver, clientSessionStateRevision)
+	}
+	rtt, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
 	}
 	var tp TransportParameters
-	if err := tp.Unmarshal(data[len(data)-r.Len():], protocol.PerspectiveServer); err != nil {
+	if err := tp.UnmarshalFromSessionTicket(data[len(data)-r.Len():]); err != nil {
 		return nil, err
 	}
+	h.rttStats.SetInitialRTT(time.Duration(rtt) * time.Microsecond)
 	return &tp, nil
 }
 
@@ -479,12 +519,16 @@ func (h *cryptoSetup) maybeSendSessionTicket() {
 	if h.tlsConf.MaxEarlyData > 0 {
 		appData = h.ourParams.MarshalForSessionTicket()
 	}
-	ticket, err := h.conn.GetSessionTicket(appData)
-	if err != nil {
-		h.onError(alertInternalError, err.Error())
-		return
-	}
-	if ticket != nil {
+	for i := 0; i < h.numSessionTickets; i++ {
+		ticket, err := h.conn.GetSessionTicket(appData)
+		if err != nil {
+			h.onError(alertInternalError, err.Error())
+			return
+		}
+		// The ticket is nil if session tickets are disabled.
+		if ticket == nil {
+			return
+		}
 		h.oneRTTStream.Write(ticket)
 	}
 }
@@ -500,6 +544,7 @@ func (h *cryptoSetup) accept0RTT(sessionTicketData []byte) bool {
 	valid := h.ourParams.ValidFor0RTT(&tp)
 	if valid {
 		h.logger.Debugf("Accepting 0-RTT.")
+		h.runner.OnAccepted0RTT()
 	} else {
 		h.logger.Debugf("Transport parameters changed. Rejecting 0-RTT.")
 	}
@@ -563,8 +608,8 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 			panic("Received 0-RTT read key for the client")
 		}
 		h.zeroRTTOpener = newLongHeaderOpener(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 		)
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Read keys (using %s)", cipherSuiteName(suite.ID))
@@ -572,8 +617,8 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 	case qtls.EncryptionHandshake:
 		h.readEncLevel = protocol.EncryptionHandshake
 		h.handshakeOpener = newHandshakeOpener(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 			h.dropInitialKeys,
 			h.perspective,
 		)
@@ -598,8 +643,8 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 			panic("Received 0-RTT write key for the server")
 		}
 		h.zeroRTTSealer = newLongHeaderSealer(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 		)
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Write keys (using %s)", cipherSuiteName(suite.ID))
@@ -607,8 +652,8 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 	case qtls.EncryptionHandshake:
 		h.writeEncLevel = protocol.EncryptionHandshake
 		h.handshakeSealer = newHandshakeSealer(
-			createAEAD(suite, trafficSecret),
-			newHeaderProtector(suite, trafficSecret, true),
+			createAEAD(suite, trafficSecret, h.version),
+			newHeaderProtector(suite, trafficSecret, true, h.version),
 			h.dropInitialKeys,
 			h.perspective,
 		)
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"math/big"
+	"os"
 	"time"
 
 	gomock "github.com/golang/mock/gomock"
@@ -96,8 +97,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 			NewMockHandshakeRunner(mockCtrl),
 			tlsConf,
 			false,
+			1,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 		qtlsConf := server.(*cryptoSetup).tlsConf
 		Expect(qtlsConf.ServerName).To(Equal(tlsConf.ServerName))
@@ -128,8 +131,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			1,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -166,8 +171,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			1,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -207,8 +214,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			serverConf,
 			false,
+			1,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -241,8 +250,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 			NewMockHandshakeRunner(mockCtrl),
 			serverConf,
 			false,
+			1,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -256,7 +267,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 	})
 
 	Context("doing the handshake", func() {
-		var testDone chan struct{}
+		var (
+			testDone          chan struct{}
+			numSessionTickets int
+		)
 
 		generateCert := func() tls.Certificate {
 			priv, err := rsa.GenerateKey(rand.Reader, 2048)
@@ -279,6 +293,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 
 		BeforeEach(func() {
 			testDone = make(chan struct{})
+			numSessionTickets = 1
 		})
 
 		AfterEach(func() {
@@ -332,6 +347,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				enable0RTT,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
 			)
 
 			var sHandshakeComplete bool
@@ -352,8 +368,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				enable0RTT,
+				numSessionTickets,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("server"),
+				protocol.VersionTLS,
 			)
 
 			handshake(client, cChunkChan, server, sChunkChan)
@@ -407,6 +425,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				false,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
 			)
 
 			done := make(chan struct{})
@@ -448,6 +467,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				false,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
 			)
 
 			sChunkChan, sInitialStream, sHandshakeStream, _ := initStreams()
@@ -469,8 +489,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				false,
+				1,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("server"),
+				protocol.VersionTLS,
 			)
 
 			done := make(chan struct{})
@@ -503,6 +525,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					false,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
 				)
 
 				sChunkChan, sInitialStream, sHandshakeStream, _ := initStreams()
@@ -519,8 +542,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					1,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("server"),
+					protocol.VersionTLS,
 				)
 
 				done := make(chan struct{})
@@ -560,6 +585,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					false,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
 				)
 
 				sChunkChan, sInitialStream, sHandshakeStream, _ := initStreams()
@@ -576,8 +602,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					1,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("server"),
+					protocol.VersionTLS,
 				)
 
 				done := make(chan struct{})
@@ -652,6 +680,133 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(client.ConnectionState().DidResume).To(BeFalse())
 			})
 
+			It("sends multiple session tickets", func() {
+				numSessionTickets = 3
+				csc := NewMockClientSessionCache(mockCtrl)
+				receivedSessionTickets := make(chan struct{}, 10)
+				csc.EXPECT().Get(gomock.Any())
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).Do(func(string, *tls.ClientSessionState) {
+					receivedSessionTickets <- struct{}{}
+				}).Times(3)
+				clientConf.ClientSessionCache = csc
+				_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				for i := 0; i < 3; i++ {
+					Eventually(receivedSessionTickets).Should(Receive())
+				}
+				Consistently(receivedSessionTickets).ShouldNot(Receive())
+			})
+
+			It("doesn't send session tickets if the server disabled them", func() {
+				numSessionTickets = 3
+				serverConf.SessionTicketsDisabled = true
+				csc := NewMockClientSessionCache(mockCtrl)
+				csc.EXPECT().Get(gomock.Any())
+				clientConf.ClientSessionCache = csc
+				_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				// give the client some time to process a session ticket, if it had been sent
+				time.Sleep(50 * time.Millisecond)
+			})
+
+			It("saves the RTT and the transport parameters in the session state", func() {
+				rttStats := &congestion.RTTStats{}
+				rttStats.UpdateRTT(1337*time.Millisecond, 0, time.Now())
+				client, _ := NewCryptoSetupClient(
+					&bytes.Buffer{},
+					&bytes.Buffer{},
+					ioutil.Discard,
+					protocol.ConnectionID{},
+					nil,
+					&TransportParameters{},
+					NewMockHandshakeRunner(mockCtrl),
+					clientConf,
+					true,
+					rttStats,
+					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
+				)
+				client.(*cryptoSetup).peerParams = &TransportParameters{InitialMaxData: 0x1337}
+				data := client.(*cryptoSetup).marshalDataForSessionState()
+
+				restoredRTTStats := &congestion.RTTStats{}
+				restored, _ := NewCryptoSetupClient(
+					&bytes.Buffer{},
+					&bytes.Buffer{},
+					ioutil.Discard,
+					protocol.ConnectionID{},
+					nil,
+					&TransportParameters{},
+					NewMockHandshakeRunner(mockCtrl),
+					clientConf,
+					true,
+					restoredRTTStats,
+					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
+				)
+				restored.(*cryptoSetup).handleDataFromSessionState(data)
+				Expect(restoredRTTStats.SmoothedRTT()).To(Equal(1337 * time.Millisecond))
+				Expect(restored.(*cryptoSetup).zeroRTTParameters).ToNot(BeNil())
+				Expect(restored.(*cryptoSetup).zeroRTTParameters.InitialMaxData).To(Equal(protocol.ByteCount(0x1337)))
+			})
+
+			It("silently ignores session states with a different revision", func() {
+				rttStats := &congestion.RTTStats{}
+				rttStats.UpdateRTT(1337*time.Millisecond, 0, time.Now())
+				client, _ := NewCryptoSetupClient(
+					&bytes.Buffer{},
+					&bytes.Buffer{},
+					ioutil.Discard,
+					protocol.ConnectionID{},
+					nil,
+					&TransportParameters{},
+					NewMockHandshakeRunner(mockCtrl),
+					clientConf,
+					true,
+					rttStats,
+					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
+				)
+				client.(*cryptoSetup).peerParams = &TransportParameters{InitialMaxData: 0x1337}
+				data := client.(*cryptoSetup).marshalDataForSessionState()
+				// replace the revision with the one preceding the current revision
+				// This also covers session tickets saved before the revision was introduced.
+				b := &bytes.Buffer{}
+				utils.WriteVarInt(b, clientSessionStateRevision-1)
+				b.Write(data[utils.VarIntLen(clientSessionStateRevision):])
+
+				restoredRTTStats := &congestion.RTTStats{}
+				restored, _ := NewCryptoSetupClient(
+					&bytes.Buffer{},
+					&bytes.Buffer{},
+					ioutil.Discard,
+					protocol.ConnectionID{},
+					nil,
+					&TransportParameters{},
+					NewMockHandshakeRunner(mockCtrl),
+					clientConf,
+					true,
+					restoredRTTStats,
+					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
+				)
+				// make sure that nothing is printed to stdout
+				stdout := os.Stdout
+				r, w, err := os.Pipe()
+				Expect(err).ToNot(HaveOccurred())
+				os.Stdout = w
+				restored.(*cryptoSetup).handleDataFromSessionState(b.Bytes())
+				os.Stdout = stdout
+				Expect(w.Close()).To(Succeed())
+				output, err := ioutil.ReadAll(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(output).To(BeEmpty())
+				Expect(restored.(*cryptoSetup).zeroRTTParameters).To(BeNil())
+				Expect(restoredRTTStats.SmoothedRTT()).To(BeZero())
+			})
+
 			It("uses 0-RTT", func() {
 				csc := NewMockClientSessionCache(mockCtrl)
 				var state *tls.ClientSessionState
@@ -688,11 +843,16 @@ var _ = Describe("Crypto Setup TLS", func() {
 					true,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
 				)
 
 				sChunkChan, sInitialStream, sHandshakeStream, _ := initStreams()
 				sRunner := NewMockHandshakeRunner(mockCtrl)
-				sRunner.EXPECT().OnReceivedParams(gomock.Any())
+				// The 0-RTT decision is made before the client's transport parameters are passed on.
+				gomock.InOrder(
+					sRunner.EXPECT().OnAccepted0RTT(),
+					sRunner.EXPECT().OnReceivedParams(gomock.Any()),
+				)
 				sRunner.EXPECT().OnHandshakeComplete()
 				server = NewCryptoSetupServer(
 					sInitialStream,
@@ -704,8 +864,86 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					true,
+					1,
+					&congestion.RTTStats{},
+					utils.DefaultLogger.WithPrefix("server"),
+					protocol.VersionTLS,
+				)
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					handshake(client, cChunkChan, server, sChunkChan)
+					close(done)
+				}()
+				Eventually(done).Should(BeClosed())
+
+				Expect(clientHelloChan).To(Receive(Not(BeNil())))
+
+				Expect(server.ConnectionState().DidResume).To(BeTrue())
+				Expect(client.ConnectionState().DidResume).To(BeTrue())
+				Expect(server.ConnectionState().Used0RTT).To(BeTrue())
+				Expect(client.ConnectionState().Used0RTT).To(BeTrue())
+			})
+
+			It("rejects 0-RTT, when the transport parameters changed", func() {
+				csc := NewMockClientSessionCache(mockCtrl)
+				var state *tls.ClientSessionState
+				receivedSessionTicket := make(chan struct{})
+				csc.EXPECT().Get(gomock.Any())
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).Do(func(_ string, css *tls.ClientSessionState) {
+					state = css
+					close(receivedSessionTicket)
+				})
+				clientConf.ClientSessionCache = csc
+				client, clientErr, server, serverErr := handshakeWithTLSConf(clientConf, serverConf, true)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Eventually(receivedSessionTicket).Should(BeClosed())
+				Expect(server.ConnectionState().DidResume).To(BeFalse())
+				Expect(client.ConnectionState().DidResume).To(BeFalse())
+
+				csc.EXPECT().Get(gomock.Any()).Return(state, true)
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).MaxTimes(1)
+
+				cChunkChan, cInitialStream, cHandshakeStream, _ := initStreams()
+				cRunner := NewMockHandshakeRunner(mockCtrl)
+				cRunner.EXPECT().OnReceivedParams(gomock.Any())
+				cRunner.EXPECT().DropKeys(protocol.Encryption0RTT).MaxTimes(1)
+				cRunner.EXPECT().OnHandshakeComplete()
+				client, clientHelloChan := NewCryptoSetupClient(
+					cInitialStream,
+					cHandshakeStream,
+					ioutil.Discard,
+					protocol.ConnectionID{},
+					nil,
+					&TransportParameters{},
+					cRunner,
+					clientConf,
+					true,
+					&congestion.RTTStats{},
+					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
+				)
+
+				sChunkChan, sInitialStream, sHandshakeStream, _ := initStreams()
+				sRunner := NewMockHandshakeRunner(mockCtrl)
+				sRunner.EXPECT().OnReceivedParams(gomock.Any())
+				sRunner.EXPECT().OnHandshakeComplete()
+				server = NewCryptoSetupServer(
+					sInitialStream,
+					sHandshakeStream,
+					ioutil.Discard,
+					protocol.ConnectionID{},
+					nil,
+					&TransportParameters{InitialMaxData: 1337},
+					sRunner,
+					serverConf,
+					true,
+					1,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("server"),
+					protocol.VersionTLS,
 				)
 
 				done := make(chan struct{})
@@ -720,6 +958,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 
 				Expect(server.ConnectionState().DidResume).To(BeTrue())
 				Expect(client.ConnectionState().DidResume).To(BeTrue())
+				Expect(server.ConnectionState().Used0RTT).To(BeFalse())
+				Expect(client.ConnectionState().Used0RTT).To(BeFalse())
 			})
 		})
 	})
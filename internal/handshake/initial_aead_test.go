@@ -1,9 +1,11 @@
 package handshake
 
 import (
+	"crypto"
 	"math/rand"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/marten-seemann/qtls"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -26,23 +28,23 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		})
 
 		It("computes the client key and IV", func() {
-			clientSecret, _ := computeSecrets(connID)
+			clientSecret, _ := computeSecrets(connID, protocol.VersionTLS)
 			Expect(clientSecret).To(Equal(splitHexString("fda3953aecc040e48b34e27ef87de3a6 098ecf0e38b7e032c5c57bcbd5975b84")))
-			key, iv := computeInitialKeyAndIV(clientSecret)
+			key, iv := computeInitialKeyAndIV(clientSecret, protocol.VersionTLS)
 			Expect(key).To(Equal(splitHexString("af7fd7efebd21878ff66811248983694")))
 			Expect(iv).To(Equal(splitHexString("8681359410a70bb9c92f0420")))
 		})
 
 		It("computes the server key and IV", func() {
-			_, serverSecret := computeSecrets(connID)
+			_, serverSecret := computeSecrets(connID, protocol.VersionTLS)
 			Expect(serverSecret).To(Equal(splitHexString("554366b81912ff90be41f17e80222130 90ab17d8149179bcadf222f29ff2ddd5")))
-			key, iv := computeInitialKeyAndIV(serverSecret)
+			key, iv := computeInitialKeyAndIV(serverSecret, protocol.VersionTLS)
 			Expect(key).To(Equal(splitHexString("5d51da9ee897a21b2659ccc7e5bfa577")))
 			Expect(iv).To(Equal(splitHexString("5e5ae651fd1e8495af13508b")))
 		})
 
 		It("encrypts the client's Initial", func() {
-			sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveClient)
+			sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.VersionTLS)
 			header := splitHexString("c3ff000017088394c8f03e5157080000449e00000002")
 			data := splitHexString("060040c4010000c003036660261ff947 cea49cce6cfad687f457cf1b14531ba1 4131a0e8f309a1d0b9c4000006130113 031302010000910000000b0009000006 736572766572ff01000100000a001400 12001d00170018001901000101010201 03010400230000003300260024001d00 204cfdfcd178b784bf328cae793b136f 2aedce005ff183d7bb14952072366470 37002b0003020304000d0020001e0403 05030603020308040805080604010501 060102010402050206020202002d0002 0101001c00024001")
 			data = append(data, make([]byte, 1162-len(data))...) // add PADDING
@@ -57,7 +59,7 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		})
 
 		It("encrypt the server's Initial", func() {
-			sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveServer)
+			sealer, _ := NewInitialAEAD(connID, protocol.PerspectiveServer, protocol.VersionTLS)
 			header := splitHexString("c1ff0000170008f067a5502a4262b50040740001")
 			data := splitHexString("0d0000000018410a020000560303eefc e7f7b37ba1d1632e96677825ddf73988 cfc79825df566dc5430b9a045a120013 0100002e00330024001d00209d3c940d 89690b84d08a60993c144eca684d1081 287c834d5311bcf32bb9da1a002b0002 0304")
 			sealed := sealer.Seal(nil, data, 1, header)
@@ -70,10 +72,48 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 		})
 	})
 
+	// values taken from Appendix A of RFC 9369
+	Context("using the test vector from the QUIC v2 RFC", func() {
+		var connID protocol.ConnectionID
+
+		BeforeEach(func() {
+			connID = protocol.ConnectionID(splitHexString("0x8394c8f03e515708"))
+		})
+
+		It("computes the client key, IV and header protection key", func() {
+			clientSecret, _ := computeSecrets(connID, protocol.Version2)
+			Expect(clientSecret).To(Equal(splitHexString("14ec9d6eb9fd7af83bf5a668bc17a7e2 83766aade7ecd0891f70f9ff7f4bf47b")))
+			key, iv := computeInitialKeyAndIV(clientSecret, protocol.Version2)
+			Expect(key).To(Equal(splitHexString("8b1a0bc121284290a29e0971b5cd045d")))
+			Expect(iv).To(Equal(splitHexString("91f73e2351d8fa91660e909f")))
+			hpKey := qtls.HkdfExpandLabel(crypto.SHA256, clientSecret, []byte{}, hkdfHeaderProtectionLabel(protocol.Version2), 16)
+			Expect(hpKey).To(Equal(splitHexString("45b95e15235d6f45a6b19cbcb0294ba9")))
+		})
+
+		It("computes the server key, IV and header protection key", func() {
+			_, serverSecret := computeSecrets(connID, protocol.Version2)
+			Expect(serverSecret).To(Equal(splitHexString("0263db1782731bf4588e7e4d93b74639 07cb8cd8200b5da55a8bd488eafc37c1")))
+			key, iv := computeInitialKeyAndIV(serverSecret, protocol.Version2)
+			Expect(key).To(Equal(splitHexString("82db637861d55e1d011f19ea71d5d2a7")))
+			Expect(iv).To(Equal(splitHexString("dd13c276499c0249d3310652")))
+			hpKey := qtls.HkdfExpandLabel(crypto.SHA256, serverSecret, []byte{}, hkdfHeaderProtectionLabel(protocol.Version2), 16)
+			Expect(hpKey).To(Equal(splitHexString("edf6d05c83121201b436e16877593c3a")))
+		})
+	})
+
+	It("doesn't interoperate between QUIC versions", func() {
+		connID := protocol.ConnectionID{0x12, 0x34, 0x56, 0x78, 0x90, 0xab, 0xcd, 0xef}
+		clientSealer, _ := NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.Version2)
+		_, serverOpener := NewInitialAEAD(connID, protocol.PerspectiveServer, protocol.VersionTLS)
+		clientMessage := clientSealer.Seal(nil, []byte("foobar"), 42, []byte("aad"))
+		_, err := serverOpener.Open(nil, clientMessage, 42, []byte("aad"))
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+	})
+
 	It("seals and opens", func() {
 		connectionID := protocol.ConnectionID{0x12, 0x34, 0x56, 0x78, 0x90, 0xab, 0xcd, 0xef}
-		clientSealer, clientOpener := NewInitialAEAD(connectionID, protocol.PerspectiveClient)
-		serverSealer, serverOpener := NewInitialAEAD(connectionID, protocol.PerspectiveServer)
+		clientSealer, clientOpener := NewInitialAEAD(connectionID, protocol.PerspectiveClient, protocol.VersionTLS)
+		serverSealer, serverOpener := NewInitialAEAD(connectionID, protocol.PerspectiveServer, protocol.VersionTLS)
 
 		clientMessage := clientSealer.Seal(nil, []byte("foobar"), 42, []byte("aad"))
 		m, err := serverOpener.Open(nil, clientMessage, 42, []byte("aad"))
@@ -88,8 +128,8 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 	It("doesn't work if initialized with different connection IDs", func() {
 		c1 := protocol.ConnectionID{0, 0, 0, 0, 0, 0, 0, 1}
 		c2 := protocol.ConnectionID{0, 0, 0, 0, 0, 0, 0, 2}
-		clientSealer, _ := NewInitialAEAD(c1, protocol.PerspectiveClient)
-		_, serverOpener := NewInitialAEAD(c2, protocol.PerspectiveServer)
+		clientSealer, _ := NewInitialAEAD(c1, protocol.PerspectiveClient, protocol.VersionTLS)
+		_, serverOpener := NewInitialAEAD(c2, protocol.PerspectiveServer, protocol.VersionTLS)
 
 		clientMessage := clientSealer.Seal(nil, []byte("foobar"), 42, []byte("aad"))
 		_, err := serverOpener.Open(nil, clientMessage, 42, []byte("aad"))
@@ -98,8 +138,8 @@ var _ = Describe("Initial AEAD using AES-GCM", func() {
 
 	It("encrypts und decrypts the header", func() {
 		connID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
-		clientSealer, clientOpener := NewInitialAEAD(connID, protocol.PerspectiveClient)
-		serverSealer, serverOpener := NewInitialAEAD(connID, protocol.PerspectiveServer)
+		clientSealer, clientOpener := NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.VersionTLS)
+		serverSealer, serverOpener := NewInitialAEAD(connID, protocol.PerspectiveServer, protocol.VersionTLS)
 
 		// the first byte and the last 4 bytes should be encrypted
 		header := []byte{0x5e, 0, 1, 2, 3, 4, 0xde, 0xad, 0xbe, 0xef}
@@ -62,9 +62,11 @@ type tlsExtensionHandler interface {
 
 type handshakeRunner interface {
 	OnReceivedParams(*TransportParameters)
+	OnAccepted0RTT()
 	OnHandshakeComplete()
 	OnError(error)
 	DropKeys(protocol.EncryptionLevel)
+	OnKeyUpdate(phase protocol.KeyPhase, remote bool)
 }
 
 // CryptoSetup handles the handshake and protecting / unprotecting packets
@@ -75,6 +77,7 @@ type CryptoSetup interface {
 
 	HandleMessage([]byte, protocol.EncryptionLevel) bool
 	SetLargest1RTTAcked(protocol.PacketNumber)
+	InitiateKeyUpdate()
 	DropHandshakeKeys()
 	ConnectionState() ConnectionState
 
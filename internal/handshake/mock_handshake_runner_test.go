@@ -46,6 +46,18 @@ func (mr *MockHandshakeRunnerMockRecorder) DropKeys(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropKeys", reflect.TypeOf((*MockHandshakeRunner)(nil).DropKeys), arg0)
 }
 
+// OnAccepted0RTT mocks base method
+func (m *MockHandshakeRunner) OnAccepted0RTT() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnAccepted0RTT")
+}
+
+// OnAccepted0RTT indicates an expected call of OnAccepted0RTT
+func (mr *MockHandshakeRunnerMockRecorder) OnAccepted0RTT() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAccepted0RTT", reflect.TypeOf((*MockHandshakeRunner)(nil).OnAccepted0RTT))
+}
+
 // OnError mocks base method
 func (m *MockHandshakeRunner) OnError(arg0 error) {
 	m.ctrl.T.Helper()
@@ -70,6 +82,18 @@ func (mr *MockHandshakeRunnerMockRecorder) OnHandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnHandshakeComplete", reflect.TypeOf((*MockHandshakeRunner)(nil).OnHandshakeComplete))
 }
 
+// OnKeyUpdate mocks base method
+func (m *MockHandshakeRunner) OnKeyUpdate(arg0 protocol.KeyPhase, arg1 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnKeyUpdate", arg0, arg1)
+}
+
+// OnKeyUpdate indicates an expected call of OnKeyUpdate
+func (mr *MockHandshakeRunnerMockRecorder) OnKeyUpdate(arg0 interface{}, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnKeyUpdate", reflect.TypeOf((*MockHandshakeRunner)(nil).OnKeyUpdate), arg0, arg1)
+}
+
 // OnReceivedParams mocks base method
 func (m *MockHandshakeRunner) OnReceivedParams(arg0 *TransportParameters) {
 	m.ctrl.T.Helper()
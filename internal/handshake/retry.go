@@ -10,11 +10,17 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
-var retryAEAD cipher.AEAD
+var (
+	retryAEAD   cipher.AEAD
+	retryAEADV2 cipher.AEAD
+)
 
 func init() {
-	var key = [16]byte{0x4d, 0x32, 0xec, 0xdb, 0x2a, 0x21, 0x33, 0xc8, 0x41, 0xe4, 0x04, 0x3d, 0xf2, 0x7d, 0x44, 0x30}
+	retryAEAD = initAEAD([16]byte{0x4d, 0x32, 0xec, 0xdb, 0x2a, 0x21, 0x33, 0xc8, 0x41, 0xe4, 0x04, 0x3d, 0xf2, 0x7d, 0x44, 0x30})
+	retryAEADV2 = initAEAD([16]byte{0x8f, 0xb4, 0xb0, 0x1b, 0x56, 0xac, 0x48, 0xe2, 0x60, 0xfb, 0xcb, 0xce, 0xad, 0x7c, 0xcc, 0x92})
+}
 
+func initAEAD(key [16]byte) cipher.AEAD {
 	aes, err := aes.NewCipher(key[:])
 	if err != nil {
 		panic(err)
@@ -23,22 +29,28 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
-	retryAEAD = aead
+	return aead
 }
 
 var retryBuf bytes.Buffer
 var retryMutex sync.Mutex
 var retryNonce = [12]byte{0x4d, 0x16, 0x11, 0xd0, 0x55, 0x13, 0xa5, 0x52, 0xc5, 0x87, 0xd5, 0x75}
+var retryNonceV2 = [12]byte{0xd8, 0x69, 0x69, 0xbc, 0x2d, 0x7c, 0x6d, 0x99, 0x90, 0xef, 0xb0, 0x4a}
 
 // GetRetryIntegrityTag calculates the integrity tag on a Retry packet
-func GetRetryIntegrityTag(retry []byte, origDestConnID protocol.ConnectionID) *[16]byte {
+func GetRetryIntegrityTag(retry []byte, origDestConnID protocol.ConnectionID, version protocol.VersionNumber) *[16]byte {
 	retryMutex.Lock()
 	retryBuf.WriteByte(uint8(origDestConnID.Len()))
 	retryBuf.Write(origDestConnID.Bytes())
 	retryBuf.Write(retry)
 
 	var tag [16]byte
-	sealed := retryAEAD.Seal(tag[:0], retryNonce[:], nil, retryBuf.Bytes())
+	var sealed []byte
+	if version == protocol.Version2 {
+		sealed = retryAEADV2.Seal(tag[:0], retryNonceV2[:], nil, retryBuf.Bytes())
+	} else {
+		sealed = retryAEAD.Seal(tag[:0], retryNonce[:], nil, retryBuf.Bytes())
+	}
 	if len(sealed) != 16 {
 		panic(fmt.Sprintf("unexpected Retry integrity tag length: %d", len(sealed)))
 	}
@@ -46,3 +58,12 @@ func GetRetryIntegrityTag(retry []byte, origDestConnID protocol.ConnectionID) *[
 	retryMutex.Unlock()
 	return &tag
 }
+
+// VerifyRetryIntegrityTag checks the integrity tag at the end of a Retry packet
+func VerifyRetryIntegrityTag(retry []byte, origDestConnID protocol.ConnectionID, version protocol.VersionNumber) bool {
+	if len(retry) < 16 {
+		return false
+	}
+	tag := GetRetryIntegrityTag(retry[:len(retry)-16], origDestConnID, version)
+	return bytes.Equal(retry[len(retry)-16:], tag[:])
+}
@@ -9,22 +9,43 @@ import (
 
 var _ = Describe("Retry Integrity Check", func() {
 	It("calculates retry integrity tags", func() {
-		fooTag := GetRetryIntegrityTag([]byte("foo"), protocol.ConnectionID{1, 2, 3, 4})
-		barTag := GetRetryIntegrityTag([]byte("bar"), protocol.ConnectionID{1, 2, 3, 4})
+		fooTag := GetRetryIntegrityTag([]byte("foo"), protocol.ConnectionID{1, 2, 3, 4}, protocol.VersionTLS)
+		barTag := GetRetryIntegrityTag([]byte("bar"), protocol.ConnectionID{1, 2, 3, 4}, protocol.VersionTLS)
 		Expect(fooTag).ToNot(BeNil())
 		Expect(barTag).ToNot(BeNil())
 		Expect(*fooTag).ToNot(Equal(*barTag))
 	})
 
 	It("includes the original connection ID in the tag calculation", func() {
-		t1 := GetRetryIntegrityTag([]byte("foobar"), protocol.ConnectionID{1, 2, 3, 4})
-		t2 := GetRetryIntegrityTag([]byte("foobar"), protocol.ConnectionID{4, 3, 2, 1})
+		t1 := GetRetryIntegrityTag([]byte("foobar"), protocol.ConnectionID{1, 2, 3, 4}, protocol.VersionTLS)
+		t2 := GetRetryIntegrityTag([]byte("foobar"), protocol.ConnectionID{4, 3, 2, 1}, protocol.VersionTLS)
 		Expect(*t1).ToNot(Equal(*t2))
 	})
 
 	It("uses the test vector from the draft", func() {
 		connID := protocol.ConnectionID(splitHexString("0x8394c8f03e515708"))
 		data := splitHexString("ffff0000190008f067a5502a4262b574 6f6b656e1e5ec5b014cbb1f0fd93df40 48c446a6")
-		Expect(GetRetryIntegrityTag(data[:len(data)-16], connID)[:]).To(Equal(data[len(data)-16:]))
+		Expect(GetRetryIntegrityTag(data[:len(data)-16], connID, protocol.VersionTLS)[:]).To(Equal(data[len(data)-16:]))
+	})
+
+	It("uses the QUIC v2 test vector from RFC 9369", func() {
+		connID := protocol.ConnectionID(splitHexString("0x8394c8f03e515708"))
+		data := splitHexString("cf6b3343cf0008f067a5502a4262b574 6f6b656ec8646ce8bfe33952d9555436 65dcc7b6")
+		Expect(GetRetryIntegrityTag(data[:len(data)-16], connID, protocol.Version2)[:]).To(Equal(data[len(data)-16:]))
+		Expect(VerifyRetryIntegrityTag(data, connID, protocol.Version2)).To(BeTrue())
+		Expect(VerifyRetryIntegrityTag(data, connID, protocol.VersionTLS)).To(BeFalse())
+	})
+
+	It("verifies retry integrity tags", func() {
+		connID := protocol.ConnectionID(splitHexString("0x8394c8f03e515708"))
+		data := splitHexString("ffff0000190008f067a5502a4262b574 6f6b656e1e5ec5b014cbb1f0fd93df40 48c446a6")
+		Expect(VerifyRetryIntegrityTag(data, connID, protocol.VersionTLS)).To(BeTrue())
+		Expect(VerifyRetryIntegrityTag(data, protocol.ConnectionID{1, 2, 3, 4}, protocol.VersionTLS)).To(BeFalse())
+		data[len(data)-1]++
+		Expect(VerifyRetryIntegrityTag(data, connID, protocol.VersionTLS)).To(BeFalse())
+	})
+
+	It("rejects packets that are too short to contain an integrity tag", func() {
+		Expect(VerifyRetryIntegrityTag(make([]byte, 15), protocol.ConnectionID{1, 2, 3, 4}, protocol.VersionTLS)).To(BeFalse())
 	})
 })
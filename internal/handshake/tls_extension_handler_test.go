@@ -18,10 +18,12 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 		handlerServer = newExtensionHandler(
 			[]byte("foobar"),
 			protocol.PerspectiveServer,
+			protocol.VersionTLS,
 		)
 		handlerClient = newExtensionHandler(
 			[]byte("raboof"),
 			protocol.PerspectiveClient,
+			protocol.VersionTLS,
 		)
 	})
 
@@ -36,7 +38,7 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 			It("adds TransportParameters to the EncryptedExtensions message", func() {
 				exts := handlerServer.GetExtensions(uint8(typeEncryptedExtensions))
 				Expect(exts).To(HaveLen(1))
-				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionType))
+				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionTypeOldDrafts))
 				Expect(exts[0].Data).To(Equal([]byte("foobar")))
 			})
 		})
@@ -105,9 +107,15 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 			It("adds TransportParameters to the ClientHello message", func() {
 				exts := handlerClient.GetExtensions(uint8(typeClientHello))
 				Expect(exts).To(HaveLen(1))
-				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionType))
+				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionTypeOldDrafts))
 				Expect(exts[0].Data).To(Equal([]byte("raboof")))
 			})
+
+			It("uses the code point of RFC 9000 for QUIC v2", func() {
+				exts := newExtensionHandler([]byte("raboof"), protocol.PerspectiveClient, protocol.Version2).GetExtensions(uint8(typeClientHello))
+				Expect(exts).To(HaveLen(1))
+				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionType))
+			})
 		})
 
 		Context("receiving", func() {
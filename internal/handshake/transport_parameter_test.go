@@ -66,52 +66,174 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.String()).To(Equal("&handshake.TransportParameters{OriginalConnectionID: 0xdeadbeef, InitialMaxStreamDataBidiLocal: 0x1234, InitialMaxStreamDataBidiRemote: 0x2345, InitialMaxStreamDataUni: 0x3456, InitialMaxData: 0x4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37s, ActiveConnectionIDLimit: 89}"))
 	})
 
-	It("marshals and unmarshals", func() {
-		var token [16]byte
-		rand.Read(token[:])
-		params := &TransportParameters{
-			InitialMaxStreamDataBidiLocal:  protocol.ByteCount(getRandomValue()),
-			InitialMaxStreamDataBidiRemote: protocol.ByteCount(getRandomValue()),
-			InitialMaxStreamDataUni:        protocol.ByteCount(getRandomValue()),
-			InitialMaxData:                 protocol.ByteCount(getRandomValue()),
-			MaxIdleTimeout:                 0xcafe * time.Second,
-			MaxBidiStreamNum:               protocol.StreamNum(getRandomValue()),
-			MaxUniStreamNum:                protocol.StreamNum(getRandomValue()),
-			DisableActiveMigration:         true,
-			StatelessResetToken:            &token,
-			OriginalConnectionID:           protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
-			AckDelayExponent:               13,
-			MaxAckDelay:                    42 * time.Millisecond,
-			ActiveConnectionIDLimit:        getRandomValue(),
-		}
-		data := params.Marshal()
+	for _, v := range []protocol.VersionNumber{protocol.VersionTLS, protocol.Version2} {
+		version := v
+
+		Context(fmt.Sprintf("using %s", version), func() {
+			It("marshals and unmarshals", func() {
+				var token [16]byte
+				rand.Read(token[:])
+				params := &TransportParameters{
+					InitialMaxStreamDataBidiLocal:  protocol.ByteCount(getRandomValue()),
+					InitialMaxStreamDataBidiRemote: protocol.ByteCount(getRandomValue()),
+					InitialMaxStreamDataUni:        protocol.ByteCount(getRandomValue()),
+					InitialMaxData:                 protocol.ByteCount(getRandomValue()),
+					MaxIdleTimeout:                 0xcafe * time.Second,
+					MaxBidiStreamNum:               protocol.StreamNum(getRandomValue()),
+					MaxUniStreamNum:                protocol.StreamNum(getRandomValue()),
+					DisableActiveMigration:         true,
+					StatelessResetToken:            &token,
+					OriginalConnectionID:           protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
+					InitialSourceConnectionID:      protocol.ConnectionID{0xca, 0xfe},
+					RetrySourceConnectionID:        &protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+					AckDelayExponent:               13,
+					MaxAckDelay:                    42 * time.Millisecond,
+					ActiveConnectionIDLimit:        getRandomValue(),
+				}
+				data := params.Marshal(version)
+
+				p := &TransportParameters{}
+				Expect(p.Unmarshal(data, protocol.PerspectiveServer, version)).To(Succeed())
+				Expect(p.InitialMaxStreamDataBidiLocal).To(Equal(params.InitialMaxStreamDataBidiLocal))
+				Expect(p.InitialMaxStreamDataBidiRemote).To(Equal(params.InitialMaxStreamDataBidiRemote))
+				Expect(p.InitialMaxStreamDataUni).To(Equal(params.InitialMaxStreamDataUni))
+				Expect(p.InitialMaxData).To(Equal(params.InitialMaxData))
+				Expect(p.MaxUniStreamNum).To(Equal(params.MaxUniStreamNum))
+				Expect(p.MaxBidiStreamNum).To(Equal(params.MaxBidiStreamNum))
+				Expect(p.MaxIdleTimeout).To(Equal(params.MaxIdleTimeout))
+				Expect(p.DisableActiveMigration).To(Equal(params.DisableActiveMigration))
+				Expect(p.StatelessResetToken).To(Equal(params.StatelessResetToken))
+				Expect(p.OriginalConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}))
+				if version.UsesConnectionIDTransportParameters() {
+					Expect(p.InitialSourceConnectionID).To(Equal(protocol.ConnectionID{0xca, 0xfe}))
+					Expect(p.RetrySourceConnectionID).To(Equal(&protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}))
+				} else {
+					Expect(p.InitialSourceConnectionID).To(BeEmpty())
+					Expect(p.RetrySourceConnectionID).To(BeNil())
+				}
+				Expect(p.AckDelayExponent).To(Equal(uint8(13)))
+				Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
+				Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+			})
+
+			It("marshals and unmarshals the preferred_address", func() {
+				pa := &PreferredAddress{
+					IPv4:                net.IPv4(127, 0, 0, 1),
+					IPv4Port:            42,
+					IPv6:                net.IP{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+					IPv6Port:            13,
+					ConnectionID:        protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+					StatelessResetToken: [16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+				}
+				data := (&TransportParameters{
+					PreferredAddress:     pa,
+					OriginalConnectionID: protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
+				}).Marshal(version)
+				p := &TransportParameters{}
+				Expect(p.Unmarshal(data, protocol.PerspectiveServer, version)).To(Succeed())
+				Expect(p.PreferredAddress.IPv4.String()).To(Equal(pa.IPv4.String()))
+				Expect(p.PreferredAddress.IPv6.String()).To(Equal(pa.IPv6.String()))
+				Expect(p.PreferredAddress.ConnectionID).To(Equal(pa.ConnectionID))
+				Expect(p.PreferredAddress.StatelessResetToken).To(Equal(pa.StatelessResetToken))
+			})
+		})
+	}
+
+	Context("authenticating connection IDs", func() {
+		It("marshals and unmarshals an empty initial_source_connection_id", func() {
+			data := (&TransportParameters{}).Marshal(protocol.Version2)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient, protocol.Version2)).To(Succeed())
+			Expect(p.InitialSourceConnectionID).To(BeEmpty())
+			Expect(p.RetrySourceConnectionID).To(BeNil())
+		})
+
+		It("errors if the initial_source_connection_id is missing", func() {
+			b := &bytes.Buffer{}
+			(&TransportParameters{}).marshalVarintParam(b, true, initialMaxDataParameterID, 0x1337)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveClient, protocol.Version2)).To(MatchError("TRANSPORT_PARAMETER_ERROR: missing initial_source_connection_id"))
+		})
+
+		It("errors if the server didn't send an original_destination_connection_id", func() {
+			data := (&TransportParameters{InitialSourceConnectionID: protocol.ConnectionID{1, 2, 3, 4}}).Marshal(protocol.Version2)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.Version2)).To(MatchError("TRANSPORT_PARAMETER_ERROR: missing original_destination_connection_id"))
+		})
+
+		It("errors if the client sent a retry_source_connection_id", func() {
+			data := (&TransportParameters{RetrySourceConnectionID: &protocol.ConnectionID{1, 2, 3, 4}}).Marshal(protocol.Version2)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient, protocol.Version2)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a retry_source_connection_id"))
+		})
+
+		It("ignores the connection ID parameters for older versions", func() {
+			b := &bytes.Buffer{}
+			writeTransportParameterHeader(b, initialSourceConnectionIDParameterID, 4, false)
+			b.Write([]byte{1, 2, 3, 4})
+			writeTransportParameterHeader(b, retrySourceConnectionIDParameterID, 4, false)
+			b.Write([]byte{5, 6, 7, 8})
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
+			Expect(p.InitialSourceConnectionID).To(BeEmpty())
+			Expect(p.RetrySourceConnectionID).To(BeNil())
+			data := (&TransportParameters{
+				InitialSourceConnectionID: protocol.ConnectionID{1, 2, 3, 4},
+				RetrySourceConnectionID:   &protocol.ConnectionID{5, 6, 7, 8},
+			}).Marshal(protocol.VersionTLS)
+			Expect(data).ToNot(ContainSubstring(string([]byte{1, 2, 3, 4})))
+			Expect(data).ToNot(ContainSubstring(string([]byte{5, 6, 7, 8})))
+		})
+	})
+
+	It("uses variable-length integers for the parameter IDs and lengths, for QUIC v2", func() {
+		data := (&TransportParameters{DisableActiveMigration: true}).Marshal(protocol.Version2)
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, uint64(disableActiveMigrationParameterID))
+		utils.WriteVarInt(b, 0)
+		Expect(data).To(ContainSubstring(b.String()))
+		// the old encoding starts with the length of the parameters
+		data = (&TransportParameters{DisableActiveMigration: true}).Marshal(protocol.VersionTLS)
+		Expect(binary.BigEndian.Uint16(data[:2])).To(BeEquivalentTo(len(data) - 2))
+	})
 
+	It("errors if a parameter is longer than the remaining data, for QUIC v2", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, uint64(initialMaxDataParameterID))
+		utils.WriteVarInt(b, 7)
+		b.Write([]byte{1, 2, 3, 4, 5, 6})
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
-		Expect(p.InitialMaxStreamDataBidiLocal).To(Equal(params.InitialMaxStreamDataBidiLocal))
-		Expect(p.InitialMaxStreamDataBidiRemote).To(Equal(params.InitialMaxStreamDataBidiRemote))
-		Expect(p.InitialMaxStreamDataUni).To(Equal(params.InitialMaxStreamDataUni))
-		Expect(p.InitialMaxData).To(Equal(params.InitialMaxData))
-		Expect(p.MaxUniStreamNum).To(Equal(params.MaxUniStreamNum))
-		Expect(p.MaxBidiStreamNum).To(Equal(params.MaxBidiStreamNum))
-		Expect(p.MaxIdleTimeout).To(Equal(params.MaxIdleTimeout))
-		Expect(p.DisableActiveMigration).To(Equal(params.DisableActiveMigration))
-		Expect(p.StatelessResetToken).To(Equal(params.StatelessResetToken))
-		Expect(p.OriginalConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}))
-		Expect(p.AckDelayExponent).To(Equal(uint8(13)))
-		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
-		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+		Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveServer, protocol.Version2)).To(MatchError("TRANSPORT_PARAMETER_ERROR: remaining length (6) smaller than parameter length (7)"))
+	})
+
+	It("doesn't parse transport parameters using the encoding of a different version", func() {
+		data := (&TransportParameters{InitialMaxData: 0x1337}).Marshal(protocol.Version2)
+		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).ToNot(Succeed())
+	})
+
+	It("marshals the max_packet_size", func() {
+		data := (&TransportParameters{MaxPacketSize: 1300}).Marshal(protocol.VersionTLS)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(Succeed())
+		Expect(p.MaxPacketSize).To(Equal(protocol.ByteCount(1300)))
+	})
+
+	It("uses the maximum receive packet size if no max_packet_size is set", func() {
+		data := (&TransportParameters{}).Marshal(protocol.VersionTLS)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(Succeed())
+		Expect(p.MaxPacketSize).To(Equal(protocol.MaxReceivePacketSize))
 	})
 
 	It("errors if the transport parameters are too short to contain the length", func() {
-		Expect((&TransportParameters{}).Unmarshal([]byte{0}, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: transport parameter data too short"))
+		Expect((&TransportParameters{}).Unmarshal([]byte{0}, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: transport parameter data too short"))
 	})
 
 	It("errors if the transport parameters are too short to contain the length", func() {
 		data := make([]byte, 2)
 		binary.BigEndian.PutUint16(data, 42)
 		data = append(data, make([]byte, 41)...)
-		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected transport parameters to be 42 bytes long, have 41"))
+		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected transport parameters to be 42 bytes long, have 41"))
 	})
 
 	It("errors when the stateless_reset_token has the wrong length", func() {
@@ -120,7 +242,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, 15)
 		b.Write(make([]byte, 15))
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for stateless_reset_token: 15 (expected 16)"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for stateless_reset_token: 15 (expected 16)"))
 	})
 
 	It("errors when the max_packet_size is too small", func() {
@@ -129,7 +251,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(1199)))
 		utils.WriteVarInt(b, 1199)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for max_packet_size: 1199 (minimum 1200)"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for max_packet_size: 1199 (minimum 1200)"))
 	})
 
 	It("errors when disable_active_migration has content", func() {
@@ -138,13 +260,13 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, 6)
 		b.Write([]byte("foobar"))
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for disable_active_migration: 6 (expected empty)"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for disable_active_migration: 6 (expected empty)"))
 	})
 
 	It("errors when the max_ack_delay is too large", func() {
-		data := (&TransportParameters{MaxAckDelay: 1 << 14 * time.Millisecond}).Marshal()
+		data := (&TransportParameters{MaxAckDelay: 1 << 14 * time.Millisecond}).Marshal(protocol.VersionTLS)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for max_ack_delay: 16384ms (maximum 16383ms)"))
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for max_ack_delay: 16384ms (maximum 16383ms)"))
 	})
 
 	It("doesn't send the max_ack_delay, if it has the default value", func() {
@@ -152,18 +274,18 @@ var _ = Describe("Transport Parameters", func() {
 		var defaultLen, dataLen int
 		// marshal 1000 times to average out the greasing transport parameter
 		for i := 0; i < num; i++ {
-			dataDefault := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay}).Marshal()
+			dataDefault := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay}).Marshal(protocol.VersionTLS)
 			defaultLen += len(dataDefault)
-			data := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay + time.Millisecond}).Marshal()
+			data := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay + time.Millisecond}).Marshal(protocol.VersionTLS)
 			dataLen += len(data)
 		}
 		Expect(float32(dataLen) / num).To(BeNumerically("~", float32(defaultLen)/num+2 /* parameter ID */ +2 /* length field */ +1 /* value */, 1))
 	})
 
 	It("errors when the ack_delay_exponenent is too large", func() {
-		data := (&TransportParameters{AckDelayExponent: 21}).Marshal()
+		data := (&TransportParameters{AckDelayExponent: 21}).Marshal(protocol.VersionTLS)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for ack_delay_exponent: 21 (maximum 20)"))
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for ack_delay_exponent: 21 (maximum 20)"))
 	})
 
 	It("doesn't send the ack_delay_exponent, if it has the default value", func() {
@@ -171,18 +293,18 @@ var _ = Describe("Transport Parameters", func() {
 		var defaultLen, dataLen int
 		// marshal 1000 times to average out the greasing transport parameter
 		for i := 0; i < num; i++ {
-			dataDefault := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent}).Marshal()
+			dataDefault := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent}).Marshal(protocol.VersionTLS)
 			defaultLen += len(dataDefault)
-			data := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent + 1}).Marshal()
+			data := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent + 1}).Marshal(protocol.VersionTLS)
 			dataLen += len(data)
 		}
 		Expect(float32(dataLen) / num).To(BeNumerically("~", float32(defaultLen)/num+2 /* parameter ID */ +2 /* length field */ +1 /* value */, 1))
 	})
 
 	It("sets the default value for the ack_delay_exponent, when no value was sent", func() {
-		data := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent}).Marshal()
+		data := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent}).Marshal(protocol.VersionTLS)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 		Expect(p.AckDelayExponent).To(BeEquivalentTo(protocol.DefaultAckDelayExponent))
 	})
 
@@ -194,11 +316,40 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(utils.VarIntLen(val)).ToNot(BeEquivalentTo(2))
 		utils.WriteVarInt(b, val)
 		p := &TransportParameters{}
-		err := p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)
+		err := p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("TRANSPORT_PARAMETER_ERROR: inconsistent transport parameter length"))
 	})
 
+	It("marshals and unmarshals the min_ack_delay", func() {
+		minAckDelay := 1500 * time.Microsecond
+		data := (&TransportParameters{
+			MaxAckDelay: protocol.DefaultMaxAckDelay,
+			MinAckDelay: &minAckDelay,
+		}).Marshal(protocol.VersionTLS)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
+		Expect(p.MinAckDelay).ToNot(BeNil())
+		Expect(*p.MinAckDelay).To(Equal(minAckDelay))
+	})
+
+	It("doesn't send the min_ack_delay, if it is not set", func() {
+		data := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay}).Marshal(protocol.VersionTLS)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
+		Expect(p.MinAckDelay).To(BeNil())
+	})
+
+	It("errors when the min_ack_delay is larger than the max_ack_delay", func() {
+		minAckDelay := 30 * time.Millisecond
+		data := (&TransportParameters{
+			MaxAckDelay: 20 * time.Millisecond,
+			MinAckDelay: &minAckDelay,
+		}).Marshal(protocol.VersionTLS)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: min_ack_delay (30ms) is larger than max_ack_delay (20ms)"))
+	})
+
 	It("handles max_ack_delays that decode to a negative duration", func() {
 		b := &bytes.Buffer{}
 		val := uint64(math.MaxUint64) / 5
@@ -206,7 +357,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(val)))
 		utils.WriteVarInt(b, val)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 		Expect(p.MaxAckDelay).To(BeNumerically(">", 290*365*24*time.Hour))
 	})
 
@@ -225,7 +376,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(0x42)))
 		utils.WriteVarInt(b, 0x42)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 		Expect(p.InitialMaxStreamDataBidiLocal).To(Equal(protocol.ByteCount(0x1337)))
 		Expect(p.InitialMaxStreamDataBidiRemote).To(Equal(protocol.ByteCount(0x42)))
 	})
@@ -245,7 +396,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(0x1337)))
 		utils.WriteVarInt(b, 0x1337)
 		p := &TransportParameters{}
-		err := p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)
+		err := p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("received duplicate transport parameter"))
 	})
@@ -256,7 +407,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, 7)
 		b.Write([]byte("foobar"))
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: remaining length (6) smaller than parameter length (7)"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: remaining length (6) smaller than parameter length (7)"))
 	})
 
 	It("errors if there's unprocessed data after reading", func() {
@@ -266,22 +417,22 @@ var _ = Describe("Transport Parameters", func() {
 		utils.WriteVarInt(b, 0x1337)
 		b.Write([]byte("foo"))
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: should have read all data. Still have 3 bytes"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: should have read all data. Still have 3 bytes"))
 	})
 
 	It("errors if the client sent a stateless_reset_token", func() {
 		var token [16]byte
 		params := &TransportParameters{StatelessResetToken: &token}
-		data := params.Marshal()
-		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a stateless_reset_token"))
+		data := params.Marshal(protocol.VersionTLS)
+		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a stateless_reset_token"))
 	})
 
 	It("errors if the client sent a stateless_reset_token", func() {
 		params := &TransportParameters{
 			OriginalConnectionID: protocol.ConnectionID{0xca, 0xfe},
 		}
-		data := params.Marshal()
-		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent an original_connection_id"))
+		data := params.Marshal(protocol.VersionTLS)
+		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent an original_connection_id"))
 	})
 
 	Context("preferred address", func() {
@@ -295,9 +446,9 @@ var _ = Describe("Transport Parameters", func() {
 		}
 
 		It("marshals and unmarshals", func() {
-			data := (&TransportParameters{PreferredAddress: pa}).Marshal()
+			data := (&TransportParameters{PreferredAddress: pa}).Marshal(protocol.VersionTLS)
 			p := &TransportParameters{}
-			Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 			Expect(p.PreferredAddress.IPv4.String()).To(Equal(pa.IPv4.String()))
 			Expect(p.PreferredAddress.IPv4Port).To(Equal(pa.IPv4Port))
 			Expect(p.PreferredAddress.IPv6.String()).To(Equal(pa.IPv6.String()))
@@ -307,9 +458,9 @@ var _ = Describe("Transport Parameters", func() {
 		})
 
 		It("errors if the client sent a preferred_address", func() {
-			data := (&TransportParameters{PreferredAddress: pa}).Marshal()
+			data := (&TransportParameters{PreferredAddress: pa}).Marshal(protocol.VersionTLS)
 			p := &TransportParameters{}
-			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a preferred_address"))
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a preferred_address"))
 		})
 
 		It("errors on EOF", func() {
@@ -327,7 +478,7 @@ var _ = Describe("Transport Parameters", func() {
 				utils.BigEndian.WriteUint16(buf, uint16(preferredAddressParamaterID))
 				buf.Write(prependLength(raw[:i]))
 				p := &TransportParameters{}
-				Expect(p.Unmarshal(prependLength(buf.Bytes()), protocol.PerspectiveServer)).ToNot(Succeed())
+				Expect(p.Unmarshal(prependLength(buf.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).ToNot(Succeed())
 			}
 		})
 	})
@@ -23,7 +23,7 @@ func init() {
 	rand.Seed(time.Now().UTC().UnixNano())
 }
 
-type transportParameterID uint16
+type transportParameterID uint64
 
 const (
 	originalConnectionIDParameterID           transportParameterID = 0x0
@@ -41,6 +41,10 @@ const (
 	disableActiveMigrationParameterID         transportParameterID = 0xc
 	preferredAddressParamaterID               transportParameterID = 0xd
 	activeConnectionIDLimitParameterID        transportParameterID = 0xe
+	initialSourceConnectionIDParameterID      transportParameterID = 0xf
+	retrySourceConnectionIDParameterID        transportParameterID = 0x10
+	// https://tools.ietf.org/html/draft-iyengar-quic-delayed-ack-00#section-3
+	minAckDelayParameterID transportParameterID = 0xde1a
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -62,6 +66,8 @@ type TransportParameters struct {
 
 	MaxAckDelay      time.Duration
 	AckDelayExponent uint8
+	// MinAckDelay is only set if the ACK frequency extension is used.
+	MinAckDelay *time.Duration
 
 	DisableActiveMigration bool
 
@@ -74,26 +80,36 @@ type TransportParameters struct {
 
 	PreferredAddress *PreferredAddress
 
-	StatelessResetToken     *[16]byte
-	OriginalConnectionID    protocol.ConnectionID
+	StatelessResetToken  *[16]byte
+	OriginalConnectionID protocol.ConnectionID
+	// InitialSourceConnectionID and RetrySourceConnectionID are only used
+	// by versions that authenticate the connection IDs, see RFC 9000 section 7.3.
+	InitialSourceConnectionID protocol.ConnectionID
+	RetrySourceConnectionID   *protocol.ConnectionID
+
 	ActiveConnectionIDLimit uint64
 }
 
-// Unmarshal the transport parameters
-func (p *TransportParameters) Unmarshal(data []byte, sentBy protocol.Perspective) error {
-	if err := p.unmarshal(data, sentBy); err != nil {
+// Unmarshal the transport parameters.
+// The encoding depends on the QUIC version.
+func (p *TransportParameters) Unmarshal(data []byte, sentBy protocol.Perspective, v protocol.VersionNumber) error {
+	if err := p.unmarshal(data, sentBy, v); err != nil {
 		return qerr.Error(qerr.TransportParameterError, err.Error())
 	}
 	return nil
 }
 
-func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective) error {
-	if len(data) < 2 {
-		return errors.New("transport parameter data too short")
-	}
-	length := binary.BigEndian.Uint16(data[:2])
-	if len(data)-2 < int(length) {
-		return fmt.Errorf("expected transport parameters to be %d bytes long, have %d", length, len(data)-2)
+func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective, v protocol.VersionNumber) error {
+	varIntEncoding := v.UsesVarIntTransportParameters()
+	if !varIntEncoding {
+		if len(data) < 2 {
+			return errors.New("transport parameter data too short")
+		}
+		length := binary.BigEndian.Uint16(data[:2])
+		if len(data)-2 < int(length) {
+			return fmt.Errorf("expected transport parameters to be %d bytes long, have %d", length, len(data)-2)
+		}
+		data = data[2:]
 	}
 
 	// needed to check that every parameter is only sent at most once
@@ -101,12 +117,17 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 
 	var readAckDelayExponent bool
 	var readMaxAckDelay bool
+	var readInitialSourceConnectionID bool
 
-	r := bytes.NewReader(data[2:])
-	for r.Len() >= 4 {
-		paramIDInt, _ := utils.BigEndian.ReadUint16(r)
-		paramID := transportParameterID(paramIDInt)
-		paramLen, _ := utils.BigEndian.ReadUint16(r)
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		if !varIntEncoding && r.Len() < 4 {
+			break
+		}
+		paramID, paramLen, err := readTransportParameterHeader(r, varIntEncoding)
+		if err != nil {
+			return err
+		}
 		parameterIDs = append(parameterIDs, paramID)
 		switch paramID {
 		case ackDelayExponentParameterID:
@@ -127,7 +148,8 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 			initialMaxStreamsUniParameterID,
 			maxIdleTimeoutParameterID,
 			maxPacketSizeParameterID,
-			activeConnectionIDLimitParameterID:
+			activeConnectionIDLimitParameterID,
+			minAckDelayParameterID:
 			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
 				return err
 			}
@@ -163,12 +185,39 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 					return errors.New("client sent an original_connection_id")
 				}
 				p.OriginalConnectionID, _ = protocol.ReadConnectionID(r, int(paramLen))
+			case initialSourceConnectionIDParameterID:
+				if !v.UsesConnectionIDTransportParameters() {
+					r.Seek(int64(paramLen), io.SeekCurrent)
+					break
+				}
+				p.InitialSourceConnectionID, _ = protocol.ReadConnectionID(r, int(paramLen))
+				readInitialSourceConnectionID = true
+			case retrySourceConnectionIDParameterID:
+				if !v.UsesConnectionIDTransportParameters() {
+					r.Seek(int64(paramLen), io.SeekCurrent)
+					break
+				}
+				if sentBy == protocol.PerspectiveClient {
+					return errors.New("client sent a retry_source_connection_id")
+				}
+				connID, _ := protocol.ReadConnectionID(r, int(paramLen))
+				p.RetrySourceConnectionID = &connID
 			default:
 				r.Seek(int64(paramLen), io.SeekCurrent)
 			}
 		}
 	}
 
+	if v.UsesConnectionIDTransportParameters() {
+		// The client's original destination connection ID is at least 8 bytes long.
+		if sentBy == protocol.PerspectiveServer && p.OriginalConnectionID.Len() == 0 {
+			return errors.New("missing original_destination_connection_id")
+		}
+		if !readInitialSourceConnectionID {
+			return errors.New("missing initial_source_connection_id")
+		}
+	}
+
 	if !readAckDelayExponent {
 		p.AckDelayExponent = protocol.DefaultAckDelayExponent
 	}
@@ -178,6 +227,9 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 	if p.MaxPacketSize == 0 {
 		p.MaxPacketSize = protocol.MaxByteCount
 	}
+	if p.MinAckDelay != nil && *p.MinAckDelay > p.MaxAckDelay {
+		return fmt.Errorf("min_ack_delay (%s) is larger than max_ack_delay (%s)", *p.MinAckDelay, p.MaxAckDelay)
+	}
 
 	// check that every transport parameter was sent at most once
 	sort.Slice(parameterIDs, func(i, j int) bool { return parameterIDs[i] < parameterIDs[j] })
@@ -193,6 +245,32 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 	return nil
 }
 
+func readTransportParameterHeader(r *bytes.Reader, varIntEncoding bool) (transportParameterID, uint64, error) {
+	if !varIntEncoding {
+		id, err := utils.BigEndian.ReadUint16(r)
+		if err != nil {
+			return 0, 0, err
+		}
+		l, err := utils.BigEndian.ReadUint16(r)
+		if err != nil {
+			return 0, 0, err
+		}
+		return transportParameterID(id), uint64(l), nil
+	}
+	id, err := utils.ReadVarInt(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	l, err := utils.ReadVarInt(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	if l > uint64(r.Len()) {
+		return 0, 0, fmt.Errorf("remaining length (%d) smaller than parameter length (%d)", r.Len(), l)
+	}
+	return transportParameterID(id), l, nil
+}
+
 func (p *TransportParameters) readPreferredAddress(r *bytes.Reader, expectedLen int) error {
 	remainingLen := r.Len()
 	pa := &PreferredAddress{}
@@ -284,64 +362,75 @@ func (p *TransportParameters) readNumericTransportParameter(
 		p.MaxAckDelay = maxAckDelay
 	case activeConnectionIDLimitParameterID:
 		p.ActiveConnectionIDLimit = val
+	case minAckDelayParameterID:
+		// prevents overflows if the value is too large
+		minAckDelay := utils.InfDuration
+		if val < uint64(utils.InfDuration/time.Microsecond) {
+			minAckDelay = time.Duration(val) * time.Microsecond
+		}
+		p.MinAckDelay = &minAckDelay
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
 	return nil
 }
 
-// Marshal the transport parameters
-func (p *TransportParameters) Marshal() []byte {
+// Marshal the transport parameters.
+// The encoding depends on the QUIC version.
+func (p *TransportParameters) Marshal(v protocol.VersionNumber) []byte {
+	varIntEncoding := v.UsesVarIntTransportParameters()
 	b := &bytes.Buffer{}
-	b.Write([]byte{0, 0}) // length. Will be replaced later
+	if !varIntEncoding {
+		b.Write([]byte{0, 0}) // length. Will be replaced later
+	}
 
 	//add a greased value
-	utils.BigEndian.WriteUint16(b, uint16(27+31*rand.Intn(100)))
 	length := rand.Intn(16)
 	randomData := make([]byte, length)
 	rand.Read(randomData)
-	utils.BigEndian.WriteUint16(b, uint16(length))
+	writeTransportParameterHeader(b, transportParameterID(27+31*rand.Intn(100)), length, varIntEncoding)
 	b.Write(randomData)
 
 	// initial_max_stream_data_bidi_local
-	p.marshalVarintParam(b, initialMaxStreamDataBidiLocalParameterID, uint64(p.InitialMaxStreamDataBidiLocal))
+	p.marshalVarintParam(b, varIntEncoding, initialMaxStreamDataBidiLocalParameterID, uint64(p.InitialMaxStreamDataBidiLocal))
 	// initial_max_stream_data_bidi_remote
-	p.marshalVarintParam(b, initialMaxStreamDataBidiRemoteParameterID, uint64(p.InitialMaxStreamDataBidiRemote))
+	p.marshalVarintParam(b, varIntEncoding, initialMaxStreamDataBidiRemoteParameterID, uint64(p.InitialMaxStreamDataBidiRemote))
 	// initial_max_stream_data_uni
-	p.marshalVarintParam(b, initialMaxStreamDataUniParameterID, uint64(p.InitialMaxStreamDataUni))
+	p.marshalVarintParam(b, varIntEncoding, initialMaxStreamDataUniParameterID, uint64(p.InitialMaxStreamDataUni))
 	// initial_max_data
-	p.marshalVarintParam(b, initialMaxDataParameterID, uint64(p.InitialMaxData))
+	p.marshalVarintParam(b, varIntEncoding, initialMaxDataParameterID, uint64(p.InitialMaxData))
 	// initial_max_bidi_streams
-	p.marshalVarintParam(b, initialMaxStreamsBidiParameterID, uint64(p.MaxBidiStreamNum))
+	p.marshalVarintParam(b, varIntEncoding, initialMaxStreamsBidiParameterID, uint64(p.MaxBidiStreamNum))
 	// initial_max_uni_streams
-	p.marshalVarintParam(b, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum))
+	p.marshalVarintParam(b, varIntEncoding, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum))
 	// idle_timeout
-	p.marshalVarintParam(b, maxIdleTimeoutParameterID, uint64(p.MaxIdleTimeout/time.Millisecond))
+	p.marshalVarintParam(b, varIntEncoding, maxIdleTimeoutParameterID, uint64(p.MaxIdleTimeout/time.Millisecond))
 	// max_packet_size
-	p.marshalVarintParam(b, maxPacketSizeParameterID, uint64(protocol.MaxReceivePacketSize))
+	maxPacketSize := p.MaxPacketSize
+	if maxPacketSize == 0 {
+		maxPacketSize = protocol.MaxReceivePacketSize
+	}
+	p.marshalVarintParam(b, varIntEncoding, maxPacketSizeParameterID, uint64(maxPacketSize))
 	// max_ack_delay
 	// Only send it if is different from the default value.
 	if p.MaxAckDelay != protocol.DefaultMaxAckDelay {
-		p.marshalVarintParam(b, maxAckDelayParameterID, uint64(p.MaxAckDelay/time.Millisecond))
+		p.marshalVarintParam(b, varIntEncoding, maxAckDelayParameterID, uint64(p.MaxAckDelay/time.Millisecond))
 	}
 	// ack_delay_exponent
 	// Only send it if is different from the default value.
 	if p.AckDelayExponent != protocol.DefaultAckDelayExponent {
-		p.marshalVarintParam(b, ackDelayExponentParameterID, uint64(p.AckDelayExponent))
+		p.marshalVarintParam(b, varIntEncoding, ackDelayExponentParameterID, uint64(p.AckDelayExponent))
 	}
 	// disable_active_migration
 	if p.DisableActiveMigration {
-		utils.BigEndian.WriteUint16(b, uint16(disableActiveMigrationParameterID))
-		utils.BigEndian.WriteUint16(b, 0)
+		writeTransportParameterHeader(b, disableActiveMigrationParameterID, 0, varIntEncoding)
 	}
 	if p.StatelessResetToken != nil {
-		utils.BigEndian.WriteUint16(b, uint16(statelessResetTokenParameterID))
-		utils.BigEndian.WriteUint16(b, 16)
+		writeTransportParameterHeader(b, statelessResetTokenParameterID, 16, varIntEncoding)
 		b.Write(p.StatelessResetToken[:])
 	}
 	if p.PreferredAddress != nil {
-		utils.BigEndian.WriteUint16(b, uint16(preferredAddressParamaterID))
-		utils.BigEndian.WriteUint16(b, 4+2+16+2+1+uint16(p.PreferredAddress.ConnectionID.Len())+16)
+		writeTransportParameterHeader(b, preferredAddressParamaterID, 4+2+16+2+1+p.PreferredAddress.ConnectionID.Len()+16, varIntEncoding)
 		ipv4 := p.PreferredAddress.IPv4
 		b.Write(ipv4[len(ipv4)-4:])
 		utils.BigEndian.WriteUint16(b, p.PreferredAddress.IPv4Port)
@@ -352,25 +441,52 @@ func (p *TransportParameters) Marshal() []byte {
 		b.Write(p.PreferredAddress.StatelessResetToken[:])
 	}
 	if p.OriginalConnectionID.Len() > 0 {
-		utils.BigEndian.WriteUint16(b, uint16(originalConnectionIDParameterID))
-		utils.BigEndian.WriteUint16(b, uint16(p.OriginalConnectionID.Len()))
+		writeTransportParameterHeader(b, originalConnectionIDParameterID, p.OriginalConnectionID.Len(), varIntEncoding)
 		b.Write(p.OriginalConnectionID.Bytes())
 	}
+	if v.UsesConnectionIDTransportParameters() {
+		// initial_source_connection_id
+		// This parameter is sent by both endpoints, and might be empty.
+		writeTransportParameterHeader(b, initialSourceConnectionIDParameterID, p.InitialSourceConnectionID.Len(), varIntEncoding)
+		b.Write(p.InitialSourceConnectionID.Bytes())
+		// retry_source_connection_id
+		if p.RetrySourceConnectionID != nil {
+			writeTransportParameterHeader(b, retrySourceConnectionIDParameterID, p.RetrySourceConnectionID.Len(), varIntEncoding)
+			b.Write(p.RetrySourceConnectionID.Bytes())
+		}
+	}
 
 	// active_connection_id_limit
-	p.marshalVarintParam(b, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit)
+	p.marshalVarintParam(b, varIntEncoding, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit)
+	// min_ack_delay
+	if p.MinAckDelay != nil {
+		p.marshalVarintParam(b, varIntEncoding, minAckDelayParameterID, uint64(*p.MinAckDelay/time.Microsecond))
+	}
 
 	data := b.Bytes()
-	binary.BigEndian.PutUint16(data[:2], uint16(b.Len()-2))
+	if !varIntEncoding {
+		binary.BigEndian.PutUint16(data[:2], uint16(b.Len()-2))
+	}
 	return data
 }
 
-func (p *TransportParameters) marshalVarintParam(b *bytes.Buffer, id transportParameterID, val uint64) {
-	utils.BigEndian.WriteUint16(b, uint16(id))
-	utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(val)))
+func (p *TransportParameters) marshalVarintParam(b *bytes.Buffer, varIntEncoding bool, id transportParameterID, val uint64) {
+	writeTransportParameterHeader(b, id, int(utils.VarIntLen(val)), varIntEncoding)
 	utils.WriteVarInt(b, val)
 }
 
+// writeTransportParameterHeader writes the ID and the length of a transport parameter.
+// Older draft versions use 16 bit integers, newer versions use variable-length integers.
+func writeTransportParameterHeader(b *bytes.Buffer, id transportParameterID, l int, varIntEncoding bool) {
+	if varIntEncoding {
+		utils.WriteVarInt(b, uint64(id))
+		utils.WriteVarInt(b, uint64(l))
+		return
+	}
+	utils.BigEndian.WriteUint16(b, uint16(id))
+	utils.BigEndian.WriteUint16(b, uint16(l))
+}
+
 // MarshalForSessionTicket marshals the transport parameters we save in the session ticket.
 // When sending a 0-RTT enabled TLS session tickets, we need to save the transport parameters.
 // The client will remember the transport parameters used in the last session,
@@ -378,7 +494,8 @@ func (p *TransportParameters) marshalVarintParam(b *bytes.Buffer, id transportPa
 // Saving the transport parameters in the ticket gives the server the option to reject 0-RTT
 // if the transport parameters changed.
 // Since the session ticket is encrypted, the serialization format is defined by the server.
-// For convenience, we use the same format that we also use for sending the transport parameters.
+// For convenience, we use the same format that we also use for sending the transport parameters
+// in protocol.VersionTLS, independent of the version that was used on the connection.
 func (p *TransportParameters) MarshalForSessionTicket() []byte {
 	b := &bytes.Buffer{}
 	utils.WriteVarInt(b, transportParameterMarshalingVersion)
@@ -386,17 +503,17 @@ func (p *TransportParameters) MarshalForSessionTicket() []byte {
 	b.Write([]byte{0, 0}) // length. Will be replaced later
 
 	// initial_max_stream_data_bidi_local
-	p.marshalVarintParam(b, initialMaxStreamDataBidiLocalParameterID, uint64(p.InitialMaxStreamDataBidiLocal))
+	p.marshalVarintParam(b, false, initialMaxStreamDataBidiLocalParameterID, uint64(p.InitialMaxStreamDataBidiLocal))
 	// initial_max_stream_data_bidi_remote
-	p.marshalVarintParam(b, initialMaxStreamDataBidiRemoteParameterID, uint64(p.InitialMaxStreamDataBidiRemote))
+	p.marshalVarintParam(b, false, initialMaxStreamDataBidiRemoteParameterID, uint64(p.InitialMaxStreamDataBidiRemote))
 	// initial_max_stream_data_uni
-	p.marshalVarintParam(b, initialMaxStreamDataUniParameterID, uint64(p.InitialMaxStreamDataUni))
+	p.marshalVarintParam(b, false, initialMaxStreamDataUniParameterID, uint64(p.InitialMaxStreamDataUni))
 	// initial_max_data
-	p.marshalVarintParam(b, initialMaxDataParameterID, uint64(p.InitialMaxData))
+	p.marshalVarintParam(b, false, initialMaxDataParameterID, uint64(p.InitialMaxData))
 	// initial_max_bidi_streams
-	p.marshalVarintParam(b, initialMaxStreamsBidiParameterID, uint64(p.MaxBidiStreamNum))
+	p.marshalVarintParam(b, false, initialMaxStreamsBidiParameterID, uint64(p.MaxBidiStreamNum))
 	// initial_max_uni_streams
-	p.marshalVarintParam(b, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum))
+	p.marshalVarintParam(b, false, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum))
 
 	data := b.Bytes()
 	binary.BigEndian.PutUint16(data[startLen:startLen+2], uint16(b.Len()-2-startLen))
@@ -413,7 +530,8 @@ func (p *TransportParameters) UnmarshalFromSessionTicket(data []byte) error {
 	if version != transportParameterMarshalingVersion {
 		return fmt.Errorf("unknown transport parameter marshaling version: %d", version)
 	}
-	return p.Unmarshal(data[len(data)-r.Len():], protocol.PerspectiveServer)
+	// The session ticket always uses the encoding of protocol.VersionTLS.
+	return p.Unmarshal(data[len(data)-r.Len():], protocol.PerspectiveServer, protocol.VersionTLS)
 }
 
 // ValidFor0RTT checks if the transport parameters match those saved in the session ticket.
@@ -434,6 +552,18 @@ func (p *TransportParameters) String() string {
 		logString += ", StatelessResetToken: %#x"
 		logParams = append(logParams, *p.StatelessResetToken)
 	}
+	if p.InitialSourceConnectionID != nil {
+		logString += ", InitialSourceConnectionID: %s"
+		logParams = append(logParams, p.InitialSourceConnectionID)
+	}
+	if p.RetrySourceConnectionID != nil {
+		logString += ", RetrySourceConnectionID: %s"
+		logParams = append(logParams, *p.RetrySourceConnectionID)
+	}
+	if p.MinAckDelay != nil {
+		logString += ", MinAckDelay: %s"
+		logParams = append(logParams, *p.MinAckDelay)
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
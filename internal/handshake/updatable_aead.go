@@ -49,6 +49,10 @@ type updatableAEAD struct {
 	largestAcked      protocol.PacketNumber
 	firstPacketNumber protocol.PacketNumber
 	keyUpdateInterval uint64
+	// set when a key update is requested by the application
+	keyUpdateRequested utils.AtomicBool
+	// called when the keys are updated, either by us or by the peer
+	onKeyUpdate func(phase protocol.KeyPhase, remote bool)
 
 	// Time when the keys should be dropped. Keys are dropped on the next call to Open().
 	prevRcvAEADExpiry time.Time
@@ -73,7 +77,8 @@ type updatableAEAD struct {
 
 	rttStats *congestion.RTTStats
 
-	logger utils.Logger
+	logger  utils.Logger
+	version protocol.VersionNumber
 
 	// use a single slice to avoid allocations
 	nonceBuf []byte
@@ -82,8 +87,9 @@ type updatableAEAD struct {
 var _ ShortHeaderOpener = &updatableAEAD{}
 var _ ShortHeaderSealer = &updatableAEAD{}
 
-func newUpdatableAEAD(rttStats *congestion.RTTStats, logger utils.Logger) *updatableAEAD {
+func newUpdatableAEAD(rttStats *congestion.RTTStats, onKeyUpdate func(protocol.KeyPhase, bool), logger utils.Logger, version protocol.VersionNumber) *updatableAEAD {
 	return &updatableAEAD{
+		onKeyUpdate:             onKeyUpdate,
 		firstPacketNumber:       protocol.InvalidPacketNumber,
 		largestAcked:            protocol.InvalidPacketNumber,
 		firstRcvdWithCurrentKey: protocol.InvalidPacketNumber,
@@ -91,6 +97,7 @@ func newUpdatableAEAD(rttStats *congestion.RTTStats, logger utils.Logger) *updat
 		keyUpdateInterval:       keyUpdateInterval,
 		rttStats:                rttStats,
 		logger:                  logger,
+		version:                 version,
 	}
 }
 
@@ -100,6 +107,7 @@ func (a *updatableAEAD) rollKeys(now time.Time) {
 	a.firstSentWithCurrentKey = protocol.InvalidPacketNumber
 	a.numRcvdWithCurrentKey = 0
 	a.numSentWithCurrentKey = 0
+	a.keyUpdateRequested.Set(false)
 	a.prevRcvAEAD = a.rcvAEAD
 	a.prevRcvAEADExpiry = now.Add(3 * a.rttStats.PTO(true))
 	a.rcvAEAD = a.nextRcvAEAD
@@ -107,19 +115,23 @@ func (a *updatableAEAD) rollKeys(now time.Time) {
 
 	a.nextRcvTrafficSecret = a.getNextTrafficSecret(a.suite.Hash, a.nextRcvTrafficSecret)
 	a.nextSendTrafficSecret = a.getNextTrafficSecret(a.suite.Hash, a.nextSendTrafficSecret)
-	a.nextRcvAEAD = createAEAD(a.suite, a.nextRcvTrafficSecret)
-	a.nextSendAEAD = createAEAD(a.suite, a.nextSendTrafficSecret)
+	a.nextRcvAEAD = createAEAD(a.suite, a.nextRcvTrafficSecret, a.version)
+	a.nextSendAEAD = createAEAD(a.suite, a.nextSendTrafficSecret, a.version)
 }
 
 func (a *updatableAEAD) getNextTrafficSecret(hash crypto.Hash, ts []byte) []byte {
-	return qtls.HkdfExpandLabel(hash, ts, []byte{}, "quic ku", hash.Size())
+	label := "quic ku"
+	if a.version == protocol.Version2 {
+		label = "quicv2 ku"
+	}
+	return qtls.HkdfExpandLabel(hash, ts, []byte{}, label, hash.Size())
 }
 
 // For the client, this function is called before SetWriteKey.
 // For the server, this function is called after SetWriteKey.
 func (a *updatableAEAD) SetReadKey(suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
-	a.rcvAEAD = createAEAD(suite, trafficSecret)
-	a.headerDecrypter = newHeaderProtector(suite, trafficSecret, false)
+	a.rcvAEAD = createAEAD(suite, trafficSecret, a.version)
+	a.headerDecrypter = newHeaderProtector(suite, trafficSecret, false, a.version)
 	if a.suite == nil {
 		a.nonceBuf = make([]byte, a.rcvAEAD.NonceSize())
 		a.aeadOverhead = a.rcvAEAD.Overhead()
@@ -127,14 +139,14 @@ func (a *updatableAEAD) SetReadKey(suite *qtls.CipherSuiteTLS13, trafficSecret [
 	}
 
 	a.nextRcvTrafficSecret = a.getNextTrafficSecret(suite.Hash, trafficSecret)
-	a.nextRcvAEAD = createAEAD(suite, a.nextRcvTrafficSecret)
+	a.nextRcvAEAD = createAEAD(suite, a.nextRcvTrafficSecret, a.version)
 }
 
 // For the client, this function is called after SetReadKey.
 // For the server, this function is called before SetWriteKey.
 func (a *updatableAEAD) SetWriteKey(suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
-	a.sendAEAD = createAEAD(suite, trafficSecret)
-	a.headerEncrypter = newHeaderProtector(suite, trafficSecret, false)
+	a.sendAEAD = createAEAD(suite, trafficSecret, a.version)
+	a.headerEncrypter = newHeaderProtector(suite, trafficSecret, false, a.version)
 	if a.suite == nil {
 		a.nonceBuf = make([]byte, a.sendAEAD.NonceSize())
 		a.aeadOverhead = a.sendAEAD.Overhead()
@@ -142,7 +154,7 @@ func (a *updatableAEAD) SetWriteKey(suite *qtls.CipherSuiteTLS13, trafficSecret
 	}
 
 	a.nextSendTrafficSecret = a.getNextTrafficSecret(suite.Hash, trafficSecret)
-	a.nextSendAEAD = createAEAD(suite, a.nextSendTrafficSecret)
+	a.nextSendAEAD = createAEAD(suite, a.nextSendTrafficSecret, a.version)
 }
 
 func (a *updatableAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
@@ -181,6 +193,7 @@ func (a *updatableAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 		a.rollKeys(rcvTime)
 		a.logger.Debugf("Peer updated keys to %s", a.keyPhase)
 		a.firstRcvdWithCurrentKey = pn
+		a.onKeyUpdate(a.keyPhase, true)
 		return dec, err
 	}
 	// The AEAD we're using here will be the qtls.aeadAESGCM13.
@@ -225,6 +238,10 @@ func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
 	if !a.updateAllowed() {
 		return false
 	}
+	if a.keyUpdateRequested.Get() {
+		a.logger.Debugf("Initiating key update to the next key phase, as requested: %s", a.keyPhase+1)
+		return true
+	}
 	if a.numRcvdWithCurrentKey >= a.keyUpdateInterval {
 		a.logger.Debugf("Received %d packets with current key phase. Initiating key update to the next key phase: %s", a.numRcvdWithCurrentKey, a.keyPhase+1)
 		return true
@@ -239,10 +256,19 @@ func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
 func (a *updatableAEAD) KeyPhase() protocol.KeyPhaseBit {
 	if a.shouldInitiateKeyUpdate() {
 		a.rollKeys(time.Now())
+		a.onKeyUpdate(a.keyPhase, false)
 	}
 	return a.keyPhase.Bit()
 }
 
+// InitiateKeyUpdate requests a key update.
+// The keys are updated when the next packet is sent,
+// as soon as the peer acknowledged a packet sent with the current keys.
+// It is safe to call it concurrently.
+func (a *updatableAEAD) InitiateKeyUpdate() {
+	a.keyUpdateRequested.Set(true)
+}
+
 func (a *updatableAEAD) Overhead() int {
 	return a.aeadOverhead
 }
@@ -25,8 +25,8 @@ var _ = Describe("Updatable AEAD", func() {
 				rand.Read(trafficSecret1)
 				rand.Read(trafficSecret2)
 
-				client = newUpdatableAEAD(rttStats, utils.DefaultLogger)
-				server = newUpdatableAEAD(rttStats, utils.DefaultLogger)
+				client = newUpdatableAEAD(rttStats, func(protocol.KeyPhase, bool) {}, utils.DefaultLogger, protocol.VersionTLS)
+				server = newUpdatableAEAD(rttStats, func(protocol.KeyPhase, bool) {}, utils.DefaultLogger, protocol.VersionTLS)
 				client.SetReadKey(cs, trafficSecret2)
 				client.SetWriteKey(cs, trafficSecret1)
 				server.SetReadKey(cs, trafficSecret1)
@@ -106,6 +106,20 @@ func (mr *MockSentPacketHandlerMockRecorder) OnLossDetectionTimeout() *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).OnLossDetectionTimeout))
 }
 
+// PacingInterval mocks base method
+func (m *MockSentPacketHandler) PacingInterval() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacingInterval")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// PacingInterval indicates an expected call of PacingInterval
+func (mr *MockSentPacketHandlerMockRecorder) PacingInterval() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacingInterval", reflect.TypeOf((*MockSentPacketHandler)(nil).PacingInterval))
+}
+
 // PeekPacketNumber mocks base method
 func (m *MockSentPacketHandler) PeekPacketNumber(arg0 protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen) {
 	m.ctrl.T.Helper()
@@ -215,6 +229,18 @@ func (mr *MockSentPacketHandlerMockRecorder) SetHandshakeComplete() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHandshakeComplete", reflect.TypeOf((*MockSentPacketHandler)(nil).SetHandshakeComplete))
 }
 
+// SetMaxSendRate mocks base method
+func (m *MockSentPacketHandler) SetMaxSendRate(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxSendRate", arg0)
+}
+
+// SetMaxSendRate indicates an expected call of SetMaxSendRate
+func (mr *MockSentPacketHandlerMockRecorder) SetMaxSendRate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxSendRate", reflect.TypeOf((*MockSentPacketHandler)(nil).SetMaxSendRate), arg0)
+}
+
 // ShouldSendNumPackets mocks base method
 func (m *MockSentPacketHandler) ShouldSendNumPackets() int {
 	m.ctrl.T.Helper()
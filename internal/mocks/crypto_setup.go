@@ -222,6 +222,18 @@ func (mr *MockCryptoSetupMockRecorder) HandleMessage(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessage", reflect.TypeOf((*MockCryptoSetup)(nil).HandleMessage), arg0, arg1)
 }
 
+// InitiateKeyUpdate mocks base method
+func (m *MockCryptoSetup) InitiateKeyUpdate() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InitiateKeyUpdate")
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate
+func (mr *MockCryptoSetupMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockCryptoSetup)(nil).InitiateKeyUpdate))
+}
+
 // RunHandshake mocks base method
 func (m *MockCryptoSetup) RunHandshake() {
 	m.ctrl.T.Helper()
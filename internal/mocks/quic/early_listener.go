@@ -78,3 +78,59 @@ func (mr *MockEarlyListenerMockRecorder) Close() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockEarlyListener)(nil).Close))
 }
+
+// DroppedPackets mocks base method
+func (m *MockEarlyListener) DroppedPackets() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DroppedPackets")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// DroppedPackets indicates an expected call of DroppedPackets
+func (mr *MockEarlyListenerMockRecorder) DroppedPackets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DroppedPackets", reflect.TypeOf((*MockEarlyListener)(nil).DroppedPackets))
+}
+
+// DualStack mocks base method
+func (m *MockEarlyListener) DualStack() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DualStack")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// DualStack indicates an expected call of DualStack
+func (mr *MockEarlyListenerMockRecorder) DualStack() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DualStack", reflect.TypeOf((*MockEarlyListener)(nil).DualStack))
+}
+
+// ReceiveBufferWarning mocks base method
+func (m *MockEarlyListener) ReceiveBufferWarning() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiveBufferWarning")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReceiveBufferWarning indicates an expected call of ReceiveBufferWarning
+func (mr *MockEarlyListenerMockRecorder) ReceiveBufferWarning() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveBufferWarning", reflect.TypeOf((*MockEarlyListener)(nil).ReceiveBufferWarning))
+}
+
+// Stats mocks base method
+func (m *MockEarlyListener) Stats() quic.ServerStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(quic.ServerStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockEarlyListenerMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockEarlyListener)(nil).Stats))
+}
@@ -8,6 +8,7 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	quic "github.com/lucas-clemente/quic-go"
@@ -68,6 +69,46 @@ func (mr *MockEarlySessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockEarlySession)(nil).AcceptUniStream), arg0)
 }
 
+// AllowMoreIncomingStreams mocks base method
+func (m *MockEarlySession) AllowMoreIncomingStreams(arg0 int, arg1 int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AllowMoreIncomingStreams", arg0, arg1)
+}
+
+// AllowMoreIncomingStreams indicates an expected call of AllowMoreIncomingStreams
+func (mr *MockEarlySessionMockRecorder) AllowMoreIncomingStreams(arg0 interface{}, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllowMoreIncomingStreams", reflect.TypeOf((*MockEarlySession)(nil).AllowMoreIncomingStreams), arg0, arg1)
+}
+
+// CloseCause mocks base method
+func (m *MockEarlySession) CloseCause() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseCause")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseCause indicates an expected call of CloseCause
+func (mr *MockEarlySessionMockRecorder) CloseCause() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseCause", reflect.TypeOf((*MockEarlySession)(nil).CloseCause))
+}
+
+// CloseGracefully mocks base method
+func (m *MockEarlySession) CloseGracefully(arg0 context.Context, arg1 protocol.ApplicationErrorCode, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseGracefully", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseGracefully indicates an expected call of CloseGracefully
+func (mr *MockEarlySessionMockRecorder) CloseGracefully(arg0 interface{}, arg1 interface{}, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseGracefully", reflect.TypeOf((*MockEarlySession)(nil).CloseGracefully), arg0, arg1, arg2)
+}
+
 // CloseWithError mocks base method
 func (m *MockEarlySession) CloseWithError(arg0 protocol.ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -110,6 +151,32 @@ func (mr *MockEarlySessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockEarlySession)(nil).Context))
 }
 
+// Drain mocks base method
+func (m *MockEarlySession) Drain(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Drain", arg0)
+}
+
+// Drain indicates an expected call of Drain
+func (mr *MockEarlySessionMockRecorder) Drain(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drain", reflect.TypeOf((*MockEarlySession)(nil).Drain), arg0)
+}
+
+// EarlyTransportParameters mocks base method
+func (m *MockEarlySession) EarlyTransportParameters() *quic.TransportParameters {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EarlyTransportParameters")
+	ret0, _ := ret[0].(*quic.TransportParameters)
+	return ret0
+}
+
+// EarlyTransportParameters indicates an expected call of EarlyTransportParameters
+func (mr *MockEarlySessionMockRecorder) EarlyTransportParameters() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EarlyTransportParameters", reflect.TypeOf((*MockEarlySession)(nil).EarlyTransportParameters))
+}
+
 // HandshakeComplete mocks base method
 func (m *MockEarlySession) HandshakeComplete() context.Context {
 	m.ctrl.T.Helper()
@@ -124,6 +191,90 @@ func (mr *MockEarlySessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockEarlySession)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method
+func (m *MockEarlySession) HandshakeConfirmed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed
+func (mr *MockEarlySessionMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockEarlySession)(nil).HandshakeConfirmed))
+}
+
+// HandshakeTiming mocks base method
+func (m *MockEarlySession) HandshakeTiming() quic.HandshakeTiming {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeTiming")
+	ret0, _ := ret[0].(quic.HandshakeTiming)
+	return ret0
+}
+
+// HandshakeTiming indicates an expected call of HandshakeTiming
+func (mr *MockEarlySessionMockRecorder) HandshakeTiming() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeTiming", reflect.TypeOf((*MockEarlySession)(nil).HandshakeTiming))
+}
+
+// IncomingStreams mocks base method
+func (m *MockEarlySession) IncomingStreams() <-chan quic.Stream {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncomingStreams")
+	ret0, _ := ret[0].(<-chan quic.Stream)
+	return ret0
+}
+
+// IncomingStreams indicates an expected call of IncomingStreams
+func (mr *MockEarlySessionMockRecorder) IncomingStreams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncomingStreams", reflect.TypeOf((*MockEarlySession)(nil).IncomingStreams))
+}
+
+// IncomingUniStreams mocks base method
+func (m *MockEarlySession) IncomingUniStreams() <-chan quic.ReceiveStream {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncomingUniStreams")
+	ret0, _ := ret[0].(<-chan quic.ReceiveStream)
+	return ret0
+}
+
+// IncomingUniStreams indicates an expected call of IncomingUniStreams
+func (mr *MockEarlySessionMockRecorder) IncomingUniStreams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncomingUniStreams", reflect.TypeOf((*MockEarlySession)(nil).IncomingUniStreams))
+}
+
+// InitialRTT mocks base method
+func (m *MockEarlySession) InitialRTT() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitialRTT")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// InitialRTT indicates an expected call of InitialRTT
+func (mr *MockEarlySessionMockRecorder) InitialRTT() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitialRTT", reflect.TypeOf((*MockEarlySession)(nil).InitialRTT))
+}
+
+// InitiateKeyUpdate mocks base method
+func (m *MockEarlySession) InitiateKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate
+func (mr *MockEarlySessionMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockEarlySession)(nil).InitiateKeyUpdate))
+}
+
 // LocalAddr mocks base method
 func (m *MockEarlySession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -138,6 +289,20 @@ func (mr *MockEarlySessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockEarlySession)(nil).LocalAddr))
 }
 
+// LocalConnectionID mocks base method
+func (m *MockEarlySession) LocalConnectionID() protocol.ConnectionID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LocalConnectionID")
+	ret0, _ := ret[0].(protocol.ConnectionID)
+	return ret0
+}
+
+// LocalConnectionID indicates an expected call of LocalConnectionID
+func (mr *MockEarlySessionMockRecorder) LocalConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalConnectionID", reflect.TypeOf((*MockEarlySession)(nil).LocalConnectionID))
+}
+
 // OpenStream mocks base method
 func (m *MockEarlySession) OpenStream() (quic.Stream, error) {
 	m.ctrl.T.Helper()
@@ -168,6 +333,36 @@ func (mr *MockEarlySessionMockRecorder) OpenStreamSync(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSync", reflect.TypeOf((*MockEarlySession)(nil).OpenStreamSync), arg0)
 }
 
+// OpenStreamWithData mocks base method
+func (m *MockEarlySession) OpenStreamWithData(arg0 []byte) (quic.Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamWithData", arg0)
+	ret0, _ := ret[0].(quic.Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamWithData indicates an expected call of OpenStreamWithData
+func (mr *MockEarlySessionMockRecorder) OpenStreamWithData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamWithData", reflect.TypeOf((*MockEarlySession)(nil).OpenStreamWithData), arg0)
+}
+
+// OpenStreamWithOptions mocks base method
+func (m *MockEarlySession) OpenStreamWithOptions(arg0 quic.StreamOptions) (quic.Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamWithOptions", arg0)
+	ret0, _ := ret[0].(quic.Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamWithOptions indicates an expected call of OpenStreamWithOptions
+func (mr *MockEarlySessionMockRecorder) OpenStreamWithOptions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamWithOptions", reflect.TypeOf((*MockEarlySession)(nil).OpenStreamWithOptions), arg0)
+}
+
 // OpenUniStream mocks base method
 func (m *MockEarlySession) OpenUniStream() (quic.SendStream, error) {
 	m.ctrl.T.Helper()
@@ -198,6 +393,20 @@ func (mr *MockEarlySessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockEarlySession)(nil).OpenUniStreamSync), arg0)
 }
 
+// PacingInterval mocks base method
+func (m *MockEarlySession) PacingInterval() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacingInterval")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// PacingInterval indicates an expected call of PacingInterval
+func (mr *MockEarlySessionMockRecorder) PacingInterval() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacingInterval", reflect.TypeOf((*MockEarlySession)(nil).PacingInterval))
+}
+
 // RemoteAddr mocks base method
 func (m *MockEarlySession) RemoteAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -211,3 +420,141 @@ func (mr *MockEarlySessionMockRecorder) RemoteAddr() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockEarlySession)(nil).RemoteAddr))
 }
+
+// RemoteConnectionID mocks base method
+func (m *MockEarlySession) RemoteConnectionID() protocol.ConnectionID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteConnectionID")
+	ret0, _ := ret[0].(protocol.ConnectionID)
+	return ret0
+}
+
+// RemoteConnectionID indicates an expected call of RemoteConnectionID
+func (mr *MockEarlySessionMockRecorder) RemoteConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteConnectionID", reflect.TypeOf((*MockEarlySession)(nil).RemoteConnectionID))
+}
+
+// RemoteTransportParameters mocks base method
+func (m *MockEarlySession) RemoteTransportParameters() (*quic.TransportParameters, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteTransportParameters")
+	ret0, _ := ret[0].(*quic.TransportParameters)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoteTransportParameters indicates an expected call of RemoteTransportParameters
+func (mr *MockEarlySessionMockRecorder) RemoteTransportParameters() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteTransportParameters", reflect.TypeOf((*MockEarlySession)(nil).RemoteTransportParameters))
+}
+
+// RotateConnectionID mocks base method
+func (m *MockEarlySession) RotateConnectionID() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateConnectionID")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateConnectionID indicates an expected call of RotateConnectionID
+func (mr *MockEarlySessionMockRecorder) RotateConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateConnectionID", reflect.TypeOf((*MockEarlySession)(nil).RotateConnectionID))
+}
+
+// SendPing mocks base method
+func (m *MockEarlySession) SendPing(arg0 context.Context) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendPing", arg0)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendPing indicates an expected call of SendPing
+func (mr *MockEarlySessionMockRecorder) SendPing(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPing", reflect.TypeOf((*MockEarlySession)(nil).SendPing), arg0)
+}
+
+// SetMaxSendRate mocks base method
+func (m *MockEarlySession) SetMaxSendRate(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxSendRate", arg0)
+}
+
+// SetMaxSendRate indicates an expected call of SetMaxSendRate
+func (mr *MockEarlySessionMockRecorder) SetMaxSendRate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxSendRate", reflect.TypeOf((*MockEarlySession)(nil).SetMaxSendRate), arg0)
+}
+
+// SetUserData mocks base method
+func (m *MockEarlySession) SetUserData(arg0 interface{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetUserData", arg0)
+}
+
+// SetUserData indicates an expected call of SetUserData
+func (mr *MockEarlySessionMockRecorder) SetUserData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserData", reflect.TypeOf((*MockEarlySession)(nil).SetUserData), arg0)
+}
+
+// Used0RTT mocks base method
+func (m *MockEarlySession) Used0RTT() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Used0RTT")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Used0RTT indicates an expected call of Used0RTT
+func (mr *MockEarlySessionMockRecorder) Used0RTT() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Used0RTT", reflect.TypeOf((*MockEarlySession)(nil).Used0RTT))
+}
+
+// UsedRetry mocks base method
+func (m *MockEarlySession) UsedRetry() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsedRetry")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// UsedRetry indicates an expected call of UsedRetry
+func (mr *MockEarlySessionMockRecorder) UsedRetry() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsedRetry", reflect.TypeOf((*MockEarlySession)(nil).UsedRetry))
+}
+
+// UserData mocks base method
+func (m *MockEarlySession) UserData() interface{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserData")
+	ret0, _ := ret[0].(interface{})
+	return ret0
+}
+
+// UserData indicates an expected call of UserData
+func (mr *MockEarlySessionMockRecorder) UserData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserData", reflect.TypeOf((*MockEarlySession)(nil).UserData))
+}
+
+// ZeroRTTRejected mocks base method
+func (m *MockEarlySession) ZeroRTTRejected() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZeroRTTRejected")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ZeroRTTRejected indicates an expected call of ZeroRTTRejected
+func (mr *MockEarlySessionMockRecorder) ZeroRTTRejected() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZeroRTTRejected", reflect.TypeOf((*MockEarlySession)(nil).ZeroRTTRejected))
+}
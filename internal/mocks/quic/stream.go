@@ -6,10 +6,12 @@ package mockquic
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	quic "github.com/lucas-clemente/quic-go"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -36,6 +38,34 @@ func (m *MockStream) EXPECT() *MockStreamMockRecorder {
 	return m.recorder
 }
 
+// AckedOffset mocks base method
+func (m *MockStream) AckedOffset() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckedOffset")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// AckedOffset indicates an expected call of AckedOffset
+func (mr *MockStreamMockRecorder) AckedOffset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckedOffset", reflect.TypeOf((*MockStream)(nil).AckedOffset))
+}
+
+// BufferedAmount mocks base method
+func (m *MockStream) BufferedAmount() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedAmount")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedAmount indicates an expected call of BufferedAmount
+func (mr *MockStreamMockRecorder) BufferedAmount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedAmount", reflect.TypeOf((*MockStream)(nil).BufferedAmount))
+}
+
 // CancelRead mocks base method
 func (m *MockStream) CancelRead(arg0 protocol.ApplicationErrorCode) {
 	m.ctrl.T.Helper()
@@ -74,6 +104,20 @@ func (mr *MockStreamMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStream)(nil).Close))
 }
 
+// CloseWithContext mocks base method
+func (m *MockStream) CloseWithContext(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithContext", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithContext indicates an expected call of CloseWithContext
+func (mr *MockStreamMockRecorder) CloseWithContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithContext", reflect.TypeOf((*MockStream)(nil).CloseWithContext), arg0)
+}
+
 // Context mocks base method
 func (m *MockStream) Context() context.Context {
 	m.ctrl.T.Helper()
@@ -103,6 +147,47 @@ func (mr *MockStreamMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStream)(nil).Read), arg0)
 }
 
+// ReadFrom mocks base method
+func (m *MockStream) ReadFrom(arg0 io.Reader) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadFrom", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadFrom indicates an expected call of ReadFrom
+func (mr *MockStreamMockRecorder) ReadFrom(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadFrom", reflect.TypeOf((*MockStream)(nil).ReadFrom), arg0)
+}
+
+// RejectStream mocks base method
+func (m *MockStream) RejectStream(arg0 protocol.ApplicationErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RejectStream", arg0)
+}
+
+// RejectStream indicates an expected call of RejectStream
+func (mr *MockStreamMockRecorder) RejectStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectStream", reflect.TypeOf((*MockStream)(nil).RejectStream), arg0)
+}
+
+// SendStats mocks base method
+func (m *MockStream) SendStats() quic.SendStreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendStats")
+	ret0, _ := ret[0].(quic.SendStreamStats)
+	return ret0
+}
+
+// SendStats indicates an expected call of SendStats
+func (mr *MockStreamMockRecorder) SendStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendStats", reflect.TypeOf((*MockStream)(nil).SendStats))
+}
+
 // SetDeadline mocks base method
 func (m *MockStream) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -173,3 +258,18 @@ func (mr *MockStreamMockRecorder) Write(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStream)(nil).Write), arg0)
 }
+
+// WriteTo mocks base method
+func (m *MockStream) WriteTo(arg0 io.Writer) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteTo", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteTo indicates an expected call of WriteTo
+func (mr *MockStreamMockRecorder) WriteTo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteTo", reflect.TypeOf((*MockStream)(nil).WriteTo), arg0)
+}
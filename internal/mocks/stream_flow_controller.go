@@ -113,6 +113,20 @@ func (mr *MockStreamFlowControllerMockRecorder) SendWindowSize() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SendWindowSize))
 }
 
+// SetReceiveWindowSize mocks base method
+func (m *MockStreamFlowController) SetReceiveWindowSize(arg0 protocol.ByteCount) protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetReceiveWindowSize", arg0)
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// SetReceiveWindowSize indicates an expected call of SetReceiveWindowSize
+func (mr *MockStreamFlowControllerMockRecorder) SetReceiveWindowSize(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SetReceiveWindowSize), arg0)
+}
+
 // UpdateHighestReceived mocks base method
 func (m *MockStreamFlowController) UpdateHighestReceived(arg0 protocol.ByteCount, arg1 bool) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,28 @@
+package protocol
+
+import "fmt"
+
+// CongestionControl is the congestion control algorithm
+type CongestionControl uint8
+
+const (
+	// CongestionControlHybrid uses NewReno with hybrid slow start.
+	CongestionControlHybrid CongestionControl = iota
+	// CongestionControlReno uses NewReno with classic slow start.
+	CongestionControlReno
+	// CongestionControlCubic uses CUBIC with hybrid slow start.
+	CongestionControlCubic
+)
+
+func (c CongestionControl) String() string {
+	switch c {
+	case CongestionControlHybrid:
+		return "hybrid"
+	case CongestionControlReno:
+		return "Reno"
+	case CongestionControlCubic:
+		return "CUBIC"
+	default:
+		return fmt.Sprintf("unknown congestion control: %d", c)
+	}
+}
@@ -0,0 +1,15 @@
+package protocol
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Congestion Control", func() {
+	It("has a string representation", func() {
+		Expect(CongestionControlHybrid.String()).To(Equal("hybrid"))
+		Expect(CongestionControlReno.String()).To(Equal("Reno"))
+		Expect(CongestionControlCubic.String()).To(Equal("CUBIC"))
+		Expect(CongestionControl(42).String()).To(Equal("unknown congestion control: 42"))
+	})
+})
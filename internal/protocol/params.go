@@ -8,9 +8,16 @@ const MaxPacketSizeIPv4 = 1252
 // MaxPacketSizeIPv6 is the maximum packet size that we use for sending IPv6 packets.
 const MaxPacketSizeIPv6 = 1232
 
+// MaxUDPPayloadSize is the largest UDP payload that can be sent over IPv4 or IPv6.
+// It is also the largest value of the max_packet_size transport parameter.
+const MaxUDPPayloadSize = 65527
+
 // MaxCongestionWindowPackets is the maximum congestion window in packet.
 const MaxCongestionWindowPackets = 10000
 
+// MaxCongestionWindow is the maximum congestion window in bytes.
+const MaxCongestionWindow = MaxCongestionWindowPackets * MaxPacketSizeIPv4
+
 // MaxUndecryptablePackets limits the number of undecryptable packets that are queued in the session.
 const MaxUndecryptablePackets = 10
 
@@ -42,6 +49,10 @@ const DefaultMaxIncomingUniStreams = 100
 // MaxSessionUnprocessedPackets is the max number of packets stored in each session that are not yet processed.
 const MaxSessionUnprocessedPackets = MaxCongestionWindowPackets
 
+// DefaultMaxServerUnprocessedPackets is the default number of packets that the server queues before processing.
+// Once the queue is full, newly received packets are dropped.
+const DefaultMaxServerUnprocessedPackets = 1000
+
 // SkipPacketAveragePeriodLength is the average period length in which one packet number is skipped to prevent an Optimistic ACK attack
 const SkipPacketAveragePeriodLength PacketNumber = 500
 
@@ -58,6 +69,9 @@ const TokenValidity = 24 * time.Hour
 // RetryTokenValidity is the duration that a retry token is considered valid
 const RetryTokenValidity = 10 * time.Second
 
+// DefaultNumSessionTickets is the number of session tickets the server sends after the handshake completes
+const DefaultNumSessionTickets = 1
+
 // MaxOutstandingSentPackets is maximum number of packets saved for retransmission.
 // When reached, it imposes a soft limit on sending new packets:
 // Sending ACKs and retransmission is still allowed, but now new regular packets can be sent.
@@ -132,9 +146,18 @@ const MinPacingDelay time.Duration = 100 * time.Microsecond
 // if no other value is configured.
 const DefaultConnectionIDLength = 4
 
-// MaxActiveConnectionIDs is the number of connection IDs that we're storing.
+// MaxActiveConnectionIDs is the number of connection IDs that we're storing,
+// if no other value is configured.
 const MaxActiveConnectionIDs = 4
 
+// MinActiveConnectionIDLimit is the minimum value of the active_connection_id_limit transport parameter.
+const MinActiveConnectionIDLimit = 2
+
+// MaxRetiredConnectionIDsPerActiveConnectionID is the number of connection IDs the peer can make us retire
+// (without acknowledging the RETIRE_CONNECTION_ID frames) per connection ID that we're storing,
+// if no other value is configured.
+const MaxRetiredConnectionIDsPerActiveConnectionID = 4
+
 // MaxIssuedConnectionIDs is the maximum number of connection IDs that we're issuing at the same time.
 const MaxIssuedConnectionIDs = 6
 
@@ -149,6 +172,9 @@ const AckDelayExponent = 3
 // The loss detection timer will not be set to a value smaller than granularity.
 const TimerGranularity = time.Millisecond
 
+// MaxInitialRTT is the largest initial RTT that can be configured.
+const MaxInitialRTT = 10 * time.Second
+
 // MaxAckDelay is the maximum time by which we delay sending ACKs.
 const MaxAckDelay = 25 * time.Millisecond
 
@@ -156,5 +182,27 @@ const MaxAckDelay = 25 * time.Millisecond
 // This is the value that should be advertised to the peer.
 const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
 
+// MinAckDelay is the min_ack_delay we advertise when the ACK frequency extension is enabled.
+// This is the minimum ACK delay the peer can request in an ACK_FREQUENCY frame.
+const MinAckDelay = TimerGranularity
+
+// AckFrequencyPacketTolerance is the number of ack-eliciting packets that we ask the peer to receive before sending an ACK,
+// if the ACK frequency extension is enabled.
+const AckFrequencyPacketTolerance = 10
+
+// DesiredReceiveBufferSize is the receive buffer size we try to configure on the UDP socket.
+// If the receive buffer is smaller than this, packets might be dropped under load.
+const DesiredReceiveBufferSize = (1 << 20) * 2 // 2 MB
+
+// MaxDSCP is the largest DSCP value. The DSCP is a 6 bit value.
+const MaxDSCP = 63
+
+// MaxWriteRetries is the maximum number of times we retry writing a packet to the socket after a transient error.
+const MaxWriteRetries = 5
+
+// WriteRetryInitialBackoff is the time we wait before the first retry of a failed write.
+// The backoff is doubled for every retry.
+const WriteRetryInitialBackoff = time.Millisecond
+
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key udpate.
 const KeyUpdateInterval = 100 * 1000
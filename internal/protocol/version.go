@@ -19,17 +19,19 @@ const (
 // The version numbers, making grepping easier
 const (
 	VersionTLS      VersionNumber = 0x51474fff
-	VersionWhatever VersionNumber = 1 // for when the version doesn't matter
+	Version2        VersionNumber = 0x6b3343cf // QUIC version 2, RFC 9369
+	VersionWhatever VersionNumber = 1          // for when the version doesn't matter
 	VersionUnknown  VersionNumber = math.MaxUint32
 )
 
 // SupportedVersions lists the versions that the server supports
 // must be in sorted descending order
+// Version2 is not supported by default. It is only used when it is configured in the Config.
 var SupportedVersions = []VersionNumber{VersionTLS}
 
 // IsValidVersion says if the version is known to quic-go
 func IsValidVersion(v VersionNumber) bool {
-	return v == VersionTLS || IsSupportedVersion(SupportedVersions, v)
+	return v == VersionTLS || v == Version2 || IsSupportedVersion(SupportedVersions, v)
 }
 
 func (vn VersionNumber) String() string {
@@ -40,6 +42,8 @@ func (vn VersionNumber) String() string {
 		return "unknown"
 	case VersionTLS:
 		return "TLS dev version (WIP)"
+	case Version2:
+		return "v2"
 	default:
 		if vn.isGQUIC() {
 			return fmt.Sprintf("gQUIC %d", vn.toGQUICVersion())
@@ -48,6 +52,21 @@ func (vn VersionNumber) String() string {
 	}
 }
 
+// UsesVarIntTransportParameters says if the transport parameter IDs and lengths are encoded as variable-length integers.
+// This changed in draft-27. Before that, 16 bit integers were used, and the list was prefixed by its total length.
+// Of the versions quic-go implements, only QUIC version 2 uses the new encoding.
+func (vn VersionNumber) UsesVarIntTransportParameters() bool {
+	return vn == Version2
+}
+
+// UsesConnectionIDTransportParameters says if the connection IDs used during the handshake are authenticated
+// using the original_destination_connection_id, initial_source_connection_id and retry_source_connection_id
+// transport parameters, and if the transport parameters are sent in the quic_transport_parameters TLS extension (0x39).
+// This was introduced by RFC 9000. Of the versions quic-go implements, only QUIC version 2 does so.
+func (vn VersionNumber) UsesConnectionIDTransportParameters() bool {
+	return vn == Version2
+}
+
 func (vn VersionNumber) isGQUIC() bool {
 	return vn > gquicVersion0 && vn <= maxGquicVersion
 }
@@ -12,6 +12,7 @@ var _ = Describe("Version", func() {
 
 	It("says if a version is valid", func() {
 		Expect(IsValidVersion(VersionTLS)).To(BeTrue())
+		Expect(IsValidVersion(Version2)).To(BeTrue())
 		Expect(IsValidVersion(VersionWhatever)).To(BeFalse())
 		Expect(IsValidVersion(VersionUnknown)).To(BeFalse())
 		Expect(IsValidVersion(1234)).To(BeFalse())
@@ -19,10 +20,12 @@ var _ = Describe("Version", func() {
 
 	It("versions don't have reserved version numbers", func() {
 		Expect(isReservedVersion(VersionTLS)).To(BeFalse())
+		Expect(isReservedVersion(Version2)).To(BeFalse())
 	})
 
 	It("has the right string representation", func() {
 		Expect(VersionTLS.String()).To(ContainSubstring("TLS"))
+		Expect(Version2.String()).To(Equal("v2"))
 		Expect(VersionWhatever.String()).To(Equal("whatever"))
 		Expect(VersionUnknown.String()).To(Equal("unknown"))
 		// check with unsupported version numbers from the wiki
@@ -33,8 +36,14 @@ var _ = Describe("Version", func() {
 		Expect(VersionNumber(0x01234567).String()).To(Equal("0x1234567"))
 	})
 
+	It("says which versions use the transport parameters of RFC 9000", func() {
+		Expect(VersionTLS.UsesConnectionIDTransportParameters()).To(BeFalse())
+		Expect(Version2.UsesConnectionIDTransportParameters()).To(BeTrue())
+	})
+
 	It("recognizes supported versions", func() {
 		Expect(IsSupportedVersion(SupportedVersions, 0)).To(BeFalse())
+		Expect(IsSupportedVersion(SupportedVersions, Version2)).To(BeFalse())
 		Expect(IsSupportedVersion(SupportedVersions, SupportedVersions[0])).To(BeTrue())
 		Expect(IsSupportedVersion(SupportedVersions, SupportedVersions[len(SupportedVersions)-1])).To(BeTrue())
 	})
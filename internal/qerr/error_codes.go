@@ -13,7 +13,7 @@ type ErrorCode uint64
 const (
 	NoError                 ErrorCode = 0x0
 	InternalError           ErrorCode = 0x1
-	ServerBusy              ErrorCode = 0x2
+	ConnectionRefused       ErrorCode = 0x2
 	FlowControlError        ErrorCode = 0x3
 	StreamLimitError        ErrorCode = 0x4
 	StreamStateError        ErrorCode = 0x5
@@ -25,6 +25,10 @@ const (
 	CryptoBufferExceeded    ErrorCode = 0xd
 )
 
+// NoApplicationProtocol is the crypto error used when ALPN negotiation fails.
+// It carries the TLS no_application_protocol alert.
+const NoApplicationProtocol ErrorCode = 0x100 + 120
+
 func (e ErrorCode) isCryptoError() bool {
 	return e >= 0x100 && e < 0x200
 }
@@ -51,8 +55,8 @@ func (e ErrorCode) String() string {
 		return "NO_ERROR"
 	case InternalError:
 		return "INTERNAL_ERROR"
-	case ServerBusy:
-		return "SERVER_BUSY"
+	case ConnectionRefused:
+		return "CONNECTION_REFUSED"
 	case FlowControlError:
 		return "FLOW_CONTROL_ERROR"
 	case StreamLimitError:
@@ -93,6 +93,14 @@ func (e *QuicError) IsApplicationError() bool {
 	return e.isApplicationError
 }
 
+// Is says if the error is a transport error with the given error code.
+// This allows checking for a specific error code using errors.Is.
+// Application errors never match, since their error codes are defined by the application.
+func (e *QuicError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && !e.isApplicationError && e.ErrorCode == code
+}
+
 // Temporary says if the error is temporary.
 func (e *QuicError) Temporary() bool {
 	return false
@@ -1,6 +1,8 @@
 package qerr
 
 import (
+	"errors"
+	"fmt"
 	"io"
 
 	. "github.com/onsi/ginkgo"
@@ -54,6 +56,13 @@ var _ = Describe("QUIC Transport Errors", func() {
 			Expect(err.IsApplicationError()).To(BeFalse())
 
 		})
+
+		It("uses the no_application_protocol alert for NoApplicationProtocol", func() {
+			err := CryptoError(120, "")
+			Expect(err.ErrorCode).To(Equal(NoApplicationProtocol))
+			Expect(errors.Is(err, NoApplicationProtocol)).To(BeTrue())
+			Expect(err.Error()).To(Equal("CRYPTO_ERROR: tls: no application protocol"))
+		})
 	})
 
 	Context("application errors", func() {
@@ -81,6 +90,19 @@ var _ = Describe("QUIC Transport Errors", func() {
 		})
 	})
 
+	Context("matching error codes", func() {
+		It("matches the error code using errors.Is", func() {
+			err := fmt.Errorf("wrapped: %w", Error(FlowControlError, "foobar"))
+			Expect(errors.Is(err, FlowControlError)).To(BeTrue())
+			Expect(errors.Is(err, ProtocolViolation)).To(BeFalse())
+		})
+
+		It("doesn't match application errors", func() {
+			err := ApplicationError(ErrorCode(FlowControlError), "foobar")
+			Expect(errors.Is(err, FlowControlError)).To(BeFalse())
+		})
+	})
+
 	Context("ToQuicError", func() {
 		It("leaves QuicError unchanged", func() {
 			err := Error(TransportParameterError, "foo")
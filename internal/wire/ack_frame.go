@@ -16,6 +16,8 @@ var errInvalidAckRanges = errors.New("AckFrame: ACK frame contains invalid ACK r
 type AckFrame struct {
 	AckRanges []AckRange // has to be ordered. The highest ACK range goes first, the lowest ACK range goes last
 	DelayTime time.Duration
+	// DelayExponent is the ack_delay_exponent used to encode the DelayTime when writing the frame.
+	DelayExponent uint8
 }
 
 // parseAckFrame reads an ACK frame
@@ -107,7 +109,7 @@ func parseAckFrame(r *bytes.Reader, ackDelayExponent uint8, _ protocol.VersionNu
 func (f *AckFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
 	b.WriteByte(0x2)
 	utils.WriteVarInt(b, uint64(f.LargestAcked()))
-	utils.WriteVarInt(b, encodeAckDelay(f.DelayTime))
+	utils.WriteVarInt(b, encodeAckDelay(f.DelayTime, f.DelayExponent))
 
 	numRanges := f.numEncodableAckRanges()
 	utils.WriteVarInt(b, uint64(numRanges-1))
@@ -130,7 +132,7 @@ func (f *AckFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
 	largestAcked := f.AckRanges[0].Largest
 	numRanges := f.numEncodableAckRanges()
 
-	length := 1 + utils.VarIntLen(uint64(largestAcked)) + utils.VarIntLen(encodeAckDelay(f.DelayTime))
+	length := 1 + utils.VarIntLen(uint64(largestAcked)) + utils.VarIntLen(encodeAckDelay(f.DelayTime, f.DelayExponent))
 
 	length += utils.VarIntLen(uint64(numRanges - 1))
 	lowestInFirstRange := f.AckRanges[0].Smallest
@@ -147,7 +149,7 @@ func (f *AckFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
 // gets the number of ACK ranges that can be encoded
 // such that the resulting frame is smaller than the maximum ACK frame size
 func (f *AckFrame) numEncodableAckRanges() int {
-	length := 1 + utils.VarIntLen(uint64(f.LargestAcked())) + utils.VarIntLen(encodeAckDelay(f.DelayTime))
+	length := 1 + utils.VarIntLen(uint64(f.LargestAcked())) + utils.VarIntLen(encodeAckDelay(f.DelayTime, f.DelayExponent))
 	length += 2 // assume that the number of ranges will consume 2 bytes
 	for i := 1; i < len(f.AckRanges); i++ {
 		gap, len := f.encodeAckRange(i)
@@ -227,6 +229,6 @@ func (f *AckFrame) AcksPacket(p protocol.PacketNumber) bool {
 	return p <= f.AckRanges[i].Largest
 }
 
-func encodeAckDelay(delay time.Duration) uint64 {
-	return uint64(delay.Nanoseconds() / (1000 * (1 << protocol.AckDelayExponent)))
+func encodeAckDelay(delay time.Duration, exponent uint8) uint64 {
+	return uint64(delay.Nanoseconds() / (1000 * (1 << exponent)))
 }
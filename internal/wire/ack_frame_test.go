@@ -118,8 +118,9 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 			const delayTime = 1 << 10 * time.Millisecond
 			buf := &bytes.Buffer{}
 			f := &AckFrame{
-				AckRanges: []AckRange{{Smallest: 1, Largest: 1}},
-				DelayTime: delayTime,
+				AckRanges:     []AckRange{{Smallest: 1, Largest: 1}},
+				DelayTime:     delayTime,
+				DelayExponent: protocol.AckDelayExponent,
 			}
 			Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
 			for i := uint8(0); i < 8; i++ {
@@ -220,8 +221,9 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 		It("writes a frame that acks a single packet", func() {
 			buf := &bytes.Buffer{}
 			f := &AckFrame{
-				AckRanges: []AckRange{{Smallest: 0x2eadbeef, Largest: 0x2eadbeef}},
-				DelayTime: 18 * time.Millisecond,
+				AckRanges:     []AckRange{{Smallest: 0x2eadbeef, Largest: 0x2eadbeef}},
+				DelayTime:     18 * time.Millisecond,
+				DelayExponent: protocol.AckDelayExponent,
 			}
 			err := f.Write(buf, versionIETFFrames)
 			Expect(err).ToNot(HaveOccurred())
@@ -229,12 +231,27 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 			b := bytes.NewReader(buf.Bytes())
 			frame, err := parseAckFrame(b, protocol.AckDelayExponent, versionIETFFrames)
 			Expect(err).ToNot(HaveOccurred())
-			Expect(frame).To(Equal(f))
+			Expect(frame.AckRanges).To(Equal(f.AckRanges))
 			Expect(frame.HasMissingRanges()).To(BeFalse())
 			Expect(frame.DelayTime).To(Equal(f.DelayTime))
 			Expect(b.Len()).To(BeZero())
 		})
 
+		It("encodes the delay time using the delay exponent", func() {
+			buf := &bytes.Buffer{}
+			f := &AckFrame{
+				AckRanges:     []AckRange{{Smallest: 1, Largest: 1}},
+				DelayTime:     10 * time.Millisecond,
+				DelayExponent: 10,
+			}
+			Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+			Expect(f.Length(versionIETFFrames)).To(BeEquivalentTo(buf.Len()))
+			frame, err := parseAckFrame(bytes.NewReader(buf.Bytes()), 10, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			// 10ms is not a multiple of 1024us, so the delay time is rounded down
+			Expect(frame.DelayTime).To(Equal(9 * 1024 * time.Microsecond))
+		})
+
 		It("writes a frame that acks many packets", func() {
 			buf := &bytes.Buffer{}
 			f := &AckFrame{
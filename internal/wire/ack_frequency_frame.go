@@ -0,0 +1,78 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const ackFrequencyFrameType = 0xaf
+
+// An AckFrequencyFrame is an ACK_FREQUENCY frame, as defined by the ACK frequency extension.
+type AckFrequencyFrame struct {
+	SequenceNumber    uint64
+	PacketTolerance   uint64
+	UpdateMaxAckDelay time.Duration
+	IgnoreOrder       bool
+}
+
+func parseAckFrequencyFrame(r *bytes.Reader, _ protocol.VersionNumber) (*AckFrequencyFrame, error) {
+	if _, err := utils.ReadVarInt(r); err != nil {
+		return nil, err
+	}
+
+	seq, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	tolerance, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if tolerance == 0 {
+		return nil, errors.New("invalid packet tolerance: 0")
+	}
+	delay, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	// prevents overflows if the delay is too large
+	if delay > uint64(protocol.MaxMaxAckDelay/time.Microsecond) {
+		delay = uint64(protocol.MaxMaxAckDelay / time.Microsecond)
+	}
+	ignoreOrder, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if ignoreOrder > 1 {
+		return nil, fmt.Errorf("invalid value for ignore order: %d", ignoreOrder)
+	}
+	return &AckFrequencyFrame{
+		SequenceNumber:    seq,
+		PacketTolerance:   tolerance,
+		UpdateMaxAckDelay: time.Duration(delay) * time.Microsecond,
+		IgnoreOrder:       ignoreOrder == 1,
+	}, nil
+}
+
+func (f *AckFrequencyFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
+	utils.WriteVarInt(b, ackFrequencyFrameType)
+	utils.WriteVarInt(b, f.SequenceNumber)
+	utils.WriteVarInt(b, f.PacketTolerance)
+	utils.WriteVarInt(b, uint64(f.UpdateMaxAckDelay/time.Microsecond))
+	if f.IgnoreOrder {
+		b.WriteByte(1)
+	} else {
+		b.WriteByte(0)
+	}
+	return nil
+}
+
+// Length of a written frame
+func (f *AckFrequencyFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return utils.VarIntLen(ackFrequencyFrameType) + utils.VarIntLen(f.SequenceNumber) + utils.VarIntLen(f.PacketTolerance) + utils.VarIntLen(uint64(f.UpdateMaxAckDelay/time.Microsecond)) + 1
+}
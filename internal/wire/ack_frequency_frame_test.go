@@ -0,0 +1,94 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACK_FREQUENCY frame", func() {
+	Context("when parsing", func() {
+		It("accepts a sample frame", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+			data = append(data, encodeVarInt(0xcafe)...)     // packet tolerance
+			data = append(data, encodeVarInt(1337)...)       // update max ack delay
+			data = append(data, 1)                           // ignore order
+			b := bytes.NewReader(data)
+			frame, err := parseAckFrequencyFrame(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.SequenceNumber).To(Equal(uint64(0xdeadbeef)))
+			Expect(frame.PacketTolerance).To(Equal(uint64(0xcafe)))
+			Expect(frame.UpdateMaxAckDelay).To(Equal(1337 * time.Microsecond))
+			Expect(frame.IgnoreOrder).To(BeTrue())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on a packet tolerance of 0", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(1)...) // sequence number
+			data = append(data, encodeVarInt(0)...) // packet tolerance
+			data = append(data, encodeVarInt(1)...) // update max ack delay
+			data = append(data, 0)                  // ignore order
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).To(MatchError("invalid packet tolerance: 0"))
+		})
+
+		It("errors on invalid values for ignore order", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(1)...) // sequence number
+			data = append(data, encodeVarInt(2)...) // packet tolerance
+			data = append(data, encodeVarInt(1)...) // update max ack delay
+			data = append(data, 2)                  // ignore order
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).To(MatchError("invalid value for ignore order: 2"))
+		})
+
+		It("errors on EOFs", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+			data = append(data, encodeVarInt(0xcafe)...)     // packet tolerance
+			data = append(data, encodeVarInt(1337)...)       // update max ack delay
+			data = append(data, 0)                           // ignore order
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseAckFrequencyFrame(bytes.NewReader(data[0:i]), versionIETFFrames)
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			frame := &AckFrequencyFrame{
+				SequenceNumber:    0x1337,
+				PacketTolerance:   0x42,
+				UpdateMaxAckDelay: 25 * time.Millisecond,
+				IgnoreOrder:       true,
+			}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+			expected := encodeVarInt(0xaf)
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, encodeVarInt(0x42)...)
+			expected = append(expected, encodeVarInt(25000)...)
+			expected = append(expected, 1)
+			Expect(b.Bytes()).To(Equal(expected))
+		})
+
+		It("has the correct length", func() {
+			frame := &AckFrequencyFrame{
+				SequenceNumber:    0xdecafbad,
+				PacketTolerance:   0xdeadbeef,
+				UpdateMaxAckDelay: 12345 * time.Microsecond,
+			}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+			Expect(frame.Length(versionIETFFrames)).To(BeEquivalentTo(b.Len()))
+		})
+	})
+})
@@ -97,6 +97,33 @@ var _ = Describe("Header", func() {
 				Expect(buf.Bytes()).To(ContainSubstring(string(expectedSubstring)))
 			})
 
+			It("writes the packet types of QUIC v2", func() {
+				for _, t := range []struct {
+					packetType protocol.PacketType
+					typeBits   byte
+				}{
+					{protocol.PacketTypeInitial, 0x1},
+					{protocol.PacketType0RTT, 0x2},
+					{protocol.PacketTypeHandshake, 0x3},
+					{protocol.PacketTypeRetry, 0x0},
+				} {
+					buf.Reset()
+					Expect((&ExtendedHeader{
+						Header: Header{
+							IsLongHeader: true,
+							Version:      protocol.Version2,
+							Type:         t.packetType,
+						},
+						PacketNumberLen: protocol.PacketNumberLen1,
+					}).Write(buf, protocol.Version2)).To(Succeed())
+					Expect(buf.Bytes()[0] & 0x30 >> 4).To(Equal(t.typeBits))
+					buf.Write(make([]byte, 20)) // enough data for the Retry token and integrity tag
+					hdr, err := parseHeader(bytes.NewReader(buf.Bytes()), 0)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hdr.Type).To(Equal(t.packetType))
+				}
+			})
+
 			It("writes a Retry packet", func() {
 				token := []byte("Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat.")
 				Expect((&ExtendedHeader{Header: Header{
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 type frameParser struct {
@@ -21,9 +23,9 @@ func NewFrameParser(v protocol.VersionNumber) FrameParser {
 	return &frameParser{version: v}
 }
 
-// ParseNextFrame parses the next frame
+// ParseNext parses the next frame, and returns its frame type.
 // It skips PADDING frames.
-func (p *frameParser) ParseNext(r *bytes.Reader, encLevel protocol.EncryptionLevel) (Frame, error) {
+func (p *frameParser) ParseNext(r *bytes.Reader, encLevel protocol.EncryptionLevel) (uint64, Frame, error) {
 	for r.Len() != 0 {
 		typeByte, _ := r.ReadByte()
 		if typeByte == 0x0 { // PADDING frame
@@ -31,22 +33,42 @@ func (p *frameParser) ParseNext(r *bytes.Reader, encLevel protocol.EncryptionLev
 		}
 		r.UnreadByte()
 
-		f, err := p.parseFrame(r, typeByte, encLevel)
+		frameType := uint64(typeByte)
+		// Frame types larger than 0x3f (used by extensions) are encoded as multi-byte variable-length integers.
+		if typeByte > 0x3f {
+			startLen := r.Len()
+			var err error
+			frameType, err = utils.ReadVarInt(r)
+			if err != nil {
+				return 0, nil, qerr.ErrorWithFrameType(qerr.FrameEncodingError, uint64(typeByte), err.Error())
+			}
+			// Frame types must use the shortest possible encoding.
+			if protocol.ByteCount(startLen-r.Len()) != utils.VarIntLen(frameType) {
+				return 0, nil, qerr.ErrorWithFrameType(qerr.FrameEncodingError, frameType, "frame type not minimally encoded")
+			}
+			r.Seek(-int64(startLen-r.Len()), io.SeekCurrent)
+		}
+
+		f, err := p.parseFrame(r, frameType, encLevel)
 		if err != nil {
-			return nil, qerr.ErrorWithFrameType(qerr.FrameEncodingError, uint64(typeByte), err.Error())
+			return 0, nil, qerr.ErrorWithFrameType(qerr.FrameEncodingError, frameType, err.Error())
+		}
+		if !p.isAllowedAtEncLevel(f, encLevel) {
+			return 0, nil, qerr.ErrorWithFrameType(qerr.ProtocolViolation, frameType, fmt.Sprintf("%s not allowed at encryption level %s", reflect.TypeOf(f).Elem().Name(), encLevel))
 		}
-		return f, nil
+		return frameType, f, nil
 	}
-	return nil, nil
+	return 0, nil, nil
 }
 
-func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protocol.EncryptionLevel) (Frame, error) {
+func (p *frameParser) parseFrame(r *bytes.Reader, frameType uint64, encLevel protocol.EncryptionLevel) (Frame, error) {
 	var frame Frame
 	var err error
-	if typeByte&0xf8 == 0x8 {
+	// STREAM frames use the frame types 0x8 to 0xf.
+	if frameType < 0x40 && frameType&0xf8 == 0x8 {
 		frame, err = parseStreamFrame(r, p.version)
 	} else {
-		switch typeByte {
+		switch frameType {
 		case 0x1:
 			frame, err = parsePingFrame(r, p.version)
 		case 0x2, 0x3:
@@ -87,6 +109,10 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseConnectionCloseFrame(r, p.version)
 		case 0x1e:
 			frame, err = parseHandshakeDoneFrame(r, p.version)
+		case immediateAckFrameType:
+			frame, err = parseImmediateAckFrame(r, p.version)
+		case ackFrequencyFrameType:
+			frame, err = parseAckFrequencyFrame(r, p.version)
 		default:
 			err = errors.New("unknown frame type")
 		}
@@ -94,9 +120,6 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 	if err != nil {
 		return nil, err
 	}
-	if !p.isAllowedAtEncLevel(frame, encLevel) {
-		return nil, fmt.Errorf("%s not allowed at encryption level %s", reflect.TypeOf(frame).Elem().Name(), encLevel)
-	}
 	return frame, nil
 }
 
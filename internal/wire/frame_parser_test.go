@@ -6,6 +6,7 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -22,7 +23,7 @@ var _ = Describe("Frame parsing", func() {
 	})
 
 	It("returns nil if there's nothing more to read", func() {
-		f, err := parser.ParseNext(bytes.NewReader(nil), protocol.Encryption1RTT)
+		_, f, err := parser.ParseNext(bytes.NewReader(nil), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(f).To(BeNil())
 	})
@@ -30,24 +31,38 @@ var _ = Describe("Frame parsing", func() {
 	It("skips PADDING frames", func() {
 		buf.Write([]byte{0}) // PADDING frame
 		(&PingFrame{}).Write(buf, versionIETFFrames)
-		f, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, f, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(f).To(Equal(&PingFrame{}))
 	})
 
 	It("handles PADDING at the end", func() {
 		r := bytes.NewReader([]byte{0, 0, 0})
-		f, err := parser.ParseNext(r, protocol.Encryption1RTT)
+		_, f, err := parser.ParseNext(r, protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(f).To(BeNil())
 		Expect(r.Len()).To(BeZero())
 	})
 
+	It("returns the frame type", func() {
+		(&PingFrame{}).Write(buf, versionIETFFrames)
+		Expect((&AckFrequencyFrame{SequenceNumber: 1, PacketTolerance: 2}).Write(buf, versionIETFFrames)).To(Succeed())
+		r := bytes.NewReader(buf.Bytes())
+		frameType, f, err := parser.ParseNext(r, protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f).To(BeAssignableToTypeOf(&PingFrame{}))
+		Expect(frameType).To(BeEquivalentTo(0x1))
+		frameType, f, err = parser.ParseNext(r, protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f).To(BeAssignableToTypeOf(&AckFrequencyFrame{}))
+		Expect(frameType).To(BeEquivalentTo(ackFrequencyFrameType))
+	})
+
 	It("unpacks ACK frames", func() {
 		f := &AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 0x13}}}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).ToNot(BeNil())
 		Expect(frame).To(BeAssignableToTypeOf(f))
@@ -57,13 +72,14 @@ var _ = Describe("Frame parsing", func() {
 	It("uses the custom ack delay exponent for 1RTT packets", func() {
 		parser.SetAckDelayExponent(protocol.AckDelayExponent + 2)
 		f := &AckFrame{
-			AckRanges: []AckRange{{Smallest: 1, Largest: 1}},
-			DelayTime: time.Second,
+			AckRanges:     []AckRange{{Smallest: 1, Largest: 1}},
+			DelayTime:     time.Second,
+			DelayExponent: protocol.AckDelayExponent,
 		}
 		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
-		// The ACK frame is always written using the protocol.AckDelayExponent.
+		// The ACK frame was written using the protocol.AckDelayExponent.
 		// That's why we expect a different value when parsing.
 		Expect(frame.(*AckFrame).DelayTime).To(Equal(4 * time.Second))
 	})
@@ -71,11 +87,12 @@ var _ = Describe("Frame parsing", func() {
 	It("uses the default ack delay exponent for non-1RTT packets", func() {
 		parser.SetAckDelayExponent(protocol.AckDelayExponent + 2)
 		f := &AckFrame{
-			AckRanges: []AckRange{{Smallest: 1, Largest: 1}},
-			DelayTime: time.Second,
+			AckRanges:     []AckRange{{Smallest: 1, Largest: 1}},
+			DelayTime:     time.Second,
+			DelayExponent: protocol.AckDelayExponent,
 		}
 		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.EncryptionHandshake)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.EncryptionHandshake)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame.(*AckFrame).DelayTime).To(Equal(time.Second))
 	})
@@ -88,7 +105,7 @@ var _ = Describe("Frame parsing", func() {
 		}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -98,7 +115,7 @@ var _ = Describe("Frame parsing", func() {
 		buf := &bytes.Buffer{}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -110,7 +127,7 @@ var _ = Describe("Frame parsing", func() {
 		}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).ToNot(BeNil())
 		Expect(frame).To(Equal(f))
@@ -120,7 +137,7 @@ var _ = Describe("Frame parsing", func() {
 		f := &NewTokenFrame{Token: []byte("foobar")}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).ToNot(BeNil())
 		Expect(frame).To(Equal(f))
@@ -135,7 +152,7 @@ var _ = Describe("Frame parsing", func() {
 		}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).ToNot(BeNil())
 		Expect(frame).To(Equal(f))
@@ -148,7 +165,7 @@ var _ = Describe("Frame parsing", func() {
 		buf := &bytes.Buffer{}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -161,7 +178,7 @@ var _ = Describe("Frame parsing", func() {
 		buf := &bytes.Buffer{}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -174,7 +191,7 @@ var _ = Describe("Frame parsing", func() {
 		buf := &bytes.Buffer{}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -184,7 +201,7 @@ var _ = Describe("Frame parsing", func() {
 		buf := &bytes.Buffer{}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -196,7 +213,7 @@ var _ = Describe("Frame parsing", func() {
 		}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -209,7 +226,7 @@ var _ = Describe("Frame parsing", func() {
 		buf := &bytes.Buffer{}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -222,7 +239,7 @@ var _ = Describe("Frame parsing", func() {
 		}
 		buf := &bytes.Buffer{}
 		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -231,7 +248,7 @@ var _ = Describe("Frame parsing", func() {
 		f := &RetireConnectionIDFrame{SequenceNumber: 0x1337}
 		buf := &bytes.Buffer{}
 		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -240,7 +257,7 @@ var _ = Describe("Frame parsing", func() {
 		f := &PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).ToNot(BeNil())
 		Expect(frame).To(BeAssignableToTypeOf(f))
@@ -251,7 +268,7 @@ var _ = Describe("Frame parsing", func() {
 		f := &PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).ToNot(BeNil())
 		Expect(frame).To(BeAssignableToTypeOf(f))
@@ -266,7 +283,7 @@ var _ = Describe("Frame parsing", func() {
 		buf := &bytes.Buffer{}
 		err := f.Write(buf, versionIETFFrames)
 		Expect(err).ToNot(HaveOccurred())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
@@ -275,16 +292,55 @@ var _ = Describe("Frame parsing", func() {
 		f := &HandshakeDoneFrame{}
 		buf := &bytes.Buffer{}
 		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
-		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
+	It("unpacks ACK_FREQUENCY frames", func() {
+		f := &AckFrequencyFrame{
+			SequenceNumber:    3,
+			PacketTolerance:   10,
+			UpdateMaxAckDelay: 25 * time.Millisecond,
+			IgnoreOrder:       true,
+		}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
+	It("unpacks IMMEDIATE_ACK frames", func() {
+		f := &ImmediateAckFrame{}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		_, frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(Equal(f))
 	})
 
 	It("errors on invalid type", func() {
-		_, err := parser.ParseNext(bytes.NewReader([]byte{0x42}), protocol.Encryption1RTT)
+		_, _, err := parser.ParseNext(bytes.NewReader(encodeVarInt(0x42)), protocol.Encryption1RTT)
 		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x42): unknown frame type"))
 	})
 
+	It("errors on frame types that only look like STREAM frames in their lowest bits", func() {
+		_, _, err := parser.ParseNext(bytes.NewReader(encodeVarInt(0x408)), protocol.Encryption1RTT)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x408): unknown frame type"))
+	})
+
+	It("errors on frame types that are not minimally encoded", func() {
+		// the STREAM frame type 0x8, encoded in two bytes
+		_, _, err := parser.ParseNext(bytes.NewReader([]byte{0x40, 0x08, 0x1, 0x2, 0x3}), protocol.Encryption1RTT)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x8): frame type not minimally encoded"))
+	})
+
+	It("errors on frame types that are not valid variable-length integers", func() {
+		_, _, err := parser.ParseNext(bytes.NewReader([]byte{0x40}), protocol.Encryption1RTT)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x40): EOF"))
+	})
+
 	It("errors on invalid frames", func() {
 		f := &MaxStreamDataFrame{
 			StreamID:   0x1337,
@@ -292,7 +348,7 @@ var _ = Describe("Frame parsing", func() {
 		}
 		b := &bytes.Buffer{}
 		f.Write(b, versionIETFFrames)
-		_, err := parser.ParseNext(bytes.NewReader(b.Bytes()[:b.Len()-2]), protocol.Encryption1RTT)
+		_, _, err := parser.ParseNext(bytes.NewReader(b.Bytes()[:b.Len()-2]), protocol.Encryption1RTT)
 		Expect(err).To(HaveOccurred())
 		Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.FrameEncodingError))
 	})
@@ -318,10 +374,21 @@ var _ = Describe("Frame parsing", func() {
 			&PathResponseFrame{},
 			&ConnectionCloseFrame{},
 			&HandshakeDoneFrame{},
+			&AckFrequencyFrame{PacketTolerance: 1},
+			&ImmediateAckFrame{},
 		}
 
 		var framesSerialized [][]byte
 
+		expectProtocolViolation := func(err error, b []byte) {
+			ExpectWithOffset(1, err).To(BeAssignableToTypeOf(&qerr.QuicError{}))
+			qErr := err.(*qerr.QuicError)
+			ExpectWithOffset(1, qErr.ErrorCode).To(Equal(qerr.ProtocolViolation))
+			frameType, err := utils.ReadVarInt(bytes.NewReader(b))
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, qErr.FrameType).To(Equal(frameType))
+		}
+
 		BeforeEach(func() {
 			framesSerialized = nil
 			for _, frame := range frames {
@@ -333,37 +400,40 @@ var _ = Describe("Frame parsing", func() {
 
 		It("rejects all frames but ACK, CRYPTO, PING and CONNECTION_CLOSE in Initial packets", func() {
 			for i, b := range framesSerialized {
-				_, err := parser.ParseNext(bytes.NewReader(b), protocol.EncryptionInitial)
+				_, _, err := parser.ParseNext(bytes.NewReader(b), protocol.EncryptionInitial)
 				switch frames[i].(type) {
 				case *AckFrame, *ConnectionCloseFrame, *CryptoFrame, *PingFrame:
 					Expect(err).ToNot(HaveOccurred())
 				default:
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("not allowed at encryption level Initial"))
+					expectProtocolViolation(err, b)
 				}
 			}
 		})
 
 		It("rejects all frames but ACK, CRYPTO, PING and CONNECTION_CLOSE in Handshake packets", func() {
 			for i, b := range framesSerialized {
-				_, err := parser.ParseNext(bytes.NewReader(b), protocol.EncryptionHandshake)
+				_, _, err := parser.ParseNext(bytes.NewReader(b), protocol.EncryptionHandshake)
 				switch frames[i].(type) {
 				case *AckFrame, *ConnectionCloseFrame, *CryptoFrame, *PingFrame:
 					Expect(err).ToNot(HaveOccurred())
 				default:
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("not allowed at encryption level Handshake"))
+					expectProtocolViolation(err, b)
 				}
 			}
 		})
 
 		It("rejects all frames but ACK, CRYPTO, CONNECTION_CLOSE, NEW_TOKEN, PATH_RESPONSE and RETIRE_CONNECTION_ID in 0-RTT packets", func() {
 			for i, b := range framesSerialized {
-				_, err := parser.ParseNext(bytes.NewReader(b), protocol.Encryption0RTT)
+				_, _, err := parser.ParseNext(bytes.NewReader(b), protocol.Encryption0RTT)
 				switch frames[i].(type) {
 				case *AckFrame, *ConnectionCloseFrame, *CryptoFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame:
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("not allowed at encryption level 0-RTT"))
+					expectProtocolViolation(err, b)
 				default:
 					Expect(err).ToNot(HaveOccurred())
 				}
@@ -372,7 +442,7 @@ var _ = Describe("Frame parsing", func() {
 
 		It("accepts all frame types in 1-RTT packets", func() {
 			for _, b := range framesSerialized {
-				_, err := parser.ParseNext(bytes.NewReader(b), protocol.Encryption1RTT)
+				_, _, err := parser.ParseNext(bytes.NewReader(b), protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 			}
 		})
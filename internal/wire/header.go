@@ -159,19 +159,33 @@ func (h *Header) parseLongHeader(b *bytes.Reader) error {
 		return h.parseVersionNegotiationPacket(b)
 	}
 	// If we don't understand the version, we have no idea how to interpret the rest of the bytes
-	if !protocol.IsSupportedVersion(protocol.SupportedVersions, h.Version) {
+	if !protocol.IsValidVersion(h.Version) {
 		return errUnsupportedVersion
 	}
 
-	switch (h.typeByte & 0x30) >> 4 {
-	case 0x0:
-		h.Type = protocol.PacketTypeInitial
-	case 0x1:
-		h.Type = protocol.PacketType0RTT
-	case 0x2:
-		h.Type = protocol.PacketTypeHandshake
-	case 0x3:
-		h.Type = protocol.PacketTypeRetry
+	// QUIC version 2 uses different values for the long header packet types (see section 3.2 of RFC 9369).
+	if h.Version == protocol.Version2 {
+		switch (h.typeByte & 0x30) >> 4 {
+		case 0x0:
+			h.Type = protocol.PacketTypeRetry
+		case 0x1:
+			h.Type = protocol.PacketTypeInitial
+		case 0x2:
+			h.Type = protocol.PacketType0RTT
+		case 0x3:
+			h.Type = protocol.PacketTypeHandshake
+		}
+	} else {
+		switch (h.typeByte & 0x30) >> 4 {
+		case 0x0:
+			h.Type = protocol.PacketTypeInitial
+		case 0x1:
+			h.Type = protocol.PacketType0RTT
+		case 0x2:
+			h.Type = protocol.PacketTypeHandshake
+		case 0x3:
+			h.Type = protocol.PacketTypeRetry
+		}
 	}
 
 	if h.Type == protocol.PacketTypeRetry {
@@ -287,6 +287,27 @@ var _ = Describe("Header Parsing", func() {
 			Expect(rest).To(BeEmpty())
 		})
 
+		It("parses the packet types of QUIC v2", func() {
+			for _, t := range []struct {
+				typeBits   byte
+				packetType protocol.PacketType
+			}{
+				{0x0, protocol.PacketTypeRetry},
+				{0x1, protocol.PacketTypeInitial},
+				{0x2, protocol.PacketType0RTT},
+				{0x3, protocol.PacketTypeHandshake},
+			} {
+				data := []byte{0xc0 | t.typeBits<<4}
+				data = appendVersion(data, protocol.Version2)
+				data = append(data, 0x0, 0x0)            // dest and src conn ID len
+				data = append(data, make([]byte, 20)...) // enough data for the token / the Retry integrity tag
+				hdr, err := parseHeader(bytes.NewReader(data), 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.Version).To(Equal(protocol.Version2))
+				Expect(hdr.Type).To(Equal(t.packetType))
+			}
+		})
+
 		It("errors if the Retry packet is too short for the integrity tag", func() {
 			data := []byte{0xc0 | 0x3<<4 | (10 - 3) /* connection ID length */}
 			data = appendVersion(data, versionIETFFrames)
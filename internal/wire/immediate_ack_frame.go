@@ -0,0 +1,30 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const immediateAckFrameType = 0xac
+
+// An ImmediateAckFrame is an IMMEDIATE_ACK frame, as defined by the ACK frequency extension.
+type ImmediateAckFrame struct{}
+
+func parseImmediateAckFrame(r *bytes.Reader, _ protocol.VersionNumber) (*ImmediateAckFrame, error) {
+	if _, err := utils.ReadVarInt(r); err != nil {
+		return nil, err
+	}
+	return &ImmediateAckFrame{}, nil
+}
+
+func (f *ImmediateAckFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
+	utils.WriteVarInt(b, immediateAckFrameType)
+	return nil
+}
+
+// Length of a written frame
+func (f *ImmediateAckFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return utils.VarIntLen(immediateAckFrameType)
+}
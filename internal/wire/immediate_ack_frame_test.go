@@ -0,0 +1,38 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IMMEDIATE_ACK frame", func() {
+	Context("when parsing", func() {
+		It("accepts a sample frame", func() {
+			b := bytes.NewReader(encodeVarInt(0xac))
+			_, err := parseImmediateAckFrame(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			_, err := parseImmediateAckFrame(bytes.NewReader([]byte{0x40}), versionIETFFrames)
+			Expect(err).To(MatchError(io.EOF))
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			b := &bytes.Buffer{}
+			Expect((&ImmediateAckFrame{}).Write(b, versionIETFFrames)).To(Succeed())
+			Expect(b.Bytes()).To(Equal(encodeVarInt(0xac)))
+		})
+
+		It("has the correct length", func() {
+			frame := &ImmediateAckFrame{}
+			Expect(frame.Length(versionIETFFrames)).To(BeEquivalentTo(2))
+		})
+	})
+})
@@ -14,6 +14,6 @@ type Frame interface {
 
 // A FrameParser parses QUIC frames, one by one.
 type FrameParser interface {
-	ParseNext(*bytes.Reader, protocol.EncryptionLevel) (Frame, error)
+	ParseNext(*bytes.Reader, protocol.EncryptionLevel) (uint64, Frame, error)
 	SetAckDelayExponent(uint8)
 }
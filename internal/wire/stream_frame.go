@@ -177,7 +177,13 @@ func (f *StreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version pro
 	new.Data, f.Data = f.Data, new.Data
 	new.fromPool, f.fromPool = f.fromPool, new.fromPool
 
-	f.Data = f.Data[:protocol.ByteCount(len(new.Data))-n]
+	if remaining := protocol.ByteCount(len(new.Data)) - n; protocol.ByteCount(cap(f.Data)) < remaining {
+		// This frame was larger than the STREAM frames from the pool.
+		f.Data = make([]byte, remaining)
+		f.fromPool = false
+	} else {
+		f.Data = f.Data[:remaining]
+	}
 	copy(f.Data, new.Data[n:])
 	new.Data = new.Data[:n]
 	f.Offset += n
@@ -2,6 +2,7 @@ package wire
 
 import (
 	"bytes"
+	"crypto/rand"
 	"io"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -370,6 +371,24 @@ var _ = Describe("STREAM frame", func() {
 			Expect(f.Data).To(Equal([]byte("bar")))
 		})
 
+		It("splits frames larger than the STREAM frames from the pool", func() {
+			data := make([]byte, 3*protocol.MaxReceivePacketSize)
+			rand.Read(data)
+			f := &StreamFrame{
+				StreamID: 0x1337,
+				Offset:   0x100,
+				Data:     data,
+			}
+			frame, needsSplit := f.MaybeSplitOffFrame(1000, versionIETFFrames)
+			Expect(needsSplit).To(BeTrue())
+			Expect(frame).ToNot(BeNil())
+			Expect(frame.Length(versionIETFFrames)).To(BeEquivalentTo(1000))
+			Expect(append(frame.Data, f.Data...)).To(Equal(data))
+			Expect(f.Offset).To(Equal(0x100 + frame.DataLen()))
+			f.PutBack()
+			frame.PutBack()
+		})
+
 		It("preserves the FIN bit", func() {
 			f := &StreamFrame{
 				StreamID: 0x1337,
@@ -0,0 +1,56 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Listening on inherited sockets", func() {
+	It("listens on an inherited socket", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		f, err := udpConn.File()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(udpConn.Close()).To(Succeed())
+		defer f.Close()
+		ln, err := ListenFile(f, testdata.GetTLSConfig(), &Config{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ln.Addr()).To(Equal(udpConn.LocalAddr()))
+		Expect(ln.(*baseServer).createdPacketConn).To(BeTrue())
+		// stop the listener
+		Expect(ln.Close()).To(Succeed())
+	})
+
+	It("listens on an inherited socket, for early sessions", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		f, err := udpConn.File()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(udpConn.Close()).To(Succeed())
+		defer f.Close()
+		ln, err := ListenFileEarly(f, testdata.GetTLSConfig(), &Config{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ln.(*earlyServer).createdPacketConn).To(BeTrue())
+		// stop the listener
+		Expect(ln.Close()).To(Succeed())
+	})
+
+	It("errors if the inherited socket is not a UDP socket", func() {
+		unixConn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+		if err != nil {
+			Skip("unix datagram sockets not available")
+		}
+		defer unixConn.Close()
+		f, err := unixConn.File()
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		_, err = ListenFile(f, testdata.GetTLSConfig(), &Config{})
+		Expect(err).To(MatchError("quic: file is not a UDP socket"))
+	})
+})
@@ -62,6 +62,20 @@ func (mr *MockConnectionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockConnection)(nil).LocalAddr))
 }
 
+// PacketConn mocks base method
+func (m *MockConnection) PacketConn() net.PacketConn {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacketConn")
+	ret0, _ := ret[0].(net.PacketConn)
+	return ret0
+}
+
+// PacketConn indicates an expected call of PacketConn
+func (mr *MockConnectionMockRecorder) PacketConn() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacketConn", reflect.TypeOf((*MockConnection)(nil).PacketConn))
+}
+
 // Read mocks base method
 func (m *MockConnection) Read(arg0 []byte) (int, net.Addr, error) {
 	m.ctrl.T.Helper()
@@ -117,3 +131,17 @@ func (mr *MockConnectionMockRecorder) Write(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockConnection)(nil).Write), arg0)
 }
+
+// WriteTo mocks base method
+func (m *MockConnection) WriteTo(arg0 []byte, arg1 net.Addr) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteTo", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteTo indicates an expected call of WriteTo
+func (mr *MockConnectionMockRecorder) WriteTo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteTo", reflect.TypeOf((*MockConnection)(nil).WriteTo), arg0, arg1)
+}
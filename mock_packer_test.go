@@ -108,6 +108,21 @@ func (mr *MockPackerMockRecorder) PackPacket() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackPacket", reflect.TypeOf((*MockPacker)(nil).PackPacket))
 }
 
+// PackPathChallenge mocks base method
+func (m *MockPacker) PackPathChallenge(arg0 *wire.PathChallengeFrame, arg1 func(wire.Frame)) (*packedPacket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PackPathChallenge", arg0, arg1)
+	ret0, _ := ret[0].(*packedPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PackPathChallenge indicates an expected call of PackPathChallenge
+func (mr *MockPackerMockRecorder) PackPathChallenge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackPathChallenge", reflect.TypeOf((*MockPacker)(nil).PackPathChallenge), arg0, arg1)
+}
+
 // SetToken mocks base method
 func (m *MockPacker) SetToken(arg0 []byte) {
 	m.ctrl.T.Helper()
@@ -100,6 +100,20 @@ func (mr *MockPacketHandlerManagerMockRecorder) GetStatelessResetToken(arg0 inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatelessResetToken", reflect.TypeOf((*MockPacketHandlerManager)(nil).GetStatelessResetToken), arg0)
 }
 
+// OOBConn mocks base method
+func (m *MockPacketHandlerManager) OOBConn() *oobConn {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OOBConn")
+	ret0, _ := ret[0].(*oobConn)
+	return ret0
+}
+
+// OOBConn indicates an expected call of OOBConn
+func (mr *MockPacketHandlerManagerMockRecorder) OOBConn() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OOBConn", reflect.TypeOf((*MockPacketHandlerManager)(nil).OOBConn))
+}
+
 // Remove mocks base method
 func (m *MockPacketHandlerManager) Remove(arg0 protocol.ConnectionID) {
 	m.ctrl.T.Helper()
@@ -8,6 +8,7 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
@@ -67,6 +68,46 @@ func (mr *MockQuicSessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockQuicSession)(nil).AcceptUniStream), arg0)
 }
 
+// AllowMoreIncomingStreams mocks base method
+func (m *MockQuicSession) AllowMoreIncomingStreams(arg0 int, arg1 int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AllowMoreIncomingStreams", arg0, arg1)
+}
+
+// AllowMoreIncomingStreams indicates an expected call of AllowMoreIncomingStreams
+func (mr *MockQuicSessionMockRecorder) AllowMoreIncomingStreams(arg0 interface{}, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllowMoreIncomingStreams", reflect.TypeOf((*MockQuicSession)(nil).AllowMoreIncomingStreams), arg0, arg1)
+}
+
+// CloseCause mocks base method
+func (m *MockQuicSession) CloseCause() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseCause")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseCause indicates an expected call of CloseCause
+func (mr *MockQuicSessionMockRecorder) CloseCause() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseCause", reflect.TypeOf((*MockQuicSession)(nil).CloseCause))
+}
+
+// CloseGracefully mocks base method
+func (m *MockQuicSession) CloseGracefully(arg0 context.Context, arg1 protocol.ApplicationErrorCode, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseGracefully", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseGracefully indicates an expected call of CloseGracefully
+func (mr *MockQuicSessionMockRecorder) CloseGracefully(arg0 interface{}, arg1 interface{}, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseGracefully", reflect.TypeOf((*MockQuicSession)(nil).CloseGracefully), arg0, arg1, arg2)
+}
+
 // CloseWithError mocks base method
 func (m *MockQuicSession) CloseWithError(arg0 protocol.ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -109,6 +150,32 @@ func (mr *MockQuicSessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockQuicSession)(nil).Context))
 }
 
+// Drain mocks base method
+func (m *MockQuicSession) Drain(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Drain", arg0)
+}
+
+// Drain indicates an expected call of Drain
+func (mr *MockQuicSessionMockRecorder) Drain(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drain", reflect.TypeOf((*MockQuicSession)(nil).Drain), arg0)
+}
+
+// EarlyTransportParameters mocks base method
+func (m *MockQuicSession) EarlyTransportParameters() *TransportParameters {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EarlyTransportParameters")
+	ret0, _ := ret[0].(*TransportParameters)
+	return ret0
+}
+
+// EarlyTransportParameters indicates an expected call of EarlyTransportParameters
+func (mr *MockQuicSessionMockRecorder) EarlyTransportParameters() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EarlyTransportParameters", reflect.TypeOf((*MockQuicSession)(nil).EarlyTransportParameters))
+}
+
 // GetVersion mocks base method
 func (m *MockQuicSession) GetVersion() protocol.VersionNumber {
 	m.ctrl.T.Helper()
@@ -137,6 +204,90 @@ func (mr *MockQuicSessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQuicSession)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method
+func (m *MockQuicSession) HandshakeConfirmed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed
+func (mr *MockQuicSessionMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockQuicSession)(nil).HandshakeConfirmed))
+}
+
+// HandshakeTiming mocks base method
+func (m *MockQuicSession) HandshakeTiming() HandshakeTiming {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeTiming")
+	ret0, _ := ret[0].(HandshakeTiming)
+	return ret0
+}
+
+// HandshakeTiming indicates an expected call of HandshakeTiming
+func (mr *MockQuicSessionMockRecorder) HandshakeTiming() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeTiming", reflect.TypeOf((*MockQuicSession)(nil).HandshakeTiming))
+}
+
+// IncomingStreams mocks base method
+func (m *MockQuicSession) IncomingStreams() <-chan Stream {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncomingStreams")
+	ret0, _ := ret[0].(<-chan Stream)
+	return ret0
+}
+
+// IncomingStreams indicates an expected call of IncomingStreams
+func (mr *MockQuicSessionMockRecorder) IncomingStreams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncomingStreams", reflect.TypeOf((*MockQuicSession)(nil).IncomingStreams))
+}
+
+// IncomingUniStreams mocks base method
+func (m *MockQuicSession) IncomingUniStreams() <-chan ReceiveStream {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncomingUniStreams")
+	ret0, _ := ret[0].(<-chan ReceiveStream)
+	return ret0
+}
+
+// IncomingUniStreams indicates an expected call of IncomingUniStreams
+func (mr *MockQuicSessionMockRecorder) IncomingUniStreams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncomingUniStreams", reflect.TypeOf((*MockQuicSession)(nil).IncomingUniStreams))
+}
+
+// InitialRTT mocks base method
+func (m *MockQuicSession) InitialRTT() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitialRTT")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// InitialRTT indicates an expected call of InitialRTT
+func (mr *MockQuicSessionMockRecorder) InitialRTT() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitialRTT", reflect.TypeOf((*MockQuicSession)(nil).InitialRTT))
+}
+
+// InitiateKeyUpdate mocks base method
+func (m *MockQuicSession) InitiateKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate
+func (mr *MockQuicSessionMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockQuicSession)(nil).InitiateKeyUpdate))
+}
+
 // LocalAddr mocks base method
 func (m *MockQuicSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -151,6 +302,20 @@ func (mr *MockQuicSessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockQuicSession)(nil).LocalAddr))
 }
 
+// LocalConnectionID mocks base method
+func (m *MockQuicSession) LocalConnectionID() protocol.ConnectionID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LocalConnectionID")
+	ret0, _ := ret[0].(protocol.ConnectionID)
+	return ret0
+}
+
+// LocalConnectionID indicates an expected call of LocalConnectionID
+func (mr *MockQuicSessionMockRecorder) LocalConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalConnectionID", reflect.TypeOf((*MockQuicSession)(nil).LocalConnectionID))
+}
+
 // OpenStream mocks base method
 func (m *MockQuicSession) OpenStream() (Stream, error) {
 	m.ctrl.T.Helper()
@@ -181,6 +346,36 @@ func (mr *MockQuicSessionMockRecorder) OpenStreamSync(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamSync), arg0)
 }
 
+// OpenStreamWithData mocks base method
+func (m *MockQuicSession) OpenStreamWithData(arg0 []byte) (Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamWithData", arg0)
+	ret0, _ := ret[0].(Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamWithData indicates an expected call of OpenStreamWithData
+func (mr *MockQuicSessionMockRecorder) OpenStreamWithData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamWithData", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamWithData), arg0)
+}
+
+// OpenStreamWithOptions mocks base method
+func (m *MockQuicSession) OpenStreamWithOptions(arg0 StreamOptions) (Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamWithOptions", arg0)
+	ret0, _ := ret[0].(Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamWithOptions indicates an expected call of OpenStreamWithOptions
+func (mr *MockQuicSessionMockRecorder) OpenStreamWithOptions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamWithOptions", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamWithOptions), arg0)
+}
+
 // OpenUniStream mocks base method
 func (m *MockQuicSession) OpenUniStream() (SendStream, error) {
 	m.ctrl.T.Helper()
@@ -211,6 +406,20 @@ func (mr *MockQuicSessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenUniStreamSync), arg0)
 }
 
+// PacingInterval mocks base method
+func (m *MockQuicSession) PacingInterval() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacingInterval")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// PacingInterval indicates an expected call of PacingInterval
+func (mr *MockQuicSessionMockRecorder) PacingInterval() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacingInterval", reflect.TypeOf((*MockQuicSession)(nil).PacingInterval))
+}
+
 // RemoteAddr mocks base method
 func (m *MockQuicSession) RemoteAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -225,6 +434,144 @@ func (mr *MockQuicSessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQuicSession)(nil).RemoteAddr))
 }
 
+// RemoteConnectionID mocks base method
+func (m *MockQuicSession) RemoteConnectionID() protocol.ConnectionID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteConnectionID")
+	ret0, _ := ret[0].(protocol.ConnectionID)
+	return ret0
+}
+
+// RemoteConnectionID indicates an expected call of RemoteConnectionID
+func (mr *MockQuicSessionMockRecorder) RemoteConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteConnectionID", reflect.TypeOf((*MockQuicSession)(nil).RemoteConnectionID))
+}
+
+// RemoteTransportParameters mocks base method
+func (m *MockQuicSession) RemoteTransportParameters() (*TransportParameters, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteTransportParameters")
+	ret0, _ := ret[0].(*TransportParameters)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoteTransportParameters indicates an expected call of RemoteTransportParameters
+func (mr *MockQuicSessionMockRecorder) RemoteTransportParameters() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteTransportParameters", reflect.TypeOf((*MockQuicSession)(nil).RemoteTransportParameters))
+}
+
+// RotateConnectionID mocks base method
+func (m *MockQuicSession) RotateConnectionID() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateConnectionID")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateConnectionID indicates an expected call of RotateConnectionID
+func (mr *MockQuicSessionMockRecorder) RotateConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateConnectionID", reflect.TypeOf((*MockQuicSession)(nil).RotateConnectionID))
+}
+
+// SendPing mocks base method
+func (m *MockQuicSession) SendPing(arg0 context.Context) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendPing", arg0)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendPing indicates an expected call of SendPing
+func (mr *MockQuicSessionMockRecorder) SendPing(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPing", reflect.TypeOf((*MockQuicSession)(nil).SendPing), arg0)
+}
+
+// SetMaxSendRate mocks base method
+func (m *MockQuicSession) SetMaxSendRate(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxSendRate", arg0)
+}
+
+// SetMaxSendRate indicates an expected call of SetMaxSendRate
+func (mr *MockQuicSessionMockRecorder) SetMaxSendRate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxSendRate", reflect.TypeOf((*MockQuicSession)(nil).SetMaxSendRate), arg0)
+}
+
+// SetUserData mocks base method
+func (m *MockQuicSession) SetUserData(arg0 interface{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetUserData", arg0)
+}
+
+// SetUserData indicates an expected call of SetUserData
+func (mr *MockQuicSessionMockRecorder) SetUserData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserData", reflect.TypeOf((*MockQuicSession)(nil).SetUserData), arg0)
+}
+
+// Used0RTT mocks base method
+func (m *MockQuicSession) Used0RTT() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Used0RTT")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Used0RTT indicates an expected call of Used0RTT
+func (mr *MockQuicSessionMockRecorder) Used0RTT() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Used0RTT", reflect.TypeOf((*MockQuicSession)(nil).Used0RTT))
+}
+
+// UsedRetry mocks base method
+func (m *MockQuicSession) UsedRetry() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsedRetry")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// UsedRetry indicates an expected call of UsedRetry
+func (mr *MockQuicSessionMockRecorder) UsedRetry() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsedRetry", reflect.TypeOf((*MockQuicSession)(nil).UsedRetry))
+}
+
+// UserData mocks base method
+func (m *MockQuicSession) UserData() interface{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserData")
+	ret0, _ := ret[0].(interface{})
+	return ret0
+}
+
+// UserData indicates an expected call of UserData
+func (mr *MockQuicSessionMockRecorder) UserData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserData", reflect.TypeOf((*MockQuicSession)(nil).UserData))
+}
+
+// ZeroRTTRejected mocks base method
+func (m *MockQuicSession) ZeroRTTRejected() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZeroRTTRejected")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ZeroRTTRejected indicates an expected call of ZeroRTTRejected
+func (mr *MockQuicSessionMockRecorder) ZeroRTTRejected() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZeroRTTRejected", reflect.TypeOf((*MockQuicSession)(nil).ZeroRTTRejected))
+}
+
 // closeForRecreating mocks base method
 func (m *MockQuicSession) closeForRecreating() protocol.PacketNumber {
 	m.ctrl.T.Helper()
@@ -5,6 +5,7 @@
 package quic
 
 import (
+	io "io"
 	reflect "reflect"
 	time "time"
 
@@ -91,6 +92,21 @@ func (mr *MockReceiveStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockReceiveStreamI)(nil).StreamID))
 }
 
+// WriteTo mocks base method
+func (m *MockReceiveStreamI) WriteTo(arg0 io.Writer) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteTo", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteTo indicates an expected call of WriteTo
+func (mr *MockReceiveStreamIMockRecorder) WriteTo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteTo", reflect.TypeOf((*MockReceiveStreamI)(nil).WriteTo), arg0)
+}
+
 // closeForShutdown mocks base method
 func (m *MockReceiveStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -6,6 +6,7 @@ package quic
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
 	time "time"
 
@@ -38,6 +39,34 @@ func (m *MockSendStreamI) EXPECT() *MockSendStreamIMockRecorder {
 	return m.recorder
 }
 
+// AckedOffset mocks base method
+func (m *MockSendStreamI) AckedOffset() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckedOffset")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// AckedOffset indicates an expected call of AckedOffset
+func (mr *MockSendStreamIMockRecorder) AckedOffset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckedOffset", reflect.TypeOf((*MockSendStreamI)(nil).AckedOffset))
+}
+
+// BufferedAmount mocks base method
+func (m *MockSendStreamI) BufferedAmount() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedAmount")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedAmount indicates an expected call of BufferedAmount
+func (mr *MockSendStreamIMockRecorder) BufferedAmount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedAmount", reflect.TypeOf((*MockSendStreamI)(nil).BufferedAmount))
+}
+
 // CancelWrite mocks base method
 func (m *MockSendStreamI) CancelWrite(arg0 protocol.ApplicationErrorCode) {
 	m.ctrl.T.Helper()
@@ -64,6 +93,20 @@ func (mr *MockSendStreamIMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSendStreamI)(nil).Close))
 }
 
+// CloseWithContext mocks base method
+func (m *MockSendStreamI) CloseWithContext(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithContext", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithContext indicates an expected call of CloseWithContext
+func (mr *MockSendStreamIMockRecorder) CloseWithContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithContext", reflect.TypeOf((*MockSendStreamI)(nil).CloseWithContext), arg0)
+}
+
 // Context mocks base method
 func (m *MockSendStreamI) Context() context.Context {
 	m.ctrl.T.Helper()
@@ -78,6 +121,35 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// ReadFrom mocks base method
+func (m *MockSendStreamI) ReadFrom(arg0 io.Reader) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadFrom", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadFrom indicates an expected call of ReadFrom
+func (mr *MockSendStreamIMockRecorder) ReadFrom(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadFrom", reflect.TypeOf((*MockSendStreamI)(nil).ReadFrom), arg0)
+}
+
+// SendStats mocks base method
+func (m *MockSendStreamI) SendStats() SendStreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendStats")
+	ret0, _ := ret[0].(SendStreamStats)
+	return ret0
+}
+
+// SendStats indicates an expected call of SendStats
+func (mr *MockSendStreamIMockRecorder) SendStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendStats", reflect.TypeOf((*MockSendStreamI)(nil).SendStats))
+}
+
 // SetWriteDeadline mocks base method
 func (m *MockSendStreamI) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -133,6 +205,32 @@ func (mr *MockSendStreamIMockRecorder) closeForShutdown(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockSendStreamI)(nil).closeForShutdown), arg0)
 }
 
+// flushed mocks base method
+func (m *MockSendStreamI) flushed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "flushed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// flushed indicates an expected call of flushed
+func (mr *MockSendStreamIMockRecorder) flushed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "flushed", reflect.TypeOf((*MockSendStreamI)(nil).flushed))
+}
+
+// handle0RTTRejection mocks base method
+func (m *MockSendStreamI) handle0RTTRejection() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "handle0RTTRejection")
+}
+
+// handle0RTTRejection indicates an expected call of handle0RTTRejection
+func (mr *MockSendStreamIMockRecorder) handle0RTTRejection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handle0RTTRejection", reflect.TypeOf((*MockSendStreamI)(nil).handle0RTTRejection))
+}
+
 // handleMaxStreamDataFrame mocks base method
 func (m *MockSendStreamI) handleMaxStreamDataFrame(arg0 *wire.MaxStreamDataFrame) {
 	m.ctrl.T.Helper()
@@ -185,3 +283,17 @@ func (mr *MockSendStreamIMockRecorder) popStreamFrame(arg0 interface{}) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockSendStreamI)(nil).popStreamFrame), arg0)
 }
+
+// writeAndClose mocks base method
+func (m *MockSendStreamI) writeAndClose(arg0 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "writeAndClose", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// writeAndClose indicates an expected call of writeAndClose
+func (mr *MockSendStreamIMockRecorder) writeAndClose(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "writeAndClose", reflect.TypeOf((*MockSendStreamI)(nil).writeAndClose), arg0)
+}
@@ -6,6 +6,7 @@ package quic
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
 	time "time"
 
@@ -38,6 +39,34 @@ func (m *MockStreamI) EXPECT() *MockStreamIMockRecorder {
 	return m.recorder
 }
 
+// AckedOffset mocks base method
+func (m *MockStreamI) AckedOffset() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckedOffset")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// AckedOffset indicates an expected call of AckedOffset
+func (mr *MockStreamIMockRecorder) AckedOffset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckedOffset", reflect.TypeOf((*MockStreamI)(nil).AckedOffset))
+}
+
+// BufferedAmount mocks base method
+func (m *MockStreamI) BufferedAmount() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedAmount")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedAmount indicates an expected call of BufferedAmount
+func (mr *MockStreamIMockRecorder) BufferedAmount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedAmount", reflect.TypeOf((*MockStreamI)(nil).BufferedAmount))
+}
+
 // CancelRead mocks base method
 func (m *MockStreamI) CancelRead(arg0 protocol.ApplicationErrorCode) {
 	m.ctrl.T.Helper()
@@ -76,6 +105,20 @@ func (mr *MockStreamIMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStreamI)(nil).Close))
 }
 
+// CloseWithContext mocks base method
+func (m *MockStreamI) CloseWithContext(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithContext", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithContext indicates an expected call of CloseWithContext
+func (mr *MockStreamIMockRecorder) CloseWithContext(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithContext", reflect.TypeOf((*MockStreamI)(nil).CloseWithContext), arg0)
+}
+
 // Context mocks base method
 func (m *MockStreamI) Context() context.Context {
 	m.ctrl.T.Helper()
@@ -105,6 +148,47 @@ func (mr *MockStreamIMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStreamI)(nil).Read), arg0)
 }
 
+// ReadFrom mocks base method
+func (m *MockStreamI) ReadFrom(arg0 io.Reader) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadFrom", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadFrom indicates an expected call of ReadFrom
+func (mr *MockStreamIMockRecorder) ReadFrom(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadFrom", reflect.TypeOf((*MockStreamI)(nil).ReadFrom), arg0)
+}
+
+// RejectStream mocks base method
+func (m *MockStreamI) RejectStream(arg0 protocol.ApplicationErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RejectStream", arg0)
+}
+
+// RejectStream indicates an expected call of RejectStream
+func (mr *MockStreamIMockRecorder) RejectStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectStream", reflect.TypeOf((*MockStreamI)(nil).RejectStream), arg0)
+}
+
+// SendStats mocks base method
+func (m *MockStreamI) SendStats() SendStreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendStats")
+	ret0, _ := ret[0].(SendStreamStats)
+	return ret0
+}
+
+// SendStats indicates an expected call of SendStats
+func (mr *MockStreamIMockRecorder) SendStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendStats", reflect.TypeOf((*MockStreamI)(nil).SendStats))
+}
+
 // SetDeadline mocks base method
 func (m *MockStreamI) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -176,6 +260,21 @@ func (mr *MockStreamIMockRecorder) Write(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStreamI)(nil).Write), arg0)
 }
 
+// WriteTo mocks base method
+func (m *MockStreamI) WriteTo(arg0 io.Writer) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteTo", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteTo indicates an expected call of WriteTo
+func (mr *MockStreamIMockRecorder) WriteTo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteTo", reflect.TypeOf((*MockStreamI)(nil).WriteTo), arg0)
+}
+
 // closeForShutdown mocks base method
 func (m *MockStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -188,6 +287,20 @@ func (mr *MockStreamIMockRecorder) closeForShutdown(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockStreamI)(nil).closeForShutdown), arg0)
 }
 
+// flushed mocks base method
+func (m *MockStreamI) flushed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "flushed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// flushed indicates an expected call of flushed
+func (mr *MockStreamIMockRecorder) flushed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "flushed", reflect.TypeOf((*MockStreamI)(nil).flushed))
+}
+
 // getWindowUpdate mocks base method
 func (m *MockStreamI) getWindowUpdate() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -202,6 +315,18 @@ func (mr *MockStreamIMockRecorder) getWindowUpdate() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getWindowUpdate", reflect.TypeOf((*MockStreamI)(nil).getWindowUpdate))
 }
 
+// handle0RTTRejection mocks base method
+func (m *MockStreamI) handle0RTTRejection() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "handle0RTTRejection")
+}
+
+// handle0RTTRejection indicates an expected call of handle0RTTRejection
+func (mr *MockStreamIMockRecorder) handle0RTTRejection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handle0RTTRejection", reflect.TypeOf((*MockStreamI)(nil).handle0RTTRejection))
+}
+
 // handleMaxStreamDataFrame mocks base method
 func (m *MockStreamI) handleMaxStreamDataFrame(arg0 *wire.MaxStreamDataFrame) {
 	m.ctrl.T.Helper()
@@ -282,3 +407,41 @@ func (mr *MockStreamIMockRecorder) popStreamFrame(arg0 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockStreamI)(nil).popStreamFrame), arg0)
 }
+
+// setNonIdempotent mocks base method
+func (m *MockStreamI) setNonIdempotent() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setNonIdempotent")
+}
+
+// setNonIdempotent indicates an expected call of setNonIdempotent
+func (mr *MockStreamIMockRecorder) setNonIdempotent() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setNonIdempotent", reflect.TypeOf((*MockStreamI)(nil).setNonIdempotent))
+}
+
+// setReceiveWindowSize mocks base method
+func (m *MockStreamI) setReceiveWindowSize(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setReceiveWindowSize", arg0)
+}
+
+// setReceiveWindowSize indicates an expected call of setReceiveWindowSize
+func (mr *MockStreamIMockRecorder) setReceiveWindowSize(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setReceiveWindowSize", reflect.TypeOf((*MockStreamI)(nil).setReceiveWindowSize), arg0)
+}
+
+// writeAndClose mocks base method
+func (m *MockStreamI) writeAndClose(arg0 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "writeAndClose", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// writeAndClose indicates an expected call of writeAndClose
+func (mr *MockStreamIMockRecorder) writeAndClose(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "writeAndClose", reflect.TypeOf((*MockStreamI)(nil).writeAndClose), arg0)
+}
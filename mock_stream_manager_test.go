@@ -67,6 +67,18 @@ func (mr *MockStreamManagerMockRecorder) AcceptUniStream(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockStreamManager)(nil).AcceptUniStream), arg0)
 }
 
+// AllowMoreIncomingStreams mocks base method
+func (m *MockStreamManager) AllowMoreIncomingStreams(arg0 uint64, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AllowMoreIncomingStreams", arg0, arg1)
+}
+
+// AllowMoreIncomingStreams indicates an expected call of AllowMoreIncomingStreams
+func (mr *MockStreamManagerMockRecorder) AllowMoreIncomingStreams(arg0 interface{}, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllowMoreIncomingStreams", reflect.TypeOf((*MockStreamManager)(nil).AllowMoreIncomingStreams), arg0, arg1)
+}
+
 // CloseWithError mocks base method
 func (m *MockStreamManager) CloseWithError(arg0 error) {
 	m.ctrl.T.Helper()
@@ -123,6 +135,18 @@ func (mr *MockStreamManagerMockRecorder) GetOrOpenSendStream(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrOpenSendStream", reflect.TypeOf((*MockStreamManager)(nil).GetOrOpenSendStream), arg0)
 }
 
+// Handle0RTTRejection mocks base method
+func (m *MockStreamManager) Handle0RTTRejection() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Handle0RTTRejection")
+}
+
+// Handle0RTTRejection indicates an expected call of Handle0RTTRejection
+func (mr *MockStreamManagerMockRecorder) Handle0RTTRejection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Handle0RTTRejection", reflect.TypeOf((*MockStreamManager)(nil).Handle0RTTRejection))
+}
+
 // HandleMaxStreamsFrame mocks base method
 func (m *MockStreamManager) HandleMaxStreamsFrame(arg0 *wire.MaxStreamsFrame) error {
 	m.ctrl.T.Helper()
@@ -167,6 +191,21 @@ func (mr *MockStreamManagerMockRecorder) OpenStreamSync(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSync", reflect.TypeOf((*MockStreamManager)(nil).OpenStreamSync), arg0)
 }
 
+// OpenStreamWithOptions mocks base method
+func (m *MockStreamManager) OpenStreamWithOptions(arg0 StreamOptions) (Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamWithOptions", arg0)
+	ret0, _ := ret[0].(Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamWithOptions indicates an expected call of OpenStreamWithOptions
+func (mr *MockStreamManagerMockRecorder) OpenStreamWithOptions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamWithOptions", reflect.TypeOf((*MockStreamManager)(nil).OpenStreamWithOptions), arg0)
+}
+
 // OpenUniStream mocks base method
 func (m *MockStreamManager) OpenUniStream() (SendStream, error) {
 	m.ctrl.T.Helper()
@@ -197,6 +236,18 @@ func (mr *MockStreamManagerMockRecorder) OpenUniStreamSync(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockStreamManager)(nil).OpenUniStreamSync), arg0)
 }
 
+// RefuseIncomingStreams mocks base method
+func (m *MockStreamManager) RefuseIncomingStreams(arg0 protocol.ApplicationErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RefuseIncomingStreams", arg0)
+}
+
+// RefuseIncomingStreams indicates an expected call of RefuseIncomingStreams
+func (mr *MockStreamManagerMockRecorder) RefuseIncomingStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefuseIncomingStreams", reflect.TypeOf((*MockStreamManager)(nil).RefuseIncomingStreams), arg0)
+}
+
 // UpdateLimits mocks base method
 func (m *MockStreamManager) UpdateLimits(arg0 *handshake.TransportParameters) error {
 	m.ctrl.T.Helper()
@@ -210,3 +261,17 @@ func (mr *MockStreamManagerMockRecorder) UpdateLimits(arg0 interface{}) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLimits", reflect.TypeOf((*MockStreamManager)(nil).UpdateLimits), arg0)
 }
+
+// WaitForFlush mocks base method
+func (m *MockStreamManager) WaitForFlush(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForFlush", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForFlush indicates an expected call of WaitForFlush
+func (mr *MockStreamManagerMockRecorder) WaitForFlush(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForFlush", reflect.TypeOf((*MockStreamManager)(nil).WaitForFlush), arg0)
+}
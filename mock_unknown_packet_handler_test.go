@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	quictrace "github.com/lucas-clemente/quic-go/quictrace"
 )
 
 // MockUnknownPacketHandler is a mock of UnknownPacketHandler interface
@@ -56,3 +57,15 @@ func (mr *MockUnknownPacketHandlerMockRecorder) setCloseError(arg0 interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setCloseError", reflect.TypeOf((*MockUnknownPacketHandler)(nil).setCloseError), arg0)
 }
+
+// tracePacketDropped mocks base method
+func (m *MockUnknownPacketHandler) tracePacketDropped(arg0 *receivedPacket, arg1 quictrace.PacketType, arg2 quictrace.PacketDropReason) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "tracePacketDropped", arg0, arg1, arg2)
+}
+
+// tracePacketDropped indicates an expected call of tracePacketDropped
+func (mr *MockUnknownPacketHandlerMockRecorder) tracePacketDropped(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "tracePacketDropped", reflect.TypeOf((*MockUnknownPacketHandler)(nil).tracePacketDropped), arg0, arg1, arg2)
+}
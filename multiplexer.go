@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"syscall"
 
 	"github.com/lucas-clemente/quic-go/internal/utils"
 )
@@ -20,6 +21,11 @@ type multiplexer interface {
 }
 
 type connManager struct {
+	// The conn and its index are stored when the conn is added.
+	// The index can't be recomputed when removing the conn, since the conn might already be closed.
+	conn  net.PacketConn
+	index string
+
 	connIDLen         int
 	statelessResetKey []byte
 	manager           packetHandlerManager
@@ -30,7 +36,7 @@ type connManager struct {
 type connMultiplexer struct {
 	mutex sync.Mutex
 
-	conns                   map[string] /* LocalAddr().String() */ connManager
+	conns                   map[string] /* see getConnIndex */ connManager
 	newPacketHandlerManager func(net.PacketConn, int, []byte, utils.Logger) packetHandlerManager // so it can be replaced in the tests
 
 	logger utils.Logger
@@ -49,6 +55,28 @@ func getMultiplexer() multiplexer {
 	return connMuxer
 }
 
+// getConnIndex returns the key used to identify a packet conn.
+// Multiple sockets can be bound to the same address using SO_REUSEPORT.
+// If the conn exposes its file descriptor, it is used to distinguish these sockets.
+// Otherwise, conns with the same local address are considered the same,
+// so that wrapping a conn doesn't create a new packet handler manager for it.
+func getConnIndex(c net.PacketConn) string {
+	index := c.LocalAddr().Network() + " " + c.LocalAddr().String()
+	sc, ok := c.(syscall.Conn)
+	if !ok {
+		return index
+	}
+	rawConn, err := sc.SyscallConn()
+	if err != nil {
+		return index
+	}
+	var fd uintptr
+	if err := rawConn.Control(func(f uintptr) { fd = f }); err != nil {
+		return index
+	}
+	return fmt.Sprintf("%s (fd %d)", index, fd)
+}
+
 func (m *connMultiplexer) AddConn(
 	c net.PacketConn,
 	connIDLen int,
@@ -57,11 +85,13 @@ func (m *connMultiplexer) AddConn(
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	connIndex := c.LocalAddr().Network() + " " + c.LocalAddr().String()
+	connIndex := getConnIndex(c)
 	p, ok := m.conns[connIndex]
 	if !ok {
 		manager := m.newPacketHandlerManager(c, connIDLen, statelessResetKey, m.logger)
 		p = connManager{
+			conn:              c,
+			index:             connIndex,
 			connIDLen:         connIDLen,
 			statelessResetKey: statelessResetKey,
 			manager:           manager,
@@ -81,11 +111,11 @@ func (m *connMultiplexer) RemoveConn(c net.PacketConn) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	connIndex := c.LocalAddr().Network() + " " + c.LocalAddr().String()
-	if _, ok := m.conns[connIndex]; !ok {
-		return fmt.Errorf("cannote remove connection, connection is unknown")
+	for _, p := range m.conns {
+		if p.conn == c {
+			delete(m.conns, p.index)
+			return nil
+		}
 	}
-
-	delete(m.conns, connIndex)
-	return nil
+	return fmt.Errorf("cannote remove connection, connection is unknown")
 }
@@ -46,4 +46,36 @@ var _ = Describe("Client Multiplexer", func() {
 		_, err = getMultiplexer().AddConn(conn, 7, []byte("raboof"))
 		Expect(err).To(MatchError("cannot use different stateless reset keys on the same packet conn"))
 	})
+
+	It("removes a conn after it was closed", func() {
+		conn := newMockPacketConn()
+		conn.addr = &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
+		manager, err := getMultiplexer().AddConn(conn, 8, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(getMultiplexer().(*connMultiplexer).conns).To(HaveKey(getConnIndex(conn)))
+		// closing the conn makes the listen goroutine remove the conn from the multiplexer
+		Expect(conn.Close()).To(Succeed())
+		Eventually(manager.(*packetHandlerMap).listening).Should(BeClosed())
+		for _, p := range getMultiplexer().(*connMultiplexer).conns {
+			Expect(p.conn).ToNot(Equal(conn))
+		}
+	})
+
+	It("errors when removing an unknown conn", func() {
+		Expect(getMultiplexer().RemoveConn(newMockPacketConn())).To(MatchError("cannote remove connection, connection is unknown"))
+	})
+
+	It("distinguishes sockets bound to the same address by their file descriptor", func() {
+		addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		conn, err := net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		index := getConnIndex(conn)
+		Expect(index).To(ContainSubstring(conn.LocalAddr().String()))
+		Expect(index).To(ContainSubstring("fd"))
+		Expect(getConnIndex(conn)).To(Equal(index))
+		// a wrapped conn doesn't expose the file descriptor
+		Expect(getConnIndex(testConn{PacketConn: conn})).To(Equal("udp " + conn.LocalAddr().String()))
+	})
 })
@@ -0,0 +1,106 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// An OffsetWriter writes data to a SendStream at explicit offsets.
+// QUIC delivers stream data in order, so data written beyond the current end of the stream
+// is buffered, and written to the stream as soon as all data preceding it was written.
+// This is useful for resumable transfers: after a connection was lost, the application opens a new stream,
+// and continues writing from the offset reported by AckedOffset.
+// Warning: This API should not be considered stable and might change soon.
+type OffsetWriter struct {
+	str SendStream
+	// baseOffset is the offset of the first byte written to the stream
+	baseOffset uint64
+
+	mutex sync.Mutex
+	// offset is the offset up to which data was passed to the stream
+	offset uint64
+	// writing is set while a call to WriteAt writes to the stream
+	writing bool
+	pending map[uint64][]byte
+	err     error
+}
+
+var _ io.WriterAt = &OffsetWriter{}
+
+// NewOffsetWriter creates a new OffsetWriter.
+// The offset is the offset of the first byte written to the stream.
+// For a new transfer, this is 0, when resuming a transfer, this is the offset
+// up to which data was acknowledged on the previous stream.
+func NewOffsetWriter(str SendStream, offset uint64) *OffsetWriter {
+	return &OffsetWriter{
+		str:        str,
+		baseOffset: offset,
+		offset:     offset,
+		pending:    make(map[uint64][]byte),
+	}
+}
+
+// WriteAt writes p at offset off.
+// If data preceding the offset hasn't been written yet, p is copied and buffered.
+// Otherwise, it blocks until p (and all buffered data following it) has been written to the stream.
+// It returns an error if p overlaps with data that was already written.
+// WriteAt can be called concurrently, as long as the ranges written don't overlap.
+func (w *OffsetWriter) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err != nil {
+		return 0, w.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	start := uint64(off)
+	end := start + uint64(len(p))
+	if start < w.offset {
+		return 0, fmt.Errorf("overlapping write at offset %d: data up to offset %d was already written", start, w.offset)
+	}
+	for o, data := range w.pending {
+		if start < o+uint64(len(data)) && o < end {
+			return 0, fmt.Errorf("overlapping write at offset %d: data at offset %d is already buffered", start, o)
+		}
+	}
+	if start != w.offset || w.writing {
+		w.pending[start] = append([]byte(nil), p...)
+		return len(p), nil
+	}
+
+	w.writing = true
+	defer func() { w.writing = false }()
+	data := p
+	var n int
+	for {
+		w.offset += uint64(len(data))
+		w.mutex.Unlock()
+		_, err := w.str.Write(data)
+		w.mutex.Lock()
+		if err != nil {
+			w.err = err
+			return n, err
+		}
+		if n == 0 {
+			n = len(p)
+		}
+		next, ok := w.pending[w.offset]
+		if !ok {
+			return n, nil
+		}
+		delete(w.pending, w.offset)
+		data = next
+	}
+}
+
+// AckedOffset returns the offset up to which all data was acknowledged by the peer.
+func (w *OffsetWriter) AckedOffset() uint64 {
+	return w.baseOffset + w.str.AckedOffset()
+}
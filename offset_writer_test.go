@@ -0,0 +1,159 @@
+package quic
+
+import (
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Offset Writer", func() {
+	var (
+		str *MockSendStreamI
+		w   *OffsetWriter
+	)
+
+	BeforeEach(func() {
+		str = NewMockSendStreamI(mockCtrl)
+		w = NewOffsetWriter(str, 0)
+	})
+
+	It("writes data at the current offset", func() {
+		gomock.InOrder(
+			str.EXPECT().Write([]byte("foo")).Return(3, nil),
+			str.EXPECT().Write([]byte("bar")).Return(3, nil),
+		)
+		n, err := w.WriteAt([]byte("foo"), 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+		n, err = w.WriteAt([]byte("bar"), 3)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+	})
+
+	It("buffers data written out of order", func() {
+		b := []byte("baz")
+		n, err := w.WriteAt(b, 6)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+		b[0] = 'x' // the data is copied
+		_, err = w.WriteAt([]byte("bar"), 3)
+		Expect(err).ToNot(HaveOccurred())
+		gomock.InOrder(
+			str.EXPECT().Write([]byte("foo")).Return(3, nil),
+			str.EXPECT().Write([]byte("bar")).Return(3, nil),
+			str.EXPECT().Write([]byte("baz")).Return(3, nil),
+		)
+		n, err = w.WriteAt([]byte("foo"), 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+	})
+
+	It("buffers data while another call is writing", func() {
+		unblock := make(chan struct{})
+		gomock.InOrder(
+			str.EXPECT().Write([]byte("foo")).DoAndReturn(func(p []byte) (int, error) {
+				<-unblock
+				return len(p), nil
+			}),
+			str.EXPECT().Write([]byte("bar")).Return(3, nil),
+		)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := w.WriteAt([]byte("foo"), 0)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Eventually(func() uint64 {
+			w.mutex.Lock()
+			defer w.mutex.Unlock()
+			return w.offset
+		}).Should(BeEquivalentTo(3))
+		n, err := w.WriteAt([]byte("bar"), 3)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+		close(unblock)
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("errors when writing data that overlaps with data that was already written", func() {
+		str.EXPECT().Write([]byte("foobar")).Return(6, nil)
+		_, err := w.WriteAt([]byte("foobar"), 0)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = w.WriteAt([]byte("foo"), 4)
+		Expect(err).To(MatchError("overlapping write at offset 4: data up to offset 6 was already written"))
+	})
+
+	It("errors when writing data that overlaps with buffered data", func() {
+		_, err := w.WriteAt([]byte("foobar"), 10)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = w.WriteAt([]byte("foo"), 8)
+		Expect(err).To(MatchError("overlapping write at offset 8: data at offset 10 is already buffered"))
+		_, err = w.WriteAt([]byte("foo"), 7)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("errors on negative offsets", func() {
+		_, err := w.WriteAt([]byte("foo"), -1)
+		Expect(err).To(MatchError("negative offset"))
+	})
+
+	It("returns the error of the stream", func() {
+		testErr := errors.New("test error")
+		str.EXPECT().Write([]byte("foo")).Return(0, testErr)
+		_, err := w.WriteAt([]byte("bar"), 3)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = w.WriteAt([]byte("foo"), 0)
+		Expect(err).To(MatchError(testErr))
+		_, err = w.WriteAt([]byte("baz"), 6)
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("starts writing at the given offset", func() {
+		w = NewOffsetWriter(str, 100)
+		str.EXPECT().Write([]byte("foo")).Return(3, nil)
+		_, err := w.WriteAt([]byte("foo"), 100)
+		Expect(err).ToNot(HaveOccurred())
+		str.EXPECT().AckedOffset().Return(uint64(2))
+		Expect(w.AckedOffset()).To(BeEquivalentTo(102))
+	})
+
+	It("sends data written out of order in the right order", func() {
+		mockSender := NewMockStreamSender(mockCtrl)
+		mockSender.EXPECT().onHasStreamData(gomock.Any()).AnyTimes()
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+		mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		sendStr := newSendStream(1337, mockSender, mockFC, protocol.VersionWhatever)
+		w = NewOffsetWriter(sendStr, 0)
+
+		chunks := map[int64]string{6: "baz", 3: "bar", 9: "qux", 0: "foo"}
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			for _, off := range []int64{6, 3, 9, 0} {
+				_, err := w.WriteAt([]byte(chunks[off]), off)
+				Expect(err).ToNot(HaveOccurred())
+			}
+		}()
+		var data []byte
+		var offset protocol.ByteCount
+		Eventually(func() []byte {
+			if frame, _ := sendStr.popStreamFrame(1000); frame != nil {
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.Offset).To(Equal(offset))
+				offset += f.DataLen()
+				data = append(data, f.Data...)
+			}
+			return data
+		}).Should(Equal([]byte("foobarbazqux")))
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -4,8 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
-	"errors"
-	"hash"
+	"fmt"
 	"net"
 	"sync"
 	"time"
@@ -13,6 +12,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
 // The packetHandlerMap stores packetHandlers, identified by connection ID.
@@ -23,6 +23,7 @@ type packetHandlerMap struct {
 	mutex sync.RWMutex
 
 	conn      net.PacketConn
+	oobConn   *oobConn // nil if the conn doesn't support reading the destination address
 	connIDLen int
 
 	handlers    map[string] /* string(ConnectionID)*/ packetHandler
@@ -35,8 +36,7 @@ type packetHandlerMap struct {
 	deleteRetiredSessionsAfter time.Duration
 
 	statelessResetEnabled bool
-	statelessResetMutex   sync.Mutex
-	statelessResetHasher  hash.Hash
+	statelessResetKey     []byte
 
 	logger utils.Logger
 }
@@ -51,13 +51,14 @@ func newPacketHandlerMap(
 ) packetHandlerManager {
 	m := &packetHandlerMap{
 		conn:                       conn,
+		oobConn:                    newOOBConn(conn),
 		connIDLen:                  connIDLen,
 		listening:                  make(chan struct{}),
 		handlers:                   make(map[string]packetHandler),
 		resetTokens:                make(map[[16]byte]packetHandler),
 		deleteRetiredSessionsAfter: protocol.RetiredConnectionIDDeleteTimeout,
 		statelessResetEnabled:      len(statelessResetKey) > 0,
-		statelessResetHasher:       hmac.New(sha256.New, statelessResetKey),
+		statelessResetKey:          statelessResetKey,
 		logger:                     logger,
 	}
 	go m.listen()
@@ -221,17 +222,26 @@ func (h *packetHandlerMap) listen() {
 		data := buffer.Slice
 		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
 		// If it does, we only read a truncated packet, which will then end up undecryptable
-		n, addr, err := h.conn.ReadFrom(data)
+		var n int
+		var addr net.Addr
+		var info *packetInfo
+		var err error
+		if h.oobConn != nil {
+			n, addr, info, err = h.oobConn.ReadPacket(data)
+		} else {
+			n, addr, err = h.conn.ReadFrom(data)
+		}
 		if err != nil {
 			h.close(err)
 			return
 		}
-		h.handlePacket(addr, buffer, data[:n])
+		h.handlePacket(addr, info, buffer, data[:n])
 	}
 }
 
 func (h *packetHandlerMap) handlePacket(
 	addr net.Addr,
+	info *packetInfo,
 	buffer *packetBuffer,
 	data []byte,
 ) {
@@ -253,6 +263,7 @@ func (h *packetHandlerMap) handlePacket(
 
 	p := &receivedPacket{
 		remoteAddr: addr,
+		info:       info,
 		rcvTime:    rcvTime,
 		buffer:     buffer,
 		data:       data,
@@ -262,6 +273,9 @@ func (h *packetHandlerMap) handlePacket(
 		return
 	}
 	if data[0]&0x80 == 0 {
+		if h.server != nil {
+			h.server.tracePacketDropped(p, quictrace.PacketType1RTT, quictrace.PacketDropUnknownConnectionID)
+		}
 		go h.maybeSendStatelessReset(p, connID)
 		return
 	}
@@ -272,6 +286,45 @@ func (h *packetHandlerMap) handlePacket(
 	h.server.handlePacket(p)
 }
 
+// A StatelessResetError occurs when the peer sent a stateless reset,
+// i.e. when the peer lost the state for this connection.
+type StatelessResetError struct {
+	Token [16]byte
+}
+
+func (e *StatelessResetError) Error() string {
+	return "received a stateless reset"
+}
+
+// ComputeStatelessResetToken computes the stateless reset token for a connection ID.
+// Using the same key as the Config.StatelessResetKey of a server, it returns the same token as that server.
+// This allows an external component (e.g. a load balancer) to send stateless resets for connections
+// that the server doesn't have any state for anymore.
+// Warning: This API should not be considered stable and might change soon.
+func ComputeStatelessResetToken(key []byte, connID ConnectionID) [16]byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(connID.Bytes())
+	var token [16]byte
+	copy(token[:], h.Sum(nil))
+	return token
+}
+
+// NewStatelessResetPacket creates a stateless reset packet of the given length.
+// The packet consists of random bytes, looking like a short header packet, followed by the stateless reset token.
+// To prevent an endless exchange of stateless resets, a stateless reset should be smaller than the packet it is sent in response to.
+// Warning: This API should not be considered stable and might change soon.
+func NewStatelessResetPacket(token [16]byte, length int) ([]byte, error) {
+	if length < protocol.MinStatelessResetSize {
+		return nil, fmt.Errorf("stateless reset packet too short: %d bytes (minimum %d)", length, protocol.MinStatelessResetSize)
+	}
+	data := make([]byte, length-16, length)
+	if _, err := rand.Read(data); err != nil {
+		return nil, err
+	}
+	data[0] = (data[0] & 0x7f) | 0x40
+	return append(data, token[:]...), nil
+}
+
 func (h *packetHandlerMap) maybeHandleStatelessReset(data []byte) bool {
 	// stateless resets are always short header packets
 	if data[0]&0x80 != 0 {
@@ -284,8 +337,8 @@ func (h *packetHandlerMap) maybeHandleStatelessReset(data []byte) bool {
 	var token [16]byte
 	copy(token[:], data[len(data)-16:])
 	if sess, ok := h.resetTokens[token]; ok {
-		h.logger.Debugf("Received a stateless retry with token %#x. Closing session.", token)
-		go sess.destroy(errors.New("received a stateless reset"))
+		h.logger.Debugf("Received a stateless reset with token %#x. Closing session.", token)
+		go sess.destroy(&StatelessResetError{Token: token})
 		return true
 	}
 	return false
@@ -300,12 +353,7 @@ func (h *packetHandlerMap) GetStatelessResetToken(connID protocol.ConnectionID)
 		rand.Read(token[:])
 		return token
 	}
-	h.statelessResetMutex.Lock()
-	h.statelessResetHasher.Write(connID.Bytes())
-	copy(token[:], h.statelessResetHasher.Sum(nil))
-	h.statelessResetHasher.Reset()
-	h.statelessResetMutex.Unlock()
-	return token
+	return ComputeStatelessResetToken(h.statelessResetKey, connID)
 }
 
 func (h *packetHandlerMap) maybeSendStatelessReset(p *receivedPacket, connID protocol.ConnectionID) {
@@ -320,11 +368,27 @@ func (h *packetHandlerMap) maybeSendStatelessReset(p *receivedPacket, connID pro
 	}
 	token := h.GetStatelessResetToken(connID)
 	h.logger.Debugf("Sending stateless reset to %s (connection ID: %s). Token: %#x", p.remoteAddr, connID, token)
-	data := make([]byte, protocol.MinStatelessResetSize-16, protocol.MinStatelessResetSize)
-	rand.Read(data)
-	data[0] = (data[0] & 0x7f) | 0x40
-	data = append(data, token[:]...)
-	if _, err := h.conn.WriteTo(data, p.remoteAddr); err != nil {
+	data, err := NewStatelessResetPacket(token, protocol.MinStatelessResetSize)
+	if err != nil {
+		h.logger.Debugf("Error creating Stateless Reset: %s", err)
+		return
+	}
+	if err := h.writeTo(data, p.remoteAddr, p.info); err != nil {
 		h.logger.Debugf("Error sending Stateless Reset: %s", err)
 	}
 }
+
+func (h *packetHandlerMap) OOBConn() *oobConn {
+	return h.oobConn
+}
+
+// writeTo sends a packet.
+// If supported by the conn, it is sent from the local address contained in info.
+func (h *packetHandlerMap) writeTo(b []byte, addr net.Addr, info *packetInfo) error {
+	if h.oobConn != nil {
+		_, err := h.oobConn.WritePacket(b, addr, info)
+		return err
+	}
+	_, err := h.conn.WriteTo(b, addr)
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"net"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -122,7 +124,7 @@ var _ = Describe("Packet Handler Map", func() {
 		})
 
 		It("drops unparseable packets", func() {
-			handler.handlePacket(nil, nil, []byte{0, 1, 2, 3})
+			handler.handlePacket(nil, nil, nil, []byte{0, 1, 2, 3})
 		})
 
 		It("deletes removed sessions immediately", func() {
@@ -130,7 +132,7 @@ var _ = Describe("Packet Handler Map", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
 			handler.Add(connID, NewMockPacketHandler(mockCtrl))
 			handler.Remove(connID)
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, nil, nil, getPacket(connID))
 			// don't EXPECT any calls to handlePacket of the MockPacketHandler
 		})
 
@@ -141,7 +143,7 @@ var _ = Describe("Packet Handler Map", func() {
 			handler.Add(connID, sess)
 			handler.Retire(connID)
 			time.Sleep(scaleDuration(30 * time.Millisecond))
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, nil, nil, getPacket(connID))
 			// don't EXPECT any calls to handlePacket of the MockPacketHandler
 		})
 
@@ -155,13 +157,13 @@ var _ = Describe("Packet Handler Map", func() {
 			})
 			handler.Add(connID, packetHandler)
 			handler.Retire(connID)
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, nil, nil, getPacket(connID))
 			Eventually(handled).Should(BeClosed())
 		})
 
 		It("drops packets for unknown receivers", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, nil, nil, getPacket(connID))
 		})
 
 		It("closes the packet handlers when reading from the conn fails", func() {
@@ -194,7 +196,7 @@ var _ = Describe("Packet Handler Map", func() {
 				Expect(cid).To(Equal(connID))
 			})
 			handler.SetServer(server)
-			handler.handlePacket(nil, nil, p)
+			handler.handlePacket(nil, nil, nil, p)
 		})
 
 		It("closes all server sessions", func() {
@@ -209,6 +211,18 @@ var _ = Describe("Packet Handler Map", func() {
 			handler.CloseServer()
 		})
 
+		It("traces short header packets with unknown connection IDs", func() {
+			connID := protocol.ConnectionID{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+			p := append([]byte{0x40} /* short header packet */, connID.Bytes()...)
+			p = append(p, make([]byte, 50)...)
+			server := NewMockUnknownPacketHandler(mockCtrl)
+			server.EXPECT().tracePacketDropped(gomock.Any(), quictrace.PacketType1RTT, quictrace.PacketDropUnknownConnectionID).Do(func(p *receivedPacket, _ quictrace.PacketType, _ quictrace.PacketDropReason) {
+				Expect(p.data).To(HaveLen(1 + 8 + 50))
+			})
+			handler.SetServer(server)
+			handler.handlePacket(nil, nil, getPacketBuffer(), p)
+		})
+
 		It("stops handling packets with unknown connection IDs after the server is closed", func() {
 			connID := protocol.ConnectionID{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
 			p := getPacket(connID)
@@ -216,7 +230,7 @@ var _ = Describe("Packet Handler Map", func() {
 			// don't EXPECT any calls to server.handlePacket
 			handler.SetServer(server)
 			handler.CloseServer()
-			handler.handlePacket(nil, nil, p)
+			handler.handlePacket(nil, nil, nil, p)
 		})
 	})
 
@@ -233,7 +247,9 @@ var _ = Describe("Packet Handler Map", func() {
 				packet := append([]byte{0x40} /* short header packet */, make([]byte, 50)...)
 				packet = append(packet, token[:]...)
 				destroyed := make(chan struct{})
-				packetHandler.EXPECT().destroy(errors.New("received a stateless reset")).Do(func(error) {
+				packetHandler.EXPECT().destroy(gomock.Any()).Do(func(err error) {
+					Expect(err).To(BeAssignableToTypeOf(&StatelessResetError{}))
+					Expect(err.(*StatelessResetError).Token).To(Equal(token))
 					close(destroyed)
 				})
 				conn.dataToRead <- packet
@@ -248,7 +264,9 @@ var _ = Describe("Packet Handler Map", func() {
 				packet := append([]byte{0x40} /* short header packet */, make([]byte, 50)...)
 				packet = append(packet, token[:]...)
 				destroyed := make(chan struct{})
-				packetHandler.EXPECT().destroy(errors.New("received a stateless reset")).Do(func(error) {
+				packetHandler.EXPECT().destroy(gomock.Any()).Do(func(err error) {
+					Expect(err).To(BeAssignableToTypeOf(&StatelessResetError{}))
+					Expect(err.(*StatelessResetError).Token).To(Equal(token))
 					close(destroyed)
 				})
 				conn.dataToRead <- packet
@@ -269,7 +287,7 @@ var _ = Describe("Packet Handler Map", func() {
 				p = append(p, token[:]...)
 
 				time.Sleep(scaleDuration(30 * time.Millisecond))
-				handler.handlePacket(nil, nil, p)
+				handler.handlePacket(nil, nil, nil, p)
 			})
 
 			It("ignores packets too small to contain a stateless reset", func() {
@@ -301,10 +319,30 @@ var _ = Describe("Packet Handler Map", func() {
 				Expect(handler.GetStatelessResetToken(connID1)).ToNot(Equal(handler.GetStatelessResetToken(connID2)))
 			})
 
+			It("computes the same stateless reset tokens as the server", func() {
+				connID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
+				Expect(ComputeStatelessResetToken(statelessResetKey, connID)).To(Equal(handler.GetStatelessResetToken(connID)))
+				Expect(ComputeStatelessResetToken([]byte("another key"), connID)).ToNot(Equal(handler.GetStatelessResetToken(connID)))
+			})
+
+			It("creates stateless reset packets", func() {
+				token := [16]byte{0xde, 0xca, 0xfb, 0xad}
+				data, err := NewStatelessResetPacket(token, 100)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(HaveLen(100))
+				Expect(data[0] & 0xc0).To(Equal(uint8(0x40))) // short header packet, with the fixed bit set
+				Expect(data[len(data)-16:]).To(Equal(token[:]))
+			})
+
+			It("refuses to create too short stateless reset packets", func() {
+				_, err := NewStatelessResetPacket([16]byte{}, protocol.MinStatelessResetSize-1)
+				Expect(err).To(MatchError(fmt.Sprintf("stateless reset packet too short: %d bytes (minimum %d)", protocol.MinStatelessResetSize-1, protocol.MinStatelessResetSize)))
+			})
+
 			It("sends stateless resets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, 100)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, nil, getPacketBuffer(), p)
 				var reset mockPacketConnWrite
 				Eventually(conn.dataWritten).Should(Receive(&reset))
 				Expect(reset.to).To(Equal(addr))
@@ -315,7 +353,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("doesn't send stateless resets for small packets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, protocol.MinStatelessResetSize-2)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, nil, getPacketBuffer(), p)
 				Consistently(conn.dataWritten).ShouldNot(Receive())
 			})
 		})
@@ -324,7 +362,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("doesn't send stateless resets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, 100)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, nil, getPacketBuffer(), p)
 				Consistently(conn.dataWritten).ShouldNot(Receive())
 			})
 		})
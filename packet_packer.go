@@ -19,6 +19,7 @@ type packer interface {
 	MaybePackProbePacket(protocol.EncryptionLevel) (*packedPacket, error)
 	MaybePackAckPacket() (*packedPacket, error)
 	PackConnectionClose(*wire.ConnectionCloseFrame) (*packedPacket, error)
+	PackPathChallenge(f *wire.PathChallengeFrame, onLost func(wire.Frame)) (*packedPacket, error)
 
 	HandleTransportParameters(*handshake.TransportParameters)
 	SetToken([]byte)
@@ -154,6 +155,11 @@ type packetPacker struct {
 
 	maxPacketSize          protocol.ByteCount
 	numNonAckElicitingAcks int
+
+	// When set, packets are sent without header protection, so that packet numbers can be read on the wire.
+	// This is only useful for debugging, and must never be used in production.
+	// It is set when building with the quic_noheaderprotection build tag.
+	disableHeaderProtection bool
 }
 
 var _ packer = &packetPacker{}
@@ -166,25 +172,31 @@ func newPacketPacker(
 	packetNumberManager packetNumberManager,
 	retransmissionQueue *retransmissionQueue,
 	remoteAddr net.Addr, // only used for determining the max packet size
+	initialMaxPacketSize protocol.ByteCount, // if set, the max packet size is not determined from the remote address
 	cryptoSetup sealingManager,
 	framer frameSource,
 	acks ackFrameSource,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) *packetPacker {
+	maxPacketSize := initialMaxPacketSize
+	if maxPacketSize == 0 {
+		maxPacketSize = getMaxPacketSize(remoteAddr)
+	}
 	return &packetPacker{
-		cryptoSetup:         cryptoSetup,
-		getDestConnID:       getDestConnID,
-		srcConnID:           srcConnID,
-		initialStream:       initialStream,
-		handshakeStream:     handshakeStream,
-		retransmissionQueue: retransmissionQueue,
-		perspective:         perspective,
-		version:             version,
-		framer:              framer,
-		acks:                acks,
-		pnManager:           packetNumberManager,
-		maxPacketSize:       getMaxPacketSize(remoteAddr),
+		cryptoSetup:             cryptoSetup,
+		getDestConnID:           getDestConnID,
+		srcConnID:               srcConnID,
+		initialStream:           initialStream,
+		handshakeStream:         handshakeStream,
+		retransmissionQueue:     retransmissionQueue,
+		perspective:             perspective,
+		version:                 version,
+		framer:                  framer,
+		acks:                    acks,
+		pnManager:               packetNumberManager,
+		maxPacketSize:           maxPacketSize,
+		disableHeaderProtection: !headerProtectionEnabled,
 	}
 }
 
@@ -225,6 +237,20 @@ func (p *packetPacker) PackConnectionClose(ccf *wire.ConnectionCloseFrame) (*pac
 	return p.writeAndSealPacket(hdr, payload, encLevel, sealer)
 }
 
+// PackPathChallenge packs a 1-RTT packet that ONLY contains a PathChallengeFrame.
+// PATH_CHALLENGE frames are not retransmitted as is, so onLost is called instead of queueing the frame again.
+func (p *packetPacker) PackPathChallenge(f *wire.PathChallengeFrame, onLost func(wire.Frame)) (*packedPacket, error) {
+	sealer, err := p.cryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	payload := payload{
+		frames: []ackhandler.Frame{{Frame: f, OnLost: onLost}},
+		length: f.Length(p.version),
+	}
+	return p.writeAndSealPacket(p.getShortHeader(sealer.KeyPhase()), payload, protocol.Encryption1RTT, sealer)
+}
+
 func (p *packetPacker) MaybePackAckPacket() (*packedPacket, error) {
 	var encLevel protocol.EncryptionLevel
 	var ack *wire.AckFrame
@@ -552,9 +578,13 @@ func (p *packetPacker) writeAndSealPacket(
 
 	if encLevel != protocol.Encryption1RTT {
 		if p.perspective == protocol.PerspectiveClient && header.Type == protocol.PacketTypeInitial {
+			// Pad the Initial to the minimum size, if it's not large enough already.
+			// This can happen when using an InitialMaxPacketSize larger than the minimum size.
 			headerLen := header.GetLength(p.version)
-			header.Length = pnLen + protocol.MinInitialPacketSize - headerLen
-			paddingLen = protocol.ByteCount(protocol.MinInitialPacketSize-sealer.Overhead()) - headerLen - payload.length
+			if minPayloadLen := protocol.ByteCount(protocol.MinInitialPacketSize-sealer.Overhead()) - headerLen; payload.length < minPayloadLen {
+				paddingLen = minPayloadLen - payload.length
+			}
+			header.Length = pnLen + protocol.ByteCount(sealer.Overhead()) + payload.length + paddingLen
 		} else {
 			header.Length = pnLen + protocol.ByteCount(sealer.Overhead()) + payload.length
 		}
@@ -571,7 +601,12 @@ func (p *packetPacker) writeAndSealPacketWithPadding(
 	encLevel protocol.EncryptionLevel,
 	sealer sealer,
 ) (*packedPacket, error) {
-	packetBuffer := getPacketBuffer()
+	var packetBuffer *packetBuffer
+	if p.maxPacketSize > protocol.MaxReceivePacketSize {
+		packetBuffer = getLargePacketBuffer(p.maxPacketSize)
+	} else {
+		packetBuffer = getPacketBuffer()
+	}
 	buffer := bytes.NewBuffer(packetBuffer.Slice[:0])
 
 	if err := header.Write(buffer, p.version); err != nil {
@@ -605,12 +640,14 @@ func (p *packetPacker) writeAndSealPacketWithPadding(
 	_ = sealer.Seal(raw[payloadOffset:payloadOffset], raw[payloadOffset:], header.PacketNumber, raw[:payloadOffset])
 	raw = raw[0 : buffer.Len()+sealer.Overhead()]
 
-	pnOffset := payloadOffset - int(header.PacketNumberLen)
-	sealer.EncryptHeader(
-		raw[pnOffset+4:pnOffset+4+16],
-		&raw[0],
-		raw[pnOffset:payloadOffset],
-	)
+	if !p.disableHeaderProtection {
+		pnOffset := payloadOffset - int(header.PacketNumberLen)
+		sealer.EncryptHeader(
+			raw[pnOffset+4:pnOffset+4+16],
+			&raw[0],
+			raw[pnOffset:payloadOffset],
+		)
+	}
 
 	num := p.pnManager.PopPacketNumber(encLevel)
 	if num != header.PacketNumber {
@@ -82,6 +82,7 @@ var _ = Describe("Packet packer", func() {
 			pnManager,
 			retransmissionQueue,
 			&net.TCPAddr{},
+			0,
 			sealingManager,
 			framer,
 			ackFramer,
@@ -90,6 +91,8 @@ var _ = Describe("Packet packer", func() {
 		)
 		packer.version = version
 		packer.maxPacketSize = maxPacketSize
+		// the header protection is tested, even when building with the quic_noheaderprotection build tag
+		packer.disableHeaderProtection = false
 	})
 
 	Context("determining the maximum packet size", func() {
@@ -107,6 +110,22 @@ var _ = Describe("Packet packer", func() {
 			addr := &net.UDPAddr{IP: ip, Port: 1337}
 			Expect(getMaxPacketSize(addr)).To(BeEquivalentTo(protocol.MaxPacketSizeIPv6))
 		})
+
+		It("uses the initial max packet size, if set", func() {
+			addr := &net.UDPAddr{IP: net.IPv4(11, 12, 13, 14), Port: 1337}
+			p := newPacketPacker(nil, nil, nil, nil, nil, nil, addr, 1400, nil, nil, nil, protocol.PerspectiveClient, version)
+			Expect(p.maxPacketSize).To(BeEquivalentTo(1400))
+		})
+
+		It("limits the initial max packet size to the peer's max_packet_size", func() {
+			addr := &net.UDPAddr{IP: net.IPv4(11, 12, 13, 14), Port: 1337}
+			p := newPacketPacker(nil, nil, nil, nil, nil, nil, addr, 8900, nil, nil, nil, protocol.PerspectiveClient, version)
+			Expect(p.maxPacketSize).To(BeEquivalentTo(8900))
+			p.HandleTransportParameters(&handshake.TransportParameters{MaxPacketSize: 10000})
+			Expect(p.maxPacketSize).To(BeEquivalentTo(8900))
+			p.HandleTransportParameters(&handshake.TransportParameters{MaxPacketSize: 5000})
+			Expect(p.maxPacketSize).To(BeEquivalentTo(5000))
+		})
 	})
 
 	Context("generating a packet header", func() {
@@ -186,6 +205,40 @@ var _ = Describe("Packet packer", func() {
 			Expect(p.raw[0:len(hdrRaw)]).To(Equal(hdrRawEncrypted))
 			Expect(p.raw[len(p.raw)-4:]).To(Equal([]byte{0xde, 0xca, 0xfb, 0xad}))
 		})
+
+		It("round-trips packets with header protection disabled", func() {
+			packer.perspective = protocol.PerspectiveClient
+			packer.disableHeaderProtection = true
+			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
+			sealer, _ := handshake.NewInitialAEAD(connID, protocol.PerspectiveClient, packer.version)
+			_, opener := handshake.NewInitialAEAD(connID, protocol.PerspectiveServer, packer.version)
+			pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+			pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42))
+			sealingManager.EXPECT().GetInitialSealer().Return(sealer, nil)
+			ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial)
+			f := &wire.CryptoFrame{Data: []byte("foobar")}
+			initialStream.EXPECT().HasData().Return(true).AnyTimes()
+			initialStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(f)
+			p, err := packer.PackPacket()
+			Expect(err).ToNot(HaveOccurred())
+			// the packet number can be read from the wire, without removing header protection
+			hdr, data, _, err := wire.ParsePacket(p.raw, 0)
+			Expect(err).ToNot(HaveOccurred())
+			extHdr, err := hdr.ParseExtended(bytes.NewReader(data), version)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(extHdr.PacketNumber).To(Equal(protocol.PacketNumber(0x42)))
+			// unpack the packet
+			cs := mocks.NewMockCryptoSetup(mockCtrl)
+			cs.EXPECT().GetInitialOpener().Return(opener, nil)
+			unpacker := newPacketUnpacker(cs, version).(*packetUnpacker)
+			unpacker.disableHeaderProtection = true
+			packet, err := unpacker.Unpack(hdr, time.Now(), data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packet.packetNumber).To(Equal(protocol.PacketNumber(0x42)))
+			_, frame, err := wire.NewFrameParser(version).ParseNext(bytes.NewReader(packet.data), protocol.EncryptionInitial)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(f))
+		})
 	})
 
 	Context("packing packets", func() {
@@ -360,6 +413,28 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.frames[0].Frame).To(Equal(&ccf))
 			})
 
+			It("packs a PATH_CHALLENGE packet", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(sealer, nil)
+				f := &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+				var lost wire.Frame
+				p, err := packer.PackPathChallenge(f, func(f wire.Frame) { lost = f })
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				Expect(p.ack).To(BeNil())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(f))
+				p.frames[0].OnLost(p.frames[0].Frame)
+				Expect(lost).To(Equal(f))
+			})
+
+			It("doesn't pack a PATH_CHALLENGE packet before the 1-RTT keys are available", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				_, err := packer.PackPathChallenge(&wire.PathChallengeFrame{}, func(wire.Frame) {})
+				Expect(err).To(MatchError(handshake.ErrKeysNotYetAvailable))
+			})
+
 			It("packs control frames", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -426,7 +501,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(firstPayloadByte).To(Equal(byte(0)))
 				// ... followed by the STREAM frame
 				frameParser := wire.NewFrameParser(packer.version)
-				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
+				_, frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
 				sf := frame.(*wire.StreamFrame)
@@ -644,6 +719,46 @@ var _ = Describe("Packet packer", func() {
 				checkLength(p.raw)
 			})
 
+			It("packs Initial packets up to the initial max packet size, and reduces the size after receiving the transport parameters", func() {
+				packer = newPacketPacker(
+					protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+					func() protocol.ConnectionID { return protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8} },
+					initialStream,
+					handshakeStream,
+					pnManager,
+					retransmissionQueue,
+					&net.UDPAddr{IP: net.IPv4(11, 12, 13, 14), Port: 1337},
+					8900,
+					sealingManager,
+					framer,
+					ackFramer,
+					protocol.PerspectiveClient,
+					version,
+				)
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).Times(2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42)).Times(2)
+				sealingManager.EXPECT().GetInitialSealer().Return(sealer, nil).Times(2)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial).Times(2)
+				initialStream.EXPECT().HasData().Return(true).AnyTimes()
+				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) *wire.CryptoFrame {
+					f := &wire.CryptoFrame{Offset: 0x1337}
+					f.Data = bytes.Repeat([]byte{'f'}, int(size-f.Length(packer.version)-1))
+					Expect(f.Length(packer.version)).To(Equal(size))
+					return f
+				}).Times(2)
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
+				Expect(p.raw).To(HaveLen(8900))
+				checkLength(p.raw)
+				// now the peer limits the packet size
+				packer.HandleTransportParameters(&handshake.TransportParameters{MaxPacketSize: 1452})
+				p, err = packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.raw).To(HaveLen(1452))
+				checkLength(p.raw)
+			})
+
 			It("adds retransmissions", func() {
 				f := &wire.CryptoFrame{Data: []byte("Initial")}
 				retransmissionQueue.AddInitial(f)
@@ -29,14 +29,21 @@ type packetUnpacker struct {
 	largestRcvdPacketNumber protocol.PacketNumber
 
 	version protocol.VersionNumber
+
+	// When set, header protection is not removed from received packets.
+	// This is only useful for debugging (together with a packer that doesn't apply header protection),
+	// and must never be used in production.
+	// It is set when building with the quic_noheaderprotection build tag.
+	disableHeaderProtection bool
 }
 
 var _ unpacker = &packetUnpacker{}
 
 func newPacketUnpacker(cs handshake.CryptoSetup, version protocol.VersionNumber) unpacker {
 	return &packetUnpacker{
-		cs:      cs,
-		version: version,
+		cs:                      cs,
+		version:                 version,
+		disableHeaderProtection: !headerProtectionEnabled,
 	}
 }
 
@@ -157,11 +164,13 @@ func (u *packetUnpacker) unpack(hd headerDecryptor, hdr *wire.Header, data []byt
 	origPNBytes := make([]byte, 4)
 	copy(origPNBytes, data[hdrLen:hdrLen+4])
 	// 2. decrypt the header, assuming a 4 byte packet number
-	hd.DecryptHeader(
-		data[hdrLen+4:hdrLen+4+16],
-		&data[0],
-		data[hdrLen:hdrLen+4],
-	)
+	if !u.disableHeaderProtection {
+		hd.DecryptHeader(
+			data[hdrLen+4:hdrLen+4+16],
+			&data[0],
+			data[hdrLen:hdrLen+4],
+		)
+	}
 	// 3. parse the header (and learn the actual length of the packet number)
 	extHdr, parseErr := hdr.ParseExtended(r, u.version)
 	if parseErr != nil && parseErr != wire.ErrInvalidReservedBits {
@@ -39,6 +39,8 @@ var _ = Describe("Packet Unpacker", func() {
 	BeforeEach(func() {
 		cs = mocks.NewMockCryptoSetup(mockCtrl)
 		unpacker = newPacketUnpacker(cs, version).(*packetUnpacker)
+		// the header protection is tested, even when building with the quic_noheaderprotection build tag
+		unpacker.disableHeaderProtection = false
 	})
 
 	It("errors when the packet is too small to obtain the header decryption sample", func() {
@@ -1,6 +1,7 @@
 package quictrace
 
 import (
+	"net"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -13,6 +14,70 @@ type Tracer interface {
 	GetAllTraces() map[string][]byte
 }
 
+// A MetricsTracer is a Tracer that is also notified about changes of the congestion controller.
+// The methods are called after every ACK frame received, and after packets were declared lost on a timeout.
+// Tracers that don't implement this interface are not notified.
+type MetricsTracer interface {
+	Tracer
+	UpdatedCongestionState(protocol.ConnectionID, CongestionState)
+	// UpdatedMetrics is called with the largest acknowledged packet number of the packet number space.
+	UpdatedMetrics(connID protocol.ConnectionID, largestAcked protocol.PacketNumber, congestionWindow, bytesInFlight protocol.ByteCount, packetsInFlight int)
+}
+
+// A PacketDropTracer is a Tracer that is also notified about packets that are dropped.
+// Tracers that don't implement this interface are not notified.
+// Warning: This API should not be considered stable and might change soon.
+type PacketDropTracer interface {
+	Tracer
+	DroppedPacket(remoteAddr net.Addr, packetType PacketType, size protocol.ByteCount, reason PacketDropReason)
+}
+
+// A KeyUpdateTracer is a Tracer that is also notified when the 1-RTT keys are updated.
+// Tracers that don't implement this interface are not notified.
+// Warning: This API should not be considered stable and might change soon.
+type KeyUpdateTracer interface {
+	Tracer
+	// UpdatedKey is called when the keys are updated to a new key phase.
+	// remote says if the key update was initiated by the peer.
+	UpdatedKey(connID protocol.ConnectionID, phase protocol.KeyPhase, remote bool)
+}
+
+// PacketType is the type of a dropped packet
+type PacketType uint8
+
+const (
+	// PacketTypeInitial is an Initial packet
+	PacketTypeInitial PacketType = 1 + iota
+	// PacketTypeHandshake is a Handshake packet
+	PacketTypeHandshake
+	// PacketTypeRetry is a Retry packet
+	PacketTypeRetry
+	// PacketType0RTT is a 0-RTT packet
+	PacketType0RTT
+	// PacketType1RTT is a 1-RTT packet (i.e. a packet with a short header)
+	PacketType1RTT
+	// PacketTypeNotDetermined is a packet whose type couldn't be determined, e.g. because it uses an unsupported version
+	PacketTypeNotDetermined
+)
+
+// PacketDropReason is the reason why a packet was dropped
+type PacketDropReason uint8
+
+const (
+	// PacketDropTooSmall means that the packet was too small, e.g. an Initial packet that wasn't padded to the minimum size
+	PacketDropTooSmall PacketDropReason = 1 + iota
+	// PacketDropUnknownConnectionID means that the packet couldn't be associated with a connection
+	PacketDropUnknownConnectionID
+	// PacketDropUnsupportedVersion means that the packet used a QUIC version we don't support
+	PacketDropUnsupportedVersion
+	// PacketDropDecryptionFailed means that the packet couldn't be decrypted
+	PacketDropDecryptionFailed
+	// PacketDropDuplicate means that the packet was received before
+	PacketDropDuplicate
+	// PacketDropUnexpectedPacketType means that a packet of this type was not expected
+	PacketDropUnexpectedPacketType
+)
+
 // EventType is the type of an event
 type EventType uint8
 
@@ -23,6 +88,22 @@ const (
 	PacketReceived
 	// PacketLost means that a packet was lost
 	PacketLost
+	// MetricsUpdated means that the congestion controller processed an ACK frame or a packet loss
+	MetricsUpdated
+	// CongestionStateUpdated means that the state of the congestion controller changed
+	CongestionStateUpdated
+)
+
+// CongestionState is the state of the congestion controller
+type CongestionState uint8
+
+const (
+	// CongestionStateSlowStart is the slow start phase
+	CongestionStateSlowStart CongestionState = iota
+	// CongestionStateCongestionAvoidance is the congestion avoidance phase
+	CongestionStateCongestionAvoidance
+	// CongestionStateRecovery is the recovery phase
+	CongestionStateRecovery
 )
 
 // Event is a quic-traceable event
@@ -44,7 +125,22 @@ type TransportState struct {
 	LatestRTT   time.Duration
 
 	BytesInFlight    protocol.ByteCount
+	PacketsInFlight  int
 	CongestionWindow protocol.ByteCount
 	InSlowStart      bool
 	InRecovery       bool
+
+	// BandwidthEstimate is the delivery rate in bits per second, estimated from the acknowledgements received.
+	BandwidthEstimate uint64
+}
+
+// CongestionState returns the state of the congestion controller
+func (s *TransportState) CongestionState() CongestionState {
+	if s.InRecovery {
+		return CongestionStateRecovery
+	}
+	if s.InSlowStart {
+		return CongestionStateSlowStart
+	}
+	return CongestionStateCongestionAvoidance
 }
@@ -0,0 +1,59 @@
+package quic
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// setReceiveBuffer tries to increase the receive buffer of the socket to protocol.DesiredReceiveBufferSize.
+// It returns an error if the receive buffer is smaller than that afterwards.
+// If increase is false, the receive buffer is only checked, e.g. because the application configured it.
+// Conns that don't expose the underlying socket are not modified.
+func setReceiveBuffer(conn net.PacketConn, increase bool) error {
+	c, ok := conn.(interface {
+		syscall.Conn
+		SetReadBuffer(int) error
+	})
+	if !ok {
+		return nil
+	}
+	size, err := inspectReadBuffer(c)
+	if err != nil {
+		return fmt.Errorf("failed to determine receive buffer size: %w", err)
+	}
+	if size >= protocol.DesiredReceiveBufferSize {
+		return nil
+	}
+	if !increase {
+		return fmt.Errorf("receive buffer size too small (got: %d kiB, wanted: %d kiB)", size/1024, protocol.DesiredReceiveBufferSize/1024)
+	}
+	if err := c.SetReadBuffer(protocol.DesiredReceiveBufferSize); err != nil {
+		return fmt.Errorf("failed to increase receive buffer size: %w", err)
+	}
+	newSize, err := inspectReadBuffer(c)
+	if err != nil {
+		return fmt.Errorf("failed to determine receive buffer size: %w", err)
+	}
+	if newSize < protocol.DesiredReceiveBufferSize {
+		return fmt.Errorf("failed to sufficiently increase receive buffer size (was: %d kiB, wanted: %d kiB, got: %d kiB)", size/1024, protocol.DesiredReceiveBufferSize/1024, newSize/1024)
+	}
+	return nil
+}
+
+func inspectReadBuffer(c syscall.Conn) (int, error) {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var size int
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		size, serr = getReceiveBufferSize(fd)
+	}); err != nil {
+		return 0, err
+	}
+	return size, serr
+}
@@ -0,0 +1,9 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package quic
+
+import "errors"
+
+func getReceiveBufferSize(uintptr) (int, error) {
+	return 0, errors.New("reading SO_RCVBUF not supported on this platform")
+}
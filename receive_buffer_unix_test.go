@@ -0,0 +1,105 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package quic
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"os"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// A fixedReceiveBufferConn ignores attempts to change the receive buffer size.
+type fixedReceiveBufferConn struct{ *net.UDPConn }
+
+func (fixedReceiveBufferConn) SetReadBuffer(int) error { return nil }
+
+var _ = Describe("Receive Buffer", func() {
+	var udpConn *net.UDPConn
+
+	BeforeEach(func() {
+		var err error
+		udpConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(udpConn.SetReadBuffer(1 << 14)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		udpConn.Close()
+	})
+
+	It("increases the receive buffer", func() {
+		err := setReceiveBuffer(udpConn, true)
+		if err != nil {
+			Skip("can't increase the receive buffer: " + err.Error())
+		}
+		size, err := inspectReadBuffer(udpConn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(size).To(BeNumerically(">=", protocol.DesiredReceiveBufferSize))
+	})
+
+	It("errors when the receive buffer can't be increased", func() {
+		Expect(setReceiveBuffer(fixedReceiveBufferConn{udpConn}, true)).To(MatchError(ContainSubstring("failed to sufficiently increase receive buffer size")))
+	})
+
+	It("doesn't error for conns that don't expose the socket", func() {
+		Expect(setReceiveBuffer(newMockPacketConn(), true)).To(Succeed())
+	})
+
+	It("only checks the receive buffer if it shouldn't be increased", func() {
+		Expect(setReceiveBuffer(udpConn, false)).To(MatchError(ContainSubstring("receive buffer size too small")))
+		size, err := inspectReadBuffer(udpConn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(size).To(BeNumerically("<", protocol.DesiredReceiveBufferSize))
+	})
+
+	Context("warnings", func() {
+		var buf *bytes.Buffer
+
+		BeforeEach(func() {
+			buf = &bytes.Buffer{}
+			log.SetOutput(buf)
+			utils.DefaultLogger.SetLogLevel(utils.LogLevelError)
+		})
+
+		AfterEach(func() {
+			log.SetOutput(os.Stdout)
+			utils.DefaultLogger.SetLogLevel(utils.LogLevelNothing)
+		})
+
+		It("warns when the receive buffer of a conn passed to Listen is too small, without increasing it", func() {
+			ln, err := Listen(udpConn, testdata.GetTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			Expect(ln.ReceiveBufferWarning()).To(MatchError(ContainSubstring("receive buffer size too small")))
+			Expect(buf.String()).To(ContainSubstring("receive buffer size too small"))
+			size, err := inspectReadBuffer(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(BeNumerically("<", protocol.DesiredReceiveBufferSize))
+		})
+
+		It("warns when the receive buffer of a conn passed to Dial is too small, without increasing it", func() {
+			_, err := newClient(udpConn, udpConn.LocalAddr(), populateClientConfig(nil, false), nil, "localhost", false, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buf.String()).To(ContainSubstring("receive buffer size too small"))
+			size, err := inspectReadBuffer(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(BeNumerically("<", protocol.DesiredReceiveBufferSize))
+		})
+
+		It("doesn't log the warning if disabled", func() {
+			ln, err := Listen(udpConn, testdata.GetTLSConfig(), &Config{DisableReceiveBufferWarning: true})
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			Expect(ln.ReceiveBufferWarning()).To(HaveOccurred())
+			Expect(buf.String()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package quic
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func getReceiveBufferSize(fd uintptr) (int, error) {
+	var v int32
+	l := int32(unsafe.Sizeof(v))
+	err := syscall.Getsockopt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, (*byte)(unsafe.Pointer(&v)), &l)
+	return int(v), err
+}
@@ -42,6 +42,7 @@ type receiveStream struct {
 	resetRemotelyErr    StreamError
 
 	closedForShutdown bool // set when CloseForShutdown() is called
+	finRcvd           bool // set when a STREAM frame with a FinBit is received
 	finRead           bool // set once we read a frame with a FinBit
 	canceledRead      bool // set when CancelRead() is called
 	resetRemotely     bool // set when HandleResetStreamFrame() is called
@@ -112,48 +113,8 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			return false, bytesRead, s.closeForShutdownErr
 		}
 
-		var deadlineTimer *utils.Timer
-		for {
-			// Stop waiting on errors
-			if s.closedForShutdown {
-				return false, bytesRead, s.closeForShutdownErr
-			}
-			if s.canceledRead {
-				return false, bytesRead, s.cancelReadErr
-			}
-			if s.resetRemotely {
-				return false, bytesRead, s.resetRemotelyErr
-			}
-
-			deadline := s.deadline
-			if !deadline.IsZero() {
-				if !time.Now().Before(deadline) {
-					return false, bytesRead, errDeadline
-				}
-				if deadlineTimer == nil {
-					deadlineTimer = utils.NewTimer()
-				}
-				deadlineTimer.Reset(deadline)
-			}
-
-			if s.currentFrame != nil || s.currentFrameIsLast {
-				break
-			}
-
-			s.mutex.Unlock()
-			if deadline.IsZero() {
-				<-s.readChan
-			} else {
-				select {
-				case <-s.readChan:
-				case <-deadlineTimer.Chan():
-					deadlineTimer.SetRead()
-				}
-			}
-			s.mutex.Lock()
-			if s.currentFrame == nil {
-				s.dequeueNextFrame()
-			}
+		if err := s.waitForFrame(); err != nil {
+			return false, bytesRead, err
 		}
 
 		if bytesRead > len(p) {
@@ -171,19 +132,144 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 		s.readOffset += protocol.ByteCount(m)
 
 		s.mutex.Lock()
-		// when a RESET_STREAM was received, the was already informed about the final byteOffset for this stream
-		if !s.resetRemotely {
-			s.flowController.AddBytesRead(protocol.ByteCount(m))
-		}
-
-		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
-			s.finRead = true
+		if s.frameConsumed(m) {
 			return true, bytesRead, io.EOF
 		}
 	}
 	return false, bytesRead, nil
 }
 
+// WriteTo implements io.WriterTo.
+// It writes the data directly from the received STREAM frames to w,
+// until the peer closes the stream or an error occurs.
+// Like io.Copy, it returns a nil error when the end of the stream is reached.
+// It must not be called concurrently with Read.
+func (s *receiveStream) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for {
+		s.mutex.Lock()
+		completed, n, err := s.writeToImpl(w)
+		s.mutex.Unlock()
+
+		written += int64(n)
+		if completed {
+			s.sender.onStreamCompleted(s.streamID)
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// writeToImpl writes the data of (the rest of) a single frame to w.
+func (s *receiveStream) writeToImpl(w io.Writer) (bool /* stream completed */, int, error) {
+	if s.finRead {
+		return false, 0, io.EOF
+	}
+	if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
+		s.dequeueNextFrame()
+	}
+	if err := s.waitForFrame(); err != nil {
+		return false, 0, err
+	}
+
+	data := s.currentFrame[s.readPosInFrame:]
+	var n int
+	var err error
+	if len(data) > 0 {
+		s.mutex.Unlock()
+		n, err = w.Write(data)
+		if err == nil && n < len(data) {
+			err = io.ErrShortWrite
+		}
+		s.readPosInFrame += n
+		s.readOffset += protocol.ByteCount(n)
+		s.mutex.Lock()
+	}
+	completed := s.frameConsumed(n)
+	if err != nil {
+		return completed, n, err
+	}
+	if completed {
+		return true, n, io.EOF
+	}
+	return false, n, nil
+}
+
+// waitForFrame blocks until a frame is available for reading,
+// or until the stream is closed, canceled or reset, or the read deadline expires.
+// It must be called with the mutex held.
+func (s *receiveStream) waitForFrame() error {
+	var deadlineTimer *utils.Timer
+	for {
+		// Stop waiting on errors
+		if s.closedForShutdown {
+			return s.closeForShutdownErr
+		}
+		if s.canceledRead {
+			return s.cancelReadErr
+		}
+		if s.resetRemotely {
+			return s.resetRemotelyErr
+		}
+
+		deadline := s.deadline
+		if !deadline.IsZero() {
+			if !time.Now().Before(deadline) {
+				return errDeadline
+			}
+			if deadlineTimer == nil {
+				deadlineTimer = utils.NewTimer()
+			}
+			deadlineTimer.Reset(deadline)
+		}
+
+		if s.currentFrame != nil || s.currentFrameIsLast {
+			return nil
+		}
+
+		s.mutex.Unlock()
+		if deadline.IsZero() {
+			<-s.readChan
+		} else {
+			select {
+			case <-s.readChan:
+			case <-deadlineTimer.Chan():
+				deadlineTimer.SetRead()
+			}
+		}
+		s.mutex.Lock()
+		if s.currentFrame == nil {
+			s.dequeueNextFrame()
+		}
+	}
+}
+
+// frameConsumed is called after n bytes of the current frame were passed to the application.
+// It returns true if the FIN was read.
+// It must be called with the mutex held.
+func (s *receiveStream) frameConsumed(n int) bool /* fin read */ {
+	// when a RESET_STREAM was received, the was already informed about the final byteOffset for this stream
+	if !s.resetRemotely {
+		s.flowController.AddBytesRead(protocol.ByteCount(n))
+	}
+	// Release the buffer as soon as all data has been read,
+	// so that we don't hold on to it until the next call to Read.
+	if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameDone != nil {
+		s.currentFrameDone()
+		s.currentFrameDone = nil
+	}
+
+	if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
+		s.finRead = true
+		return true
+	}
+	return false
+}
+
 func (s *receiveStream) dequeueNextFrame() {
 	var offset protocol.ByteCount
 	// We're done with the last frame. Release the buffer.
@@ -195,6 +281,17 @@ func (s *receiveStream) dequeueNextFrame() {
 	s.readPosInFrame = 0
 }
 
+// setReceiveWindowSize sets the size of the receive window.
+// If this increases the window, the new window is sent to the peer right away.
+func (s *receiveStream) setReceiveWindowSize(size protocol.ByteCount) {
+	if offset := s.flowController.SetReceiveWindowSize(size); offset > 0 {
+		s.sender.queueControlFrame(&wire.MaxStreamDataFrame{
+			StreamID:   s.streamID,
+			ByteOffset: offset,
+		})
+	}
+}
+
 func (s *receiveStream) CancelRead(errorCode protocol.ApplicationErrorCode) {
 	s.mutex.Lock()
 	completed := s.cancelReadImpl(errorCode)
@@ -242,6 +339,7 @@ func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /*
 	if frame.FinBit {
 		newlyRcvdFinalOffset = s.finalOffset == protocol.MaxByteCount
 		s.finalOffset = maxOffset
+		s.finRcvd = true
 	}
 	if s.canceledRead {
 		return newlyRcvdFinalOffset, nil
@@ -279,6 +377,10 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 	if s.resetRemotely {
 		return false, nil
 	}
+	// If all data was already received, the application can read it up to the end of the stream.
+	if s.allDataReceived() {
+		return false, nil
+	}
 	s.resetRemotely = true
 	s.resetRemotelyErr = streamCanceledError{
 		errorCode: frame.ErrorCode,
@@ -305,12 +407,20 @@ func (s *receiveStream) SetReadDeadline(t time.Time) error {
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RESET.
 func (s *receiveStream) closeForShutdown(err error) {
 	s.mutex.Lock()
-	s.closedForShutdown = true
-	s.closeForShutdownErr = err
+	// If all data was already received, the application can read it up to the end of the stream.
+	if !s.allDataReceived() || s.canceledRead || s.resetRemotely {
+		s.closedForShutdown = true
+		s.closeForShutdownErr = err
+	}
 	s.mutex.Unlock()
 	s.signalRead()
 }
 
+// allDataReceived says if all data up to the FIN was received.
+func (s *receiveStream) allDataReceived() bool {
+	return s.finRcvd && s.frameQueue.ContiguousEnd() >= s.finalOffset
+}
+
 func (s *receiveStream) getWindowUpdate() protocol.ByteCount {
 	return s.flowController.GetWindowUpdate()
 }
@@ -1,14 +1,21 @@
 package quic
 
 import (
+	"bytes"
+	"crypto/rand"
 	"errors"
 	"io"
+	"io/ioutil"
 	"runtime"
+	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -39,6 +46,22 @@ var _ = Describe("Receive Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	Context("setting the receive window size", func() {
+		It("sends a MAX_STREAM_DATA frame when the window is increased", func() {
+			mockFC.EXPECT().SetReceiveWindowSize(protocol.ByteCount(1000)).Return(protocol.ByteCount(1000))
+			mockSender.EXPECT().queueControlFrame(&wire.MaxStreamDataFrame{
+				StreamID:   streamID,
+				ByteOffset: 1000,
+			})
+			str.setReceiveWindowSize(1000)
+		})
+
+		It("doesn't send a MAX_STREAM_DATA frame when the window is not increased", func() {
+			mockFC.EXPECT().SetReceiveWindowSize(protocol.ByteCount(10))
+			str.setReceiveWindowSize(10)
+		})
+	})
+
 	Context("reading", func() {
 		It("reads a single STREAM frame", func() {
 			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
@@ -77,6 +100,20 @@ var _ = Describe("Receive Stream", func() {
 			Expect(b).To(Equal([]byte{0xBE, 0xEF}))
 		})
 
+		It("releases the buffer as soon as all data of a frame has been read", func() {
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+			var released bool
+			Expect(str.frameQueue.Push([]byte{0xDE, 0xAD, 0xBE, 0xEF}, 0, func() { released = true })).To(Succeed())
+			b := make([]byte, 2)
+			_, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(released).To(BeFalse())
+			_, err = strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte{0xBE, 0xEF}))
+			Expect(released).To(BeTrue())
+		})
+
 		It("reads all data available", func() {
 			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
 			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
@@ -443,6 +480,144 @@ var _ = Describe("Receive Stream", func() {
 				Expect(n).To(BeZero())
 				Expect(err).To(MatchError(testErr))
 			})
+
+			It("allows reading all data, if it was received up to the FIN", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					Data:   []byte("foobar"),
+					FinBit: true,
+				})).To(Succeed())
+				str.closeForShutdown(testErr)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := make([]byte, 10)
+				n, err := strWithTimeout.Read(b)
+				Expect(n).To(Equal(6))
+				Expect(err).To(MatchError(io.EOF))
+				Expect(b[:n]).To(Equal([]byte("foobar")))
+			})
+
+			It("errors if data before the FIN is still missing", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					Offset: 3,
+					Data:   []byte("bar"),
+					FinBit: true,
+				})).To(Succeed())
+				str.closeForShutdown(testErr)
+				n, err := strWithTimeout.Read(make([]byte, 10))
+				Expect(n).To(BeZero())
+				Expect(err).To(MatchError(testErr))
+			})
+		})
+	})
+
+	Context("writing to an io.Writer", func() {
+		It("writes all data until the FIN", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(3), false)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3)).Times(2)
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo")})).To(Succeed())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar"), FinBit: true})).To(Succeed())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			buf := &bytes.Buffer{}
+			n, err := io.Copy(buf, str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(6))
+			Expect(buf.String()).To(Equal("foobar"))
+			// further calls return immediately
+			n, err = str.WriteTo(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeZero())
+		})
+
+		It("releases the frames once they were written", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(3), true)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+			var released bool
+			Expect(str.frameQueue.Push([]byte("foo"), 0, func() { released = true })).To(Succeed())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, FinBit: true})).To(Succeed())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			_, err := str.WriteTo(&bytes.Buffer{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(released).To(BeTrue())
+		})
+
+		It("waits for data", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(3), true)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			buf := gbytes.NewBuffer()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				n, err := str.WriteTo(buf)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(3))
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo"), FinBit: true})).To(Succeed())
+			Eventually(done).Should(BeClosed())
+			Expect(buf.Contents()).To(Equal([]byte("foo")))
+		})
+
+		It("returns errors from the io.Writer", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+			testErr := errors.New("test error")
+			n, err := str.WriteTo(&errorWriter{maxLen: 2, err: testErr})
+			Expect(err).To(MatchError(testErr))
+			Expect(n).To(BeEquivalentTo(2))
+			// the rest of the data can still be read
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+			b := make([]byte, 4)
+			_, err = strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("obar")))
+		})
+
+		It("returns io.ErrShortWrite on short writes", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+			n, err := str.WriteTo(&errorWriter{maxLen: 4})
+			Expect(err).To(MatchError(io.ErrShortWrite))
+			Expect(n).To(BeEquivalentTo(4))
+		})
+
+		It("returns the error when the stream is reset", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(3), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo")})).To(Succeed())
+			buf := gbytes.NewBuffer()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				n, err := str.WriteTo(buf)
+				Expect(err).To(MatchError("stream 1337 was reset with error code 1234"))
+				Expect(n).To(BeEquivalentTo(3))
+				close(done)
+			}()
+			Eventually(buf).Should(gbytes.Say("foo"))
+			Consistently(done).ShouldNot(BeClosed())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+			mockFC.EXPECT().Abandon()
+			Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+				StreamID:   streamID,
+				ByteOffset: 42,
+				ErrorCode:  1234,
+			})).To(Succeed())
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("respects the read deadline", func() {
+			str.SetReadDeadline(time.Now().Add(scaleDuration(20 * time.Millisecond)))
+			n, err := str.WriteTo(&bytes.Buffer{})
+			Expect(err).To(MatchError(errDeadline))
+			Expect(n).To(BeZero())
 		})
 	})
 
@@ -630,6 +805,25 @@ var _ = Describe("Receive Stream", func() {
 				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
 			})
 
+			It("allows reading all data, if it was received up to the FIN before the RESET_STREAM", func() {
+				data := make([]byte, int(rst.ByteOffset))
+				rand.Read(data)
+				mockFC.EXPECT().UpdateHighestReceived(rst.ByteOffset, true).Times(2)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					StreamID: streamID,
+					Data:     data,
+					FinBit:   true,
+				})).To(Succeed())
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(rst.ByteOffset)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := make([]byte, 100)
+				n, err := strWithTimeout.Read(b)
+				Expect(n).To(Equal(len(data)))
+				Expect(err).To(MatchError(io.EOF))
+				Expect(b[:n]).To(Equal(data))
+			})
+
 			It("doesn't do anyting when it was closed for shutdown", func() {
 				str.closeForShutdown(nil)
 				err := str.handleResetStreamFrame(rst)
@@ -650,9 +844,117 @@ var _ = Describe("Receive Stream", func() {
 			Expect(err).To(MatchError(testErr))
 		})
 
+		It("surfaces flow control violations as an Error, after the session was closed", func() {
+			rttStats := &congestion.RTTStats{}
+			cfc := flowcontrol.NewConnectionFlowController(protocol.MaxByteCount, protocol.MaxByteCount, func() {}, rttStats, utils.DefaultLogger)
+			fc := flowcontrol.NewStreamFlowController(streamID, cfc, 10, 10, protocol.MaxByteCount, func(protocol.StreamID) {}, rttStats, utils.DefaultLogger)
+			str = newReceiveStream(streamID, mockSender, fc, protocol.VersionWhatever)
+			err := str.handleStreamFrame(&wire.StreamFrame{
+				StreamID: streamID,
+				Data:     make([]byte, 11),
+			})
+			Expect(err).To(HaveOccurred())
+			// the session is closed with the flow control error
+			str.closeForShutdown(err)
+			_, err = str.Read(make([]byte, 10))
+			var qErr *Error
+			Expect(errors.As(err, &qErr)).To(BeTrue())
+			Expect(qErr.ErrorCode).To(Equal(FlowControlError))
+			Expect(qErr.IsApplicationError()).To(BeFalse())
+			Expect(errors.Is(err, FlowControlError)).To(BeTrue())
+			Expect(errors.Is(err, ProtocolViolation)).To(BeFalse())
+		})
+
+		It("bounds the out-of-order data by the flow control window", func() {
+			rttStats := &congestion.RTTStats{}
+			cfc := flowcontrol.NewConnectionFlowController(protocol.MaxByteCount, protocol.MaxByteCount, func() {}, rttStats, utils.DefaultLogger)
+			fc := flowcontrol.NewStreamFlowController(streamID, cfc, 1000, 1000, protocol.MaxByteCount, func(protocol.StreamID) {}, rttStats, utils.DefaultLogger)
+			str = newReceiveStream(streamID, mockSender, fc, protocol.VersionWhatever)
+			for offset := protocol.ByteCount(1); offset < 1000; offset += 10 {
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					StreamID: streamID,
+					Offset:   offset,
+					Data:     []byte("f"),
+				})).To(Succeed())
+			}
+			err := str.handleStreamFrame(&wire.StreamFrame{
+				StreamID: streamID,
+				Offset:   1000,
+				Data:     []byte("f"),
+			})
+			Expect(errors.Is(err, FlowControlError)).To(BeTrue())
+		})
+
 		It("gets a window update", func() {
 			mockFC.EXPECT().GetWindowUpdate().Return(protocol.ByteCount(0x100))
 			Expect(str.getWindowUpdate()).To(Equal(protocol.ByteCount(0x100)))
 		})
 	})
 })
+
+// errorWriter accepts at most maxLen bytes per call to Write, and then returns err.
+type errorWriter struct {
+	maxLen int
+	err    error
+}
+
+func (w *errorWriter) Write(p []byte) (int, error) {
+	if len(p) > w.maxLen {
+		return w.maxLen, w.err
+	}
+	return len(p), nil
+}
+
+type nopStreamSender struct{}
+
+func (nopStreamSender) queueControlFrame(wire.Frame)        {}
+func (nopStreamSender) onHasStreamData(protocol.StreamID)   {}
+func (nopStreamSender) onStreamCompleted(protocol.StreamID) {}
+
+func newBenchmarkStreamFlowController() flowcontrol.StreamFlowController {
+	rttStats := &congestion.RTTStats{}
+	cfc := flowcontrol.NewConnectionFlowController(protocol.MaxByteCount, protocol.MaxByteCount, func() {}, rttStats, utils.DefaultLogger)
+	cfc.UpdateSendWindow(protocol.MaxByteCount)
+	return flowcontrol.NewStreamFlowController(1, cfc, protocol.MaxByteCount, protocol.MaxByteCount, protocol.MaxByteCount, func(protocol.StreamID) {}, rttStats, utils.DefaultLogger)
+}
+
+func benchmarkReceiveStreamCopy(b *testing.B, copyFunc func(io.Writer, *receiveStream) (int64, error)) {
+	const frameSize = 1200
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		str := newReceiveStream(1, nopStreamSender{}, newBenchmarkStreamFlowController(), protocol.VersionWhatever)
+		for offset := 0; offset < len(data); offset += frameSize {
+			end := utils.Min(offset+frameSize, len(data))
+			if err := str.handleStreamFrame(&wire.StreamFrame{
+				Offset: protocol.ByteCount(offset),
+				Data:   data[offset:end],
+				FinBit: end == len(data),
+			}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		n, err := copyFunc(ioutil.Discard, str)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if n != int64(len(data)) {
+			b.Fatalf("copied %d bytes, expected %d", n, len(data))
+		}
+	}
+}
+
+func BenchmarkReceiveStreamWriteTo(b *testing.B) {
+	benchmarkReceiveStreamCopy(b, func(w io.Writer, str *receiveStream) (int64, error) {
+		return io.Copy(w, str)
+	})
+}
+
+func BenchmarkReceiveStreamGenericCopy(b *testing.B) {
+	benchmarkReceiveStreamCopy(b, func(w io.Writer, str *receiveStream) (int64, error) {
+		// hide the io.WriterTo and io.ReaderFrom implementations from io.Copy
+		return io.Copy(struct{ io.Writer }{w}, struct{ io.Reader }{str})
+	})
+}
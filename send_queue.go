@@ -1,25 +1,59 @@
 package quic
 
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
 type sendQueue struct {
-	queue     chan *packedPacket
-	closeChan chan struct{}
-	conn      connection
+	queue       chan *packedPacket
+	closeChan   chan struct{}
+	runStopped  chan struct{}
+	conn        connection
+	retryWrites bool
 }
 
-func newSendQueue(conn connection) *sendQueue {
+func newSendQueue(conn connection, retryWrites bool) *sendQueue {
 	s := &sendQueue{
-		conn:      conn,
-		closeChan: make(chan struct{}),
-		queue:     make(chan *packedPacket, 1),
+		conn:        conn,
+		retryWrites: retryWrites,
+		runStopped:  make(chan struct{}),
+		closeChan:   make(chan struct{}),
+		queue:       make(chan *packedPacket, 1),
 	}
 	return s
 }
 
+// Send sends out a packet.
+// It doesn't block once Run has returned, the packet is dropped in that case.
 func (h *sendQueue) Send(p *packedPacket) {
-	h.queue <- p
+	// If Run already returned, select might still pick the queue if there's space in it.
+	select {
+	case <-h.runStopped:
+		p.buffer.Release()
+		return
+	default:
+	}
+	select {
+	case h.queue <- p:
+	case <-h.runStopped:
+		p.buffer.Release()
+		return
+	}
+	// Run might have returned (and released the queued packets) while we were queueing the packet.
+	select {
+	case <-h.runStopped:
+		h.releaseQueued()
+	default:
+	}
 }
 
 func (h *sendQueue) Run() error {
+	defer func() {
+		close(h.runStopped)
+		h.releaseQueued()
+	}()
 	var p *packedPacket
 	for {
 		select {
@@ -27,10 +61,43 @@ func (h *sendQueue) Run() error {
 			return nil
 		case p = <-h.queue:
 		}
-		if err := h.conn.Write(p.raw); err != nil {
+		err := h.write(p.raw)
+		p.buffer.Release()
+		if err != nil {
 			return err
 		}
-		p.buffer.Release()
+	}
+}
+
+// releaseQueued releases the packets that are still queued.
+func (h *sendQueue) releaseQueued() {
+	for {
+		select {
+		case p := <-h.queue:
+			p.buffer.Release()
+		default:
+			return
+		}
+	}
+}
+
+// write writes a packet to the connection.
+// Transient errors are retried with an exponential backoff, up to protocol.MaxWriteRetries times.
+func (h *sendQueue) write(b []byte) error {
+	backoff := protocol.WriteRetryInitialBackoff
+	for i := 0; ; i++ {
+		err := h.conn.Write(b)
+		if err == nil || !h.retryWrites || i >= protocol.MaxWriteRetries || !isTransientWriteError(err) {
+			return err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-h.closeChan:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		backoff *= 2
 	}
 }
 
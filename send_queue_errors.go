@@ -0,0 +1,14 @@
+// +build !plan9
+
+package quic
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isTransientWriteError says if writing to the socket might succeed when retried,
+// e.g. once the socket's send buffer has drained.
+func isTransientWriteError(err error) bool {
+	return errors.Is(err, syscall.ENOBUFS) || errors.Is(err, syscall.EAGAIN)
+}
@@ -0,0 +1,5 @@
+package quic
+
+func isTransientWriteError(error) bool {
+	return false
+}
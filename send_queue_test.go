@@ -1,7 +1,15 @@
 package quic
 
 import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -12,7 +20,7 @@ var _ = Describe("Send Queue", func() {
 
 	BeforeEach(func() {
 		c = NewMockConnection(mockCtrl)
-		q = newSendQueue(c)
+		q = newSendQueue(c, true)
 	})
 
 	getPacket := func(b []byte) *packedPacket {
@@ -47,7 +55,7 @@ var _ = Describe("Send Queue", func() {
 		q.Send(getPacket([]byte("foobar")))
 
 		written := make(chan []byte, 2)
-		c.EXPECT().Write(gomock.Any()).Do(func(p []byte) { written <- p }).Times(2)
+		c.EXPECT().Write(gomock.Any()).Do(func(p []byte) { written <- append([]byte{}, p...) }).Times(2)
 
 		sent := make(chan struct{})
 		go func() {
@@ -70,4 +78,105 @@ var _ = Describe("Send Queue", func() {
 		q.Close()
 		Eventually(done).Should(BeClosed())
 	})
+
+	It("doesn't block sending after Run returned due to a write error", func() {
+		testErr := errors.New("write error")
+		c.EXPECT().Write(gomock.Any()).Return(testErr)
+		q.Send(getPacket([]byte("foobar")))
+		errChan := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			errChan <- q.Run()
+		}()
+		Eventually(errChan).Should(Receive(MatchError(testErr)))
+
+		sent := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Send(getPacket([]byte("raboof")))
+			q.Send(getPacket([]byte("lorem")))
+			close(sent)
+		}()
+		Eventually(sent).Should(BeClosed())
+	})
+
+	It("releases the packets that are queued when Run returns", func() {
+		p := getPacket([]byte("foobar"))
+		c.EXPECT().Write(p.raw).MaxTimes(1)
+		q.Send(p)
+		q.Close()
+		Expect(q.Run()).To(Succeed())
+		Expect(p.buffer.refCount).To(BeZero())
+		Expect(q.queue).To(BeEmpty())
+	})
+
+	It("releases packets sent after Run returned", func() {
+		testErr := errors.New("write error")
+		p1 := getPacket([]byte("foobar"))
+		c.EXPECT().Write(p1.raw).Return(testErr)
+		q.Send(p1)
+		Expect(q.Run()).To(MatchError(testErr))
+		Expect(p1.buffer.refCount).To(BeZero())
+		p2 := getPacket([]byte("raboof"))
+		q.Send(p2)
+		Expect(p2.buffer.refCount).To(BeZero())
+		Expect(q.queue).To(BeEmpty())
+	})
+
+	Context("retrying writes", func() {
+		enobufs := &net.OpError{Op: "write", Net: "udp", Err: os.NewSyscallError("sendto", syscall.ENOBUFS)}
+
+		run := func() <-chan error {
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- q.Run()
+			}()
+			return errChan
+		}
+
+		It("retries when the write fails with a transient error", func() {
+			p := getPacket([]byte("foobar"))
+			written := make(chan struct{})
+			gomock.InOrder(
+				c.EXPECT().Write(p.raw).Return(enobufs).Times(3),
+				c.EXPECT().Write(p.raw).Do(func([]byte) { close(written) }),
+			)
+			q.Send(p)
+			errChan := run()
+			Eventually(written).Should(BeClosed())
+			Consistently(errChan).ShouldNot(Receive())
+			q.Close()
+			Eventually(errChan).Should(Receive(BeNil()))
+		})
+
+		It("returns the error when the retries are exhausted", func() {
+			c.EXPECT().Write(gomock.Any()).Return(enobufs).Times(protocol.MaxWriteRetries + 1)
+			q.Send(getPacket([]byte("foobar")))
+			Eventually(run()).Should(Receive(MatchError(enobufs)))
+		})
+
+		It("doesn't retry fatal errors", func() {
+			ebadf := &net.OpError{Op: "write", Net: "udp", Err: os.NewSyscallError("sendto", syscall.EBADF)}
+			c.EXPECT().Write(gomock.Any()).Return(ebadf)
+			q.Send(getPacket([]byte("foobar")))
+			Eventually(run()).Should(Receive(MatchError(ebadf)))
+		})
+
+		It("doesn't retry if retries are disabled", func() {
+			q = newSendQueue(c, false)
+			c.EXPECT().Write(gomock.Any()).Return(enobufs)
+			q.Send(getPacket([]byte("foobar")))
+			Eventually(run()).Should(Receive(MatchError(enobufs)))
+		})
+
+		It("stops retrying when closed", func() {
+			c.EXPECT().Write(gomock.Any()).Return(enobufs).MinTimes(1)
+			q.Send(getPacket([]byte("foobar")))
+			errChan := run()
+			time.Sleep(2 * protocol.WriteRetryInitialBackoff)
+			q.Close()
+			Eventually(errChan).Should(Receive(BeNil()))
+		})
+	})
 })
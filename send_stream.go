@@ -3,6 +3,8 @@ package quic
 import (
 	"context"
 	"fmt"
+	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,8 +21,11 @@ type sendStreamI interface {
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	hasData() bool
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
+	writeAndClose([]byte) error
 	closeForShutdown(error)
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
+	handle0RTTRejection()
+	flushed() <-chan struct{}
 }
 
 type sendStream struct {
@@ -36,6 +41,12 @@ type sendStream struct {
 	sender   streamSender
 
 	writeOffset protocol.ByteCount
+	// ackedOffset is the offset up to which all data was acknowledged.
+	// ackedRanges are the acknowledged ranges beyond the ackedOffset, sorted by offset.
+	ackedOffset protocol.ByteCount
+	ackedRanges []utils.ByteInterval
+	// bytesRetransmitted is the number of bytes of STREAM frames that were retransmitted
+	bytesRetransmitted protocol.ByteCount
 
 	cancelWriteErr      error
 	closeForShutdownErr error
@@ -45,12 +56,16 @@ type sendStream struct {
 	canceledWrite     bool // set when CancelWrite() is called, or a STOP_SENDING frame is received
 	finSent           bool // set when a STREAM_FRAME with FIN bit has been sent
 	completed         bool // set when this stream has been reported to the streamSender as completed
+	nonIdempotent     bool // set when the stream was opened with StreamOptions.NonIdempotent
 
 	dataForWriting []byte
 
 	writeChan chan struct{}
 	deadline  time.Time
 
+	completedChan chan struct{} // closed when all data (including the FIN) was acknowledged, or the stream is canceled or closed for shutdown
+	flushedChan   chan struct{} // created by flushed(), closed once all data written so far was acknowledged
+
 	flowController flowcontrol.StreamFlowController
 
 	version protocol.VersionNumber
@@ -70,6 +85,7 @@ func newSendStream(
 		sender:         sender,
 		flowController: flowController,
 		writeChan:      make(chan struct{}, 1),
+		completedChan:  make(chan struct{}),
 		version:        version,
 	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
@@ -149,6 +165,90 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	return bytesWritten, nil
 }
 
+// writeAndClose queues p for sending and closes the stream.
+// In contrast to Write, it doesn't wait until the data was sent.
+func (s *sendStream) writeAndClose(p []byte) error {
+	s.mutex.Lock()
+	if s.finishedWriting {
+		s.mutex.Unlock()
+		return fmt.Errorf("write on closed stream %d", s.streamID)
+	}
+	if s.canceledWrite {
+		s.mutex.Unlock()
+		return s.cancelWriteErr
+	}
+	if s.closeForShutdownErr != nil {
+		s.mutex.Unlock()
+		return s.closeForShutdownErr
+	}
+	if len(p) > 0 {
+		s.dataForWriting = make([]byte, len(p))
+		copy(s.dataForWriting, p)
+	}
+	s.ctxCancel()
+	s.finishedWriting = true
+	s.mutex.Unlock()
+
+	s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	return nil
+}
+
+// readFromBufferSize is the size of the buffers used by ReadFrom.
+// Every call to Write might produce an additional (small) STREAM frame,
+// so it's more efficient to read in chunks that span many packets.
+// This is the buffer size that io.Copy uses.
+const readFromBufferSize = 32 * 1024
+
+var readFromBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, readFromBufferSize)
+		return &b
+	},
+}
+
+// ReadFrom implements io.ReaderFrom.
+// It reads data from r into a pooled buffer, and writes it to the stream,
+// until r returns io.EOF or an error occurs.
+// Like io.Copy, it returns a nil error when r returns io.EOF.
+// The stream is not closed.
+// It must not be called concurrently with Write.
+func (s *sendStream) ReadFrom(r io.Reader) (int64, error) {
+	bufPtr := readFromBufferPool.Get().(*[]byte)
+	defer readFromBufferPool.Put(bufPtr)
+	buf := *bufPtr
+
+	var written int64
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			m, err := s.Write(buf[:n])
+			written += int64(m)
+			if err != nil {
+				return written, err
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// BufferedAmount returns the number of bytes that were written, but not sent yet.
+// This includes data that was lost and is waiting to be retransmitted.
+func (s *sendStream) BufferedAmount() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	n := protocol.ByteCount(len(s.dataForWriting))
+	for _, f := range s.retransmissionQueue {
+		n += f.DataLen()
+	}
+	return uint64(n)
+}
+
 // popStreamFrame returns the next STREAM frame that is supposed to be sent on this stream
 // maxBytes is the maximum length this frame (including frame header) will have.
 func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool /* has more data to send */) {
@@ -172,13 +272,21 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 			if f == nil {
 				return nil, true
 			}
+			s.bytesRetransmitted += f.DataLen()
 			// We always claim that we have more data to send.
 			// This might be incorrect, in which case there'll be a spurious call to popStreamFrame in the future.
 			return f, true
 		}
 	}
 
-	f := wire.GetStreamFrame()
+	var f *wire.StreamFrame
+	if maxBytes > protocol.MaxReceivePacketSize {
+		// The STREAM frames from the pool can't hold that much data.
+		// This happens when sending packets larger than MaxReceivePacketSize.
+		f = &wire.StreamFrame{Data: make([]byte, 0, utils.MinByteCount(maxBytes, protocol.ByteCount(len(s.dataForWriting))))}
+	} else {
+		f = wire.GetStreamFrame()
+	}
 	f.FinBit = false
 	f.StreamID = s.streamID
 	f.Offset = s.writeOffset
@@ -271,14 +379,19 @@ func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.By
 }
 
 func (s *sendStream) frameAcked(f wire.Frame) {
-	f.(*wire.StreamFrame).PutBack()
+	sf := f.(*wire.StreamFrame)
+	start := sf.Offset
+	end := sf.Offset + sf.DataLen()
+	sf.PutBack()
 
 	s.mutex.Lock()
+	s.updateAckedOffset(start, end)
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
 	}
 	newlyCompleted := s.isNewlyCompleted()
+	s.maybeCloseFlushedChan()
 	s.mutex.Unlock()
 
 	if newlyCompleted {
@@ -286,24 +399,119 @@ func (s *sendStream) frameAcked(f wire.Frame) {
 	}
 }
 
+// updateAckedOffset must be called with the mutex held.
+func (s *sendStream) updateAckedOffset(start, end protocol.ByteCount) {
+	if end <= s.ackedOffset {
+		return
+	}
+	if start > s.ackedOffset {
+		i := sort.Search(len(s.ackedRanges), func(i int) bool { return s.ackedRanges[i].Start > start })
+		s.ackedRanges = append(s.ackedRanges, utils.ByteInterval{})
+		copy(s.ackedRanges[i+1:], s.ackedRanges[i:])
+		s.ackedRanges[i] = utils.ByteInterval{Start: start, End: end}
+		return
+	}
+	s.ackedOffset = end
+	var i int
+	for ; i < len(s.ackedRanges) && s.ackedRanges[i].Start <= s.ackedOffset; i++ {
+		s.ackedOffset = utils.MaxByteCount(s.ackedOffset, s.ackedRanges[i].End)
+	}
+	s.ackedRanges = s.ackedRanges[i:]
+}
+
+func (s *sendStream) SendStats() SendStreamStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return SendStreamStats{
+		BytesSent:          uint64(s.writeOffset),
+		BytesRetransmitted: uint64(s.bytesRetransmitted),
+	}
+}
+
+// AckedOffset returns the offset up to which all data was acknowledged by the peer.
+func (s *sendStream) AckedOffset() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return uint64(s.ackedOffset)
+}
+
 func (s *sendStream) isNewlyCompleted() bool {
 	completed := (s.finSent || s.canceledWrite) && s.numOutstandingFrames == 0 && len(s.retransmissionQueue) == 0
 	if completed && !s.completed {
 		s.completed = true
+		s.closeCompletedChan()
 		return true
 	}
 	return false
 }
 
+// must be called after locking the mutex
+func (s *sendStream) closeCompletedChan() {
+	select {
+	case <-s.completedChan:
+	default:
+		close(s.completedChan)
+	}
+	s.maybeCloseFlushedChan()
+}
+
+// flushed returns a channel that is closed once the peer acknowledged all data written to the stream so far,
+// including the FIN if the stream was closed, or when the stream is completed.
+func (s *sendStream) flushed() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.flushedChan == nil {
+		s.flushedChan = make(chan struct{})
+		s.maybeCloseFlushedChan()
+	}
+	return s.flushedChan
+}
+
+// must be called after locking the mutex
+func (s *sendStream) maybeCloseFlushedChan() {
+	if s.flushedChan == nil || !s.isFlushed() {
+		return
+	}
+	select {
+	case <-s.flushedChan:
+	default:
+		close(s.flushedChan)
+	}
+}
+
+// must be called after locking the mutex
+func (s *sendStream) isFlushed() bool {
+	select {
+	case <-s.completedChan:
+		return true
+	default:
+	}
+	// If the stream was closed, all data is acknowledged once the stream is completed.
+	return !s.finishedWriting && s.dataForWriting == nil && s.numOutstandingFrames == 0 && len(s.retransmissionQueue) == 0
+}
+
 func (s *sendStream) queueRetransmission(f wire.Frame) {
 	sf := f.(*wire.StreamFrame)
 	sf.DataLenPresent = true
 	s.mutex.Lock()
-	s.retransmissionQueue = append(s.retransmissionQueue, sf)
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
 	}
+	// STREAM frames are not retransmitted after the stream was reset.
+	if s.canceledWrite {
+		newlyCompleted := s.isNewlyCompleted()
+		s.mutex.Unlock()
+		sf.PutBack()
+		if newlyCompleted {
+			s.sender.onStreamCompleted(s.streamID)
+		}
+		return
+	}
+	s.retransmissionQueue = append(s.retransmissionQueue, sf)
 	s.mutex.Unlock()
 
 	s.sender.onHasStreamData(s.streamID)
@@ -323,6 +531,24 @@ func (s *sendStream) Close() error {
 	return nil
 }
 
+func (s *sendStream) CloseWithContext(ctx context.Context) error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+	select {
+	case <-s.completedChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closeForShutdownErr != nil {
+		return s.closeForShutdownErr
+	}
+	return s.cancelWriteErr
+}
+
 func (s *sendStream) CancelWrite(errorCode protocol.ApplicationErrorCode) {
 	s.cancelWriteImpl(errorCode, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
 
@@ -338,6 +564,7 @@ func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, wr
 	s.ctxCancel()
 	s.canceledWrite = true
 	s.cancelWriteErr = writeErr
+	s.retransmissionQueue = nil
 	newlyCompleted := s.isNewlyCompleted()
 	s.mutex.Unlock()
 
@@ -352,6 +579,26 @@ func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, wr
 	}
 }
 
+func (s *sendStream) setNonIdempotent() {
+	s.mutex.Lock()
+	s.nonIdempotent = true
+	s.mutex.Unlock()
+}
+
+// handle0RTTRejection is called when the server rejects 0-RTT.
+// Non-idempotent streams that already sent data are reset,
+// all other streams retransmit their data in 1-RTT packets.
+func (s *sendStream) handle0RTTRejection() {
+	s.mutex.Lock()
+	sentData := s.writeOffset > 0 || s.finSent
+	reset := s.nonIdempotent && sentData
+	s.mutex.Unlock()
+
+	if reset {
+		s.cancelWriteImpl(0, Err0RTTRejected)
+	}
+}
+
 func (s *sendStream) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) {
 	s.mutex.Lock()
 	hasStreamData := s.dataForWriting != nil
@@ -391,6 +638,7 @@ func (s *sendStream) closeForShutdown(err error) {
 	s.ctxCancel()
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
+	s.closeCompletedChan()
 	s.mutex.Unlock()
 	s.signalWrite()
 }
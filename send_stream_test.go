@@ -2,9 +2,12 @@ package quic
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"errors"
 	"io"
 	"runtime"
+	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -105,6 +108,62 @@ var _ = Describe("Send Stream", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("returns the amount of buffered data", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			frameHeaderLen := protocol.ByteCount(4)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999)).Times(2)
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).Times(2)
+			Expect(str.BufferedAmount()).To(BeZero())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			Expect(str.BufferedAmount()).To(BeEquivalentTo(6))
+			frame, _ := str.popStreamFrame(2 + frameHeaderLen)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("fo")))
+			Expect(str.BufferedAmount()).To(BeEquivalentTo(4))
+			frame2, _ := str.popStreamFrame(100)
+			Expect(frame2.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("obar")))
+			Eventually(done).Should(BeClosed())
+			Expect(str.BufferedAmount()).To(BeZero())
+			// lost data needs to be sent again
+			frame.OnLost(frame.Frame)
+			Expect(str.BufferedAmount()).To(BeEquivalentTo(2))
+		})
+
+		It("pops STREAM frames larger than the STREAM frames from the pool", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).Times(2)
+			data := make([]byte, 3*protocol.MaxReceivePacketSize)
+			rand.Read(data)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				n, err := strWithTimeout.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(len(data)))
+				close(done)
+			}()
+			waitForWrite()
+			frame, hasMoreData := str.popStreamFrame(2 * protocol.MaxReceivePacketSize)
+			Expect(hasMoreData).To(BeTrue())
+			f1 := frame.Frame.(*wire.StreamFrame)
+			Expect(f1.Length(protocol.VersionWhatever)).To(Equal(2 * protocol.MaxReceivePacketSize))
+			frame, hasMoreData = str.popStreamFrame(2 * protocol.MaxReceivePacketSize)
+			Expect(hasMoreData).To(BeFalse())
+			f2 := frame.Frame.(*wire.StreamFrame)
+			Expect(append(f1.Data, f2.Data...)).To(Equal(data))
+			Eventually(done).Should(BeClosed())
+			// make sure the frames can be put back
+			f1.PutBack()
+			f2.PutBack()
+		})
+
 		It("popStreamFrame returns nil if no data is available", func() {
 			frame, hasMoreData := str.popStreamFrame(1000)
 			Expect(frame).To(BeNil())
@@ -433,6 +492,30 @@ var _ = Describe("Send Stream", func() {
 				Expect(f.FinBit).To(BeTrue())
 			})
 
+			It("queues data and a FIN without blocking", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				data := []byte("foobar")
+				Expect(str.writeAndClose(data)).To(Succeed())
+				data[0] = 'x' // the data is copied
+				Expect(str.Context().Done()).To(BeClosed())
+				frame, hasMoreData := str.popStreamFrame(1000)
+				Expect(frame).ToNot(BeNil())
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.Data).To(Equal([]byte("foobar")))
+				Expect(f.FinBit).To(BeTrue())
+				Expect(hasMoreData).To(BeFalse())
+			})
+
+			It("doesn't allow writes after data was queued with a FIN", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				Expect(str.writeAndClose([]byte("foobar"))).To(Succeed())
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).To(MatchError("write on closed stream 1337"))
+				Expect(str.writeAndClose([]byte("foobar"))).To(MatchError("write on closed stream 1337"))
+			})
+
 			It("doesn't allow FIN after it is closed for shutdown", func() {
 				str.closeForShutdown(errors.New("test"))
 				f, hasMoreData := str.popStreamFrame(1000)
@@ -494,6 +577,238 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("reading from an io.Reader", func() {
+		It("reads all data", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			data := make([]byte, 3*readFromBufferSize/2)
+			rand.Read(data)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				// hide the io.WriterTo implementation of the bytes.Reader
+				n, err := str.ReadFrom(struct{ io.Reader }{bytes.NewReader(data)})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(len(data)))
+				close(done)
+			}()
+			var received []byte
+			for len(received) < len(data) {
+				waitForWrite()
+				frame, _ := str.popStreamFrame(protocol.MaxReceivePacketSize)
+				Expect(frame).ToNot(BeNil())
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.Offset).To(BeEquivalentTo(len(received)))
+				received = append(received, f.Data...)
+			}
+			Eventually(done).Should(BeClosed())
+			Expect(received).To(Equal(data))
+			// the stream is not closed
+			Expect(str.finishedWriting).To(BeFalse())
+		})
+
+		It("returns errors from the io.Reader", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			testErr := errors.New("test error")
+			pr, pw := io.Pipe()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				n, err := str.ReadFrom(pr)
+				Expect(err).To(MatchError(testErr))
+				Expect(n).To(BeEquivalentTo(6))
+				close(done)
+			}()
+			go func() {
+				defer GinkgoRecover()
+				_, err := pw.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				pw.CloseWithError(testErr)
+			}()
+			waitForWrite()
+			frame, _ := str.popStreamFrame(1000)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns the number of bytes written when the stream is canceled", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				n, err := str.ReadFrom(bytes.NewReader([]byte("foobar")))
+				Expect(err).To(MatchError("stream 1337 was reset with error code 123"))
+				Expect(n).To(BeEquivalentTo(3))
+				close(done)
+			}()
+			waitForWrite()
+			frameHeaderLen := protocol.ByteCount(4)
+			frame, _ := str.popStreamFrame(3 + frameHeaderLen)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+			Consistently(done).ShouldNot(BeClosed())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			str.handleStopSendingFrame(&wire.StopSendingFrame{
+				StreamID:  streamID,
+				ErrorCode: 123,
+			})
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("respects the write deadline", func() {
+			str.SetWriteDeadline(time.Now().Add(-time.Second))
+			n, err := str.ReadFrom(bytes.NewReader([]byte("foobar")))
+			Expect(err).To(MatchError(errDeadline))
+			Expect(n).To(BeZero())
+		})
+	})
+
+	Context("closing with a context", func() {
+		It("returns after the FIN was acknowledged", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(str.CloseWithContext(context.Background())).To(Succeed())
+				close(done)
+			}()
+			Eventually(func() bool {
+				str.mutex.Lock()
+				defer str.mutex.Unlock()
+				return str.finishedWriting
+			}).Should(BeTrue())
+			frame, _ := str.popStreamFrame(1000)
+			Expect(frame).ToNot(BeNil())
+			Expect(frame.Frame.(*wire.StreamFrame).FinBit).To(BeTrue())
+			Consistently(done).ShouldNot(BeClosed())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			frame.OnAcked(frame.Frame)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("waits until retransmitted data is acknowledged", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2) // once for Close, once for the retransmission
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			str.dataForWriting = []byte("foobar")
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(str.CloseWithContext(context.Background())).To(Succeed())
+				close(done)
+			}()
+			Eventually(func() bool {
+				str.mutex.Lock()
+				defer str.mutex.Unlock()
+				return str.finishedWriting
+			}).Should(BeTrue())
+			frame, _ := str.popStreamFrame(1000)
+			Expect(frame).ToNot(BeNil())
+			Expect(frame.Frame.(*wire.StreamFrame).FinBit).To(BeTrue())
+			frame.OnLost(frame.Frame)
+			Consistently(done).ShouldNot(BeClosed())
+			frame, _ = str.popStreamFrame(1000)
+			Expect(frame).ToNot(BeNil())
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			Expect(frame.Frame.(*wire.StreamFrame).FinBit).To(BeTrue())
+			Consistently(done).ShouldNot(BeClosed())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			frame.OnAcked(frame.Frame)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns the context's error when the context is done before the FIN is acknowledged", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+			defer cancel()
+			Expect(str.CloseWithContext(ctx)).To(MatchError(context.DeadlineExceeded))
+			// the FIN is still sent
+			frame, _ := str.popStreamFrame(1000)
+			Expect(frame).ToNot(BeNil())
+			Expect(frame.Frame.(*wire.StreamFrame).FinBit).To(BeTrue())
+		})
+
+		It("returns the error when the stream is closed for shutdown", func() {
+			testErr := errors.New("test")
+			mockSender.EXPECT().onHasStreamData(streamID)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(str.CloseWithContext(context.Background())).To(MatchError(testErr))
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			str.closeForShutdown(testErr)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns the error when the stream is canceled", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(str.CloseWithContext(context.Background())).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Context("waiting for data to be flushed", func() {
+		It("is flushed when no data was written", func() {
+			Expect(str.flushed()).To(BeClosed())
+		})
+
+		It("is flushed once all data was acknowledged", func() {
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			str.dataForWriting = []byte("foobar")
+			flushed := str.flushed()
+			Expect(flushed).ToNot(BeClosed())
+			frame1, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame1).ToNot(BeNil())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			frame1.OnLost(frame1.Frame)
+			Expect(flushed).ToNot(BeClosed())
+			frame2, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame2).ToNot(BeNil())
+			Expect(flushed).ToNot(BeClosed())
+			frame2.OnAcked(frame2.Frame)
+			Expect(flushed).To(BeClosed())
+		})
+
+		It("is flushed once the FIN was acknowledged", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			flushed := str.flushed()
+			Expect(flushed).ToNot(BeClosed())
+			frame, _ := str.popStreamFrame(1000)
+			Expect(frame).ToNot(BeNil())
+			Expect(frame.Frame.(*wire.StreamFrame).FinBit).To(BeTrue())
+			Expect(flushed).ToNot(BeClosed())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			frame.OnAcked(frame.Frame)
+			Expect(flushed).To(BeClosed())
+		})
+
+		It("is flushed when the stream is closed for shutdown", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			flushed := str.flushed()
+			Expect(flushed).ToNot(BeClosed())
+			str.closeForShutdown(errors.New("shutdown"))
+			Expect(flushed).To(BeClosed())
+		})
+	})
+
 	Context("handling MAX_STREAM_DATA frames", func() {
 		It("informs the flow controller", func() {
 			mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(0x1337))
@@ -750,6 +1065,162 @@ var _ = Describe("Send Stream", func() {
 			Expect(newFrame).ToNot(BeNil())
 			Expect(newFrame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
 		})
+
+		It("counts retransmitted bytes", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(3)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Eventually(done).Should(BeClosed())
+			Expect(frame).ToNot(BeNil())
+			Expect(str.SendStats()).To(Equal(SendStreamStats{BytesSent: 6}))
+
+			// lose the frame twice
+			frame.OnLost(frame.Frame)
+			frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			Expect(str.SendStats()).To(Equal(SendStreamStats{BytesSent: 6, BytesRetransmitted: 6}))
+			frame.OnLost(frame.Frame)
+			frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			Expect(str.SendStats()).To(Equal(SendStreamStats{BytesSent: 6, BytesRetransmitted: 12}))
+		})
+	})
+
+	Context("handling 0-RTT rejection", func() {
+		// sendData writes 6 bytes and pops them in a single STREAM frame
+		sendData := func() *ackhandler.Frame {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Eventually(done).Should(BeClosed())
+			Expect(frame).ToNot(BeNil())
+			return frame
+		}
+
+		It("retransmits data sent on idempotent streams", func() {
+			frame := sendData()
+			// don't EXPECT any calls to queueControlFrame
+			str.handle0RTTRejection()
+			mockSender.EXPECT().onHasStreamData(streamID)
+			frame.OnLost(frame.Frame)
+			newFrame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(newFrame).ToNot(BeNil())
+			Expect(newFrame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+		})
+
+		It("resets non-idempotent streams that sent data", func() {
+			str.setNonIdempotent()
+			frame := sendData()
+			mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+				StreamID:   streamID,
+				ByteOffset: 6,
+			})
+			str.handle0RTTRejection()
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).To(MatchError(Err0RTTRejected))
+			// the lost data is not retransmitted
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			frame.OnLost(frame.Frame)
+			newFrame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(newFrame).To(BeNil())
+		})
+
+		It("doesn't reset non-idempotent streams that didn't send any data", func() {
+			str.setNonIdempotent()
+			// don't EXPECT any calls to queueControlFrame
+			str.handle0RTTRejection()
+			sendData()
+		})
+
+		It("doesn't retransmit data after the stream was canceled", func() {
+			frame := sendData()
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			str.CancelWrite(1234)
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			frame.OnLost(frame.Frame)
+			Expect(str.hasData()).To(BeFalse())
+		})
+	})
+
+	Context("tracking the acknowledged offset", func() {
+		BeforeEach(func() {
+			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		})
+
+		popFrames := func(n int) []ackhandler.Frame {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Write(make([]byte, n))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			var frames []ackhandler.Frame
+			for {
+				frame, hasMoreData := str.popStreamFrame(100)
+				if frame == nil {
+					continue
+				}
+				frames = append(frames, *frame)
+				if !hasMoreData {
+					break
+				}
+			}
+			Eventually(done).Should(BeClosed())
+			return frames
+		}
+
+		It("reports the offset up to which all data was acknowledged", func() {
+			frames := popFrames(500)
+			Expect(len(frames)).To(BeNumerically(">", 3))
+			Expect(str.AckedOffset()).To(BeZero())
+			// acknowledge all frames, except for the first one
+			for _, f := range frames[1:] {
+				f.OnAcked(f.Frame)
+			}
+			Expect(str.AckedOffset()).To(BeZero())
+			frames[0].OnAcked(frames[0].Frame)
+			Expect(str.AckedOffset()).To(BeEquivalentTo(500))
+		})
+
+		It("doesn't report data that was lost", func() {
+			frames := popFrames(500)
+			Expect(len(frames)).To(BeNumerically(">", 3))
+			frames[0].OnAcked(frames[0].Frame)
+			offset := str.AckedOffset()
+			Expect(offset).ToNot(BeZero())
+			frames[1].OnLost(frames[1].Frame)
+			for _, f := range frames[2:] {
+				f.OnAcked(f.Frame)
+			}
+			Expect(str.AckedOffset()).To(Equal(offset))
+			// acknowledge the retransmission
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			frame.OnAcked(frame.Frame)
+			Expect(str.AckedOffset()).To(BeEquivalentTo(500))
+		})
 	})
 
 	Context("determining when a stream is completed", func() {
@@ -836,3 +1307,55 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 })
+
+// poppingStreamSender pops all STREAM frames from the stream as soon as it has data,
+// and immediately acknowledges them.
+type poppingStreamSender struct {
+	nopStreamSender
+	str *sendStream
+}
+
+func (s *poppingStreamSender) onHasStreamData(protocol.StreamID) {
+	for {
+		f, hasMoreData := s.str.popStreamFrame(protocol.MaxReceivePacketSize)
+		if f != nil {
+			f.OnAcked(f.Frame)
+		}
+		if !hasMoreData {
+			return
+		}
+	}
+}
+
+func benchmarkSendStreamCopy(b *testing.B, copyFunc func(*sendStream, io.Reader) (int64, error)) {
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sender := &poppingStreamSender{}
+		str := newSendStream(1, sender, newBenchmarkStreamFlowController(), protocol.VersionWhatever)
+		sender.str = str
+		// hide the io.WriterTo implementation of the bytes.Reader from io.Copy
+		n, err := copyFunc(str, struct{ io.Reader }{bytes.NewReader(data)})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if n != int64(len(data)) {
+			b.Fatalf("copied %d bytes, expected %d", n, len(data))
+		}
+	}
+}
+
+func BenchmarkSendStreamReadFrom(b *testing.B) {
+	benchmarkSendStreamCopy(b, func(str *sendStream, r io.Reader) (int64, error) {
+		return io.Copy(str, r)
+	})
+}
+
+func BenchmarkSendStreamGenericCopy(b *testing.B) {
+	benchmarkSendStreamCopy(b, func(str *sendStream, r io.Reader) (int64, error) {
+		// hide the io.ReaderFrom implementation from io.Copy
+		return io.Copy(struct{ io.Writer }{str}, r)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
 // packetHandler handles packets
@@ -28,6 +30,7 @@ type packetHandler interface {
 
 type unknownPacketHandler interface {
 	handlePacket(*receivedPacket)
+	tracePacketDropped(*receivedPacket, quictrace.PacketType, quictrace.PacketDropReason)
 	setCloseError(error)
 }
 
@@ -36,6 +39,9 @@ type packetHandlerManager interface {
 	sessionRunner
 	SetServer(unknownPacketHandler)
 	CloseServer()
+	// OOBConn returns the conn used to read the destination address of packets.
+	// It is nil if the conn doesn't support this.
+	OOBConn() *oobConn
 }
 
 type quicSession interface {
@@ -60,18 +66,32 @@ type baseServer struct {
 	config  *Config
 
 	conn net.PacketConn
+	// nil if the conn doesn't support reading the destination address of received packets
+	oobConn *oobConn
 	// If the server is started with ListenAddr, we create a packet conn.
 	// If it is started with Listen, we take a packet conn as a parameter.
 	createdPacketConn bool
 
-	tokenGenerator *handshake.TokenGenerator
+	tokenGenerator tokenGenerator
 
 	sessionHandler packetHandlerManager
 
 	receivedPackets chan *receivedPacket
+	droppedPackets  uint64 // to be used as an atomic
+
+	// statistics, to be used as atomics
+	sessionsAccepted              uint64
+	sessionsRefused               uint64
+	retriesSent                   uint64
+	versionNegotiationPacketsSent uint64
+	malformedPacketsDropped       uint64
+
+	// the number of sessions that were created and haven't been closed yet, to be used as an atomic
+	activeSessions int32
 
 	// set as a member, so they can be set in the tests
-	newSession func(connection, sessionRunner, protocol.ConnectionID /* original connection ID */, protocol.ConnectionID /* client dest connection ID */, protocol.ConnectionID /* destination connection ID */, protocol.ConnectionID /* source connection ID */, [16]byte, *Config, *tls.Config, *handshake.TokenGenerator, bool /* enable 0-RTT */, utils.Logger, protocol.VersionNumber) quicSession
+	minInitialSize func(protocol.VersionNumber) int
+	newSession     func(connection, sessionRunner, protocol.ConnectionID /* original connection ID */, protocol.ConnectionID /* client dest connection ID */, protocol.ConnectionID /* destination connection ID */, protocol.ConnectionID /* source connection ID */, [16]byte, *Config, *tls.Config, tokenGenerator, bool /* enable 0-RTT */, utils.Logger, protocol.VersionNumber) quicSession
 
 	serverError error
 	errorChan   chan struct{}
@@ -80,6 +100,9 @@ type baseServer struct {
 	sessionQueue    chan quicSession
 	sessionQueueLen int32 // to be used as an atomic
 
+	// set if the receive buffer of the socket couldn't be increased to the desired size
+	receiveBufferErr error
+
 	logger utils.Logger
 }
 
@@ -110,16 +133,77 @@ func ListenAddrEarly(addr string, tlsConf *tls.Config, config *Config) (EarlyLis
 	return &earlyServer{s}, nil
 }
 
+// ListenUDPAddr works like ListenAddr, but takes an address that was already resolved.
+// This avoids resolving the address again, and preserves the zone of link-local IPv6 addresses.
+// Warning: This API should not be considered stable and might change soon.
+func ListenUDPAddr(addr *net.UDPAddr, tlsConf *tls.Config, config *Config) (Listener, error) {
+	return listenUDPAddr(addr, tlsConf, config, false)
+}
+
+// ListenUDPAddrEarly works like ListenUDPAddr, but it returns sessions before the handshake completes.
+// Warning: This API should not be considered stable and might change soon.
+func ListenUDPAddrEarly(addr *net.UDPAddr, tlsConf *tls.Config, config *Config) (EarlyListener, error) {
+	s, err := listenUDPAddr(addr, tlsConf, config, true)
+	if err != nil {
+		return nil, err
+	}
+	return &earlyServer{s}, nil
+}
+
+// ListenFile creates a QUIC server on a UDP socket that was already bound,
+// e.g. a socket inherited from a parent process or received via SCM_RIGHTS.
+// This allows handing off the socket to a new process without downtime.
+// The file is duplicated, so the caller should close it after ListenFile returns.
+// Sessions of the previous process are not transferred.
+// Warning: This API should not be considered stable and might change soon.
+func ListenFile(f *os.File, tlsConf *tls.Config, config *Config) (Listener, error) {
+	return listenFile(f, tlsConf, config, false)
+}
+
+// ListenFileEarly works like ListenFile, but it returns sessions before the handshake completes.
+// Warning: This API should not be considered stable and might change soon.
+func ListenFileEarly(f *os.File, tlsConf *tls.Config, config *Config) (EarlyListener, error) {
+	s, err := listenFile(f, tlsConf, config, true)
+	if err != nil {
+		return nil, err
+	}
+	return &earlyServer{s}, nil
+}
+
+func listenFile(f *os.File, tlsConf *tls.Config, config *Config, acceptEarly bool) (*baseServer, error) {
+	conn, err := net.FilePacketConn(f)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := conn.(*net.UDPConn); !ok {
+		conn.Close()
+		return nil, errors.New("quic: file is not a UDP socket")
+	}
+	// The socket was created by the application, so don't change its receive buffer.
+	serv, err := listen(conn, tlsConf, config, acceptEarly, false)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	serv.createdPacketConn = true
+	return serv, nil
+}
+
 func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bool) (*baseServer, error) {
 	udpAddr, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
 		return nil, err
 	}
-	conn, err := net.ListenUDP("udp", udpAddr)
+	return listenUDPAddr(udpAddr, tlsConf, config, acceptEarly)
+}
+
+func listenUDPAddr(addr *net.UDPAddr, tlsConf *tls.Config, config *Config, acceptEarly bool) (*baseServer, error) {
+	conn, err := listenUDP(context.Background(), addr, config)
 	if err != nil {
 		return nil, err
 	}
-	serv, err := listen(conn, tlsConf, config, acceptEarly)
+	// Don't override a receive buffer size set using the SocketControl.
+	serv, err := listen(conn, tlsConf, config, acceptEarly, config == nil || config.SocketControl == nil)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +211,16 @@ func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bo
 	return serv, nil
 }
 
+// listenUDP creates the UDP socket used by DialAddr and ListenAddr.
+// The Config.SocketControl function is applied to the socket before it is bound.
+func listenUDP(ctx context.Context, addr *net.UDPAddr, config *Config) (net.PacketConn, error) {
+	if config == nil || config.SocketControl == nil {
+		return net.ListenUDP("udp", addr)
+	}
+	lc := &net.ListenConfig{Control: config.SocketControl}
+	return lc.ListenPacket(ctx, "udp", addr.String())
+}
+
 // Listen listens for QUIC connections on a given net.PacketConn.
 // A single net.PacketConn only be used for a single call to Listen.
 // The PacketConn can be used for simultaneous calls to Dial.
@@ -135,27 +229,40 @@ func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bo
 // The tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites.
 // Furthermore, it must define an application control (using NextProtos).
 // The quic.Config may be nil, in that case the default values will be used.
+// Multiple servers can share a port (e.g. to scale across CPU cores), by calling Listen with different
+// net.PacketConns that are bound to the same address. The application is responsible for setting
+// the SO_REUSEPORT socket option on these sockets (e.g. using net.ListenConfig.Control).
+// The kernel distributes packets to the sockets based on the client's address,
+// so a connection is not handled by the same server any more if the client's address changes.
 func Listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener, error) {
-	return listen(conn, tlsConf, config, false)
+	return listen(conn, tlsConf, config, false, false)
 }
 
 // ListenEarly works like Listen, but it returns sessions before the handshake completes.
 func ListenEarly(conn net.PacketConn, tlsConf *tls.Config, config *Config) (EarlyListener, error) {
-	s, err := listen(conn, tlsConf, config, true)
+	s, err := listen(conn, tlsConf, config, true, false)
 	if err != nil {
 		return nil, err
 	}
 	return &earlyServer{s}, nil
 }
 
-func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarly bool) (*baseServer, error) {
+// The receive buffer is only increased if increaseReceiveBuffer is set.
+// Otherwise, it is only checked, so that a size set by the application is not overridden.
+func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarly, increaseReceiveBuffer bool) (*baseServer, error) {
 	if tlsConf == nil {
 		return nil, errors.New("quic: tls.Config not set")
 	}
 	config = populateServerConfig(config)
-	for _, v := range config.Versions {
-		if !protocol.IsValidVersion(v) {
-			return nil, fmt.Errorf("%s is not a valid QUIC version", v)
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+	if err := validateSessionTicketConfig(config); err != nil {
+		return nil, err
+	}
+	if config.DSCP != 0 {
+		if err := setDSCP(conn, config.DSCP); err != nil {
+			return nil, err
 		}
 	}
 
@@ -163,23 +270,34 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 	if err != nil {
 		return nil, err
 	}
-	tokenGenerator, err := handshake.NewTokenGenerator()
-	if err != nil {
-		return nil, err
+	var tokenGenerator tokenGenerator
+	if config.TokenGenerator != nil {
+		tokenGenerator = &customTokenGenerator{config.TokenGenerator}
+	} else {
+		tokenGenerator, err = handshake.NewTokenGenerator()
+		if err != nil {
+			return nil, err
+		}
 	}
 	s := &baseServer{
 		conn:                conn,
+		oobConn:             sessionHandler.OOBConn(),
 		tlsConf:             tlsConf,
 		config:              config,
 		tokenGenerator:      tokenGenerator,
 		sessionHandler:      sessionHandler,
 		sessionQueue:        make(chan quicSession),
 		errorChan:           make(chan struct{}),
-		receivedPackets:     make(chan *receivedPacket, 1000),
+		receivedPackets:     make(chan *receivedPacket, config.MaxIncomingPacketQueue),
+		minInitialSize:      minInitialPacketSize,
 		newSession:          newSession,
 		logger:              utils.DefaultLogger.WithPrefix("server"),
 		acceptEarlySessions: acceptEarly,
 	}
+	s.receiveBufferErr = setReceiveBuffer(conn, increaseReceiveBuffer)
+	if s.receiveBufferErr != nil && !config.DisableReceiveBufferWarning {
+		s.logger.Errorf("%s. Packets might be dropped under load.", s.receiveBufferErr)
+	}
 	go s.run()
 	sessionHandler.SetServer(s)
 	s.logger.Debugf("Listening for %s connections on %s", conn.LocalAddr().Network(), conn.LocalAddr().String())
@@ -228,15 +346,113 @@ var defaultAcceptToken = func(clientAddr net.Addr, token *Token) bool {
 // it may be called with nil
 func populateServerConfig(config *Config) *Config {
 	config = populateConfig(config)
-	if config.ConnectionIDLength == 0 {
+	if config.ConnectionIDLength == 0 && !config.ZeroLengthConnectionIDs {
 		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
 	}
 	if config.AcceptToken == nil {
 		config.AcceptToken = defaultAcceptToken
 	}
+	if config.MaxIncomingPacketQueue <= 0 {
+		config.MaxIncomingPacketQueue = protocol.DefaultMaxServerUnprocessedPackets
+	}
+	if config.NumSessionTickets == 0 {
+		config.NumSessionTickets = protocol.DefaultNumSessionTickets
+	}
 	return config
 }
 
+// validateConfig validates the config values used by both the client and the server.
+func validateConfig(config *Config) error {
+	for _, v := range config.Versions {
+		if !protocol.IsValidVersion(v) {
+			return fmt.Errorf("%s is not a valid QUIC version", v)
+		}
+	}
+	if config.MaxUDPPayloadSize != 0 && config.MaxUDPPayloadSize < protocol.MinInitialPacketSize {
+		return fmt.Errorf("invalid MaxUDPPayloadSize: %d (minimum %d)", config.MaxUDPPayloadSize, protocol.MinInitialPacketSize)
+	}
+	if err := validateInitialMaxPacketSize(config); err != nil {
+		return err
+	}
+	if err := validateAckDelayConfig(config); err != nil {
+		return err
+	}
+	if err := validateRTTConfig(config); err != nil {
+		return err
+	}
+	if err := validateCongestionConfig(config); err != nil {
+		return err
+	}
+	if err := validateConnectionIDConfig(config); err != nil {
+		return err
+	}
+	return validateDSCPConfig(config)
+}
+
+func validateInitialMaxPacketSize(config *Config) error {
+	if config.InitialMaxPacketSize == 0 {
+		return nil
+	}
+	if config.InitialMaxPacketSize < protocol.MinInitialPacketSize || config.InitialMaxPacketSize > protocol.MaxUDPPayloadSize {
+		return fmt.Errorf("invalid InitialMaxPacketSize: %d (must be between %d and %d)", config.InitialMaxPacketSize, protocol.MinInitialPacketSize, protocol.MaxUDPPayloadSize)
+	}
+	return nil
+}
+
+func validateSessionTicketConfig(config *Config) error {
+	if config.NumSessionTickets < 0 {
+		return fmt.Errorf("invalid NumSessionTickets: %d", config.NumSessionTickets)
+	}
+	return nil
+}
+
+func validateAckDelayConfig(config *Config) error {
+	if config.MaxAckDelay < 0 || config.MaxAckDelay+protocol.TimerGranularity >= protocol.MaxMaxAckDelay {
+		return fmt.Errorf("invalid MaxAckDelay: %s (must be smaller than %s)", config.MaxAckDelay, protocol.MaxMaxAckDelay-protocol.TimerGranularity)
+	}
+	if config.AckDelayExponent > protocol.MaxAckDelayExponent {
+		return fmt.Errorf("invalid AckDelayExponent: %d (maximum %d)", config.AckDelayExponent, protocol.MaxAckDelayExponent)
+	}
+	return nil
+}
+
+func validateRTTConfig(config *Config) error {
+	if config.MinRTT < 0 || config.MaxRTT < 0 {
+		return fmt.Errorf("invalid RTT bounds: %s, %s (must not be negative)", config.MinRTT, config.MaxRTT)
+	}
+	if config.MaxRTT != 0 && config.MaxRTT < config.MinRTT {
+		return fmt.Errorf("invalid RTT bounds: MaxRTT (%s) is smaller than MinRTT (%s)", config.MaxRTT, config.MinRTT)
+	}
+	if config.InitialRTT != 0 && (config.InitialRTT < protocol.TimerGranularity || config.InitialRTT > protocol.MaxInitialRTT) {
+		return fmt.Errorf("invalid InitialRTT: %s (must be between %s and %s)", config.InitialRTT, protocol.TimerGranularity, protocol.MaxInitialRTT)
+	}
+	return nil
+}
+
+func validateCongestionConfig(config *Config) error {
+	if config.CongestionControl > protocol.CongestionControlCubic {
+		return fmt.Errorf("invalid CongestionControl: %d", config.CongestionControl)
+	}
+	if config.MinCongestionWindow > protocol.MaxCongestionWindow {
+		return fmt.Errorf("invalid MinCongestionWindow: %d (maximum %d)", config.MinCongestionWindow, protocol.MaxCongestionWindow)
+	}
+	return nil
+}
+
+func validateConnectionIDConfig(config *Config) error {
+	if config.ZeroLengthConnectionIDs && config.ConnectionIDLength != 0 {
+		return fmt.Errorf("invalid ConnectionIDLength: %d (must not be set when using zero-length connection IDs)", config.ConnectionIDLength)
+	}
+	return nil
+}
+
+func validateDSCPConfig(config *Config) error {
+	if config.DSCP < 0 || config.DSCP > protocol.MaxDSCP {
+		return fmt.Errorf("invalid DSCP: %d (must be between 0 and %d)", config.DSCP, protocol.MaxDSCP)
+	}
+	return nil
+}
+
 func populateConfig(config *Config) *Config {
 	if config == nil {
 		config = &Config{}
@@ -257,10 +473,16 @@ func populateConfig(config *Config) *Config {
 	if maxReceiveStreamFlowControlWindow == 0 {
 		maxReceiveStreamFlowControlWindow = protocol.DefaultMaxReceiveStreamFlowControlWindow
 	}
+	if config.MaxStreamReassemblyBuffer != 0 && maxReceiveStreamFlowControlWindow > config.MaxStreamReassemblyBuffer {
+		maxReceiveStreamFlowControlWindow = config.MaxStreamReassemblyBuffer
+	}
 	maxReceiveConnectionFlowControlWindow := config.MaxReceiveConnectionFlowControlWindow
 	if maxReceiveConnectionFlowControlWindow == 0 {
 		maxReceiveConnectionFlowControlWindow = protocol.DefaultMaxReceiveConnectionFlowControlWindow
 	}
+	if config.MaxConnectionReceiveBuffer != 0 && maxReceiveConnectionFlowControlWindow > config.MaxConnectionReceiveBuffer {
+		maxReceiveConnectionFlowControlWindow = config.MaxConnectionReceiveBuffer
+	}
 	maxIncomingStreams := config.MaxIncomingStreams
 	if maxIncomingStreams == 0 {
 		maxIncomingStreams = protocol.DefaultMaxIncomingStreams
@@ -273,20 +495,94 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxIncomingStreamsLimit := config.MaxIncomingStreamsLimit
+	if maxIncomingStreamsLimit == 0 || maxIncomingStreamsLimit > uint64(protocol.MaxStreamCount) {
+		maxIncomingStreamsLimit = uint64(protocol.MaxStreamCount)
+	}
+	activeConnectionIDLimit := config.ActiveConnectionIDLimit
+	if activeConnectionIDLimit == 0 {
+		activeConnectionIDLimit = protocol.MaxActiveConnectionIDs
+	} else if activeConnectionIDLimit < protocol.MinActiveConnectionIDLimit {
+		activeConnectionIDLimit = protocol.MinActiveConnectionIDLimit
+	}
+	maxRetiredConnectionIDs := config.MaxRetiredConnectionIDs
+	if maxRetiredConnectionIDs == 0 {
+		maxRetiredConnectionIDs = protocol.MaxRetiredConnectionIDsPerActiveConnectionID * activeConnectionIDLimit
+	}
+	// A single NEW_CONNECTION_ID frame can legitimately retire all connection IDs we're storing,
+	// so allow at least twice the active_connection_id_limit (see section 5.1.2 of RFC 9000).
+	if maxRetiredConnectionIDs < 2*activeConnectionIDLimit {
+		maxRetiredConnectionIDs = 2 * activeConnectionIDLimit
+	}
+
+	maxUDPPayloadSize := config.MaxUDPPayloadSize
+	if maxUDPPayloadSize == 0 || maxUDPPayloadSize > uint64(protocol.MaxReceivePacketSize) {
+		maxUDPPayloadSize = uint64(protocol.MaxReceivePacketSize)
+	}
+
+	maxAckDelay := config.MaxAckDelay
+	if maxAckDelay == 0 {
+		maxAckDelay = protocol.MaxAckDelay
+	}
+	ackDelayExponent := config.AckDelayExponent
+	if ackDelayExponent == 0 {
+		ackDelayExponent = protocol.AckDelayExponent
+	} else if ackDelayExponent < 0 {
+		ackDelayExponent = 0
+	}
 
 	return &Config{
 		Versions:                              versions,
+		VersionNegotiation:                    config.VersionNegotiation,
 		HandshakeTimeout:                      handshakeTimeout,
 		MaxIdleTimeout:                        idleTimeout,
+		MaxConnectionDuration:                 config.MaxConnectionDuration,
 		AcceptToken:                           config.AcceptToken,
 		KeepAlive:                             config.KeepAlive,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
+		MaxConnectionReceiveBuffer:            config.MaxConnectionReceiveBuffer,
+		MaxStreamReassemblyBuffer:             config.MaxStreamReassemblyBuffer,
 		MaxIncomingStreams:                    maxIncomingStreams,
+		MaxIncomingStreamsLimit:               maxIncomingStreamsLimit,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
+		ActiveConnectionIDLimit:               activeConnectionIDLimit,
+		MaxRetiredConnectionIDs:               maxRetiredConnectionIDs,
+		MaxUDPPayloadSize:                     maxUDPPayloadSize,
+		InitialMaxPacketSize:                  config.InitialMaxPacketSize,
+		MaxAckDelay:                           maxAckDelay,
+		DisableDelayedAcks:                    config.DisableDelayedAcks,
+		AckDelayExponent:                      ackDelayExponent,
+		EnableACKFrequency:                    config.EnableACKFrequency,
+		CongestionControl:                     config.CongestionControl,
+		DisableHyStart:                        config.DisableHyStart,
+		MinCongestionWindow:                   config.MinCongestionWindow,
 		ConnectionIDLength:                    config.ConnectionIDLength,
+		ZeroLengthConnectionIDs:               config.ZeroLengthConnectionIDs,
+		DisableWriteRetries:                   config.DisableWriteRetries,
+		DSCP:                                  config.DSCP,
+		DisableReceiveBufferWarning:           config.DisableReceiveBufferWarning,
+		MaxIncomingPacketQueue:                config.MaxIncomingPacketQueue,
+		MaxActiveSessions:                     config.MaxActiveSessions,
+		NumSessionTickets:                     config.NumSessionTickets,
+		DisableVersionNegotiationPackets:      config.DisableVersionNegotiationPackets,
+		RefuseConnection:                      config.RefuseConnection,
+		OnNewConnection:                       config.OnNewConnection,
+		MinRTT:                                config.MinRTT,
+		MaxRTT:                                config.MaxRTT,
+		InitialRTT:                            config.InitialRTT,
+		ChooseConnectionID:                    config.ChooseConnectionID,
+		ChooseRetryConnectionID:               config.ChooseRetryConnectionID,
+		EnableActiveMigration:                 config.EnableActiveMigration,
+		SocketControl:                         config.SocketControl,
+		OnPathChange:                          config.OnPathChange,
+		OnEarlySession:                        config.OnEarlySession,
+		OnFlowControlBlocked:                  config.OnFlowControlBlocked,
+		TransportParametersHook:               config.TransportParametersHook,
 		StatelessResetKey:                     config.StatelessResetKey,
 		TokenStore:                            config.TokenStore,
+		TokenGenerator:                        config.TokenGenerator,
+		DisableSNI:                            config.DisableSNI,
 		QuicTracer:                            config.QuicTracer,
 	}
 }
@@ -347,19 +643,52 @@ func (s *baseServer) Addr() net.Addr {
 	return s.conn.LocalAddr()
 }
 
+// DualStack says if the server's socket is an IPv6 socket that also accepts IPv4 packets.
+func (s *baseServer) DualStack() bool {
+	return isDualStack(s.conn)
+}
+
+// ReceiveBufferWarning returns the error that occurred when increasing the socket's receive buffer.
+func (s *baseServer) ReceiveBufferWarning() error {
+	return s.receiveBufferErr
+}
+
+// DroppedPackets returns the number of packets that were dropped because the packet queue was full.
+func (s *baseServer) DroppedPackets() uint64 {
+	return atomic.LoadUint64(&s.droppedPackets)
+}
+
+// Stats returns statistics about the connection attempts the server handled.
+func (s *baseServer) Stats() ServerStats {
+	return ServerStats{
+		SessionsAccepted:              atomic.LoadUint64(&s.sessionsAccepted),
+		SessionsRefused:               atomic.LoadUint64(&s.sessionsRefused),
+		RetriesSent:                   atomic.LoadUint64(&s.retriesSent),
+		VersionNegotiationPacketsSent: atomic.LoadUint64(&s.versionNegotiationPacketsSent),
+		MalformedPacketsDropped:       atomic.LoadUint64(&s.malformedPacketsDropped),
+		DroppedPackets:                atomic.LoadUint64(&s.droppedPackets),
+		ActiveSessions:                int(atomic.LoadInt32(&s.activeSessions)),
+	}
+}
+
 func (s *baseServer) handlePacket(p *receivedPacket) {
-	s.receivedPackets <- p
+	// Don't block the packet handler map when the server is busy.
+	// Instead, drop packets once the queue is full.
+	select {
+	case s.receivedPackets <- p:
+	default:
+		atomic.AddUint64(&s.droppedPackets, 1)
+		s.logger.Debugf("Dropping packet from %s (%d bytes). Server receive queue full.", p.remoteAddr, len(p.data))
+		p.buffer.Release()
+	}
 }
 
 func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* was the packet passed on to a session */ {
-	if len(p.data) < protocol.MinInitialPacketSize {
-		s.logger.Debugf("Dropping a packet that is too small to be a valid Initial (%d bytes)", len(p.data))
-		return false
-	}
 	// If we're creating a new session, the packet will be passed to the session.
 	// The header will then be parsed again.
 	hdr, _, _, err := wire.ParsePacket(p.data, s.config.ConnectionIDLength)
 	if err != nil {
+		atomic.AddUint64(&s.malformedPacketsDropped, 1)
 		s.logger.Debugf("Error parsing packet: %s", err)
 		return false
 	}
@@ -367,8 +696,21 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* was the packet
 	if !hdr.IsLongHeader {
 		return false
 	}
+	// The minimum size depends on the version.
+	// This also applies to unsupported versions, so we don't send Version Negotiation packets in response to small packets.
+	if minSize := s.minInitialSize(hdr.Version); len(p.data) < minSize {
+		atomic.AddUint64(&s.malformedPacketsDropped, 1)
+		s.logger.Debugf("Dropping a packet that is too small to be a valid Initial (%d bytes, minimum %d bytes)", len(p.data), minSize)
+		s.tracePacketDropped(p, getTracePacketType(hdr), quictrace.PacketDropTooSmall)
+		return false
+	}
 	// send a Version Negotiation Packet if the client is speaking a different protocol version
 	if !protocol.IsSupportedVersion(s.config.Versions, hdr.Version) {
+		if s.config.DisableVersionNegotiationPackets {
+			s.logger.Debugf("Dropping packet with unsupported version %s (%d bytes)", hdr.Version, len(p.data))
+			s.tracePacketDropped(p, getTracePacketType(hdr), quictrace.PacketDropUnsupportedVersion)
+			return false
+		}
 		go s.sendVersionNegotiationPacket(p, hdr)
 		return false
 	}
@@ -377,6 +719,7 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* was the packet
 		// There's litte point in sending a Stateless Reset, since the client
 		// might not have received the token yet.
 		s.logger.Debugf("Dropping long header packet of type %s (%d bytes)", hdr.Type, len(p.data))
+		s.tracePacketDropped(p, getTracePacketType(hdr), quictrace.PacketDropUnknownConnectionID)
 		return false
 	}
 
@@ -397,50 +740,77 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* was the packet
 	return true
 }
 
+// minInitialPacketSize returns the minimum size of a UDP datagram carrying an Initial packet of a version.
+// All versions quic-go implements use the same minimum.
+// Experimental versions with a different minimum can be added here.
+func minInitialPacketSize(protocol.VersionNumber) int {
+	return protocol.MinInitialPacketSize
+}
+
+func (s *baseServer) tracePacketDropped(p *receivedPacket, packetType quictrace.PacketType, reason quictrace.PacketDropReason) {
+	if tracer, ok := s.config.QuicTracer.(quictrace.PacketDropTracer); ok {
+		tracer.DroppedPacket(p.remoteAddr, packetType, protocol.ByteCount(len(p.data)), reason)
+	}
+}
+
 func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) (quicSession, error) {
 	if len(hdr.Token) == 0 && hdr.DestConnectionID.Len() < protocol.MinConnectionIDLenInitial {
 		return nil, errors.New("too short connection ID")
 	}
 
+	if s.config.OnNewConnection != nil {
+		if err := s.config.OnNewConnection(p.remoteAddr, newInitialHeader(hdr)); err != nil {
+			s.logger.Debugf("Rejecting new connection from %s: %s", p.remoteAddr, err)
+			s.refuseConnection(p, hdr, err)
+			return nil, nil
+		}
+	}
+
 	var token *Token
 	var origDestConnectionID protocol.ConnectionID
 	if len(hdr.Token) > 0 {
 		c, err := s.tokenGenerator.DecodeToken(hdr.Token)
-		if err == nil {
+		if err == nil && c != nil {
 			token = &Token{
-				IsRetryToken: c.IsRetryToken,
-				RemoteAddr:   c.RemoteAddr,
-				SentTime:     c.SentTime,
+				IsRetryToken:             c.IsRetryToken,
+				RemoteAddr:               c.RemoteAddr,
+				SentTime:                 c.SentTime,
+				OriginalDestConnectionID: c.OriginalDestConnectionID,
 			}
 			origDestConnectionID = c.OriginalDestConnectionID
 		}
 	}
 	if !s.config.AcceptToken(p.remoteAddr, token) {
 		go func() {
-			if err := s.sendRetry(p.remoteAddr, hdr); err != nil {
+			if err := s.sendRetry(p.remoteAddr, p.info, hdr); err != nil {
 				s.logger.Debugf("Error sending Retry: %s", err)
+				return
 			}
+			atomic.AddUint64(&s.retriesSent, 1)
 		}()
 		return nil, nil
 	}
 
 	if queueLen := atomic.LoadInt32(&s.sessionQueueLen); queueLen >= protocol.MaxAcceptQueueSize {
 		s.logger.Debugf("Rejecting new connection. Server currently busy. Accept queue length: %d (max %d)", queueLen, protocol.MaxAcceptQueueSize)
-		go func() {
-			if err := s.sendServerBusy(p.remoteAddr, hdr); err != nil {
-				s.logger.Debugf("Error rejecting connection: %s", err)
-			}
-		}()
+		s.refuseConnection(p, hdr, nil)
+		return nil, nil
+	}
+	// The number of active sessions is only incremented on this go routine, so it can't exceed the limit.
+	if numSessions := atomic.LoadInt32(&s.activeSessions); s.config.MaxActiveSessions > 0 && int(numSessions) >= s.config.MaxActiveSessions {
+		s.logger.Debugf("Rejecting new connection. Reached the maximum number of active sessions: %d", s.config.MaxActiveSessions)
+		s.refuseConnection(p, hdr, nil)
 		return nil, nil
 	}
 
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	connID, err := s.chooseConnectionID(p.remoteAddr, hdr)
 	if err != nil {
 		return nil, err
 	}
 	s.logger.Debugf("Changing connection ID to %s.", connID)
 	sess := s.createNewSession(
 		p.remoteAddr,
+		p.info,
 		origDestConnectionID,
 		hdr.DestConnectionID,
 		hdr.SrcConnectionID,
@@ -453,8 +823,55 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) (qui
 	return sess, nil
 }
 
+// refuseConnection sends a CONNECTION_CLOSE in response to an Initial packet, without creating a session.
+// If refuseErr is nil, the error code and reason are determined by Config.RefuseConnection.
+func (s *baseServer) refuseConnection(p *receivedPacket, hdr *wire.Header, refuseErr error) {
+	atomic.AddUint64(&s.sessionsRefused, 1)
+	go func() {
+		errorCode := qerr.ConnectionRefused
+		var reason string
+		if refuseErr != nil {
+			reason = refuseErr.Error()
+			var qErr *qerr.QuicError
+			if errors.As(refuseErr, &qErr) && !qErr.IsApplicationError() {
+				errorCode = qErr.ErrorCode
+				reason = qErr.ErrorMessage
+			}
+		} else if s.config.RefuseConnection != nil {
+			errorCode, reason = s.config.RefuseConnection(p.remoteAddr)
+		}
+		if err := s.sendConnectionRefused(p.remoteAddr, p.info, hdr, errorCode, reason); err != nil {
+			s.logger.Debugf("Error rejecting connection: %s", err)
+		}
+	}()
+}
+
+func newInitialHeader(hdr *wire.Header) *InitialHeader {
+	return &InitialHeader{
+		Version:          hdr.Version,
+		DestConnectionID: hdr.DestConnectionID,
+		SrcConnectionID:  hdr.SrcConnectionID,
+		Token:            hdr.Token,
+	}
+}
+
+func (s *baseServer) chooseConnectionID(remoteAddr net.Addr, hdr *wire.Header) (protocol.ConnectionID, error) {
+	if s.config.ChooseConnectionID == nil {
+		return generateConnectionID(s.config.ConnectionIDLength)
+	}
+	connID, err := s.config.ChooseConnectionID(remoteAddr, newInitialHeader(hdr))
+	if err != nil {
+		return nil, err
+	}
+	if connID.Len() != s.config.ConnectionIDLength {
+		return nil, fmt.Errorf("ChooseConnectionID returned a connection ID of length %d, expected %d", connID.Len(), s.config.ConnectionIDLength)
+	}
+	return connID, nil
+}
+
 func (s *baseServer) createNewSession(
 	remoteAddr net.Addr,
+	info *packetInfo,
 	origDestConnID protocol.ConnectionID,
 	clientDestConnID protocol.ConnectionID,
 	destConnID protocol.ConnectionID,
@@ -462,7 +879,7 @@ func (s *baseServer) createNewSession(
 	version protocol.VersionNumber,
 ) quicSession {
 	sess := s.newSession(
-		&conn{pconn: s.conn, currentAddr: remoteAddr},
+		&conn{pconn: s.conn, currentAddr: remoteAddr, oob: s.oobConn, info: info},
 		s.sessionHandler,
 		origDestConnID,
 		clientDestConnID,
@@ -481,7 +898,21 @@ func (s *baseServer) createNewSession(
 		// This might happen if we receive two copies of the Initial at the same time.
 		return nil
 	}
-	s.sessionHandler.Add(srcConnID, sess)
+	if added := s.sessionHandler.Add(srcConnID, sess); !added {
+		// When using zero-length connection IDs, this is the case if there already is a session.
+		s.sessionHandler.Remove(clientDestConnID)
+		s.logger.Debugf("Rejecting new connection. Connection ID %s is already in use.", srcConnID)
+		atomic.AddUint64(&s.sessionsRefused, 1)
+		go func() {
+			hdr := &wire.Header{DestConnectionID: clientDestConnID, SrcConnectionID: destConnID, Version: version}
+			if err := s.sendConnectionRefused(remoteAddr, info, hdr, qerr.ConnectionRefused, "connection ID already in use"); err != nil {
+				s.logger.Debugf("Error rejecting connection: %s", err)
+			}
+		}()
+		return nil
+	}
+	atomic.AddUint64(&s.sessionsAccepted, 1)
+	atomic.AddInt32(&s.activeSessions, 1)
 	go sess.run()
 	go s.handleNewSession(sess)
 	return sess
@@ -489,6 +920,11 @@ func (s *baseServer) createNewSession(
 
 func (s *baseServer) handleNewSession(sess quicSession) {
 	sessCtx := sess.Context()
+	// The session counts as active until it is closed, even after it was accepted.
+	defer func() {
+		<-sessCtx.Done()
+		atomic.AddInt32(&s.activeSessions, -1)
+	}()
 	if s.acceptEarlySessions {
 		// wait until the early session is ready (or the handshake fails)
 		select {
@@ -515,7 +951,7 @@ func (s *baseServer) handleNewSession(sess quicSession) {
 	}
 }
 
-func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
+func (s *baseServer) sendRetry(remoteAddr net.Addr, info *packetInfo, hdr *wire.Header) error {
 	// Log the Initial packet now.
 	// If no Retry is sent, the packet will be logged by the session.
 	(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
@@ -523,7 +959,7 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 	if err != nil {
 		return err
 	}
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	connID, err := s.chooseRetryConnectionID(remoteAddr, hdr)
 	if err != nil {
 		return err
 	}
@@ -542,19 +978,51 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 		return err
 	}
 	// append the Retry integrity tag
-	tag := handshake.GetRetryIntegrityTag(buf.Bytes(), hdr.DestConnectionID)
+	tag := handshake.GetRetryIntegrityTag(buf.Bytes(), hdr.DestConnectionID, hdr.Version)
 	buf.Write(tag[:])
-	_, err = s.conn.WriteTo(buf.Bytes(), remoteAddr)
-	return err
+	return s.writeTo(buf.Bytes(), remoteAddr, info)
 }
 
-func (s *baseServer) sendServerBusy(remoteAddr net.Addr, hdr *wire.Header) error {
-	sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer)
+func (s *baseServer) chooseRetryConnectionID(remoteAddr net.Addr, hdr *wire.Header) (protocol.ConnectionID, error) {
+	// The client uses the Retry connection ID for its next Initial.
+	// When using zero-length connection IDs, it must not be empty,
+	// otherwise it would collide with the connection ID of the new session.
+	if s.config.ChooseRetryConnectionID == nil {
+		if s.config.ZeroLengthConnectionIDs {
+			return generateConnectionIDForInitial()
+		}
+		return generateConnectionID(s.config.ConnectionIDLength)
+	}
+	connID, err := s.config.ChooseRetryConnectionID(remoteAddr, hdr.DestConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	// The client ignores Retry packets that use its destination connection ID as the source connection ID.
+	if connID.Equal(hdr.DestConnectionID) {
+		return nil, errors.New("ChooseRetryConnectionID returned the client's destination connection ID")
+	}
+	if s.config.ZeroLengthConnectionIDs {
+		if connID.Len() == 0 {
+			return nil, errors.New("ChooseRetryConnectionID returned a zero-length connection ID")
+		}
+		return connID, nil
+	}
+	if connID.Len() != s.config.ConnectionIDLength {
+		return nil, fmt.Errorf("ChooseRetryConnectionID returned a connection ID of length %d, expected %d", connID.Len(), s.config.ConnectionIDLength)
+	}
+	return connID, nil
+}
+
+func (s *baseServer) sendConnectionRefused(remoteAddr net.Addr, info *packetInfo, hdr *wire.Header, errorCode qerr.ErrorCode, reason string) error {
+	sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
 	packetBuffer := getPacketBuffer()
 	defer packetBuffer.Release()
 	buf := bytes.NewBuffer(packetBuffer.Slice[:0])
 
-	ccf := &wire.ConnectionCloseFrame{ErrorCode: qerr.ServerBusy}
+	ccf := &wire.ConnectionCloseFrame{
+		ErrorCode:    errorCode,
+		ReasonPhrase: reason,
+	}
 
 	replyHdr := &wire.ExtendedHeader{}
 	replyHdr.IsLongHeader = true
@@ -586,8 +1054,7 @@ func (s *baseServer) sendServerBusy(remoteAddr net.Addr, hdr *wire.Header) error
 
 	replyHdr.Log(s.logger)
 	wire.LogFrame(s.logger, ccf, true)
-	_, err := s.conn.WriteTo(raw, remoteAddr)
-	return err
+	return s.writeTo(raw, remoteAddr, info)
 }
 
 func (s *baseServer) sendVersionNegotiationPacket(p *receivedPacket, hdr *wire.Header) {
@@ -597,7 +1064,20 @@ func (s *baseServer) sendVersionNegotiationPacket(p *receivedPacket, hdr *wire.H
 		s.logger.Debugf("Error composing Version Negotiation: %s", err)
 		return
 	}
-	if _, err := s.conn.WriteTo(data, p.remoteAddr); err != nil {
+	if err := s.writeTo(data, p.remoteAddr, p.info); err != nil {
 		s.logger.Debugf("Error sending Version Negotiation: %s", err)
+		return
 	}
+	atomic.AddUint64(&s.versionNegotiationPacketsSent, 1)
+}
+
+// writeTo sends a packet.
+// If supported by the conn, it is sent from the local address contained in info.
+func (s *baseServer) writeTo(b []byte, addr net.Addr, info *packetInfo) error {
+	if s.oobConn != nil {
+		_, err := s.oobConn.WritePacket(b, addr, info)
+		return err
+	}
+	_, err := s.conn.WriteTo(b, addr)
+	return err
 }
@@ -5,17 +5,21 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/tls"
+	"encoding/binary"
 	"errors"
 	"net"
 	"reflect"
 	"runtime/pprof"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/testdata"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -25,6 +29,32 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type fakeTokenGenerator struct{}
+
+var _ TokenGenerator = &fakeTokenGenerator{}
+
+func (fakeTokenGenerator) NewRetryToken(_ net.Addr, origDestConnID ConnectionID) ([]byte, error) {
+	return append([]byte("retry:"), origDestConnID...), nil
+}
+
+func (fakeTokenGenerator) NewToken(net.Addr) ([]byte, error) {
+	return []byte("token"), nil
+}
+
+func (fakeTokenGenerator) DecodeToken(data []byte) (*Token, error) {
+	if bytes.HasPrefix(data, []byte("retry:")) {
+		return &Token{
+			IsRetryToken:             true,
+			RemoteAddr:               "tenant-42",
+			OriginalDestConnectionID: data[len("retry:"):],
+		}, nil
+	}
+	if bytes.Equal(data, []byte("token")) {
+		return &Token{RemoteAddr: "tenant-42"}, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
 func areServersRunning() bool {
 	var b bytes.Buffer
 	pprof.Lookup("goroutine").WriteTo(&b, 1)
@@ -101,6 +131,69 @@ var _ = Describe("Server", func() {
 		Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
 	})
 
+	It("errors when the Config contains a too small MaxUDPPayloadSize", func() {
+		_, err := Listen(nil, tlsConf, &Config{MaxUDPPayloadSize: 1199})
+		Expect(err).To(MatchError("invalid MaxUDPPayloadSize: 1199 (minimum 1200)"))
+	})
+
+	It("errors when the Config contains an invalid InitialMaxPacketSize", func() {
+		_, err := Listen(nil, tlsConf, &Config{InitialMaxPacketSize: 70000})
+		Expect(err).To(MatchError("invalid InitialMaxPacketSize: 70000 (must be between 1200 and 65527)"))
+	})
+
+	It("errors when the Config contains a too large MaxAckDelay", func() {
+		_, err := Listen(nil, tlsConf, &Config{MaxAckDelay: 20 * time.Second})
+		Expect(err).To(MatchError("invalid MaxAckDelay: 20s (must be smaller than 16.383s)"))
+	})
+
+	It("errors when the Config contains invalid RTT bounds", func() {
+		_, err := Listen(nil, tlsConf, &Config{MinRTT: time.Second, MaxRTT: 100 * time.Millisecond})
+		Expect(err).To(MatchError("invalid RTT bounds: MaxRTT (100ms) is smaller than MinRTT (1s)"))
+		_, err = Listen(nil, tlsConf, &Config{MinRTT: -time.Second})
+		Expect(err).To(MatchError("invalid RTT bounds: -1s, 0s (must not be negative)"))
+	})
+
+	It("errors when the Config contains an invalid InitialRTT", func() {
+		_, err := Listen(nil, tlsConf, &Config{InitialRTT: time.Microsecond})
+		Expect(err).To(MatchError("invalid InitialRTT: 1µs (must be between 1ms and 10s)"))
+		_, err = Listen(nil, tlsConf, &Config{InitialRTT: time.Minute})
+		Expect(err).To(MatchError("invalid InitialRTT: 1m0s (must be between 1ms and 10s)"))
+	})
+
+	It("errors when the Config sets a ConnectionIDLength and uses zero-length connection IDs", func() {
+		_, err := Listen(nil, tlsConf, &Config{ZeroLengthConnectionIDs: true, ConnectionIDLength: 8})
+		Expect(err).To(MatchError("invalid ConnectionIDLength: 8 (must not be set when using zero-length connection IDs)"))
+	})
+
+	It("uses zero-length connection IDs", func() {
+		ln, err := Listen(conn, tlsConf, &Config{ZeroLengthConnectionIDs: true})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		Expect(ln.(*baseServer).config.ConnectionIDLength).To(BeZero())
+	})
+
+	It("errors when the Config contains an invalid DSCP", func() {
+		_, err := Listen(nil, tlsConf, &Config{DSCP: 64})
+		Expect(err).To(MatchError("invalid DSCP: 64 (must be between 0 and 63)"))
+		_, err = Listen(nil, tlsConf, &Config{DSCP: -1})
+		Expect(err).To(MatchError("invalid DSCP: -1 (must be between 0 and 63)"))
+	})
+
+	It("errors when the Config contains an invalid CongestionControl", func() {
+		_, err := Listen(nil, tlsConf, &Config{CongestionControl: 42})
+		Expect(err).To(MatchError("invalid CongestionControl: 42"))
+	})
+
+	It("errors when the Config contains a too large MinCongestionWindow", func() {
+		_, err := Listen(nil, tlsConf, &Config{MinCongestionWindow: protocol.MaxCongestionWindow + 1})
+		Expect(err).To(MatchError("invalid MinCongestionWindow: 12520001 (maximum 12520000)"))
+	})
+
+	It("errors when the Config contains an invalid NumSessionTickets", func() {
+		_, err := Listen(nil, tlsConf, &Config{NumSessionTickets: -1})
+		Expect(err).To(MatchError("invalid NumSessionTickets: -1"))
+	})
+
 	It("fills in default values if options are not set in the Config", func() {
 		ln, err := Listen(conn, tlsConf, &Config{})
 		Expect(err).ToNot(HaveOccurred())
@@ -108,8 +201,13 @@ var _ = Describe("Server", func() {
 		Expect(server.config.Versions).To(Equal(protocol.SupportedVersions))
 		Expect(server.config.HandshakeTimeout).To(Equal(protocol.DefaultHandshakeTimeout))
 		Expect(server.config.MaxIdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
+		Expect(server.config.MaxUDPPayloadSize).To(BeEquivalentTo(protocol.MaxReceivePacketSize))
+		Expect(server.config.MaxAckDelay).To(Equal(protocol.MaxAckDelay))
+		Expect(server.config.AckDelayExponent).To(Equal(protocol.AckDelayExponent))
 		Expect(reflect.ValueOf(server.config.AcceptToken)).To(Equal(reflect.ValueOf(defaultAcceptToken)))
 		Expect(server.config.KeepAlive).To(BeFalse())
+		Expect(server.config.MaxIncomingPacketQueue).To(Equal(protocol.DefaultMaxServerUnprocessedPackets))
+		Expect(server.config.NumSessionTickets).To(Equal(protocol.DefaultNumSessionTickets))
 		// stop the listener
 		Expect(ln.Close()).To(Succeed())
 	})
@@ -126,6 +224,7 @@ var _ = Describe("Server", func() {
 			KeepAlive:         true,
 			StatelessResetKey: []byte("foobar"),
 			QuicTracer:        tracer,
+			NumSessionTickets: 3,
 		}
 		ln, err := Listen(conn, tlsConf, &config)
 		Expect(err).ToNot(HaveOccurred())
@@ -138,10 +237,120 @@ var _ = Describe("Server", func() {
 		Expect(server.config.KeepAlive).To(BeTrue())
 		Expect(server.config.StatelessResetKey).To(Equal([]byte("foobar")))
 		Expect(server.config.QuicTracer).To(Equal(tracer))
+		Expect(server.config.NumSessionTickets).To(Equal(3))
 		// stop the listener
 		Expect(ln.Close()).To(Succeed())
 	})
 
+	It("drops packets when the receive queue is full", func() {
+		blockAcceptToken := make(chan struct{})
+		tokenChecked := make(chan struct{}, 100)
+		ln, err := Listen(conn, tlsConf, &Config{
+			MaxIncomingPacketQueue: 5,
+			AcceptToken: func(net.Addr, *Token) bool {
+				tokenChecked <- struct{}{}
+				<-blockAcceptToken
+				return false
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		serv := ln.(*baseServer)
+		// the first packet blocks the run loop
+		serv.handlePacket(getInitialWithRandomDestConnID())
+		Eventually(tokenChecked).Should(Receive())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			for i := 0; i < 5+3; i++ {
+				serv.handlePacket(getInitialWithRandomDestConnID())
+			}
+		}()
+		Eventually(done).Should(BeClosed())
+		Expect(ln.DroppedPackets()).To(BeEquivalentTo(3))
+		Expect(ln.Stats().DroppedPackets).To(BeEquivalentTo(3))
+		close(blockAcceptToken)
+		// the queued packets are processed
+		for i := 0; i < 5; i++ {
+			Eventually(tokenChecked).Should(Receive())
+		}
+		Consistently(tokenChecked).ShouldNot(Receive())
+	})
+
+	It("uses a custom token generator", func() {
+		tokenChan := make(chan *Token, 1)
+		ln, err := Listen(conn, tlsConf, &Config{
+			TokenGenerator: &fakeTokenGenerator{},
+			AcceptToken: func(_ net.Addr, token *Token) bool {
+				tokenChan <- token
+				return false
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		hdr := &wire.Header{
+			IsLongHeader:     true,
+			Type:             protocol.PacketTypeInitial,
+			SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+			DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			Token:            []byte("retry:foobar"),
+			Version:          protocol.VersionTLS,
+		}
+		packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+		packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+		ln.(*baseServer).handlePacket(packet)
+		var token *Token
+		Eventually(tokenChan).Should(Receive(&token))
+		Expect(token).ToNot(BeNil())
+		Expect(token.IsRetryToken).To(BeTrue())
+		Expect(token.RemoteAddr).To(Equal("tenant-42"))
+		Expect(token.OriginalDestConnectionID).To(Equal(protocol.ConnectionID("foobar")))
+		// the token was not accepted, so the server sends a Retry
+		var write mockPacketConnWrite
+		Eventually(conn.dataWritten).Should(Receive(&write))
+		replyHdr := parseHeader(write.data)
+		Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+		Expect(replyHdr.Token).To(Equal(append([]byte("retry:"), hdr.DestConnectionID...)))
+	})
+
+	It("passes an empty token to the callback, if the custom token generator fails to decode the token", func() {
+		tokenChan := make(chan *Token, 1)
+		ln, err := Listen(conn, tlsConf, &Config{
+			TokenGenerator: &fakeTokenGenerator{},
+			AcceptToken: func(_ net.Addr, token *Token) bool {
+				tokenChan <- token
+				return false
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		packet := getPacket(&wire.Header{
+			IsLongHeader:     true,
+			Type:             protocol.PacketTypeInitial,
+			SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+			DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			Token:            []byte("invalid"),
+			Version:          protocol.VersionTLS,
+		}, make([]byte, protocol.MinInitialPacketSize))
+		packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+		ln.(*baseServer).handlePacket(packet)
+		var token *Token
+		Eventually(tokenChan).Should(Receive(&token))
+		Expect(token).To(BeNil())
+	})
+
+	It("uses the OOB conn of the packet handler map", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		ln, err := Listen(udpConn, tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		serv := ln.(*baseServer)
+		Expect(serv.oobConn).To(BeIdenticalTo(serv.sessionHandler.OOBConn()))
+	})
+
 	It("listens on a given address", func() {
 		addr := "127.0.0.1:13579"
 		ln, err := ListenAddr(addr, tlsConf, &Config{})
@@ -151,6 +360,93 @@ var _ = Describe("Server", func() {
 		Expect(ln.Close()).To(Succeed())
 	})
 
+	It("applies the socket control function to the socket created by ListenAddr", func() {
+		var network, address string
+		config := &Config{
+			SocketControl: func(n, a string, c syscall.RawConn) error {
+				network = n
+				address = a
+				return c.Control(func(uintptr) {})
+			},
+		}
+		ln, err := ListenAddr("127.0.0.1:0", tlsConf, config)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		Expect(network).To(Equal("udp4"))
+		Expect(address).To(Equal("127.0.0.1:0"))
+	})
+
+	It("returns the error of the socket control function", func() {
+		testErr := errors.New("socket control error")
+		config := &Config{
+			SocketControl: func(string, string, syscall.RawConn) error { return testErr },
+		}
+		_, err := ListenAddr("127.0.0.1:0", tlsConf, config)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, testErr)).To(BeTrue())
+	})
+
+	Context("address family", func() {
+		It("reports a dual-stack socket", func() {
+			ln, err := ListenAddr("[::]:0", tlsConf, nil)
+			if err != nil {
+				Skip("IPv6 not available")
+			}
+			defer ln.Close()
+			Expect(ln.Addr().(*net.UDPAddr).IP.To4()).To(BeNil())
+			Expect(ln.DualStack()).To(BeTrue())
+		})
+
+		It("reports an IPv4 socket", func() {
+			conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			ln, err := Listen(conn, tlsConf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			Expect(ln.Addr().(*net.UDPAddr).IP.To4()).ToNot(BeNil())
+			Expect(ln.DualStack()).To(BeFalse())
+		})
+
+		It("reports an IPv6-only socket", func() {
+			conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6unspecified})
+			if err != nil {
+				Skip("IPv6 not available")
+			}
+			defer conn.Close()
+			ln, err := Listen(conn, tlsConf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			Expect(ln.Addr().(*net.UDPAddr).IP.To4()).To(BeNil())
+			Expect(ln.DualStack()).To(BeFalse())
+		})
+
+		It("doesn't report conns that don't expose the socket as dual-stack", func() {
+			ln, err := Listen(conn, tlsConf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			Expect(ln.DualStack()).To(BeFalse())
+		})
+	})
+
+	It("listens on a resolved address", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0}
+		ln, err := ListenUDPAddr(addr, tlsConf, &Config{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ln.Addr().(*net.UDPAddr).IP.Equal(addr.IP)).To(BeTrue())
+		Expect(ln.Addr().(*net.UDPAddr).Port).ToNot(BeZero())
+		// stop the listener
+		Expect(ln.Close()).To(Succeed())
+	})
+
+	It("listens on a resolved address, for early sessions", func() {
+		ln, err := ListenUDPAddrEarly(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}, tlsConf, &Config{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ln.(*earlyServer).createdPacketConn).To(BeTrue())
+		// stop the listener
+		Expect(ln.Close()).To(Succeed())
+	})
+
 	It("errors if given an invalid address", func() {
 		addr := "127.0.0.1"
 		_, err := ListenAddr(addr, tlsConf, &Config{})
@@ -202,6 +498,57 @@ var _ = Describe("Server", func() {
 				}, make([]byte, protocol.MinInitialPacketSize-100),
 				))
 				Consistently(conn.dataWritten).ShouldNot(Receive())
+				Expect(serv.Stats().MalformedPacketsDropped).To(BeEquivalentTo(1))
+			})
+
+			It("uses the minimum Initial size of the version", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
+				serv.config.Versions = []protocol.VersionNumber{protocol.VersionTLS, protocol.Version2}
+				serv.minInitialSize = func(v protocol.VersionNumber) int {
+					if v == protocol.Version2 {
+						return 1000
+					}
+					return protocol.MinInitialPacketSize
+				}
+				getSmallInitial := func(v protocol.VersionNumber) *receivedPacket {
+					p := getPacket(&wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeInitial,
+						SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+						DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+						Version:          v,
+					}, nil)
+					p.data = append(p.data, make([]byte, protocol.MinInitialPacketSize-1-len(p.data))...)
+					p.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+					return p
+				}
+				// just under the minimum size for this version
+				serv.handlePacket(getSmallInitial(protocol.VersionTLS))
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+				Expect(serv.Stats().MalformedPacketsDropped).To(BeEquivalentTo(1))
+				// large enough for a version with a lower minimum size
+				serv.handlePacket(getSmallInitial(protocol.Version2))
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				Expect(parseHeader(write.data).Type).To(Equal(protocol.PacketTypeRetry))
+				Expect(serv.Stats().MalformedPacketsDropped).To(BeEquivalentTo(1))
+			})
+
+			It("drops packets that can't be parsed", func() {
+				data := make([]byte, protocol.MinInitialPacketSize)
+				data[0] = 0xc0 // Initial packet
+				binary.BigEndian.PutUint32(data[1:5], uint32(serv.config.Versions[0]))
+				data[5] = 8  // destination connection ID length
+				data[14] = 8 // source connection ID length
+				// data[23] is the token length (0)
+				data[24], data[25] = 0x7f, 0xff // the length field exceeds the packet size
+				serv.handlePacket(&receivedPacket{
+					remoteAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337},
+					data:       data,
+					buffer:     getPacketBuffer(),
+				})
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+				Expect(serv.Stats().MalformedPacketsDropped).To(BeEquivalentTo(1))
 			})
 
 			It("drops packets with a too short connection ID", func() {
@@ -225,6 +572,73 @@ var _ = Describe("Server", func() {
 				))
 			})
 
+			Context("tracing dropped packets", func() {
+				var tracer *packetDropTracer
+				remoteAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+
+				BeforeEach(func() {
+					tracer = newPacketDropTracer()
+					serv.config.QuicTracer = tracer
+				})
+
+				It("traces too small Initial packets", func() {
+					p := getPacket(&wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeInitial,
+						DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+						Version:          serv.config.Versions[0],
+					}, make([]byte, protocol.MinInitialPacketSize-100))
+					p.remoteAddr = remoteAddr
+					size := protocol.ByteCount(len(p.data))
+					serv.handlePacket(p)
+					Eventually(tracer.dropped).Should(Receive(Equal(droppedPacket{
+						remoteAddr: remoteAddr,
+						packetType: quictrace.PacketTypeInitial,
+						size:       size,
+						reason:     quictrace.PacketDropTooSmall,
+					})))
+				})
+
+				It("traces packets with an unsupported version, if Version Negotiation is disabled", func() {
+					serv.config.DisableVersionNegotiationPackets = true
+					p := getPacket(&wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeInitial,
+						SrcConnectionID:  protocol.ConnectionID{1, 2, 3, 4, 5},
+						DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+						Version:          0x42,
+					}, make([]byte, protocol.MinInitialPacketSize))
+					p.remoteAddr = remoteAddr
+					size := protocol.ByteCount(len(p.data))
+					serv.handlePacket(p)
+					Eventually(tracer.dropped).Should(Receive(Equal(droppedPacket{
+						remoteAddr: remoteAddr,
+						packetType: quictrace.PacketTypeNotDetermined,
+						size:       size,
+						reason:     quictrace.PacketDropUnsupportedVersion,
+					})))
+				})
+
+				It("traces non-Initial packets for unknown connections", func() {
+					p := getPacket(&wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeHandshake,
+						DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+						Version:          serv.config.Versions[0],
+					}, make([]byte, protocol.MinInitialPacketSize))
+					p.remoteAddr = remoteAddr
+					size := protocol.ByteCount(len(p.data))
+					serv.handlePacket(p)
+					Eventually(tracer.dropped).Should(Receive(Equal(droppedPacket{
+						remoteAddr: remoteAddr,
+						packetType: quictrace.PacketTypeHandshake,
+						size:       size,
+						reason:     quictrace.PacketDropUnknownConnectionID,
+					})))
+					Consistently(conn.dataWritten).ShouldNot(Receive())
+				})
+			})
+
 			It("decodes the token from the Token field", func() {
 				raddr := &net.UDPAddr{
 					IP:   net.IPv4(192, 168, 13, 37),
@@ -293,6 +707,21 @@ var _ = Describe("Server", func() {
 				Expect(hdr.DestConnectionID).To(Equal(srcConnID))
 				Expect(hdr.SrcConnectionID).To(Equal(destConnID))
 				Expect(hdr.SupportedVersions).ToNot(ContainElement(protocol.VersionNumber(0x42)))
+				Eventually(func() uint64 { return serv.Stats().VersionNegotiationPacketsSent }).Should(BeEquivalentTo(1))
+			})
+
+			It("doesn't send a Version Negotiation Packet, if disabled", func() {
+				serv.config.DisableVersionNegotiationPackets = true
+				packet := getPacket(&wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{1, 2, 3, 4, 5},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6},
+					Version:          0x42,
+				}, make([]byte, protocol.MinInitialPacketSize))
+				packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				serv.handlePacket(packet)
+				Consistently(conn.dataWritten).ShouldNot(Receive())
 			})
 
 			It("replies with a Retry packet, if a Token is required", func() {
@@ -315,7 +744,138 @@ var _ = Describe("Server", func() {
 				Expect(replyHdr.SrcConnectionID).ToNot(Equal(hdr.DestConnectionID))
 				Expect(replyHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
 				Expect(replyHdr.Token).ToNot(BeEmpty())
-				Expect(write.data[len(write.data)-16:]).To(Equal(handshake.GetRetryIntegrityTag(write.data[:len(write.data)-16], hdr.DestConnectionID)[:]))
+				Expect(write.data[len(write.data)-16:]).To(Equal(handshake.GetRetryIntegrityTag(write.data[:len(write.data)-16], hdr.DestConnectionID, hdr.Version)[:]))
+				Eventually(func() uint64 { return serv.Stats().RetriesSent }).Should(BeEquivalentTo(1))
+				Expect(serv.Stats().SessionsAccepted).To(BeZero())
+			})
+
+			Context("refusing connections", func() {
+				parseConnectionClose := func(data []byte, connID protocol.ConnectionID) *wire.ConnectionCloseFrame {
+					hdr, data, _, err := wire.ParsePacket(data, 0)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hdr.Type).To(Equal(protocol.PacketTypeInitial))
+					_, opener := handshake.NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.VersionTLS)
+					cs := mocks.NewMockCryptoSetup(mockCtrl)
+					cs.EXPECT().GetInitialOpener().Return(opener, nil)
+					unpacked, err := newPacketUnpacker(cs, hdr.Version).Unpack(hdr, time.Now(), data)
+					Expect(err).ToNot(HaveOccurred())
+					_, frame, err := wire.NewFrameParser(hdr.Version).ParseNext(bytes.NewReader(unpacked.data), protocol.EncryptionInitial)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(frame).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
+					return frame.(*wire.ConnectionCloseFrame)
+				}
+
+				var hdr *wire.Header
+
+				BeforeEach(func() {
+					serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+					serv.sessionQueueLen = protocol.MaxAcceptQueueSize
+					hdr = &wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeInitial,
+						SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+						DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+						Version:          protocol.VersionTLS,
+					}
+				})
+
+				It("refuses connections with CONNECTION_REFUSED when the accept queue is full", func() {
+					packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+					packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+					serv.handlePacket(packet)
+					var write mockPacketConnWrite
+					Eventually(conn.dataWritten).Should(Receive(&write))
+					Expect(write.to.String()).To(Equal("127.0.0.1:1337"))
+					ccf := parseConnectionClose(write.data, hdr.DestConnectionID)
+					Expect(ccf.IsApplicationError).To(BeFalse())
+					Expect(ccf.ErrorCode).To(Equal(qerr.ConnectionRefused))
+					Expect(ccf.ReasonPhrase).To(BeEmpty())
+				})
+
+				It("uses the error code and reason phrase returned by the callback", func() {
+					var clientAddr net.Addr
+					serv.config.RefuseConnection = func(addr net.Addr) (TransportErrorCode, string) {
+						clientAddr = addr
+						return TransportErrorCode(0x42), "blocked"
+					}
+					packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+					packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+					serv.handlePacket(packet)
+					var write mockPacketConnWrite
+					Eventually(conn.dataWritten).Should(Receive(&write))
+					Expect(clientAddr).To(Equal(packet.remoteAddr))
+					ccf := parseConnectionClose(write.data, hdr.DestConnectionID)
+					Expect(ccf.ErrorCode).To(BeEquivalentTo(0x42))
+					Expect(ccf.ReasonPhrase).To(Equal("blocked"))
+				})
+
+				Context("using the OnNewConnection callback", func() {
+					BeforeEach(func() {
+						serv.sessionQueueLen = 0
+						serv.newSession = func(
+							connection,
+							sessionRunner,
+							protocol.ConnectionID,
+							protocol.ConnectionID,
+							protocol.ConnectionID,
+							protocol.ConnectionID,
+							[16]byte,
+							*Config,
+							*tls.Config,
+							tokenGenerator,
+							bool,
+							utils.Logger,
+							protocol.VersionNumber,
+						) quicSession {
+							Fail("shouldn't create a session")
+							return nil
+						}
+					})
+
+					It("refuses connections with CONNECTION_REFUSED, using the error as the reason phrase", func() {
+						var clientAddr net.Addr
+						var initialHdr *InitialHeader
+						serv.config.AcceptToken = func(net.Addr, *Token) bool {
+							Fail("shouldn't validate the token")
+							return false
+						}
+						serv.config.OnNewConnection = func(addr net.Addr, h *InitialHeader) error {
+							clientAddr = addr
+							initialHdr = h
+							return errors.New("blocked")
+						}
+						packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+						packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+						serv.handlePacket(packet)
+						var write mockPacketConnWrite
+						Eventually(conn.dataWritten).Should(Receive(&write))
+						Expect(write.to.String()).To(Equal("127.0.0.1:1337"))
+						Expect(clientAddr).To(Equal(packet.remoteAddr))
+						Expect(initialHdr.Version).To(Equal(hdr.Version))
+						Expect(initialHdr.SrcConnectionID).To(Equal(hdr.SrcConnectionID))
+						Expect(initialHdr.DestConnectionID).To(Equal(hdr.DestConnectionID))
+						ccf := parseConnectionClose(write.data, hdr.DestConnectionID)
+						Expect(ccf.IsApplicationError).To(BeFalse())
+						Expect(ccf.ErrorCode).To(Equal(qerr.ConnectionRefused))
+						Expect(ccf.ReasonPhrase).To(Equal("blocked"))
+						Expect(serv.Stats().SessionsAccepted).To(BeZero())
+						Expect(serv.Stats().SessionsRefused).To(BeEquivalentTo(1))
+					})
+
+					It("uses the error code of a transport error returned by the callback", func() {
+						serv.config.OnNewConnection = func(net.Addr, *InitialHeader) error {
+							return qerr.Error(qerr.ErrorCode(0x42), "go away")
+						}
+						packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+						packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+						serv.handlePacket(packet)
+						var write mockPacketConnWrite
+						Eventually(conn.dataWritten).Should(Receive(&write))
+						ccf := parseConnectionClose(write.data, hdr.DestConnectionID)
+						Expect(ccf.ErrorCode).To(BeEquivalentTo(0x42))
+						Expect(ccf.ReasonPhrase).To(Equal("go away"))
+					})
+				})
 			})
 
 			It("creates a session, if no Token is required", func() {
@@ -347,7 +907,7 @@ var _ = Describe("Server", func() {
 					tokenP [16]byte,
 					_ *Config,
 					_ *tls.Config,
-					_ *handshake.TokenGenerator,
+					_ tokenGenerator,
 					enable0RTT bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
@@ -386,6 +946,186 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("uses the connection ID chosen by the application", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Version:          protocol.VersionTLS,
+				}
+				chosenConnID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+				serv.config.ChooseConnectionID = func(addr net.Addr, h *InitialHeader) (ConnectionID, error) {
+					Expect(addr).To(Equal(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}))
+					Expect(h.Version).To(Equal(protocol.VersionTLS))
+					Expect(h.DestConnectionID).To(Equal(hdr.DestConnectionID))
+					Expect(h.SrcConnectionID).To(Equal(hdr.SrcConnectionID))
+					Expect(h.Token).To(BeEmpty())
+					return chosenConnID, nil
+				}
+				p := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				p.remoteAddr = &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}
+				run := make(chan struct{})
+				phm.EXPECT().GetStatelessResetToken(chosenConnID)
+				sess := NewMockQuicSession(mockCtrl)
+				serv.newSession = func(
+					_ connection,
+					_ sessionRunner,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					srcConnID protocol.ConnectionID,
+					_ [16]byte,
+					_ *Config,
+					_ *tls.Config,
+					_ tokenGenerator,
+					_ bool,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					Expect(srcConnID).To(Equal(chosenConnID))
+					sess.EXPECT().handlePacket(p)
+					sess.EXPECT().run().Do(func() { close(run) })
+					sess.EXPECT().Context().Return(context.Background())
+					sess.EXPECT().HandshakeComplete().Return(context.Background())
+					return sess
+				}
+				phm.EXPECT().Add(hdr.DestConnectionID, sess).Return(true)
+				phm.EXPECT().Add(chosenConnID, sess).Return(true)
+				serv.handlePacket(p)
+				Eventually(run).Should(BeClosed())
+			})
+
+			It("drops the Initial if the application chooses a connection ID of the wrong length", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				serv.config.ChooseConnectionID = func(net.Addr, *InitialHeader) (ConnectionID, error) {
+					return protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, nil
+				}
+				serv.newSession = func(
+					_ connection,
+					_ sessionRunner,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ [16]byte,
+					_ *Config,
+					_ *tls.Config,
+					_ tokenGenerator,
+					_ bool,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					Fail("shouldn't create a session")
+					return nil
+				}
+				serv.handlePacket(getInitial(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}))
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+			})
+
+			It("uses the Retry connection ID chosen by the application", func() {
+				serv.config.AcceptToken = func(_ net.Addr, token *Token) bool { return token != nil && token.IsRetryToken }
+				origDestConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+				derivedConnID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+				remoteAddr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}
+				serv.config.ChooseRetryConnectionID = func(addr net.Addr, connID ConnectionID) (ConnectionID, error) {
+					Expect(addr).To(Equal(remoteAddr))
+					Expect(connID).To(Equal(origDestConnID))
+					return derivedConnID, nil
+				}
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: origDestConnID,
+					Version:          protocol.VersionTLS,
+				}
+				p := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				p.remoteAddr = remoteAddr
+				serv.handlePacket(p)
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				retryHdr := parseHeader(write.data)
+				Expect(retryHdr.Type).To(Equal(protocol.PacketTypeRetry))
+				Expect(retryHdr.SrcConnectionID).To(Equal(derivedConnID))
+
+				// the client's next Initial uses the connection ID from the Retry and carries the Retry token
+				hdr = &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: derivedConnID,
+					Token:            retryHdr.Token,
+					Version:          protocol.VersionTLS,
+				}
+				p = getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				p.remoteAddr = remoteAddr
+				run := make(chan struct{})
+				phm.EXPECT().GetStatelessResetToken(gomock.Any())
+				sess := NewMockQuicSession(mockCtrl)
+				serv.newSession = func(
+					_ connection,
+					_ sessionRunner,
+					origConnID protocol.ConnectionID,
+					clientDestConnID protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ [16]byte,
+					_ *Config,
+					_ *tls.Config,
+					_ tokenGenerator,
+					_ bool,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					Expect(origConnID).To(Equal(origDestConnID))
+					Expect(clientDestConnID).To(Equal(derivedConnID))
+					sess.EXPECT().handlePacket(p)
+					sess.EXPECT().run().Do(func() { close(run) })
+					sess.EXPECT().Context().Return(context.Background())
+					sess.EXPECT().HandshakeComplete().Return(context.Background())
+					return sess
+				}
+				phm.EXPECT().Add(derivedConnID, sess).Return(true)
+				phm.EXPECT().Add(gomock.Any(), sess).Return(true)
+				serv.handlePacket(p)
+				Eventually(run).Should(BeClosed())
+			})
+
+			It("doesn't send a Retry if the application chooses a Retry connection ID of the wrong length", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
+				serv.config.ChooseRetryConnectionID = func(net.Addr, ConnectionID) (ConnectionID, error) {
+					return protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, nil
+				}
+				serv.handlePacket(getInitial(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}))
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+				Expect(serv.Stats().RetriesSent).To(BeZero())
+			})
+
+			It("doesn't send a Retry if the application chooses the client's destination connection ID", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
+				serv.config.ConnectionIDLength = 9
+				serv.config.ChooseRetryConnectionID = func(_ net.Addr, connID ConnectionID) (ConnectionID, error) {
+					return connID, nil
+				}
+				serv.handlePacket(getInitial(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}))
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+				Expect(serv.Stats().RetriesSent).To(BeZero())
+			})
+
+			It("uses a non-empty Retry connection ID when using zero-length connection IDs", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
+				serv.config.ZeroLengthConnectionIDs = true
+				serv.config.ConnectionIDLength = 0
+				serv.handlePacket(getInitial(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}))
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				retryHdr := parseHeader(write.data)
+				Expect(retryHdr.Type).To(Equal(protocol.PacketTypeRetry))
+				Expect(retryHdr.SrcConnectionID.Len()).To(BeNumerically(">=", protocol.MinConnectionIDLenInitial))
+			})
+
 			It("only creates a single session for a duplicate Initial", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
 				var createdSession bool
@@ -400,7 +1140,7 @@ var _ = Describe("Server", func() {
 					_ [16]byte,
 					_ *Config,
 					_ *tls.Config,
-					_ *handshake.TokenGenerator,
+					_ tokenGenerator,
 					_ bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
@@ -429,7 +1169,7 @@ var _ = Describe("Server", func() {
 					_ [16]byte,
 					_ *Config,
 					_ *tls.Config,
-					_ *handshake.TokenGenerator,
+					_ tokenGenerator,
 					_ bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
@@ -470,6 +1210,119 @@ var _ = Describe("Server", func() {
 				Expect(rejectHdr.Version).To(Equal(hdr.Version))
 				Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
 				Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
+				stats := serv.Stats()
+				Expect(stats.SessionsAccepted).To(BeEquivalentTo(protocol.MaxAcceptQueueSize))
+				Expect(stats.SessionsRefused).To(BeEquivalentTo(1))
+				Expect(stats.RetriesSent).To(BeZero())
+				Expect(stats.VersionNegotiationPacketsSent).To(BeZero())
+				Expect(stats.MalformedPacketsDropped).To(BeZero())
+			})
+
+			It("rejects new connection attempts if the maximum number of active sessions is reached", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				serv.config.MaxActiveSessions = 2
+
+				var cancels []context.CancelFunc
+				serv.newSession = func(
+					_ connection,
+					runner sessionRunner,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ [16]byte,
+					_ *Config,
+					_ *tls.Config,
+					_ tokenGenerator,
+					_ bool,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					sess := NewMockQuicSession(mockCtrl)
+					sess.EXPECT().handlePacket(gomock.Any())
+					sess.EXPECT().run()
+					ctx, cancel := context.WithCancel(context.Background())
+					cancels = append(cancels, cancel)
+					sess.EXPECT().Context().Return(ctx)
+					// the handshake never completes
+					sess.EXPECT().HandshakeComplete().Return(context.Background()).MaxTimes(1)
+					return sess
+				}
+
+				phm.EXPECT().GetStatelessResetToken(gomock.Any()).Times(3)
+				phm.EXPECT().Add(gomock.Any(), gomock.Any()).Return(true).Times(6)
+
+				Expect(serv.handlePacketImpl(getInitialWithRandomDestConnID())).To(BeTrue())
+				Expect(serv.handlePacketImpl(getInitialWithRandomDestConnID())).To(BeTrue())
+				Expect(serv.Stats().ActiveSessions).To(Equal(2))
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+
+				p := getInitialWithRandomDestConnID()
+				hdr := parseHeader(p.data)
+				Expect(serv.handlePacketImpl(p)).To(BeFalse())
+				var reject mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&reject))
+				Expect(reject.to).To(Equal(p.remoteAddr))
+				rejectHdr := parseHeader(reject.data)
+				Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+				Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
+				stats := serv.Stats()
+				Expect(stats.SessionsAccepted).To(BeEquivalentTo(2))
+				Expect(stats.SessionsRefused).To(BeEquivalentTo(1))
+				Expect(stats.ActiveSessions).To(Equal(2))
+
+				// once a session is closed, new sessions are accepted again
+				cancels[0]()
+				Eventually(func() int { return serv.Stats().ActiveSessions }).Should(Equal(1))
+				Expect(serv.handlePacketImpl(getInitialWithRandomDestConnID())).To(BeTrue())
+				Expect(serv.Stats().ActiveSessions).To(Equal(2))
+				Expect(serv.Stats().SessionsAccepted).To(BeEquivalentTo(3))
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+				for _, cancel := range cancels {
+					cancel()
+				}
+			})
+
+			It("rejects new connection attempts if the connection ID is already in use", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				senderAddr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}
+				p := getInitialWithRandomDestConnID()
+				hdr := parseHeader(p.data)
+				serv.config.ZeroLengthConnectionIDs = true
+				serv.config.ConnectionIDLength = 0
+				serv.newSession = func(
+					_ connection,
+					runner sessionRunner,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ [16]byte,
+					_ *Config,
+					_ *tls.Config,
+					_ tokenGenerator,
+					_ bool,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					return NewMockQuicSession(mockCtrl)
+				}
+				phm.EXPECT().GetStatelessResetToken(gomock.Any())
+				gomock.InOrder(
+					phm.EXPECT().Add(hdr.DestConnectionID, gomock.Any()).Return(true),
+					phm.EXPECT().Add(protocol.ConnectionID{}, gomock.Any()).Return(false),
+					phm.EXPECT().Remove(hdr.DestConnectionID),
+				)
+				serv.handlePacket(p)
+				var reject mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&reject))
+				Expect(reject.to).To(Equal(senderAddr))
+				rejectHdr := parseHeader(reject.data)
+				Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+				Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
+				Expect(serv.Stats().SessionsRefused).To(BeEquivalentTo(1))
 			})
 
 			It("doesn't accept new sessions if they were closed in the mean time", func() {
@@ -489,7 +1342,7 @@ var _ = Describe("Server", func() {
 					_ [16]byte,
 					_ *Config,
 					_ *tls.Config,
-					_ *handshake.TokenGenerator,
+					_ tokenGenerator,
 					_ bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
@@ -592,7 +1445,7 @@ var _ = Describe("Server", func() {
 					_ [16]byte,
 					_ *Config,
 					_ *tls.Config,
-					_ *handshake.TokenGenerator,
+					_ tokenGenerator,
 					_ bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
@@ -604,7 +1457,7 @@ var _ = Describe("Server", func() {
 				}
 				phm.EXPECT().GetStatelessResetToken(gomock.Any())
 				phm.EXPECT().Add(gomock.Any(), gomock.Any()).Return(true).Times(2)
-				serv.createNewSession(&net.UDPAddr{}, nil, nil, nil, nil, protocol.VersionWhatever)
+				serv.createNewSession(&net.UDPAddr{}, nil, nil, nil, nil, nil, protocol.VersionWhatever)
 				Consistently(done).ShouldNot(BeClosed())
 				cancel() // complete the handshake
 				Eventually(done).Should(BeClosed())
@@ -654,7 +1507,7 @@ var _ = Describe("Server", func() {
 				_ [16]byte,
 				_ *Config,
 				_ *tls.Config,
-				_ *handshake.TokenGenerator,
+				_ tokenGenerator,
 				enable0RTT bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
@@ -667,7 +1520,7 @@ var _ = Describe("Server", func() {
 			}
 			phm.EXPECT().GetStatelessResetToken(gomock.Any())
 			phm.EXPECT().Add(gomock.Any(), sess).Return(true).Times(2)
-			serv.createNewSession(&net.UDPAddr{}, nil, nil, nil, nil, protocol.VersionWhatever)
+			serv.createNewSession(&net.UDPAddr{}, nil, nil, nil, nil, nil, protocol.VersionWhatever)
 			Consistently(done).ShouldNot(BeClosed())
 			close(ready)
 			Eventually(done).Should(BeClosed())
@@ -687,7 +1540,7 @@ var _ = Describe("Server", func() {
 				_ [16]byte,
 				_ *Config,
 				_ *tls.Config,
-				_ *handshake.TokenGenerator,
+				_ tokenGenerator,
 				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
@@ -746,7 +1599,7 @@ var _ = Describe("Server", func() {
 				_ [16]byte,
 				_ *Config,
 				_ *tls.Config,
-				_ *handshake.TokenGenerator,
+				_ tokenGenerator,
 				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
@@ -3,6 +3,7 @@ package quic
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"net"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
@@ -38,12 +40,17 @@ type streamManager interface {
 	OpenStream() (Stream, error)
 	OpenUniStream() (SendStream, error)
 	OpenStreamSync(context.Context) (Stream, error)
+	OpenStreamWithOptions(StreamOptions) (Stream, error)
 	OpenUniStreamSync(context.Context) (SendStream, error)
 	AcceptStream(context.Context) (Stream, error)
 	AcceptUniStream(context.Context) (ReceiveStream, error)
 	DeleteStream(protocol.StreamID) error
 	UpdateLimits(*handshake.TransportParameters) error
 	HandleMaxStreamsFrame(*wire.MaxStreamsFrame) error
+	AllowMoreIncomingStreams(bidi, uni uint64)
+	RefuseIncomingStreams(protocol.ApplicationErrorCode)
+	Handle0RTTRejection()
+	WaitForFlush(context.Context) error
 	CloseWithError(error)
 }
 
@@ -51,6 +58,7 @@ type cryptoStreamHandler interface {
 	RunHandshake()
 	ChangeConnectionID(protocol.ConnectionID)
 	SetLargest1RTTAcked(protocol.PacketNumber)
+	InitiateKeyUpdate()
 	DropHandshakeKeys()
 	io.Closer
 	ConnectionState() handshake.ConnectionState
@@ -58,6 +66,7 @@ type cryptoStreamHandler interface {
 
 type receivedPacket struct {
 	remoteAddr net.Addr
+	info       *packetInfo // the local address the packet was received on. Only set if the conn supports it.
 	rcvTime    time.Time
 	data       []byte
 
@@ -67,6 +76,7 @@ type receivedPacket struct {
 func (p *receivedPacket) Clone() *receivedPacket {
 	return &receivedPacket{
 		remoteAddr: p.remoteAddr,
+		info:       p.info,
 		rcvTime:    p.rcvTime,
 		data:       p.data,
 		buffer:     p.buffer,
@@ -86,15 +96,19 @@ type sessionRunner interface {
 
 type handshakeRunner struct {
 	onReceivedParams    func(*handshake.TransportParameters)
+	onAccepted0RTT      func()
 	onError             func(error)
 	dropKeys            func(protocol.EncryptionLevel)
 	onHandshakeComplete func()
+	onKeyUpdate         func(protocol.KeyPhase, bool)
 }
 
 func (r *handshakeRunner) OnReceivedParams(tp *handshake.TransportParameters) { r.onReceivedParams(tp) }
+func (r *handshakeRunner) OnAccepted0RTT()                                    { r.onAccepted0RTT() }
 func (r *handshakeRunner) OnError(e error)                                    { r.onError(e) }
 func (r *handshakeRunner) DropKeys(el protocol.EncryptionLevel)               { r.dropKeys(el) }
 func (r *handshakeRunner) OnHandshakeComplete()                               { r.onHandshakeComplete() }
+func (r *handshakeRunner) OnKeyUpdate(kp protocol.KeyPhase, remote bool)      { r.onKeyUpdate(kp, remote) }
 
 type closeError struct {
 	err       error
@@ -102,17 +116,37 @@ type closeError struct {
 	immediate bool
 }
 
+// A pathChallenge is sent to validate a new address of the peer.
+type pathChallenge struct {
+	addr     net.Addr
+	data     [8]byte
+	deadline time.Time
+	// lost is set when the packet carrying the most recent PATH_CHALLENGE was declared lost.
+	lost bool
+}
+
 var errCloseForRecreating = errors.New("closing session in order to recreate it")
 
+var errHandshakeNotComplete = errors.New("handshake not complete")
+
 // A Session is a QUIC session
 type session struct {
 	// Destination connection ID used during the handshake.
 	// Used to check source connection ID on incoming packets.
 	handshakeDestConnID protocol.ConnectionID
-	// if the server sends a Retry, this is the connection ID we used initially
+	// The client's original destination connection ID.
+	// On the server side, it is only set if the client presented a Retry token.
 	origDestConnID protocol.ConnectionID
+	// The source connection ID of the Retry packet, if the client received one.
+	retrySrcConnID protocol.ConnectionID
 	srcConnIDLen   int
 
+	// The connection IDs that are currently used.
+	// They are only written on the run loop, and read by the application.
+	connIDMutex       sync.Mutex
+	currentSrcConnID  protocol.ConnectionID
+	currentDestConnID protocol.ConnectionID
+
 	perspective    protocol.Perspective
 	initialVersion protocol.VersionNumber // if version negotiation is performed, this is the version we initially tried
 	version        protocol.VersionNumber
@@ -134,8 +168,8 @@ type session struct {
 	framer                framer
 	windowUpdateQueue     *windowUpdateQueue
 	connFlowController    flowcontrol.ConnectionFlowController
-	tokenStoreKey         string                    // only set for the client
-	tokenGenerator        *handshake.TokenGenerator // only set for the server
+	tokenStoreKey         string         // only set for the client
+	tokenGenerator        tokenGenerator // only set for the server
 
 	unpacker    unpacker
 	frameParser wire.FrameParser
@@ -145,13 +179,23 @@ type session struct {
 
 	receivedPackets  chan *receivedPacket
 	sendingScheduled chan struct{}
+	// rotateConnIDChan is used by the application to request a connection ID change.
+	// The connection ID manager is only accessed from the run loop.
+	rotateConnIDChan chan chan error
 
 	closeOnce sync.Once
 	// closeChan is used to notify the run loop that it should terminate
 	closeChan chan closeError
 
-	ctx                context.Context
-	ctxCancel          context.CancelFunc
+	drainMutex sync.Mutex
+	// drainTimer is set by the first call to Drain, and stopped when the session is closed.
+	drainTimer *time.Timer
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	// closeErr is the error that caused the session to close.
+	// It is set before ctx is canceled, and must only be read after that.
+	closeErr           error
 	handshakeCtx       context.Context
 	handshakeCtxCancel context.CancelFunc
 
@@ -161,9 +205,25 @@ type session struct {
 	earlySessionReadyChan chan struct{}
 	handshakeCompleteChan chan struct{} // is closed when the handshake completes
 	handshakeComplete     bool
+	// handshakeConfirmedChan is closed when the handshake is confirmed
+	handshakeConfirmedChan chan struct{}
+	handshakeConfirmed     bool
+	// receivedHandshakeDone is set when the client receives a HANDSHAKE_DONE frame
+	receivedHandshakeDone bool
 
 	receivedRetry       bool
 	receivedFirstPacket bool
+	sentFirstInitial    bool
+	// used0RTT is set when 0-RTT is used.
+	// On the client side, it is set when sending the ClientHello with restored transport parameters.
+	// On the server side, it is set on the handshake go routine when 0-RTT is accepted.
+	used0RTT utils.AtomicBool
+	// zeroRTTRejected is set when the server rejects 0-RTT.
+	// It is set on the handshake go routine, and read by the application.
+	zeroRTTRejected utils.AtomicBool
+	// usedRetry is set when the client's address was validated using a Retry.
+	// On the server side, it is set when the session is created.
+	usedRetry utils.AtomicBool
 
 	idleTimeout         time.Duration
 	sessionCreationTime time.Time
@@ -173,8 +233,20 @@ type session struct {
 	firstAckElicitingPacketAfterIdleSentTime time.Time
 	// pacingDeadline is the time when the next packet should be sent
 	pacingDeadline time.Time
+	// maxSendRate is the maximum send rate set by the application (in bytes per second).
+	// It is accessed atomically.
+	maxSendRate uint64
 
-	peerParams *handshake.TransportParameters
+	// peerParamsMutex protects peerParams when it is read by the application before the handshake completes
+	peerParamsMutex sync.Mutex
+	peerParams      *handshake.TransportParameters
+
+	// The largest packet number of a 1-RTT packet received.
+	// Only packets with a higher packet number can cause a path validation.
+	largestRcvd1RTTPacketNumber protocol.PacketNumber
+	// pathChallenge is the path validation that is currently in progress.
+	pathChallenge     *pathChallenge
+	sentPathChallenge bool
 
 	timer *utils.Timer
 	// keepAlivePingSent stores whether a keep alive PING is in flight.
@@ -184,6 +256,18 @@ type session struct {
 
 	traceCallback func(quictrace.Event)
 
+	userDataMutex sync.Mutex
+	userData      interface{}
+
+	incomingStreamsOnce    sync.Once
+	incomingStreamsChan    chan Stream
+	incomingUniStreamsOnce sync.Once
+	incomingUniStreamsChan chan ReceiveStream
+
+	// handshakeTiming is set on the run loop go routine, and read by the application.
+	handshakeTimingMutex sync.Mutex
+	handshakeTiming      HandshakeTiming
+
 	logID  string
 	logger utils.Logger
 }
@@ -202,33 +286,40 @@ var newSession = func(
 	statelessResetToken [16]byte,
 	conf *Config,
 	tlsConf *tls.Config,
-	tokenGenerator *handshake.TokenGenerator,
+	tokenGenerator tokenGenerator,
 	enable0RTT bool,
 	logger utils.Logger,
 	v protocol.VersionNumber,
 ) quicSession {
 	s := &session{
-		conn:                  conn,
-		config:                conf,
-		handshakeDestConnID:   destConnID,
-		srcConnIDLen:          srcConnID.Len(),
-		tokenGenerator:        tokenGenerator,
-		perspective:           protocol.PerspectiveServer,
-		handshakeCompleteChan: make(chan struct{}),
-		logger:                logger,
-		version:               v,
+		conn:                   conn,
+		config:                 conf,
+		handshakeDestConnID:    destConnID,
+		srcConnIDLen:           srcConnID.Len(),
+		currentSrcConnID:       srcConnID,
+		currentDestConnID:      destConnID,
+		tokenGenerator:         tokenGenerator,
+		perspective:            protocol.PerspectiveServer,
+		handshakeCompleteChan:  make(chan struct{}),
+		handshakeConfirmedChan: make(chan struct{}),
+		logger:                 logger,
+		version:                v,
 	}
 	if origDestConnID != nil {
+		// The original destination connection ID is only set if the client presented a Retry token.
+		s.usedRetry.Set(true)
 		s.logID = origDestConnID.String()
 	} else {
 		s.logID = destConnID.String()
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
+		s.config.ActiveConnectionIDLimit,
+		s.config.MaxRetiredConnectionIDs,
 		func(token [16]byte) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		runner.RetireResetToken,
-		s.queueControlFrame,
+		s.queueControlFrameWithCallbacks,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
@@ -241,25 +332,37 @@ var newSession = func(
 		s.queueControlFrame,
 	)
 	s.preSetup()
-	s.sentPacketHandler = ackhandler.NewSentPacketHandler(0, s.rttStats, s.traceCallback, s.logger)
+	s.sentPacketHandler = ackhandler.NewSentPacketHandler(0, s.rttStats, s.config.CongestionControl, !s.config.DisableHyStart, protocol.ByteCount(s.config.MinCongestionWindow), s.traceCallback, s.logger)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
 	oneRTTStream := newPostHandshakeCryptoStream(s.framer)
 	params := &handshake.TransportParameters{
-		InitialMaxStreamDataBidiLocal:  protocol.InitialMaxStreamData,
-		InitialMaxStreamDataBidiRemote: protocol.InitialMaxStreamData,
-		InitialMaxStreamDataUni:        protocol.InitialMaxStreamData,
-		InitialMaxData:                 protocol.InitialMaxData,
+		InitialMaxStreamDataBidiLocal:  s.initialMaxStreamData(),
+		InitialMaxStreamDataBidiRemote: s.initialMaxStreamData(),
+		InitialMaxStreamDataUni:        s.initialMaxStreamData(),
+		InitialMaxData:                 s.initialMaxData(),
 		MaxIdleTimeout:                 s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
-		AckDelayExponent:               protocol.AckDelayExponent,
-		DisableActiveMigration:         true,
+		MaxAckDelay:                    s.config.MaxAckDelay + protocol.TimerGranularity,
+		AckDelayExponent:               uint8(s.config.AckDelayExponent),
+		MinAckDelay:                    s.minAckDelay(),
+		DisableActiveMigration:         !s.config.EnableActiveMigration,
 		StatelessResetToken:            &statelessResetToken,
 		OriginalConnectionID:           origDestConnID,
-		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
+		ActiveConnectionIDLimit:        s.config.ActiveConnectionIDLimit,
+		MaxPacketSize:                  protocol.ByteCount(s.config.MaxUDPPayloadSize),
+	}
+	if v.UsesConnectionIDTransportParameters() {
+		params.InitialSourceConnectionID = srcConnID
+		if origDestConnID != nil {
+			params.RetrySourceConnectionID = &clientDestConnID
+		} else {
+			// Without a Retry, the original_destination_connection_id is always sent.
+			params.OriginalConnectionID = clientDestConnID
+		}
 	}
+	s.applyTransportParametersHook(params)
 	cs := handshake.NewCryptoSetupServer(
 		initialStream,
 		handshakeStream,
@@ -269,8 +372,10 @@ var newSession = func(
 		params,
 		&handshakeRunner{
 			onReceivedParams: s.processTransportParameters,
+			onAccepted0RTT:   func() { s.used0RTT.Set(true) },
 			onError:          s.closeLocal,
 			dropKeys:         s.dropEncryptionLevel,
+			onKeyUpdate:      s.traceKeyUpdate,
 			onHandshakeComplete: func() {
 				runner.Retire(clientDestConnID)
 				close(s.handshakeCompleteChan)
@@ -278,8 +383,10 @@ var newSession = func(
 		},
 		tlsConf,
 		enable0RTT,
+		s.config.NumSessionTickets,
 		s.rttStats,
 		logger,
+		s.version,
 	)
 	s.cryptoStreamHandler = cs
 	s.packer = newPacketPacker(
@@ -290,6 +397,7 @@ var newSession = func(
 		s.sentPacketHandler,
 		s.retransmissionQueue,
 		s.RemoteAddr(),
+		protocol.ByteCount(s.config.InitialMaxPacketSize),
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
@@ -316,23 +424,29 @@ var newClientSession = func(
 	v protocol.VersionNumber,
 ) quicSession {
 	s := &session{
-		conn:                  conn,
-		config:                conf,
-		handshakeDestConnID:   destConnID,
-		srcConnIDLen:          srcConnID.Len(),
-		perspective:           protocol.PerspectiveClient,
-		handshakeCompleteChan: make(chan struct{}),
-		logID:                 destConnID.String(),
-		logger:                logger,
-		initialVersion:        initialVersion,
-		version:               v,
+		conn:                   conn,
+		config:                 conf,
+		handshakeDestConnID:    destConnID,
+		origDestConnID:         destConnID,
+		srcConnIDLen:           srcConnID.Len(),
+		currentSrcConnID:       srcConnID,
+		currentDestConnID:      destConnID,
+		perspective:            protocol.PerspectiveClient,
+		handshakeCompleteChan:  make(chan struct{}),
+		handshakeConfirmedChan: make(chan struct{}),
+		logID:                  destConnID.String(),
+		logger:                 logger,
+		initialVersion:         initialVersion,
+		version:                v,
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
+		s.config.ActiveConnectionIDLimit,
+		s.config.MaxRetiredConnectionIDs,
 		func(token [16]byte) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		runner.RetireResetToken,
-		s.queueControlFrame,
+		s.queueControlFrameWithCallbacks,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
@@ -345,23 +459,29 @@ var newClientSession = func(
 		s.queueControlFrame,
 	)
 	s.preSetup()
-	s.sentPacketHandler = ackhandler.NewSentPacketHandler(initialPacketNumber, s.rttStats, s.traceCallback, s.logger)
+	s.sentPacketHandler = ackhandler.NewSentPacketHandler(initialPacketNumber, s.rttStats, s.config.CongestionControl, !s.config.DisableHyStart, protocol.ByteCount(s.config.MinCongestionWindow), s.traceCallback, s.logger)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
 	oneRTTStream := newPostHandshakeCryptoStream(s.framer)
 	params := &handshake.TransportParameters{
-		InitialMaxStreamDataBidiRemote: protocol.InitialMaxStreamData,
-		InitialMaxStreamDataBidiLocal:  protocol.InitialMaxStreamData,
-		InitialMaxStreamDataUni:        protocol.InitialMaxStreamData,
-		InitialMaxData:                 protocol.InitialMaxData,
+		InitialMaxStreamDataBidiRemote: s.initialMaxStreamData(),
+		InitialMaxStreamDataBidiLocal:  s.initialMaxStreamData(),
+		InitialMaxStreamDataUni:        s.initialMaxStreamData(),
+		InitialMaxData:                 s.initialMaxData(),
 		MaxIdleTimeout:                 s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
-		AckDelayExponent:               protocol.AckDelayExponent,
-		DisableActiveMigration:         true,
-		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
+		MaxAckDelay:                    s.config.MaxAckDelay + protocol.TimerGranularity,
+		AckDelayExponent:               uint8(s.config.AckDelayExponent),
+		MinAckDelay:                    s.minAckDelay(),
+		DisableActiveMigration:         !s.config.EnableActiveMigration,
+		ActiveConnectionIDLimit:        s.config.ActiveConnectionIDLimit,
+		MaxPacketSize:                  protocol.ByteCount(s.config.MaxUDPPayloadSize),
 	}
+	if v.UsesConnectionIDTransportParameters() {
+		params.InitialSourceConnectionID = srcConnID
+	}
+	s.applyTransportParametersHook(params)
 	cs, clientHelloWritten := handshake.NewCryptoSetupClient(
 		initialStream,
 		handshakeStream,
@@ -371,14 +491,17 @@ var newClientSession = func(
 		params,
 		&handshakeRunner{
 			onReceivedParams:    s.processTransportParameters,
+			onAccepted0RTT:      func() {}, // only called for the server
 			onError:             s.closeLocal,
 			dropKeys:            s.dropEncryptionLevel,
 			onHandshakeComplete: func() { close(s.handshakeCompleteChan) },
+			onKeyUpdate:         s.traceKeyUpdate,
 		},
 		tlsConf,
 		enable0RTT,
 		s.rttStats,
 		logger,
+		s.version,
 	)
 	s.clientHelloWritten = clientHelloWritten
 	s.cryptoStreamHandler = cs
@@ -392,6 +515,7 @@ var newClientSession = func(
 		s.sentPacketHandler,
 		s.retransmissionQueue,
 		s.RemoteAddr(),
+		protocol.ByteCount(s.config.InitialMaxPacketSize),
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
@@ -412,13 +536,22 @@ var newClientSession = func(
 }
 
 func (s *session) preSetup() {
-	s.sendQueue = newSendQueue(s.conn)
+	s.sendQueue = newSendQueue(s.conn, !s.config.DisableWriteRetries)
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
 	s.frameParser = wire.NewFrameParser(s.version)
 	s.rttStats = &congestion.RTTStats{}
-	s.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(s.rttStats, s.logger, s.version)
+	s.rttStats.SetRTTBounds(s.config.MinRTT, s.config.MaxRTT)
+	s.rttStats.SetDefaultRTT(s.config.InitialRTT)
+	s.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(
+		s.rttStats,
+		s.config.MaxAckDelay,
+		uint8(s.config.AckDelayExponent),
+		s.config.DisableDelayedAcks,
+		s.logger,
+		s.version,
+	)
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
-		protocol.InitialMaxData,
+		s.initialMaxData(),
 		protocol.ByteCount(s.config.MaxReceiveConnectionFlowControlWindow),
 		s.onHasConnectionWindowUpdate,
 		s.rttStats,
@@ -430,6 +563,7 @@ func (s *session) preSetup() {
 		s.newFlowController,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
+		s.config.MaxIncomingStreamsLimit,
 		s.perspective,
 		s.version,
 	)
@@ -437,6 +571,7 @@ func (s *session) preSetup() {
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
+	s.rotateConnIDChan = make(chan chan error)
 	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
@@ -445,12 +580,25 @@ func (s *session) preSetup() {
 	now := time.Now()
 	s.lastPacketReceivedTime = now
 	s.sessionCreationTime = now
+	s.setHandshakeTiming(func(t *HandshakeTiming) { t.StartTime = now })
 
 	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
 
 	if s.config.QuicTracer != nil {
+		metricsTracer, _ := s.config.QuicTracer.(quictrace.MetricsTracer)
 		s.traceCallback = func(ev quictrace.Event) {
-			s.config.QuicTracer.Trace(s.origDestConnID, ev)
+			switch ev.EventType {
+			case quictrace.MetricsUpdated:
+				if metricsTracer != nil {
+					metricsTracer.UpdatedMetrics(s.origDestConnID, ev.PacketNumber, ev.TransportState.CongestionWindow, ev.TransportState.BytesInFlight, ev.TransportState.PacketsInFlight)
+				}
+			case quictrace.CongestionStateUpdated:
+				if metricsTracer != nil {
+					metricsTracer.UpdatedCongestionState(s.origDestConnID, ev.TransportState.CongestionState())
+				}
+			default:
+				s.config.QuicTracer.Trace(s.origDestConnID, ev)
+			}
 		}
 	}
 }
@@ -471,7 +619,13 @@ func (s *session) run() error {
 		case zeroRTTParams := <-s.clientHelloWritten:
 			s.scheduleSending()
 			if zeroRTTParams != nil {
-				s.processTransportParameters(zeroRTTParams)
+				s.used0RTT.Set(true)
+				// The transport parameters remembered from the last session don't contain any connection IDs.
+				s.setTransportParameters(zeroRTTParams)
+				// Data queued in this callback is sent before DialEarly returns.
+				if s.config.OnEarlySession != nil {
+					s.config.OnEarlySession(s)
+				}
 				close(s.earlySessionReadyChan)
 			}
 		case closeErr := <-s.closeChan:
@@ -514,9 +668,19 @@ runLoop:
 			}
 		case <-s.handshakeCompleteChan:
 			s.handleHandshakeComplete()
+		case errChan := <-s.rotateConnIDChan:
+			errChan <- s.connIDManager.Rotate()
 		}
 
 		now := time.Now()
+		if s.config.MaxConnectionDuration > 0 && now.Sub(s.sessionCreationTime) >= s.config.MaxConnectionDuration {
+			s.closeLocal(qerr.Error(qerr.NoError, "maximum connection duration reached"))
+			continue
+		}
+		if s.pathChallenge != nil && !now.Before(s.pathChallenge.deadline) {
+			s.logger.Debugf("Validation of the path to %s timed out.", s.pathChallenge.addr)
+			s.abandonPathValidation()
+		}
 		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
 			// This could cause packets to be retransmitted.
 			// Check it before trying to send packets.
@@ -524,6 +688,9 @@ runLoop:
 				s.closeLocal(err)
 			}
 		}
+		if s.pathChallenge != nil && s.pathChallenge.lost {
+			s.sendPathChallenge()
+		}
 
 		var pacingDeadline time.Time
 		if s.pacingDeadline.IsZero() { // the timer didn't have a pacing deadline set
@@ -563,12 +730,47 @@ runLoop:
 	}
 
 	s.handleCloseError(closeErr)
+	s.drainMutex.Lock()
+	if s.drainTimer != nil {
+		s.drainTimer.Stop()
+	}
+	s.drainMutex.Unlock()
+	// release the user data, so it can be garbage collected
+	s.userDataMutex.Lock()
+	s.userData = nil
+	s.userDataMutex.Unlock()
 	s.logger.Infof("Connection %s closed.", s.logID)
 	s.cryptoStreamHandler.Close()
 	s.sendQueue.Close()
 	return closeErr.err
 }
 
+func (s *session) CloseCause() error {
+	select {
+	case <-s.ctx.Done():
+		return s.closeErr
+	default:
+		return nil
+	}
+}
+
+func (s *session) SetUserData(data interface{}) {
+	s.userDataMutex.Lock()
+	defer s.userDataMutex.Unlock()
+	// Don't store any data after the session was closed.
+	// It would never be released.
+	if s.ctx.Err() != nil {
+		return
+	}
+	s.userData = data
+}
+
+func (s *session) UserData() interface{} {
+	s.userDataMutex.Lock()
+	defer s.userDataMutex.Unlock()
+	return s.userData
+}
+
 // blocks until the early session can be used
 func (s *session) earlySessionReady() <-chan struct{} {
 	return s.earlySessionReadyChan
@@ -578,6 +780,58 @@ func (s *session) HandshakeComplete() context.Context {
 	return s.handshakeCtx
 }
 
+func (s *session) HandshakeConfirmed() <-chan struct{} {
+	return s.handshakeConfirmedChan
+}
+
+func (s *session) ZeroRTTRejected() bool {
+	return s.zeroRTTRejected.Get()
+}
+
+func (s *session) Used0RTT() bool {
+	return s.used0RTT.Get() && !s.zeroRTTRejected.Get()
+}
+
+func (s *session) UsedRetry() bool {
+	return s.usedRetry.Get()
+}
+
+func (s *session) HandshakeTiming() HandshakeTiming {
+	s.handshakeTimingMutex.Lock()
+	defer s.handshakeTimingMutex.Unlock()
+	return s.handshakeTiming
+}
+
+func (s *session) setHandshakeTiming(f func(*HandshakeTiming)) {
+	s.handshakeTimingMutex.Lock()
+	f(&s.handshakeTiming)
+	s.handshakeTimingMutex.Unlock()
+}
+
+func (s *session) PacingInterval() time.Duration {
+	return s.sentPacketHandler.PacingInterval()
+}
+
+func (s *session) InitiateKeyUpdate() error {
+	select {
+	case <-s.handshakeConfirmedChan:
+	default:
+		return errors.New("cannot initiate a key update before the handshake is confirmed")
+	}
+	s.cryptoStreamHandler.InitiateKeyUpdate()
+	return nil
+}
+
+func (s *session) InitialRTT() time.Duration {
+	return s.rttStats.InitialRTT()
+}
+
+func (s *session) SetMaxSendRate(bytesPerSecond uint64) {
+	atomic.StoreUint64(&s.maxSendRate, bytesPerSecond)
+	s.sentPacketHandler.SetMaxSendRate(bytesPerSecond)
+	s.scheduleSending()
+}
+
 func (s *session) Context() context.Context {
 	return s.ctx
 }
@@ -586,6 +840,106 @@ func (s *session) ConnectionState() ConnectionState {
 	return s.cryptoStreamHandler.ConnectionState()
 }
 
+func (s *session) SendPing(ctx context.Context) (time.Duration, error) {
+	rttChan := make(chan time.Duration, 1)
+	s.queuePing(rttChan)
+	select {
+	case rtt := <-rttChan:
+		return rtt, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-s.ctx.Done():
+		return 0, s.closeErr
+	}
+}
+
+func (s *session) RotateConnectionID() error {
+	errChan := make(chan error, 1)
+	select {
+	case s.rotateConnIDChan <- errChan:
+	case <-s.ctx.Done():
+		return s.closeErr
+	}
+	return <-errChan
+}
+
+func (s *session) RemoteTransportParameters() (*TransportParameters, error) {
+	// The peer's transport parameters are processed before the handshake completes,
+	// and they are not modified afterwards.
+	select {
+	case <-s.handshakeCtx.Done():
+	default:
+		return nil, errHandshakeNotComplete
+	}
+	return toTransportParameters(s.peerParams), nil
+}
+
+func (s *session) EarlyTransportParameters() *TransportParameters {
+	s.peerParamsMutex.Lock()
+	defer s.peerParamsMutex.Unlock()
+	if s.peerParams == nil {
+		return nil
+	}
+	return toTransportParameters(s.peerParams)
+}
+
+func toTransportParameters(params *handshake.TransportParameters) *TransportParameters {
+	return &TransportParameters{
+		MaxIdleTimeout:                 params.MaxIdleTimeout,
+		MaxPacketSize:                  uint64(params.MaxPacketSize),
+		InitialMaxData:                 uint64(params.InitialMaxData),
+		InitialMaxStreamDataBidiLocal:  uint64(params.InitialMaxStreamDataBidiLocal),
+		InitialMaxStreamDataBidiRemote: uint64(params.InitialMaxStreamDataBidiRemote),
+		InitialMaxStreamDataUni:        uint64(params.InitialMaxStreamDataUni),
+		MaxBidiStreams:                 int64(params.MaxBidiStreamNum),
+		MaxUniStreams:                  int64(params.MaxUniStreamNum),
+		AckDelayExponent:               params.AckDelayExponent,
+		MaxAckDelay:                    params.MaxAckDelay,
+		DisableActiveMigration:         params.DisableActiveMigration,
+		ActiveConnectionIDLimit:        params.ActiveConnectionIDLimit,
+	}
+}
+
+// applyTransportParametersHook lets the application modify the transport parameters we send.
+func (s *session) applyTransportParametersHook(params *handshake.TransportParameters) {
+	if s.config.TransportParametersHook == nil {
+		return
+	}
+	p := toTransportParameters(params)
+	s.config.TransportParametersHook(p)
+	params.MaxIdleTimeout = p.MaxIdleTimeout
+	params.MaxPacketSize = protocol.ByteCount(p.MaxPacketSize)
+	params.InitialMaxData = protocol.ByteCount(p.InitialMaxData)
+	params.InitialMaxStreamDataBidiLocal = protocol.ByteCount(p.InitialMaxStreamDataBidiLocal)
+	params.InitialMaxStreamDataBidiRemote = protocol.ByteCount(p.InitialMaxStreamDataBidiRemote)
+	params.InitialMaxStreamDataUni = protocol.ByteCount(p.InitialMaxStreamDataUni)
+	params.MaxBidiStreamNum = protocol.StreamNum(p.MaxBidiStreams)
+	params.MaxUniStreamNum = protocol.StreamNum(p.MaxUniStreams)
+	params.AckDelayExponent = p.AckDelayExponent
+	params.MaxAckDelay = p.MaxAckDelay
+	params.DisableActiveMigration = p.DisableActiveMigration
+	params.ActiveConnectionIDLimit = p.ActiveConnectionIDLimit
+}
+
+// queuePing queues a PING frame.
+// When the PING is acknowledged, the RTT sample taken from the ACK is sent on rttChan.
+// If the packet carrying the PING is lost, a new PING is queued.
+func (s *session) queuePing(rttChan chan<- time.Duration) {
+	s.framer.QueueControlFrameWithCallbacks(ackhandler.Frame{
+		Frame: &wire.PingFrame{},
+		// The RTT is updated before the OnAcked callbacks are called,
+		// so the latest RTT is the sample taken from the ACK that acknowledged the PING.
+		OnAcked: func(wire.Frame) {
+			select {
+			case rttChan <- s.rttStats.LatestRTT():
+			default:
+			}
+		},
+		OnLost: func(wire.Frame) { s.queuePing(rttChan) },
+	})
+	s.scheduleSending()
+}
+
 // Time when the next keep-alive packet should be sent.
 // It returns a zero time if no keep-alive should be sent.
 func (s *session) nextKeepAliveTime() time.Time {
@@ -616,6 +970,12 @@ func (s *session) maybeResetTimer() {
 	if !s.pacingDeadline.IsZero() {
 		deadline = utils.MinTime(deadline, s.pacingDeadline)
 	}
+	if s.pathChallenge != nil {
+		deadline = utils.MinTime(deadline, s.pathChallenge.deadline)
+	}
+	if s.config.MaxConnectionDuration > 0 {
+		deadline = utils.MinTime(deadline, s.sessionCreationTime.Add(s.config.MaxConnectionDuration))
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -626,6 +986,7 @@ func (s *session) idleTimeoutStartTime() time.Time {
 
 func (s *session) handleHandshakeComplete() {
 	s.handshakeComplete = true
+	s.setHandshakeTiming(func(t *HandshakeTiming) { t.CompleteTime = time.Now() })
 	s.handshakeCompleteChan = nil // prevent this case from ever being selected again
 	s.handshakeCtxCancel()
 
@@ -640,10 +1001,36 @@ func (s *session) handleHandshakeComplete() {
 		s.queueControlFrame(&wire.NewTokenFrame{Token: token})
 		s.cryptoStreamHandler.DropHandshakeKeys()
 		s.queueControlFrame(&wire.HandshakeDoneFrame{})
+		s.handleHandshakeConfirmed()
+	} else if s.receivedHandshakeDone {
+		// The HANDSHAKE_DONE frame was processed before the handshake completion.
+		s.handleHandshakeConfirmed()
+	}
+
+	// If both endpoints support the ACK frequency extension, ask the peer to send fewer ACKs.
+	if s.config.EnableACKFrequency && s.peerParams != nil && s.peerParams.MinAckDelay != nil {
+		s.queueControlFrame(&wire.AckFrequencyFrame{
+			PacketTolerance:   protocol.AckFrequencyPacketTolerance,
+			UpdateMaxAckDelay: s.peerParams.MaxAckDelay,
+		})
 	}
 }
 
 func (s *session) handlePacketImpl(rp *receivedPacket) bool {
+	if protocol.ByteCount(len(rp.data)) > protocol.ByteCount(s.config.MaxUDPPayloadSize) {
+		s.logger.Debugf("Dropping packet exceeding our max_packet_size (%d bytes, maximum %d bytes)", len(rp.data), s.config.MaxUDPPayloadSize)
+		rp.buffer.Release()
+		return false
+	}
+	// We sent the disable_active_migration transport parameter.
+	// The client is not allowed to migrate, so we drop packets arriving on a new path.
+	if s.perspective == protocol.PerspectiveServer && !s.config.EnableActiveMigration &&
+		rp.remoteAddr != nil && !sameAddr(rp.remoteAddr, s.conn.RemoteAddr()) {
+		s.logger.Debugf("Dropping packet from %s. Active migration is disabled.", rp.remoteAddr)
+		rp.buffer.Release()
+		return false
+	}
+
 	var counter uint8
 	var lastConnID protocol.ConnectionID
 	var processed bool
@@ -697,17 +1084,24 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 	}()
 
 	if hdr.Type == protocol.PacketTypeRetry {
-		return s.handleRetryPacket(hdr, p.data)
+		if s.perspective == protocol.PerspectiveServer {
+			s.logger.Debugf("Ignoring Retry.")
+			s.tracePacketDropped(p, hdr, quictrace.PacketDropUnexpectedPacketType)
+			return false
+		}
+		return s.handleRetryPacket(p, hdr)
 	}
 
 	// The server can change the source connection ID with the first Handshake packet.
 	// After this, all packets with a different source connection have to be ignored.
 	if s.receivedFirstPacket && hdr.IsLongHeader && !hdr.SrcConnectionID.Equal(s.handshakeDestConnID) {
 		s.logger.Debugf("Dropping %s packet (%d bytes) with unexpected source connection ID: %s (expected %s)", hdr.PacketType(), len(p.data), hdr.SrcConnectionID, s.handshakeDestConnID)
+		s.tracePacketDropped(p, hdr, quictrace.PacketDropUnknownConnectionID)
 		return false
 	}
 	// drop 0-RTT packets, if we are a client
 	if s.perspective == protocol.PerspectiveClient && hdr.Type == protocol.PacketType0RTT {
+		s.tracePacketDropped(p, hdr, quictrace.PacketDropUnexpectedPacketType)
 		return false
 	}
 
@@ -716,6 +1110,7 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		switch err {
 		case handshake.ErrKeysDropped:
 			s.logger.Debugf("Dropping %s packet (%d bytes) because we already dropped the keys.", hdr.PacketType(), len(p.data))
+			s.tracePacketDropped(p, hdr, quictrace.PacketDropDecryptionFailed)
 		case handshake.ErrKeysNotYetAvailable:
 			// Sealer for this encryption level not yet available.
 			// Try again later.
@@ -727,10 +1122,17 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 			// This might be a packet injected by an attacker.
 			// Drop it.
 			s.logger.Debugf("Dropping %s packet (%d bytes) that could not be unpacked. Error: %s", hdr.PacketType(), len(p.data), err)
+			s.tracePacketDropped(p, hdr, quictrace.PacketDropDecryptionFailed)
 		}
 		return false
 	}
 
+	if s.receivedPacketHandler.IsPotentiallyDuplicate(packet.packetNumber, packet.encryptionLevel) {
+		s.logger.Debugf("Dropping (potentially) duplicate %s packet %#x (%d bytes).", hdr.PacketType(), packet.packetNumber, len(p.data))
+		s.tracePacketDropped(p, hdr, quictrace.PacketDropDuplicate)
+		return false
+	}
+
 	if s.logger.Debug() {
 		s.logger.Debugf("<- Reading packet %#x (%d bytes) for connection %s, %s", packet.packetNumber, len(p.data), hdr.DestConnectionID, packet.encryptionLevel)
 		packet.hdr.Log(s.logger)
@@ -740,40 +1142,85 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		s.closeLocal(err)
 		return false
 	}
+	if packet.encryptionLevel == protocol.Encryption1RTT && packet.packetNumber >= s.largestRcvd1RTTPacketNumber {
+		s.largestRcvd1RTTPacketNumber = packet.packetNumber
+		// Only the server validates new addresses.
+		// The client doesn't expect the server's address to change.
+		if s.perspective == protocol.PerspectiveServer && s.config.EnableActiveMigration &&
+			p.remoteAddr != nil && !sameAddr(p.remoteAddr, s.conn.RemoteAddr()) {
+			s.maybeStartPathValidation(p.remoteAddr)
+		}
+	}
+	if !hdr.DestConnectionID.Equal(s.currentSrcConnID) {
+		s.connIDMutex.Lock()
+		s.currentSrcConnID = hdr.DestConnectionID
+		s.connIDMutex.Unlock()
+	}
 	return true
 }
 
-func (s *session) handleRetryPacket(hdr *wire.Header, data []byte) bool /* was this a valid Retry */ {
-	if s.perspective == protocol.PerspectiveServer {
-		s.logger.Debugf("Ignoring Retry.")
-		return false
+func (s *session) traceKeyUpdate(phase protocol.KeyPhase, remote bool) {
+	if tracer, ok := s.config.QuicTracer.(quictrace.KeyUpdateTracer); ok {
+		tracer.UpdatedKey(s.origDestConnID, phase, remote)
+	}
+}
+
+func (s *session) tracePacketDropped(p *receivedPacket, hdr *wire.Header, reason quictrace.PacketDropReason) {
+	if tracer, ok := s.config.QuicTracer.(quictrace.PacketDropTracer); ok {
+		tracer.DroppedPacket(p.remoteAddr, getTracePacketType(hdr), protocol.ByteCount(len(p.data)), reason)
+	}
+}
+
+func getTracePacketType(hdr *wire.Header) quictrace.PacketType {
+	if !hdr.IsLongHeader {
+		return quictrace.PacketType1RTT
+	}
+	switch hdr.Type {
+	case protocol.PacketTypeInitial:
+		return quictrace.PacketTypeInitial
+	case protocol.PacketTypeHandshake:
+		return quictrace.PacketTypeHandshake
+	case protocol.PacketTypeRetry:
+		return quictrace.PacketTypeRetry
+	case protocol.PacketType0RTT:
+		return quictrace.PacketType0RTT
+	default:
+		return quictrace.PacketTypeNotDetermined
 	}
+}
+
+func (s *session) handleRetryPacket(p *receivedPacket, hdr *wire.Header) bool /* was this a valid Retry */ {
 	if s.receivedFirstPacket {
 		s.logger.Debugf("Ignoring Retry, since we already received a packet.")
+		s.tracePacketDropped(p, hdr, quictrace.PacketDropUnexpectedPacketType)
 		return false
 	}
 	(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
 	destConnID := s.connIDManager.Get()
 	if hdr.SrcConnectionID.Equal(destConnID) {
 		s.logger.Debugf("Ignoring Retry, since the server didn't change the Source Connection ID.")
+		s.tracePacketDropped(p, hdr, quictrace.PacketDropUnexpectedPacketType)
 		return false
 	}
-	tag := handshake.GetRetryIntegrityTag(data[:len(data)-16], destConnID)
-	if !bytes.Equal(data[len(data)-16:], tag[:]) {
+	if !handshake.VerifyRetryIntegrityTag(p.data, destConnID, hdr.Version) {
 		s.logger.Debugf("Ignoring spoofed Retry. Integrity Tag doesn't match.")
+		s.tracePacketDropped(p, hdr, quictrace.PacketDropDecryptionFailed)
 		return false
 	}
 	// If a token is already set, this means that we already received a Retry from the server.
 	// Ignore this Retry packet.
 	if s.receivedRetry {
 		s.logger.Debugf("Ignoring Retry, since a Retry was already received.")
+		s.tracePacketDropped(p, hdr, quictrace.PacketDropUnexpectedPacketType)
 		return false
 	}
 	s.logger.Debugf("<- Received Retry")
 	s.logger.Debugf("Switching destination connection ID to: %s", hdr.SrcConnectionID)
-	s.origDestConnID = s.handshakeDestConnID
 	newDestConnID := hdr.SrcConnectionID
+	s.retrySrcConnID = newDestConnID
 	s.receivedRetry = true
+	s.usedRetry.Set(true)
+	s.setHandshakeTiming(func(t *HandshakeTiming) { t.RetryReceivedTime = time.Now() })
 	if err := s.sentPacketHandler.ResetForRetry(); err != nil {
 		s.closeLocal(err)
 		return false
@@ -812,7 +1259,7 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 	r := bytes.NewReader(packet.data)
 	var isAckEliciting bool
 	for {
-		frame, err := s.frameParser.ParseNext(r, packet.encryptionLevel)
+		frameType, frame, err := s.frameParser.ParseNext(r, packet.encryptionLevel)
 		if err != nil {
 			return err
 		}
@@ -826,7 +1273,7 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 			frames = append(frames, frame)
 		}
 		if err := s.handleFrame(frame, packet.packetNumber, packet.encryptionLevel); err != nil {
-			return err
+			return addFrameType(err, frameType)
 		}
 	}
 
@@ -875,8 +1322,7 @@ func (s *session) handleFrame(f wire.Frame, pn protocol.PacketNumber, encLevel p
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		err = s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -885,12 +1331,34 @@ func (s *session) handleFrame(f wire.Frame, pn protocol.PacketNumber, encLevel p
 		err = s.handleRetireConnectionIDFrame(frame)
 	case *wire.HandshakeDoneFrame:
 		err = s.handleHandshakeDoneFrame()
+	case *wire.AckFrequencyFrame:
+		err = s.handleAckFrequencyFrame(frame)
+	case *wire.ImmediateAckFrame:
+		err = s.handleImmediateAckFrame()
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
 	return err
 }
 
+// addFrameType adds the type of the frame that triggered a transport error,
+// such that it is sent to the peer in the CONNECTION_CLOSE frame.
+func addFrameType(err error, frameType uint64) error {
+	var quicErr *qerr.QuicError
+	switch e := err.(type) {
+	case *qerr.QuicError:
+		if e.IsApplicationError() || e.FrameType != 0 {
+			return err
+		}
+		quicErr = e
+	case qerr.ErrorCode:
+		quicErr = qerr.Error(e, "")
+	default:
+		return err
+	}
+	return qerr.ErrorWithFrameType(quicErr.ErrorCode, frameType, quicErr.ErrorMessage)
+}
+
 // handlePacket is called by the server with a new packet
 func (s *session) handlePacket(p *receivedPacket) {
 	// Discard packets once the amount of queued packets is larger than
@@ -985,6 +1453,106 @@ func (s *session) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+func (s *session) handlePathResponseFrame(frame *wire.PathResponseFrame) error {
+	if !s.sentPathChallenge {
+		return errors.New("unexpected PATH_RESPONSE frame")
+	}
+	// Ignore PATH_RESPONSEs that don't match the current PATH_CHALLENGE.
+	// They might be late responses for a path validation that was abandoned.
+	if s.pathChallenge == nil || frame.Data != s.pathChallenge.data {
+		return nil
+	}
+	oldAddr := s.conn.RemoteAddr()
+	newAddr := s.pathChallenge.addr
+	s.pathChallenge = nil
+	s.logger.Infof("Validated the path to %s. Migrating the connection.", newAddr)
+	s.conn.SetCurrentRemoteAddr(newAddr)
+	// The RTT of the new path might be very different.
+	// There's no need to reset the RTT estimate if only the port changed, e.g. due to a NAT rebinding.
+	if !sameIP(oldAddr, newAddr) {
+		s.rttStats.OnConnectionMigration()
+	}
+	if s.config.OnPathChange != nil {
+		s.config.OnPathChange(oldAddr, newAddr, true)
+	}
+	return nil
+}
+
+// maybeStartPathValidation sends a PATH_CHALLENGE to a new address of the peer.
+// Packets are only sent to the new address after the peer responded with a matching PATH_RESPONSE.
+func (s *session) maybeStartPathValidation(addr net.Addr) {
+	if s.pathChallenge != nil {
+		if sameAddr(s.pathChallenge.addr, addr) {
+			return
+		}
+		s.logger.Debugf("Abandoning validation of the path to %s.", s.pathChallenge.addr)
+		s.abandonPathValidation()
+	}
+	s.logger.Debugf("Received a packet from a new address %s. Validating the path.", addr)
+	s.sentPathChallenge = true
+	s.pathChallenge = &pathChallenge{
+		addr:     addr,
+		deadline: time.Now().Add(3 * s.rttStats.PTO(true)),
+	}
+	s.sendPathChallenge()
+}
+
+// sendPathChallenge sends a PATH_CHALLENGE with a fresh payload for the path validation in progress.
+// If the packet is lost, a new PATH_CHALLENGE is sent, until the path validation times out.
+func (s *session) sendPathChallenge() {
+	challenge := s.pathChallenge
+	challenge.lost = false
+	if _, err := rand.Read(challenge.data[:]); err != nil {
+		s.closeLocal(err)
+		return
+	}
+	data := challenge.data
+	packet, err := s.packer.PackPathChallenge(&wire.PathChallengeFrame{Data: data}, func(wire.Frame) {
+		// Earlier PATH_CHALLENGEs were already replaced by a new one.
+		if s.pathChallenge == challenge && challenge.data == data {
+			challenge.lost = true
+		}
+	})
+	if err != nil {
+		s.closeLocal(err)
+		return
+	}
+	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.retransmissionQueue))
+	s.logPacket(packet)
+	if err := s.conn.WriteTo(packet.raw, challenge.addr); err != nil {
+		s.logger.Debugf("Error sending PATH_CHALLENGE to %s: %s", challenge.addr, err)
+	}
+	packet.buffer.Release()
+}
+
+// sameAddr says if two addresses are equal, without allocating for UDP addresses
+func sameAddr(a, b net.Addr) bool {
+	udpA, okA := a.(*net.UDPAddr)
+	udpB, okB := b.(*net.UDPAddr)
+	if !okA || !okB {
+		return a.String() == b.String()
+	}
+	return udpA.Port == udpB.Port && udpA.IP.Equal(udpB.IP) && udpA.Zone == udpB.Zone
+}
+
+// sameIP says if two UDP addresses have the same IP, i.e. if they differ at most in the port
+func sameIP(a, b net.Addr) bool {
+	udpA, okA := a.(*net.UDPAddr)
+	udpB, okB := b.(*net.UDPAddr)
+	if !okA || !okB {
+		return false
+	}
+	return udpA.IP.Equal(udpB.IP)
+}
+
+func (s *session) abandonPathValidation() {
+	addr := s.pathChallenge.addr
+	s.pathChallenge = nil
+	if s.config.OnPathChange != nil {
+		s.config.OnPathChange(s.conn.RemoteAddr(), addr, false)
+	}
+}
+
 func (s *session) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return qerr.Error(qerr.ProtocolViolation, "Received NEW_TOKEN frame from the client.")
@@ -1008,6 +1576,39 @@ func (s *session) handleHandshakeDoneFrame() error {
 		return qerr.Error(qerr.ProtocolViolation, "received a HANDSHAKE_DONE frame")
 	}
 	s.cryptoStreamHandler.DropHandshakeKeys()
+	s.receivedHandshakeDone = true
+	if s.handshakeComplete {
+		s.handleHandshakeConfirmed()
+	}
+	return nil
+}
+
+// handleHandshakeConfirmed is called when the handshake is confirmed.
+// The server confirms the handshake when it completes, the client when it receives the HANDSHAKE_DONE frame.
+func (s *session) handleHandshakeConfirmed() {
+	if s.handshakeConfirmed {
+		return
+	}
+	s.handshakeConfirmed = true
+	close(s.handshakeConfirmedChan)
+}
+
+func (s *session) handleAckFrequencyFrame(frame *wire.AckFrequencyFrame) error {
+	if !s.config.EnableACKFrequency {
+		return qerr.Error(qerr.ProtocolViolation, "received an ACK_FREQUENCY frame, but didn't enable the ACK frequency extension")
+	}
+	if frame.UpdateMaxAckDelay < protocol.MinAckDelay {
+		return qerr.Error(qerr.ProtocolViolation, fmt.Sprintf("requested max ack delay (%s) smaller than min_ack_delay (%s)", frame.UpdateMaxAckDelay, protocol.MinAckDelay))
+	}
+	s.receivedPacketHandler.ReceivedAckFrequency(frame)
+	return nil
+}
+
+func (s *session) handleImmediateAckFrame() error {
+	if !s.config.EnableACKFrequency {
+		return qerr.Error(qerr.ProtocolViolation, "received an IMMEDIATE_ACK frame, but didn't enable the ACK frequency extension")
+	}
+	s.receivedPacketHandler.ReceivedImmediateAck()
 	return nil
 }
 
@@ -1079,6 +1680,19 @@ func (s *session) CloseWithError(code protocol.ApplicationErrorCode, desc string
 	return nil
 }
 
+func (s *session) CloseGracefully(ctx context.Context, code protocol.ApplicationErrorCode, desc string) error {
+	err := s.streamsMap.WaitForFlush(ctx)
+	closeErr := qerr.ApplicationError(qerr.ErrorCode(code), desc)
+	s.closeLocal(closeErr)
+	<-s.ctx.Done()
+	// If the session was closed by something else while we were waiting, e.g. by the peer or by an idle timeout,
+	// all streams were completed, and the data written to them might have been lost.
+	if s.closeErr != error(closeErr) {
+		return s.closeErr
+	}
+	return err
+}
+
 func (s *session) handleCloseError(closeErr closeError) {
 	if closeErr.err == nil {
 		closeErr.err = qerr.ApplicationError(0, "")
@@ -1090,7 +1704,14 @@ func (s *session) handleCloseError(closeErr closeError) {
 		quicErr = qerr.ToQuicError(closeErr.err)
 	}
 
-	s.streamsMap.CloseWithError(quicErr)
+	var streamErr error = quicErr
+	// Pass stateless resets on to the application unchanged,
+	// so that they can be distinguished from other errors.
+	if resetErr, ok := closeErr.err.(*StatelessResetError); ok {
+		streamErr = resetErr
+	}
+	s.closeErr = streamErr
+	s.streamsMap.CloseWithError(streamErr)
 	s.connIDManager.Close()
 
 	// If this is a remote close we're done here
@@ -1111,19 +1732,30 @@ func (s *session) handleCloseError(closeErr closeError) {
 }
 
 func (s *session) dropEncryptionLevel(encLevel protocol.EncryptionLevel) {
+	// 0-RTT keys are only dropped by the client when the server rejects 0-RTT.
+	// Data sent in 0-RTT packets is then queued for retransmission with 1-RTT keys,
+	// unless it was sent on a non-idempotent stream. These streams are reset.
+	if encLevel == protocol.Encryption0RTT {
+		s.zeroRTTRejected.Set(true)
+		s.streamsMap.Handle0RTTRejection()
+	}
 	s.sentPacketHandler.DropPackets(encLevel)
 	s.receivedPacketHandler.DropPackets(encLevel)
 }
 
 func (s *session) processTransportParameters(params *handshake.TransportParameters) {
-	// check the Retry token
-	if s.perspective == protocol.PerspectiveClient && !params.OriginalConnectionID.Equal(s.origDestConnID) {
-		s.closeLocal(qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected original_connection_id to equal %s, is %s", s.origDestConnID, params.OriginalConnectionID)))
+	if err := s.checkTransportParameters(params); err != nil {
+		s.closeLocal(err)
 		return
 	}
+	s.setTransportParameters(params)
+}
 
+func (s *session) setTransportParameters(params *handshake.TransportParameters) {
 	s.logger.Debugf("Processed Transport Parameters: %s", params)
+	s.peerParamsMutex.Lock()
 	s.peerParams = params
+	s.peerParamsMutex.Unlock()
 	// Our local idle timeout will always be > 0.
 	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
 	s.keepAliveInterval = utils.MinDuration(s.idleTimeout/2, protocol.MaxKeepAliveInterval)
@@ -1152,6 +1784,47 @@ func (s *session) processTransportParameters(params *handshake.TransportParamete
 	}
 }
 
+func (s *session) checkTransportParameters(params *handshake.TransportParameters) error {
+	if !s.version.UsesConnectionIDTransportParameters() {
+		// check the Retry token
+		if s.perspective == protocol.PerspectiveServer {
+			return nil
+		}
+		var origDestConnID protocol.ConnectionID
+		if s.receivedRetry {
+			origDestConnID = s.origDestConnID
+		}
+		if !params.OriginalConnectionID.Equal(origDestConnID) {
+			return qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected original_connection_id to equal %s, is %s", origDestConnID, params.OriginalConnectionID))
+		}
+		return nil
+	}
+
+	// check the initial_source_connection_id
+	if !params.InitialSourceConnectionID.Equal(s.handshakeDestConnID) {
+		return qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected initial_source_connection_id to equal %s, is %s", s.handshakeDestConnID, params.InitialSourceConnectionID))
+	}
+	if s.perspective == protocol.PerspectiveServer {
+		return nil
+	}
+	// check the original_destination_connection_id
+	if !params.OriginalConnectionID.Equal(s.origDestConnID) {
+		return qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected original_destination_connection_id to equal %s, is %s", s.origDestConnID, params.OriginalConnectionID))
+	}
+	// check the retry_source_connection_id
+	if s.receivedRetry {
+		if params.RetrySourceConnectionID == nil {
+			return qerr.Error(qerr.TransportParameterError, "missing retry_source_connection_id")
+		}
+		if !params.RetrySourceConnectionID.Equal(s.retrySrcConnID) {
+			return qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected retry_source_connection_id to equal %s, is %s", s.retrySrcConnID, *params.RetrySourceConnectionID))
+		}
+	} else if params.RetrySourceConnectionID != nil {
+		return qerr.Error(qerr.TransportParameterError, "received retry_source_connection_id, although no Retry was performed")
+	}
+	return nil
+}
+
 func (s *session) sendPackets() error {
 	s.pacingDeadline = time.Time{}
 
@@ -1169,15 +1842,21 @@ sendLoop:
 			break sendLoop
 		case ackhandler.SendAck:
 			// If we already sent packets, and the send mode switches to SendAck,
-			// we've just become congestion limited.
+			// we've just become congestion limited (or limited by the maximum send rate).
 			// There's no need to try to send an ACK at this moment.
 			if numPacketsSent > 0 {
+				s.setPacingDeadlineForSendRate()
 				return nil
 			}
 			// We can at most send a single ACK only packet.
 			// There will only be a new ACK after receiving new packets.
-			// SendAck is only returned when we're congestion limited, so we don't need to set the pacingt timer.
-			return s.maybeSendAckOnlyPacket()
+			// When we're congestion limited, we don't need to set the pacing timer.
+			// When we're limited by the maximum send rate, we need to wake up when the next packet can be sent.
+			if err := s.maybeSendAckOnlyPacket(); err != nil {
+				return err
+			}
+			s.setPacingDeadlineForSendRate()
+			return nil
 		case ackhandler.SendPTOInitial:
 			if err := s.sendProbePacket(protocol.EncryptionInitial); err != nil {
 				return err
@@ -1218,6 +1897,16 @@ sendLoop:
 	return nil
 }
 
+// setPacingDeadlineForSendRate sets the pacing deadline if sending is limited by the maximum send rate.
+func (s *session) setPacingDeadlineForSendRate() {
+	if atomic.LoadUint64(&s.maxSendRate) == 0 {
+		return
+	}
+	if deadline := s.sentPacketHandler.TimeUntilSend(); deadline.After(time.Now()) {
+		s.pacingDeadline = deadline
+	}
+}
+
 func (s *session) maybeSendAckOnlyPacket() error {
 	packet, err := s.packer.MaybePackAckPacket()
 	if err != nil {
@@ -1292,6 +1981,10 @@ func (s *session) sendPackedPacket(packet *packedPacket) {
 	if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && packet.IsAckEliciting() {
 		s.firstAckElicitingPacketAfterIdleSentTime = time.Now()
 	}
+	if !s.sentFirstInitial && packet.EncryptionLevel() == protocol.EncryptionInitial {
+		s.sentFirstInitial = true
+		s.setHandshakeTiming(func(t *HandshakeTiming) { t.FirstInitialSentTime = time.Now() })
+	}
 	if s.traceCallback != nil {
 		frames := make([]wire.Frame, 0, len(packet.frames))
 		for _, f := range packet.frames {
@@ -1308,6 +2001,14 @@ func (s *session) sendPackedPacket(packet *packedPacket) {
 		})
 	}
 	s.logPacket(packet)
+	if s.config.OnFlowControlBlocked != nil {
+		s.reportFlowControlBlocked(packet.frames)
+	}
+	if destConnID := packet.header.DestConnectionID; !destConnID.Equal(s.currentDestConnID) {
+		s.connIDMutex.Lock()
+		s.currentDestConnID = destConnID
+		s.connIDMutex.Unlock()
+	}
 	s.connIDManager.SentPacket()
 	s.sendQueue.Send(packet)
 }
@@ -1335,6 +2036,17 @@ func (s *session) sendConnectionClose(quicErr *qerr.QuicError) ([]byte, error) {
 	return packet.raw, s.conn.Write(packet.raw)
 }
 
+func (s *session) reportFlowControlBlocked(frames []ackhandler.Frame) {
+	for _, f := range frames {
+		switch frame := f.Frame.(type) {
+		case *wire.DataBlockedFrame:
+			s.config.OnFlowControlBlocked(true, 0, uint64(frame.DataLimit))
+		case *wire.StreamDataBlockedFrame:
+			s.config.OnFlowControlBlocked(false, frame.StreamID, uint64(frame.DataLimit))
+		}
+	}
+}
+
 func (s *session) logPacket(packet *packedPacket) {
 	if !s.logger.Debug() {
 		// We don't need to allocate the slices for calling the format functions
@@ -1359,15 +2071,72 @@ func (s *session) AcceptUniStream(ctx context.Context) (ReceiveStream, error) {
 	return s.streamsMap.AcceptUniStream(ctx)
 }
 
+func (s *session) IncomingStreams() <-chan Stream {
+	s.incomingStreamsOnce.Do(func() {
+		s.incomingStreamsChan = make(chan Stream)
+		go func() {
+			defer close(s.incomingStreamsChan)
+			for {
+				str, err := s.AcceptStream(s.ctx)
+				if err != nil {
+					return
+				}
+				select {
+				case s.incomingStreamsChan <- str:
+				case <-s.ctx.Done():
+					return
+				}
+			}
+		}()
+	})
+	return s.incomingStreamsChan
+}
+
+func (s *session) IncomingUniStreams() <-chan ReceiveStream {
+	s.incomingUniStreamsOnce.Do(func() {
+		s.incomingUniStreamsChan = make(chan ReceiveStream)
+		go func() {
+			defer close(s.incomingUniStreamsChan)
+			for {
+				str, err := s.AcceptUniStream(s.ctx)
+				if err != nil {
+					return
+				}
+				select {
+				case s.incomingUniStreamsChan <- str:
+				case <-s.ctx.Done():
+					return
+				}
+			}
+		}()
+	})
+	return s.incomingUniStreamsChan
+}
+
 // OpenStream opens a stream
 func (s *session) OpenStream() (Stream, error) {
 	return s.streamsMap.OpenStream()
 }
 
+func (s *session) OpenStreamWithOptions(opts StreamOptions) (Stream, error) {
+	return s.streamsMap.OpenStreamWithOptions(opts)
+}
+
 func (s *session) OpenStreamSync(ctx context.Context) (Stream, error) {
 	return s.streamsMap.OpenStreamSync(ctx)
 }
 
+func (s *session) OpenStreamWithData(data []byte) (Stream, error) {
+	str, err := s.streamsMap.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	if err := str.(sendStreamI).writeAndClose(data); err != nil {
+		return nil, err
+	}
+	return str, nil
+}
+
 func (s *session) OpenUniStream() (SendStream, error) {
 	return s.streamsMap.OpenUniStream()
 }
@@ -1376,6 +2145,52 @@ func (s *session) OpenUniStreamSync(ctx context.Context) (SendStream, error) {
 	return s.streamsMap.OpenUniStreamSync(ctx)
 }
 
+func (s *session) AllowMoreIncomingStreams(bidi, uni int) {
+	s.streamsMap.AllowMoreIncomingStreams(uint64(utils.Max(bidi, 0)), uint64(utils.Max(uni, 0)))
+}
+
+func (s *session) Drain(timeout time.Duration) {
+	s.drainMutex.Lock()
+	defer s.drainMutex.Unlock()
+	// The session is already draining.
+	if s.drainTimer != nil {
+		return
+	}
+	s.streamsMap.RefuseIncomingStreams(0)
+	s.drainTimer = time.AfterFunc(timeout, func() {
+		s.closeLocal(qerr.ApplicationError(0, "draining"))
+	})
+}
+
+// initialMaxData is the initial connection-level flow control window.
+// It is reduced if the application limits the amount of buffered data.
+func (s *session) initialMaxData() protocol.ByteCount {
+	if s.config.MaxConnectionReceiveBuffer != 0 {
+		return utils.MinByteCount(protocol.InitialMaxData, protocol.ByteCount(s.config.MaxConnectionReceiveBuffer))
+	}
+	return protocol.InitialMaxData
+}
+
+// initialMaxStreamData returns the initial stream-level flow control window.
+// It is capped by the MaxStreamReassemblyBuffer, such that the peer can't make us buffer more
+// out-of-order data on a stream than configured.
+func (s *session) initialMaxStreamData() protocol.ByteCount {
+	if s.config.MaxStreamReassemblyBuffer != 0 {
+		return utils.MinByteCount(protocol.InitialMaxStreamData, protocol.ByteCount(s.config.MaxStreamReassemblyBuffer))
+	}
+	return protocol.InitialMaxStreamData
+}
+
+// minAckDelay returns the min_ack_delay sent in the transport parameters.
+// It is only set if the ACK frequency extension is enabled.
+func (s *session) minAckDelay() *time.Duration {
+	if !s.config.EnableACKFrequency {
+		return nil
+	}
+	minAckDelay := protocol.MinAckDelay
+	return &minAckDelay
+}
+
 func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlowController {
 	var initialSendWindow protocol.ByteCount
 	if s.peerParams != nil {
@@ -1392,7 +2207,7 @@ func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlow
 	return flowcontrol.NewStreamFlowController(
 		id,
 		s.connFlowController,
-		protocol.InitialMaxStreamData,
+		s.initialMaxStreamData(),
 		protocol.ByteCount(s.config.MaxReceiveStreamFlowControlWindow),
 		initialSendWindow,
 		s.onHasStreamWindowUpdate,
@@ -1434,6 +2249,11 @@ func (s *session) queueControlFrame(f wire.Frame) {
 	s.scheduleSending()
 }
 
+func (s *session) queueControlFrameWithCallbacks(f ackhandler.Frame) {
+	s.framer.QueueControlFrameWithCallbacks(f)
+	s.scheduleSending()
+}
+
 func (s *session) onHasStreamWindowUpdate(id protocol.StreamID) {
 	s.windowUpdateQueue.AddStream(id)
 	s.scheduleSending()
@@ -1463,6 +2283,18 @@ func (s *session) RemoteAddr() net.Addr {
 	return s.conn.RemoteAddr()
 }
 
+func (s *session) LocalConnectionID() ConnectionID {
+	s.connIDMutex.Lock()
+	defer s.connIDMutex.Unlock()
+	return s.currentSrcConnID
+}
+
+func (s *session) RemoteConnectionID() ConnectionID {
+	s.connIDMutex.Lock()
+	defer s.connIDMutex.Unlock()
+	return s.currentDestConnID
+}
+
 func (s *session) getPerspective() protocol.Perspective {
 	return s.perspective
 }
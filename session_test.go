@@ -25,6 +25,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/testutils"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
 func areSessionsRunning() bool {
@@ -39,6 +40,30 @@ func areClosedSessionsRunning() bool {
 	return strings.Contains(b.String(), "quic-go.(*closedLocalSession).run")
 }
 
+type droppedPacket struct {
+	remoteAddr net.Addr
+	packetType quictrace.PacketType
+	size       protocol.ByteCount
+	reason     quictrace.PacketDropReason
+}
+
+type packetDropTracer struct {
+	dropped chan droppedPacket
+}
+
+var _ quictrace.PacketDropTracer = &packetDropTracer{}
+
+func newPacketDropTracer() *packetDropTracer {
+	return &packetDropTracer{dropped: make(chan droppedPacket, 10)}
+}
+
+func (t *packetDropTracer) Trace(protocol.ConnectionID, quictrace.Event) {}
+func (t *packetDropTracer) GetAllTraces() map[string][]byte              { return nil }
+
+func (t *packetDropTracer) DroppedPacket(remoteAddr net.Addr, packetType quictrace.PacketType, size protocol.ByteCount, reason quictrace.PacketDropReason) {
+	t.dropped <- droppedPacket{remoteAddr: remoteAddr, packetType: packetType, size: size, reason: reason}
+}
+
 var _ = Describe("Session", func() {
 	var (
 		sess          *session
@@ -293,7 +318,7 @@ var _ = Describe("Session", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("rejects PATH_RESPONSE frames", func() {
+		It("rejects PATH_RESPONSE frames, if no PATH_CHALLENGE was sent", func() {
 			err := sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, 0, protocol.EncryptionUnspecified)
 			Expect(err).To(MatchError("unexpected PATH_RESPONSE frame"))
 		})
@@ -350,6 +375,7 @@ var _ = Describe("Session", func() {
 			}
 			Expect(sess.handleFrame(ccf, 0, protocol.EncryptionUnspecified)).To(Succeed())
 			Eventually(sess.Context().Done()).Should(BeClosed())
+			Expect(sess.CloseCause()).To(MatchError(testErr))
 		})
 
 		It("handles CONNECTION_CLOSE frames, with an application error code", func() {
@@ -375,11 +401,46 @@ var _ = Describe("Session", func() {
 			}
 			Expect(sess.handleFrame(ccf, 0, protocol.EncryptionUnspecified)).To(Succeed())
 			Eventually(sess.Context().Done()).Should(BeClosed())
+			Expect(sess.CloseCause()).To(MatchError(testErr))
 		})
 
 		It("errors on HANDSHAKE_DONE frames", func() {
 			Expect(sess.handleHandshakeDoneFrame()).To(MatchError("PROTOCOL_VIOLATION: received a HANDSHAKE_DONE frame"))
 		})
+
+		Context("handling ACK_FREQUENCY and IMMEDIATE_ACK frames", func() {
+			It("passes ACK_FREQUENCY frames to the received packet handler", func() {
+				sess.config.EnableACKFrequency = true
+				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				sess.receivedPacketHandler = rph
+				f := &wire.AckFrequencyFrame{SequenceNumber: 1, PacketTolerance: 10, UpdateMaxAckDelay: 20 * time.Millisecond}
+				rph.EXPECT().ReceivedAckFrequency(f)
+				Expect(sess.handleFrame(f, 1, protocol.Encryption1RTT)).To(Succeed())
+			})
+
+			It("passes IMMEDIATE_ACK frames to the received packet handler", func() {
+				sess.config.EnableACKFrequency = true
+				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				sess.receivedPacketHandler = rph
+				rph.EXPECT().ReceivedImmediateAck()
+				Expect(sess.handleFrame(&wire.ImmediateAckFrame{}, 1, protocol.Encryption1RTT)).To(Succeed())
+			})
+
+			It("rejects ACK_FREQUENCY frames that request a max ack delay smaller than the min_ack_delay", func() {
+				sess.config.EnableACKFrequency = true
+				f := &wire.AckFrequencyFrame{PacketTolerance: 10, UpdateMaxAckDelay: protocol.MinAckDelay / 2}
+				Expect(sess.handleFrame(f, 1, protocol.Encryption1RTT)).To(MatchError("PROTOCOL_VIOLATION: requested max ack delay (500µs) smaller than min_ack_delay (1ms)"))
+			})
+
+			It("rejects ACK_FREQUENCY frames, if the extension is not enabled", func() {
+				f := &wire.AckFrequencyFrame{PacketTolerance: 10, UpdateMaxAckDelay: 20 * time.Millisecond}
+				Expect(sess.handleFrame(f, 1, protocol.Encryption1RTT)).To(MatchError("PROTOCOL_VIOLATION: received an ACK_FREQUENCY frame, but didn't enable the ACK frequency extension"))
+			})
+
+			It("rejects IMMEDIATE_ACK frames, if the extension is not enabled", func() {
+				Expect(sess.handleFrame(&wire.ImmediateAckFrame{}, 1, protocol.Encryption1RTT)).To(MatchError("PROTOCOL_VIOLATION: received an IMMEDIATE_ACK frame, but didn't enable the ACK frequency extension"))
+			})
+		})
 	})
 
 	It("tells its versions", func() {
@@ -451,9 +512,117 @@ var _ = Describe("Session", func() {
 				return &packedPacket{}, nil
 			})
 			mconn.EXPECT().Write(gomock.Any())
+			Expect(sess.CloseCause()).To(BeNil())
 			sess.CloseWithError(0x1337, "test error")
 			Eventually(areSessionsRunning).Should(BeFalse())
 			Expect(sess.Context().Done()).To(BeClosed())
+			Expect(sess.CloseCause()).To(MatchError(qerr.ApplicationError(0x1337, "test error")))
+		})
+
+		It("closes gracefully, after all stream data was acknowledged", func() {
+			flushed := make(chan struct{})
+			streamManager.EXPECT().WaitForFlush(gomock.Any()).DoAndReturn(func(context.Context) error {
+				<-flushed
+				return nil
+			})
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(sess.CloseGracefully(context.Background(), 0x1337, "test error")).To(Succeed())
+				close(done)
+			}()
+			Consistently(sess.Context().Done()).ShouldNot(BeClosed())
+			streamManager.EXPECT().CloseWithError(qerr.ApplicationError(0x1337, "test error"))
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(f *wire.ConnectionCloseFrame) (*packedPacket, error) {
+				Expect(f.IsApplicationError).To(BeTrue())
+				Expect(f.ErrorCode).To(BeEquivalentTo(0x1337))
+				return &packedPacket{}, nil
+			})
+			mconn.EXPECT().Write(gomock.Any())
+			close(flushed)
+			Eventually(done).Should(BeClosed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.Context().Done()).To(BeClosed())
+		})
+
+		It("returns the close error when the peer closes the session during a graceful close", func() {
+			testErr := qerr.ApplicationError(0x42, "peer closed")
+			streamManager.EXPECT().CloseWithError(testErr)
+			sessionRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+			streamManager.EXPECT().WaitForFlush(gomock.Any()).DoAndReturn(func(context.Context) error {
+				Expect(sess.handleFrame(&wire.ConnectionCloseFrame{
+					ErrorCode:          0x42,
+					ReasonPhrase:       "peer closed",
+					IsApplicationError: true,
+				}, 0, protocol.EncryptionUnspecified)).To(Succeed())
+				Eventually(sess.Context().Done()).Should(BeClosed())
+				// closing the session completes all streams
+				return nil
+			})
+			Expect(sess.CloseGracefully(context.Background(), 0x1337, "test error")).To(MatchError(testErr))
+			Eventually(areSessionsRunning).Should(BeFalse())
+			expectedRunErr = testErr
+		})
+
+		It("closes gracefully, when the context is done before all stream data was acknowledged", func() {
+			streamManager.EXPECT().WaitForFlush(gomock.Any()).Return(context.DeadlineExceeded)
+			streamManager.EXPECT().CloseWithError(qerr.ApplicationError(0x1337, "test error"))
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			Expect(sess.CloseGracefully(context.Background(), 0x1337, "test error")).To(MatchError(context.DeadlineExceeded))
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.Context().Done()).To(BeClosed())
+		})
+
+		It("drains", func() {
+			streamManager.EXPECT().RefuseIncomingStreams(protocol.ApplicationErrorCode(0))
+			sess.Drain(200 * time.Millisecond)
+			sess.Drain(0) // ignored, since the session is already draining
+			Consistently(sess.Context().Done(), 100*time.Millisecond).ShouldNot(BeClosed())
+			streamManager.EXPECT().CloseWithError(qerr.ApplicationError(0, "draining"))
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(f *wire.ConnectionCloseFrame) (*packedPacket, error) {
+				Expect(f.IsApplicationError).To(BeTrue())
+				Expect(f.ErrorCode).To(BeZero())
+				Expect(f.ReasonPhrase).To(Equal("draining"))
+				return &packedPacket{}, nil
+			})
+			mconn.EXPECT().Write(gomock.Any())
+			Eventually(sess.Context().Done()).Should(BeClosed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+		})
+
+		It("stops the drain timer when the session is closed", func() {
+			streamManager.EXPECT().RefuseIncomingStreams(protocol.ApplicationErrorCode(0))
+			sess.Drain(time.Hour)
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			sess.CloseWithError(0x1337, "test error")
+			Eventually(areSessionsRunning).Should(BeFalse())
+			sess.drainMutex.Lock()
+			defer sess.drainMutex.Unlock()
+			Expect(sess.drainTimer.Stop()).To(BeFalse()) // already stopped
+		})
+
+		It("returns the close error when sending a PING after closing", func() {
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			sess.CloseWithError(0x1337, "test error")
+			Eventually(areSessionsRunning).Should(BeFalse())
+			_, err := sess.SendPing(context.Background())
+			Expect(err).To(MatchError(qerr.ApplicationError(0x1337, "test error")))
 		})
 
 		It("includes the frame type in transport-level close frames", func() {
@@ -512,6 +681,21 @@ var _ = Describe("Session", func() {
 			expectedRunErr = testErr
 		})
 
+		It("passes a stateless reset error to the streams", func() {
+			resetErr := &StatelessResetError{Token: [16]byte{1, 2, 3}}
+			streamManager.EXPECT().CloseWithError(resetErr)
+			sessionRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+			cryptoSetup.EXPECT().Close()
+			// don't EXPECT any calls to mconn.Write()
+			sess.destroy(resetErr)
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.Context().Done()).To(BeClosed())
+			_, err := sess.SendPing(context.Background())
+			Expect(err).To(Equal(resetErr))
+			Expect(sess.CloseCause()).To(Equal(resetErr))
+			expectedRunErr = resetErr
+		})
+
 		It("cancels the context when the run loop exists", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			expectReplaceWithClosed()
@@ -530,6 +714,25 @@ var _ = Describe("Session", func() {
 			sess.shutdown()
 			Eventually(returned).Should(BeClosed())
 		})
+
+		It("stores user data, and releases it when the session is closed", func() {
+			Expect(sess.UserData()).To(BeNil())
+			sess.SetUserData("foobar")
+			Expect(sess.UserData()).To(Equal("foobar"))
+			sess.SetUserData(42)
+			Expect(sess.UserData()).To(Equal(42))
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			sess.shutdown()
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.UserData()).To(BeNil())
+			// data set after the session was closed is not stored
+			sess.SetUserData("foobar")
+			Expect(sess.UserData()).To(BeNil())
+		})
 	})
 
 	Context("receiving packets", func() {
@@ -557,6 +760,164 @@ var _ = Describe("Session", func() {
 			Expect(sess.handlePacketImpl(getPacket(&wire.ExtendedHeader{Header: hdr}, nil))).To(BeFalse())
 		})
 
+		Context("tracing dropped packets", func() {
+			var tracer *packetDropTracer
+			remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4321}
+
+			BeforeEach(func() {
+				tracer = newPacketDropTracer()
+				sess.config.QuicTracer = tracer
+				mconn.EXPECT().RemoteAddr().Return(remoteAddr).AnyTimes()
+			})
+
+			getTracedPacket := func(extHdr *wire.ExtendedHeader, data []byte) *receivedPacket {
+				p := getPacket(extHdr, data)
+				p.remoteAddr = remoteAddr
+				return p
+			}
+
+			It("traces Retry packets", func() {
+				p := getTracedPacket(&wire.ExtendedHeader{Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeRetry,
+					DestConnectionID: srcConnID,
+					SrcConnectionID:  protocol.ConnectionID{1, 2, 3, 4},
+					Token:            []byte("foobar"),
+					Version:          sess.version,
+				}}, make([]byte, 16))
+				size := protocol.ByteCount(len(p.data))
+				Expect(sess.handlePacketImpl(p)).To(BeFalse())
+				Expect(tracer.dropped).To(Receive(Equal(droppedPacket{
+					remoteAddr: remoteAddr,
+					packetType: quictrace.PacketTypeRetry,
+					size:       size,
+					reason:     quictrace.PacketDropUnexpectedPacketType,
+				})))
+			})
+
+			It("traces packets that can't be decrypted", func() {
+				hdr := &wire.ExtendedHeader{
+					Header:          wire.Header{DestConnectionID: srcConnID},
+					PacketNumber:    0x37,
+					PacketNumberLen: protocol.PacketNumberLen1,
+				}
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
+				p := getTracedPacket(hdr, []byte("foobar"))
+				size := protocol.ByteCount(len(p.data))
+				Expect(sess.handlePacketImpl(p)).To(BeFalse())
+				Expect(tracer.dropped).To(Receive(Equal(droppedPacket{
+					remoteAddr: remoteAddr,
+					packetType: quictrace.PacketType1RTT,
+					size:       size,
+					reason:     quictrace.PacketDropDecryptionFailed,
+				})))
+			})
+
+			It("traces packets for which the keys were already dropped", func() {
+				hdr := &wire.ExtendedHeader{
+					Header: wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeInitial,
+						DestConnectionID: srcConnID,
+						Length:           1,
+						Version:          sess.version,
+					},
+					PacketNumber:    0x37,
+					PacketNumberLen: protocol.PacketNumberLen1,
+				}
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrKeysDropped)
+				p := getTracedPacket(hdr, nil)
+				size := protocol.ByteCount(len(p.data))
+				Expect(sess.handlePacketImpl(p)).To(BeFalse())
+				Expect(tracer.dropped).To(Receive(Equal(droppedPacket{
+					remoteAddr: remoteAddr,
+					packetType: quictrace.PacketTypeInitial,
+					size:       size,
+					reason:     quictrace.PacketDropDecryptionFailed,
+				})))
+			})
+
+			It("drops and traces duplicate packets", func() {
+				hdr := &wire.ExtendedHeader{
+					Header:          wire.Header{DestConnectionID: srcConnID},
+					PacketNumber:    0x37,
+					PacketNumberLen: protocol.PacketNumberLen1,
+				}
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+					packetNumber:    0x37,
+					encryptionLevel: protocol.Encryption1RTT,
+					hdr:             hdr,
+					data:            []byte{0}, // one PADDING frame
+				}, nil).Times(2)
+				Expect(sess.handlePacketImpl(getTracedPacket(hdr, nil))).To(BeTrue())
+				Expect(tracer.dropped).ToNot(Receive())
+				p := getTracedPacket(hdr, nil)
+				size := protocol.ByteCount(len(p.data))
+				Expect(sess.handlePacketImpl(p)).To(BeFalse())
+				Expect(tracer.dropped).To(Receive(Equal(droppedPacket{
+					remoteAddr: remoteAddr,
+					packetType: quictrace.PacketType1RTT,
+					size:       size,
+					reason:     quictrace.PacketDropDuplicate,
+				})))
+			})
+
+			It("traces packets with a different source connection ID", func() {
+				hdr1 := &wire.ExtendedHeader{
+					Header: wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeHandshake,
+						DestConnectionID: destConnID,
+						SrcConnectionID:  srcConnID,
+						Length:           1,
+						Version:          sess.version,
+					},
+					PacketNumberLen: protocol.PacketNumberLen1,
+					PacketNumber:    1,
+				}
+				hdr2 := &wire.ExtendedHeader{
+					Header: wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeHandshake,
+						DestConnectionID: destConnID,
+						SrcConnectionID:  protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
+						Length:           1,
+						Version:          sess.version,
+					},
+					PacketNumberLen: protocol.PacketNumberLen1,
+					PacketNumber:    2,
+				}
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+					encryptionLevel: protocol.Encryption1RTT,
+					hdr:             hdr1,
+					data:            []byte{0}, // one PADDING frame
+				}, nil)
+				Expect(sess.handlePacketImpl(getTracedPacket(hdr1, nil))).To(BeTrue())
+				p := getTracedPacket(hdr2, nil)
+				size := protocol.ByteCount(len(p.data))
+				Expect(sess.handlePacketImpl(p)).To(BeFalse())
+				Expect(tracer.dropped).To(Receive(Equal(droppedPacket{
+					remoteAddr: remoteAddr,
+					packetType: quictrace.PacketTypeHandshake,
+					size:       size,
+					reason:     quictrace.PacketDropUnknownConnectionID,
+				})))
+			})
+		})
+
+		It("drops packets larger than the max_packet_size we advertised", func() {
+			sess.config.MaxUDPPayloadSize = 1200
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			packet := getPacket(hdr, make([]byte, 1200))
+			Expect(sess.handlePacketImpl(packet)).To(BeFalse())
+			// make sure the buffer was returned to the pool
+			Expect(packet.buffer.refCount).To(BeZero())
+		})
+
 		It("informs the ReceivedPacketHandler about non-ack-eliciting packets", func() {
 			hdr := &wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
@@ -571,13 +932,34 @@ var _ = Describe("Session", func() {
 				data:            []byte{0}, // one PADDING frame
 			}, nil)
 			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
-			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.EncryptionInitial, rcvTime, false)
+			gomock.InOrder(
+				rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.EncryptionInitial),
+				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.EncryptionInitial, rcvTime, false),
+			)
 			sess.receivedPacketHandler = rph
 			packet := getPacket(hdr, nil)
 			packet.rcvTime = rcvTime
 			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
 		})
 
+		It("updates the local connection ID when receiving a packet", func() {
+			Expect(sess.LocalConnectionID()).To(Equal(srcConnID))
+			newConnID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad, 0xde, 0xca, 0xfb, 0xad}
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: newConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x1337,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			Expect(sess.handlePacketImpl(getPacket(hdr, nil))).To(BeTrue())
+			Expect(sess.LocalConnectionID()).To(Equal(newConnID))
+		})
+
 		It("informs the ReceivedPacketHandler about ack-eliciting packets", func() {
 			hdr := &wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
@@ -594,7 +976,10 @@ var _ = Describe("Session", func() {
 				data:            buf.Bytes(),
 			}, nil)
 			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
-			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.Encryption1RTT, rcvTime, true)
+			gomock.InOrder(
+				rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.Encryption1RTT),
+				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.Encryption1RTT, rcvTime, true),
+			)
 			sess.receivedPacketHandler = rph
 			packet := getPacket(hdr, nil)
 			packet.rcvTime = rcvTime
@@ -672,8 +1057,9 @@ var _ = Describe("Session", func() {
 
 		It("rejects packets with empty payload", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
-				hdr:  &wire.ExtendedHeader{},
-				data: []byte{}, // no payload
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             &wire.ExtendedHeader{},
+				data:            []byte{}, // no payload
 			}, nil)
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			cryptoSetup.EXPECT().Close()
@@ -695,6 +1081,80 @@ var _ = Describe("Session", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("sends the type of the offending frame in the CONNECTION_CLOSE", func() {
+			buf := &bytes.Buffer{}
+			Expect((&wire.HandshakeDoneFrame{}).Write(buf, sess.version)).To(Succeed())
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             &wire.ExtendedHeader{},
+				data:            buf.Bytes(),
+			}, nil)
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(f *wire.ConnectionCloseFrame) (*packedPacket, error) {
+				Expect(f.IsApplicationError).To(BeFalse())
+				Expect(f.ErrorCode).To(Equal(qerr.ProtocolViolation))
+				Expect(f.FrameType).To(BeEquivalentTo(0x1e))
+				return &packedPacket{}, nil
+			})
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				Expect(err).To(MatchError("PROTOCOL_VIOLATION (frame type: 0x1e): received a HANDSHAKE_DONE frame"))
+				close(done)
+			}()
+			expectReplaceWithClosed()
+			mconn.EXPECT().Write(gomock.Any())
+			sess.handlePacket(getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, nil))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection when the peer floods NEW_CONNECTION_ID frames", func() {
+			buf := &bytes.Buffer{}
+			for i := uint8(1); i <= 100; i++ {
+				f := &wire.NewConnectionIDFrame{
+					SequenceNumber:      uint64(i),
+					ConnectionID:        protocol.ConnectionID{i, i, i, i},
+					StatelessResetToken: [16]byte{i},
+				}
+				Expect(f.Write(buf, sess.version)).To(Succeed())
+			}
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             &wire.ExtendedHeader{},
+				data:            buf.Bytes(),
+			}, nil)
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(f *wire.ConnectionCloseFrame) (*packedPacket, error) {
+				Expect(f.IsApplicationError).To(BeFalse())
+				Expect(f.ErrorCode).To(Equal(qerr.ConnectionIDLimitError))
+				Expect(f.FrameType).To(BeEquivalentTo(0x18))
+				return &packedPacket{}, nil
+			})
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				Expect(err).To(MatchError("CONNECTION_ID_LIMIT_ERROR (frame type: 0x18)"))
+				close(done)
+			}()
+			expectReplaceWithClosed()
+			mconn.EXPECT().Write(gomock.Any())
+			sess.handlePacket(getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, nil))
+			Eventually(done).Should(BeClosed())
+			Expect(sess.connIDManager.queue.Len()).To(BeNumerically("<=", protocol.MaxActiveConnectionIDs))
+		})
+
 		It("ignores packets with a different source connection ID", func() {
 			hdr1 := &wire.ExtendedHeader{
 				Header: wire.Header{
@@ -754,8 +1214,30 @@ var _ = Describe("Session", func() {
 		})
 
 		Context("updating the remote address", func() {
-			It("doesn't support connection migration", func() {
+			type pathChange struct {
+				oldAddr, newAddr net.Addr
+				validated        bool
+			}
+
+			var (
+				oldAddr, newAddr net.Addr
+				pathChanges      []pathChange
+			)
+
+			BeforeEach(func() {
+				oldAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1000}
+				newAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 100), Port: 1337}
+				mconn.EXPECT().RemoteAddr().Return(oldAddr).AnyTimes()
+				sess.config.EnableActiveMigration = true
+				pathChanges = nil
+				sess.config.OnPathChange = func(oldAddr, newAddr net.Addr, validated bool) {
+					pathChanges = append(pathChanges, pathChange{oldAddr: oldAddr, newAddr: newAddr, validated: validated})
+				}
+			})
+
+			receivePacketFrom := func(addr net.Addr, pn protocol.PacketNumber) {
 				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+					packetNumber:    pn,
 					encryptionLevel: protocol.Encryption1RTT,
 					hdr:             &wire.ExtendedHeader{},
 					data:            []byte{0}, // one PADDING frame
@@ -764,8 +1246,170 @@ var _ = Describe("Session", func() {
 					Header:          wire.Header{DestConnectionID: srcConnID},
 					PacketNumberLen: protocol.PacketNumberLen1,
 				}, nil)
-				packet.remoteAddr = &net.IPAddr{IP: net.IPv4(192, 168, 0, 100)}
-				Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+				packet.remoteAddr = addr
+				ExpectWithOffset(1, sess.handlePacketImpl(packet)).To(BeTrue())
+			}
+
+			packPathChallenge := func() *packedPacket {
+				buffer := getPacketBuffer()
+				return &packedPacket{
+					raw:    append(buffer.Slice[:0], []byte("foobar")...),
+					buffer: buffer,
+					header: &wire.ExtendedHeader{PacketNumber: 10},
+				}
+			}
+
+			var onPathChallengeLost func(wire.Frame)
+
+			expectPackPathChallenge := func() *wire.PathChallengeFrame {
+				challenge := &wire.PathChallengeFrame{}
+				packer.EXPECT().PackPathChallenge(gomock.Any(), gomock.Any()).DoAndReturn(func(f *wire.PathChallengeFrame, onLost func(wire.Frame)) (*packedPacket, error) {
+					*challenge = *f
+					onPathChallengeLost = onLost
+					return packPathChallenge(), nil
+				})
+				return challenge
+			}
+
+			expectPathChallenge := func(addr net.Addr) *wire.PathChallengeFrame {
+				challenge := expectPackPathChallenge()
+				mconn.EXPECT().WriteTo([]byte("foobar"), addr)
+				receivePacketFrom(addr, 42)
+				return challenge
+			}
+
+			It("doesn't validate the path if the address didn't change", func() {
+				receivePacketFrom(oldAddr, 42)
+				Expect(pathChanges).To(BeEmpty())
+			})
+
+			It("migrates to a new address after validating it", func() {
+				challenge := expectPathChallenge(newAddr)
+				// packets received from the new address while the validation is in progress don't trigger another PATH_CHALLENGE
+				receivePacketFrom(newAddr, 43)
+				Expect(pathChanges).To(BeEmpty())
+				mconn.EXPECT().SetCurrentRemoteAddr(newAddr)
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: challenge.Data}, 42, protocol.Encryption1RTT)).To(Succeed())
+				Expect(pathChanges).To(Equal([]pathChange{{oldAddr: oldAddr, newAddr: newAddr, validated: true}}))
+			})
+
+			It("resets the RTT estimate when migrating to a new IP address", func() {
+				sess.rttStats.UpdateRTT(10*time.Millisecond, 0, time.Now())
+				challenge := expectPathChallenge(newAddr)
+				mconn.EXPECT().SetCurrentRemoteAddr(newAddr)
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: challenge.Data}, 42, protocol.Encryption1RTT)).To(Succeed())
+				Expect(sess.rttStats.SmoothedRTT()).To(BeZero())
+				Expect(sess.rttStats.LatestRTT()).To(BeZero())
+			})
+
+			It("keeps the RTT estimate when only the port changes", func() {
+				sess.rttStats.UpdateRTT(10*time.Millisecond, 0, time.Now())
+				rebindAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 2000}
+				challenge := expectPathChallenge(rebindAddr)
+				mconn.EXPECT().SetCurrentRemoteAddr(rebindAddr)
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: challenge.Data}, 42, protocol.Encryption1RTT)).To(Succeed())
+				Expect(sess.rttStats.SmoothedRTT()).To(Equal(10 * time.Millisecond))
+			})
+
+			It("ignores PATH_RESPONSE frames that don't match the PATH_CHALLENGE", func() {
+				challenge := expectPathChallenge(newAddr)
+				data := challenge.Data
+				data[0]++
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: data}, 42, protocol.Encryption1RTT)).To(Succeed())
+				Expect(pathChanges).To(BeEmpty())
+			})
+
+			It("retransmits a lost PATH_CHALLENGE with a new payload", func() {
+				challenge := expectPathChallenge(newAddr)
+				onPathChallengeLost(challenge)
+				Expect(sess.pathChallenge.lost).To(BeTrue())
+				retransmission := expectPackPathChallenge()
+				mconn.EXPECT().WriteTo([]byte("foobar"), newAddr)
+				sess.sendPathChallenge()
+				Expect(sess.pathChallenge.lost).To(BeFalse())
+				Expect(retransmission.Data).ToNot(Equal(challenge.Data))
+				// a late PATH_RESPONSE for the lost PATH_CHALLENGE is ignored
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: challenge.Data}, 42, protocol.Encryption1RTT)).To(Succeed())
+				Expect(pathChanges).To(BeEmpty())
+				mconn.EXPECT().SetCurrentRemoteAddr(newAddr)
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: retransmission.Data}, 42, protocol.Encryption1RTT)).To(Succeed())
+				Expect(pathChanges).To(Equal([]pathChange{{oldAddr: oldAddr, newAddr: newAddr, validated: true}}))
+			})
+
+			It("doesn't retransmit a PATH_CHALLENGE that was replaced", func() {
+				challenge := expectPathChallenge(newAddr)
+				lostOnPathChallengeLost := onPathChallengeLost
+				retransmission := expectPackPathChallenge()
+				mconn.EXPECT().WriteTo([]byte("foobar"), newAddr)
+				sess.sendPathChallenge()
+				Expect(retransmission.Data).ToNot(Equal(challenge.Data))
+				lostOnPathChallengeLost(challenge)
+				Expect(sess.pathChallenge.lost).To(BeFalse())
+			})
+
+			It("drops packets from a new address if active migration is disabled", func() {
+				sess.config.EnableActiveMigration = false
+				packet := getPacket(&wire.ExtendedHeader{
+					Header:          wire.Header{DestConnectionID: srcConnID},
+					PacketNumberLen: protocol.PacketNumberLen1,
+				}, nil)
+				packet.remoteAddr = newAddr
+				Expect(sess.handlePacketImpl(packet)).To(BeFalse())
+				// make sure the buffer was returned to the pool
+				Expect(packet.buffer.refCount).To(BeZero())
+				Expect(sess.pathChallenge).To(BeNil())
+				Expect(sess.sentPathChallenge).To(BeFalse())
+				Expect(pathChanges).To(BeEmpty())
+			})
+
+			It("accepts packets from the current address if active migration is disabled", func() {
+				sess.config.EnableActiveMigration = false
+				receivePacketFrom(oldAddr, 42)
+				Expect(pathChanges).To(BeEmpty())
+			})
+
+			It("doesn't validate the path for reordered packets", func() {
+				receivePacketFrom(oldAddr, 100)
+				receivePacketFrom(newAddr, 99)
+				Expect(sess.pathChallenge).To(BeNil())
+			})
+
+			It("reports a failed path validation when the address changes again", func() {
+				expectPathChallenge(newAddr)
+				otherAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 200), Port: 1234}
+				packer.EXPECT().PackPathChallenge(gomock.Any(), gomock.Any()).Return(packPathChallenge(), nil)
+				mconn.EXPECT().WriteTo(gomock.Any(), otherAddr)
+				receivePacketFrom(otherAddr, 43)
+				Expect(pathChanges).To(Equal([]pathChange{{oldAddr: oldAddr, newAddr: newAddr, validated: false}}))
+			})
+
+			It("reports a failed path validation when it times out", func() {
+				expectPathChallenge(newAddr)
+				Expect(sess.pathChallenge.deadline).To(BeTemporally(">", time.Now()))
+				sess.pathChallenge.deadline = time.Now().Add(-time.Millisecond)
+				changes := make(chan pathChange, 1)
+				sess.config.OnPathChange = func(oldAddr, newAddr net.Addr, validated bool) {
+					changes <- pathChange{oldAddr: oldAddr, newAddr: newAddr, validated: validated}
+				}
+				// start the run loop to trigger the timeout
+				sess.lastPacketReceivedTime = time.Now()
+				packer.EXPECT().PackPacket().AnyTimes()
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+					sess.run()
+					close(done)
+				}()
+				Eventually(changes).Should(Receive(Equal(pathChange{oldAddr: oldAddr, newAddr: newAddr, validated: false})))
+				// make the go routine return
+				expectReplaceWithClosed()
+				streamManager.EXPECT().CloseWithError(gomock.Any())
+				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+				cryptoSetup.EXPECT().Close()
+				mconn.EXPECT().Write(gomock.Any())
+				sess.shutdown()
+				Eventually(done).Should(BeClosed())
 			})
 		})
 
@@ -921,6 +1565,33 @@ var _ = Describe("Session", func() {
 			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.DataBlockedFrame{DataLimit: 1337}}}))
 		})
 
+		It("reports sent DATA_BLOCKED and STREAM_DATA_BLOCKED frames", func() {
+			type blockedEvent struct {
+				connectionLevel bool
+				streamID        protocol.StreamID
+				limit           uint64
+			}
+			var events []blockedEvent
+			sess.config.OnFlowControlBlocked = func(connectionLevel bool, streamID protocol.StreamID, limit uint64) {
+				events = append(events, blockedEvent{connectionLevel: connectionLevel, streamID: streamID, limit: limit})
+			}
+			p := getPacket(1)
+			p.frames = []ackhandler.Frame{
+				{Frame: &wire.StreamDataBlockedFrame{StreamID: 5, DataLimit: 42}},
+				{Frame: &wire.PingFrame{}},
+				{Frame: &wire.DataBlockedFrame{DataLimit: 1337}},
+			}
+			packer.EXPECT().PackPacket().Return(p, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			sent, err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sent).To(BeTrue())
+			Expect(events).To(Equal([]blockedEvent{
+				{connectionLevel: false, streamID: 5, limit: 42},
+				{connectionLevel: true, limit: 1337},
+			}))
+		})
+
 		It("doesn't send when the SentPacketHandler doesn't allow it", func() {
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
@@ -1071,6 +1742,38 @@ var _ = Describe("Session", func() {
 			Eventually(written, 2*pacingDelay).Should(HaveLen(2))
 		})
 
+		It("sets a pacing deadline when limited by the maximum send rate", func() {
+			pacingDelay := scaleDuration(100 * time.Millisecond)
+			sph.EXPECT().SetMaxSendRate(uint64(1000))
+			sess.SetMaxSendRate(1000)
+			sph.EXPECT().SentPacket(gomock.Any()).Times(2)
+			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(-time.Minute)) // send one packet immediately
+			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(pacingDelay))  // then wait until the rate limit allows sending the next packet
+			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
+			sph.EXPECT().ShouldSendNumPackets().Return(1000)
+			sph.EXPECT().ShouldSendNumPackets().Return(1)
+			gomock.InOrder(
+				sph.EXPECT().SendMode().Return(ackhandler.SendAny),
+				sph.EXPECT().SendMode().Return(ackhandler.SendAck),
+				sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes(),
+			)
+			packer.EXPECT().PackPacket().Return(getPacket(100), nil)
+			packer.EXPECT().PackPacket().Return(getPacket(101), nil)
+			written := make(chan struct{}, 2)
+			mconn.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				written <- struct{}{}
+				return len(p), nil
+			}).Times(2)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			Eventually(written).Should(HaveLen(1))
+			Consistently(written, pacingDelay/2).Should(HaveLen(1))
+			Eventually(written, 2*pacingDelay).Should(HaveLen(2))
+		})
+
 		It("sends multiple packets at once", func() {
 			sph.EXPECT().SentPacket(gomock.Any()).Times(3)
 			sph.EXPECT().ShouldSendNumPackets().Return(3)
@@ -1175,31 +1878,95 @@ var _ = Describe("Session", func() {
 		})
 	})
 
-	It("cancels the HandshakeComplete context and informs the SentPacketHandler when the handshake completes", func() {
+	It("cancels the HandshakeComplete context and informs the SentPacketHandler when the handshake completes", func() {
+		packer.EXPECT().PackPacket().AnyTimes()
+		finishHandshake := make(chan struct{})
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sess.sentPacketHandler = sph
+		sphNotified := make(chan struct{})
+		sph.EXPECT().SetHandshakeComplete().Do(func() { close(sphNotified) })
+		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+		sph.EXPECT().TimeUntilSend().AnyTimes()
+		sph.EXPECT().SendMode().AnyTimes()
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		go func() {
+			defer GinkgoRecover()
+			<-finishHandshake
+			cryptoSetup.EXPECT().RunHandshake()
+			cryptoSetup.EXPECT().DropHandshakeKeys()
+			close(sess.handshakeCompleteChan)
+			sess.run()
+		}()
+		handshakeCtx := sess.HandshakeComplete()
+		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
+		mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}) // the remote addr is needed for the token
+		close(finishHandshake)
+		Eventually(handshakeCtx.Done()).Should(BeClosed())
+		Eventually(sphNotified).Should(BeClosed())
+		// make sure the go routine returns
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+		cryptoSetup.EXPECT().Close()
+		mconn.EXPECT().Write(gomock.Any())
+		sess.shutdown()
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
+	It("records the handshake timing", func() {
+		const delay = 100 * time.Millisecond
+		packer.EXPECT().PackPacket().AnyTimes()
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		// the start time is set when the session is created
+		Expect(sess.HandshakeTiming().StartTime).To(BeTemporally("~", time.Now(), scaleDuration(delay)))
+		Expect(sess.HandshakeTiming().CompleteTime).To(BeZero())
+		Expect(sess.HandshakeTiming().HandshakeDuration()).To(BeZero())
+		go func() {
+			defer GinkgoRecover()
+			cryptoSetup.EXPECT().RunHandshake()
+			sess.run()
+		}()
+		time.Sleep(scaleDuration(delay))
+		cryptoSetup.EXPECT().DropHandshakeKeys()
+		mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}) // the remote addr is needed for the token
+		close(sess.handshakeCompleteChan)
+		Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+		timing := sess.HandshakeTiming()
+		Expect(timing.CompleteTime).To(BeTemporally(">", timing.StartTime))
+		Expect(timing.RetryReceivedTime).To(BeZero())
+		Expect(timing.HandshakeDuration()).To(BeNumerically(">=", scaleDuration(delay)))
+		Expect(timing.HandshakeDuration()).To(BeNumerically("<", 2*scaleDuration(delay)))
+		// make sure the go routine returns
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+		cryptoSetup.EXPECT().Close()
+		mconn.EXPECT().Write(gomock.Any())
+		sess.shutdown()
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
+	It("confirms the handshake after it completes", func() {
 		packer.EXPECT().PackPacket().AnyTimes()
-		finishHandshake := make(chan struct{})
-		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
-		sess.sentPacketHandler = sph
-		sphNotified := make(chan struct{})
-		sph.EXPECT().SetHandshakeComplete().Do(func() { close(sphNotified) })
-		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
-		sph.EXPECT().TimeUntilSend().AnyTimes()
-		sph.EXPECT().SendMode().AnyTimes()
 		sessionRunner.EXPECT().Retire(clientDestConnID)
 		go func() {
 			defer GinkgoRecover()
-			<-finishHandshake
 			cryptoSetup.EXPECT().RunHandshake()
-			cryptoSetup.EXPECT().DropHandshakeKeys()
-			close(sess.handshakeCompleteChan)
 			sess.run()
 		}()
-		handshakeCtx := sess.HandshakeComplete()
-		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
+		confirmed := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			<-sess.HandshakeConfirmed()
+			// the handshake must have completed before it was confirmed
+			Expect(sess.HandshakeComplete().Done()).To(BeClosed())
+			close(confirmed)
+		}()
+		Consistently(sess.HandshakeConfirmed()).ShouldNot(BeClosed())
+		cryptoSetup.EXPECT().DropHandshakeKeys()
 		mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}) // the remote addr is needed for the token
-		close(finishHandshake)
-		Eventually(handshakeCtx.Done()).Should(BeClosed())
-		Eventually(sphNotified).Should(BeClosed())
+		close(sess.handshakeCompleteChan)
+		Eventually(confirmed).Should(BeClosed())
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
@@ -1263,6 +2030,54 @@ var _ = Describe("Session", func() {
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	It("asks the peer to send fewer ACKs when the handshake completes, if both support the ACK frequency extension", func() {
+		sess.config.EnableACKFrequency = true
+		minAckDelay := 2 * time.Millisecond
+		sess.peerParams = &handshake.TransportParameters{
+			MaxAckDelay: 30 * time.Millisecond,
+			MinAckDelay: &minAckDelay,
+		}
+		done := make(chan struct{})
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		packer.EXPECT().PackPacket().DoAndReturn(func() (*packedPacket, error) {
+			frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			var ackFrequencyFrame *wire.AckFrequencyFrame
+			for _, f := range frames {
+				if af, ok := f.Frame.(*wire.AckFrequencyFrame); ok {
+					ackFrequencyFrame = af
+				}
+			}
+			Expect(ackFrequencyFrame).To(Equal(&wire.AckFrequencyFrame{
+				PacketTolerance:   protocol.AckFrequencyPacketTolerance,
+				UpdateMaxAckDelay: 30 * time.Millisecond,
+			}))
+			defer close(done)
+			return &packedPacket{
+				header: &wire.ExtendedHeader{},
+				buffer: getPacketBuffer(),
+			}, nil
+		})
+		packer.EXPECT().PackPacket().AnyTimes()
+		go func() {
+			defer GinkgoRecover()
+			cryptoSetup.EXPECT().RunHandshake()
+			cryptoSetup.EXPECT().DropHandshakeKeys()
+			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}) // the remote addr is needed for the token
+			mconn.EXPECT().Write(gomock.Any())
+			close(sess.handshakeCompleteChan)
+			sess.run()
+		}()
+		Eventually(done).Should(BeClosed())
+		// make sure the go routine returns
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+		cryptoSetup.EXPECT().Close()
+		mconn.EXPECT().Write(gomock.Any())
+		sess.shutdown()
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
 	It("doesn't return a run error when closing", func() {
 		done := make(chan struct{})
 		go func() {
@@ -1335,6 +2150,45 @@ var _ = Describe("Session", func() {
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
+
+		It("uses the InitialMaxPacketSize for the packets sent before the handshake completes", func() {
+			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}).Times(2)
+			tokenGenerator, err := handshake.NewTokenGenerator()
+			Expect(err).ToNot(HaveOccurred())
+			s := newSession(
+				mconn,
+				sessionRunner,
+				nil,
+				clientDestConnID,
+				destConnID,
+				srcConnID,
+				[16]byte{},
+				populateServerConfig(&Config{InitialMaxPacketSize: 8900}),
+				nil, // tls.Config
+				tokenGenerator,
+				false,
+				utils.DefaultLogger,
+				protocol.VersionTLS,
+			).(*session)
+			Expect(s.packer.(*packetPacker).maxPacketSize).To(BeEquivalentTo(8900))
+		})
+
+		It("caps the initial stream-level flow control window at the MaxStreamReassemblyBuffer", func() {
+			Expect(sess.initialMaxStreamData()).To(BeEquivalentTo(protocol.InitialMaxStreamData))
+			sess.config.MaxStreamReassemblyBuffer = 1000
+			Expect(sess.initialMaxStreamData()).To(BeEquivalentTo(1000))
+			fc := sess.newFlowController(3)
+			Expect(fc.UpdateHighestReceived(1000, false)).To(Succeed())
+			Expect(fc.UpdateHighestReceived(1001, false)).ToNot(Succeed())
+		})
+
+		It("checks the client's initial_source_connection_id, for QUIC v2", func() {
+			sess.version = protocol.Version2
+			Expect(sess.checkTransportParameters(&handshake.TransportParameters{InitialSourceConnectionID: destConnID})).To(Succeed())
+			Expect(sess.checkTransportParameters(&handshake.TransportParameters{
+				InitialSourceConnectionID: protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+			})).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected initial_source_connection_id to equal 0x0807060504030201, is 0xdecafbad"))
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -1503,6 +2357,9 @@ var _ = Describe("Session", func() {
 				close(done)
 			}()
 			Eventually(done).Should(BeClosed())
+			cause, ok := sess.CloseCause().(net.Error)
+			Expect(ok).To(BeTrue())
+			Expect(cause.Timeout()).To(BeTrue())
 		})
 
 		It("doesn't time out when it just sent a packet", func() {
@@ -1523,6 +2380,30 @@ var _ = Describe("Session", func() {
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
+
+		It("closes the session after the maximum connection duration", func() {
+			sess.config.MaxConnectionDuration = 200 * time.Millisecond
+			sess.sessionCreationTime = time.Now()
+			packer.EXPECT().PackPacket().AnyTimes()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(f *wire.ConnectionCloseFrame) (*packedPacket, error) {
+				Expect(f.IsApplicationError).To(BeFalse())
+				Expect(f.ErrorCode).To(Equal(qerr.NoError))
+				return &packedPacket{}, nil
+			})
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				Expect(err).To(MatchError(qerr.Error(qerr.NoError, "maximum connection duration reached")))
+				close(done)
+			}()
+			Consistently(done, 150*time.Millisecond).ShouldNot(BeClosed())
+			Eventually(done).Should(BeClosed())
+		})
 	})
 
 	It("stores up to MaxSessionUnprocessedPackets packets", func(done Done) {
@@ -1550,6 +2431,14 @@ var _ = Describe("Session", func() {
 			Expect(str).To(Equal(mstr))
 		})
 
+		It("opens streams with options", func() {
+			mstr := NewMockStreamI(mockCtrl)
+			streamManager.EXPECT().OpenStreamWithOptions(StreamOptions{InitialReceiveWindow: 1337}).Return(mstr, nil)
+			str, err := sess.OpenStreamWithOptions(StreamOptions{InitialReceiveWindow: 1337})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(mstr))
+		})
+
 		It("opens unidirectional streams", func() {
 			mstr := NewMockSendStreamI(mockCtrl)
 			streamManager.EXPECT().OpenUniStream().Return(mstr, nil)
@@ -1585,6 +2474,227 @@ var _ = Describe("Session", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
 		})
+
+		It("delivers incoming streams on a channel", func() {
+			mstr1 := NewMockStreamI(mockCtrl)
+			mstr2 := NewMockStreamI(mockCtrl)
+			gomock.InOrder(
+				streamManager.EXPECT().AcceptStream(sess.Context()).Return(mstr1, nil),
+				streamManager.EXPECT().AcceptStream(sess.Context()).Return(mstr2, nil),
+				streamManager.EXPECT().AcceptStream(sess.Context()).Return(nil, errors.New("session closed")),
+			)
+			strChan := sess.IncomingStreams()
+			Expect(sess.IncomingStreams()).To(Equal(strChan))
+			var str Stream
+			Eventually(strChan).Should(Receive(&str))
+			Expect(str).To(Equal(mstr1))
+			Eventually(strChan).Should(Receive(&str))
+			Expect(str).To(Equal(mstr2))
+			Eventually(strChan).Should(BeClosed())
+		})
+
+		It("delivers incoming unidirectional streams on a channel", func() {
+			mstr := NewMockReceiveStreamI(mockCtrl)
+			gomock.InOrder(
+				streamManager.EXPECT().AcceptUniStream(sess.Context()).Return(mstr, nil),
+				streamManager.EXPECT().AcceptUniStream(sess.Context()).Return(nil, errors.New("session closed")),
+			)
+			strChan := sess.IncomingUniStreams()
+			var str ReceiveStream
+			Eventually(strChan).Should(Receive(&str))
+			Expect(str).To(Equal(mstr))
+			Eventually(strChan).Should(BeClosed())
+		})
+	})
+
+	Context("sending PINGs", func() {
+		getPing := func() ackhandler.Frame {
+			var frames []ackhandler.Frame
+			Eventually(func() []ackhandler.Frame {
+				frames, _ = sess.framer.AppendControlFrames(nil, 1000)
+				return frames
+			}).Should(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(&wire.PingFrame{}))
+			return frames[0]
+		}
+
+		It("returns the RTT sample when the PING is acknowledged", func() {
+			sess.rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				rtt, err := sess.SendPing(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				// not the smoothed RTT
+				Expect(rtt).To(Equal(1337 * time.Millisecond))
+			}()
+			ping := getPing()
+			sess.rttStats.UpdateRTT(1337*time.Millisecond, 0, time.Now())
+			ping.OnAcked(ping.Frame)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("sends a new PING when the PING is lost", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				rtt, err := sess.SendPing(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rtt).To(Equal(42 * time.Millisecond))
+			}()
+			ping := getPing()
+			ping.OnLost(ping.Frame)
+			Consistently(done).ShouldNot(BeClosed())
+			ping = getPing()
+			sess.rttStats.UpdateRTT(42*time.Millisecond, 0, time.Now())
+			ping.OnAcked(ping.Frame)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns when the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := sess.SendPing(ctx)
+				Expect(err).To(MatchError(context.Canceled))
+			}()
+			getPing()
+			Consistently(done).ShouldNot(BeClosed())
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Context("remote transport parameters", func() {
+		It("errors before the handshake completes", func() {
+			_, err := sess.RemoteTransportParameters()
+			Expect(err).To(MatchError(errHandshakeNotComplete))
+		})
+
+		It("returns the transport parameters after the handshake completes", func() {
+			sess.peerParams = &handshake.TransportParameters{
+				MaxIdleTimeout:                 42 * time.Second,
+				MaxPacketSize:                  1337,
+				InitialMaxData:                 0x1000,
+				InitialMaxStreamDataBidiLocal:  0x2000,
+				InitialMaxStreamDataBidiRemote: 0x3000,
+				InitialMaxStreamDataUni:        0x4000,
+				MaxBidiStreamNum:               10,
+				MaxUniStreamNum:                20,
+				AckDelayExponent:               5,
+				MaxAckDelay:                    30 * time.Millisecond,
+				DisableActiveMigration:         true,
+				ActiveConnectionIDLimit:        4,
+			}
+			sess.handshakeCtxCancel()
+			params, err := sess.RemoteTransportParameters()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(params).To(Equal(&TransportParameters{
+				MaxIdleTimeout:                 42 * time.Second,
+				MaxPacketSize:                  1337,
+				InitialMaxData:                 0x1000,
+				InitialMaxStreamDataBidiLocal:  0x2000,
+				InitialMaxStreamDataBidiRemote: 0x3000,
+				InitialMaxStreamDataUni:        0x4000,
+				MaxBidiStreams:                 10,
+				MaxUniStreams:                  20,
+				AckDelayExponent:               5,
+				MaxAckDelay:                    30 * time.Millisecond,
+				DisableActiveMigration:         true,
+				ActiveConnectionIDLimit:        4,
+			}))
+		})
+	})
+
+	Context("transport parameters hook", func() {
+		It("applies the modifications made by the hook", func() {
+			token := &[16]byte{1, 2, 3}
+			params := &handshake.TransportParameters{
+				MaxIdleTimeout:      30 * time.Second,
+				MaxBidiStreamNum:    100,
+				StatelessResetToken: token,
+			}
+			sess.config.TransportParametersHook = func(p *TransportParameters) {
+				Expect(p.MaxIdleTimeout).To(Equal(30 * time.Second))
+				p.MaxIdleTimeout = 1337 * time.Second
+				p.MaxBidiStreams = 5
+			}
+			sess.applyTransportParametersHook(params)
+			Expect(params.MaxIdleTimeout).To(Equal(1337 * time.Second))
+			Expect(params.MaxBidiStreamNum).To(Equal(protocol.StreamNum(5)))
+			// fields not exposed to the hook are not changed
+			Expect(params.StatelessResetToken).To(Equal(token))
+		})
+	})
+
+	Context("early transport parameters", func() {
+		It("returns nil before receiving the transport parameters", func() {
+			Expect(sess.EarlyTransportParameters()).To(BeNil())
+		})
+
+		It("returns the transport parameters before the handshake completes", func() {
+			sess.peerParams = &handshake.TransportParameters{
+				InitialMaxData:   0x1000,
+				MaxBidiStreamNum: 10,
+			}
+			params := sess.EarlyTransportParameters()
+			Expect(params.InitialMaxData).To(BeEquivalentTo(0x1000))
+			Expect(params.MaxBidiStreams).To(BeEquivalentTo(10))
+		})
+	})
+
+	It("records if 0-RTT was accepted", func() {
+		Expect(sess.Used0RTT()).To(BeFalse())
+		sess.used0RTT.Set(true)
+		Expect(sess.Used0RTT()).To(BeTrue())
+	})
+
+	It("records if the client's address was not validated using a Retry", func() {
+		Expect(sess.UsedRetry()).To(BeFalse())
+	})
+
+	It("records if the client's address was validated using a Retry", func() {
+		conn := NewMockConnection(mockCtrl)
+		conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}).AnyTimes()
+		s := newSession(
+			conn,
+			sessionRunner,
+			protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+			clientDestConnID,
+			destConnID,
+			srcConnID,
+			[16]byte{},
+			populateServerConfig(&Config{}),
+			nil, // tls.Config
+			nil, // token generator
+			false,
+			utils.DefaultLogger,
+			protocol.VersionTLS,
+		).(*session)
+		Expect(s.UsedRetry()).To(BeTrue())
+	})
+
+	It("doesn't initiate a key update before the handshake is confirmed", func() {
+		Expect(sess.InitiateKeyUpdate()).To(MatchError("cannot initiate a key update before the handshake is confirmed"))
+	})
+
+	It("initiates a key update", func() {
+		close(sess.handshakeConfirmedChan)
+		cryptoSetup.EXPECT().InitiateKeyUpdate()
+		Expect(sess.InitiateKeyUpdate()).To(Succeed())
+	})
+
+	It("records when 0-RTT is rejected", func() {
+		sess.used0RTT.Set(true)
+		Expect(sess.ZeroRTTRejected()).To(BeFalse())
+		streamManager.EXPECT().Handle0RTTRejection()
+		sess.dropEncryptionLevel(protocol.Encryption0RTT)
+		Expect(sess.ZeroRTTRejected()).To(BeTrue())
+		Expect(sess.Used0RTT()).To(BeFalse())
 	})
 
 	It("returns the local address", func() {
@@ -1593,6 +2703,15 @@ var _ = Describe("Session", func() {
 		Expect(sess.LocalAddr()).To(Equal(addr))
 	})
 
+	It("returns the current remote connection ID", func() {
+		Expect(sess.RemoteConnectionID()).To(Equal(destConnID))
+		newConnID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+		packet := getPacket(1)
+		packet.header.DestConnectionID = newConnID
+		sess.sendPackedPacket(packet)
+		Expect(sess.RemoteConnectionID()).To(Equal(newConnID))
+	})
+
 	It("returns the remote address", func() {
 		addr := &net.UDPAddr{IP: net.IPv4(1, 2, 7, 1), Port: 7331}
 		mconn.EXPECT().RemoteAddr().Return(addr)
@@ -1663,6 +2782,30 @@ var _ = Describe("Client Session", func() {
 		sess.cryptoStreamHandler = cryptoSetup
 	})
 
+	Context("using a configured initial RTT", func() {
+		BeforeEach(func() {
+			quicConf = populateClientConfig(&Config{InitialRTT: 30 * time.Millisecond}, true)
+		})
+
+		It("uses the initial RTT for the first PTO, if no RTT is restored from a session ticket", func() {
+			Expect(sess.InitialRTT()).To(Equal(30 * time.Millisecond))
+			sendTime := time.Now()
+			sess.sentPacketHandler.SentPacket(&ackhandler.Packet{
+				PacketNumber:    42,
+				Frames:          []ackhandler.Frame{{Frame: &wire.PingFrame{}}},
+				Length:          1200,
+				EncryptionLevel: protocol.EncryptionInitial,
+				SendTime:        sendTime,
+			})
+			Expect(sess.sentPacketHandler.GetLossDetectionTimeout()).To(Equal(sendTime.Add(60 * time.Millisecond)))
+		})
+
+		It("prefers the RTT restored from a session ticket", func() {
+			sess.rttStats.SetInitialRTT(50 * time.Millisecond)
+			Expect(sess.InitialRTT()).To(Equal(50 * time.Millisecond))
+		})
+	})
+
 	It("changes the connection ID when receiving the first packet from the server", func() {
 		unpacker := NewMockUnpacker(mockCtrl)
 		unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, _ time.Time, data []byte) (*unpackedPacket, error) {
@@ -1729,6 +2872,116 @@ var _ = Describe("Client Session", func() {
 		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
 	})
 
+	It("confirms the handshake when receiving a HANDSHAKE_DONE frame", func() {
+		sess.handleHandshakeComplete()
+		Expect(sess.HandshakeComplete().Done()).To(BeClosed())
+		Expect(sess.HandshakeConfirmed()).ToNot(BeClosed())
+		cryptoSetup.EXPECT().DropHandshakeKeys()
+		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+		Expect(sess.HandshakeConfirmed()).To(BeClosed())
+	})
+
+	It("doesn't confirm the handshake before it completes, when the HANDSHAKE_DONE frame is processed first", func() {
+		cryptoSetup.EXPECT().DropHandshakeKeys()
+		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+		Expect(sess.HandshakeConfirmed()).ToNot(BeClosed())
+		sess.handleHandshakeComplete()
+		Expect(sess.HandshakeComplete().Done()).To(BeClosed())
+		Expect(sess.HandshakeConfirmed()).To(BeClosed())
+	})
+
+	It("sends data queued in OnEarlySession in the first flight", func() {
+		sealer, _ := handshake.NewInitialAEAD(destConnID, protocol.PerspectiveClient, sess.version)
+		_, opener := handshake.NewInitialAEAD(destConnID, protocol.PerspectiveServer, sess.version)
+		cryptoSetup.EXPECT().GetInitialSealer().Return(sealer, nil).AnyTimes()
+		cryptoSetup.EXPECT().GetHandshakeSealer().Return(nil, handshake.ErrKeysNotYetAvailable).AnyTimes()
+		cryptoSetup.EXPECT().Get0RTTSealer().Return(sealer, nil).AnyTimes()
+		cryptoSetup.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable).AnyTimes()
+		initialStream := newCryptoStream()
+		_, err := initialStream.Write([]byte("ClientHello"))
+		Expect(err).ToNot(HaveOccurred())
+		sess.packer = newPacketPacker(
+			srcConnID,
+			sess.connIDManager.Get,
+			initialStream,
+			newCryptoStream(),
+			sess.sentPacketHandler,
+			sess.retransmissionQueue,
+			&net.UDPAddr{},
+			protocol.MinInitialPacketSize,
+			cryptoSetup,
+			sess.framer,
+			sess.receivedPacketHandler,
+			protocol.PerspectiveClient,
+			sess.version,
+		)
+		var str Stream
+		sess.config.OnEarlySession = func(s EarlySession) {
+			defer GinkgoRecover()
+			var err error
+			str, err = s.OpenStreamWithData([]byte("early request"))
+			Expect(err).ToNot(HaveOccurred())
+		}
+		written := make(chan []byte, 10)
+		mconn.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) error {
+			written <- append([]byte{}, b...)
+			return nil
+		}).AnyTimes()
+		clientHelloWritten := make(chan *handshake.TransportParameters, 1)
+		sess.clientHelloWritten = clientHelloWritten
+		clientHelloWritten <- &handshake.TransportParameters{
+			InitialMaxStreamDataBidiRemote: 0x1000,
+			InitialMaxData:                 0x1000,
+			MaxBidiStreamNum:               1,
+		}
+		go func() {
+			defer GinkgoRecover()
+			cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+			sess.run()
+		}()
+		Eventually(sess.earlySessionReady()).Should(BeClosed())
+		Expect(str).ToNot(BeNil())
+
+		unpackFrames := func(data []byte) (protocol.PacketType, []wire.Frame) {
+			hdr, packetData, _, err := wire.ParsePacket(data, 0)
+			Expect(err).ToNot(HaveOccurred())
+			cs := mocks.NewMockCryptoSetup(mockCtrl)
+			cs.EXPECT().GetInitialOpener().Return(opener, nil).AnyTimes()
+			cs.EXPECT().Get0RTTOpener().Return(opener, nil).AnyTimes()
+			unpacked, err := newPacketUnpacker(cs, sess.version).Unpack(hdr, time.Now(), packetData)
+			Expect(err).ToNot(HaveOccurred())
+			var frames []wire.Frame
+			r := bytes.NewReader(unpacked.data)
+			for r.Len() > 0 {
+				_, frame, err := wire.NewFrameParser(sess.version).ParseNext(r, unpacked.encryptionLevel)
+				Expect(err).ToNot(HaveOccurred())
+				if frame != nil {
+					frames = append(frames, frame)
+				}
+			}
+			return hdr.Type, frames
+		}
+		var firstPacket, secondPacket []byte
+		Eventually(written).Should(Receive(&firstPacket))
+		Eventually(written).Should(Receive(&secondPacket))
+		packetType, frames := unpackFrames(firstPacket)
+		Expect(packetType).To(Equal(protocol.PacketTypeInitial))
+		Expect(frames).To(ContainElement(&wire.CryptoFrame{Data: []byte("ClientHello")}))
+		packetType, frames = unpackFrames(secondPacket)
+		Expect(packetType).To(Equal(protocol.PacketType0RTT))
+		Expect(frames).To(HaveLen(1))
+		Expect(frames[0]).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+		frame := frames[0].(*wire.StreamFrame)
+		Expect(frame.StreamID).To(Equal(str.StreamID()))
+		Expect(frame.Data).To(Equal([]byte("early request")))
+		Expect(frame.FinBit).To(BeTrue())
+		// make sure the go routine returns
+		expectReplaceWithClosed()
+		cryptoSetup.EXPECT().Close()
+		sess.shutdown()
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
 	Context("handling tokens", func() {
 		var mockTokenStore *MockTokenStore
 
@@ -1767,29 +3020,96 @@ var _ = Describe("Client Session", func() {
 		getRetryTag := func(hdr *wire.ExtendedHeader) []byte {
 			buf := &bytes.Buffer{}
 			hdr.Write(buf, sess.version)
-			return handshake.GetRetryIntegrityTag(buf.Bytes(), origDestConnID)[:]
+			return handshake.GetRetryIntegrityTag(buf.Bytes(), origDestConnID, sess.version)[:]
 		}
 
 		It("handles Retry packets", func() {
 			cryptoSetup.EXPECT().ChangeConnectionID(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef})
 			packer.EXPECT().SetToken([]byte("foobar"))
+			Expect(sess.UsedRetry()).To(BeFalse())
 			Expect(sess.handlePacketImpl(getPacket(retryHdr, getRetryTag(retryHdr)))).To(BeTrue())
+			Expect(sess.UsedRetry()).To(BeTrue())
+			Expect(sess.origDestConnID).To(Equal(origDestConnID))
+			Expect(sess.retrySrcConnID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}))
 		})
 
+		expectRetryDropped := func(tracer *packetDropTracer, p *receivedPacket, reason quictrace.PacketDropReason) {
+			Expect(tracer.dropped).To(Receive(Equal(droppedPacket{
+				packetType: quictrace.PacketTypeRetry,
+				size:       protocol.ByteCount(len(p.data)),
+				reason:     reason,
+			})))
+		}
+
 		It("ignores Retry packets after receiving a regular packet", func() {
+			tracer := newPacketDropTracer()
+			sess.config.QuicTracer = tracer
 			sess.receivedFirstPacket = true
-			Expect(sess.handlePacketImpl(getPacket(retryHdr, getRetryTag(retryHdr)))).To(BeFalse())
+			p := getPacket(retryHdr, getRetryTag(retryHdr))
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			expectRetryDropped(tracer, p, quictrace.PacketDropUnexpectedPacketType)
 		})
 
 		It("ignores Retry packets if the server didn't change the connection ID", func() {
+			tracer := newPacketDropTracer()
+			sess.config.QuicTracer = tracer
 			retryHdr.SrcConnectionID = destConnID
-			Expect(sess.handlePacketImpl(getPacket(retryHdr, getRetryTag(retryHdr)))).To(BeFalse())
+			p := getPacket(retryHdr, getRetryTag(retryHdr))
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			expectRetryDropped(tracer, p, quictrace.PacketDropUnexpectedPacketType)
 		})
 
 		It("ignores Retry packets with the a wrong Integrity tag", func() {
+			tracer := newPacketDropTracer()
+			sess.config.QuicTracer = tracer
+			tag := getRetryTag(retryHdr)
+			tag[0]++
+			p := getPacket(retryHdr, tag)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			expectRetryDropped(tracer, p, quictrace.PacketDropDecryptionFailed)
+		})
+
+		It("ignores Retry packets after a Retry was already received", func() {
+			tracer := newPacketDropTracer()
+			sess.config.QuicTracer = tracer
+			sess.receivedRetry = true
+			p := getPacket(retryHdr, getRetryTag(retryHdr))
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			expectRetryDropped(tracer, p, quictrace.PacketDropUnexpectedPacketType)
+		})
+
+		It("keeps using the original connection ID after ignoring a spoofed Retry", func() {
+			tag := getRetryTag(retryHdr)
+			tag[len(tag)-1]++
+			Expect(sess.handlePacketImpl(getPacket(retryHdr, tag))).To(BeFalse())
+			Expect(sess.receivedRetry).To(BeFalse())
+			Expect(sess.handshakeDestConnID).To(Equal(destConnID))
+			// a valid Retry is still accepted
+			cryptoSetup.EXPECT().ChangeConnectionID(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef})
+			packer.EXPECT().SetToken([]byte("foobar"))
+			Expect(sess.handlePacketImpl(getPacket(retryHdr, getRetryTag(retryHdr)))).To(BeTrue())
+		})
+
+		It("keeps retransmitting the original Initial after ignoring a spoofed Retry", func() {
+			var lost []wire.Frame
+			cf := &wire.CryptoFrame{Data: []byte("foobar")}
+			sess.sentPacketHandler.SentPacket(&ackhandler.Packet{
+				PacketNumber:    42,
+				Frames:          []ackhandler.Frame{{Frame: cf, OnLost: func(f wire.Frame) { lost = append(lost, f) }}},
+				Length:          1200,
+				EncryptionLevel: protocol.EncryptionInitial,
+				SendTime:        time.Now(),
+			})
 			tag := getRetryTag(retryHdr)
 			tag[0]++
 			Expect(sess.handlePacketImpl(getPacket(retryHdr, tag))).To(BeFalse())
+			// the Initial is still outstanding, and is retransmitted when the PTO fires
+			Expect(lost).To(BeEmpty())
+			Expect(sess.sentPacketHandler.GetLossDetectionTimeout()).ToNot(BeZero())
+			Expect(sess.sentPacketHandler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(sess.sentPacketHandler.SendMode()).To(Equal(ackhandler.SendPTOInitial))
+			Expect(sess.sentPacketHandler.QueueProbePacket(protocol.EncryptionInitial)).To(BeTrue())
+			Expect(lost).To(Equal([]wire.Frame{cf}))
 		})
 	})
 
@@ -1864,6 +3184,7 @@ var _ = Describe("Client Session", func() {
 		})
 
 		It("errors if the TransportParameters contain a wrong original_connection_id", func() {
+			sess.receivedRetry = true
 			sess.origDestConnID = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
 			expectClose()
 			sess.processTransportParameters(&handshake.TransportParameters{
@@ -1874,6 +3195,74 @@ var _ = Describe("Client Session", func() {
 		})
 	})
 
+	Context("authenticating connection IDs, for QUIC v2", func() {
+		serverConnID := protocol.ConnectionID{0xc0, 0xff, 0xee}
+		retrySrcConnID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+
+		JustBeforeEach(func() {
+			sess.version = protocol.Version2
+			sess.handshakeDestConnID = serverConnID
+		})
+
+		It("accepts the connection IDs", func() {
+			Expect(sess.checkTransportParameters(&handshake.TransportParameters{
+				OriginalConnectionID:      destConnID,
+				InitialSourceConnectionID: serverConnID,
+			})).To(Succeed())
+		})
+
+		It("errors if the original_destination_connection_id is wrong", func() {
+			Expect(sess.checkTransportParameters(&handshake.TransportParameters{
+				OriginalConnectionID:      retrySrcConnID,
+				InitialSourceConnectionID: serverConnID,
+			})).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected original_destination_connection_id to equal 0x0807060504030201, is 0xdecafbad"))
+		})
+
+		It("errors if the initial_source_connection_id is wrong", func() {
+			Expect(sess.checkTransportParameters(&handshake.TransportParameters{
+				OriginalConnectionID:      destConnID,
+				InitialSourceConnectionID: retrySrcConnID,
+			})).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected initial_source_connection_id to equal 0xc0ffee, is 0xdecafbad"))
+		})
+
+		It("accepts the retry_source_connection_id after a Retry", func() {
+			sess.receivedRetry = true
+			sess.retrySrcConnID = retrySrcConnID
+			Expect(sess.checkTransportParameters(&handshake.TransportParameters{
+				OriginalConnectionID:      destConnID,
+				InitialSourceConnectionID: serverConnID,
+				RetrySourceConnectionID:   &retrySrcConnID,
+			})).To(Succeed())
+		})
+
+		It("errors if the retry_source_connection_id is missing after a Retry", func() {
+			sess.receivedRetry = true
+			sess.retrySrcConnID = retrySrcConnID
+			Expect(sess.checkTransportParameters(&handshake.TransportParameters{
+				OriginalConnectionID:      destConnID,
+				InitialSourceConnectionID: serverConnID,
+			})).To(MatchError("TRANSPORT_PARAMETER_ERROR: missing retry_source_connection_id"))
+		})
+
+		It("errors if the retry_source_connection_id is wrong", func() {
+			sess.receivedRetry = true
+			sess.retrySrcConnID = retrySrcConnID
+			Expect(sess.checkTransportParameters(&handshake.TransportParameters{
+				OriginalConnectionID:      destConnID,
+				InitialSourceConnectionID: serverConnID,
+				RetrySourceConnectionID:   &serverConnID,
+			})).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected retry_source_connection_id to equal 0xdecafbad, is 0xc0ffee"))
+		})
+
+		It("errors if there's a retry_source_connection_id, although no Retry was performed", func() {
+			Expect(sess.checkTransportParameters(&handshake.TransportParameters{
+				OriginalConnectionID:      destConnID,
+				InitialSourceConnectionID: serverConnID,
+				RetrySourceConnectionID:   &retrySrcConnID,
+			})).To(MatchError("TRANSPORT_PARAMETER_ERROR: received retry_source_connection_id, although no Retry was performed"))
+		})
+	})
+
 	Context("handling potentially injected packets", func() {
 		var unpacker *MockUnpacker
 
@@ -1953,6 +3342,30 @@ var _ = Describe("Client Session", func() {
 			Expect(sess.handlePacketImpl(getPacket(hdr, []byte("foobar")))).To(BeFalse())
 		})
 
+		It("traces ignored 0-RTT packets", func() {
+			tracer := newPacketDropTracer()
+			sess.config.QuicTracer = tracer
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketType0RTT,
+					DestConnectionID: srcConnID,
+					Length:           2 + 6,
+					Version:          sess.version,
+				},
+				PacketNumber:    0x42,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}
+			p := getPacket(hdr, []byte("foobar"))
+			size := protocol.ByteCount(len(p.data))
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			var dropped droppedPacket
+			Expect(tracer.dropped).To(Receive(&dropped))
+			Expect(dropped.packetType).To(Equal(quictrace.PacketType0RTT))
+			Expect(dropped.size).To(Equal(size))
+			Expect(dropped.reason).To(Equal(quictrace.PacketDropUnexpectedPacketType))
+		})
+
 		// Illustrates that an injected Initial with an ACK frame for an unsent packet causes
 		// the connection to immediately break down
 		It("fails on Initial-level ACK for unsent packet", func() {
@@ -0,0 +1,48 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package quic
+
+import (
+	"syscall"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Socket Control", func() {
+	getsockoptInt := func(c syscall.RawConn, opt int) int {
+		var v int
+		var serr error
+		Expect(c.Control(func(fd uintptr) {
+			v, serr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, opt)
+		})).To(Succeed())
+		Expect(serr).ToNot(HaveOccurred())
+		return v
+	}
+
+	It("sets socket options on the socket created by ListenAddr", func() {
+		const bufferSize = 1 << 14
+		config := &Config{
+			SocketControl: func(_, _ string, c syscall.RawConn) error {
+				var serr error
+				if err := c.Control(func(fd uintptr) {
+					serr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, bufferSize)
+				}); err != nil {
+					return err
+				}
+				return serr
+			},
+		}
+		ln, err := ListenAddr("127.0.0.1:0", testdata.GetTLSConfig(), config)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		rawConn, err := ln.(*baseServer).conn.(syscall.Conn).SyscallConn()
+		Expect(err).ToNot(HaveOccurred())
+		// Linux doubles the value, to allow space for bookkeeping overhead.
+		v := getsockoptInt(rawConn, syscall.SO_RCVBUF)
+		Expect(v).To(BeNumerically(">=", bufferSize))
+		Expect(v).To(BeNumerically("<=", 2*bufferSize))
+	})
+})
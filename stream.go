@@ -47,11 +47,16 @@ type streamI interface {
 	handleStreamFrame(*wire.StreamFrame) error
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
 	getWindowUpdate() protocol.ByteCount
+	setReceiveWindowSize(protocol.ByteCount)
 	// for sending
 	hasData() bool
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
+	writeAndClose([]byte) error
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
+	setNonIdempotent()
+	handle0RTTRejection()
+	flushed() <-chan struct{}
 }
 
 var _ receiveStreamI = (streamI)(nil)
@@ -135,6 +140,11 @@ func (s *stream) Close() error {
 	return nil
 }
 
+func (s *stream) RejectStream(errorCode protocol.ApplicationErrorCode) {
+	s.receiveStream.CancelRead(errorCode)
+	s.sendStream.CancelWrite(errorCode)
+}
+
 func (s *stream) SetDeadline(t time.Time) error {
 	_ = s.SetReadDeadline(t)  // SetReadDeadline never errors
 	_ = s.SetWriteDeadline(t) // SetWriteDeadline never errors
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -76,6 +77,21 @@ var _ = Describe("Stream", func() {
 		})
 	})
 
+	It("rejects the stream", func() {
+		var frames []wire.Frame
+		mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) { frames = append(frames, f) }).Times(2)
+		mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+		str.RejectStream(1234)
+		Expect(frames).To(Equal([]wire.Frame{
+			&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234},
+			&wire.ResetStreamFrame{StreamID: streamID, ErrorCode: 1234},
+		}))
+		_, err := strWithTimeout.Read([]byte{0})
+		Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+		_, err = strWithTimeout.Write([]byte("foobar"))
+		Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+	})
+
 	Context("completing", func() {
 		It("is not completed when only the receive side is completed", func() {
 			// don't EXPECT a call to mockSender.onStreamCompleted()
@@ -63,6 +63,7 @@ func newStreamsMap(
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
+	maxIncomingStreamsLimit uint64,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) streamManager {
@@ -84,6 +85,7 @@ func newStreamsMap(
 			return newStream(id, m.sender, m.newFlowController(id), version)
 		},
 		maxIncomingBidiStreams,
+		maxIncomingStreamsLimit,
 		sender.queueControlFrame,
 	)
 	m.outgoingUniStreams = newOutgoingUniStreamsMap(
@@ -99,6 +101,7 @@ func newStreamsMap(
 			return newReceiveStream(id, m.sender, m.newFlowController(id), version)
 		},
 		maxIncomingUniStreams,
+		maxIncomingStreamsLimit,
 		sender.queueControlFrame,
 	)
 	return m
@@ -114,6 +117,20 @@ func (m *streamsMap) OpenStreamSync(ctx context.Context) (Stream, error) {
 	return str, convertStreamError(err, protocol.StreamTypeBidi, m.perspective)
 }
 
+func (m *streamsMap) OpenStreamWithOptions(opts StreamOptions) (Stream, error) {
+	str, err := m.outgoingBidiStreams.OpenStream()
+	if err != nil {
+		return nil, convertStreamError(err, protocol.StreamTypeBidi, m.perspective)
+	}
+	if opts.InitialReceiveWindow > 0 {
+		str.setReceiveWindowSize(protocol.ByteCount(opts.InitialReceiveWindow))
+	}
+	if opts.NonIdempotent {
+		str.setNonIdempotent()
+	}
+	return str, nil
+}
+
 func (m *streamsMap) OpenUniStream() (SendStream, error) {
 	str, err := m.outgoingUniStreams.OpenStream()
 	return str, convertStreamError(err, protocol.StreamTypeBidi, m.perspective)
@@ -234,6 +251,45 @@ func (m *streamsMap) UpdateLimits(p *handshake.TransportParameters) error {
 	return nil
 }
 
+func (m *streamsMap) AllowMoreIncomingStreams(bidi, uni uint64) {
+	m.incomingBidiStreams.IncreaseMaxStreams(bidi)
+	m.incomingUniStreams.IncreaseMaxStreams(uni)
+}
+
+func (m *streamsMap) RefuseIncomingStreams(errorCode protocol.ApplicationErrorCode) {
+	m.incomingBidiStreams.StopAccepting(func(str streamI) {
+		str.CancelRead(errorCode)
+		str.CancelWrite(errorCode)
+	})
+	m.incomingUniStreams.StopAccepting(func(str receiveStreamI) {
+		str.CancelRead(errorCode)
+	})
+}
+
+// Handle0RTTRejection is called when the server rejects 0-RTT.
+// Only outgoing streams can have sent data in 0-RTT packets.
+func (m *streamsMap) Handle0RTTRejection() {
+	m.outgoingBidiStreams.ForEach(func(str streamI) { str.handle0RTTRejection() })
+	m.outgoingUniStreams.ForEach(func(str sendStreamI) { str.handle0RTTRejection() })
+}
+
+// WaitForFlush blocks until the peer acknowledged all data written to the streams,
+// including the FINs of streams that were closed, or until the context is done.
+func (m *streamsMap) WaitForFlush(ctx context.Context) error {
+	var flushed []<-chan struct{}
+	m.outgoingBidiStreams.ForEach(func(str streamI) { flushed = append(flushed, str.flushed()) })
+	m.incomingBidiStreams.ForEach(func(str streamI) { flushed = append(flushed, str.flushed()) })
+	m.outgoingUniStreams.ForEach(func(str sendStreamI) { flushed = append(flushed, str.flushed()) })
+	for _, c := range flushed {
+		select {
+		case <-c:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
 func (m *streamsMap) CloseWithError(err error) {
 	m.outgoingBidiStreams.CloseWithError(err)
 	m.outgoingUniStreams.CloseWithError(err)
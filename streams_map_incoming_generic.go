@@ -11,8 +11,11 @@ import (
 //go:generate genny -in $GOFILE -out streams_map_incoming_bidi.go gen "item=streamI Item=BidiStream streamTypeGeneric=protocol.StreamTypeBidi"
 //go:generate genny -in $GOFILE -out streams_map_incoming_uni.go gen "item=receiveStreamI Item=UniStream streamTypeGeneric=protocol.StreamTypeUni"
 type incomingItemsMap struct {
-	mutex         sync.RWMutex
-	newStreamChan chan struct{}
+	mutex sync.RWMutex
+	// acceptQueue contains the AcceptStream calls waiting for a stream, in the order they were made.
+	// Streams are handed to the first call in the queue.
+	// The channels are buffered, and closed when the map is closed.
+	acceptQueue []chan item
 
 	streams map[protocol.StreamNum]item
 	// When a stream is deleted before it was accepted, we can't delete it immediately.
@@ -23,9 +26,15 @@ type incomingItemsMap struct {
 	nextStreamToOpen   protocol.StreamNum // the highest stream that the peer openend
 	maxStream          protocol.StreamNum // the highest stream that the peer is allowed to open
 	maxNumStreams      uint64             // maximum number of streams
+	maxNumStreamsLimit uint64             // IncreaseMaxStreams doesn't increase maxNumStreams beyond this value
 
 	newStream        func(protocol.StreamNum) item
 	queueMaxStreamID func(*wire.MaxStreamsFrame)
+
+	// refuse is set once the map stops accepting streams (see StopAccepting).
+	// Streams starting at refuseFrom are passed to refuse, and never returned by AcceptStream.
+	refuse     func(item)
+	refuseFrom protocol.StreamNum
 	// streamNumToID    func(protocol.StreamNum) protocol.StreamID // only used for generating errors
 
 	closeErr error
@@ -34,14 +43,15 @@ type incomingItemsMap struct {
 func newIncomingItemsMap(
 	newStream func(protocol.StreamNum) item,
 	maxStreams uint64,
+	maxStreamsLimit uint64,
 	queueControlFrame func(wire.Frame),
 ) *incomingItemsMap {
 	return &incomingItemsMap{
-		newStreamChan:      make(chan struct{}),
 		streams:            make(map[protocol.StreamNum]item),
 		streamsToDelete:    make(map[protocol.StreamNum]struct{}),
 		maxStream:          protocol.StreamNum(maxStreams),
 		maxNumStreams:      maxStreams,
+		maxNumStreamsLimit: maxStreamsLimit,
 		newStream:          newStream,
 		nextStreamToOpen:   1,
 		nextStreamToAccept: 1,
@@ -51,39 +61,76 @@ func newIncomingItemsMap(
 
 func (m *incomingItemsMap) AcceptStream(ctx context.Context) (item, error) {
 	m.mutex.Lock()
+	if m.closeErr != nil {
+		m.mutex.Unlock()
+		return nil, m.closeErr
+	}
+	// Don't overtake calls that are already waiting.
+	if len(m.acceptQueue) == 0 && m.canAccept() {
+		str := m.acceptNext()
+		m.mutex.Unlock()
+		return str, nil
+	}
+	c := make(chan item, 1)
+	m.acceptQueue = append(m.acceptQueue, c)
+	m.mutex.Unlock()
 
-	var num protocol.StreamNum
-	var str item
-	for {
-		num = m.nextStreamToAccept
-		if m.closeErr != nil {
-			m.mutex.Unlock()
+	select {
+	case str, ok := <-c:
+		if !ok {
 			return nil, m.closeErr
 		}
-		var ok bool
-		str, ok = m.streams[num]
-		if ok {
-			break
+		return str, nil
+	case <-ctx.Done():
+		m.mutex.Lock()
+		for i, q := range m.acceptQueue {
+			if q == c {
+				m.acceptQueue = append(m.acceptQueue[:i], m.acceptQueue[i+1:]...)
+				m.mutex.Unlock()
+				return nil, ctx.Err()
+			}
 		}
 		m.mutex.Unlock()
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-m.newStreamChan:
+		// A stream was handed to this call (or the map was closed) before the context was canceled.
+		// Return it, so that the stream is not lost.
+		str, ok := <-c
+		if !ok {
+			return nil, m.closeErr
 		}
-		m.mutex.Lock()
+		return str, nil
 	}
+}
+
+// canAccept says if the next stream can be accepted.
+// It must be called with the mutex held.
+func (m *incomingItemsMap) canAccept() bool {
+	_, ok := m.streams[m.nextStreamToAccept]
+	return ok && !m.isRefused(m.nextStreamToAccept)
+}
+
+// acceptNext accepts the next stream. canAccept must have returned true.
+// It must be called with the mutex held.
+func (m *incomingItemsMap) acceptNext() item {
+	num := m.nextStreamToAccept
+	str := m.streams[num]
 	m.nextStreamToAccept++
 	// If this stream was completed before being accepted, we can delete it now.
 	if _, ok := m.streamsToDelete[num]; ok {
 		delete(m.streamsToDelete, num)
-		if err := m.deleteStream(num); err != nil {
-			m.mutex.Unlock()
-			return nil, err
-		}
+		// This can't fail, since the stream exists.
+		_ = m.deleteStream(num)
+	}
+	return str
+}
+
+// handOffStreams passes streams that can be accepted to the waiting AcceptStream calls.
+// It must be called with the mutex held.
+func (m *incomingItemsMap) handOffStreams() {
+	for len(m.acceptQueue) > 0 && m.canAccept() {
+		c := m.acceptQueue[0]
+		m.acceptQueue = m.acceptQueue[1:]
+		c <- m.acceptNext()
 	}
-	m.mutex.Unlock()
-	return str, nil
 }
 
 func (m *incomingItemsMap) GetOrOpenStream(num protocol.StreamNum) (item, error) {
@@ -113,16 +160,24 @@ func (m *incomingItemsMap) GetOrOpenStream(num protocol.StreamNum) (item, error)
 	// no need to check the two error conditions from above again
 	// * maxStream can only increase, so if the id was valid before, it definitely is valid now
 	// * highestStream is only modified by this function
+	var refused []item
 	for newNum := m.nextStreamToOpen; newNum <= num; newNum++ {
-		m.streams[newNum] = m.newStream(newNum)
-		select {
-		case m.newStreamChan <- struct{}{}:
-		default:
+		str := m.newStream(newNum)
+		m.streams[newNum] = str
+		if m.isRefused(newNum) {
+			refused = append(refused, str)
 		}
 	}
 	m.nextStreamToOpen = num + 1
+	m.handOffStreams()
 	s := m.streams[num]
+	refuse := m.refuse
 	m.mutex.Unlock()
+	// Refusing a stream might complete it, which then deletes it from this map.
+	// This can only be done after releasing the mutex.
+	for _, str := range refused {
+		refuse(str)
+	}
 	return s, nil
 }
 
@@ -143,7 +198,8 @@ func (m *incomingItemsMap) deleteStream(num protocol.StreamNum) error {
 
 	// Don't delete this stream yet, if it was not yet accepted.
 	// Just save it to streamsToDelete map, to make sure it is deleted as soon as it gets accepted.
-	if num >= m.nextStreamToAccept {
+	// Refused streams are never accepted, so they can be deleted right away.
+	if num >= m.nextStreamToAccept && !m.isRefused(num) {
 		if _, ok := m.streamsToDelete[num]; ok {
 			return streamError{
 				message: "Tried to delete incoming stream %d multiple times",
@@ -156,7 +212,7 @@ func (m *incomingItemsMap) deleteStream(num protocol.StreamNum) error {
 
 	delete(m.streams, num)
 	// queue a MAX_STREAM_ID frame, giving the peer the option to open a new stream
-	if m.maxNumStreams > uint64(len(m.streams)) {
+	if m.refuse == nil && m.maxNumStreams > uint64(len(m.streams)) {
 		numNewStreams := m.maxNumStreams - uint64(len(m.streams))
 		m.maxStream = m.nextStreamToOpen + protocol.StreamNum(numNewStreams) - 1
 		m.queueMaxStreamID(&wire.MaxStreamsFrame{
@@ -167,12 +223,80 @@ func (m *incomingItemsMap) deleteStream(num protocol.StreamNum) error {
 	return nil
 }
 
+// IncreaseMaxStreams allows the peer to open num more streams concurrently.
+// The limit is never increased beyond maxNumStreamsLimit.
+// The new limit is announced in a MAX_STREAMS frame.
+func (m *incomingItemsMap) IncreaseMaxStreams(num uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.maxNumStreams >= m.maxNumStreamsLimit {
+		return
+	}
+	if num > m.maxNumStreamsLimit-m.maxNumStreams {
+		m.maxNumStreams = m.maxNumStreamsLimit
+	} else {
+		m.maxNumStreams += num
+	}
+	if m.refuse != nil || m.maxNumStreams <= uint64(len(m.streams)) {
+		return
+	}
+	maxStream := m.nextStreamToOpen + protocol.StreamNum(m.maxNumStreams-uint64(len(m.streams))) - 1
+	if maxStream > protocol.MaxStreamCount {
+		maxStream = protocol.MaxStreamCount
+	}
+	if maxStream <= m.maxStream {
+		return
+	}
+	m.maxStream = maxStream
+	m.queueMaxStreamID(&wire.MaxStreamsFrame{
+		Type:         streamTypeGeneric,
+		MaxStreamNum: m.maxStream,
+	})
+}
+
+// StopAccepting stops passing new streams to AcceptStream.
+// Streams that the peer opens afterwards are passed to refuse,
+// and the peer isn't allowed to open any more streams than the current limit.
+// Streams that the peer already opened can still be accepted.
+func (m *incomingItemsMap) StopAccepting(refuse func(item)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.refuse != nil {
+		return
+	}
+	m.refuse = refuse
+	m.refuseFrom = m.nextStreamToOpen
+}
+
+func (m *incomingItemsMap) isRefused(num protocol.StreamNum) bool {
+	return m.refuse != nil && num >= m.refuseFrom
+}
+
+// ForEach calls f for every open stream.
+// f is called without holding the mutex, so it may delete streams.
+func (m *incomingItemsMap) ForEach(f func(item)) {
+	m.mutex.RLock()
+	streams := make([]item, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	m.mutex.RUnlock()
+	for _, str := range streams {
+		f(str)
+	}
+}
+
 func (m *incomingItemsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
 	for _, str := range m.streams {
 		str.closeForShutdown(err)
 	}
+	for _, c := range m.acceptQueue {
+		close(c)
+	}
+	m.acceptQueue = nil
 	m.mutex.Unlock()
-	close(m.newStreamChan)
 }
@@ -3,6 +3,9 @@ package quic
 import (
 	"context"
 	"errors"
+	"math/rand"
+	"sync"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -44,6 +47,7 @@ var _ = Describe("Streams Map (incoming)", func() {
 				return &mockGenericStream{num: num}
 			},
 			maxNumStreams,
+			uint64(protocol.MaxStreamCount),
 			mockSender.queueControlFrame,
 		)
 	})
@@ -132,6 +136,132 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	Context("concurrent AcceptStream calls", func() {
+		numWaiting := func() int {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			return len(m.acceptQueue)
+		}
+
+		It("hands out streams in the order AcceptStream was called", func() {
+			var strChans []chan item
+			for i := 0; i < 3; i++ {
+				strChan := make(chan item, 1)
+				strChans = append(strChans, strChan)
+				go func() {
+					defer GinkgoRecover()
+					str, err := m.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					strChan <- str
+				}()
+				Eventually(numWaiting).Should(Equal(i + 1))
+			}
+			_, err := m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			for i, strChan := range strChans {
+				var str item
+				Eventually(strChan).Should(Receive(&str))
+				Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(i + 1)))
+			}
+			Expect(numWaiting()).To(BeZero())
+		})
+
+		It("doesn't consume streams when AcceptStream calls are canceled", func() {
+			var strChans []chan item
+			var cancels []context.CancelFunc
+			for i := 0; i < 4; i++ {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancels = append(cancels, cancel)
+				strChan := make(chan item, 1)
+				strChans = append(strChans, strChan)
+				go func() {
+					defer GinkgoRecover()
+					str, err := m.AcceptStream(ctx)
+					if err != nil {
+						Expect(err).To(MatchError("context canceled"))
+						close(strChan)
+						return
+					}
+					strChan <- str
+				}()
+				Eventually(numWaiting).Should(Equal(i + 1))
+			}
+			cancels[0]()
+			cancels[2]()
+			Eventually(strChans[0]).Should(BeClosed())
+			Eventually(strChans[2]).Should(BeClosed())
+			Expect(numWaiting()).To(Equal(2))
+			_, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			var str item
+			Eventually(strChans[1]).Should(Receive(&str))
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+			Eventually(strChans[3]).Should(Receive(&str))
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(2)))
+			cancels[1]()
+			cancels[3]()
+		})
+
+		It("returns every stream exactly once when AcceptStream calls are canceled concurrently", func() {
+			const num = 200
+			m = newIncomingItemsMap(
+				func(num protocol.StreamNum) item { return &mockGenericStream{num: num} },
+				num,
+				uint64(protocol.MaxStreamCount),
+				mockSender.queueControlFrame,
+			)
+			accepted := make(chan protocol.StreamNum, num)
+			var wg sync.WaitGroup
+			var cancels []context.CancelFunc
+			for i := 0; i < num; i++ {
+				wg.Add(1)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancels = append(cancels, cancel)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					str, err := m.AcceptStream(ctx)
+					if err != nil {
+						Expect(err).To(MatchError("context canceled"))
+						return
+					}
+					accepted <- str.(*mockGenericStream).num
+				}()
+				if rand.Intn(2) == 0 {
+					go cancel()
+				}
+				if i%10 == 0 {
+					_, err := m.GetOrOpenStream(protocol.StreamNum(i/2 + 1))
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}
+			_, err := m.GetOrOpenStream(num)
+			Expect(err).ToNot(HaveOccurred())
+			// accept all streams that weren't returned to any of the AcceptStream calls above
+			for len(accepted) < num {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				str, err := m.AcceptStream(ctx)
+				cancel()
+				if err != nil {
+					Expect(err).To(MatchError(context.DeadlineExceeded))
+					continue
+				}
+				accepted <- str.(*mockGenericStream).num
+			}
+			for _, cancel := range cancels {
+				cancel()
+			}
+			wg.Wait()
+			close(accepted)
+			seen := make(map[protocol.StreamNum]struct{})
+			for n := range accepted {
+				Expect(seen).ToNot(HaveKey(n))
+				seen[n] = struct{}{}
+			}
+			Expect(seen).To(HaveLen(num))
+		})
+	})
+
 	It("errors AcceptStream immediately if it is closed", func() {
 		testErr := errors.New("test error")
 		m.CloseWithError(testErr)
@@ -218,4 +348,121 @@ var _ = Describe("Streams Map (incoming)", func() {
 		})
 		Expect(m.DeleteStream(4)).To(Succeed())
 	})
+
+	It("increases the stream limit", func() {
+		_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 1))
+		Expect(err).To(HaveOccurred())
+		mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+			Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(maxNumStreams + 3)))
+		})
+		m.IncreaseMaxStreams(3)
+		_, err = m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 3))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 4))
+		Expect(err).To(HaveOccurred())
+		// the increase is additive
+		mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+			Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(maxNumStreams + 5)))
+		})
+		m.IncreaseMaxStreams(2)
+		// deleting a stream takes the increased limit into account
+		_, err = m.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+			Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(maxNumStreams + 6)))
+		})
+		Expect(m.DeleteStream(1)).To(Succeed())
+	})
+
+	It("doesn't increase the stream limit beyond the maximum stream count", func() {
+		mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+			Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.MaxStreamCount))
+		})
+		m.IncreaseMaxStreams(uint64(protocol.MaxStreamCount))
+		m.IncreaseMaxStreams(1000) // doesn't queue a MAX_STREAMS frame
+	})
+
+	It("doesn't increase the stream limit beyond the configured limit", func() {
+		m.maxNumStreamsLimit = maxNumStreams + 10
+		mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+			Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(maxNumStreams + 8)))
+		})
+		m.IncreaseMaxStreams(8)
+		mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+			Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(maxNumStreams + 10)))
+		})
+		m.IncreaseMaxStreams(5)
+		m.IncreaseMaxStreams(1) // doesn't queue a MAX_STREAMS frame
+		_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 10))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 11))
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("refusing streams", func() {
+		var refused []protocol.StreamNum
+
+		BeforeEach(func() {
+			refused = nil
+		})
+
+		refuse := func(str item) {
+			refused = append(refused, str.(*mockGenericStream).num)
+		}
+
+		It("refuses streams opened after StopAccepting", func() {
+			_, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			m.StopAccepting(refuse)
+			str, err := m.GetOrOpenStream(4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(4)))
+			Expect(refused).To(Equal([]protocol.StreamNum{3, 4}))
+			// frames for refused streams are still passed to the stream
+			str, err = m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(3)))
+			Expect(refused).To(HaveLen(2))
+		})
+
+		It("only accepts streams opened before StopAccepting", func() {
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			m.StopAccepting(refuse)
+			_, err = m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err = m.AcceptStream(ctx)
+			Expect(err).To(MatchError(context.Canceled))
+		})
+
+		It("deletes refused streams right away, without sending MAX_STREAMS frames", func() {
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			m.StopAccepting(refuse)
+			_, err = m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			// don't EXPECT any calls to queueControlFrame
+			Expect(m.DeleteStream(1)).To(Succeed())
+			Expect(m.DeleteStream(2)).To(Succeed())
+			Expect(m.streams).To(BeEmpty())
+			m.IncreaseMaxStreams(10)
+		})
+
+		It("ignores repeated calls to StopAccepting", func() {
+			m.StopAccepting(refuse)
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			m.StopAccepting(func(item) { Fail("didn't expect a call") })
+			_, err = m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refused).To(Equal([]protocol.StreamNum{1, 2}))
+		})
+	})
 })
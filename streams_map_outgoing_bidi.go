@@ -203,3 +203,17 @@ func (m *outgoingBidiStreamsMap) CloseWithError(err error) {
 	}
 	m.mutex.Unlock()
 }
+
+// ForEach calls f for every open stream.
+// f is called without holding the mutex, so it may delete streams.
+func (m *outgoingBidiStreamsMap) ForEach(f func(streamI)) {
+	m.mutex.RLock()
+	streams := make([]streamI, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	m.mutex.RUnlock()
+	for _, str := range streams {
+		f(str)
+	}
+}
@@ -201,3 +201,17 @@ func (m *outgoingItemsMap) CloseWithError(err error) {
 	}
 	m.mutex.Unlock()
 }
+
+// ForEach calls f for every open stream.
+// f is called without holding the mutex, so it may delete streams.
+func (m *outgoingItemsMap) ForEach(f func(item)) {
+	m.mutex.RLock()
+	streams := make([]item, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	m.mutex.RUnlock()
+	for _, str := range streams {
+		f(str)
+	}
+}
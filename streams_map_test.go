@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
@@ -89,7 +90,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, protocol.VersionWhatever).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, uint64(protocol.MaxStreamCount), perspective, protocol.VersionWhatever).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -105,6 +106,36 @@ var _ = Describe("Streams Map", func() {
 					Expect(str.StreamID()).To(Equal(ids.firstOutgoingBidiStream + 4))
 				})
 
+				It("opens bidirectional streams with options", func() {
+					allowUnlimitedStreams()
+					fc := mocks.NewMockStreamFlowController(mockCtrl)
+					m.newFlowController = func(protocol.StreamID) flowcontrol.StreamFlowController { return fc }
+					fc.EXPECT().SetReceiveWindowSize(protocol.ByteCount(1 << 20)).Return(protocol.ByteCount(1 << 20))
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamDataFrame{
+						StreamID:   ids.firstOutgoingBidiStream,
+						ByteOffset: 1 << 20,
+					})
+					str, err := m.OpenStreamWithOptions(StreamOptions{InitialReceiveWindow: 1 << 20})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str).To(BeAssignableToTypeOf(&stream{}))
+					Expect(str.StreamID()).To(Equal(ids.firstOutgoingBidiStream))
+				})
+
+				It("resets non-idempotent streams when 0-RTT is rejected", func() {
+					allowUnlimitedStreams()
+					str, err := m.OpenStreamWithOptions(StreamOptions{NonIdempotent: true})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.(*stream).nonIdempotent).To(BeTrue())
+					str.(*stream).writeOffset = 10 // pretend data was sent
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+						StreamID:   ids.firstOutgoingBidiStream,
+						ByteOffset: 10,
+					})
+					m.Handle0RTTRejection()
+					_, err = str.Write([]byte("foobar"))
+					Expect(err).To(MatchError(Err0RTTRejected))
+				})
+
 				It("opens unidirectional streams", func() {
 					allowUnlimitedStreams()
 					str, err := m.OpenUniStream()
@@ -429,6 +460,86 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 
+			Context("refusing incoming streams", func() {
+				It("resets new bidirectional streams in both directions", func() {
+					m.RefuseIncomingStreams(42)
+					mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: ids.firstIncomingBidiStream, ErrorCode: 42})
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{StreamID: ids.firstIncomingBidiStream, ErrorCode: 42})
+					mockSender.EXPECT().onStreamCompleted(gomock.Any()).AnyTimes()
+					str, err := m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstIncomingBidiStream))
+				})
+
+				It("stops sending on new unidirectional streams", func() {
+					m.RefuseIncomingStreams(42)
+					mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: ids.firstIncomingUniStream, ErrorCode: 42})
+					str, err := m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstIncomingUniStream))
+				})
+
+				It("still accepts streams that were opened before", func() {
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+					m.RefuseIncomingStreams(42)
+					str, err := m.AcceptUniStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstIncomingUniStream))
+				})
+			})
+
+			Context("waiting for data to be flushed", func() {
+				It("returns immediately when no data was written", func() {
+					allowUnlimitedStreams()
+					_, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = m.GetOrOpenSendStream(ids.firstIncomingBidiStream)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(m.WaitForFlush(context.Background())).To(Succeed())
+				})
+
+				It("waits for the FIN on outgoing streams", func() {
+					allowUnlimitedStreams()
+					str, err := m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					mockSender.EXPECT().onHasStreamData(str.StreamID())
+					Expect(str.Close()).To(Succeed())
+					ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+					defer cancel()
+					Expect(m.WaitForFlush(ctx)).To(MatchError(context.DeadlineExceeded))
+				})
+
+				It("waits for the FIN on incoming streams", func() {
+					str, err := m.GetOrOpenSendStream(ids.firstIncomingBidiStream)
+					Expect(err).ToNot(HaveOccurred())
+					mockSender.EXPECT().onHasStreamData(str.StreamID())
+					Expect(str.Close()).To(Succeed())
+					ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+					defer cancel()
+					Expect(m.WaitForFlush(ctx)).To(MatchError(context.DeadlineExceeded))
+				})
+
+				It("stops waiting when the streams map is closed", func() {
+					allowUnlimitedStreams()
+					str, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					mockSender.EXPECT().onHasStreamData(str.StreamID())
+					Expect(str.Close()).To(Succeed())
+					done := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						Expect(m.WaitForFlush(context.Background())).To(Succeed())
+						close(done)
+					}()
+					Consistently(done).ShouldNot(BeClosed())
+					m.CloseWithError(errors.New("test error"))
+					Eventually(done).Should(BeClosed())
+				})
+			})
+
 			It("closes", func() {
 				testErr := errors.New("test error")
 				m.CloseWithError(testErr)
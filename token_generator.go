@@ -0,0 +1,37 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// The tokenGenerator is implemented by the handshake.TokenGenerator.
+type tokenGenerator interface {
+	NewRetryToken(net.Addr, protocol.ConnectionID) ([]byte, error)
+	NewToken(net.Addr) ([]byte, error)
+	DecodeToken([]byte) (*handshake.Token, error)
+}
+
+var _ tokenGenerator = &handshake.TokenGenerator{}
+
+// customTokenGenerator wraps a TokenGenerator configured by the application.
+type customTokenGenerator struct {
+	TokenGenerator
+}
+
+var _ tokenGenerator = &customTokenGenerator{}
+
+func (g *customTokenGenerator) DecodeToken(data []byte) (*handshake.Token, error) {
+	t, err := g.TokenGenerator.DecodeToken(data)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	return &handshake.Token{
+		IsRetryToken:             t.IsRetryToken,
+		RemoteAddr:               t.RemoteAddr,
+		SentTime:                 t.SentTime,
+		OriginalDestConnectionID: t.OriginalDestConnectionID,
+	}, nil
+}